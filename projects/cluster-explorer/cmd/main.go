@@ -16,7 +16,6 @@ import (
 	"gopkg.in/yaml.v3"
 
 	"github.com/saif-islam/es-playground/projects/cluster-explorer/internal/handlers"
-	"github.com/saif-islam/es-playground/projects/cluster-explorer/internal/models"
 	"github.com/saif-islam/es-playground/projects/cluster-explorer/internal/services"
 	"github.com/saif-islam/es-playground/shared"
 )
@@ -25,6 +24,12 @@ import (
 type Config struct {
 	Server        ServerConfig        `yaml:"server"`
 	Elasticsearch ElasticsearchConfig `yaml:"elasticsearch"`
+	// Additional named clusters, selected per request with the X-ES-Cluster header
+	Clusters      map[string]ElasticsearchConfig `yaml:"clusters"`
+	// API keys required by mutating requests
+	Auth          shared.AuthConfig   `yaml:"auth"`
+	// Per-client limit on cluster settings changes
+	RateLimit     shared.RateLimitConfig `yaml:"rate_limit"`
 	Logging       LoggingConfig       `yaml:"logging"`
 }
 
@@ -42,16 +47,25 @@ type ElasticsearchConfig struct {
 	Password  string    `yaml:"password"`
 	APIKey    string    `yaml:"api_key"`
 	TLSConfig TLSConfig `yaml:"tls"`
+	// Per-request timeout for individual Elasticsearch calls
+	RequestTimeout time.Duration `yaml:"request_timeout"`
+	// Fail fast after repeated failures instead of waiting on a struggling cluster
+	CircuitBreaker shared.BreakerConfig `yaml:"circuit_breaker"`
 }
 
 type TLSConfig struct {
-	InsecureSkipVerify bool `yaml:"insecure_skip_verify"`
+	InsecureSkipVerify bool   `yaml:"insecure_skip_verify"`
+	CACertPath         string `yaml:"ca_cert_path"`
+	ClientCertPath     string `yaml:"client_cert_path"`
+	ClientKeyPath      string `yaml:"client_key_path"`
 }
 
 type LoggingConfig struct {
 	Level  string `yaml:"level"`
 	Format string `yaml:"format"`
 	Output string `yaml:"output"`
+
+	shared.BodyCaptureConfig `yaml:",inline"`
 }
 
 func main() {
@@ -73,17 +87,7 @@ func main() {
 		zap.Int("port", config.Server.Port))
 
 	// Initialize Elasticsearch client
-	esConfig := &shared.ESConfig{
-		URLs:     config.Elasticsearch.URLs,
-		Username: config.Elasticsearch.Username,
-		Password: config.Elasticsearch.Password,
-		APIKey:   config.Elasticsearch.APIKey,
-		TLSConfig: &shared.TLSConfig{
-			InsecureSkipVerify: config.Elasticsearch.TLSConfig.InsecureSkipVerify,
-		},
-	}
-
-	esClient, err := shared.NewESClient(esConfig, logger)
+	defaultClient, err := shared.NewESClient(toESConfig(config.Elasticsearch), logger)
 	if err != nil {
 		logger.Fatal("Failed to create Elasticsearch client", zap.Error(err))
 	}
@@ -92,10 +96,33 @@ func main() {
 	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
 	defer cancel()
 
-	if err := esClient.WaitForCluster(ctx, "yellow", 30*time.Second); err != nil {
+	if err := defaultClient.WaitForCluster(ctx, "yellow", 30*time.Second); err != nil {
 		logger.Fatal("Elasticsearch cluster not ready", zap.Error(err))
 	}
 
+	// Register additional clusters; one that is unreachable at startup is skipped rather
+	// than keeping the default cluster from being served
+	clusters := shared.NewClusterRegistry(shared.DefaultClusterName, defaultClient, logger)
+	for name, clusterConfig := range config.Clusters {
+		if name == shared.DefaultClusterName {
+			logger.Warn("Ignoring cluster with reserved name", zap.String("cluster", name))
+			continue
+		}
+
+		client, err := shared.NewESClient(toESConfig(clusterConfig), logger)
+		if err != nil {
+			logger.Error("Failed to connect to cluster, skipping", zap.String("cluster", name), zap.Error(err))
+			continue
+		}
+		clusters.Register(name, client)
+	}
+
+	// Services share one client that routes each request to the selected cluster
+	esClient, err := clusters.NewRoutingClient()
+	if err != nil {
+		logger.Fatal("Failed to create cluster routing client", zap.Error(err))
+	}
+
 	// Initialize services
 	clusterService := services.NewClusterService(esClient, logger)
 
@@ -107,7 +134,16 @@ func main() {
 		gin.SetMode(gin.ReleaseMode)
 	}
 
-	router := setupRoutes(clusterHandler, logger)
+	if !config.Auth.Enabled() {
+		logger.Warn("No API keys configured, mutating endpoints are unauthenticated")
+	}
+
+	settingsLimiter, err := shared.NewRateLimiter(config.RateLimit)
+	if err != nil {
+		logger.Fatal("Invalid rate limit configuration", zap.Error(err))
+	}
+
+	router := setupRoutes(clusterHandler, clusters, config.Auth, settingsLimiter, config.Logging.BodyCaptureConfig, logger)
 
 	server := &http.Server{
 		Addr:         fmt.Sprintf("%s:%d", config.Server.Host, config.Server.Port),
@@ -145,6 +181,24 @@ func main() {
 	logger.Info("Cluster Explorer exited")
 }
 
+// toESConfig converts a cluster's configuration to the shared client configuration
+func toESConfig(config ElasticsearchConfig) *shared.ESConfig {
+	return &shared.ESConfig{
+		URLs:     config.URLs,
+		Username: config.Username,
+		Password: config.Password,
+		APIKey:   config.APIKey,
+		TLSConfig: &shared.TLSConfig{
+			InsecureSkipVerify: config.TLSConfig.InsecureSkipVerify,
+			CACertPath:         config.TLSConfig.CACertPath,
+			ClientCertPath:     config.TLSConfig.ClientCertPath,
+			ClientKeyPath:      config.TLSConfig.ClientKeyPath,
+		},
+		RequestTimeout: config.RequestTimeout,
+		CircuitBreaker: config.CircuitBreaker,
+	}
+}
+
 func loadConfig() (*Config, error) {
 	configPath := os.Getenv("CONFIG_PATH")
 	if configPath == "" {
@@ -173,6 +227,16 @@ func loadConfig() (*Config, error) {
 			TLSConfig: TLSConfig{
 				InsecureSkipVerify: false,
 			},
+			RequestTimeout: 30 * time.Second,
+			CircuitBreaker: shared.BreakerConfig{
+				FailureThreshold: 5,
+				OpenTimeout:      30 * time.Second,
+			},
+		},
+		RateLimit: shared.RateLimitConfig{
+			RequestsPerSecond: 1,
+			Burst:             5,
+			Key:               shared.RateLimitByIP,
 		},
 		Logging: LoggingConfig{
 			Level:  "info",
@@ -190,6 +254,11 @@ func loadConfig() (*Config, error) {
 		log.Printf("Config file not found at %s, using defaults", configPath)
 	}
 
+	// Keep the key out of the config file in deployments
+	if apiKey := os.Getenv("API_KEY"); apiKey != "" {
+		config.Auth.APIKeys = append(config.Auth.APIKeys, apiKey)
+	}
+
 	return config, nil
 }
 
@@ -217,7 +286,7 @@ func initLogger(config LoggingConfig) (*zap.Logger, error) {
 	return zapConfig.Build()
 }
 
-func setupRoutes(clusterHandler *handlers.ClusterHandler, logger *zap.Logger) *gin.Engine {
+func setupRoutes(clusterHandler *handlers.ClusterHandler, clusters *shared.ClusterRegistry, auth shared.AuthConfig, settingsLimiter *shared.RateLimiter, bodyCapture shared.BodyCaptureConfig, logger *zap.Logger) *gin.Engine {
 	router := gin.New()
 
 	// Middleware
@@ -235,6 +304,36 @@ func setupRoutes(clusterHandler *handlers.ClusterHandler, logger *zap.Logger) *g
 		c.Next()
 	})
 
+	// Request and response bodies at debug level, when logging.capture_bodies is set
+	router.Use(shared.CaptureBodies(bodyCapture, logger))
+
+	// Cluster selection middleware: X-ES-Cluster header or ?cluster= picks a registered cluster
+	router.Use(func(c *gin.Context) {
+		cluster := c.GetHeader("X-ES-Cluster")
+		if cluster == "" {
+			cluster = c.Query("cluster")
+		}
+		if cluster == "" {
+			c.Next()
+			return
+		}
+
+		if _, ok := clusters.Client(cluster); !ok {
+			c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{
+				"error":      "Unknown cluster",
+				"message":    fmt.Sprintf("Cluster %s is not registered", cluster),
+				"clusters":   clusters.Names(),
+				"request_id": c.GetString("request_id"),
+				"timestamp":  time.Now(),
+			})
+			return
+		}
+
+		c.Request = c.Request.WithContext(shared.WithCluster(c.Request.Context(), cluster))
+		c.Set("cluster", cluster)
+		c.Next()
+	})
+
 	// Serve static files (for the web UI)
 	router.Static("/static", "./static")
 	router.LoadHTMLGlob("templates/*")
@@ -254,18 +353,39 @@ func setupRoutes(clusterHandler *handlers.ClusterHandler, logger *zap.Logger) *g
 
 	// Health check
 	router.GET("/health", func(c *gin.Context) {
+		// An open circuit breaker means requests to that cluster are failing fast
+		status := "healthy"
+		breakers := clusters.BreakerStates()
+		for _, state := range breakers {
+			if state != shared.BreakerClosed {
+				status = "degraded"
+			}
+		}
+
 		c.JSON(http.StatusOK, gin.H{
-			"status":     "healthy",
+			"status":     status,
 			"service":    "cluster-explorer",
 			"version":    "1.0.0",
+			"breakers":   breakers,
 			"request_id": c.GetString("request_id"),
 			"timestamp":  time.Now(),
 		})
 	})
 
-	// API routes
+	// API routes; reads and writes need an API key unless auth.public_paths opens a read
 	v1 := router.Group("/api/v1")
+	v1.Use(shared.RequireAPIKey(auth, logger))
 	{
+		// Registered Elasticsearch clusters
+		v1.GET("/clusters", func(c *gin.Context) {
+			c.JSON(http.StatusOK, gin.H{
+				"clusters":   clusters.Names(),
+				"default":    clusters.DefaultName(),
+				"request_id": c.GetString("request_id"),
+				"timestamp":  time.Now(),
+			})
+		})
+
 		cluster := v1.Group("/cluster")
 		{
 			// Comprehensive cluster information
@@ -283,19 +403,36 @@ func setupRoutes(clusterHandler *handlers.ClusterHandler, logger *zap.Logger) *g
 
 			// Index management
 			cluster.GET("/indices", clusterHandler.GetIndices)
+			cluster.GET("/aliases", clusterHandler.GetAliases)
+			cluster.GET("/templates", clusterHandler.GetTemplates)
 
 			// Shard management
 			cluster.GET("/shards", clusterHandler.GetShardAllocation)
+			cluster.GET("/recovery", clusterHandler.GetRecoveryStatus)
+			cluster.GET("/disk/alerts", clusterHandler.GetDiskAlerts)
+
+			// Long-running tasks such as reindex, force merge and delete-by-query
+			cluster.GET("/tasks", clusterHandler.ListTasks)
+			cluster.POST("/tasks/:id/_cancel", clusterHandler.CancelTask)
+
+			// Snapshot and restore
+			cluster.PUT("/snapshots/:repo", clusterHandler.RegisterSnapshotRepository)
+			cluster.GET("/snapshots/:repo", clusterHandler.ListSnapshots)
+			cluster.PUT("/snapshots/:repo/:snapshot", clusterHandler.CreateSnapshot)
+			cluster.GET("/snapshots/:repo/:snapshot", clusterHandler.GetSnapshot)
+			cluster.POST("/snapshots/:repo/:snapshot/_restore", clusterHandler.RestoreSnapshot)
 
 			// Performance monitoring
 			cluster.GET("/performance", clusterHandler.GetPerformanceMetrics)
+			cluster.GET("/threadpools", clusterHandler.GetThreadPools)
 
 			// Real-time monitoring
 			cluster.GET("/monitor/health", clusterHandler.MonitorHealth)
+			cluster.GET("/monitor/health/ws", clusterHandler.MonitorHealthWS)
 
 			// Settings management
 			cluster.GET("/settings", clusterHandler.GetClusterSettings)
-			cluster.PUT("/settings", clusterHandler.UpdateClusterSettings)
+			cluster.PUT("/settings", settingsLimiter.Middleware(logger), clusterHandler.UpdateClusterSettings)
 		}
 	}
 
@@ -2,8 +2,8 @@ package handlers
 
 import (
 	"context"
+	"errors"
 	"net/http"
-	"strconv"
 	"time"
 
 	"github.com/gin-gonic/gin"
@@ -180,7 +180,7 @@ func (h *ClusterHandler) GetIndices(c *gin.Context) {
 
 // GetShardAllocation handles GET /api/v1/cluster/shards
 func (h *ClusterHandler) GetShardAllocation(c *gin.Context) {
-	ctx, cancel := context.WithTimeout(c.Request.Context(), 15*time.Second)
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 30*time.Second) // allocation explain runs per unassigned shard
 	defer cancel()
 
 	allocation, err := h.clusterService.GetShardAllocation(ctx)
@@ -257,24 +257,11 @@ func (h *ClusterHandler) GetHotThreads(c *gin.Context) {
 
 // MonitorHealth handles GET /api/v1/cluster/monitor/health
 func (h *ClusterHandler) MonitorHealth(c *gin.Context) {
-	// Parse interval parameter
-	intervalStr := c.DefaultQuery("interval", "5s")
-	interval, err := time.ParseDuration(intervalStr)
-	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{
-			"error":      "Invalid interval format",
-			"message":    "Use format like '5s', '1m', '10s'",
-			"request_id": c.GetString("request_id"),
-			"timestamp":  time.Now(),
-		})
+	interval, ok := monitorInterval(c)
+	if !ok {
 		return
 	}
 
-	// Set minimum interval to prevent too frequent requests
-	if interval < time.Second {
-		interval = time.Second
-	}
-
 	ctx, cancel := context.WithCancel(c.Request.Context())
 	defer cancel()
 
@@ -312,6 +299,105 @@ func (h *ClusterHandler) MonitorHealth(c *gin.Context) {
 	}
 }
 
+// GetDiskAlerts handles GET /api/v1/cluster/disk/alerts
+func (h *ClusterHandler) GetDiskAlerts(c *gin.Context) {
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 15*time.Second)
+	defer cancel()
+
+	alerts, err := h.clusterService.GetDiskAlerts(ctx)
+	if err != nil {
+		h.logger.Error("Failed to check disk watermarks", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":      "Failed to check disk watermarks",
+			"message":    err.Error(),
+			"request_id": c.GetString("request_id"),
+			"timestamp":  time.Now(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"disk":       alerts,
+		"request_id": c.GetString("request_id"),
+		"timestamp":  time.Now(),
+	})
+}
+
+// GetThreadPools handles GET /api/v1/cluster/threadpools
+func (h *ClusterHandler) GetThreadPools(c *gin.Context) {
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 15*time.Second)
+	defer cancel()
+
+	report, err := h.clusterService.GetThreadPoolStats(ctx)
+	if err != nil {
+		h.logger.Error("Failed to get thread pool stats", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":      "Failed to retrieve thread pool stats",
+			"message":    err.Error(),
+			"request_id": c.GetString("request_id"),
+			"timestamp":  time.Now(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"thread_pools": report,
+		"request_id":   c.GetString("request_id"),
+		"timestamp":    time.Now(),
+	})
+}
+
+// GetRecoveryStatus handles GET /api/v1/cluster/recovery
+func (h *ClusterHandler) GetRecoveryStatus(c *gin.Context) {
+	index := c.Query("index")
+	activeOnly := c.DefaultQuery("active_only", "true") != "false"
+	waitForCompletion := c.Query("wait_for_completion") == "true"
+
+	// Waits longer than the server's write_timeout are cut off; raise it for long recoveries
+	timeout := 20 * time.Second
+	if timeoutStr := c.Query("timeout"); timeoutStr != "" {
+		parsed, err := time.ParseDuration(timeoutStr)
+		if err != nil || parsed <= 0 {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error":      "Invalid timeout format",
+				"message":    "Use format like '30s', '5m'",
+				"request_id": c.GetString("request_id"),
+				"timestamp":  time.Now(),
+			})
+			return
+		}
+		timeout = parsed
+	}
+
+	var (
+		status *models.RecoveryStatus
+		err    error
+	)
+	if waitForCompletion {
+		// Leave headroom for the final status request after the wait times out
+		ctx, cancel := context.WithTimeout(c.Request.Context(), timeout+5*time.Second)
+		defer cancel()
+		status, err = h.clusterService.WaitForRecovery(ctx, index, 2*time.Second, timeout)
+	} else {
+		ctx, cancel := context.WithTimeout(c.Request.Context(), 15*time.Second)
+		defer cancel()
+		status, err = h.clusterService.GetRecoveryStatus(ctx, index, activeOnly)
+	}
+	if err != nil {
+		h.logger.Error("Failed to get recovery status", zap.String("index", index), zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":      "Failed to retrieve recovery status",
+			"message":    err.Error(),
+			"request_id": c.GetString("request_id"),
+			"timestamp":  time.Now(),
+		})
+		return
+	}
+
+	status.RequestID = c.GetString("request_id")
+	c.JSON(http.StatusOK, status)
+}
+
 // GetClusterSettings handles GET /api/v1/cluster/settings
 func (h *ClusterHandler) GetClusterSettings(c *gin.Context) {
 	ctx, cancel := context.WithTimeout(c.Request.Context(), 10*time.Second)
@@ -336,7 +422,8 @@ func (h *ClusterHandler) GetClusterSettings(c *gin.Context) {
 	})
 }
 
-// UpdateClusterSettings handles PUT /api/v1/cluster/settings
+// UpdateClusterSettings handles PUT /api/v1/cluster/settings. With ?dry_run=true the
+// settings are validated and the changes they would make returned without applying them.
 func (h *ClusterHandler) UpdateClusterSettings(c *gin.Context) {
 	ctx, cancel := context.WithTimeout(c.Request.Context(), 10*time.Second)
 	defer cancel()
@@ -357,8 +444,37 @@ func (h *ClusterHandler) UpdateClusterSettings(c *gin.Context) {
 		return
 	}
 
+	if c.Query("dry_run") == "true" {
+		changes, err := h.clusterService.PreviewClusterSettings(ctx, request.Settings, request.Persistent)
+		if err != nil {
+			if h.respondInvalidSetting(c, err) {
+				return
+			}
+			h.logger.Error("Failed to preview cluster settings", zap.Error(err))
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"error":      "Failed to preview cluster settings",
+				"message":    err.Error(),
+				"request_id": c.GetString("request_id"),
+				"timestamp":  time.Now(),
+			})
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{
+			"dry_run":    true,
+			"changes":    changes,
+			"persistent": request.Persistent,
+			"request_id": c.GetString("request_id"),
+			"timestamp":  time.Now(),
+		})
+		return
+	}
+
 	err := h.clusterService.UpdateClusterSettings(ctx, request.Settings, request.Persistent)
 	if err != nil {
+		if h.respondInvalidSetting(c, err) {
+			return
+		}
 		h.logger.Error("Failed to update cluster settings", 
 			zap.Any("settings", request.Settings),
 			zap.Bool("persistent", request.Persistent),
@@ -382,6 +498,24 @@ func (h *ClusterHandler) UpdateClusterSettings(c *gin.Context) {
 	})
 }
 
+// respondInvalidSetting responds 400 naming the offending key when err is a setting
+// rejected by validation, reporting whether it did
+func (h *ClusterHandler) respondInvalidSetting(c *gin.Context, err error) bool {
+	var invalid *services.InvalidSettingError
+	if !errors.As(err, &invalid) {
+		return false
+	}
+
+	c.JSON(http.StatusBadRequest, gin.H{
+		"error":      "Invalid cluster setting",
+		"message":    invalid.Error(),
+		"key":        invalid.Key,
+		"request_id": c.GetString("request_id"),
+		"timestamp":  time.Now(),
+	})
+	return true
+}
+
 // GetClusterOverview handles GET /api/v1/cluster/overview
 func (h *ClusterHandler) GetClusterOverview(c *gin.Context) {
 	ctx, cancel := context.WithTimeout(c.Request.Context(), 20*time.Second)
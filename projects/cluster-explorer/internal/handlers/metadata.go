@@ -0,0 +1,80 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+
+	"github.com/saif-islam/es-playground/projects/cluster-explorer/internal/models"
+	"github.com/saif-islam/es-playground/projects/cluster-explorer/internal/services"
+)
+
+// GetAliases handles GET /api/v1/cluster/aliases
+func (h *ClusterHandler) GetAliases(c *gin.Context) {
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 15*time.Second)
+	defer cancel()
+
+	aliases, err := h.clusterService.GetAliases(ctx)
+	if err != nil {
+		h.logger.Error("Failed to get aliases", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":      "Failed to retrieve aliases",
+			"message":    err.Error(),
+			"request_id": c.GetString("request_id"),
+			"timestamp":  time.Now(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"aliases":    aliases,
+		"count":      len(aliases),
+		"request_id": c.GetString("request_id"),
+		"timestamp":  time.Now(),
+	})
+}
+
+// GetTemplates handles GET /api/v1/cluster/templates. With ?index=<name> the response
+// also reports which templates a new index of that name would be created from.
+func (h *ClusterHandler) GetTemplates(c *gin.Context) {
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 15*time.Second)
+	defer cancel()
+
+	templates, err := h.clusterService.GetTemplates(ctx)
+	if err != nil {
+		h.logger.Error("Failed to get index templates", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":      "Failed to retrieve index templates",
+			"message":    err.Error(),
+			"request_id": c.GetString("request_id"),
+			"timestamp":  time.Now(),
+		})
+		return
+	}
+
+	composable := []models.TemplateInfo{}
+	legacy := []models.TemplateInfo{}
+	for _, template := range templates {
+		if template.Type == services.TemplateComposable {
+			composable = append(composable, template)
+		} else {
+			legacy = append(legacy, template)
+		}
+	}
+
+	response := gin.H{
+		"composable": composable,
+		"legacy":     legacy,
+		"count":      len(templates),
+		"request_id": c.GetString("request_id"),
+		"timestamp":  time.Now(),
+	}
+	if index := c.Query("index"); index != "" {
+		response["match"] = services.ResolveTemplate(templates, index)
+	}
+
+	c.JSON(http.StatusOK, response)
+}
@@ -0,0 +1,105 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gorilla/websocket"
+	"go.uber.org/zap"
+)
+
+// healthWriteTimeout bounds each write to a health stream client
+const healthWriteTimeout = 10 * time.Second
+
+var upgrader = websocket.Upgrader{
+	CheckOrigin: func(r *http.Request) bool {
+		return true // Allow all origins in development
+	},
+}
+
+// MonitorHealthWS handles GET /api/v1/cluster/monitor/health/ws
+func (h *ClusterHandler) MonitorHealthWS(c *gin.Context) {
+	interval, ok := monitorInterval(c)
+	if !ok {
+		return
+	}
+
+	// The request context is not cancelled when a hijacked connection drops; the read
+	// loop below cancels it instead
+	ctx, cancel := context.WithCancel(c.Request.Context())
+	defer cancel()
+
+	healthCh, err := h.clusterService.MonitorClusterHealth(ctx, interval)
+	if err != nil {
+		h.logger.Error("Failed to start health monitoring", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":      "Failed to start health monitoring",
+			"message":    err.Error(),
+			"request_id": c.GetString("request_id"),
+			"timestamp":  time.Now(),
+		})
+		return
+	}
+
+	conn, err := upgrader.Upgrade(c.Writer, c.Request, nil)
+	if err != nil {
+		h.logger.Error("Failed to upgrade WebSocket connection", zap.Error(err))
+		return
+	}
+	defer conn.Close()
+
+	// Read messages from client (for ping/pong and close) until it goes away
+	go func() {
+		defer cancel()
+		for {
+			if _, _, err := conn.ReadMessage(); err != nil {
+				if websocket.IsUnexpectedCloseError(err, websocket.CloseGoingAway, websocket.CloseNormalClosure) {
+					h.logger.Warn("Health stream client error", zap.Error(err))
+				}
+				return
+			}
+		}
+	}()
+
+	requestID := c.GetString("request_id")
+	for health := range healthCh {
+		conn.SetWriteDeadline(time.Now().Add(healthWriteTimeout))
+		if err := conn.WriteJSON(gin.H{
+			"health":     health,
+			"request_id": requestID,
+			"timestamp":  time.Now(),
+		}); err != nil {
+			h.logger.Debug("Failed to write health update", zap.Error(err))
+			return
+		}
+	}
+
+	// The channel closes once ctx is done; say goodbye in case the server ended the stream
+	conn.SetWriteDeadline(time.Now().Add(healthWriteTimeout))
+	conn.WriteMessage(websocket.CloseMessage,
+		websocket.FormatCloseMessage(websocket.CloseNormalClosure, "health monitoring stopped"))
+}
+
+// monitorInterval parses the interval query parameter of the health monitors, replying with
+// a 400 and reporting false when it is malformed
+func monitorInterval(c *gin.Context) (time.Duration, bool) {
+	intervalStr := c.DefaultQuery("interval", "5s")
+	interval, err := time.ParseDuration(intervalStr)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":      "Invalid interval format",
+			"message":    "Use format like '5s', '1m', '10s'",
+			"request_id": c.GetString("request_id"),
+			"timestamp":  time.Now(),
+		})
+		return 0, false
+	}
+
+	// Set minimum interval to prevent too frequent requests
+	if interval < time.Second {
+		interval = time.Second
+	}
+	return interval, true
+}
@@ -0,0 +1,110 @@
+package handlers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gorilla/websocket"
+	"go.uber.org/zap"
+
+	"github.com/saif-islam/es-playground/projects/cluster-explorer/internal/services"
+	"github.com/saif-islam/es-playground/shared"
+)
+
+// newHealthStreamServer serves the health stream backed by a fake Elasticsearch reporting a
+// yellow cluster. It returns the server, a count of health requests and a channel closed when
+// the handler returns.
+func newHealthStreamServer(t *testing.T) (*httptest.Server, *atomic.Int32, <-chan struct{}) {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+
+	var healthRequests atomic.Int32
+	es := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("X-Elastic-Product", "Elasticsearch")
+		w.Header().Set("Content-Type", "application/json")
+		if r.URL.Path == "/_cluster/health" {
+			healthRequests.Add(1)
+			w.Write([]byte(`{"cluster_name":"playground","status":"yellow","timed_out":false,"number_of_nodes":1,"number_of_data_nodes":1,"active_primary_shards":4,"active_shards":4,"unassigned_shards":4}`))
+			return
+		}
+		w.Write([]byte(`{"version":{"number":"8.11.1"}}`))
+	}))
+	t.Cleanup(es.Close)
+
+	client, err := shared.NewESClient(&shared.ESConfig{URLs: []string{es.URL}}, zap.NewNop())
+	if err != nil {
+		t.Fatalf("Failed to create client: %v", err)
+	}
+	handler := NewClusterHandler(services.NewClusterService(client, zap.NewNop()), zap.NewNop())
+
+	done := make(chan struct{})
+	router := gin.New()
+	router.GET("/api/v1/cluster/monitor/health/ws", func(c *gin.Context) {
+		defer close(done)
+		handler.MonitorHealthWS(c)
+	})
+
+	server := httptest.NewServer(router)
+	t.Cleanup(server.Close)
+	return server, &healthRequests, done
+}
+
+func TestMonitorHealthWS(t *testing.T) {
+	server, healthRequests, done := newHealthStreamServer(t)
+
+	url := "ws" + strings.TrimPrefix(server.URL, "http") + "/api/v1/cluster/monitor/health/ws?interval=1s"
+	conn, _, err := websocket.DefaultDialer.Dial(url, nil)
+	if err != nil {
+		t.Fatalf("Failed to connect: %v", err)
+	}
+	defer conn.Close()
+
+	var update struct {
+		Health struct {
+			ClusterName string `json:"cluster_name"`
+			Status      string `json:"status"`
+		} `json:"health"`
+	}
+	conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+	if err := conn.ReadJSON(&update); err != nil {
+		t.Fatalf("Expected a health update, got %v", err)
+	}
+	if update.Health.ClusterName != "playground" || update.Health.Status != "yellow" {
+		t.Errorf("Expected the yellow playground cluster, got %+v", update.Health)
+	}
+
+	// Closing the connection stops polling Elasticsearch
+	conn.Close()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("Expected the handler to return once the client went away")
+	}
+	polled := healthRequests.Load()
+	time.Sleep(1500 * time.Millisecond)
+	if healthRequests.Load() != polled {
+		t.Errorf("Expected no health requests after the client left, got %d more", healthRequests.Load()-polled)
+	}
+}
+
+func TestMonitorHealthWS_InvalidInterval(t *testing.T) {
+	server, healthRequests, _ := newHealthStreamServer(t)
+
+	res, err := http.Get(server.URL + "/api/v1/cluster/monitor/health/ws?interval=often")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	res.Body.Close()
+
+	if res.StatusCode != http.StatusBadRequest {
+		t.Errorf("Expected status %d, got %d", http.StatusBadRequest, res.StatusCode)
+	}
+	if healthRequests.Load() != 0 {
+		t.Errorf("Expected no health requests for an invalid interval, got %d", healthRequests.Load())
+	}
+}
@@ -0,0 +1,245 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+
+	"github.com/saif-islam/es-playground/projects/cluster-explorer/internal/models"
+)
+
+// RegisterSnapshotRepository handles PUT /api/v1/cluster/snapshots/:repo
+func (h *ClusterHandler) RegisterSnapshotRepository(c *gin.Context) {
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 30*time.Second)
+	defer cancel()
+
+	repository := c.Param("repo")
+
+	var req models.SnapshotRepositoryRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":      "Invalid request body",
+			"message":    err.Error(),
+			"request_id": c.GetString("request_id"),
+			"timestamp":  time.Now(),
+		})
+		return
+	}
+
+	if err := h.clusterService.RegisterSnapshotRepository(ctx, repository, &req); err != nil {
+		h.logger.Error("Failed to register snapshot repository",
+			zap.String("repository", repository),
+			zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":      "Failed to register snapshot repository",
+			"message":    err.Error(),
+			"request_id": c.GetString("request_id"),
+			"timestamp":  time.Now(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success":    true,
+		"message":    "Snapshot repository registered successfully",
+		"repository": repository,
+		"type":       req.Type,
+		"request_id": c.GetString("request_id"),
+		"timestamp":  time.Now(),
+	})
+}
+
+// ListSnapshots handles GET /api/v1/cluster/snapshots/:repo
+func (h *ClusterHandler) ListSnapshots(c *gin.Context) {
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 15*time.Second)
+	defer cancel()
+
+	repository := c.Param("repo")
+
+	snapshots, err := h.clusterService.ListSnapshots(ctx, repository)
+	if err != nil {
+		h.logger.Error("Failed to list snapshots",
+			zap.String("repository", repository),
+			zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":      "Failed to list snapshots",
+			"message":    err.Error(),
+			"request_id": c.GetString("request_id"),
+			"timestamp":  time.Now(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"repository": repository,
+		"snapshots":  snapshots,
+		"count":      len(snapshots),
+		"request_id": c.GetString("request_id"),
+		"timestamp":  time.Now(),
+	})
+}
+
+// CreateSnapshot handles PUT /api/v1/cluster/snapshots/:repo/:snapshot
+func (h *ClusterHandler) CreateSnapshot(c *gin.Context) {
+	repository := c.Param("repo")
+	snapshot := c.Param("snapshot")
+
+	// The body is optional; without one every index is snapshotted
+	var req models.SnapshotRequest
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error":      "Invalid request body",
+				"message":    err.Error(),
+				"request_id": c.GetString("request_id"),
+				"timestamp":  time.Now(),
+			})
+			return
+		}
+	}
+
+	// Blocking calls are cut off at the server's write_timeout; large snapshots should
+	// return at once and be polled instead
+	wait := c.Query("wait_for_completion") != "false"
+	timeout := 30 * time.Second
+	if wait {
+		timeout = 300 * time.Second // 5 minutes for snapshots
+	}
+	ctx, cancel := context.WithTimeout(c.Request.Context(), timeout)
+	defer cancel()
+
+	info, err := h.clusterService.CreateSnapshot(ctx, repository, snapshot, &req, wait)
+	if err != nil {
+		h.logger.Error("Failed to create snapshot",
+			zap.String("repository", repository),
+			zap.String("snapshot", snapshot),
+			zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":      "Failed to create snapshot",
+			"message":    err.Error(),
+			"request_id": c.GetString("request_id"),
+			"timestamp":  time.Now(),
+		})
+		return
+	}
+
+	if !wait {
+		c.JSON(http.StatusAccepted, gin.H{
+			"snapshot":   info,
+			"status_url": fmt.Sprintf("/api/v1/cluster/snapshots/%s/%s", repository, snapshot),
+			"request_id": c.GetString("request_id"),
+			"timestamp":  time.Now(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"snapshot":   info,
+		"request_id": c.GetString("request_id"),
+		"timestamp":  time.Now(),
+	})
+}
+
+// GetSnapshot handles GET /api/v1/cluster/snapshots/:repo/:snapshot
+func (h *ClusterHandler) GetSnapshot(c *gin.Context) {
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 15*time.Second)
+	defer cancel()
+
+	repository := c.Param("repo")
+	snapshot := c.Param("snapshot")
+
+	info, found, err := h.clusterService.GetSnapshot(ctx, repository, snapshot)
+	if err != nil {
+		h.logger.Error("Failed to get snapshot",
+			zap.String("repository", repository),
+			zap.String("snapshot", snapshot),
+			zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":      "Failed to retrieve snapshot",
+			"message":    err.Error(),
+			"request_id": c.GetString("request_id"),
+			"timestamp":  time.Now(),
+		})
+		return
+	}
+
+	if !found {
+		c.JSON(http.StatusNotFound, gin.H{
+			"error":      "Snapshot not found",
+			"message":    fmt.Sprintf("Snapshot %s does not exist in repository %s", snapshot, repository),
+			"request_id": c.GetString("request_id"),
+			"timestamp":  time.Now(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"snapshot":   info,
+		"request_id": c.GetString("request_id"),
+		"timestamp":  time.Now(),
+	})
+}
+
+// RestoreSnapshot handles POST /api/v1/cluster/snapshots/:repo/:snapshot/_restore
+func (h *ClusterHandler) RestoreSnapshot(c *gin.Context) {
+	repository := c.Param("repo")
+	snapshot := c.Param("snapshot")
+
+	// The body is optional; without one every index in the snapshot is restored
+	var req models.RestoreRequest
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error":      "Invalid request body",
+				"message":    err.Error(),
+				"request_id": c.GetString("request_id"),
+				"timestamp":  time.Now(),
+			})
+			return
+		}
+	}
+
+	wait := c.Query("wait_for_completion") != "false"
+	timeout := 30 * time.Second
+	if wait {
+		timeout = 300 * time.Second // 5 minutes for restores
+	}
+	ctx, cancel := context.WithTimeout(c.Request.Context(), timeout)
+	defer cancel()
+
+	result, err := h.clusterService.RestoreSnapshot(ctx, repository, snapshot, &req, wait)
+	if err != nil {
+		h.logger.Error("Failed to restore snapshot",
+			zap.String("repository", repository),
+			zap.String("snapshot", snapshot),
+			zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":      "Failed to restore snapshot",
+			"message":    err.Error(),
+			"request_id": c.GetString("request_id"),
+			"timestamp":  time.Now(),
+		})
+		return
+	}
+
+	// Restored shards recover like any other; the recovery endpoint follows their progress
+	if !wait {
+		c.JSON(http.StatusAccepted, gin.H{
+			"restore":    result,
+			"status_url": "/api/v1/cluster/recovery",
+			"request_id": c.GetString("request_id"),
+			"timestamp":  time.Now(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"restore":    result,
+		"request_id": c.GetString("request_id"),
+		"timestamp":  time.Now(),
+	})
+}
@@ -0,0 +1,111 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+
+	"github.com/saif-islam/es-playground/projects/cluster-explorer/internal/services"
+)
+
+// taskIDPattern matches Elasticsearch task IDs, node_id:task_number
+var taskIDPattern = regexp.MustCompile(`^[\w-]+:\d+$`)
+
+// ListTasks handles GET /api/v1/cluster/tasks
+func (h *ClusterHandler) ListTasks(c *gin.Context) {
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 15*time.Second)
+	defer cancel()
+
+	opts := services.TaskListOptions{
+		Actions:      splitQueryList(c.Query("actions")),
+		Detailed:     c.Query("detailed") == "true",
+		Nodes:        splitQueryList(c.Query("nodes")),
+		ParentTaskID: c.Query("parent_task_id"),
+	}
+
+	tasks, err := h.clusterService.ListTasks(ctx, opts)
+	if err != nil {
+		h.logger.Error("Failed to list tasks", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":      "Failed to list tasks",
+			"message":    err.Error(),
+			"request_id": c.GetString("request_id"),
+			"timestamp":  time.Now(),
+		})
+		return
+	}
+
+	tasks.RequestID = c.GetString("request_id")
+	c.JSON(http.StatusOK, tasks)
+}
+
+// CancelTask handles POST /api/v1/cluster/tasks/:id/_cancel
+func (h *ClusterHandler) CancelTask(c *gin.Context) {
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 15*time.Second)
+	defer cancel()
+
+	taskID := c.Param("id")
+	if !taskIDPattern.MatchString(taskID) {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":      "Invalid task ID",
+			"message":    "Task IDs look like node_id:task_number, as listed by GET /api/v1/cluster/tasks",
+			"request_id": c.GetString("request_id"),
+			"timestamp":  time.Now(),
+		})
+		return
+	}
+
+	cancelled, found, err := h.clusterService.CancelTask(ctx, taskID)
+	if err != nil {
+		h.logger.Error("Failed to cancel task",
+			zap.String("task_id", taskID),
+			zap.Error(err))
+
+		status := http.StatusInternalServerError
+		if errors.Is(err, services.ErrTaskNotCancellable) {
+			status = http.StatusConflict
+		}
+		c.JSON(status, gin.H{
+			"error":      "Failed to cancel task",
+			"message":    err.Error(),
+			"request_id": c.GetString("request_id"),
+			"timestamp":  time.Now(),
+		})
+		return
+	}
+
+	if !found {
+		c.JSON(http.StatusNotFound, gin.H{
+			"error":      "Task not found",
+			"message":    "No running task " + taskID + "; it may already have finished",
+			"request_id": c.GetString("request_id"),
+			"timestamp":  time.Now(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success":    true,
+		"task_id":    taskID,
+		"cancelled":  cancelled,
+		"request_id": c.GetString("request_id"),
+		"timestamp":  time.Now(),
+	})
+}
+
+// splitQueryList splits a comma-separated query parameter, dropping empty entries
+func splitQueryList(value string) []string {
+	var items []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
@@ -749,4 +749,239 @@ type IndexingMetrics struct {
 	NoopUpdateTotal    int64         `json:"noop_update_total"`
 	IsThrottled        bool          `json:"is_throttled"`
 	ThrottleTime       time.Duration `json:"throttle_time_in_millis"`
-}
\ No newline at end of file
+}
+
+// RecoveryStatus represents shard recovery progress across the cluster or one index
+type RecoveryStatus struct {
+	Index                  string          `json:"index,omitempty"`
+	ActiveShards           int             `json:"active_shards"`
+	CompletedShards        int             `json:"completed_shards"`
+	BytesTotal             int64           `json:"bytes_total"`
+	BytesRecovered         int64           `json:"bytes_recovered"`
+	BytesPercent           float64         `json:"bytes_percent"`
+	EstimatedTimeRemaining time.Duration   `json:"estimated_time_remaining"` // slowest active shard
+	Done                   bool            `json:"done"`
+	Shards                 []ShardRecovery `json:"shards"`
+	RequestID              string          `json:"request_id"`
+	Timestamp              time.Time       `json:"timestamp"`
+}
+
+// ShardRecovery represents the recovery progress of a single shard copy
+type ShardRecovery struct {
+	Index                  string        `json:"index"`
+	Shard                  int           `json:"shard"`
+	Primary                bool          `json:"primary"`
+	Type                   string        `json:"type"`  // EMPTY_STORE, EXISTING_STORE, PEER, SNAPSHOT, LOCAL_SHARDS
+	Stage                  string        `json:"stage"` // INIT, INDEX, VERIFY_INDEX, TRANSLOG, FINALIZE, DONE
+	SourceNode             string        `json:"source_node,omitempty"`
+	TargetNode             string        `json:"target_node"`
+	FilesPercent           float64       `json:"files_percent"`
+	BytesPercent           float64       `json:"bytes_percent"`
+	TranslogPercent        float64       `json:"translog_percent"`
+	BytesTotal             int64         `json:"bytes_total"`
+	BytesRecovered         int64         `json:"bytes_recovered"`
+	Elapsed                time.Duration `json:"elapsed"`
+	EstimatedTimeRemaining time.Duration `json:"estimated_time_remaining"`
+}
+
+// SnapshotRepositoryRequest represents a request to register a snapshot repository
+type SnapshotRepositoryRequest struct {
+	Type     string                 `json:"type" binding:"required"`     // fs, url, s3, gcs, azure...
+	Settings map[string]interface{} `json:"settings" binding:"required"` // e.g. location for fs, bucket for s3
+	Verify   *bool                  `json:"verify,omitempty"`            // check every node can reach it (default true)
+}
+
+// SnapshotRequest represents a request to create a snapshot
+type SnapshotRequest struct {
+	Indices            []string               `json:"indices,omitempty"` // all indices when empty
+	IgnoreUnavailable  bool                   `json:"ignore_unavailable,omitempty"`
+	IncludeGlobalState *bool                  `json:"include_global_state,omitempty"`
+	Partial            bool                   `json:"partial,omitempty"` // snapshot available shards when some primaries are missing
+	Metadata           map[string]interface{} `json:"metadata,omitempty"`
+}
+
+// RestoreRequest represents a request to restore indices from a snapshot
+type RestoreRequest struct {
+	Indices            []string               `json:"indices,omitempty"` // all indices in the snapshot when empty
+	IgnoreUnavailable  bool                   `json:"ignore_unavailable,omitempty"`
+	IncludeGlobalState bool                   `json:"include_global_state,omitempty"`
+	IncludeAliases     *bool                  `json:"include_aliases,omitempty"`
+	RenamePattern      string                 `json:"rename_pattern,omitempty"`     // restore alongside open indices, e.g. (.+)
+	RenameReplacement  string                 `json:"rename_replacement,omitempty"` // e.g. restored-$1
+	IndexSettings      map[string]interface{} `json:"index_settings,omitempty"`
+}
+
+// SnapshotInfo represents a snapshot and its state: IN_PROGRESS, SUCCESS, PARTIAL or FAILED
+type SnapshotInfo struct {
+	Repository string         `json:"repository"`
+	Snapshot   string         `json:"snapshot"`
+	UUID       string         `json:"uuid,omitempty"`
+	State      string         `json:"state"`
+	Indices    []string       `json:"indices,omitempty"`
+	StartTime  time.Time      `json:"start_time,omitempty"`
+	EndTime    time.Time      `json:"end_time,omitempty"`
+	Duration   time.Duration  `json:"duration"`
+	Shards     SnapshotShards `json:"shards"`
+	Failures   []string       `json:"failures,omitempty"`
+}
+
+// SnapshotShards counts the shards of a snapshot or restore
+type SnapshotShards struct {
+	Total      int `json:"total"`
+	Successful int `json:"successful"`
+	Failed     int `json:"failed"`
+}
+
+// RestoreResult represents the outcome of a restore. When it was not waited for, only
+// Accepted is set; follow the restored shards with the recovery API.
+type RestoreResult struct {
+	Repository string         `json:"repository"`
+	Snapshot   string         `json:"snapshot"`
+	Accepted   bool           `json:"accepted"`
+	Completed  bool           `json:"completed"`
+	Indices    []string       `json:"indices,omitempty"`
+	Shards     SnapshotShards `json:"shards"`
+}
+
+// TaskList represents the tasks running on the cluster, longest-running first
+type TaskList struct {
+	Tasks     []TaskInfo `json:"tasks"`
+	Count     int        `json:"count"`
+	RequestID string     `json:"request_id"`
+	Timestamp time.Time  `json:"timestamp"`
+}
+
+// TaskInfo represents a single running task, such as a reindex, force merge or
+// delete-by-query started with wait_for_completion=false
+type TaskInfo struct {
+	ID           string        `json:"id"` // node_id:task_number
+	Node         string        `json:"node"`
+	Action       string        `json:"action"`
+	Type         string        `json:"type"`
+	Description  string        `json:"description,omitempty"` // only with detailed
+	StartTime    time.Time     `json:"start_time"`
+	RunningTime  time.Duration `json:"running_time"`
+	Cancellable  bool          `json:"cancellable"`
+	Cancelled    bool          `json:"cancelled"`
+	ParentTaskID string        `json:"parent_task_id,omitempty"`
+	// Action-specific status, e.g. the document counts of a reindex; only with detailed
+	Status map[string]interface{} `json:"status,omitempty"`
+	// Share of documents processed, for by-query and reindex tasks that report a total
+	ProgressPercent float64 `json:"progress_percent,omitempty"`
+}
+
+// AliasInfo represents an alias and the indices it points at
+type AliasInfo struct {
+	Name       string       `json:"name"`
+	Indices    []AliasIndex `json:"indices"`
+	WriteIndex string       `json:"write_index,omitempty"` // receives writes sent to the alias
+}
+
+// AliasIndex represents one index behind an alias and how the alias routes to it
+type AliasIndex struct {
+	Index         string `json:"index"`
+	IsWriteIndex  bool   `json:"is_write_index"`
+	Filtered      bool   `json:"filtered"` // the alias only exposes documents matching a filter
+	IndexRouting  string `json:"index_routing,omitempty"`
+	SearchRouting string `json:"search_routing,omitempty"`
+}
+
+// TemplateInfo represents a composable or legacy index template
+type TemplateInfo struct {
+	Name          string   `json:"name"`
+	Type          string   `json:"type"` // composable or legacy
+	IndexPatterns []string `json:"index_patterns"`
+	// Composable templates: the highest priority match wins. Legacy templates: every match
+	// applies, higher order overriding lower.
+	Priority   int64    `json:"priority"`
+	ComposedOf []string `json:"composed_of,omitempty"`
+	DataStream bool     `json:"data_stream,omitempty"`
+	Version    *int64   `json:"version,omitempty"`
+}
+
+// TemplateMatch reports which templates a new index with a given name would be created from
+type TemplateMatch struct {
+	Index    string `json:"index"`
+	Template string `json:"template,omitempty"` // the winning template; empty when none matches
+	Type     string `json:"type,omitempty"`
+	// Matching templates of the winning type, highest precedence first
+	Matching []string `json:"matching"`
+	// Legacy templates that match but are ignored because a composable template matches
+	Shadowed []string `json:"shadowed,omitempty"`
+}
+
+// DiskAlerts reports nodes whose disk usage has crossed one of the cluster's watermarks
+type DiskAlerts struct {
+	Status           string          `json:"status"` // ok, warning or critical
+	ThresholdEnabled bool            `json:"threshold_enabled"`
+	Watermarks       DiskWatermarks  `json:"watermarks"`
+	NodesChecked     int             `json:"nodes_checked"`
+	Alerts           []NodeDiskAlert `json:"alerts"`
+}
+
+// DiskWatermarks holds the effective disk watermark settings, as percentages, ratios or
+// byte sizes of free space
+type DiskWatermarks struct {
+	Low                   string `json:"low"`
+	LowMaxHeadroom        string `json:"low_max_headroom,omitempty"`
+	High                  string `json:"high"`
+	HighMaxHeadroom       string `json:"high_max_headroom,omitempty"`
+	FloodStage            string `json:"flood_stage"`
+	FloodStageMaxHeadroom string `json:"flood_stage_max_headroom,omitempty"`
+}
+
+// NodeDiskAlert represents a node over a disk watermark
+type NodeDiskAlert struct {
+	NodeID         string  `json:"node_id"`
+	NodeName       string  `json:"node_name"`
+	Host           string  `json:"host"`
+	Watermark      string  `json:"watermark"` // the highest watermark crossed: low, high or flood_stage
+	Severity       string  `json:"severity"`  // warning or critical
+	TotalBytes     int64   `json:"total_bytes"`
+	AvailableBytes int64   `json:"available_bytes"`
+	UsedPercent    float64 `json:"used_percent"`
+	// Free space the crossed watermark requires
+	RequiredFreeBytes int64  `json:"required_free_bytes"`
+	Message           string `json:"message"`
+}
+
+// ThreadPoolReport represents per-node thread pool usage and rejections
+type ThreadPoolReport struct {
+	Nodes []NodeThreadPools `json:"nodes"`
+	// Pools whose rejections increased since the previous sample, as node/pool
+	Rejecting []string  `json:"rejecting"`
+	SampledAt time.Time `json:"sampled_at"`
+	// When the previous sample was taken; unset on the first request, when no increase
+	// can be reported yet
+	PreviousSample *time.Time `json:"previous_sample,omitempty"`
+}
+
+// NodeThreadPools represents the thread pools of one node
+type NodeThreadPools struct {
+	NodeID   string            `json:"node_id"`
+	NodeName string            `json:"node_name"`
+	Pools    []ThreadPoolUsage `json:"pools"`
+}
+
+// ThreadPoolUsage represents one thread pool on a node
+type ThreadPoolUsage struct {
+	Name     string `json:"name"`
+	Threads  int    `json:"threads"`
+	Active   int    `json:"active"`
+	Queue    int    `json:"queue"`
+	Rejected int64  `json:"rejected"` // since the node started
+	// Rejections since the previous sample
+	NewRejections       int64 `json:"new_rejections"`
+	RejectionsIncreased bool  `json:"rejections_increased"`
+}
+
+// SettingChange describes what a cluster setting update would do to one setting
+type SettingChange struct {
+	Key     string      `json:"key"`
+	Current interface{} `json:"current"`          // effective value now, including defaults
+	Source  string      `json:"source,omitempty"` // where Current comes from: transient, persistent or defaults
+	New     interface{} `json:"new"`              // null resets the setting
+	Changed bool        `json:"changed"`
+	// A persistent update has no effect while the same transient setting is set
+	OverriddenByTransient bool `json:"overridden_by_transient,omitempty"`
+}
@@ -0,0 +1,168 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"go.uber.org/zap"
+
+	"github.com/saif-islam/es-playground/projects/cluster-explorer/internal/models"
+	"github.com/saif-islam/es-playground/shared"
+)
+
+// maxAllocationExplanations caps the allocation explain calls made for one shard allocation
+// request; a cluster with many unassigned shards usually has only a few distinct causes
+const maxAllocationExplanations = 20
+
+// catShardColumns are the _cat/shards columns requested, including why unassigned shards
+// are unassigned
+var catShardColumns = []string{
+	"index", "shard", "prirep", "state", "docs", "store", "node",
+	"unassigned.reason", "unassigned.at", "unassigned.details",
+}
+
+// catShard is a row of the _cat/shards API; cat APIs report every value as a string
+type catShard struct {
+	Index             string `json:"index"`
+	Shard             string `json:"shard"`
+	Prirep            string `json:"prirep"`
+	State             string `json:"state"`
+	Docs              string `json:"docs"`
+	Store             string `json:"store"`
+	Node              string `json:"node"`
+	UnassignedReason  string `json:"unassigned.reason"`
+	UnassignedAt      string `json:"unassigned.at"`
+	UnassignedDetails string `json:"unassigned.details"`
+}
+
+// shardDetails converts a cat row to the shard details model
+func (c catShard) shardDetails() models.ShardDetails {
+	shard, _ := strconv.Atoi(c.Shard)
+	docs, _ := strconv.ParseInt(c.Docs, 10, 64)
+	return models.ShardDetails{
+		Index:   c.Index,
+		Shard:   shard,
+		Primary: c.Prirep == "p",
+		State:   c.State,
+		Node:    c.Node,
+		Docs:    docs,
+		Store:   c.Store,
+	}
+}
+
+// allocationExplainResponse is the part of the allocation explain API response used to
+// describe why a shard is unassigned
+type allocationExplainResponse struct {
+	UnassignedInfo struct {
+		Reason  string `json:"reason"`
+		At      string `json:"at"`
+		Details string `json:"details"`
+	} `json:"unassigned_info"`
+	CanAllocate             string `json:"can_allocate"`
+	AllocateExplanation     string `json:"allocate_explanation"`
+	NodeAllocationDecisions []struct {
+		NodeName     string `json:"node_name"`
+		NodeDecision string `json:"node_decision"`
+		Deciders     []struct {
+			Decider     string `json:"decider"`
+			Decision    string `json:"decision"`
+			Explanation string `json:"explanation"`
+		} `json:"deciders"`
+	} `json:"node_allocation_decisions"`
+}
+
+// explainUnassignedShards fills in why each unassigned shard cannot be allocated, up to
+// maxAllocationExplanations of them. A failed explanation leaves the shard as the cat API
+// reported it.
+func (s *ClusterService) explainUnassignedShards(ctx context.Context, unassigned []models.UnassignedShardDetails) {
+	for i := range unassigned {
+		if i == maxAllocationExplanations {
+			s.logger.Info("Skipped allocation explanations beyond the limit",
+				zap.Int("unassigned_shards", len(unassigned)),
+				zap.Int("explained", maxAllocationExplanations))
+			return
+		}
+
+		explanation, err := s.explainAllocation(ctx, unassigned[i].Index, unassigned[i].Shard, unassigned[i].Primary)
+		if err != nil {
+			s.logger.Warn("Failed to explain shard allocation",
+				zap.String("index", unassigned[i].Index),
+				zap.Int("shard", unassigned[i].Shard),
+				zap.Bool("primary", unassigned[i].Primary),
+				zap.Error(err))
+			continue
+		}
+		applyAllocationExplanation(&unassigned[i], explanation)
+	}
+}
+
+// explainAllocation asks the cluster why a shard copy is where it is, or why it is nowhere
+func (s *ClusterService) explainAllocation(ctx context.Context, index string, shard int, primary bool) (*allocationExplainResponse, error) {
+	bodyBytes, err := json.Marshal(map[string]interface{}{
+		"index":   index,
+		"shard":   shard,
+		"primary": primary,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal allocation explain request: %w", err)
+	}
+
+	res, err := s.esClient.Cluster.AllocationExplain(
+		s.esClient.Cluster.AllocationExplain.WithContext(ctx),
+		s.esClient.Cluster.AllocationExplain.WithBody(strings.NewReader(string(bodyBytes))),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("allocation explain request failed: %w", err)
+	}
+	defer res.Body.Close()
+
+	if res.IsError() {
+		return nil, shared.ParseESError(res)
+	}
+
+	var explanation allocationExplainResponse
+	if err := shared.DecodeJSONResponse(res, &explanation); err != nil {
+		return nil, fmt.Errorf("failed to decode allocation explanation: %w", err)
+	}
+	return &explanation, nil
+}
+
+// applyAllocationExplanation copies the unassigned reason, the overall explanation and each
+// node's blocking deciders onto the shard details
+func applyAllocationExplanation(details *models.UnassignedShardDetails, explanation *allocationExplainResponse) {
+	if explanation.UnassignedInfo.Reason != "" {
+		details.Reason = explanation.UnassignedInfo.Reason
+	}
+	if explanation.UnassignedInfo.At != "" {
+		details.Since = explanation.UnassignedInfo.At
+	}
+
+	details.Details = explanation.AllocateExplanation
+	if explanation.UnassignedInfo.Details != "" {
+		if details.Details != "" {
+			details.Details += " "
+		}
+		details.Details += "(" + explanation.UnassignedInfo.Details + ")"
+	}
+
+	details.NodeDecisions = nil
+	for _, node := range explanation.NodeAllocationDecisions {
+		// Only the deciders that said no or throttle explain the node's decision
+		var reasons []string
+		for _, decider := range node.Deciders {
+			if decider.Decision == "YES" {
+				continue
+			}
+			reasons = append(reasons, fmt.Sprintf("[%s] %s", decider.Decider, decider.Explanation))
+		}
+
+		details.NodeDecisions = append(details.NodeDecisions, models.NodeDecision{
+			NodeName: node.NodeName,
+			Decision: node.NodeDecision,
+			Reason:   strings.Join(reasons, "; "),
+		})
+	}
+}
@@ -0,0 +1,144 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+// catShardsAPIResponse follows GET /_cat/shards?format=json with the requested columns: one
+// started primary and two unassigned copies, one of them a replica stuck after a node left
+const catShardsAPIResponse = `[
+  {"index": "logs-000001", "shard": "0", "prirep": "p", "state": "STARTED", "docs": "1200", "store": "1.2mb", "node": "es-node-1", "unassigned.reason": null, "unassigned.at": null, "unassigned.details": null},
+  {"index": "logs-000001", "shard": "0", "prirep": "r", "state": "UNASSIGNED", "docs": null, "store": null, "node": null, "unassigned.reason": "NODE_LEFT", "unassigned.at": "2023-11-14T22:13:20.000Z", "unassigned.details": "node_left [Qm8pR2]"},
+  {"index": "metrics-000001", "shard": "1", "prirep": "p", "state": "UNASSIGNED", "docs": null, "store": null, "node": null, "unassigned.reason": "INDEX_CREATED", "unassigned.at": "2023-11-14T22:20:00.000Z", "unassigned.details": null}
+]`
+
+// allocationExplainAPIResponse follows POST /_cluster/allocation/explain for the replica: the
+// only other node already holds the primary
+const allocationExplainAPIResponse = `{
+  "index": "logs-000001",
+  "shard": 0,
+  "primary": false,
+  "current_state": "unassigned",
+  "unassigned_info": {"reason": "NODE_LEFT", "at": "2023-11-14T22:13:20.000Z", "details": "node_left [Qm8pR2]", "last_allocation_status": "no_attempt"},
+  "can_allocate": "no",
+  "allocate_explanation": "Elasticsearch isn't allowed to allocate this shard to any of the nodes in the cluster.",
+  "node_allocation_decisions": [
+    {
+      "node_id": "Yx3kT1",
+      "node_name": "es-node-1",
+      "transport_address": "10.0.0.1:9300",
+      "node_decision": "no",
+      "weight_ranking": 1,
+      "deciders": [
+        {"decider": "same_shard", "decision": "NO", "explanation": "a copy of this shard is already allocated to this node [[logs-000001][0], node[Yx3kT1], [P], s[STARTED], a[id=abc]]"},
+        {"decider": "disk_threshold", "decision": "YES", "explanation": "enough disk for shard on node"}
+      ]
+    }
+  ]
+}`
+
+func TestGetShardAllocation(t *testing.T) {
+	var columns string
+	var explained []string
+	service := newTestClusterService(t, func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/_cat/shards":
+			columns = r.URL.Query().Get("h")
+			w.Write([]byte(catShardsAPIResponse))
+		case "/_cluster/allocation/explain":
+			var body struct {
+				Index   string `json:"index"`
+				Shard   int    `json:"shard"`
+				Primary bool   `json:"primary"`
+			}
+			json.NewDecoder(r.Body).Decode(&body)
+			explained = append(explained, fmt.Sprintf("%s/%d/%v", body.Index, body.Shard, body.Primary))
+			if body.Index != "logs-000001" {
+				w.WriteHeader(http.StatusInternalServerError)
+				w.Write([]byte(`{"error":{"type":"illegal_state_exception","reason":"explain failed"},"status":500}`))
+				return
+			}
+			w.Write([]byte(allocationExplainAPIResponse))
+		default:
+			w.Write([]byte(`{"version":{"number":"8.11.1"}}`))
+		}
+	})
+
+	allocation, err := service.GetShardAllocation(context.Background())
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if columns != strings.Join(catShardColumns, ",") {
+		t.Errorf("Expected the unassigned columns to be requested, got h=%s", columns)
+	}
+	if strings.Join(explained, " ") != "logs-000001/0/false metrics-000001/1/true" {
+		t.Errorf("Expected one explanation per unassigned copy, got %v", explained)
+	}
+
+	summary := allocation.Summary
+	if summary.TotalShards != 3 || summary.AssignedShards != 1 || summary.UnassignedShards != 2 {
+		t.Errorf("Expected 1 assigned and 2 unassigned of 3 shards, got %+v", summary)
+	}
+	if started := allocation.Indices["logs-000001"].Shards["0"]; len(started) != 1 || started[0].Docs != 1200 || !started[0].Primary {
+		t.Errorf("Expected the started primary with its doc count, got %+v", started)
+	}
+
+	if len(allocation.Unassigned) != 2 {
+		t.Fatalf("Expected 2 unassigned shards, got %d", len(allocation.Unassigned))
+	}
+
+	replica := allocation.Unassigned[0]
+	if replica.Reason != "NODE_LEFT" || replica.Since != "2023-11-14T22:13:20.000Z" {
+		t.Errorf("Expected the replica unassigned by NODE_LEFT, got %+v", replica)
+	}
+	expectedDetails := "Elasticsearch isn't allowed to allocate this shard to any of the nodes in the cluster. (node_left [Qm8pR2])"
+	if replica.Details != expectedDetails {
+		t.Errorf("Expected details %q, got %q", expectedDetails, replica.Details)
+	}
+	if len(replica.NodeDecisions) != 1 || replica.NodeDecisions[0].NodeName != "es-node-1" || replica.NodeDecisions[0].Decision != "no" ||
+		!strings.HasPrefix(replica.NodeDecisions[0].Reason, "[same_shard] a copy of this shard") || strings.Contains(replica.NodeDecisions[0].Reason, "disk_threshold") {
+		t.Errorf("Expected es-node-1 refusing through same_shard only, got %+v", replica.NodeDecisions)
+	}
+
+	// A failed explanation keeps what the cat API reported
+	primary := allocation.Unassigned[1]
+	if primary.Reason != "INDEX_CREATED" || primary.Details != "" || len(primary.NodeDecisions) != 0 {
+		t.Errorf("Expected the cat API reason for the unexplained primary, got %+v", primary)
+	}
+}
+
+func TestGetShardAllocation_ExplanationLimit(t *testing.T) {
+	explainCalls := 0
+	service := newTestClusterService(t, func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/_cat/shards":
+			rows := make([]string, maxAllocationExplanations+5)
+			for i := range rows {
+				rows[i] = fmt.Sprintf(`{"index":"logs","shard":"%d","prirep":"r","state":"UNASSIGNED","unassigned.reason":"NODE_LEFT"}`, i)
+			}
+			w.Write([]byte("[" + strings.Join(rows, ",") + "]"))
+		case "/_cluster/allocation/explain":
+			explainCalls++
+			w.Write([]byte(`{"unassigned_info":{"reason":"NODE_LEFT"},"can_allocate":"no","allocate_explanation":"no node"}`))
+		default:
+			w.Write([]byte(`{"version":{"number":"8.11.1"}}`))
+		}
+	})
+
+	allocation, err := service.GetShardAllocation(context.Background())
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if explainCalls != maxAllocationExplanations {
+		t.Errorf("Expected %d explanations, got %d", maxAllocationExplanations, explainCalls)
+	}
+	if len(allocation.Unassigned) != maxAllocationExplanations+5 || allocation.Unassigned[maxAllocationExplanations].Details != "" {
+		t.Errorf("Expected every unassigned shard listed and the ones past the limit unexplained, got %d", len(allocation.Unassigned))
+	}
+}
@@ -5,10 +5,11 @@ import (
 	"encoding/json"
 	"fmt"
 	"io"
-	"net/http"
 	"strings"
+	"sync"
 	"time"
 
+	"github.com/elastic/go-elasticsearch/v8/esapi"
 	"go.uber.org/zap"
 
 	"github.com/saif-islam/es-playground/shared"
@@ -19,6 +20,10 @@ import (
 type ClusterService struct {
 	esClient *shared.ESClient
 	logger   *zap.Logger
+
+	// Rejection counters from the previous thread pool sample
+	threadPoolMu    sync.Mutex
+	lastThreadPools *threadPoolSample
 }
 
 // NewClusterService creates a new cluster service instance
@@ -361,7 +366,7 @@ func (s *ClusterService) GetShardAllocation(ctx context.Context) (*models.ShardA
 	res, err := s.esClient.Cat.Shards(
 		s.esClient.Cat.Shards.WithContext(ctx),
 		s.esClient.Cat.Shards.WithFormat("json"),
-		s.esClient.Cat.Shards.WithV(true),
+		s.esClient.Cat.Shards.WithH(catShardColumns...),
 	)
 	if err != nil {
 		return nil, fmt.Errorf("shard allocation request failed: %w", err)
@@ -372,8 +377,8 @@ func (s *ClusterService) GetShardAllocation(ctx context.Context) (*models.ShardA
 		return nil, shared.ParseESError(res)
 	}
 
-	var shards []models.ShardDetails
-	if err := shared.DecodeJSONResponse(res, &shards); err != nil {
+	var rows []catShard
+	if err := shared.DecodeJSONResponse(res, &rows); err != nil {
 		return nil, fmt.Errorf("failed to decode shard allocation: %w", err)
 	}
 
@@ -382,7 +387,8 @@ func (s *ClusterService) GetShardAllocation(ctx context.Context) (*models.ShardA
 	var unassigned []models.UnassignedShardDetails
 	summary := models.AllocationSummary{}
 
-	for _, shard := range shards {
+	for _, row := range rows {
+		shard := row.shardDetails()
 		summary.TotalShards++
 		
 		if shard.State == "UNASSIGNED" {
@@ -392,7 +398,9 @@ func (s *ClusterService) GetShardAllocation(ctx context.Context) (*models.ShardA
 				Shard:        shard.Shard,
 				Primary:      shard.Primary,
 				CurrentState: shard.State,
-				Reason:       "Unknown", // This would need additional API call to get exact reason
+				Reason:       row.UnassignedReason,
+				Since:        row.UnassignedAt,
+				Details:      row.UnassignedDetails,
 			})
 		} else {
 			summary.AssignedShards++
@@ -414,6 +422,9 @@ func (s *ClusterService) GetShardAllocation(ctx context.Context) (*models.ShardA
 		}
 	}
 
+	// The cat API only gives a reason code; ask the allocator what is blocking each shard
+	s.explainUnassignedShards(ctx, unassigned)
+
 	allocation := &models.ShardAllocation{
 		Indices:    indices,
 		Unassigned: unassigned,
@@ -433,7 +444,7 @@ func (s *ClusterService) GetPerformanceMetrics(ctx context.Context) (*models.Per
 	// Get node stats for performance metrics
 	res, err := s.esClient.Nodes.Stats(
 		s.esClient.Nodes.Stats.WithContext(ctx),
-		s.esClient.Nodes.Stats.WithMetric("os", "process", "jvm", "fs", "thread_pool", "indices"),
+		s.esClient.Nodes.Stats.WithMetric("os", "process", "jvm", "fs", "transport", "thread_pool", "indices"),
 	)
 	if err != nil {
 		return nil, fmt.Errorf("node stats request failed: %w", err)
@@ -444,38 +455,25 @@ func (s *ClusterService) GetPerformanceMetrics(ctx context.Context) (*models.Per
 		return nil, shared.ParseESError(res)
 	}
 
-	var response struct {
-		Nodes map[string]interface{} `json:"nodes"`
-	}
+	var response nodeStatsResponse
 	if err := shared.DecodeJSONResponse(res, &response); err != nil {
 		return nil, fmt.Errorf("failed to decode node stats: %w", err)
 	}
 
 	// Aggregate performance metrics from all nodes
-	metrics := &models.PerformanceMetrics{
-		CPU:              models.CPUMetrics{},
-		Memory:           models.MemoryMetrics{},
-		Disk:             models.DiskMetrics{},
-		Network:          models.NetworkMetrics{},
-		GarbageCollection: models.GCMetrics{},
-		ThreadPools:      models.ThreadPoolMetrics{},
-		Search:           models.SearchMetrics{},
-		Indexing:         models.IndexingMetrics{},
-	}
+	metrics := aggregateNodeStats(response)
 
-	// This is a simplified aggregation - in a real implementation,
-	// you would properly aggregate metrics from all nodes
-	nodeCount := len(response.Nodes)
-	if nodeCount > 0 {
-		s.logger.Info("Aggregated performance metrics", zap.Int("node_count", nodeCount))
-	}
+	s.logger.Info("Aggregated performance metrics",
+		zap.Int("node_count", len(response.Nodes)),
+		zap.Float64("cpu_percent", metrics.CPU.UsagePercent),
+		zap.Float64("heap_used_percent", metrics.Memory.HeapUsedPercent))
 
 	return metrics, nil
 }
 
 // GetHotThreads retrieves hot threads information for performance analysis
 func (s *ClusterService) GetHotThreads(ctx context.Context, nodeID string) (string, error) {
-	var res *http.Response
+	var res *esapi.Response
 	var err error
 
 	if nodeID != "" {
@@ -538,8 +536,14 @@ func (s *ClusterService) MonitorClusterHealth(ctx context.Context, interval time
 	return healthCh, nil
 }
 
-// UpdateClusterSettings updates cluster settings
+// UpdateClusterSettings updates cluster settings. Settings are checked against an
+// allowlist first and an *InvalidSettingError returned for the first bad one.
 func (s *ClusterService) UpdateClusterSettings(ctx context.Context, settings map[string]interface{}, persistent bool) error {
+	settings, err := ValidateClusterSettings(settings)
+	if err != nil {
+		return err
+	}
+
 	var body map[string]interface{}
 	
 	if persistent {
@@ -558,8 +562,8 @@ func (s *ClusterService) UpdateClusterSettings(ctx context.Context, settings map
 	}
 
 	res, err := s.esClient.Cluster.PutSettings(
-		s.esClient.Cluster.PutSettings.WithContext(ctx),
 		strings.NewReader(string(bodyBytes)),
+		s.esClient.Cluster.PutSettings.WithContext(ctx),
 	)
 	if err != nil {
 		return fmt.Errorf("update cluster settings request failed: %w", err)
@@ -0,0 +1,356 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+
+	"go.uber.org/zap"
+
+	"github.com/saif-islam/es-playground/projects/cluster-explorer/internal/models"
+	"github.com/saif-islam/es-playground/shared"
+)
+
+// Disk watermarks, in increasing order of severity
+const (
+	WatermarkLow        = "low"
+	WatermarkHigh       = "high"
+	WatermarkFloodStage = "flood_stage"
+)
+
+// Disk alert severities
+const (
+	DiskStatusOK       = "ok"
+	DiskStatusWarning  = "warning"
+	DiskStatusCritical = "critical"
+)
+
+const diskSettingsPrefix = "cluster.routing.allocation.disk."
+
+// Watermarks used when the settings don't report them, matching Elasticsearch 8's defaults
+var defaultWatermarks = models.DiskWatermarks{
+	Low:                   "85%",
+	LowMaxHeadroom:        "200gb",
+	High:                  "90%",
+	HighMaxHeadroom:       "150gb",
+	FloodStage:            "95%",
+	FloodStageMaxHeadroom: "100gb",
+}
+
+// diskStatsResponse is the part of the _nodes/stats/fs API response used for disk alerts
+type diskStatsResponse struct {
+	Nodes map[string]struct {
+		Name string `json:"name"`
+		Host string `json:"host"`
+		FS   struct {
+			Total struct {
+				TotalInBytes     int64 `json:"total_in_bytes"`
+				AvailableInBytes int64 `json:"available_in_bytes"`
+			} `json:"total"`
+		} `json:"fs"`
+	} `json:"nodes"`
+}
+
+// diskThreshold is a watermark resolved to the free space it requires on a disk
+type diskThreshold struct {
+	name string
+	// Fraction of the disk that may be used, when the watermark is a percentage or ratio
+	usedRatio float64
+	// Free bytes required, when the watermark is an absolute size
+	freeBytes int64
+	absolute  bool
+	// Cap on the free space a percentage watermark requires on large disks; -1 for none
+	maxHeadroom int64
+}
+
+// GetDiskAlerts compares every node's disk usage with the cluster's low, high and flood
+// stage watermarks and lists the nodes over one. Past the high watermark Elasticsearch
+// moves shards off a node and past flood stage it makes the node's indices read-only, so
+// alerts are logged, at ERROR for flood stage and WARN otherwise. Frozen-tier watermarks
+// are not checked.
+func (s *ClusterService) GetDiskAlerts(ctx context.Context) (*models.DiskAlerts, error) {
+	settings, err := s.GetClusterSettings(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get disk watermarks: %w", err)
+	}
+	watermarks, thresholdEnabled := effectiveWatermarks(settings)
+
+	thresholds, err := resolveWatermarks(watermarks)
+	if err != nil {
+		return nil, err
+	}
+
+	res, err := s.esClient.Nodes.Stats(
+		s.esClient.Nodes.Stats.WithContext(ctx),
+		s.esClient.Nodes.Stats.WithMetric("fs"),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("node stats request failed: %w", err)
+	}
+	defer res.Body.Close()
+
+	if res.IsError() {
+		return nil, shared.ParseESError(res)
+	}
+
+	var response diskStatsResponse
+	if err := shared.DecodeJSONResponse(res, &response); err != nil {
+		return nil, fmt.Errorf("failed to decode node stats: %w", err)
+	}
+
+	result := &models.DiskAlerts{
+		Status:           DiskStatusOK,
+		ThresholdEnabled: thresholdEnabled,
+		Watermarks:       watermarks,
+		NodesChecked:     len(response.Nodes),
+		Alerts:           []models.NodeDiskAlert{},
+	}
+
+	for id, node := range response.Nodes {
+		total, available := node.FS.Total.TotalInBytes, node.FS.Total.AvailableInBytes
+		if total <= 0 {
+			continue
+		}
+
+		crossed, required, ok := highestWatermarkCrossed(thresholds, total, available)
+		if !ok {
+			continue
+		}
+
+		alert := models.NodeDiskAlert{
+			NodeID:            id,
+			NodeName:          node.Name,
+			Host:              node.Host,
+			Watermark:         crossed,
+			Severity:          DiskStatusWarning,
+			TotalBytes:        total,
+			AvailableBytes:    available,
+			UsedPercent:       float64(total-available) / float64(total) * 100.0,
+			RequiredFreeBytes: required,
+		}
+		switch crossed {
+		case WatermarkLow:
+			alert.Message = "No new shards will be allocated to this node"
+		case WatermarkHigh:
+			alert.Message = "Shards are being relocated away from this node"
+		case WatermarkFloodStage:
+			alert.Severity = DiskStatusCritical
+			alert.Message = "Indices with a shard on this node are read-only; writes to them fail until disk is freed"
+		}
+		if !thresholdEnabled {
+			alert.Message += " once disk thresholds are enabled"
+		}
+		result.Alerts = append(result.Alerts, alert)
+	}
+
+	// Most severe first, then fullest
+	sort.Slice(result.Alerts, func(i, j int) bool {
+		a, b := result.Alerts[i], result.Alerts[j]
+		if a.Watermark != b.Watermark {
+			return watermarkRank(a.Watermark) > watermarkRank(b.Watermark)
+		}
+		return a.UsedPercent > b.UsedPercent
+	})
+
+	for _, alert := range result.Alerts {
+		fields := []zap.Field{
+			zap.String("node_id", alert.NodeID),
+			zap.String("node_name", alert.NodeName),
+			zap.String("watermark", alert.Watermark),
+			zap.Float64("used_percent", alert.UsedPercent),
+			zap.Int64("available_bytes", alert.AvailableBytes),
+		}
+		message := fmt.Sprintf("Node disk usage over %s watermark", alert.Watermark)
+		if alert.Severity == DiskStatusCritical {
+			result.Status = DiskStatusCritical
+			s.logger.Error(message, fields...)
+			continue
+		}
+		s.logger.Warn(message, fields...)
+		if result.Status == DiskStatusOK {
+			result.Status = DiskStatusWarning
+		}
+	}
+
+	return result, nil
+}
+
+// highestWatermarkCrossed returns the most severe watermark a disk with the given total and
+// available bytes is over, and the free space it requires
+func highestWatermarkCrossed(thresholds []diskThreshold, total, available int64) (string, int64, bool) {
+	for i := len(thresholds) - 1; i >= 0; i-- {
+		required := thresholds[i].requiredFreeBytes(total)
+		if available < required {
+			return thresholds[i].name, required, true
+		}
+	}
+	return "", 0, false
+}
+
+// requiredFreeBytes returns the free space the watermark requires on a disk of total bytes
+func (t diskThreshold) requiredFreeBytes(total int64) int64 {
+	if t.absolute {
+		return t.freeBytes
+	}
+	required := int64(math.Ceil(float64(total) * (1 - t.usedRatio)))
+	if t.maxHeadroom >= 0 && required > t.maxHeadroom {
+		return t.maxHeadroom
+	}
+	return required
+}
+
+func watermarkRank(name string) int {
+	switch name {
+	case WatermarkLow:
+		return 1
+	case WatermarkHigh:
+		return 2
+	case WatermarkFloodStage:
+		return 3
+	}
+	return 0
+}
+
+// effectiveWatermarks reads the disk watermarks from cluster settings fetched with their
+// defaults, transient settings overriding persistent ones overriding defaults. It also
+// reports whether disk thresholds are enforced at all.
+//
+// A max headroom only applies to a watermark set explicitly when it is set explicitly too;
+// Elasticsearch drops the default headroom once the watermark is changed.
+func effectiveWatermarks(settings map[string]interface{}) (models.DiskWatermarks, bool) {
+	lookup := func(key string) (string, bool, bool) {
+		for _, source := range []string{"transient", "persistent"} {
+			if group, ok := settings[source].(map[string]interface{}); ok {
+				if value, ok := settingValue(group, diskSettingsPrefix+key); ok {
+					return value, true, true
+				}
+			}
+		}
+		if group, ok := settings["defaults"].(map[string]interface{}); ok {
+			if value, ok := settingValue(group, diskSettingsPrefix+key); ok {
+				return value, false, true
+			}
+		}
+		return "", false, false
+	}
+
+	resolve := func(name, fallback, fallbackHeadroom string) (string, string) {
+		watermark, explicit, ok := lookup("watermark." + name)
+		if !ok {
+			watermark, explicit = fallback, false
+		}
+		headroom, explicitHeadroom, ok := lookup("watermark." + name + ".max_headroom")
+		if !ok {
+			headroom = fallbackHeadroom
+		}
+		if explicit && !explicitHeadroom {
+			headroom = ""
+		}
+		return watermark, headroom
+	}
+
+	var watermarks models.DiskWatermarks
+	watermarks.Low, watermarks.LowMaxHeadroom = resolve(WatermarkLow, defaultWatermarks.Low, defaultWatermarks.LowMaxHeadroom)
+	watermarks.High, watermarks.HighMaxHeadroom = resolve(WatermarkHigh, defaultWatermarks.High, defaultWatermarks.HighMaxHeadroom)
+	watermarks.FloodStage, watermarks.FloodStageMaxHeadroom = resolve(WatermarkFloodStage, defaultWatermarks.FloodStage, defaultWatermarks.FloodStageMaxHeadroom)
+
+	enabled := true
+	if value, _, ok := lookup("threshold_enabled"); ok {
+		enabled = value != "false"
+	}
+	return watermarks, enabled
+}
+
+// settingValue looks up a dotted setting key holding a string in a settings tree
+func settingValue(settings map[string]interface{}, key string) (string, bool) {
+	value, ok := lookupSetting(settings, key)
+	text, isText := value.(string)
+	return text, ok && isText
+}
+
+// resolveWatermarks parses the low, high and flood stage watermarks, in that order
+func resolveWatermarks(watermarks models.DiskWatermarks) ([]diskThreshold, error) {
+	levels := []struct {
+		name, watermark, headroom string
+	}{
+		{WatermarkLow, watermarks.Low, watermarks.LowMaxHeadroom},
+		{WatermarkHigh, watermarks.High, watermarks.HighMaxHeadroom},
+		{WatermarkFloodStage, watermarks.FloodStage, watermarks.FloodStageMaxHeadroom},
+	}
+
+	thresholds := make([]diskThreshold, 0, len(levels))
+	for _, level := range levels {
+		threshold, err := parseWatermark(level.name, level.watermark, level.headroom)
+		if err != nil {
+			return nil, err
+		}
+		thresholds = append(thresholds, threshold)
+	}
+	return thresholds, nil
+}
+
+// parseWatermark parses a watermark given as a percentage ("85%"), a ratio ("0.85") or a
+// byte size of free space ("500mb"), with an optional max headroom byte size
+func parseWatermark(name, watermark, headroom string) (diskThreshold, error) {
+	threshold := diskThreshold{name: name, maxHeadroom: -1}
+	value := strings.TrimSpace(watermark)
+
+	switch {
+	case strings.HasSuffix(value, "%"):
+		percent, err := strconv.ParseFloat(strings.TrimSuffix(value, "%"), 64)
+		if err != nil || percent < 0 || percent > 100 {
+			return threshold, fmt.Errorf("invalid %s disk watermark %q", name, watermark)
+		}
+		threshold.usedRatio = percent / 100
+	default:
+		if ratio, err := strconv.ParseFloat(value, 64); err == nil {
+			if ratio < 0 || ratio > 1 {
+				return threshold, fmt.Errorf("invalid %s disk watermark %q", name, watermark)
+			}
+			threshold.usedRatio = ratio
+			break
+		}
+		bytes, err := parseByteSize(value)
+		if err != nil {
+			return threshold, fmt.Errorf("invalid %s disk watermark %q: %w", name, watermark, err)
+		}
+		threshold.freeBytes = bytes
+		threshold.absolute = true
+		return threshold, nil
+	}
+
+	if headroom != "" && headroom != "-1" {
+		bytes, err := parseByteSize(headroom)
+		if err != nil {
+			return threshold, fmt.Errorf("invalid %s disk watermark max headroom %q: %w", name, headroom, err)
+		}
+		threshold.maxHeadroom = bytes
+	}
+	return threshold, nil
+}
+
+// parseByteSize parses an Elasticsearch byte size such as "500mb" or "1.5gb", whose units
+// are powers of 1024
+func parseByteSize(value string) (int64, error) {
+	value = strings.ToLower(strings.TrimSpace(value))
+
+	units := []struct {
+		suffix     string
+		multiplier float64
+	}{
+		{"pb", 1 << 50}, {"tb", 1 << 40}, {"gb", 1 << 30}, {"mb", 1 << 20}, {"kb", 1 << 10}, {"b", 1},
+	}
+	for _, unit := range units {
+		if number, found := strings.CutSuffix(value, unit.suffix); found {
+			size, err := strconv.ParseFloat(strings.TrimSpace(number), 64)
+			if err != nil || size < 0 {
+				return 0, fmt.Errorf("invalid byte size %q", value)
+			}
+			return int64(size * unit.multiplier), nil
+		}
+	}
+	return 0, fmt.Errorf("byte size %q has no unit", value)
+}
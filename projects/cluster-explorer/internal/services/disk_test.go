@@ -0,0 +1,182 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+const gb = int64(1) << 30
+
+// defaultDiskSettings are the disk defaults GET /_cluster/settings?include_defaults=true
+// reports on 8.11, where a setting and its max_headroom share a prefix
+const defaultDiskSettings = `{
+  "cluster": {
+    "routing": {
+      "allocation": {
+        "disk": {
+          "threshold_enabled": "true",
+          "watermark": {
+            "low": "85%",
+            "low.max_headroom": "200gb",
+            "high": "90%",
+            "high.max_headroom": "150gb",
+            "flood_stage": "95%",
+            "flood_stage.max_headroom": "100gb"
+          }
+        }
+      }
+    }
+  }
+}`
+
+// diskNodeStatsAPIResponse follows GET /_nodes/stats/fs for the given nodes, each a name
+// with its total and available bytes
+func diskNodeStatsAPIResponse(nodes ...[3]interface{}) string {
+	entries := make([]string, 0, len(nodes))
+	for i, node := range nodes {
+		entries = append(entries, fmt.Sprintf(`"node%d": {
+      "timestamp": 1700000000000,
+      "name": %q,
+      "transport_address": "10.0.0.%d:9300",
+      "host": "10.0.0.%d",
+      "ip": "10.0.0.%d:9300",
+      "roles": ["data", "ingest"],
+      "fs": {
+        "timestamp": 1700000000000,
+        "total": {"total_in_bytes": %d, "free_in_bytes": %d, "available_in_bytes": %d},
+        "data": [{"path": "/usr/share/elasticsearch/data", "mount": "/ (overlay)", "type": "overlay", "total_in_bytes": %d, "free_in_bytes": %d, "available_in_bytes": %d}]
+      }
+    }`, i, node[0], i+1, i+1, i+1, node[1], node[2], node[2], node[1], node[2], node[2]))
+	}
+	return fmt.Sprintf(`{"_nodes": {"total": %d, "successful": %d, "failed": 0}, "cluster_name": "playground", "nodes": {%s}}`,
+		len(nodes), len(nodes), strings.Join(entries, ","))
+}
+
+func TestGetDiskAlerts(t *testing.T) {
+	testCases := []struct {
+		name             string
+		persistent       string
+		transient        string
+		nodes            [][3]interface{}
+		expectedError    string
+		expectedStatus   string
+		expectedAlerts   []string // node:watermark, most severe first
+		expectedRequired []int64  // free bytes, give or take rounding
+		expectedEnabled  bool
+	}{
+		{
+			name: "default watermarks",
+			nodes: [][3]interface{}{
+				{"es-node-1", 100 * gb, 20 * gb},
+				{"es-node-2", 100 * gb, 12 * gb},
+				{"es-node-3", 4096 * gb, 150 * gb},
+			},
+			expectedStatus: DiskStatusWarning,
+			// 200gb of headroom caps the 614gb the low watermark requires on the 4tb disk
+			expectedAlerts:   []string{"es-node-3:low", "es-node-2:low"},
+			expectedRequired: []int64{200 * gb, 15 * gb},
+			expectedEnabled:  true,
+		},
+		{
+			name:       "explicit high watermark drops its default headroom",
+			persistent: `{"cluster": {"routing": {"allocation": {"disk": {"watermark": {"high": "88%"}}}}}}`,
+			nodes: [][3]interface{}{
+				{"es-node-1", 100 * gb, 10 * gb},
+				{"es-node-2", 100 * gb, 4 * gb},
+				{"es-node-3", 4096 * gb, 150 * gb},
+			},
+			expectedStatus:   DiskStatusCritical,
+			expectedAlerts:   []string{"es-node-2:flood_stage", "es-node-3:high", "es-node-1:high"},
+			expectedRequired: []int64{5 * gb, 491520 * gb / 1000, 12 * gb},
+			expectedEnabled:  true,
+		},
+		{
+			name:      "transient byte size watermark with thresholds disabled",
+			transient: `{"cluster.routing.allocation.disk.threshold_enabled": "false", "cluster.routing.allocation.disk.watermark.flood_stage": "10gb"}`,
+			nodes: [][3]interface{}{
+				{"es-node-1", 1000 * gb, 9 * gb},
+			},
+			expectedStatus:   DiskStatusCritical,
+			expectedAlerts:   []string{"es-node-1:flood_stage"},
+			expectedRequired: []int64{10 * gb},
+		},
+		{
+			name:            "no node over a watermark",
+			nodes:           [][3]interface{}{{"es-node-1", 100 * gb, 50 * gb}},
+			expectedStatus:  DiskStatusOK,
+			expectedAlerts:  []string{},
+			expectedEnabled: true,
+		},
+		{
+			name:          "invalid watermark",
+			persistent:    `{"cluster.routing.allocation.disk.watermark.low": "most"}`,
+			nodes:         [][3]interface{}{{"es-node-1", 100 * gb, 50 * gb}},
+			expectedError: `invalid low disk watermark "most"`,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			var includeDefaults string
+			var statsRequested bool
+			service := newTestClusterService(t, func(w http.ResponseWriter, r *http.Request) {
+				switch r.URL.Path {
+				case "/_cluster/settings":
+					includeDefaults = r.URL.Query().Get("include_defaults")
+					persistent, transient := tc.persistent, tc.transient
+					if persistent == "" {
+						persistent = "{}"
+					}
+					if transient == "" {
+						transient = "{}"
+					}
+					fmt.Fprintf(w, `{"persistent": %s, "transient": %s, "defaults": %s}`, persistent, transient, defaultDiskSettings)
+				case "/_nodes/stats/fs":
+					statsRequested = true
+					w.Write([]byte(diskNodeStatsAPIResponse(tc.nodes...)))
+				default:
+					w.Write([]byte(`{"version":{"number":"8.11.1"}}`))
+				}
+			})
+
+			result, err := service.GetDiskAlerts(context.Background())
+			if tc.expectedError != "" {
+				if err == nil || !strings.Contains(err.Error(), tc.expectedError) {
+					t.Fatalf("Expected an error containing %q, got %v", tc.expectedError, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if includeDefaults != "true" || !statsRequested {
+				t.Errorf("Expected settings with defaults and fs node stats, got include_defaults=%q and node stats requested %v", includeDefaults, statsRequested)
+			}
+
+			if result.Status != tc.expectedStatus || result.ThresholdEnabled != tc.expectedEnabled || result.NodesChecked != len(tc.nodes) {
+				t.Errorf("Expected status %s with thresholds enabled %v over %d nodes, got %+v", tc.expectedStatus, tc.expectedEnabled, len(tc.nodes), result)
+			}
+			alerts := []string{}
+			var required []int64
+			for _, alert := range result.Alerts {
+				alerts = append(alerts, alert.NodeName+":"+alert.Watermark)
+				required = append(required, alert.RequiredFreeBytes)
+				if !tc.expectedEnabled && !strings.HasSuffix(alert.Message, "once disk thresholds are enabled") {
+					t.Errorf("Expected the message to say thresholds are disabled, got %q", alert.Message)
+				}
+			}
+			if !reflect.DeepEqual(alerts, tc.expectedAlerts) {
+				t.Fatalf("Expected alerts %v, got %v", tc.expectedAlerts, alerts)
+			}
+			for i, expected := range tc.expectedRequired {
+				if diff := required[i] - expected; diff < -1 || diff > 1 {
+					t.Errorf("Expected %s to require %d free bytes, got %d", alerts[i], expected, required[i])
+				}
+			}
+		})
+	}
+}
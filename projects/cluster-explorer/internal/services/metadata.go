@@ -0,0 +1,281 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	"go.uber.org/zap"
+
+	"github.com/saif-islam/es-playground/projects/cluster-explorer/internal/models"
+	"github.com/saif-islam/es-playground/shared"
+)
+
+// Template types
+const (
+	TemplateComposable = "composable"
+	TemplateLegacy     = "legacy"
+)
+
+// aliasesResponse is the get-alias API response, keyed by index
+type aliasesResponse map[string]struct {
+	Aliases map[string]struct {
+		Filter        map[string]interface{} `json:"filter"`
+		IndexRouting  string                 `json:"index_routing"`
+		SearchRouting string                 `json:"search_routing"`
+		IsWriteIndex  *bool                  `json:"is_write_index"`
+	} `json:"aliases"`
+}
+
+// GetAliases lists every alias with the indices behind it, sorted by alias name
+func (s *ClusterService) GetAliases(ctx context.Context) ([]models.AliasInfo, error) {
+	res, err := s.esClient.Indices.GetAlias(
+		s.esClient.Indices.GetAlias.WithContext(ctx),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("aliases request failed: %w", err)
+	}
+	defer res.Body.Close()
+
+	if res.IsError() {
+		return nil, shared.ParseESError(res)
+	}
+
+	var response aliasesResponse
+	if err := shared.DecodeJSONResponse(res, &response); err != nil {
+		return nil, fmt.Errorf("failed to decode aliases: %w", err)
+	}
+
+	aliases := groupAliases(response)
+
+	s.logger.Info("Retrieved aliases", zap.Int("count", len(aliases)))
+	return aliases, nil
+}
+
+// groupAliases turns the per-index alias listing into a per-alias one
+func groupAliases(response aliasesResponse) []models.AliasInfo {
+	byName := make(map[string]*models.AliasInfo)
+	// Aliases that set is_write_index false somewhere, which stops a single-index alias
+	// from defaulting to writing to its index
+	readOnly := make(map[string]bool)
+	for index, entry := range response {
+		for name, alias := range entry.Aliases {
+			info, ok := byName[name]
+			if !ok {
+				info = &models.AliasInfo{Name: name}
+				byName[name] = info
+			}
+
+			aliasIndex := models.AliasIndex{
+				Index:         index,
+				IsWriteIndex:  alias.IsWriteIndex != nil && *alias.IsWriteIndex,
+				Filtered:      len(alias.Filter) > 0,
+				IndexRouting:  alias.IndexRouting,
+				SearchRouting: alias.SearchRouting,
+			}
+			info.Indices = append(info.Indices, aliasIndex)
+			if aliasIndex.IsWriteIndex {
+				info.WriteIndex = index
+			}
+			if alias.IsWriteIndex != nil && !*alias.IsWriteIndex {
+				readOnly[name] = true
+			}
+		}
+	}
+
+	aliases := make([]models.AliasInfo, 0, len(byName))
+	for _, info := range byName {
+		sort.Slice(info.Indices, func(i, j int) bool { return info.Indices[i].Index < info.Indices[j].Index })
+		if info.WriteIndex == "" && len(info.Indices) == 1 && !readOnly[info.Name] {
+			info.WriteIndex = info.Indices[0].Index
+		}
+		aliases = append(aliases, *info)
+	}
+	sort.Slice(aliases, func(i, j int) bool { return aliases[i].Name < aliases[j].Name })
+	return aliases
+}
+
+// GetTemplates lists composable and legacy index templates, composable first, each by
+// descending precedence
+func (s *ClusterService) GetTemplates(ctx context.Context) ([]models.TemplateInfo, error) {
+	composable, err := s.getComposableTemplates(ctx)
+	if err != nil {
+		return nil, err
+	}
+	legacy, err := s.getLegacyTemplates(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	sortByPrecedence(composable)
+	sortByPrecedence(legacy)
+
+	s.logger.Info("Retrieved index templates",
+		zap.Int("composable", len(composable)),
+		zap.Int("legacy", len(legacy)))
+
+	return append(composable, legacy...), nil
+}
+
+func (s *ClusterService) getComposableTemplates(ctx context.Context) ([]models.TemplateInfo, error) {
+	res, err := s.esClient.Indices.GetIndexTemplate(
+		s.esClient.Indices.GetIndexTemplate.WithContext(ctx),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("index templates request failed: %w", err)
+	}
+	defer res.Body.Close()
+
+	if res.IsError() {
+		return nil, shared.ParseESError(res)
+	}
+
+	var response struct {
+		IndexTemplates []struct {
+			Name          string `json:"name"`
+			IndexTemplate struct {
+				IndexPatterns []string               `json:"index_patterns"`
+				Priority      int64                  `json:"priority"`
+				ComposedOf    []string               `json:"composed_of"`
+				DataStream    map[string]interface{} `json:"data_stream"`
+				Version       *int64                 `json:"version"`
+			} `json:"index_template"`
+		} `json:"index_templates"`
+	}
+	if err := shared.DecodeJSONResponse(res, &response); err != nil {
+		return nil, fmt.Errorf("failed to decode index templates: %w", err)
+	}
+
+	templates := make([]models.TemplateInfo, 0, len(response.IndexTemplates))
+	for _, template := range response.IndexTemplates {
+		templates = append(templates, models.TemplateInfo{
+			Name:          template.Name,
+			Type:          TemplateComposable,
+			IndexPatterns: template.IndexTemplate.IndexPatterns,
+			Priority:      template.IndexTemplate.Priority,
+			ComposedOf:    template.IndexTemplate.ComposedOf,
+			DataStream:    template.IndexTemplate.DataStream != nil,
+			Version:       template.IndexTemplate.Version,
+		})
+	}
+	return templates, nil
+}
+
+func (s *ClusterService) getLegacyTemplates(ctx context.Context) ([]models.TemplateInfo, error) {
+	res, err := s.esClient.Indices.GetTemplate(
+		s.esClient.Indices.GetTemplate.WithContext(ctx),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("legacy templates request failed: %w", err)
+	}
+	defer res.Body.Close()
+
+	if res.IsError() {
+		return nil, shared.ParseESError(res)
+	}
+
+	var response map[string]struct {
+		Order         int64    `json:"order"`
+		IndexPatterns []string `json:"index_patterns"`
+		Version       *int64   `json:"version"`
+	}
+	if err := shared.DecodeJSONResponse(res, &response); err != nil {
+		return nil, fmt.Errorf("failed to decode legacy templates: %w", err)
+	}
+
+	templates := make([]models.TemplateInfo, 0, len(response))
+	for name, template := range response {
+		templates = append(templates, models.TemplateInfo{
+			Name:          name,
+			Type:          TemplateLegacy,
+			IndexPatterns: template.IndexPatterns,
+			Priority:      template.Order,
+			Version:       template.Version,
+		})
+	}
+	return templates, nil
+}
+
+// sortByPrecedence orders templates by descending priority, then name
+func sortByPrecedence(templates []models.TemplateInfo) {
+	sort.Slice(templates, func(i, j int) bool {
+		if templates[i].Priority != templates[j].Priority {
+			return templates[i].Priority > templates[j].Priority
+		}
+		return templates[i].Name < templates[j].Name
+	})
+}
+
+// ResolveTemplate works out which templates a new index named index would be created
+// from. The matching composable template with the highest priority wins, and legacy
+// templates are then ignored. Only when no composable template matches do the matching
+// legacy templates apply, merged so that higher orders override lower ones.
+func ResolveTemplate(templates []models.TemplateInfo, index string) *models.TemplateMatch {
+	var composable, legacy []models.TemplateInfo
+	for _, template := range templates {
+		if !matchesAnyPattern(template.IndexPatterns, index) {
+			continue
+		}
+		if template.Type == TemplateComposable {
+			composable = append(composable, template)
+		} else {
+			legacy = append(legacy, template)
+		}
+	}
+	sortByPrecedence(composable)
+	sortByPrecedence(legacy)
+
+	match := &models.TemplateMatch{Index: index, Matching: []string{}}
+	winners := composable
+	if len(composable) == 0 {
+		winners = legacy
+	} else {
+		for _, template := range legacy {
+			match.Shadowed = append(match.Shadowed, template.Name)
+		}
+	}
+
+	for _, template := range winners {
+		match.Matching = append(match.Matching, template.Name)
+	}
+	if len(winners) > 0 {
+		match.Template = winners[0].Name
+		match.Type = winners[0].Type
+	}
+	return match
+}
+
+// matchesAnyPattern reports whether name matches one of the index patterns
+func matchesAnyPattern(patterns []string, name string) bool {
+	for _, pattern := range patterns {
+		if wildcardMatch(pattern, name) {
+			return true
+		}
+	}
+	return false
+}
+
+// wildcardMatch matches name against a pattern in which * stands for any run of
+// characters, the only wildcard index patterns support
+func wildcardMatch(pattern, name string) bool {
+	parts := strings.Split(pattern, "*")
+	if len(parts) == 1 {
+		return pattern == name
+	}
+
+	if !strings.HasPrefix(name, parts[0]) {
+		return false
+	}
+	name = name[len(parts[0]):]
+
+	last := parts[len(parts)-1]
+	for _, part := range parts[1 : len(parts)-1] {
+		i := strings.Index(name, part)
+		if i < 0 {
+			return false
+		}
+		name = name[i+len(part):]
+	}
+	return len(name) >= len(last) && strings.HasSuffix(name, last)
+}
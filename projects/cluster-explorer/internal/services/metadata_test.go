@@ -0,0 +1,157 @@
+package services
+
+import (
+	"context"
+	"net/http"
+	"reflect"
+	"testing"
+)
+
+// aliasesAPIResponse follows GET /_alias: logs rolls over with an explicit write index,
+// products-read points at a single index without one, and archive is filtered and routed
+// and marks its only index as not writable
+const aliasesAPIResponse = `{
+  "logs-000001": {"aliases": {"logs": {"is_write_index": false}}},
+  "logs-000002": {"aliases": {"logs": {"is_write_index": true}}},
+  "products-v2": {"aliases": {"products-read": {}}},
+  "orders-2023": {
+    "aliases": {
+      "archive": {
+        "filter": {"range": {"created_at": {"lt": "2024-01-01"}}},
+        "index_routing": "1",
+        "search_routing": "1,2",
+        "is_write_index": false
+      }
+    }
+  },
+  "scratch": {"aliases": {}}
+}`
+
+// indexTemplatesAPIResponse follows GET /_index_template with a data stream template
+// and a catch-all template for logs
+const indexTemplatesAPIResponse = `{
+  "index_templates": [
+    {
+      "name": "logs-default",
+      "index_template": {
+        "index_patterns": ["logs-*"],
+        "template": {"settings": {"index": {"number_of_shards": "1"}}},
+        "composed_of": ["logs-mappings", "logs-settings"],
+        "priority": 100,
+        "version": 3,
+        "data_stream": {"hidden": false, "allow_custom_routing": false}
+      }
+    },
+    {
+      "name": "logs-app",
+      "index_template": {
+        "index_patterns": ["logs-app-*"],
+        "composed_of": [],
+        "priority": 200
+      }
+    }
+  ]
+}`
+
+// legacyTemplatesAPIResponse follows GET /_template
+const legacyTemplatesAPIResponse = `{
+  "old-logs": {"order": 5, "index_patterns": ["logs-*"], "settings": {"index": {"number_of_replicas": "2"}}, "mappings": {}, "aliases": {}},
+  "everything": {"order": 0, "version": 1, "index_patterns": ["*"], "settings": {}, "mappings": {}, "aliases": {}},
+  "orders": {"order": 10, "index_patterns": ["orders-*", "*-orders"], "settings": {}, "mappings": {}, "aliases": {}}
+}`
+
+func TestGetAliases(t *testing.T) {
+	var path string
+	service := newTestClusterService(t, func(w http.ResponseWriter, r *http.Request) {
+		path = r.URL.Path
+		w.Write([]byte(aliasesAPIResponse))
+	})
+
+	aliases, err := service.GetAliases(context.Background())
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if path != "/_alias" {
+		t.Errorf("Expected /_alias, got %s", path)
+	}
+
+	if len(aliases) != 3 {
+		t.Fatalf("Expected 3 aliases, got %+v", aliases)
+	}
+	testCases := []struct {
+		alias              string
+		expectedIndices    []string
+		expectedWriteIndex string
+	}{
+		{alias: "archive", expectedIndices: []string{"orders-2023"}},
+		{alias: "logs", expectedIndices: []string{"logs-000001", "logs-000002"}, expectedWriteIndex: "logs-000002"},
+		{alias: "products-read", expectedIndices: []string{"products-v2"}, expectedWriteIndex: "products-v2"},
+	}
+	for i, tc := range testCases {
+		alias := aliases[i]
+		var indices []string
+		for _, index := range alias.Indices {
+			indices = append(indices, index.Index)
+		}
+		if alias.Name != tc.alias || !reflect.DeepEqual(indices, tc.expectedIndices) || alias.WriteIndex != tc.expectedWriteIndex {
+			t.Errorf("Expected %s on %v writing to %q, got %+v", tc.alias, tc.expectedIndices, tc.expectedWriteIndex, alias)
+		}
+	}
+
+	archive := aliases[0].Indices[0]
+	if !archive.Filtered || archive.IndexRouting != "1" || archive.SearchRouting != "1,2" || archive.IsWriteIndex {
+		t.Errorf("Expected a filtered, routed, read-only archive alias, got %+v", archive)
+	}
+}
+
+func TestGetTemplates(t *testing.T) {
+	service := newTestClusterService(t, func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/_index_template":
+			w.Write([]byte(indexTemplatesAPIResponse))
+		case "/_template":
+			w.Write([]byte(legacyTemplatesAPIResponse))
+		default:
+			w.Write([]byte(`{"version":{"number":"8.11.1"}}`))
+		}
+	})
+
+	templates, err := service.GetTemplates(context.Background())
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	// Composable first, then legacy, each by descending precedence
+	var names []string
+	for _, template := range templates {
+		names = append(names, template.Type+":"+template.Name)
+	}
+	expectedNames := []string{"composable:logs-app", "composable:logs-default", "legacy:orders", "legacy:old-logs", "legacy:everything"}
+	if !reflect.DeepEqual(names, expectedNames) {
+		t.Fatalf("Expected %v, got %v", expectedNames, names)
+	}
+	logsDefault := templates[1]
+	if !logsDefault.DataStream || logsDefault.Priority != 100 || len(logsDefault.ComposedOf) != 2 || logsDefault.Version == nil || *logsDefault.Version != 3 {
+		t.Errorf("Expected the logs-default data stream template, got %+v", logsDefault)
+	}
+
+	testCases := []struct {
+		index            string
+		expectedTemplate string
+		expectedMatching []string
+		expectedShadowed []string
+	}{
+		{index: "logs-app-2024.01.01", expectedTemplate: "logs-app", expectedMatching: []string{"logs-app", "logs-default"}, expectedShadowed: []string{"old-logs", "everything"}},
+		{index: "logs-web", expectedTemplate: "logs-default", expectedMatching: []string{"logs-default"}, expectedShadowed: []string{"old-logs", "everything"}},
+		{index: "eu-orders", expectedTemplate: "orders", expectedMatching: []string{"orders", "everything"}},
+		{index: "metrics", expectedTemplate: "everything", expectedMatching: []string{"everything"}},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.index, func(t *testing.T) {
+			match := ResolveTemplate(templates, tc.index)
+			if match.Template != tc.expectedTemplate || !reflect.DeepEqual(match.Matching, tc.expectedMatching) || !reflect.DeepEqual(match.Shadowed, tc.expectedShadowed) {
+				t.Errorf("Expected %s from %v shadowing %v, got %+v", tc.expectedTemplate, tc.expectedMatching, tc.expectedShadowed, match)
+			}
+		})
+	}
+}
@@ -0,0 +1,203 @@
+package services
+
+import (
+	"time"
+
+	"github.com/saif-islam/es-playground/projects/cluster-explorer/internal/models"
+)
+
+// nodeStatsResponse is the part of the _nodes/stats API response used for performance metrics
+type nodeStatsResponse struct {
+	Nodes map[string]nodeStats `json:"nodes"`
+}
+
+type nodeStats struct {
+	OS struct {
+		CPU struct {
+			Percent     float64 `json:"percent"`
+			LoadAverage struct {
+				OneMinute      float64 `json:"1m"`
+				FiveMinutes    float64 `json:"5m"`
+				FifteenMinutes float64 `json:"15m"`
+			} `json:"load_average"`
+		} `json:"cpu"`
+	} `json:"os"`
+	JVM struct {
+		Mem struct {
+			HeapUsedInBytes    int64   `json:"heap_used_in_bytes"`
+			HeapUsedPercent    float64 `json:"heap_used_percent"`
+			HeapMaxInBytes     int64   `json:"heap_max_in_bytes"`
+			NonHeapUsedInBytes int64   `json:"non_heap_used_in_bytes"`
+		} `json:"mem"`
+		GC struct {
+			Collectors map[string]struct {
+				CollectionCount        int64 `json:"collection_count"`
+				CollectionTimeInMillis int64 `json:"collection_time_in_millis"`
+			} `json:"collectors"`
+		} `json:"gc"`
+		BufferPools map[string]struct {
+			UsedInBytes int64 `json:"used_in_bytes"`
+		} `json:"buffer_pools"`
+	} `json:"jvm"`
+	FS struct {
+		Total struct {
+			TotalInBytes int64 `json:"total_in_bytes"`
+			FreeInBytes  int64 `json:"free_in_bytes"`
+		} `json:"total"`
+	} `json:"fs"`
+	Transport struct {
+		RxCount       int64 `json:"rx_count"`
+		RxSizeInBytes int64 `json:"rx_size_in_bytes"`
+		TxCount       int64 `json:"tx_count"`
+		TxSizeInBytes int64 `json:"tx_size_in_bytes"`
+	} `json:"transport"`
+	ThreadPool map[string]struct {
+		Threads   int   `json:"threads"`
+		Queue     int   `json:"queue"`
+		Active    int   `json:"active"`
+		Rejected  int64 `json:"rejected"`
+		Largest   int   `json:"largest"`
+		Completed int64 `json:"completed"`
+	} `json:"thread_pool"`
+	Indices struct {
+		Search struct {
+			QueryTotal          int64 `json:"query_total"`
+			QueryTimeInMillis   int64 `json:"query_time_in_millis"`
+			QueryCurrent        int64 `json:"query_current"`
+			FetchTotal          int64 `json:"fetch_total"`
+			FetchTimeInMillis   int64 `json:"fetch_time_in_millis"`
+			FetchCurrent        int64 `json:"fetch_current"`
+			ScrollTotal         int64 `json:"scroll_total"`
+			ScrollTimeInMillis  int64 `json:"scroll_time_in_millis"`
+			ScrollCurrent       int64 `json:"scroll_current"`
+			SuggestTotal        int64 `json:"suggest_total"`
+			SuggestTimeInMillis int64 `json:"suggest_time_in_millis"`
+			SuggestCurrent      int64 `json:"suggest_current"`
+		} `json:"search"`
+		Indexing struct {
+			IndexTotal           int64 `json:"index_total"`
+			IndexTimeInMillis    int64 `json:"index_time_in_millis"`
+			IndexCurrent         int64 `json:"index_current"`
+			IndexFailed          int64 `json:"index_failed"`
+			DeleteTotal          int64 `json:"delete_total"`
+			DeleteTimeInMillis   int64 `json:"delete_time_in_millis"`
+			DeleteCurrent        int64 `json:"delete_current"`
+			NoopUpdateTotal      int64 `json:"noop_update_total"`
+			IsThrottled          bool  `json:"is_throttled"`
+			ThrottleTimeInMillis int64 `json:"throttle_time_in_millis"`
+		} `json:"indexing"`
+	} `json:"indices"`
+}
+
+// aggregateNodeStats combines the stats of all nodes into cluster-wide metrics: counters and
+// sizes are summed, CPU and heap percentages averaged. Disk usage is worked out from the
+// summed sizes so large nodes weigh more. I/O rates need two samples and are left unset.
+func aggregateNodeStats(response nodeStatsResponse) *models.PerformanceMetrics {
+	metrics := &models.PerformanceMetrics{}
+
+	nodeCount := len(response.Nodes)
+	if nodeCount == 0 {
+		return metrics
+	}
+
+	for _, node := range response.Nodes {
+		metrics.CPU.UsagePercent += node.OS.CPU.Percent
+		metrics.CPU.LoadAverage.OneMinute += node.OS.CPU.LoadAverage.OneMinute
+		metrics.CPU.LoadAverage.FiveMinutes += node.OS.CPU.LoadAverage.FiveMinutes
+		metrics.CPU.LoadAverage.FifteenMinutes += node.OS.CPU.LoadAverage.FifteenMinutes
+
+		metrics.Memory.HeapUsedPercent += node.JVM.Mem.HeapUsedPercent
+		metrics.Memory.HeapUsedBytes += node.JVM.Mem.HeapUsedInBytes
+		metrics.Memory.HeapMaxBytes += node.JVM.Mem.HeapMaxInBytes
+		metrics.Memory.NonHeapUsedBytes += node.JVM.Mem.NonHeapUsedInBytes
+		metrics.Memory.DirectMemoryUsed += node.JVM.BufferPools["direct"].UsedInBytes
+
+		metrics.Disk.TotalBytes += node.FS.Total.TotalInBytes
+		metrics.Disk.FreeBytes += node.FS.Total.FreeInBytes
+
+		metrics.Network.BytesReceived += node.Transport.RxSizeInBytes
+		metrics.Network.BytesSent += node.Transport.TxSizeInBytes
+		metrics.Network.PacketsReceived += node.Transport.RxCount
+		metrics.Network.PacketsSent += node.Transport.TxCount
+
+		young, old := node.JVM.GC.Collectors["young"], node.JVM.GC.Collectors["old"]
+		metrics.GarbageCollection.YoungGenCollections += young.CollectionCount
+		metrics.GarbageCollection.YoungGenTime += millis(young.CollectionTimeInMillis)
+		metrics.GarbageCollection.OldGenCollections += old.CollectionCount
+		metrics.GarbageCollection.OldGenTime += millis(old.CollectionTimeInMillis)
+
+		// index and bulk were merged into the write pool in Elasticsearch 6.3
+		addThreadPool(&metrics.ThreadPools.Search, node, "search")
+		addThreadPool(&metrics.ThreadPools.Get, node, "get")
+		addThreadPool(&metrics.ThreadPools.Management, node, "management")
+		if _, ok := node.ThreadPool["index"]; ok {
+			addThreadPool(&metrics.ThreadPools.Index, node, "index")
+		} else {
+			addThreadPool(&metrics.ThreadPools.Index, node, "write")
+		}
+		if _, ok := node.ThreadPool["bulk"]; ok {
+			addThreadPool(&metrics.ThreadPools.Bulk, node, "bulk")
+		} else {
+			addThreadPool(&metrics.ThreadPools.Bulk, node, "write")
+		}
+
+		search := node.Indices.Search
+		metrics.Search.QueryTotal += search.QueryTotal
+		metrics.Search.QueryTime += millis(search.QueryTimeInMillis)
+		metrics.Search.QueryCurrent += search.QueryCurrent
+		metrics.Search.FetchTotal += search.FetchTotal
+		metrics.Search.FetchTime += millis(search.FetchTimeInMillis)
+		metrics.Search.FetchCurrent += search.FetchCurrent
+		metrics.Search.ScrollTotal += search.ScrollTotal
+		metrics.Search.ScrollTime += millis(search.ScrollTimeInMillis)
+		metrics.Search.ScrollCurrent += search.ScrollCurrent
+		metrics.Search.SuggestTotal += search.SuggestTotal
+		metrics.Search.SuggestTime += millis(search.SuggestTimeInMillis)
+		metrics.Search.SuggestCurrent += search.SuggestCurrent
+
+		indexing := node.Indices.Indexing
+		metrics.Indexing.IndexTotal += indexing.IndexTotal
+		metrics.Indexing.IndexTime += millis(indexing.IndexTimeInMillis)
+		metrics.Indexing.IndexCurrent += indexing.IndexCurrent
+		metrics.Indexing.IndexFailed += indexing.IndexFailed
+		metrics.Indexing.DeleteTotal += indexing.DeleteTotal
+		metrics.Indexing.DeleteTime += millis(indexing.DeleteTimeInMillis)
+		metrics.Indexing.DeleteCurrent += indexing.DeleteCurrent
+		metrics.Indexing.NoopUpdateTotal += indexing.NoopUpdateTotal
+		metrics.Indexing.IsThrottled = metrics.Indexing.IsThrottled || indexing.IsThrottled
+		metrics.Indexing.ThrottleTime += millis(indexing.ThrottleTimeInMillis)
+	}
+
+	count := float64(nodeCount)
+	metrics.CPU.UsagePercent /= count
+	metrics.CPU.LoadAverage.OneMinute /= count
+	metrics.CPU.LoadAverage.FiveMinutes /= count
+	metrics.CPU.LoadAverage.FifteenMinutes /= count
+	metrics.Memory.HeapUsedPercent /= count
+
+	metrics.Disk.UsedBytes = metrics.Disk.TotalBytes - metrics.Disk.FreeBytes
+	if metrics.Disk.TotalBytes > 0 {
+		metrics.Disk.UsedPercent = float64(metrics.Disk.UsedBytes) / float64(metrics.Disk.TotalBytes) * 100.0
+	}
+
+	return metrics
+}
+
+// addThreadPool adds a node's stats for the named thread pool to the cluster totals
+func addThreadPool(total *models.ThreadPoolStats, node nodeStats, name string) {
+	pool, ok := node.ThreadPool[name]
+	if !ok {
+		return
+	}
+	total.Threads += pool.Threads
+	total.Queue += pool.Queue
+	total.Active += pool.Active
+	total.Rejected += pool.Rejected
+	total.Largest += pool.Largest
+	total.Completed += pool.Completed
+}
+
+// millis converts a millisecond count reported by Elasticsearch to a duration
+func millis(value int64) time.Duration {
+	return time.Duration(value) * time.Millisecond
+}
@@ -0,0 +1,151 @@
+package services
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/saif-islam/es-playground/shared"
+)
+
+// newTestClusterService returns a cluster service whose client talks to handler as if it
+// were Elasticsearch. The product check header is already set, so handlers only write the body.
+func newTestClusterService(t *testing.T, handler http.HandlerFunc) *ClusterService {
+	t.Helper()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("X-Elastic-Product", "Elasticsearch")
+		w.Header().Set("Content-Type", "application/json")
+		handler(w, r)
+	}))
+	t.Cleanup(server.Close)
+
+	client, err := shared.NewESClient(&shared.ESConfig{URLs: []string{server.URL}}, zap.NewNop())
+	if err != nil {
+		t.Fatalf("Failed to create client: %v", err)
+	}
+	return NewClusterService(client, zap.NewNop())
+}
+
+// nodeStatsAPIResponse follows GET /_nodes/stats of a two-node cluster: an 8.11 node with the
+// merged write pool and a 6.2 node still reporting separate index and bulk pools
+const nodeStatsAPIResponse = `{
+  "_nodes": {"total": 2, "successful": 2, "failed": 0},
+  "cluster_name": "playground",
+  "nodes": {
+    "Yx3kT1": {
+      "name": "es-node-1",
+      "os": {"cpu": {"percent": 40, "load_average": {"1m": 2.0, "5m": 1.5, "15m": 1.0}}},
+      "jvm": {
+        "mem": {"heap_used_in_bytes": 600, "heap_used_percent": 60, "heap_max_in_bytes": 1000, "non_heap_used_in_bytes": 100},
+        "gc": {"collectors": {"young": {"collection_count": 10, "collection_time_in_millis": 200}, "old": {"collection_count": 1, "collection_time_in_millis": 50}}},
+        "buffer_pools": {"direct": {"count": 4, "used_in_bytes": 64, "total_capacity_in_bytes": 64}, "mapped": {"count": 2, "used_in_bytes": 999, "total_capacity_in_bytes": 999}}
+      },
+      "fs": {"total": {"total_in_bytes": 3000, "free_in_bytes": 1000, "available_in_bytes": 900}},
+      "transport": {"server_open": 13, "rx_count": 100, "rx_size_in_bytes": 10000, "tx_count": 90, "tx_size_in_bytes": 9000},
+      "thread_pool": {
+        "search": {"threads": 7, "queue": 2, "active": 3, "rejected": 1, "largest": 7, "completed": 500},
+        "write": {"threads": 4, "queue": 0, "active": 1, "rejected": 5, "largest": 4, "completed": 800},
+        "get": {"threads": 2, "queue": 0, "active": 0, "rejected": 0, "largest": 2, "completed": 40}
+      },
+      "indices": {
+        "search": {"query_total": 1000, "query_time_in_millis": 5000, "query_current": 2, "fetch_total": 900, "fetch_time_in_millis": 900, "fetch_current": 0},
+        "indexing": {"index_total": 2000, "index_time_in_millis": 4000, "index_current": 1, "index_failed": 3, "is_throttled": false, "throttle_time_in_millis": 0}
+      }
+    },
+    "Qm8pR2": {
+      "name": "es-node-2",
+      "os": {"cpu": {"percent": 80, "load_average": {"1m": 4.0, "5m": 2.5, "15m": 2.0}}},
+      "jvm": {
+        "mem": {"heap_used_in_bytes": 900, "heap_used_percent": 90, "heap_max_in_bytes": 1000, "non_heap_used_in_bytes": 150},
+        "gc": {"collectors": {"young": {"collection_count": 20, "collection_time_in_millis": 300}, "old": {"collection_count": 2, "collection_time_in_millis": 150}}}
+      },
+      "fs": {"total": {"total_in_bytes": 1000, "free_in_bytes": 1000, "available_in_bytes": 1000}},
+      "transport": {"server_open": 13, "rx_count": 50, "rx_size_in_bytes": 5000, "tx_count": 40, "tx_size_in_bytes": 4000},
+      "thread_pool": {
+        "search": {"threads": 7, "queue": 1, "active": 1, "rejected": 0, "largest": 7, "completed": 300},
+        "index": {"threads": 2, "queue": 0, "active": 0, "rejected": 0, "largest": 2, "completed": 60},
+        "bulk": {"threads": 2, "queue": 3, "active": 2, "rejected": 7, "largest": 2, "completed": 70}
+      },
+      "indices": {
+        "search": {"query_total": 500, "query_time_in_millis": 1000, "query_current": 1},
+        "indexing": {"index_total": 100, "index_time_in_millis": 100, "is_throttled": true, "throttle_time_in_millis": 30}
+      }
+    }
+  }
+}`
+
+func TestGetPerformanceMetrics(t *testing.T) {
+	var path string
+	service := newTestClusterService(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/" {
+			w.Write([]byte(`{"version":{"number":"8.11.1"}}`))
+			return
+		}
+		path = r.URL.Path
+		w.Write([]byte(nodeStatsAPIResponse))
+	})
+
+	metrics, err := service.GetPerformanceMetrics(context.Background())
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if path != "/_nodes/stats/os,process,jvm,fs,transport,thread_pool,indices" {
+		t.Errorf("Expected node stats with the transport metric, got %s", path)
+	}
+
+	// Percentages are averaged per node
+	if metrics.CPU.UsagePercent != 60 || metrics.CPU.LoadAverage.OneMinute != 3 || metrics.Memory.HeapUsedPercent != 75 {
+		t.Errorf("Expected 60%% CPU, a 3.0 load and 75%% heap, got %+v and %+v", metrics.CPU, metrics.Memory)
+	}
+	if metrics.Memory.HeapUsedBytes != 1500 || metrics.Memory.HeapMaxBytes != 2000 || metrics.Memory.DirectMemoryUsed != 64 {
+		t.Errorf("Expected summed heap and direct memory, got %+v", metrics.Memory)
+	}
+
+	// Disk usage comes from the summed sizes, so the larger node weighs more
+	if metrics.Disk.TotalBytes != 4000 || metrics.Disk.UsedBytes != 2000 || metrics.Disk.UsedPercent != 50 {
+		t.Errorf("Expected 2000 of 4000 bytes used, got %+v", metrics.Disk)
+	}
+
+	if metrics.Network.BytesReceived != 15000 || metrics.Network.BytesSent != 13000 || metrics.Network.PacketsReceived != 150 || metrics.Network.PacketsSent != 130 {
+		t.Errorf("Expected summed transport stats, got %+v", metrics.Network)
+	}
+	if metrics.GarbageCollection.YoungGenCollections != 30 || metrics.GarbageCollection.OldGenTime != 200*time.Millisecond {
+		t.Errorf("Expected 30 young collections and 200ms of old GC, got %+v", metrics.GarbageCollection)
+	}
+
+	// The write pool counts as both index and bulk on nodes that merged them
+	pools := metrics.ThreadPools
+	if pools.Search.Active != 4 || pools.Search.Queue != 3 || pools.Search.Completed != 800 {
+		t.Errorf("Expected summed search pools, got %+v", pools.Search)
+	}
+	if pools.Index.Completed != 860 || pools.Bulk.Completed != 870 || pools.Bulk.Rejected != 12 {
+		t.Errorf("Expected the write pool in both index and bulk, got index %+v and bulk %+v", pools.Index, pools.Bulk)
+	}
+
+	if metrics.Search.QueryTotal != 1500 || metrics.Search.QueryTime != 6*time.Second || metrics.Search.QueryCurrent != 3 {
+		t.Errorf("Expected 1500 queries in 6s, got %+v", metrics.Search)
+	}
+	if metrics.Indexing.IndexTotal != 2100 || metrics.Indexing.IndexFailed != 3 || !metrics.Indexing.IsThrottled || metrics.Indexing.ThrottleTime != 30*time.Millisecond {
+		t.Errorf("Expected summed indexing with one throttled node, got %+v", metrics.Indexing)
+	}
+}
+
+func TestGetPerformanceMetrics_NoNodes(t *testing.T) {
+	service := newTestClusterService(t, func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"_nodes":{"total":0,"successful":0,"failed":0},"cluster_name":"playground","nodes":{}}`))
+	})
+
+	metrics, err := service.GetPerformanceMetrics(context.Background())
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if metrics.CPU.UsagePercent != 0 || metrics.Disk.UsedPercent != 0 {
+		t.Errorf("Expected empty metrics without nodes, got %+v", metrics)
+	}
+}
@@ -0,0 +1,192 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/elastic/go-elasticsearch/v8/esapi"
+	"go.uber.org/zap"
+
+	"github.com/saif-islam/es-playground/projects/cluster-explorer/internal/models"
+	"github.com/saif-islam/es-playground/shared"
+)
+
+// recoveryResponse is the part of the _recovery API response used for progress reporting
+type recoveryResponse map[string]struct {
+	Shards []struct {
+		ID                int    `json:"id"`
+		Type              string `json:"type"`
+		Stage             string `json:"stage"`
+		Primary           bool   `json:"primary"`
+		TotalTimeInMillis int64  `json:"total_time_in_millis"`
+		Source            struct {
+			Name string `json:"name"`
+		} `json:"source"`
+		Target struct {
+			Name string `json:"name"`
+		} `json:"target"`
+		Index struct {
+			Size struct {
+				TotalInBytes     int64  `json:"total_in_bytes"`
+				RecoveredInBytes int64  `json:"recovered_in_bytes"`
+				Percent          string `json:"percent"`
+			} `json:"size"`
+			Files struct {
+				Percent string `json:"percent"`
+			} `json:"files"`
+		} `json:"index"`
+		Translog struct {
+			Percent string `json:"percent"`
+		} `json:"translog"`
+	} `json:"shards"`
+}
+
+// GetRecoveryStatus reports per-shard recovery stage and progress, for one index or the
+// whole cluster when index is empty. With activeOnly, finished recoveries are left out.
+func (s *ClusterService) GetRecoveryStatus(ctx context.Context, index string, activeOnly bool) (*models.RecoveryStatus, error) {
+	opts := []func(*esapi.IndicesRecoveryRequest){
+		s.esClient.Indices.Recovery.WithContext(ctx),
+		s.esClient.Indices.Recovery.WithActiveOnly(activeOnly),
+	}
+	if index != "" {
+		opts = append(opts, s.esClient.Indices.Recovery.WithIndex(index))
+	}
+
+	res, err := s.esClient.Indices.Recovery(opts...)
+	if err != nil {
+		return nil, fmt.Errorf("recovery request failed: %w", err)
+	}
+	defer res.Body.Close()
+
+	if res.IsError() {
+		return nil, shared.ParseESError(res)
+	}
+
+	var response recoveryResponse
+	if err := shared.DecodeJSONResponse(res, &response); err != nil {
+		return nil, fmt.Errorf("failed to decode recovery status: %w", err)
+	}
+
+	status := summarizeRecovery(response)
+	status.Index = index
+	status.RequestID = generateRequestID()
+	status.Timestamp = time.Now()
+
+	s.logger.Info("Retrieved recovery status",
+		zap.String("index", index),
+		zap.Int("active_shards", status.ActiveShards),
+		zap.Float64("bytes_percent", status.BytesPercent),
+		zap.Duration("estimated_time_remaining", status.EstimatedTimeRemaining))
+
+	return status, nil
+}
+
+// WaitForRecovery polls recovery status until no shard is recovering or the timeout
+// expires, and returns the last status observed
+func (s *ClusterService) WaitForRecovery(ctx context.Context, index string, interval, timeout time.Duration) (*models.RecoveryStatus, error) {
+	deadline := time.Now().Add(timeout)
+
+	for {
+		status, err := s.GetRecoveryStatus(ctx, index, true)
+		if err != nil {
+			return nil, err
+		}
+
+		if status.Done || time.Now().After(deadline) {
+			return status, nil
+		}
+
+		select {
+		case <-ctx.Done():
+			return status, ctx.Err()
+		case <-time.After(interval):
+		}
+	}
+}
+
+// summarizeRecovery converts a _recovery response into per-shard progress and totals.
+// Shards recover in parallel, so the overall estimate is that of the slowest shard.
+func summarizeRecovery(response recoveryResponse) *models.RecoveryStatus {
+	status := &models.RecoveryStatus{
+		Shards: []models.ShardRecovery{},
+	}
+
+	for indexName, index := range response {
+		for _, shard := range index.Shards {
+			recovery := models.ShardRecovery{
+				Index:           indexName,
+				Shard:           shard.ID,
+				Primary:         shard.Primary,
+				Type:            shard.Type,
+				Stage:           shard.Stage,
+				SourceNode:      shard.Source.Name,
+				TargetNode:      shard.Target.Name,
+				FilesPercent:    parsePercent(shard.Index.Files.Percent),
+				BytesPercent:    parsePercent(shard.Index.Size.Percent),
+				TranslogPercent: parsePercent(shard.Translog.Percent),
+				BytesTotal:      shard.Index.Size.TotalInBytes,
+				BytesRecovered:  shard.Index.Size.RecoveredInBytes,
+				Elapsed:         time.Duration(shard.TotalTimeInMillis) * time.Millisecond,
+			}
+
+			if shard.Stage == "DONE" {
+				status.CompletedShards++
+			} else {
+				status.ActiveShards++
+				recovery.EstimatedTimeRemaining = estimateRemaining(recovery.Elapsed, recovery.BytesRecovered, recovery.BytesTotal)
+				if recovery.EstimatedTimeRemaining > status.EstimatedTimeRemaining {
+					status.EstimatedTimeRemaining = recovery.EstimatedTimeRemaining
+				}
+			}
+
+			status.BytesTotal += recovery.BytesTotal
+			status.BytesRecovered += recovery.BytesRecovered
+			status.Shards = append(status.Shards, recovery)
+		}
+	}
+
+	// Most remaining work first
+	sort.Slice(status.Shards, func(i, j int) bool {
+		a, b := status.Shards[i], status.Shards[j]
+		if a.EstimatedTimeRemaining != b.EstimatedTimeRemaining {
+			return a.EstimatedTimeRemaining > b.EstimatedTimeRemaining
+		}
+		if a.Index != b.Index {
+			return a.Index < b.Index
+		}
+		return a.Shard < b.Shard
+	})
+
+	if status.BytesTotal > 0 {
+		status.BytesPercent = float64(status.BytesRecovered) / float64(status.BytesTotal) * 100.0
+	} else if status.ActiveShards == 0 {
+		status.BytesPercent = 100.0
+	}
+	status.Done = status.ActiveShards == 0
+
+	return status
+}
+
+// estimateRemaining extrapolates a shard's remaining time from its transfer rate so far.
+// Until some bytes have been copied there is no rate, so no estimate is given.
+func estimateRemaining(elapsed time.Duration, recovered, total int64) time.Duration {
+	if recovered <= 0 || total <= recovered || elapsed <= 0 {
+		return 0
+	}
+	rate := float64(recovered) / elapsed.Seconds()
+	return time.Duration(float64(total-recovered) / rate * float64(time.Second))
+}
+
+// parsePercent parses percentages as reported by Elasticsearch, e.g. "42.5%". Unknown
+// progress is reported as "-1.0%" and treated as zero.
+func parsePercent(value string) float64 {
+	percent, err := strconv.ParseFloat(strings.TrimSuffix(value, "%"), 64)
+	if err != nil || percent < 0 {
+		return 0
+	}
+	return percent
+}
@@ -0,0 +1,184 @@
+package services
+
+import (
+	"context"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/saif-islam/es-playground/shared"
+)
+
+// recoveryAPIResponse follows the GET /_recovery response of an 8.11 cluster, with every
+// field Elasticsearch returns: a replica of logs-000001 is copying files, its primary
+// recovered from disk, and a replica of metrics-000001 is replaying its translog
+const recoveryAPIResponse = `{
+  "logs-000001": {
+    "shards": [
+      {
+        "id": 0,
+        "type": "PEER",
+        "stage": "INDEX",
+        "primary": false,
+        "start_time_in_millis": 1700000000000,
+        "total_time_in_millis": 10000,
+        "source": {"id": "Yx3kT1", "host": "10.0.0.1", "transport_address": "10.0.0.1:9300", "ip": "10.0.0.1", "name": "es-node-1"},
+        "target": {"id": "Qm8pR2", "host": "10.0.0.2", "transport_address": "10.0.0.2:9300", "ip": "10.0.0.2", "name": "es-node-2"},
+        "index": {
+          "size": {"total_in_bytes": 1000000, "reused_in_bytes": 0, "recovered_in_bytes": 250000, "percent": "25.0%"},
+          "files": {"total": 40, "reused": 0, "recovered": 12, "percent": "30.0%"},
+          "total_time_in_millis": 9800,
+          "source_throttle_time_in_millis": 0,
+          "target_throttle_time_in_millis": 0
+        },
+        "translog": {"recovered": 0, "total": -1, "percent": "-1.0%", "total_on_start": -1, "total_time_in_millis": 0},
+        "verify_index": {"check_index_time_in_millis": 0, "total_time_in_millis": 0}
+      },
+      {
+        "id": 1,
+        "type": "EXISTING_STORE",
+        "stage": "DONE",
+        "primary": true,
+        "start_time_in_millis": 1699999000000,
+        "stop_time_in_millis": 1699999000500,
+        "total_time_in_millis": 500,
+        "source": {"bootstrap_new_history_uuid": false},
+        "target": {"id": "Yx3kT1", "host": "10.0.0.1", "transport_address": "10.0.0.1:9300", "ip": "10.0.0.1", "name": "es-node-1"},
+        "index": {
+          "size": {"total_in_bytes": 2000000, "reused_in_bytes": 2000000, "recovered_in_bytes": 0, "percent": "100.0%"},
+          "files": {"total": 52, "reused": 52, "recovered": 0, "percent": "100.0%"},
+          "total_time_in_millis": 3,
+          "source_throttle_time_in_millis": 0,
+          "target_throttle_time_in_millis": 0
+        },
+        "translog": {"recovered": 0, "total": 0, "percent": "100.0%", "total_on_start": 0, "total_time_in_millis": 120},
+        "verify_index": {"check_index_time_in_millis": 0, "total_time_in_millis": 0}
+      }
+    ]
+  },
+  "metrics-000001": {
+    "shards": [
+      {
+        "id": 0,
+        "type": "PEER",
+        "stage": "TRANSLOG",
+        "primary": false,
+        "start_time_in_millis": 1699999990000,
+        "total_time_in_millis": 20000,
+        "source": {"id": "Qm8pR2", "host": "10.0.0.2", "transport_address": "10.0.0.2:9300", "ip": "10.0.0.2", "name": "es-node-2"},
+        "target": {"id": "Zt5nW3", "host": "10.0.0.3", "transport_address": "10.0.0.3:9300", "ip": "10.0.0.3", "name": "es-node-3"},
+        "index": {
+          "size": {"total_in_bytes": 4000000, "reused_in_bytes": 0, "recovered_in_bytes": 4000000, "percent": "100.0%"},
+          "files": {"total": 18, "reused": 0, "recovered": 18, "percent": "100.0%"},
+          "total_time_in_millis": 18000,
+          "source_throttle_time_in_millis": 0,
+          "target_throttle_time_in_millis": 0
+        },
+        "translog": {"recovered": 60, "total": 100, "percent": "60.0%", "total_on_start": 100, "total_time_in_millis": 2000},
+        "verify_index": {"check_index_time_in_millis": 0, "total_time_in_millis": 0}
+      }
+    ]
+  }
+}`
+
+func TestGetRecoveryStatus(t *testing.T) {
+	var activeOnly string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("X-Elastic-Product", "Elasticsearch")
+		w.Header().Set("Content-Type", "application/json")
+		if r.URL.Path != "/_recovery" {
+			w.Write([]byte(`{}`))
+			return
+		}
+		activeOnly = r.URL.Query().Get("active_only")
+		w.Write([]byte(recoveryAPIResponse))
+	}))
+	defer server.Close()
+
+	client, err := shared.NewESClient(&shared.ESConfig{URLs: []string{server.URL}}, zap.NewNop())
+	if err != nil {
+		t.Fatalf("Failed to create client: %v", err)
+	}
+	service := NewClusterService(client, zap.NewNop())
+
+	status, err := service.GetRecoveryStatus(context.Background(), "", false)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if activeOnly != "false" {
+		t.Errorf("Expected active_only=false, got %q", activeOnly)
+	}
+
+	if status.ActiveShards != 2 || status.CompletedShards != 1 || status.Done {
+		t.Errorf("Expected 2 active and 1 completed shard, got %d active and %d completed (done %v)", status.ActiveShards, status.CompletedShards, status.Done)
+	}
+	if status.BytesTotal != 7000000 || status.BytesRecovered != 4250000 {
+		t.Errorf("Expected 4250000 of 7000000 bytes, got %d of %d", status.BytesRecovered, status.BytesTotal)
+	}
+	if math.Abs(status.BytesPercent-60.714) > 0.01 {
+		t.Errorf("Expected about 60.71%% of bytes recovered, got %.2f", status.BytesPercent)
+	}
+	// 250kB copied in 10s leaves 750kB at 25kB/s
+	if status.EstimatedTimeRemaining != 30*time.Second {
+		t.Errorf("Expected the slowest shard's 30s estimate, got %v", status.EstimatedTimeRemaining)
+	}
+
+	if len(status.Shards) != 3 {
+		t.Fatalf("Expected 3 shard recoveries, got %d", len(status.Shards))
+	}
+
+	// Most remaining work first, then by index and shard
+	copying := status.Shards[0]
+	if copying.Index != "logs-000001" || copying.Shard != 0 || copying.Primary || copying.Type != "PEER" || copying.Stage != "INDEX" {
+		t.Errorf("Expected the copying replica of logs-000001 first, got %+v", copying)
+	}
+	if copying.SourceNode != "es-node-1" || copying.TargetNode != "es-node-2" {
+		t.Errorf("Expected a copy from es-node-1 to es-node-2, got %s to %s", copying.SourceNode, copying.TargetNode)
+	}
+	if copying.FilesPercent != 30 || copying.BytesPercent != 25 || copying.TranslogPercent != 0 {
+		t.Errorf("Expected 30%% files, 25%% bytes and unknown translog progress as 0, got %+v", copying)
+	}
+	if copying.Elapsed != 10*time.Second || copying.EstimatedTimeRemaining != 30*time.Second {
+		t.Errorf("Expected 10s elapsed and 30s remaining, got %v and %v", copying.Elapsed, copying.EstimatedTimeRemaining)
+	}
+
+	existing := status.Shards[1]
+	if existing.Index != "logs-000001" || existing.Shard != 1 || !existing.Primary || existing.Stage != "DONE" || existing.SourceNode != "" {
+		t.Errorf("Expected the recovered primary of logs-000001 without a source node, got %+v", existing)
+	}
+	if existing.EstimatedTimeRemaining != 0 {
+		t.Errorf("Expected no estimate for a finished recovery, got %v", existing.EstimatedTimeRemaining)
+	}
+
+	replaying := status.Shards[2]
+	if replaying.Index != "metrics-000001" || replaying.Stage != "TRANSLOG" || replaying.TranslogPercent != 60 {
+		t.Errorf("Expected metrics-000001 replaying its translog at 60%%, got %+v", replaying)
+	}
+	if replaying.EstimatedTimeRemaining != 0 {
+		t.Errorf("Expected no estimate once every byte is copied, got %v", replaying.EstimatedTimeRemaining)
+	}
+}
+
+func TestParsePercent(t *testing.T) {
+	testCases := []struct {
+		value    string
+		expected float64
+	}{
+		{"42.5%", 42.5},
+		{"100.0%", 100},
+		{"0.0%", 0},
+		{"-1.0%", 0},
+		{"", 0},
+		{"n/a", 0},
+	}
+
+	for _, tc := range testCases {
+		if got := parsePercent(tc.value); got != tc.expected {
+			t.Errorf("Expected %q to parse as %g, got %g", tc.value, tc.expected, got)
+		}
+	}
+}
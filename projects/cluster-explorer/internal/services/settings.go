@@ -0,0 +1,326 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/saif-islam/es-playground/projects/cluster-explorer/internal/models"
+)
+
+// InvalidSettingError is returned when a cluster setting update names a setting that is
+// not on the allowlist or gives it a value of the wrong type
+type InvalidSettingError struct {
+	Key    string
+	Reason string
+}
+
+func (e *InvalidSettingError) Error() string {
+	return fmt.Sprintf("invalid cluster setting %s: %s", e.Key, e.Reason)
+}
+
+// settingRule checks a setting value, given as Elasticsearch renders it in a string
+type settingRule func(value string) error
+
+// Dynamic cluster settings that can be changed through this API. Elasticsearch quietly
+// accepts unknown transient settings, so anything else is rejected to catch typos.
+var clusterSettingRules = map[string]settingRule{
+	// Shard allocation and rebalancing
+	"cluster.routing.allocation.enable":                                  enumSetting("all", "primaries", "new_primaries", "none"),
+	"cluster.routing.rebalance.enable":                                   enumSetting("all", "primaries", "replicas", "none"),
+	"cluster.routing.allocation.allow_rebalance":                         enumSetting("always", "indices_primaries_active", "indices_all_active"),
+	"cluster.routing.allocation.cluster_concurrent_rebalance":            intSetting(-1),
+	"cluster.routing.allocation.node_concurrent_recoveries":              intSetting(0),
+	"cluster.routing.allocation.node_concurrent_incoming_recoveries":     intSetting(0),
+	"cluster.routing.allocation.node_concurrent_outgoing_recoveries":     intSetting(0),
+	"cluster.routing.allocation.node_initial_primaries_recoveries":       intSetting(0),
+	"cluster.routing.allocation.total_shards_per_node":                   intSetting(-1),
+	"cluster.routing.allocation.awareness.attributes":                    stringSetting,
+	"cluster.max_shards_per_node":                                        intSetting(1),
+	"cluster.routing.allocation.disk.threshold_enabled":                  boolSetting,
+	"cluster.routing.allocation.disk.watermark.low":                      watermarkSetting,
+	"cluster.routing.allocation.disk.watermark.low.max_headroom":         headroomSetting,
+	"cluster.routing.allocation.disk.watermark.high":                     watermarkSetting,
+	"cluster.routing.allocation.disk.watermark.high.max_headroom":        headroomSetting,
+	"cluster.routing.allocation.disk.watermark.flood_stage":              watermarkSetting,
+	"cluster.routing.allocation.disk.watermark.flood_stage.max_headroom": headroomSetting,
+	"cluster.info.update.interval":                                       timeSetting,
+
+	// Recovery
+	"indices.recovery.max_bytes_per_sec":          byteSizeSetting,
+	"indices.recovery.max_concurrent_file_chunks": intSetting(1),
+
+	// Circuit breakers
+	"indices.breaker.total.limit":     percentOrBytesSetting,
+	"indices.breaker.request.limit":   percentOrBytesSetting,
+	"indices.breaker.fielddata.limit": percentOrBytesSetting,
+
+	// Search and indexing
+	"search.default_search_timeout":   timeSetting,
+	"search.max_buckets":              intSetting(0),
+	"action.auto_create_index":        stringSetting,
+	"indices.lifecycle.poll_interval": timeSetting,
+
+	// Safety
+	"action.destructive_requires_name":      boolSetting,
+	"cluster.blocks.read_only":              boolSetting,
+	"cluster.blocks.read_only_allow_delete": boolSetting,
+}
+
+// Setting families allowed under a key prefix, such as allocation filters by node attribute
+var clusterSettingPrefixRules = []struct {
+	prefix string
+	rule   settingRule
+}{
+	{"cluster.routing.allocation.exclude.", stringSetting},
+	{"cluster.routing.allocation.include.", stringSetting},
+	{"cluster.routing.allocation.require.", stringSetting},
+	{"logger.", enumSetting("trace", "debug", "info", "warn", "error", "fatal", "off")},
+}
+
+// timeValuePattern matches Elasticsearch time values such as "30s" or "500ms"
+var timeValuePattern = regexp.MustCompile(`^\d+(nanos|micros|ms|s|m|h|d)$`)
+
+func boolSetting(value string) error {
+	if value != "true" && value != "false" {
+		return fmt.Errorf("must be true or false, got %q", value)
+	}
+	return nil
+}
+
+func intSetting(min int64) settingRule {
+	return func(value string) error {
+		n, err := strconv.ParseInt(value, 10, 64)
+		if err != nil {
+			return fmt.Errorf("must be an integer, got %q", value)
+		}
+		if n < min {
+			return fmt.Errorf("must be at least %d, got %d", min, n)
+		}
+		return nil
+	}
+}
+
+func enumSetting(allowed ...string) settingRule {
+	return func(value string) error {
+		for _, candidate := range allowed {
+			if strings.EqualFold(value, candidate) {
+				return nil
+			}
+		}
+		return fmt.Errorf("must be one of %s, got %q", strings.Join(allowed, ", "), value)
+	}
+}
+
+func stringSetting(value string) error {
+	if strings.TrimSpace(value) == "" {
+		return fmt.Errorf("must not be empty")
+	}
+	return nil
+}
+
+func timeSetting(value string) error {
+	if value != "-1" && value != "0" && !timeValuePattern.MatchString(value) {
+		return fmt.Errorf("must be a time value such as 30s or 5m, got %q", value)
+	}
+	return nil
+}
+
+func byteSizeSetting(value string) error {
+	if value == "-1" || value == "0" {
+		return nil
+	}
+	_, err := parseByteSize(value)
+	return err
+}
+
+func headroomSetting(value string) error {
+	if err := byteSizeSetting(value); err != nil {
+		return fmt.Errorf("must be a byte size such as 100gb, or -1: %w", err)
+	}
+	return nil
+}
+
+// percentOrBytesSetting accepts a percentage ("70%") or a byte size ("2gb")
+func percentOrBytesSetting(value string) error {
+	if percent, found := strings.CutSuffix(value, "%"); found {
+		if p, err := strconv.ParseFloat(percent, 64); err != nil || p < 0 || p > 100 {
+			return fmt.Errorf("must be a percentage between 0%% and 100%%, got %q", value)
+		}
+		return nil
+	}
+	if _, err := parseByteSize(value); err != nil {
+		return fmt.Errorf("must be a percentage or a byte size, got %q", value)
+	}
+	return nil
+}
+
+func watermarkSetting(value string) error {
+	if _, err := parseWatermark("", value, ""); err != nil {
+		return fmt.Errorf("must be a percentage, a ratio or a byte size of free space, got %q", value)
+	}
+	return nil
+}
+
+// ValidateClusterSettings checks cluster setting updates against the allowlist and
+// returns them flattened to dotted keys. Settings may be given nested or dotted, and a
+// null value resets a setting to its default.
+func ValidateClusterSettings(settings map[string]interface{}) (map[string]interface{}, error) {
+	flat := make(map[string]interface{})
+	flattenSettings("", settings, flat)
+	if len(flat) == 0 {
+		return nil, &InvalidSettingError{Key: "settings", Reason: "no settings given"}
+	}
+
+	// Sorted so the same bad update always reports the same key
+	keys := make([]string, 0, len(flat))
+	for key := range flat {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		value := flat[key]
+		rule, ok := clusterSettingRule(key)
+		if !ok {
+			return nil, &InvalidSettingError{Key: key, Reason: "not a known dynamic cluster setting that can be changed through this API"}
+		}
+		if value == nil {
+			continue
+		}
+
+		text, ok := settingString(value)
+		if !ok {
+			return nil, &InvalidSettingError{Key: key, Reason: fmt.Sprintf("unsupported value type %T", value)}
+		}
+		if err := rule(text); err != nil {
+			return nil, &InvalidSettingError{Key: key, Reason: err.Error()}
+		}
+	}
+	return flat, nil
+}
+
+// clusterSettingRule returns the rule for an allowlisted setting key
+func clusterSettingRule(key string) (settingRule, bool) {
+	if rule, ok := clusterSettingRules[key]; ok {
+		return rule, true
+	}
+	for _, prefixed := range clusterSettingPrefixRules {
+		if strings.HasPrefix(key, prefixed.prefix) && len(key) > len(prefixed.prefix) {
+			return prefixed.rule, true
+		}
+	}
+	return nil, false
+}
+
+// flattenSettings copies a nested settings tree into flat under dotted keys
+func flattenSettings(prefix string, settings map[string]interface{}, flat map[string]interface{}) {
+	for key, value := range settings {
+		if prefix != "" {
+			key = prefix + "." + key
+		}
+		if child, ok := value.(map[string]interface{}); ok {
+			flattenSettings(key, child, flat)
+			continue
+		}
+		flat[key] = value
+	}
+}
+
+// settingString renders a JSON setting value the way Elasticsearch reports it
+func settingString(value interface{}) (string, bool) {
+	switch v := value.(type) {
+	case string:
+		return v, true
+	case bool:
+		return strconv.FormatBool(v), true
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64), true
+	}
+	return "", false
+}
+
+// PreviewClusterSettings validates cluster setting updates and reports what each would
+// change, without applying them
+func (s *ClusterService) PreviewClusterSettings(ctx context.Context, settings map[string]interface{}, persistent bool) ([]models.SettingChange, error) {
+	flat, err := ValidateClusterSettings(settings)
+	if err != nil {
+		return nil, err
+	}
+
+	current, err := s.GetClusterSettings(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get current cluster settings: %w", err)
+	}
+
+	changes := make([]models.SettingChange, 0, len(flat))
+	for key, value := range flat {
+		change := models.SettingChange{Key: key, New: value}
+		change.Current, change.Source = effectiveSetting(current, key)
+
+		// A null resets the setting at this level, falling back to the other level or the default
+		if value == nil {
+			change.Changed = change.Source == levelName(persistent)
+		} else {
+			text, _ := settingString(value)
+			change.Changed = change.Current == nil || fmt.Sprint(change.Current) != text
+		}
+		if persistent {
+			if group, ok := current["transient"].(map[string]interface{}); ok {
+				_, change.OverriddenByTransient = lookupSetting(group, key)
+				if change.OverriddenByTransient {
+					change.Changed = false
+				}
+			}
+		}
+
+		changes = append(changes, change)
+	}
+
+	sort.Slice(changes, func(i, j int) bool { return changes[i].Key < changes[j].Key })
+	return changes, nil
+}
+
+func levelName(persistent bool) string {
+	if persistent {
+		return "persistent"
+	}
+	return "transient"
+}
+
+// effectiveSetting returns a setting's current value from settings fetched with their
+// defaults, and where it comes from: transient, persistent or defaults
+func effectiveSetting(settings map[string]interface{}, key string) (interface{}, string) {
+	for _, source := range []string{"transient", "persistent", "defaults"} {
+		if group, ok := settings[source].(map[string]interface{}); ok {
+			if value, ok := lookupSetting(group, key); ok {
+				return value, source
+			}
+		}
+	}
+	return nil, ""
+}
+
+// lookupSetting looks up a dotted setting key in a settings tree, which Elasticsearch
+// nests by key segment except where a key is also the prefix of another
+// (watermark.flood_stage and watermark.flood_stage.frozen, for instance)
+func lookupSetting(settings map[string]interface{}, key string) (interface{}, bool) {
+	if value, ok := settings[key]; ok {
+		return value, true
+	}
+
+	for i := range key {
+		if key[i] != '.' {
+			continue
+		}
+		if child, ok := settings[key[:i]].(map[string]interface{}); ok {
+			if value, ok := lookupSetting(child, key[i+1:]); ok {
+				return value, true
+			}
+		}
+	}
+	return nil, false
+}
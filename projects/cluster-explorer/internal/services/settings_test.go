@@ -0,0 +1,280 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"go.uber.org/zap"
+
+	"github.com/saif-islam/es-playground/shared"
+)
+
+func TestValidateClusterSettings(t *testing.T) {
+	testCases := []struct {
+		name        string
+		settings    map[string]interface{}
+		expectedKey string // key reported by the error, empty when the update is valid
+	}{
+		// Allowed keys
+		{
+			name:     "allocation enable",
+			settings: map[string]interface{}{"cluster.routing.allocation.enable": "primaries"},
+		},
+		{
+			name:     "enum is case insensitive",
+			settings: map[string]interface{}{"cluster.routing.rebalance.enable": "NONE"},
+		},
+		{
+			name:     "integer from JSON number",
+			settings: map[string]interface{}{"cluster.routing.allocation.node_concurrent_recoveries": float64(4)},
+		},
+		{
+			name:     "unlimited total shards per node",
+			settings: map[string]interface{}{"cluster.routing.allocation.total_shards_per_node": "-1"},
+		},
+		{
+			name:     "boolean from JSON bool",
+			settings: map[string]interface{}{"action.destructive_requires_name": true},
+		},
+		{
+			name: "watermarks as percentage, ratio and free space",
+			settings: map[string]interface{}{
+				"cluster.routing.allocation.disk.watermark.low":         "85%",
+				"cluster.routing.allocation.disk.watermark.high":        "0.9",
+				"cluster.routing.allocation.disk.watermark.flood_stage": "5gb",
+			},
+		},
+		{
+			name:     "breaker limit as byte size",
+			settings: map[string]interface{}{"indices.breaker.request.limit": "2gb"},
+		},
+		{
+			name:     "recovery throttle",
+			settings: map[string]interface{}{"indices.recovery.max_bytes_per_sec": "100mb"},
+		},
+		{
+			name:     "time value",
+			settings: map[string]interface{}{"search.default_search_timeout": "30s"},
+		},
+		{
+			name: "nested settings",
+			settings: map[string]interface{}{
+				"cluster": map[string]interface{}{
+					"routing": map[string]interface{}{"allocation": map[string]interface{}{"enable": "all"}},
+				},
+			},
+		},
+		{
+			name:     "null resets to the default",
+			settings: map[string]interface{}{"cluster.max_shards_per_node": nil},
+		},
+
+		// Denied keys and values
+		{
+			name:        "no settings",
+			settings:    map[string]interface{}{},
+			expectedKey: "settings",
+		},
+		{
+			name:        "typo in a key",
+			settings:    map[string]interface{}{"cluster.routing.allocation.enabled": "all"},
+			expectedKey: "cluster.routing.allocation.enabled",
+		},
+		{
+			name:        "static setting",
+			settings:    map[string]interface{}{"cluster.name": "prod"},
+			expectedKey: "cluster.name",
+		},
+		{
+			name:        "index setting",
+			settings:    map[string]interface{}{"index.number_of_replicas": "1"},
+			expectedKey: "index.number_of_replicas",
+		},
+		{
+			name:        "value outside the enum",
+			settings:    map[string]interface{}{"cluster.routing.allocation.enable": "some"},
+			expectedKey: "cluster.routing.allocation.enable",
+		},
+		{
+			name:        "integer below the minimum",
+			settings:    map[string]interface{}{"cluster.max_shards_per_node": float64(0)},
+			expectedKey: "cluster.max_shards_per_node",
+		},
+		{
+			name:        "fractional integer",
+			settings:    map[string]interface{}{"search.max_buckets": float64(1.5)},
+			expectedKey: "search.max_buckets",
+		},
+		{
+			name:        "percentage over 100",
+			settings:    map[string]interface{}{"indices.breaker.total.limit": "120%"},
+			expectedKey: "indices.breaker.total.limit",
+		},
+		{
+			name:        "byte size without a unit",
+			settings:    map[string]interface{}{"indices.recovery.max_bytes_per_sec": "100"},
+			expectedKey: "indices.recovery.max_bytes_per_sec",
+		},
+		{
+			name:        "time value without a unit",
+			settings:    map[string]interface{}{"cluster.info.update.interval": "30"},
+			expectedKey: "cluster.info.update.interval",
+		},
+		{
+			name:        "empty string",
+			settings:    map[string]interface{}{"action.auto_create_index": " "},
+			expectedKey: "action.auto_create_index",
+		},
+		{
+			name:        "unsupported value type",
+			settings:    map[string]interface{}{"cluster.routing.allocation.awareness.attributes": []interface{}{"zone"}},
+			expectedKey: "cluster.routing.allocation.awareness.attributes",
+		},
+		{
+			name: "first bad key in sorted order",
+			settings: map[string]interface{}{
+				"search.max_buckets":                 "many",
+				"cluster.routing.allocation.enable":  "all",
+				"indices.breaker.fielddata.limit":    "lots",
+				"cluster.routing.allocation.enabled": "all",
+			},
+			expectedKey: "cluster.routing.allocation.enabled",
+		},
+
+		// Prefix edge cases
+		{
+			name:     "allocation filter by node attribute",
+			settings: map[string]interface{}{"cluster.routing.allocation.exclude._name": "node-1,node-2"},
+		},
+		{
+			name:     "nested allocation filter",
+			settings: map[string]interface{}{"cluster": map[string]interface{}{"routing": map[string]interface{}{"allocation": map[string]interface{}{"require": map[string]interface{}{"data": "hot"}}}}},
+		},
+		{
+			name:     "logger for a package",
+			settings: map[string]interface{}{"logger.org.elasticsearch.discovery": "DEBUG"},
+		},
+		{
+			name:        "bare prefix",
+			settings:    map[string]interface{}{"cluster.routing.allocation.include.": "hot"},
+			expectedKey: "cluster.routing.allocation.include.",
+		},
+		{
+			name:        "bare logger prefix",
+			settings:    map[string]interface{}{"logger.": "debug"},
+			expectedKey: "logger.",
+		},
+		{
+			name:        "prefix without its dot",
+			settings:    map[string]interface{}{"cluster.routing.allocation.excluded": "node-1"},
+			expectedKey: "cluster.routing.allocation.excluded",
+		},
+		{
+			name:        "prefix in the middle of a key",
+			settings:    map[string]interface{}{"x.logger.org.elasticsearch": "debug"},
+			expectedKey: "x.logger.org.elasticsearch",
+		},
+		{
+			name:        "empty allocation filter",
+			settings:    map[string]interface{}{"cluster.routing.allocation.require.zone": ""},
+			expectedKey: "cluster.routing.allocation.require.zone",
+		},
+		{
+			name:        "unknown log level",
+			settings:    map[string]interface{}{"logger.org.elasticsearch.discovery": "verbose"},
+			expectedKey: "logger.org.elasticsearch.discovery",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			flat, err := ValidateClusterSettings(tc.settings)
+
+			if tc.expectedKey == "" {
+				if err != nil {
+					t.Fatalf("Expected the update to be valid, got %v", err)
+				}
+				if len(flat) == 0 {
+					t.Errorf("Expected flattened settings, got none")
+				}
+				return
+			}
+
+			var settingErr *InvalidSettingError
+			if !errors.As(err, &settingErr) {
+				t.Fatalf("Expected an InvalidSettingError, got %v", err)
+			}
+			if settingErr.Key != tc.expectedKey {
+				t.Errorf("Expected the error to name %s, got %s (%v)", tc.expectedKey, settingErr.Key, err)
+			}
+		})
+	}
+}
+
+func TestValidateClusterSettings_Flattens(t *testing.T) {
+	flat, err := ValidateClusterSettings(map[string]interface{}{
+		"cluster": map[string]interface{}{
+			"routing": map[string]interface{}{
+				"allocation": map[string]interface{}{"enable": "primaries"},
+			},
+			"max_shards_per_node": nil,
+		},
+		"indices.recovery.max_bytes_per_sec": "50mb",
+	})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	expected := map[string]interface{}{
+		"cluster.routing.allocation.enable":  "primaries",
+		"cluster.max_shards_per_node":        nil,
+		"indices.recovery.max_bytes_per_sec": "50mb",
+	}
+	if len(flat) != len(expected) {
+		t.Fatalf("Expected %d settings, got %v", len(expected), flat)
+	}
+	for key, value := range expected {
+		if got, ok := flat[key]; !ok || got != value {
+			t.Errorf("Expected %s = %v, got %v", key, value, got)
+		}
+	}
+}
+
+func TestUpdateClusterSettings(t *testing.T) {
+	var requests []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("X-Elastic-Product", "Elasticsearch")
+		w.Header().Set("Content-Type", "application/json")
+		if r.URL.Path != "/_cluster/settings" {
+			w.Write([]byte(`{}`))
+			return
+		}
+		body, _ := io.ReadAll(r.Body)
+		requests = append(requests, r.Method+" "+string(body))
+		w.Write([]byte(`{"acknowledged":true,"persistent":{},"transient":{}}`))
+	}))
+	defer server.Close()
+
+	client, err := shared.NewESClient(&shared.ESConfig{URLs: []string{server.URL}}, zap.NewNop())
+	if err != nil {
+		t.Fatalf("Failed to create client: %v", err)
+	}
+	service := NewClusterService(client, zap.NewNop())
+
+	settings := map[string]interface{}{"cluster": map[string]interface{}{"routing": map[string]interface{}{"allocation": map[string]interface{}{"enable": "primaries"}}}}
+	if err := service.UpdateClusterSettings(context.Background(), settings, true); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if err := service.UpdateClusterSettings(context.Background(), map[string]interface{}{"cluster.name": "prod"}, false); err == nil {
+		t.Error("Expected a setting outside the allowlist to be rejected")
+	}
+
+	expected := `PUT {"persistent":{"cluster.routing.allocation.enable":"primaries"}}`
+	if len(requests) != 1 || requests[0] != expected {
+		t.Errorf("Expected only the valid update to reach Elasticsearch as %s, got %v", expected, requests)
+	}
+}
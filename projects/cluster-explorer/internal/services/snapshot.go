@@ -0,0 +1,328 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/elastic/go-elasticsearch/v8/esapi"
+	"go.uber.org/zap"
+
+	"github.com/saif-islam/es-playground/projects/cluster-explorer/internal/models"
+	"github.com/saif-islam/es-playground/shared"
+)
+
+// snapshotResponse is a snapshot as reported by the snapshot APIs
+type snapshotResponse struct {
+	Snapshot          string   `json:"snapshot"`
+	UUID              string   `json:"uuid"`
+	State             string   `json:"state"`
+	Indices           []string `json:"indices"`
+	StartTimeInMillis int64    `json:"start_time_in_millis"`
+	EndTimeInMillis   int64    `json:"end_time_in_millis"`
+	DurationInMillis  int64    `json:"duration_in_millis"`
+	Shards            struct {
+		Total      int `json:"total"`
+		Successful int `json:"successful"`
+		Failed     int `json:"failed"`
+	} `json:"shards"`
+	Failures []struct {
+		Index   string `json:"index"`
+		ShardID int    `json:"shard_id"`
+		Reason  string `json:"reason"`
+	} `json:"failures"`
+}
+
+// snapshotInfo converts a snapshot API entry to the snapshot model
+func (r snapshotResponse) snapshotInfo(repository string) models.SnapshotInfo {
+	info := models.SnapshotInfo{
+		Repository: repository,
+		Snapshot:   r.Snapshot,
+		UUID:       r.UUID,
+		State:      r.State,
+		Indices:    r.Indices,
+		Duration:   time.Duration(r.DurationInMillis) * time.Millisecond,
+		Shards: models.SnapshotShards{
+			Total:      r.Shards.Total,
+			Successful: r.Shards.Successful,
+			Failed:     r.Shards.Failed,
+		},
+	}
+	if r.StartTimeInMillis > 0 {
+		info.StartTime = time.UnixMilli(r.StartTimeInMillis)
+	}
+	if r.EndTimeInMillis > 0 {
+		info.EndTime = time.UnixMilli(r.EndTimeInMillis)
+	}
+	for _, failure := range r.Failures {
+		info.Failures = append(info.Failures, fmt.Sprintf("%s[%d]: %s", failure.Index, failure.ShardID, failure.Reason))
+	}
+	return info
+}
+
+// RegisterSnapshotRepository registers or updates a snapshot repository. Unless verification
+// is turned off, Elasticsearch checks that every node can write to it.
+func (s *ClusterService) RegisterSnapshotRepository(ctx context.Context, repository string, req *models.SnapshotRepositoryRequest) error {
+	bodyBytes, err := json.Marshal(map[string]interface{}{
+		"type":     req.Type,
+		"settings": req.Settings,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to marshal repository: %w", err)
+	}
+
+	opts := []func(*esapi.SnapshotCreateRepositoryRequest){
+		s.esClient.Snapshot.CreateRepository.WithContext(ctx),
+	}
+	if req.Verify != nil {
+		opts = append(opts, s.esClient.Snapshot.CreateRepository.WithVerify(*req.Verify))
+	}
+
+	res, err := s.esClient.Snapshot.CreateRepository(repository, strings.NewReader(string(bodyBytes)), opts...)
+	if err != nil {
+		return fmt.Errorf("repository request failed: %w", err)
+	}
+	defer res.Body.Close()
+
+	if res.IsError() {
+		return shared.ParseESError(res)
+	}
+
+	s.logger.Info("Registered snapshot repository",
+		zap.String("repository", repository),
+		zap.String("type", req.Type))
+
+	return nil
+}
+
+// CreateSnapshot snapshots the requested indices, or all of them. When wait is false it
+// returns as soon as the snapshot has started, in state IN_PROGRESS; poll GetSnapshot for
+// its final state.
+func (s *ClusterService) CreateSnapshot(ctx context.Context, repository, snapshot string, req *models.SnapshotRequest, wait bool) (*models.SnapshotInfo, error) {
+	body := map[string]interface{}{}
+	if len(req.Indices) > 0 {
+		body["indices"] = strings.Join(req.Indices, ",")
+	}
+	if req.IgnoreUnavailable {
+		body["ignore_unavailable"] = true
+	}
+	if req.IncludeGlobalState != nil {
+		body["include_global_state"] = *req.IncludeGlobalState
+	}
+	if req.Partial {
+		body["partial"] = true
+	}
+	if req.Metadata != nil {
+		body["metadata"] = req.Metadata
+	}
+
+	bodyBytes, err := json.Marshal(body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal snapshot request: %w", err)
+	}
+
+	// A blocking snapshot outlasts the per-request timeout; ctx alone bounds it
+	if wait {
+		ctx = shared.WithRequestTimeout(ctx, 0)
+	}
+
+	res, err := s.esClient.Snapshot.Create(
+		repository,
+		snapshot,
+		s.esClient.Snapshot.Create.WithContext(ctx),
+		s.esClient.Snapshot.Create.WithBody(strings.NewReader(string(bodyBytes))),
+		s.esClient.Snapshot.Create.WithWaitForCompletion(wait),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("snapshot request failed: %w", err)
+	}
+	defer res.Body.Close()
+
+	if res.IsError() {
+		return nil, shared.ParseESError(res)
+	}
+
+	var response struct {
+		Accepted bool              `json:"accepted"`
+		Snapshot *snapshotResponse `json:"snapshot"`
+	}
+	if err := shared.DecodeJSONResponse(res, &response); err != nil {
+		return nil, fmt.Errorf("failed to decode snapshot response: %w", err)
+	}
+
+	info := models.SnapshotInfo{
+		Repository: repository,
+		Snapshot:   snapshot,
+		State:      "IN_PROGRESS",
+		Indices:    req.Indices,
+	}
+	if response.Snapshot != nil {
+		info = response.Snapshot.snapshotInfo(repository)
+	}
+
+	s.logger.Info("Created snapshot",
+		zap.String("repository", repository),
+		zap.String("snapshot", snapshot),
+		zap.String("state", info.State))
+
+	return &info, nil
+}
+
+// ListSnapshots lists the snapshots of a repository, newest first
+func (s *ClusterService) ListSnapshots(ctx context.Context, repository string) ([]models.SnapshotInfo, error) {
+	snapshots, _, err := s.getSnapshots(ctx, repository, "_all")
+	return snapshots, err
+}
+
+// GetSnapshot returns a snapshot with its current state, reporting false if it does not exist
+func (s *ClusterService) GetSnapshot(ctx context.Context, repository, snapshot string) (*models.SnapshotInfo, bool, error) {
+	snapshots, found, err := s.getSnapshots(ctx, repository, snapshot)
+	if err != nil || !found || len(snapshots) == 0 {
+		return nil, false, err
+	}
+	return &snapshots[0], true, nil
+}
+
+func (s *ClusterService) getSnapshots(ctx context.Context, repository, snapshot string) ([]models.SnapshotInfo, bool, error) {
+	res, err := s.esClient.Snapshot.Get(
+		repository,
+		[]string{snapshot},
+		s.esClient.Snapshot.Get.WithContext(ctx),
+	)
+	if err != nil {
+		return nil, false, fmt.Errorf("get snapshots request failed: %w", err)
+	}
+	defer res.Body.Close()
+
+	// A missing snapshot is a 404; a missing repository is reported as an error
+	if res.StatusCode == http.StatusNotFound && snapshot != "_all" {
+		var missing struct {
+			Error struct {
+				Type string `json:"type"`
+			} `json:"error"`
+		}
+		if err := shared.DecodeJSONResponse(res, &missing); err == nil && missing.Error.Type == "snapshot_missing_exception" {
+			return nil, false, nil
+		}
+		return nil, false, fmt.Errorf("snapshot repository %s not found", repository)
+	}
+
+	if res.IsError() {
+		return nil, false, shared.ParseESError(res)
+	}
+
+	var response struct {
+		Snapshots []snapshotResponse `json:"snapshots"`
+	}
+	if err := shared.DecodeJSONResponse(res, &response); err != nil {
+		return nil, false, fmt.Errorf("failed to decode snapshots: %w", err)
+	}
+
+	snapshots := make([]models.SnapshotInfo, 0, len(response.Snapshots))
+	for _, entry := range response.Snapshots {
+		snapshots = append(snapshots, entry.snapshotInfo(repository))
+	}
+	sort.Slice(snapshots, func(i, j int) bool {
+		return snapshots[i].StartTime.After(snapshots[j].StartTime)
+	})
+
+	return snapshots, true, nil
+}
+
+// RestoreSnapshot restores indices from a snapshot. Open indices of the same name must be
+// closed or deleted first, or restored under new names with a rename pattern. When wait is
+// false it returns once the restore is accepted; the recovery API reports its progress.
+func (s *ClusterService) RestoreSnapshot(ctx context.Context, repository, snapshot string, req *models.RestoreRequest, wait bool) (*models.RestoreResult, error) {
+	if (req.RenamePattern == "") != (req.RenameReplacement == "") {
+		return nil, fmt.Errorf("rename_pattern and rename_replacement must be set together")
+	}
+
+	body := map[string]interface{}{}
+	if len(req.Indices) > 0 {
+		body["indices"] = strings.Join(req.Indices, ",")
+	}
+	if req.IgnoreUnavailable {
+		body["ignore_unavailable"] = true
+	}
+	if req.IncludeGlobalState {
+		body["include_global_state"] = true
+	}
+	if req.IncludeAliases != nil {
+		body["include_aliases"] = *req.IncludeAliases
+	}
+	if req.RenamePattern != "" {
+		body["rename_pattern"] = req.RenamePattern
+		body["rename_replacement"] = req.RenameReplacement
+	}
+	if req.IndexSettings != nil {
+		body["index_settings"] = req.IndexSettings
+	}
+
+	bodyBytes, err := json.Marshal(body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal restore request: %w", err)
+	}
+
+	// A blocking restore outlasts the per-request timeout; ctx alone bounds it
+	if wait {
+		ctx = shared.WithRequestTimeout(ctx, 0)
+	}
+
+	res, err := s.esClient.Snapshot.Restore(
+		repository,
+		snapshot,
+		s.esClient.Snapshot.Restore.WithContext(ctx),
+		s.esClient.Snapshot.Restore.WithBody(strings.NewReader(string(bodyBytes))),
+		s.esClient.Snapshot.Restore.WithWaitForCompletion(wait),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("restore request failed: %w", err)
+	}
+	defer res.Body.Close()
+
+	if res.IsError() {
+		return nil, shared.ParseESError(res)
+	}
+
+	var response struct {
+		Accepted bool `json:"accepted"`
+		Snapshot *struct {
+			Indices []string `json:"indices"`
+			Shards  struct {
+				Total      int `json:"total"`
+				Successful int `json:"successful"`
+				Failed     int `json:"failed"`
+			} `json:"shards"`
+		} `json:"snapshot"`
+	}
+	if err := shared.DecodeJSONResponse(res, &response); err != nil {
+		return nil, fmt.Errorf("failed to decode restore response: %w", err)
+	}
+
+	result := &models.RestoreResult{
+		Repository: repository,
+		Snapshot:   snapshot,
+		Accepted:   response.Accepted || response.Snapshot != nil,
+		Completed:  response.Snapshot != nil,
+	}
+	if response.Snapshot != nil {
+		result.Indices = response.Snapshot.Indices
+		result.Shards = models.SnapshotShards{
+			Total:      response.Snapshot.Shards.Total,
+			Successful: response.Snapshot.Shards.Successful,
+			Failed:     response.Snapshot.Shards.Failed,
+		}
+	}
+
+	s.logger.Info("Restored snapshot",
+		zap.String("repository", repository),
+		zap.String("snapshot", snapshot),
+		zap.Bool("completed", result.Completed),
+		zap.Strings("indices", result.Indices))
+
+	return result, nil
+}
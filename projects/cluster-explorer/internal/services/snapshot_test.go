@@ -0,0 +1,241 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"reflect"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/saif-islam/es-playground/projects/cluster-explorer/internal/models"
+)
+
+// snapshotsAPIResponse follows GET /_snapshot/backups/_all: a partial nightly snapshot
+// followed by one still running
+const snapshotsAPIResponse = `{
+  "snapshots": [
+    {
+      "snapshot": "nightly-2023.11.13",
+      "uuid": "dKb54xw67gvdRctLCxSket",
+      "repository": "backups",
+      "version_id": 8110199,
+      "version": "8.11.1",
+      "indices": ["logs-000001", "metrics-000001"],
+      "data_streams": [],
+      "include_global_state": true,
+      "state": "PARTIAL",
+      "start_time": "2023-11-13T22:13:20.000Z",
+      "start_time_in_millis": 1699913600000,
+      "end_time": "2023-11-13T22:14:20.000Z",
+      "end_time_in_millis": 1699913660000,
+      "duration_in_millis": 60000,
+      "failures": [{"index": "metrics-000001", "index_uuid": "abc", "shard_id": 1, "reason": "primary shard is not allocated", "status": "INTERNAL_SERVER_ERROR"}],
+      "shards": {"total": 4, "failed": 1, "successful": 3}
+    },
+    {
+      "snapshot": "nightly-2023.11.14",
+      "uuid": "v1Vh9jzaTUmhPgQGOq6qvQ",
+      "repository": "backups",
+      "indices": ["logs-000001"],
+      "state": "IN_PROGRESS",
+      "start_time_in_millis": 1700000000000,
+      "end_time_in_millis": 0,
+      "duration_in_millis": 0,
+      "failures": [],
+      "shards": {"total": 0, "failed": 0, "successful": 0}
+    }
+  ],
+  "total": 2,
+  "remaining": 0
+}`
+
+func TestRegisterSnapshotRepository(t *testing.T) {
+	var path, verify string
+	var body map[string]interface{}
+	service := newTestClusterService(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/" {
+			w.Write([]byte(`{"version":{"number":"8.11.1"}}`))
+			return
+		}
+		path = r.Method + " " + r.URL.Path
+		verify = r.URL.Query().Get("verify")
+		json.NewDecoder(r.Body).Decode(&body)
+		w.Write([]byte(`{"acknowledged":true}`))
+	})
+
+	noVerify := false
+	err := service.RegisterSnapshotRepository(context.Background(), "backups", &models.SnapshotRepositoryRequest{
+		Type:     "fs",
+		Settings: map[string]interface{}{"location": "/mnt/backups"},
+		Verify:   &noVerify,
+	})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if path != "PUT /_snapshot/backups" || verify != "false" {
+		t.Errorf("Expected PUT /_snapshot/backups with verify=false, got %s with verify=%q", path, verify)
+	}
+	expected := map[string]interface{}{"type": "fs", "settings": map[string]interface{}{"location": "/mnt/backups"}}
+	if !reflect.DeepEqual(body, expected) {
+		t.Errorf("Expected body %v, got %v", expected, body)
+	}
+}
+
+func TestCreateSnapshot(t *testing.T) {
+	testCases := []struct {
+		name          string
+		wait          bool
+		response      string
+		expectedState string
+	}{
+		{name: "started", response: `{"accepted":true}`, expectedState: "IN_PROGRESS"},
+		{
+			name:          "waited for",
+			wait:          true,
+			response:      `{"snapshot":{"snapshot":"nightly","uuid":"u1","state":"SUCCESS","indices":["logs-000001"],"start_time_in_millis":1700000000000,"end_time_in_millis":1700000030000,"duration_in_millis":30000,"failures":[],"shards":{"total":2,"failed":0,"successful":2}}}`,
+			expectedState: "SUCCESS",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			var path, waitParam, body string
+			service := newTestClusterService(t, func(w http.ResponseWriter, r *http.Request) {
+				if r.URL.Path == "/" {
+					w.Write([]byte(`{"version":{"number":"8.11.1"}}`))
+					return
+				}
+				payload, _ := io.ReadAll(r.Body)
+				path, waitParam, body = r.Method+" "+r.URL.Path, r.URL.Query().Get("wait_for_completion"), string(payload)
+				w.Write([]byte(tc.response))
+			})
+
+			includeGlobalState := false
+			info, err := service.CreateSnapshot(context.Background(), "backups", "nightly", &models.SnapshotRequest{
+				Indices:            []string{"logs-000001"},
+				IncludeGlobalState: &includeGlobalState,
+			}, tc.wait)
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+
+			if path != "PUT /_snapshot/backups/nightly" || waitParam != strconv.FormatBool(tc.wait) {
+				t.Errorf("Expected PUT /_snapshot/backups/nightly waiting %v, got %s with wait_for_completion=%q", tc.wait, path, waitParam)
+			}
+			if expected := `{"include_global_state":false,"indices":"logs-000001"}`; body != expected {
+				t.Errorf("Expected body %s, got %s", expected, body)
+			}
+			if info.State != tc.expectedState || info.Repository != "backups" || !reflect.DeepEqual(info.Indices, []string{"logs-000001"}) {
+				t.Errorf("Expected a %s snapshot of logs-000001, got %+v", tc.expectedState, info)
+			}
+			if tc.wait && (info.Duration != 30*time.Second || info.Shards.Successful != 2) {
+				t.Errorf("Expected the final duration and shard counts, got %+v", info)
+			}
+		})
+	}
+}
+
+func TestListSnapshots(t *testing.T) {
+	var path string
+	service := newTestClusterService(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/" {
+			w.Write([]byte(`{"version":{"number":"8.11.1"}}`))
+			return
+		}
+		path = r.URL.Path
+		w.Write([]byte(snapshotsAPIResponse))
+	})
+
+	snapshots, err := service.ListSnapshots(context.Background(), "backups")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if path != "/_snapshot/backups/_all" {
+		t.Errorf("Expected GET /_snapshot/backups/_all, got %s", path)
+	}
+	if len(snapshots) != 2 || snapshots[0].Snapshot != "nightly-2023.11.14" || snapshots[1].Snapshot != "nightly-2023.11.13" {
+		t.Fatalf("Expected the newest snapshot first, got %+v", snapshots)
+	}
+
+	running := snapshots[0]
+	if running.State != "IN_PROGRESS" || !running.EndTime.IsZero() {
+		t.Errorf("Expected the running snapshot without an end time, got %+v", running)
+	}
+
+	partial := snapshots[1]
+	if partial.State != "PARTIAL" || partial.Duration != time.Minute || partial.Shards.Failed != 1 {
+		t.Errorf("Expected a partial snapshot with one failed shard, got %+v", partial)
+	}
+	if !reflect.DeepEqual(partial.Failures, []string{"metrics-000001[1]: primary shard is not allocated"}) {
+		t.Errorf("Expected the shard failure, got %v", partial.Failures)
+	}
+}
+
+func TestGetSnapshot(t *testing.T) {
+	service := newTestClusterService(t, func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/":
+			w.Write([]byte(`{"version":{"number":"8.11.1"}}`))
+		case "/_snapshot/backups/missing":
+			w.WriteHeader(http.StatusNotFound)
+			w.Write([]byte(`{"error":{"root_cause":[{"type":"snapshot_missing_exception","reason":"[backups:missing] is missing"}],"type":"snapshot_missing_exception","reason":"[backups:missing] is missing"},"status":404}`))
+		case "/_snapshot/nowhere/nightly":
+			w.WriteHeader(http.StatusNotFound)
+			w.Write([]byte(`{"error":{"root_cause":[{"type":"repository_missing_exception","reason":"[nowhere] missing"}],"type":"repository_missing_exception","reason":"[nowhere] missing"},"status":404}`))
+		default:
+			w.Write([]byte(snapshotsAPIResponse))
+		}
+	})
+
+	if info, found, err := service.GetSnapshot(context.Background(), "backups", "nightly-2023.11.13"); err != nil || !found || info.Snapshot == "" {
+		t.Errorf("Expected the snapshot to be found, got %+v, %v, %v", info, found, err)
+	}
+	if _, found, err := service.GetSnapshot(context.Background(), "backups", "missing"); err != nil || found {
+		t.Errorf("Expected a missing snapshot to be reported as not found, got %v, %v", found, err)
+	}
+	if _, _, err := service.GetSnapshot(context.Background(), "nowhere", "nightly"); err == nil {
+		t.Error("Expected a missing repository to be an error")
+	}
+}
+
+func TestRestoreSnapshot(t *testing.T) {
+	var path, body string
+	service := newTestClusterService(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/" {
+			w.Write([]byte(`{"version":{"number":"8.11.1"}}`))
+			return
+		}
+		payload, _ := io.ReadAll(r.Body)
+		path, body = r.Method+" "+r.URL.Path, string(payload)
+		w.Write([]byte(`{"snapshot":{"snapshot":"nightly","indices":["restored-logs-000001"],"shards":{"total":2,"failed":0,"successful":2}}}`))
+	})
+
+	result, err := service.RestoreSnapshot(context.Background(), "backups", "nightly", &models.RestoreRequest{
+		Indices:           []string{"logs-000001"},
+		RenamePattern:     "(.+)",
+		RenameReplacement: "restored-$1",
+	}, true)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if path != "POST /_snapshot/backups/nightly/_restore" {
+		t.Errorf("Expected POST /_snapshot/backups/nightly/_restore, got %s", path)
+	}
+	if expected := `{"indices":"logs-000001","rename_pattern":"(.+)","rename_replacement":"restored-$1"}`; body != expected {
+		t.Errorf("Expected body %s, got %s", expected, body)
+	}
+	if !result.Accepted || !result.Completed || !reflect.DeepEqual(result.Indices, []string{"restored-logs-000001"}) || result.Shards.Successful != 2 {
+		t.Errorf("Expected a completed restore of restored-logs-000001, got %+v", result)
+	}
+
+	path = ""
+	if _, err := service.RestoreSnapshot(context.Background(), "backups", "nightly", &models.RestoreRequest{RenamePattern: "(.+)"}, false); err == nil || path != "" {
+		t.Errorf("Expected a rename pattern without a replacement to be rejected before reaching Elasticsearch, got %v", err)
+	}
+}
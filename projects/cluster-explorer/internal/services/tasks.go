@@ -0,0 +1,195 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/elastic/go-elasticsearch/v8/esapi"
+	"go.uber.org/zap"
+
+	"github.com/saif-islam/es-playground/projects/cluster-explorer/internal/models"
+	"github.com/saif-islam/es-playground/shared"
+)
+
+// ErrTaskNotCancellable is returned when Elasticsearch refuses to cancel a task
+var ErrTaskNotCancellable = errors.New("task cannot be cancelled")
+
+// TaskListOptions filters the tasks returned by ListTasks
+type TaskListOptions struct {
+	// Actions are action patterns such as *reindex or indices:data/write/*
+	Actions []string
+	// Detailed adds each task's description and status, e.g. the progress of a reindex
+	Detailed bool
+	// Nodes limits the listing to tasks running on these nodes
+	Nodes []string
+	// ParentTaskID lists only the children of a task, e.g. the slices of a sliced reindex
+	ParentTaskID string
+}
+
+// taskResponse is a task as reported by the tasks API
+type taskResponse struct {
+	Node               string                 `json:"node"`
+	ID                 int64                  `json:"id"`
+	Type               string                 `json:"type"`
+	Action             string                 `json:"action"`
+	Description        string                 `json:"description"`
+	StartTimeInMillis  int64                  `json:"start_time_in_millis"`
+	RunningTimeInNanos int64                  `json:"running_time_in_nanos"`
+	Cancellable        bool                   `json:"cancellable"`
+	Cancelled          bool                   `json:"cancelled"`
+	ParentTaskID       string                 `json:"parent_task_id"`
+	Status             map[string]interface{} `json:"status"`
+}
+
+// taskFailure is a task the tasks API could not act on
+type taskFailure struct {
+	TaskID int64  `json:"task_id"`
+	NodeID string `json:"node_id"`
+	Reason struct {
+		Type   string `json:"type"`
+		Reason string `json:"reason"`
+	} `json:"reason"`
+}
+
+// ListTasks lists the tasks running on the cluster, longest-running first
+func (s *ClusterService) ListTasks(ctx context.Context, opts TaskListOptions) (*models.TaskList, error) {
+	options := []func(*esapi.TasksListRequest){
+		s.esClient.Tasks.List.WithContext(ctx),
+		s.esClient.Tasks.List.WithGroupBy("none"),
+		s.esClient.Tasks.List.WithDetailed(opts.Detailed),
+	}
+	if len(opts.Actions) > 0 {
+		options = append(options, s.esClient.Tasks.List.WithActions(opts.Actions...))
+	}
+	if len(opts.Nodes) > 0 {
+		options = append(options, s.esClient.Tasks.List.WithNodes(opts.Nodes...))
+	}
+	if opts.ParentTaskID != "" {
+		options = append(options, s.esClient.Tasks.List.WithParentTaskID(opts.ParentTaskID))
+	}
+
+	res, err := s.esClient.Tasks.List(options...)
+	if err != nil {
+		return nil, fmt.Errorf("tasks request failed: %w", err)
+	}
+	defer res.Body.Close()
+
+	if res.IsError() {
+		return nil, shared.ParseESError(res)
+	}
+
+	var response struct {
+		Tasks map[string]taskResponse `json:"tasks"`
+	}
+	if err := shared.DecodeJSONResponse(res, &response); err != nil {
+		return nil, fmt.Errorf("failed to decode tasks: %w", err)
+	}
+
+	list := &models.TaskList{
+		Tasks:     make([]models.TaskInfo, 0, len(response.Tasks)),
+		RequestID: generateRequestID(),
+		Timestamp: time.Now(),
+	}
+	for _, task := range response.Tasks {
+		list.Tasks = append(list.Tasks, task.taskInfo())
+	}
+	sort.Slice(list.Tasks, func(i, j int) bool {
+		return list.Tasks[i].RunningTime > list.Tasks[j].RunningTime
+	})
+	list.Count = len(list.Tasks)
+
+	s.logger.Info("Listed tasks",
+		zap.Strings("actions", opts.Actions),
+		zap.Bool("detailed", opts.Detailed),
+		zap.Int("count", list.Count))
+
+	return list, nil
+}
+
+// CancelTask cancels a task and its children, returning the tasks that were cancelled.
+// It reports false when no such task is running.
+func (s *ClusterService) CancelTask(ctx context.Context, taskID string) ([]models.TaskInfo, bool, error) {
+	res, err := s.esClient.Tasks.Cancel(
+		s.esClient.Tasks.Cancel.WithContext(ctx),
+		s.esClient.Tasks.Cancel.WithTaskID(taskID),
+	)
+	if err != nil {
+		return nil, false, fmt.Errorf("cancel request failed: %w", err)
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode == http.StatusNotFound {
+		return nil, false, nil
+	}
+	if res.IsError() {
+		return nil, false, shared.ParseESError(res)
+	}
+
+	// Cancelled tasks are grouped by node
+	var response struct {
+		Nodes map[string]struct {
+			Tasks map[string]taskResponse `json:"tasks"`
+		} `json:"nodes"`
+		TaskFailures []taskFailure `json:"task_failures"`
+	}
+	if err := shared.DecodeJSONResponse(res, &response); err != nil {
+		return nil, false, fmt.Errorf("failed to decode cancel response: %w", err)
+	}
+
+	if len(response.TaskFailures) > 0 {
+		reasons := make([]string, 0, len(response.TaskFailures))
+		for _, failure := range response.TaskFailures {
+			reasons = append(reasons, failure.Reason.Reason)
+		}
+		return nil, true, fmt.Errorf("%w: %s", ErrTaskNotCancellable, strings.Join(reasons, "; "))
+	}
+
+	var cancelled []models.TaskInfo
+	for _, node := range response.Nodes {
+		for _, task := range node.Tasks {
+			cancelled = append(cancelled, task.taskInfo())
+		}
+	}
+
+	s.logger.Info("Cancelled task",
+		zap.String("task_id", taskID),
+		zap.Int("cancelled", len(cancelled)))
+
+	return cancelled, true, nil
+}
+
+// taskInfo converts a tasks API entry, working out progress from its status when the
+// task reports a document total
+func (t taskResponse) taskInfo() models.TaskInfo {
+	info := models.TaskInfo{
+		ID:           fmt.Sprintf("%s:%d", t.Node, t.ID),
+		Node:         t.Node,
+		Action:       t.Action,
+		Type:         t.Type,
+		Description:  t.Description,
+		StartTime:    time.UnixMilli(t.StartTimeInMillis),
+		RunningTime:  time.Duration(t.RunningTimeInNanos),
+		Cancellable:  t.Cancellable,
+		Cancelled:    t.Cancelled,
+		ParentTaskID: t.ParentTaskID,
+		Status:       t.Status,
+	}
+
+	// Reindex, update-by-query and delete-by-query count every document they handle
+	if total, ok := t.Status["total"].(float64); ok && total > 0 {
+		var processed float64
+		for _, field := range []string{"created", "updated", "deleted", "noops", "version_conflicts"} {
+			if count, ok := t.Status[field].(float64); ok {
+				processed += count
+			}
+		}
+		info.ProgressPercent = processed / total * 100
+	}
+
+	return info
+}
@@ -0,0 +1,192 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"math"
+	"net/http"
+	"testing"
+	"time"
+)
+
+// tasksAPIResponse follows GET /_tasks?group_by=none&detailed=true&actions=*reindex,*byquery
+// while a sliced reindex and a delete-by-query run
+const tasksAPIResponse = `{
+  "tasks": {
+    "oTUltX4IQMOUUVeiohTt8A:124": {
+      "node": "oTUltX4IQMOUUVeiohTt8A",
+      "id": 124,
+      "type": "transport",
+      "action": "indices:data/write/reindex",
+      "status": {"slice_id": 0, "total": 6154, "updated": 0, "created": 3500, "deleted": 0, "batches": 4, "version_conflicts": 77, "noops": 0, "retries": {"bulk": 0, "search": 0}, "throttled_millis": 0, "requests_per_second": -1.0, "throttled_until_millis": 0},
+      "description": "reindex from [products] to [products-v2]",
+      "start_time_in_millis": 1700000000000,
+      "running_time_in_nanos": 95000000000,
+      "cancellable": true,
+      "cancelled": false,
+      "parent_task_id": "oTUltX4IQMOUUVeiohTt8A:123",
+      "headers": {}
+    },
+    "Qm8pR2vKRhe1h5cT9dbz4w:88": {
+      "node": "Qm8pR2vKRhe1h5cT9dbz4w",
+      "id": 88,
+      "type": "transport",
+      "action": "indices:data/write/delete/byquery",
+      "status": {"total": 400, "updated": 0, "created": 0, "deleted": 100, "batches": 1, "version_conflicts": 0, "noops": 0, "retries": {"bulk": 0, "search": 0}, "throttled_millis": 0, "requests_per_second": -1.0, "throttled_until_millis": 0},
+      "description": "delete-by-query [logs-2023]",
+      "start_time_in_millis": 1700000080000,
+      "running_time_in_nanos": 15000000000,
+      "cancellable": true,
+      "cancelled": false,
+      "headers": {}
+    },
+    "oTUltX4IQMOUUVeiohTt8A:123": {
+      "node": "oTUltX4IQMOUUVeiohTt8A",
+      "id": 123,
+      "type": "transport",
+      "action": "indices:data/write/reindex",
+      "status": {"total": 0, "updated": 0, "created": 0, "deleted": 0, "batches": 0, "version_conflicts": 0, "noops": 0, "retries": {"bulk": 0, "search": 0}, "throttled_millis": 0, "requests_per_second": 0.0, "throttled_until_millis": 0, "slices": [null]},
+      "description": "reindex from [products] to [products-v2]",
+      "start_time_in_millis": 1699999999000,
+      "running_time_in_nanos": 96000000000,
+      "cancellable": true,
+      "cancelled": false,
+      "headers": {}
+    }
+  }
+}`
+
+// cancelTaskAPIResponse follows POST /_tasks/oTUltX4IQMOUUVeiohTt8A:123/_cancel, grouped by node
+const cancelTaskAPIResponse = `{
+  "nodes": {
+    "oTUltX4IQMOUUVeiohTt8A": {
+      "name": "es-node-1",
+      "transport_address": "10.0.0.1:9300",
+      "host": "10.0.0.1",
+      "ip": "10.0.0.1:9300",
+      "roles": ["data", "master"],
+      "tasks": {
+        "oTUltX4IQMOUUVeiohTt8A:123": {
+          "node": "oTUltX4IQMOUUVeiohTt8A",
+          "id": 123,
+          "type": "transport",
+          "action": "indices:data/write/reindex",
+          "start_time_in_millis": 1699999999000,
+          "running_time_in_nanos": 97000000000,
+          "cancellable": true,
+          "cancelled": true,
+          "headers": {}
+        }
+      }
+    }
+  }
+}`
+
+func TestListTasks(t *testing.T) {
+	var path, query string
+	service := newTestClusterService(t, func(w http.ResponseWriter, r *http.Request) {
+		path, query = r.URL.Path, r.URL.RawQuery
+		w.Write([]byte(tasksAPIResponse))
+	})
+
+	list, err := service.ListTasks(context.Background(), TaskListOptions{
+		Actions:  []string{"*reindex", "*byquery"},
+		Detailed: true,
+	})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if path != "/_tasks" || query != "actions=%2Areindex%2C%2Abyquery&detailed=true&group_by=none" {
+		t.Errorf("Expected /_tasks with the actions, detailed and group_by=none, got %s?%s", path, query)
+	}
+
+	if list.Count != 3 || len(list.Tasks) != 3 {
+		t.Fatalf("Expected 3 tasks, got %d", list.Count)
+	}
+	// Longest-running first
+	expectedOrder := []string{"oTUltX4IQMOUUVeiohTt8A:123", "oTUltX4IQMOUUVeiohTt8A:124", "Qm8pR2vKRhe1h5cT9dbz4w:88"}
+	for i, id := range expectedOrder {
+		if list.Tasks[i].ID != id {
+			t.Errorf("Expected task %d to be %s, got %s", i, id, list.Tasks[i].ID)
+		}
+	}
+
+	slice := list.Tasks[1]
+	if slice.ParentTaskID != "oTUltX4IQMOUUVeiohTt8A:123" || slice.RunningTime != 95*time.Second || !slice.StartTime.Equal(time.UnixMilli(1700000000000)) {
+		t.Errorf("Expected the reindex slice of task 123, got %+v", slice)
+	}
+	// 3500 created and 77 version conflicts of 6154
+	if math.Abs(slice.ProgressPercent-58.125) > 0.01 {
+		t.Errorf("Expected about 58.13%% progress, got %.2f", slice.ProgressPercent)
+	}
+	if list.Tasks[2].ProgressPercent != 25 {
+		t.Errorf("Expected 25%% progress for the delete-by-query, got %.2f", list.Tasks[2].ProgressPercent)
+	}
+	if list.Tasks[0].ProgressPercent != 0 {
+		t.Errorf("Expected no progress for a parent without a total, got %.2f", list.Tasks[0].ProgressPercent)
+	}
+}
+
+func TestCancelTask(t *testing.T) {
+	testCases := []struct {
+		name              string
+		taskID            string
+		status            int
+		response          string
+		expectedFound     bool
+		expectedCancelled int
+		expectedErr       error
+	}{
+		{
+			name:              "cancelled",
+			taskID:            "oTUltX4IQMOUUVeiohTt8A:123",
+			status:            http.StatusOK,
+			response:          cancelTaskAPIResponse,
+			expectedFound:     true,
+			expectedCancelled: 1,
+		},
+		{
+			name:     "not running",
+			taskID:   "oTUltX4IQMOUUVeiohTt8A:999",
+			status:   http.StatusNotFound,
+			response: `{"error":{"root_cause":[{"type":"resource_not_found_exception","reason":"task [oTUltX4IQMOUUVeiohTt8A:999] is not found"}],"type":"resource_not_found_exception","reason":"task [oTUltX4IQMOUUVeiohTt8A:999] is not found"},"status":404}`,
+		},
+		{
+			name:          "not cancellable",
+			taskID:        "oTUltX4IQMOUUVeiohTt8A:7",
+			status:        http.StatusOK,
+			response:      `{"node_failures":[],"task_failures":[{"task_id":7,"node_id":"oTUltX4IQMOUUVeiohTt8A","status":"INTERNAL_SERVER_ERROR","reason":{"type":"illegal_argument_exception","reason":"task [oTUltX4IQMOUUVeiohTt8A:7] doesn't support cancellation"}}],"nodes":{}}`,
+			expectedFound: true,
+			expectedErr:   ErrTaskNotCancellable,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			var requested string
+			service := newTestClusterService(t, func(w http.ResponseWriter, r *http.Request) {
+				if r.URL.Path == "/" {
+					w.Write([]byte(`{"version":{"number":"8.11.1"}}`))
+					return
+				}
+				requested = r.Method + " " + r.URL.Path
+				w.WriteHeader(tc.status)
+				w.Write([]byte(tc.response))
+			})
+
+			cancelled, found, err := service.CancelTask(context.Background(), tc.taskID)
+			if requested != "POST /_tasks/"+tc.taskID+"/_cancel" {
+				t.Errorf("Expected POST /_tasks/%s/_cancel, got %s", tc.taskID, requested)
+			}
+			if !errors.Is(err, tc.expectedErr) {
+				t.Fatalf("Expected error %v, got %v", tc.expectedErr, err)
+			}
+			if found != tc.expectedFound || len(cancelled) != tc.expectedCancelled {
+				t.Errorf("Expected found %v with %d cancelled, got found %v with %d", tc.expectedFound, tc.expectedCancelled, found, len(cancelled))
+			}
+			if tc.expectedCancelled > 0 && (!cancelled[0].Cancelled || cancelled[0].ID != tc.taskID) {
+				t.Errorf("Expected %s to be cancelled, got %+v", tc.taskID, cancelled[0])
+			}
+		})
+	}
+}
@@ -0,0 +1,140 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/saif-islam/es-playground/projects/cluster-explorer/internal/models"
+	"github.com/saif-islam/es-playground/shared"
+)
+
+// Thread pools reported by GetThreadPoolStats. bulk only exists before Elasticsearch 6.3,
+// which merged it into write.
+var monitoredThreadPools = []string{"write", "search", "get", "bulk"}
+
+// threadPoolStatsResponse is the part of the _nodes/stats/thread_pool API response used
+// for the thread pool monitor
+type threadPoolStatsResponse struct {
+	Nodes map[string]struct {
+		Name       string `json:"name"`
+		ThreadPool map[string]struct {
+			Threads  int   `json:"threads"`
+			Queue    int   `json:"queue"`
+			Active   int   `json:"active"`
+			Rejected int64 `json:"rejected"`
+		} `json:"thread_pool"`
+	} `json:"nodes"`
+}
+
+// threadPoolSample keeps the rejection counters of a sample, keyed by node ID and pool
+type threadPoolSample struct {
+	at       time.Time
+	rejected map[string]int64
+}
+
+// GetThreadPoolStats returns the active, queued and rejected counts of the write, search,
+// get and bulk thread pools on every node. Rejections mean a pool's queue was full, an
+// early sign of cluster stress, so pools whose rejections increased since the previous
+// call are flagged and logged.
+func (s *ClusterService) GetThreadPoolStats(ctx context.Context) (*models.ThreadPoolReport, error) {
+	res, err := s.esClient.Nodes.Stats(
+		s.esClient.Nodes.Stats.WithContext(ctx),
+		s.esClient.Nodes.Stats.WithMetric("thread_pool"),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("node stats request failed: %w", err)
+	}
+	defer res.Body.Close()
+
+	if res.IsError() {
+		return nil, shared.ParseESError(res)
+	}
+
+	var response threadPoolStatsResponse
+	if err := shared.DecodeJSONResponse(res, &response); err != nil {
+		return nil, fmt.Errorf("failed to decode node stats: %w", err)
+	}
+
+	current := &threadPoolSample{at: time.Now(), rejected: make(map[string]int64)}
+
+	s.threadPoolMu.Lock()
+	previous := s.lastThreadPools
+	s.lastThreadPools = current
+	s.threadPoolMu.Unlock()
+
+	report := &models.ThreadPoolReport{
+		Nodes:     make([]models.NodeThreadPools, 0, len(response.Nodes)),
+		Rejecting: []string{},
+		SampledAt: current.at,
+	}
+	if previous != nil {
+		report.PreviousSample = &previous.at
+	}
+
+	for id, node := range response.Nodes {
+		nodePools := models.NodeThreadPools{
+			NodeID:   id,
+			NodeName: node.Name,
+			Pools:    []models.ThreadPoolUsage{},
+		}
+
+		for _, name := range monitoredThreadPools {
+			pool, ok := node.ThreadPool[name]
+			if !ok {
+				continue
+			}
+
+			key := id + "/" + name
+			current.rejected[key] = pool.Rejected
+
+			usage := models.ThreadPoolUsage{
+				Name:     name,
+				Threads:  pool.Threads,
+				Active:   pool.Active,
+				Queue:    pool.Queue,
+				Rejected: pool.Rejected,
+			}
+			if previous != nil {
+				usage.NewRejections = newRejections(previous.rejected, key, pool.Rejected)
+				usage.RejectionsIncreased = usage.NewRejections > 0
+			}
+			nodePools.Pools = append(nodePools.Pools, usage)
+
+			if usage.RejectionsIncreased {
+				report.Rejecting = append(report.Rejecting, node.Name+"/"+name)
+				s.logger.Warn("Thread pool rejections increased",
+					zap.String("node_id", id),
+					zap.String("node_name", node.Name),
+					zap.String("pool", name),
+					zap.Int64("new_rejections", usage.NewRejections),
+					zap.Int("queue", pool.Queue),
+					zap.Int("active", pool.Active))
+			}
+		}
+
+		report.Nodes = append(report.Nodes, nodePools)
+	}
+
+	sort.Slice(report.Nodes, func(i, j int) bool { return report.Nodes[i].NodeName < report.Nodes[j].NodeName })
+	sort.Strings(report.Rejecting)
+
+	return report, nil
+}
+
+// newRejections returns how many rejections a pool had since the previous sample. Nodes
+// that joined since then have no baseline and report none. The counters reset when a node
+// restarts, in which case everything counted since is new.
+func newRejections(previous map[string]int64, key string, rejected int64) int64 {
+	before, ok := previous[key]
+	if !ok {
+		return 0
+	}
+	if rejected < before {
+		return rejected
+	}
+	return rejected - before
+}
@@ -0,0 +1,124 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// threadPoolNode is a node in a threadPoolStatsAPIResponse: its rejected count per pool
+type threadPoolNode struct {
+	id, name string
+	rejected map[string]int64
+}
+
+// threadPoolStatsAPIResponse follows GET /_nodes/stats/thread_pool, with the rejected
+// count of each of a node's pools and the pools the monitor ignores alongside them
+func threadPoolStatsAPIResponse(nodes ...threadPoolNode) string {
+	entries := make([]string, 0, len(nodes))
+	for _, node := range nodes {
+		pools := []string{`"management": {"threads": 2, "queue": 0, "active": 1, "rejected": 0, "largest": 2, "completed": 1890}`}
+		for name, rejected := range node.rejected {
+			pools = append(pools, fmt.Sprintf(`%q: {"threads": 8, "queue": 120, "active": 8, "rejected": %d, "largest": 8, "completed": 982311}`, name, rejected))
+		}
+		entries = append(entries, fmt.Sprintf(`%q: {"timestamp": 1700000000000, "name": %q, "transport_address": "10.0.0.1:9300", "host": "10.0.0.1", "ip": "10.0.0.1:9300", "roles": ["data"], "thread_pool": {%s}}`,
+			node.id, node.name, strings.Join(pools, ", ")))
+	}
+	return fmt.Sprintf(`{"_nodes": {"total": %d, "successful": %d, "failed": 0}, "cluster_name": "playground", "nodes": {%s}}`,
+		len(nodes), len(nodes), strings.Join(entries, ", "))
+}
+
+func TestGetThreadPoolStats(t *testing.T) {
+	// Successive samples of the same cluster
+	samples := [][]threadPoolNode{
+		{
+			{id: "Yx3kT1", name: "es-node-1", rejected: map[string]int64{"write": 10, "search": 0, "get": 0}},
+			{id: "Qm8pR2", name: "es-node-2", rejected: map[string]int64{"write": 0, "search": 4, "get": 0}},
+		},
+		// es-node-1 rejects writes, and es-node-3, a 6.2 node with a bulk pool, joins
+		{
+			{id: "Yx3kT1", name: "es-node-1", rejected: map[string]int64{"write": 25, "search": 0, "get": 0}},
+			{id: "Qm8pR2", name: "es-node-2", rejected: map[string]int64{"write": 0, "search": 4, "get": 0}},
+			{id: "Zt5nW3", name: "es-node-3", rejected: map[string]int64{"index": 2, "bulk": 40, "search": 0, "get": 0}},
+		},
+		// es-node-2 restarted and has rejected 3 searches since, es-node-3 rejects bulk requests
+		{
+			{id: "Yx3kT1", name: "es-node-1", rejected: map[string]int64{"write": 25, "search": 0, "get": 0}},
+			{id: "Qm8pR2", name: "es-node-2", rejected: map[string]int64{"write": 0, "search": 3, "get": 0}},
+			{id: "Zt5nW3", name: "es-node-3", rejected: map[string]int64{"index": 2, "bulk": 41, "search": 0, "get": 0}},
+		},
+	}
+
+	var sample int
+	var metricPath string
+	service := newTestClusterService(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/" {
+			w.Write([]byte(`{"version":{"number":"8.11.1"}}`))
+			return
+		}
+		metricPath = r.URL.Path
+		w.Write([]byte(threadPoolStatsAPIResponse(samples[sample]...)))
+	})
+
+	testCases := []struct {
+		name              string
+		expectedRejecting []string
+		expectedNew       map[string]int64 // node/pool to new rejections
+	}{
+		{
+			name:              "first sample has no baseline",
+			expectedRejecting: []string{},
+			expectedNew:       map[string]int64{"es-node-1/write": 0},
+		},
+		{
+			name:              "rejections increased",
+			expectedRejecting: []string{"es-node-1/write"},
+			expectedNew:       map[string]int64{"es-node-1/write": 15, "es-node-2/search": 0, "es-node-3/bulk": 0},
+		},
+		{
+			name:              "counter reset by a restart",
+			expectedRejecting: []string{"es-node-2/search", "es-node-3/bulk"},
+			expectedNew:       map[string]int64{"es-node-1/write": 0, "es-node-2/search": 3, "es-node-3/bulk": 1},
+		},
+	}
+
+	for i, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			sample = i
+			report, err := service.GetThreadPoolStats(context.Background())
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if metricPath != "/_nodes/stats/thread_pool" {
+				t.Errorf("Expected /_nodes/stats/thread_pool, got %s", metricPath)
+			}
+			if (report.PreviousSample != nil) != (i > 0) {
+				t.Errorf("Expected a previous sample %v, got %v", i > 0, report.PreviousSample)
+			}
+			if !reflect.DeepEqual(report.Rejecting, tc.expectedRejecting) {
+				t.Errorf("Expected %v rejecting, got %v", tc.expectedRejecting, report.Rejecting)
+			}
+
+			newRejections := make(map[string]int64)
+			for _, node := range report.Nodes {
+				for _, pool := range node.Pools {
+					if pool.Name == "index" || pool.Name == "management" {
+						t.Errorf("Expected only the monitored pools, got %s on %s", pool.Name, node.NodeName)
+					}
+					if pool.RejectionsIncreased != (pool.NewRejections > 0) {
+						t.Errorf("Expected rejections increased only with new rejections, got %+v", pool)
+					}
+					newRejections[node.NodeName+"/"+pool.Name] = pool.NewRejections
+				}
+			}
+			for key, expected := range tc.expectedNew {
+				if actual, ok := newRejections[key]; !ok || actual != expected {
+					t.Errorf("Expected %d new rejections for %s, got %d (reported %v)", expected, key, actual, ok)
+				}
+			}
+		})
+	}
+}
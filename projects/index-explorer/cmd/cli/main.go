@@ -8,9 +8,12 @@ import (
 	"io"
 	"net/http"
 	"os"
+	"path/filepath"
 	"strconv"
 	"strings"
 	"time"
+
+	"github.com/saif-islam/es-playground/projects/index-explorer/internal/perftest"
 )
 
 const (
@@ -20,6 +23,7 @@ const (
 
 type CLI struct {
 	APIURL  string
+	APIKey  string // sent as a bearer token when the API requires authentication
 	client  *http.Client
 	scanner *bufio.Scanner
 }
@@ -40,6 +44,7 @@ func main() {
 	
 	cli := &CLI{
 		APIURL:  apiURL,
+		APIKey:  getEnv("API_KEY", ""),
 		client:  &http.Client{Timeout: 30 * time.Second},
 		scanner: bufio.NewScanner(os.Stdin),
 	}
@@ -347,22 +352,69 @@ func (c *CLI) ndjsonImport() {
 		return
 	}
 	
-	docCount, _ := strconv.Atoi(c.promptWithDefault("Number of documents to generate", "200"))
+	path := c.promptFilePath("NDJSON file (.ndjson or .ndjson.gz, blank to generate sample data)")
+	
+	docCount := 0
+	if path == "" {
+		docCount, _ = strconv.Atoi(c.promptWithDefault("Number of documents to generate", "200"))
+	}
 	batchSize, _ := strconv.Atoi(c.promptWithDefault("Batch size", "500"))
 	workers, _ := strconv.Atoi(c.promptWithDefault("Workers", "4"))
 	
-	// Generate NDJSON data
-	fmt.Printf("📝 Generating NDJSON data with %d documents...\n", docCount)
-	ndjsonData := c.generateNDJSONData(docCount)
-	
 	url := fmt.Sprintf("%s/api/v1/indices/%s/import/ndjson?batch_size=%d&workers=%d",
 		c.APIURL, indexName, batchSize, workers)
 	
+	var body io.Reader
+	var size int64
+	var progress *progressReader
+	gzipped := false
+	if path == "" {
+		// Generate NDJSON data
+		fmt.Printf("📝 Generating NDJSON data with %d documents...\n", docCount)
+		ndjsonData := c.generateNDJSONData(docCount)
+		body, size = strings.NewReader(ndjsonData), int64(len(ndjsonData))
+	} else {
+		file, err := os.Open(path)
+		if err != nil {
+			fmt.Printf("❌ Failed to open %s: %v\n", path, err)
+			return
+		}
+		defer file.Close()
+		
+		info, err := file.Stat()
+		if err != nil {
+			fmt.Printf("❌ Failed to read %s: %v\n", path, err)
+			return
+		}
+		
+		// Gzipped files are sent as they are and decompressed by the server
+		gzipped = strings.HasSuffix(path, ".gz")
+		progress = newProgressReader(file, info.Size(), !gzipped)
+		body, size = progress, info.Size()
+	}
+	
+	req, err := http.NewRequest(http.MethodPost, url, body)
+	if err != nil {
+		fmt.Printf("❌ Failed to create request: %v\n", err)
+		return
+	}
+	req.ContentLength = size
+	req.Header.Set("Content-Type", "application/x-ndjson")
+	c.authorize(req)
+	if gzipped {
+		req.Header.Set("Content-Encoding", "gzip")
+	}
+	
 	fmt.Printf("📄 Importing NDJSON data...\n")
 	
+	// No client timeout: large files take as long as they take
 	start := time.Now()
-	resp, err := http.Post(url, "application/x-ndjson", strings.NewReader(ndjsonData))
+	resp, err := http.DefaultClient.Do(req)
 	duration := time.Since(start)
+	if progress != nil {
+		progress.finish()
+		docCount = int(progress.docs)
+	}
 	
 	if err != nil {
 		fmt.Printf("❌ Failed to import NDJSON: %v\n", err)
@@ -370,12 +422,22 @@ func (c *CLI) ndjsonImport() {
 	}
 	defer resp.Body.Close()
 	
-	body, _ := io.ReadAll(resp.Body)
+	respBody, _ := io.ReadAll(resp.Body)
 	var result interface{}
-	json.Unmarshal(body, &result)
+	json.Unmarshal(respBody, &result)
 	
-	docsPerSec := float64(docCount) / duration.Seconds()
-	fmt.Printf("✅ NDJSON import completed in %v (%.2f docs/sec)!\n", duration, docsPerSec)
+	if resp.StatusCode >= http.StatusBadRequest {
+		fmt.Printf("❌ NDJSON import failed with status %d\n", resp.StatusCode)
+		c.prettyPrintJSON(result)
+		return
+	}
+	
+	if docCount > 0 {
+		docsPerSec := float64(docCount) / duration.Seconds()
+		fmt.Printf("✅ NDJSON import completed in %v (%.2f docs/sec)!\n", duration, docsPerSec)
+	} else {
+		fmt.Printf("✅ NDJSON import completed in %v!\n", duration)
+	}
 	c.prettyPrintJSON(result)
 }
 
@@ -442,11 +504,11 @@ func (c *CLI) performanceTest() {
 	
 	switch choice {
 	case "1":
-		docCount, workers, batchSize = 100, 4, 50
+		docCount, workers, batchSize, _ = perftest.Preset("quick")
 	case "2":
-		docCount, workers, batchSize = 1000, 8, 500
+		docCount, workers, batchSize, _ = perftest.Preset("medium")
 	case "3":
-		docCount, workers, batchSize = 10000, 16, 1000
+		docCount, workers, batchSize, _ = perftest.Preset("heavy")
 	case "4":
 		docCount, _ = strconv.Atoi(c.promptWithDefault("Document count", "500"))
 		workers, _ = strconv.Atoi(c.promptWithDefault("Workers", "8"))
@@ -456,14 +518,20 @@ func (c *CLI) performanceTest() {
 		return
 	}
 	
+	if docCount <= 0 || workers <= 0 || batchSize <= 0 {
+		fmt.Println("❌ Document count, workers and batch size must be positive numbers")
+		return
+	}
+	
 	fmt.Printf("🚀 Running performance test with %d documents...\n", docCount)
-	fmt.Printf("⚙️  Configuration: %d workers, batch size %d\n", workers, batchSize)
 	
-	// This would ideally call the separate performance test binary
-	// For now, we'll show a simplified version
-	fmt.Println("💡 For comprehensive performance testing, use:")
-	fmt.Printf("   cd projects/index-explorer && go run cmd/perf-test/main.go\n")
-	fmt.Printf("   Or: make perf-test\n")
+	perfTest := perftest.New(c.APIURL, docCount, workers, batchSize)
+	perfTest.APIKey = c.APIKey
+	perfTest.PrintConfiguration()
+	
+	results := perftest.Run(perfTest)
+	perftest.DisplayResults(results)
+	perftest.Cleanup(perfTest)
 }
 
 func (c *CLI) showExamples() {
@@ -543,6 +611,40 @@ func (c *CLI) promptWithOptions(message string, options []string, defaultValue s
 	return defaultValue
 }
 
+// promptFilePath asks for a file, returning "" when left blank. A leading ~ is expanded,
+// and when the path does not name a file the files starting with it are suggested.
+func (c *CLI) promptFilePath(message string) string {
+	for {
+		path := c.prompt(message)
+		if path == "" {
+			return ""
+		}
+		if strings.HasPrefix(path, "~/") {
+			if home, err := os.UserHomeDir(); err == nil {
+				path = filepath.Join(home, path[2:])
+			}
+		}
+		
+		if info, err := os.Stat(path); err == nil && !info.IsDir() {
+			return path
+		}
+		
+		matches, _ := filepath.Glob(path + "*")
+		if len(matches) == 0 {
+			fmt.Printf("  ⚠️  No file matches %s\n", path)
+			continue
+		}
+		fmt.Println("  ⚠️  Not a file. Did you mean:")
+		for i, match := range matches {
+			if i == 10 {
+				fmt.Printf("     ... and %d more\n", len(matches)-10)
+				break
+			}
+			fmt.Printf("     %s\n", match)
+		}
+	}
+}
+
 func (c *CLI) promptBool(message string, defaultValue bool) bool {
 	defaultStr := "N"
 	if defaultValue {
@@ -560,6 +662,13 @@ func (c *CLI) promptBool(message string, defaultValue bool) bool {
 	return defaultValue
 }
 
+// authorize adds the API key to a request, if one is set
+func (c *CLI) authorize(req *http.Request) {
+	if c.APIKey != "" {
+		req.Header.Set("Authorization", "Bearer "+c.APIKey)
+	}
+}
+
 func (c *CLI) makeRequest(method, endpoint string, payload interface{}) (interface{}, error) {
 	var body io.Reader
 	if payload != nil {
@@ -578,6 +687,7 @@ func (c *CLI) makeRequest(method, endpoint string, payload interface{}) (interfa
 	if payload != nil {
 		req.Header.Set("Content-Type", "application/json")
 	}
+	c.authorize(req)
 	
 	resp, err := c.client.Do(req)
 	if err != nil {
@@ -684,6 +794,78 @@ func (c *CLI) generateNDJSONData(count int) string {
 	return builder.String()
 }
 
+// progressReader counts the bytes (and, for uncompressed NDJSON, documents) read from a
+// file being uploaded and draws a progress bar as they are sent
+type progressReader struct {
+	reader    io.Reader
+	total     int64
+	countDocs bool
+	sent      int64
+	docs      int64
+	lastByte  byte
+	lastDrawn time.Time
+}
+
+func newProgressReader(reader io.Reader, total int64, countDocs bool) *progressReader {
+	return &progressReader{reader: reader, total: total, countDocs: countDocs}
+}
+
+func (p *progressReader) Read(buf []byte) (int, error) {
+	n, err := p.reader.Read(buf)
+	if n > 0 {
+		p.sent += int64(n)
+		if p.countDocs {
+			p.docs += int64(bytes.Count(buf[:n], []byte("\n")))
+			p.lastByte = buf[n-1]
+		}
+		if time.Since(p.lastDrawn) >= 200*time.Millisecond {
+			p.draw()
+		}
+	}
+	return n, err
+}
+
+// finish draws the final state of the bar and ends its line
+func (p *progressReader) finish() {
+	// The last document needs no trailing newline
+	if p.countDocs && p.sent > 0 && p.lastByte != '\n' {
+		p.docs++
+	}
+	p.draw()
+	fmt.Println()
+}
+
+func (p *progressReader) draw() {
+	p.lastDrawn = time.Now()
+	
+	const width = 30
+	fraction := 1.0
+	if p.total > 0 {
+		fraction = float64(p.sent) / float64(p.total)
+	}
+	filled := int(fraction * width)
+	bar := strings.Repeat("█", filled) + strings.Repeat("░", width-filled)
+	
+	line := fmt.Sprintf("\r  [%s] %5.1f%% %s / %s", bar, fraction*100, formatBytes(p.sent), formatBytes(p.total))
+	if p.countDocs {
+		line += fmt.Sprintf(", %d docs", p.docs)
+	}
+	fmt.Print(line)
+}
+
+func formatBytes(n int64) string {
+	const unit = 1024
+	if n < unit {
+		return fmt.Sprintf("%d B", n)
+	}
+	div, exp := int64(unit), 0
+	for m := n / unit; m >= unit; m /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %cB", float64(n)/float64(div), "KMGTPE"[exp])
+}
+
 func getEnv(key, defaultValue string) string {
 	if value := os.Getenv(key); value != "" {
 		return value
@@ -0,0 +1,147 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+
+	"github.com/saif-islam/es-playground/projects/index-explorer/internal/handlers"
+	"github.com/saif-islam/es-playground/projects/index-explorer/internal/services"
+	"github.com/saif-islam/es-playground/shared"
+)
+
+const testAPIKey = "cli-test-key"
+
+// newImportAPI serves the NDJSON import endpoint behind API key authentication, backed by
+// a fake Elasticsearch. It returns the API URL and a count of documents Elasticsearch got.
+func newImportAPI(t *testing.T) (string, *atomic.Int64) {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+
+	var indexed atomic.Int64
+	es := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("X-Elastic-Product", "Elasticsearch")
+		w.Header().Set("Content-Type", "application/json")
+		if !strings.HasSuffix(r.URL.Path, "/_bulk") {
+			w.Write([]byte(`{"version":{"number":"8.11.1"}}`))
+			return
+		}
+
+		var items []string
+		scanner := bufio.NewScanner(r.Body)
+		for scanner.Scan() {
+			if scanner.Scan() {
+				items = append(items, fmt.Sprintf(`{"index":{"_index":"products","_id":"%d","result":"created","status":201}}`, len(items)))
+			}
+		}
+		indexed.Add(int64(len(items)))
+		fmt.Fprintf(w, `{"took":1,"errors":false,"items":[%s]}`, strings.Join(items, ","))
+	}))
+	t.Cleanup(es.Close)
+
+	client, err := shared.NewESClient(&shared.ESConfig{URLs: []string{es.URL}}, zap.NewNop())
+	if err != nil {
+		t.Fatalf("Failed to create client: %v", err)
+	}
+	documentHandler := handlers.NewDocumentHandler(services.NewDocumentService(client, zap.NewNop()), services.NewIndexService(client, zap.NewNop()), zap.NewNop())
+
+	router := gin.New()
+	v1 := router.Group("/api/v1", shared.RequireAPIKey(shared.AuthConfig{APIKeys: []string{testAPIKey}}, zap.NewNop()))
+	v1.POST("/indices/:index/import/ndjson", documentHandler.BulkImportNDJSON)
+
+	api := httptest.NewServer(router)
+	t.Cleanup(api.Close)
+	return api.URL, &indexed
+}
+
+// newTestCLI returns a CLI answering its prompts with input
+func newTestCLI(apiURL, input string) *CLI {
+	return &CLI{
+		APIURL:  apiURL,
+		APIKey:  testAPIKey,
+		client:  http.DefaultClient,
+		scanner: bufio.NewScanner(strings.NewReader(input)),
+	}
+}
+
+func TestNDJSONImport_File(t *testing.T) {
+	var ndjson strings.Builder
+	for i := 0; i < 250; i++ {
+		data, _ := json.Marshal(map[string]interface{}{"title": fmt.Sprintf("Product %d", i), "price": i})
+		ndjson.Write(data)
+		ndjson.WriteString("\n")
+	}
+
+	var compressed bytes.Buffer
+	gz := gzip.NewWriter(&compressed)
+	gz.Write([]byte(ndjson.String()))
+	gz.Close()
+
+	dir := t.TempDir()
+	plainPath := filepath.Join(dir, "products.ndjson")
+	gzipPath := filepath.Join(dir, "products.ndjson.gz")
+	os.WriteFile(plainPath, []byte(ndjson.String()), 0o600)
+	os.WriteFile(gzipPath, compressed.Bytes(), 0o600)
+
+	testCases := []struct {
+		name  string
+		input string // index, file, batch size and workers
+	}{
+		{name: "NDJSON file", input: "products\n" + plainPath + "\n100\n2\n"},
+		{name: "gzipped NDJSON file", input: "products\n" + gzipPath + "\n100\n2\n"},
+		{name: "file found after a wrong path", input: "products\n" + filepath.Join(dir, "products.nd") + "\n" + plainPath + "\n100\n2\n"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			apiURL, indexed := newImportAPI(t)
+
+			newTestCLI(apiURL, tc.input).ndjsonImport()
+
+			if indexed.Load() != 250 {
+				t.Errorf("Expected 250 documents to reach Elasticsearch, got %d", indexed.Load())
+			}
+		})
+	}
+}
+
+func TestProgressReader(t *testing.T) {
+	testCases := []struct {
+		name         string
+		data         string
+		countDocs    bool
+		expectedDocs int64
+	}{
+		{name: "trailing newline", data: "{\"a\":1}\n{\"a\":2}\n", countDocs: true, expectedDocs: 2},
+		{name: "no trailing newline", data: "{\"a\":1}\n{\"a\":2}", countDocs: true, expectedDocs: 2},
+		{name: "compressed", data: "\x1f\x8b\x08\x00\n\n", expectedDocs: 0},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			progress := newProgressReader(strings.NewReader(tc.data), int64(len(tc.data)), tc.countDocs)
+			var sent bytes.Buffer
+			sent.ReadFrom(progress)
+			progress.finish()
+
+			if sent.String() != tc.data || progress.sent != int64(len(tc.data)) {
+				t.Errorf("Expected the data to pass through unchanged, got %q", sent.String())
+			}
+			if progress.docs != tc.expectedDocs {
+				t.Errorf("Expected %d docs, got %d", tc.expectedDocs, progress.docs)
+			}
+		})
+	}
+}
@@ -12,10 +12,12 @@ import (
 	"time"
 
 	"github.com/gin-gonic/gin"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
 	"go.uber.org/zap"
 	"gopkg.in/yaml.v3"
 
 	"github.com/saif-islam/es-playground/projects/index-explorer/internal/handlers"
+	"github.com/saif-islam/es-playground/projects/index-explorer/internal/models"
 	"github.com/saif-islam/es-playground/projects/index-explorer/internal/services"
 	"github.com/saif-islam/es-playground/shared"
 )
@@ -24,7 +26,17 @@ import (
 type Config struct {
 	Server        ServerConfig        `yaml:"server"`
 	Elasticsearch ElasticsearchConfig `yaml:"elasticsearch"`
-	Logging       LoggingConfig       `yaml:"logging"`
+	// Additional named clusters, selected per request with the X-ES-Cluster header
+	Clusters       map[string]ElasticsearchConfig `yaml:"clusters"`
+	Dashboard      DashboardConfig                `yaml:"dashboard"`
+	Rollover       RolloverConfig                 `yaml:"rollover"`
+	Bulk           BulkConfig                     `yaml:"bulk"`
+	MetricsHistory MetricsHistoryConfig           `yaml:"metrics_history"`
+	// API keys required by mutating requests
+	Auth shared.AuthConfig `yaml:"auth"`
+	// Per-client limit on the bulk, import, by-query and reindex routes
+	RateLimit shared.RateLimitConfig `yaml:"rate_limit"`
+	Logging   LoggingConfig          `yaml:"logging"`
 }
 
 type ServerConfig struct {
@@ -41,16 +53,61 @@ type ElasticsearchConfig struct {
 	Password  string    `yaml:"password"`
 	APIKey    string    `yaml:"api_key"`
 	TLSConfig TLSConfig `yaml:"tls"`
+	// Per-request timeout for individual Elasticsearch calls
+	RequestTimeout time.Duration `yaml:"request_timeout"`
+	// Fail fast after repeated failures instead of waiting on a struggling cluster
+	CircuitBreaker shared.BreakerConfig `yaml:"circuit_breaker"`
 }
 
 type TLSConfig struct {
-	InsecureSkipVerify bool `yaml:"insecure_skip_verify"`
+	InsecureSkipVerify bool   `yaml:"insecure_skip_verify"`
+	CACertPath         string `yaml:"ca_cert_path"`
+	ClientCertPath     string `yaml:"client_cert_path"`
+	ClientKeyPath      string `yaml:"client_key_path"`
+}
+
+// DashboardConfig locates the other services aggregated by the overview endpoint
+type DashboardConfig struct {
+	ClusterExplorerURL string        `yaml:"cluster_explorer_url"`
+	SearchAPIURL       string        `yaml:"search_api_url"`
+	Timeout            time.Duration `yaml:"timeout"`
+}
+
+// RolloverConfig lists the aliases rolled over automatically once their write index
+// crosses one of its thresholds
+type RolloverConfig struct {
+	CheckInterval time.Duration                       `yaml:"check_interval"`
+	Aliases       map[string]RolloverThresholdsConfig `yaml:"aliases"`
+}
+
+type RolloverThresholdsConfig struct {
+	MaxPrimarySizeGB float64 `yaml:"max_primary_size_gb"`
+	MaxAge           string  `yaml:"max_age"`
+	MaxDocs          int64   `yaml:"max_docs"`
+}
+
+// BulkConfig controls background bulk jobs and resumable imports
+type BulkConfig struct {
+	// How long a finished job stays queryable
+	JobTTL time.Duration `yaml:"job_ttl"`
+	// How long an import checkpoint is kept after its last update
+	CheckpointTTL time.Duration `yaml:"checkpoint_ttl"`
+}
+
+// MetricsHistoryConfig controls the background sampling of per-index write metrics
+type MetricsHistoryConfig struct {
+	Interval  time.Duration `yaml:"interval"`
+	Retention time.Duration `yaml:"retention"`
+	// Index patterns to sample; all open indices when empty
+	Indices []string `yaml:"indices"`
 }
 
 type LoggingConfig struct {
 	Level  string `yaml:"level"`
 	Format string `yaml:"format"`
 	Output string `yaml:"output"`
+
+	shared.BodyCaptureConfig `yaml:",inline"`
 }
 
 func main() {
@@ -72,17 +129,7 @@ func main() {
 		zap.Int("port", config.Server.Port))
 
 	// Initialize Elasticsearch client
-	esConfig := &shared.ESConfig{
-		URLs:     config.Elasticsearch.URLs,
-		Username: config.Elasticsearch.Username,
-		Password: config.Elasticsearch.Password,
-		APIKey:   config.Elasticsearch.APIKey,
-		TLSConfig: &shared.TLSConfig{
-			InsecureSkipVerify: config.Elasticsearch.TLSConfig.InsecureSkipVerify,
-		},
-	}
-
-	esClient, err := shared.NewESClient(esConfig, logger)
+	defaultClient, err := shared.NewESClient(toESConfig(config.Elasticsearch), logger)
 	if err != nil {
 		logger.Fatal("Failed to create Elasticsearch client", zap.Error(err))
 	}
@@ -91,24 +138,93 @@ func main() {
 	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
 	defer cancel()
 
-	if err := esClient.WaitForCluster(ctx, "yellow", 30*time.Second); err != nil {
+	if err := defaultClient.WaitForCluster(ctx, "yellow", 30*time.Second); err != nil {
 		logger.Fatal("Elasticsearch cluster not ready", zap.Error(err))
 	}
 
+	// Register additional clusters; one that is unreachable at startup is skipped rather
+	// than keeping the default cluster from being served
+	clusters := shared.NewClusterRegistry(shared.DefaultClusterName, defaultClient, logger)
+	for name, clusterConfig := range config.Clusters {
+		if name == shared.DefaultClusterName {
+			logger.Warn("Ignoring cluster with reserved name", zap.String("cluster", name))
+			continue
+		}
+
+		client, err := shared.NewESClient(toESConfig(clusterConfig), logger)
+		if err != nil {
+			logger.Error("Failed to connect to cluster, skipping", zap.String("cluster", name), zap.Error(err))
+			continue
+		}
+		clusters.Register(name, client)
+	}
+
+	// Services share one client that routes each request to the selected cluster
+	esClient, err := clusters.NewRoutingClient()
+	if err != nil {
+		logger.Fatal("Failed to create cluster routing client", zap.Error(err))
+	}
+
 	// Initialize services
 	indexService := services.NewIndexService(esClient, logger)
 	documentService := services.NewDocumentService(esClient, logger)
+	documentService.SetBulkJobTTL(config.Bulk.JobTTL)
+	documentService.SetCheckpointTTL(config.Bulk.CheckpointTTL)
+	overviewService := services.NewOverviewService(esClient, services.OverviewConfig{
+		ClusterExplorerURL: config.Dashboard.ClusterExplorerURL,
+		SearchAPIURL:       config.Dashboard.SearchAPIURL,
+		Timeout:            config.Dashboard.Timeout,
+	}, logger)
+
+	// Automatic rollover watches aliases on the default cluster
+	rolloverAliases := make(map[string]models.RolloverThresholds, len(config.Rollover.Aliases))
+	for alias, thresholds := range config.Rollover.Aliases {
+		rolloverAliases[alias] = models.RolloverThresholds{
+			MaxPrimarySizeGB: thresholds.MaxPrimarySizeGB,
+			MaxAge:           thresholds.MaxAge,
+			MaxDocs:          thresholds.MaxDocs,
+		}
+	}
+	rolloverMonitor, err := services.NewRolloverMonitor(defaultClient, indexService, services.RolloverMonitorConfig{
+		Interval: config.Rollover.CheckInterval,
+		Aliases:  rolloverAliases,
+	}, logger)
+	if err != nil {
+		logger.Fatal("Invalid rollover configuration", zap.Error(err))
+	}
+
+	monitorCtx, stopMonitor := context.WithCancel(context.Background())
+	defer stopMonitor()
+	rolloverMonitor.Start(monitorCtx)
+
+	// Write metrics are sampled on the default cluster, like rollover
+	documentService.StartWriteMetricsSampler(monitorCtx, services.WriteMetricsSamplerConfig{
+		Interval:  config.MetricsHistory.Interval,
+		Retention: config.MetricsHistory.Retention,
+		Indices:   config.MetricsHistory.Indices,
+	})
 
 	// Initialize handlers
 	indexHandler := handlers.NewIndexHandler(indexService, documentService, logger)
-	documentHandler := handlers.NewDocumentHandler(documentService, logger)
+	documentHandler := handlers.NewDocumentHandler(documentService, indexService, logger)
+	overviewHandler := handlers.NewOverviewHandler(overviewService, logger)
+	rolloverHandler := handlers.NewRolloverHandler(rolloverMonitor, logger)
 
 	// Setup HTTP server
 	if config.Logging.Level != "debug" {
 		gin.SetMode(gin.ReleaseMode)
 	}
 
-	router := setupRoutes(indexHandler, documentHandler, logger)
+	if !config.Auth.Enabled() {
+		logger.Warn("No API keys configured, mutating endpoints are unauthenticated")
+	}
+
+	writeLimiter, err := shared.NewRateLimiter(config.RateLimit)
+	if err != nil {
+		logger.Fatal("Invalid rate limit configuration", zap.Error(err))
+	}
+
+	router := setupRoutes(indexHandler, documentHandler, overviewHandler, rolloverHandler, clusters, config.Auth, writeLimiter, config.Logging.BodyCaptureConfig, logger)
 
 	server := &http.Server{
 		Addr:         fmt.Sprintf("%s:%d", config.Server.Host, config.Server.Port),
@@ -146,6 +262,24 @@ func main() {
 	logger.Info("Index & Document Explorer exited")
 }
 
+// toESConfig converts a cluster's configuration to the shared client configuration
+func toESConfig(config ElasticsearchConfig) *shared.ESConfig {
+	return &shared.ESConfig{
+		URLs:     config.URLs,
+		Username: config.Username,
+		Password: config.Password,
+		APIKey:   config.APIKey,
+		TLSConfig: &shared.TLSConfig{
+			InsecureSkipVerify: config.TLSConfig.InsecureSkipVerify,
+			CACertPath:         config.TLSConfig.CACertPath,
+			ClientCertPath:     config.TLSConfig.ClientCertPath,
+			ClientKeyPath:      config.TLSConfig.ClientKeyPath,
+		},
+		RequestTimeout: config.RequestTimeout,
+		CircuitBreaker: config.CircuitBreaker,
+	}
+}
+
 func loadConfig() (*Config, error) {
 	configPath := os.Getenv("CONFIG_PATH")
 	if configPath == "" {
@@ -174,6 +308,29 @@ func loadConfig() (*Config, error) {
 			TLSConfig: TLSConfig{
 				InsecureSkipVerify: false,
 			},
+			RequestTimeout: 60 * time.Second,
+			CircuitBreaker: shared.BreakerConfig{
+				FailureThreshold: 5,
+				OpenTimeout:      30 * time.Second,
+			},
+		},
+		Dashboard: DashboardConfig{
+			ClusterExplorerURL: "http://localhost:8081",
+			SearchAPIURL:       "http://localhost:8083",
+			Timeout:            5 * time.Second,
+		},
+		Bulk: BulkConfig{
+			JobTTL:        time.Hour,
+			CheckpointTTL: 24 * time.Hour,
+		},
+		MetricsHistory: MetricsHistoryConfig{
+			Interval:  30 * time.Second,
+			Retention: 24 * time.Hour,
+		},
+		RateLimit: shared.RateLimitConfig{
+			RequestsPerSecond: 10,
+			Burst:             20,
+			Key:               shared.RateLimitByIP,
 		},
 		Logging: LoggingConfig{
 			Level:  "info",
@@ -191,6 +348,11 @@ func loadConfig() (*Config, error) {
 		log.Printf("Config file not found at %s, using defaults", configPath)
 	}
 
+	// Keep the key out of the config file in deployments
+	if apiKey := os.Getenv("API_KEY"); apiKey != "" {
+		config.Auth.APIKeys = append(config.Auth.APIKeys, apiKey)
+	}
+
 	return config, nil
 }
 
@@ -218,9 +380,13 @@ func initLogger(config LoggingConfig) (*zap.Logger, error) {
 	return zapConfig.Build()
 }
 
-func setupRoutes(indexHandler *handlers.IndexHandler, documentHandler *handlers.DocumentHandler, logger *zap.Logger) *gin.Engine {
+func setupRoutes(indexHandler *handlers.IndexHandler, documentHandler *handlers.DocumentHandler, overviewHandler *handlers.OverviewHandler, rolloverHandler *handlers.RolloverHandler, clusters *shared.ClusterRegistry, auth shared.AuthConfig, writeLimiter *shared.RateLimiter, bodyCapture shared.BodyCaptureConfig, logger *zap.Logger) *gin.Engine {
 	router := gin.New()
 
+	// Write-heavy routes share a per-client budget so concurrent clients can't push
+	// Elasticsearch into rejecting bulk requests
+	writeLimit := writeLimiter.Middleware(logger)
+
 	// Middleware
 	router.Use(gin.Logger())
 	router.Use(gin.Recovery())
@@ -236,11 +402,15 @@ func setupRoutes(indexHandler *handlers.IndexHandler, documentHandler *handlers.
 		c.Next()
 	})
 
+	// Request and response bodies at debug level, when logging.capture_bodies is set
+	router.Use(shared.CaptureBodies(bodyCapture, logger))
+
 	// CORS middleware for development
 	router.Use(func(c *gin.Context) {
 		c.Header("Access-Control-Allow-Origin", "*")
 		c.Header("Access-Control-Allow-Methods", "GET, POST, PUT, DELETE, OPTIONS")
-		c.Header("Access-Control-Allow-Headers", "Content-Type, Authorization, X-Request-ID")
+		c.Header("Access-Control-Allow-Headers", "Content-Type, Authorization, X-Request-ID, Idempotency-Key, X-ES-Cluster, X-API-Key")
+		c.Header("Access-Control-Expose-Headers", "Retry-After")
 		
 		if c.Request.Method == "OPTIONS" {
 			c.AbortWithStatus(http.StatusNoContent)
@@ -250,18 +420,58 @@ func setupRoutes(indexHandler *handlers.IndexHandler, documentHandler *handlers.
 		c.Next()
 	})
 
+	// Cluster selection middleware: X-ES-Cluster header or ?cluster= picks a registered cluster
+	router.Use(func(c *gin.Context) {
+		cluster := c.GetHeader("X-ES-Cluster")
+		if cluster == "" {
+			cluster = c.Query("cluster")
+		}
+		if cluster == "" {
+			c.Next()
+			return
+		}
+
+		if _, ok := clusters.Client(cluster); !ok {
+			c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{
+				"error":      "Unknown cluster",
+				"message":    fmt.Sprintf("Cluster %s is not registered", cluster),
+				"clusters":   clusters.Names(),
+				"request_id": c.GetString("request_id"),
+				"timestamp":  time.Now(),
+			})
+			return
+		}
+
+		c.Request = c.Request.WithContext(shared.WithCluster(c.Request.Context(), cluster))
+		c.Set("cluster", cluster)
+		c.Next()
+	})
+
 	// Health check
 	router.GET("/health", func(c *gin.Context) {
+		// An open circuit breaker means requests to that cluster are failing fast
+		status := "healthy"
+		breakers := clusters.BreakerStates()
+		for _, state := range breakers {
+			if state != shared.BreakerClosed {
+				status = "degraded"
+			}
+		}
+
 		c.JSON(http.StatusOK, gin.H{
-			"status":     "healthy",
+			"status":     status,
 			"service":    "index-explorer",
 			"version":    "1.0.0",
 			"focus":      "write-optimized Elasticsearch operations",
+			"breakers":   breakers,
 			"request_id": c.GetString("request_id"),
 			"timestamp":  time.Now(),
 		})
 	})
 
+	// Prometheus metrics: bulk documents, batches, latency and failures by index
+	router.GET("/metrics", gin.WrapH(promhttp.Handler()))
+
 	// Landing page - redirect to dashboard
 	router.GET("/", func(c *gin.Context) {
 		c.Redirect(http.StatusTemporaryRedirect, "/dashboard")
@@ -291,7 +501,13 @@ func setupRoutes(indexHandler *handlers.IndexHandler, documentHandler *handlers.
 				"indices":   "/api/v1/indices",
 				"documents": "/api/v1/indices/{index}/documents",
 				"bulk":      "/api/v1/indices/{index}/bulk",
+				"overview":  "/api/v1/overview",
+				"rollover":  "/api/v1/rollover",
+				"templates": "/api/v1/templates",
+				"pipelines": "/api/v1/pipelines",
+				"aliases":   "/api/v1/aliases",
 				"health":    "/health",
+				"metrics":   "/metrics",
 				"dashboard": "/dashboard",
 			},
 			"request_id": c.GetString("request_id"),
@@ -299,9 +515,20 @@ func setupRoutes(indexHandler *handlers.IndexHandler, documentHandler *handlers.
 		})
 	})
 
-	// API routes
+	// API routes; reads and writes need an API key unless auth.public_paths opens a read
 	v1 := router.Group("/api/v1")
+	v1.Use(shared.RequireAPIKey(auth, logger))
 	{
+		// Registered Elasticsearch clusters
+		v1.GET("/clusters", func(c *gin.Context) {
+			c.JSON(http.StatusOK, gin.H{
+				"clusters":   clusters.Names(),
+				"default":    clusters.DefaultName(),
+				"request_id": c.GetString("request_id"),
+				"timestamp":  time.Now(),
+			})
+		})
+
 		// Index management routes
 		indices := v1.Group("/indices")
 		{
@@ -313,35 +540,111 @@ func setupRoutes(indexHandler *handlers.IndexHandler, documentHandler *handlers.
 
 			// Write-optimized index creation
 			indices.POST("/write-optimized", indexHandler.CreateWriteOptimizedIndex)
+			indices.POST("/_preview-settings", indexHandler.PreviewSettings)
+			indices.POST("/_advise-shards", indexHandler.AdviseShards)
 
 			// Index optimization and tuning
 			indices.POST("/:index/optimize", indexHandler.OptimizeIndex)
 			indices.GET("/:index/recommendations", indexHandler.GetIndexRecommendations)
+			indices.GET("/:index/compliance", indexHandler.CheckCompliance)
 			indices.POST("/:index/tune/write-heavy", indexHandler.TuneIndexForWriteWorkload)
+			indices.POST("/:index/settings/diff", indexHandler.DiffIndexSettings)
 
 			// Performance analysis
 			indices.GET("/:index/performance/write", indexHandler.GetIndexWritePerformance)
 			indices.GET("/:index/analyze/write-performance", indexHandler.AnalyzeIndexWritePerformance)
+			indices.PUT("/:index/slowlog", indexHandler.ConfigureSlowLog)
+
+			// Replica management (load with 0 replicas, then ramp up for durability)
+			indices.POST("/:index/replicas", indexHandler.SetReplicas)
+			indices.PUT("/:index/replicas", indexHandler.SetReplicas)
+
+			// Merge segments down once writes have stopped
+			indices.POST("/:index/forcemerge", indexHandler.ForceMerge)
+
+			// Copy documents from another index, e.g. into a new write-optimized one
+			indices.POST("/:index/_reindex", writeLimit, indexHandler.Reindex)
+
+			// Consolidate into fewer shards once ingestion is done
+			indices.POST("/:index/_shrink", indexHandler.ShrinkIndex)
+
+			// Scale out into more shards when writes bottleneck
+			indices.POST("/:index/_split", indexHandler.SplitIndex)
+
+			// Snapshot restore under a new name with write-optimized settings
+			indices.POST("/:index/_restore", indexHandler.RestoreWriteOptimized)
+
+			// Aliases pointing at the index
+			indices.GET("/:index/aliases", indexHandler.GetIndexAliases)
+
+			// Suggest an explicit mapping from a sample document, for review before creating the index
+			indices.POST("/:index/mappings/infer", indexHandler.InferMapping)
 
 			// Document operations within index context
 			indices.POST("/:index/documents", documentHandler.IndexDocument)
 			indices.GET("/:index/documents/:id", documentHandler.GetDocument)
 			indices.PUT("/:index/documents/:id", documentHandler.UpdateDocument)
 			indices.DELETE("/:index/documents/:id", documentHandler.DeleteDocument)
+			indices.POST("/:index/_delete_by_query", writeLimit, documentHandler.DeleteByQuery)
+			indices.POST("/:index/_update_by_query", writeLimit, documentHandler.UpdateByQuery)
 
 			// Bulk operations (the primary focus)
-			indices.POST("/:index/bulk", documentHandler.BulkIndex)
-			indices.POST("/:index/import/ndjson", documentHandler.BulkImportNDJSON)
+			indices.POST("/:index/bulk", writeLimit, documentHandler.BulkIndex)
+			indices.POST("/:index/bulk/stream", writeLimit, documentHandler.BulkIndexStream)
+			indices.POST("/:index/import/ndjson", writeLimit, documentHandler.BulkImportNDJSON)
+			indices.POST("/:index/import/csv", writeLimit, documentHandler.BulkImportCSV)
+			indices.GET("/:index/import/checkpoints/:key", documentHandler.GetImportCheckpoint)
 
 			// Write performance metrics
 			indices.GET("/:index/metrics/write-performance", documentHandler.GetWritePerformanceMetrics)
+			indices.GET("/:index/metrics/write-performance/history", documentHandler.GetWritePerformanceHistory)
+		}
+
+		// Composable index templates applying write-optimized settings to matching indices
+		templates := v1.Group("/templates")
+		{
+			templates.POST("", indexHandler.CreateIndexTemplate)
+			templates.GET("", indexHandler.ListIndexTemplates)
+			templates.DELETE("/:name", indexHandler.DeleteIndexTemplate)
+		}
+
+		// Ingest pipelines enriching documents at index time; bulk requests use one
+		// through settings.pipeline
+		pipelines := v1.Group("/pipelines")
+		{
+			pipelines.GET("", indexHandler.ListPipelines)
+			pipelines.GET("/:id", indexHandler.GetPipeline)
+			pipelines.PUT("/:id", indexHandler.PutPipeline)
+			pipelines.DELETE("/:id", indexHandler.DeletePipeline)
+		}
+
+		// Alias management
+		aliases := v1.Group("/aliases")
+		{
+			// Atomic add/remove, e.g. swapping a read alias onto a reindexed index
+			aliases.POST("", indexHandler.UpdateAliases)
+			// Roll the write alias over to a new index once a condition is met
+			aliases.POST("/:alias/_rollover", indexHandler.RolloverAlias)
 		}
 
 		// Global bulk operations
 		bulk := v1.Group("/bulk")
 		{
-			bulk.POST("/adaptive", documentHandler.AdaptiveBulkIndex)
+			bulk.POST("/adaptive", writeLimit, documentHandler.AdaptiveBulkIndex)
 			bulk.GET("/status", documentHandler.GetBulkOperationStatus)
+			bulk.POST("/async", writeLimit, documentHandler.StartBulkJob)
+			bulk.DELETE("/async/:job_id", documentHandler.CancelBulkJob)
+		}
+
+		// Aggregated status of all playground services for the dashboard
+		v1.GET("/overview", overviewHandler.GetOverview)
+
+		// Automatic rollover of aliases by write index size, age and document count
+		rollover := v1.Group("/rollover")
+		{
+			rollover.GET("", rolloverHandler.ListRollovers)
+			rollover.PUT("/:alias", rolloverHandler.SetRolloverThresholds)
+			rollover.DELETE("/:alias", rolloverHandler.RemoveRollover)
 		}
 
 		// Metrics and monitoring
@@ -391,8 +694,15 @@ func setupRoutes(indexHandler *handlers.IndexHandler, documentHandler *handlers.
 				"bulk_import": gin.H{
 					"url":     "POST /api/v1/indices/{index}/import/ndjson",
 					"params":  "?batch_size=1000&workers=8",
+					"headers": "Idempotency-Key: <key> (optional, enables resumable checkpoints); Content-Encoding: gzip (optional)",
 					"example": "Send NDJSON data in request body",
 				},
+				"csv_import": gin.H{
+					"url":     "POST /api/v1/indices/{index}/import/csv",
+					"params":  "?delimiter=,&header=true&infer_types=true&batch_size=1000",
+					"headers": "Content-Encoding: gzip (optional)",
+					"example": "Send CSV data in request body; a column named _id sets the document ID",
+				},
 				"adaptive_bulk": gin.H{
 					"url":    "POST /api/v1/bulk/adaptive",
 					"example": gin.H{
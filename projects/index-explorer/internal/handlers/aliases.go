@@ -0,0 +1,123 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+
+	"github.com/saif-islam/es-playground/projects/index-explorer/internal/models"
+)
+
+// GetIndexAliases handles GET /api/v1/indices/:index/aliases
+func (h *IndexHandler) GetIndexAliases(c *gin.Context) {
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 15*time.Second)
+	defer cancel()
+
+	indexName := c.Param("index")
+
+	aliases, err := h.indexService.GetIndexAliases(ctx, indexName)
+	if err != nil {
+		h.logger.Error("Failed to get index aliases",
+			zap.String("index", indexName),
+			zap.Error(err))
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{
+			Error:     "Failed to get index aliases",
+			Message:   err.Error(),
+			RequestID: c.GetString("request_id"),
+			Timestamp: time.Now(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"index":      indexName,
+		"aliases":    aliases,
+		"count":      len(aliases),
+		"request_id": c.GetString("request_id"),
+		"timestamp":  time.Now(),
+	})
+}
+
+// UpdateAliases handles POST /api/v1/aliases
+func (h *IndexHandler) UpdateAliases(c *gin.Context) {
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 30*time.Second)
+	defer cancel()
+
+	var req struct {
+		Actions []models.AliasAction `json:"actions" binding:"required,min=1,dive"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		h.logger.Error("Invalid alias update request", zap.Error(err))
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{
+			Error:     "Invalid request",
+			Message:   err.Error(),
+			RequestID: c.GetString("request_id"),
+			Timestamp: time.Now(),
+		})
+		return
+	}
+
+	if err := h.indexService.UpdateAliases(ctx, req.Actions); err != nil {
+		h.logger.Error("Failed to update aliases", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{
+			Error:     "Failed to update aliases",
+			Message:   err.Error(),
+			RequestID: c.GetString("request_id"),
+			Timestamp: time.Now(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message":    "Aliases updated successfully",
+		"actions":    req.Actions,
+		"request_id": c.GetString("request_id"),
+		"timestamp":  time.Now(),
+	})
+}
+
+// RolloverAlias handles POST /api/v1/aliases/:alias/_rollover
+func (h *IndexHandler) RolloverAlias(c *gin.Context) {
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 60*time.Second)
+	defer cancel()
+
+	alias := c.Param("alias")
+
+	// Conditions are optional; without a body the alias rolls over unconditionally
+	var conditions models.RolloverConditions
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&conditions); err != nil {
+			h.logger.Error("Invalid rollover request", zap.Error(err))
+			c.JSON(http.StatusBadRequest, models.ErrorResponse{
+				Error:     "Invalid request",
+				Message:   err.Error(),
+				RequestID: c.GetString("request_id"),
+				Timestamp: time.Now(),
+			})
+			return
+		}
+	}
+
+	result, err := h.indexService.Rollover(ctx, alias, conditions)
+	if err != nil {
+		h.logger.Error("Failed to roll over alias",
+			zap.String("alias", alias),
+			zap.Error(err))
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{
+			Error:     "Failed to roll over alias",
+			Message:   err.Error(),
+			RequestID: c.GetString("request_id"),
+			Timestamp: time.Now(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"rollover":   result,
+		"request_id": c.GetString("request_id"),
+		"timestamp":  time.Now(),
+	})
+}
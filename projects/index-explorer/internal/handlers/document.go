@@ -1,8 +1,15 @@
 package handlers
 
 import (
+	"compress/flate"
+	"compress/gzip"
 	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
 	"strconv"
+	"strings"
 	"time"
 
 	"github.com/gin-gonic/gin"
@@ -10,18 +17,21 @@ import (
 
 	"github.com/saif-islam/es-playground/projects/index-explorer/internal/models"
 	"github.com/saif-islam/es-playground/projects/index-explorer/internal/services"
+	"github.com/saif-islam/es-playground/shared"
 )
 
 // DocumentHandler handles HTTP requests for document operations
 type DocumentHandler struct {
 	documentService *services.DocumentService
+	indexService    *services.IndexService
 	logger          *zap.Logger
 }
 
 // NewDocumentHandler creates a new document handler
-func NewDocumentHandler(documentService *services.DocumentService, logger *zap.Logger) *DocumentHandler {
+func NewDocumentHandler(documentService *services.DocumentService, indexService *services.IndexService, logger *zap.Logger) *DocumentHandler {
 	return &DocumentHandler{
 		documentService: documentService,
+		indexService:    indexService,
 		logger:          logger,
 	}
 }
@@ -65,6 +75,9 @@ func (h *DocumentHandler) BulkIndex(c *gin.Context) {
 		zap.String("optimize_for", req.OptimizeFor))
 
 	response, err := h.documentService.BulkIndex(ctx, &req)
+	if h.respondToleranceExceeded(c, err) {
+		return
+	}
 	if err != nil {
 		h.logger.Error("Failed to process bulk index",
 			zap.String("index", req.IndexName),
@@ -81,6 +94,83 @@ func (h *DocumentHandler) BulkIndex(c *gin.Context) {
 	c.JSON(http.StatusOK, response)
 }
 
+// BulkIndexStream handles POST /api/v1/indices/:index/bulk/stream. It runs a bulk request
+// like BulkIndex but answers with Server-Sent Events: a "progress" event after each batch
+// and a final "summary" event with the bulk response, or an "error" event if it fails.
+func (h *DocumentHandler) BulkIndexStream(c *gin.Context) {
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 300*time.Second) // 5 minutes for bulk operations
+	defer cancel()
+
+	var req models.BulkRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		h.logger.Error("Invalid bulk stream request", zap.Error(err))
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{
+			Error:     "Invalid request",
+			Message:   err.Error(),
+			RequestID: c.GetString("request_id"),
+			Timestamp: time.Now(),
+		})
+		return
+	}
+
+	// Set index name from URL if not provided in request
+	if req.IndexName == "" {
+		req.IndexName = c.Param("index")
+	}
+
+	// Once the event stream starts the status is 200, so reject bad requests first
+	if err := h.documentService.ValidateBulkRequest(&req); err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{
+			Error:     "Invalid bulk request",
+			Message:   err.Error(),
+			RequestID: c.GetString("request_id"),
+			Timestamp: time.Now(),
+		})
+		return
+	}
+
+	// Set up Server-Sent Events
+	c.Header("Content-Type", "text/event-stream")
+	c.Header("Cache-Control", "no-cache")
+	c.Header("Connection", "keep-alive")
+
+	response, err := h.documentService.StreamBulkIndex(ctx, &req, func(progress *models.BulkProgress) {
+		// A client that went away just stops receiving; the request itself is cancelled
+		// through its context
+		if c.Request.Context().Err() != nil {
+			return
+		}
+		c.SSEvent("progress", progress)
+		c.Writer.Flush()
+	})
+
+	var toleranceErr *services.BulkToleranceError
+	switch {
+	case errors.As(err, &toleranceErr):
+		c.SSEvent("error", gin.H{
+			"error":           "Error tolerance exceeded",
+			"message":         err.Error(),
+			"error_tolerance": toleranceErr.Tolerance,
+			"bulk_response":   toleranceErr.Response,
+			"request_id":      c.GetString("request_id"),
+			"timestamp":       time.Now(),
+		})
+	case err != nil:
+		h.logger.Error("Failed to process bulk index stream",
+			zap.String("index", req.IndexName),
+			zap.Error(err))
+		c.SSEvent("error", models.ErrorResponse{
+			Error:     "Failed to process bulk index",
+			Message:   err.Error(),
+			RequestID: c.GetString("request_id"),
+			Timestamp: time.Now(),
+		})
+	default:
+		c.SSEvent("summary", response)
+	}
+	c.Writer.Flush()
+}
+
 // BulkImportNDJSON handles POST /api/v1/indices/:index/import/ndjson
 func (h *DocumentHandler) BulkImportNDJSON(c *gin.Context) {
 	ctx, cancel := context.WithTimeout(c.Request.Context(), 600*time.Second) // 10 minutes for large imports
@@ -97,12 +187,245 @@ func (h *DocumentHandler) BulkImportNDJSON(c *gin.Context) {
 		return
 	}
 
-	// Parse query parameters for import options
+	options := importOptions(c)
+
+	// Idempotency key enables checkpointing so an interrupted import can be resumed
+	options.IdempotencyKey = c.GetHeader("Idempotency-Key")
+	if options.IdempotencyKey == "" {
+		options.IdempotencyKey = c.Query("idempotency_key")
+	}
+
+	if intervalStr := c.Query("checkpoint_interval"); intervalStr != "" {
+		if interval, err := strconv.Atoi(intervalStr); err == nil && interval > 0 {
+			options.CheckpointInterval = interval
+		}
+	}
+
+	targetReplicas := importTargetReplicas(c)
+
+	h.logger.Info("Processing NDJSON bulk import",
+		zap.String("index", indexName),
+		zap.Int("batch_size", options.BatchSize),
+		zap.Int("workers", options.ParallelWorkers),
+		zap.Bool("calibrate", options.Calibrate),
+		zap.String("idempotency_key", options.IdempotencyKey))
+
+	// Get request body as NDJSON
+	defer c.Request.Body.Close()
+	body, ok := h.importBody(c)
+	if !ok {
+		return
+	}
+
+	response, err := h.documentService.BulkImportFromNDJSON(ctx, indexName, body, options)
+	if h.respondToleranceExceeded(c, err) || h.respondCorruptEncoding(c, err) {
+		return
+	}
+	if errors.Is(err, services.ErrImportInProgress) {
+		c.JSON(http.StatusConflict, models.ErrorResponse{
+			Error:     "Import in progress",
+			Message:   err.Error(),
+			Details:   "wait for the running import to finish; if it stopped, retry to resume from its checkpoint",
+			RequestID: c.GetString("request_id"),
+			Timestamp: time.Now(),
+		})
+		return
+	}
+	if err != nil {
+		h.logger.Error("Failed to import NDJSON",
+			zap.String("index", indexName),
+			zap.Error(err))
+		errResp := models.ErrorResponse{
+			Error:     "Failed to import NDJSON",
+			Message:   err.Error(),
+			RequestID: c.GetString("request_id"),
+			Timestamp: time.Now(),
+		}
+		if checkpoint, err := h.documentService.GetImportCheckpoint(c.Request.Context(), options.IdempotencyKey); err == nil && checkpoint != nil {
+			errResp.Details = fmt.Sprintf("checkpoint at line %d (%d bytes); re-send with Idempotency-Key %s to resume",
+				checkpoint.LinesProcessed, checkpoint.BytesProcessed, checkpoint.IdempotencyKey)
+		}
+		c.JSON(http.StatusInternalServerError, errResp)
+		return
+	}
+
+	result := gin.H{
+		"message":    "NDJSON import completed successfully",
+		"index_name": indexName,
+		"summary":    response.Summary,
+		"request_id": c.GetString("request_id"),
+		"timestamp":  time.Now(),
+	}
+	if options.Calibrate {
+		if calibration, ok := h.documentService.GetCalibration(indexName); ok {
+			result["calibration"] = calibration
+		}
+	}
+	if checkpoint, err := h.documentService.GetImportCheckpoint(c.Request.Context(), options.IdempotencyKey); err == nil && checkpoint != nil {
+		result["checkpoint"] = checkpoint
+	}
+
+	h.rampUpAfterImport(c.Request.Context(), indexName, targetReplicas, result)
+
+	c.JSON(http.StatusOK, result)
+}
+
+// BulkImportCSV handles POST /api/v1/indices/:index/import/csv?delimiter=;&header=true&infer_types=true
+func (h *DocumentHandler) BulkImportCSV(c *gin.Context) {
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 600*time.Second) // 10 minutes for large imports
+	defer cancel()
+
+	indexName := c.Param("index")
+	if indexName == "" {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{
+			Error:     "Missing index name",
+			Message:   "Index name is required",
+			RequestID: c.GetString("request_id"),
+			Timestamp: time.Now(),
+		})
+		return
+	}
+
+	options := services.CSVImportOptions{
+		BulkImportOptions: *importOptions(c),
+		Delimiter:         ',',
+		Header:            c.DefaultQuery("header", "true") != "false",
+		InferTypes:        c.Query("infer_types") == "true",
+	}
+
+	// "\t" is accepted for tab-separated input, which is awkward to put in a URL
+	if delimiter := c.Query("delimiter"); delimiter != "" {
+		if delimiter == "\\t" {
+			delimiter = "\t"
+		}
+		runes := []rune(delimiter)
+		if len(runes) != 1 || runes[0] == '"' || runes[0] == '\r' || runes[0] == '\n' {
+			c.JSON(http.StatusBadRequest, models.ErrorResponse{
+				Error:     "Invalid request",
+				Message:   "delimiter must be a single character other than a quote or newline",
+				RequestID: c.GetString("request_id"),
+				Timestamp: time.Now(),
+			})
+			return
+		}
+		options.Delimiter = runes[0]
+	}
+
+	targetReplicas := importTargetReplicas(c)
+
+	h.logger.Info("Processing CSV bulk import",
+		zap.String("index", indexName),
+		zap.String("delimiter", string(options.Delimiter)),
+		zap.Bool("header", options.Header),
+		zap.Bool("infer_types", options.InferTypes))
+
+	defer c.Request.Body.Close()
+	body, ok := h.importBody(c)
+	if !ok {
+		return
+	}
+
+	response, err := h.documentService.BulkImportFromCSV(ctx, indexName, body, options)
+	if h.respondToleranceExceeded(c, err) || h.respondCorruptEncoding(c, err) {
+		return
+	}
+	if err != nil {
+		h.logger.Error("Failed to import CSV",
+			zap.String("index", indexName),
+			zap.Error(err))
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{
+			Error:     "Failed to import CSV",
+			Message:   err.Error(),
+			RequestID: c.GetString("request_id"),
+			Timestamp: time.Now(),
+		})
+		return
+	}
+
+	result := gin.H{
+		"message":    "CSV import completed successfully",
+		"index_name": indexName,
+		"summary":    response.Summary,
+		"request_id": c.GetString("request_id"),
+		"timestamp":  time.Now(),
+	}
+	if options.Calibrate {
+		if calibration, ok := h.documentService.GetCalibration(indexName); ok {
+			result["calibration"] = calibration
+		}
+	}
+
+	h.rampUpAfterImport(c.Request.Context(), indexName, targetReplicas, result)
+
+	c.JSON(http.StatusOK, result)
+}
+
+// importBody returns the import request body, decompressed when it is sent with
+// Content-Encoding: gzip. It responds and reports false when the encoding is unsupported
+// or the body does not start with a gzip header.
+func (h *DocumentHandler) importBody(c *gin.Context) (io.Reader, bool) {
+	switch contentEncoding(c) {
+	case "", "identity":
+		return c.Request.Body, true
+	case "gzip", "x-gzip":
+		gz, err := gzip.NewReader(c.Request.Body)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, models.ErrorResponse{
+				Error:     "Invalid content encoding",
+				Message:   fmt.Sprintf("Content-Encoding is gzip but the body is not a gzip stream: %v", err),
+				RequestID: c.GetString("request_id"),
+				Timestamp: time.Now(),
+			})
+			return nil, false
+		}
+		return gz, true
+	default:
+		c.JSON(http.StatusUnsupportedMediaType, models.ErrorResponse{
+			Error:     "Unsupported content encoding",
+			Message:   fmt.Sprintf("Content-Encoding %q is not supported; send the body uncompressed or gzipped", c.GetHeader("Content-Encoding")),
+			RequestID: c.GetString("request_id"),
+			Timestamp: time.Now(),
+		})
+		return nil, false
+	}
+}
+
+// respondCorruptEncoding responds with 400 when a gzipped import failed because the
+// stream turned out to be corrupt or truncated part way through
+func (h *DocumentHandler) respondCorruptEncoding(c *gin.Context, err error) bool {
+	if err == nil || (contentEncoding(c) != "gzip" && contentEncoding(c) != "x-gzip") {
+		return false
+	}
+
+	var corrupt flate.CorruptInputError
+	if !errors.Is(err, gzip.ErrChecksum) && !errors.Is(err, gzip.ErrHeader) &&
+		!errors.Is(err, io.ErrUnexpectedEOF) && !errors.As(err, &corrupt) {
+		return false
+	}
+
+	h.logger.Warn("Import body is not valid gzip", zap.Error(err))
+	c.JSON(http.StatusBadRequest, models.ErrorResponse{
+		Error:     "Invalid content encoding",
+		Message:   fmt.Sprintf("Content-Encoding is gzip but the body is not a valid gzip stream: %v", err),
+		RequestID: c.GetString("request_id"),
+		Timestamp: time.Now(),
+	})
+	return true
+}
+
+// contentEncoding returns the request's normalized Content-Encoding
+func contentEncoding(c *gin.Context) string {
+	return strings.ToLower(strings.TrimSpace(c.GetHeader("Content-Encoding")))
+}
+
+// importOptions parses the bulk options shared by the import endpoints from the query
+func importOptions(c *gin.Context) *services.BulkImportOptions {
 	options := &services.BulkImportOptions{
 		BatchSize:       1000, // Default
 		ParallelWorkers: 8,    // Default
-		ErrorTolerance:  "medium",
+		ErrorTolerance:  services.ErrorToleranceMedium,
 		GenerateIDs:     true,
+		Priority:        services.PriorityLow, // Imports yield to interactive writes
 	}
 
 	if batchSizeStr := c.Query("batch_size"); batchSizeStr != "" {
@@ -121,37 +444,92 @@ func (h *DocumentHandler) BulkImportNDJSON(c *gin.Context) {
 		options.ErrorTolerance = tolerance
 	}
 
+	if priority := c.Query("priority"); priority != "" {
+		options.Priority = priority
+	}
+
 	if generateIDs := c.Query("generate_ids"); generateIDs == "false" {
 		options.GenerateIDs = false
 	}
 
-	h.logger.Info("Processing NDJSON bulk import",
-		zap.String("index", indexName),
-		zap.Int("batch_size", options.BatchSize),
-		zap.Int("workers", options.ParallelWorkers))
+	if deadLetterIndex := c.Query("dead_letter_index"); deadLetterIndex != "" {
+		options.DeadLetterIndex = deadLetterIndex
+	}
 
-	// Get request body as NDJSON
-	body := c.Request.Body
-	defer body.Close()
+	// Calibration measures batch size and workers on a sample, replacing the values above
+	if calibrate := c.Query("calibrate"); calibrate == "true" {
+		options.Calibrate = true
+	}
 
-	response, err := h.documentService.BulkImportFromNDJSON(ctx, indexName, body, options)
+	return options
+}
+
+// importTargetReplicas parses target_replicas: the index is loaded with its current
+// replicas, then ramped up to this count once the import is done. -1 means no ramp-up.
+func importTargetReplicas(c *gin.Context) int {
+	if replicasStr := c.Query("target_replicas"); replicasStr != "" {
+		if replicas, err := strconv.Atoi(replicasStr); err == nil && replicas >= 0 {
+			return replicas
+		}
+	}
+	return -1
+}
+
+// rampUpAfterImport raises the index's replicas to targetReplicas and adds the outcome to
+// result. The import already succeeded, so a failed ramp-up is reported rather than
+// failing the request. The ramp-up doesn't share the import's deadline: like
+// SetReplicas, it gets defaultReplicaStepTimeout for each step plus one more.
+func (h *DocumentHandler) rampUpAfterImport(requestCtx context.Context, indexName string, targetReplicas int, result gin.H) {
+	if targetReplicas < 0 {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(requestCtx, defaultReplicaStepTimeout*time.Duration(targetReplicas+1))
+	defer cancel()
+
+	rampUp, err := h.indexService.RampUpReplicas(ctx, indexName, targetReplicas, defaultReplicaStepTimeout)
 	if err != nil {
-		h.logger.Error("Failed to import NDJSON",
+		h.logger.Warn("Failed to ramp up replicas after import",
 			zap.String("index", indexName),
+			zap.Int("target_replicas", targetReplicas),
 			zap.Error(err))
-		c.JSON(http.StatusInternalServerError, models.ErrorResponse{
-			Error:     "Failed to import NDJSON",
+		result["replica_ramp_up_error"] = err.Error()
+		return
+	}
+	result["replica_ramp_up"] = rampUp
+}
+
+// GetImportCheckpoint handles GET /api/v1/indices/:index/import/checkpoints/:key
+func (h *DocumentHandler) GetImportCheckpoint(c *gin.Context) {
+	indexName := c.Param("index")
+	key := c.Param("key")
+
+	checkpoint, err := h.documentService.GetImportCheckpoint(c.Request.Context(), key)
+	if err != nil {
+		h.logger.Error("Failed to get import checkpoint",
+			zap.String("index", indexName),
+			zap.String("idempotency_key", key),
+			zap.Error(err))
+		c.JSON(shared.HTTPStatus(err), models.ErrorResponse{
+			Error:     "Failed to get import checkpoint",
 			Message:   err.Error(),
 			RequestID: c.GetString("request_id"),
 			Timestamp: time.Now(),
 		})
 		return
 	}
+	if checkpoint == nil || checkpoint.IndexName != indexName {
+		c.JSON(http.StatusNotFound, models.ErrorResponse{
+			Error:     "Checkpoint not found",
+			Message:   fmt.Sprintf("No import checkpoint for key %s on index %s", key, indexName),
+			RequestID: c.GetString("request_id"),
+			Timestamp: time.Now(),
+		})
+		return
+	}
 
 	c.JSON(http.StatusOK, gin.H{
-		"message":    "NDJSON import completed successfully",
-		"index_name": indexName,
-		"summary":    response.Summary,
+		"checkpoint": checkpoint,
 		"request_id": c.GetString("request_id"),
 		"timestamp":  time.Now(),
 	})
@@ -203,6 +581,7 @@ func (h *DocumentHandler) AdaptiveBulkIndex(c *gin.Context) {
 		Operations:     operations,
 		OptimizeFor:    req.OptimizeFor,
 		ErrorTolerance: req.ErrorTolerance,
+		Adaptive:       true,
 	}
 
 	// Set defaults
@@ -210,7 +589,7 @@ func (h *DocumentHandler) AdaptiveBulkIndex(c *gin.Context) {
 		bulkReq.OptimizeFor = "write_throughput"
 	}
 	if bulkReq.ErrorTolerance == "" {
-		bulkReq.ErrorTolerance = "medium"
+		bulkReq.ErrorTolerance = services.ErrorToleranceMedium
 	}
 
 	// Adaptive batch sizing based on target throughput
@@ -220,6 +599,9 @@ func (h *DocumentHandler) AdaptiveBulkIndex(c *gin.Context) {
 	}
 
 	response, err := h.documentService.BulkIndex(ctx, bulkReq)
+	if h.respondToleranceExceeded(c, err) {
+		return
+	}
 	if err != nil {
 		h.logger.Error("Failed to process adaptive bulk index",
 			zap.String("index", req.IndexName),
@@ -233,13 +615,17 @@ func (h *DocumentHandler) AdaptiveBulkIndex(c *gin.Context) {
 		return
 	}
 
-	// Add adaptive parameters to response
+	// Add adaptive parameters to response: the settings the request started with and those
+	// it ended on after adapting to the cluster (the timeline is in bulk_response.adaptation)
 	adaptiveResponse := gin.H{
 		"bulk_response": response,
 		"adaptive_settings": gin.H{
-			"batch_size":       bulkReq.BatchSize,
-			"parallel_workers": bulkReq.ParallelWorkers,
-			"target_throughput": req.TargetThroughput,
+			"batch_size":             bulkReq.BatchSize,
+			"parallel_workers":       bulkReq.ParallelWorkers,
+			"final_batch_size":       response.Adaptation.FinalBatchSize,
+			"final_parallel_workers": response.Adaptation.FinalWorkers,
+			"adjustments":            response.Adaptation.Adjustments,
+			"target_throughput":      req.TargetThroughput,
 		},
 		"request_id": c.GetString("request_id"),
 		"timestamp":  time.Now(),
@@ -248,6 +634,28 @@ func (h *DocumentHandler) AdaptiveBulkIndex(c *gin.Context) {
 	c.JSON(http.StatusOK, adaptiveResponse)
 }
 
+// respondToleranceExceeded answers 422 with the partial results when a bulk request failed
+// more operations than its error_tolerance allows, reporting whether it did
+func (h *DocumentHandler) respondToleranceExceeded(c *gin.Context, err error) bool {
+	var toleranceErr *services.BulkToleranceError
+	if !errors.As(err, &toleranceErr) {
+		return false
+	}
+
+	h.logger.Warn("Bulk request exceeded its error tolerance",
+		zap.String("error_tolerance", toleranceErr.Tolerance),
+		zap.Error(err))
+	c.JSON(http.StatusUnprocessableEntity, gin.H{
+		"error":           "Error tolerance exceeded",
+		"message":         err.Error(),
+		"error_tolerance": toleranceErr.Tolerance,
+		"bulk_response":   toleranceErr.Response,
+		"request_id":      c.GetString("request_id"),
+		"timestamp":       time.Now(),
+	})
+	return true
+}
+
 // calculateAdaptiveBatchSize calculates optimal batch size based on target throughput
 func (h *DocumentHandler) calculateAdaptiveBatchSize(documents []map[string]interface{}, targetThroughput string) int {
 	// Estimate average document size from sample
@@ -385,7 +793,7 @@ func (h *DocumentHandler) IndexDocument(c *gin.Context) {
 			zap.String("index", indexName),
 			zap.String("id", docID),
 			zap.Error(err))
-		c.JSON(http.StatusInternalServerError, models.ErrorResponse{
+		c.JSON(shared.HTTPStatus(err), models.ErrorResponse{
 			Error:     "Failed to index document",
 			Message:   err.Error(),
 			RequestID: c.GetString("request_id"),
@@ -421,13 +829,7 @@ func (h *DocumentHandler) GetDocument(c *gin.Context) {
 			zap.String("index", indexName),
 			zap.String("id", docID),
 			zap.Error(err))
-		
-		status := http.StatusInternalServerError
-		if err.Error() == "document not found" {
-			status = http.StatusNotFound
-		}
-		
-		c.JSON(status, models.ErrorResponse{
+		c.JSON(shared.HTTPStatus(err), models.ErrorResponse{
 			Error:     "Failed to get document",
 			Message:   err.Error(),
 			RequestID: c.GetString("request_id"),
@@ -481,7 +883,7 @@ func (h *DocumentHandler) UpdateDocument(c *gin.Context) {
 			zap.String("index", indexName),
 			zap.String("id", docID),
 			zap.Error(err))
-		c.JSON(http.StatusInternalServerError, models.ErrorResponse{
+		c.JSON(shared.HTTPStatus(err), models.ErrorResponse{
 			Error:     "Failed to update document",
 			Message:   err.Error(),
 			RequestID: c.GetString("request_id"),
@@ -517,7 +919,7 @@ func (h *DocumentHandler) DeleteDocument(c *gin.Context) {
 			zap.String("index", indexName),
 			zap.String("id", docID),
 			zap.Error(err))
-		c.JSON(http.StatusInternalServerError, models.ErrorResponse{
+		c.JSON(shared.HTTPStatus(err), models.ErrorResponse{
 			Error:     "Failed to delete document",
 			Message:   err.Error(),
 			RequestID: c.GetString("request_id"),
@@ -529,14 +931,250 @@ func (h *DocumentHandler) DeleteDocument(c *gin.Context) {
 	c.JSON(http.StatusOK, response)
 }
 
+// DeleteByQuery handles POST /api/v1/indices/:index/_delete_by_query
+func (h *DocumentHandler) DeleteByQuery(c *gin.Context) {
+	indexName := c.Param("index")
+
+	var req struct {
+		Query map[string]interface{} `json:"query" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		h.logger.Error("Invalid delete by query request", zap.Error(err))
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{
+			Error:     "Invalid request",
+			Message:   err.Error(),
+			RequestID: c.GetString("request_id"),
+			Timestamp: time.Now(),
+		})
+		return
+	}
+
+	// Large purges can outlast any request; hand back a task to poll instead
+	if c.Query("wait_for_completion") == "false" {
+		ctx, cancel := context.WithTimeout(c.Request.Context(), 30*time.Second)
+		defer cancel()
+
+		result, err := h.documentService.StartDeleteByQuery(ctx, indexName, req.Query)
+		if err != nil {
+			h.respondDeleteByQueryError(c, indexName, err)
+			return
+		}
+
+		c.JSON(http.StatusAccepted, gin.H{
+			"delete_by_query": result,
+			"status_url":      "/_tasks/" + result.TaskID, // on the Elasticsearch cluster
+			"request_id":      c.GetString("request_id"),
+			"timestamp":       time.Now(),
+		})
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 300*time.Second) // 5 minutes for large deletes
+	defer cancel()
+
+	result, err := h.documentService.DeleteByQuery(ctx, indexName, req.Query)
+	if err != nil {
+		h.respondDeleteByQueryError(c, indexName, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"delete_by_query": result,
+		"request_id":      c.GetString("request_id"),
+		"timestamp":       time.Now(),
+	})
+}
+
+func (h *DocumentHandler) respondDeleteByQueryError(c *gin.Context, indexName string, err error) {
+	h.logger.Error("Failed to delete by query",
+		zap.String("index", indexName),
+		zap.Error(err))
+
+	message := err.Error()
+	if errors.Is(err, context.DeadlineExceeded) {
+		message += " (the delete continues in Elasticsearch; use wait_for_completion=false for large deletes)"
+	}
+
+	c.JSON(http.StatusInternalServerError, models.ErrorResponse{
+		Error:     "Failed to delete by query",
+		Message:   message,
+		RequestID: c.GetString("request_id"),
+		Timestamp: time.Now(),
+	})
+}
+
+// UpdateByQuery handles POST /api/v1/indices/:index/_update_by_query
+func (h *DocumentHandler) UpdateByQuery(c *gin.Context) {
+	indexName := c.Param("index")
+
+	var req models.UpdateByQueryRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		h.logger.Error("Invalid update by query request", zap.Error(err))
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{
+			Error:     "Invalid request",
+			Message:   err.Error(),
+			RequestID: c.GetString("request_id"),
+			Timestamp: time.Now(),
+		})
+		return
+	}
+
+	// Large rewrites can outlast any request; hand back a task to poll instead
+	if c.Query("wait_for_completion") == "false" {
+		ctx, cancel := context.WithTimeout(c.Request.Context(), 30*time.Second)
+		defer cancel()
+
+		result, err := h.documentService.StartUpdateByQuery(ctx, indexName, &req)
+		if err != nil {
+			h.respondUpdateByQueryError(c, indexName, err)
+			return
+		}
+
+		c.JSON(http.StatusAccepted, gin.H{
+			"update_by_query": result,
+			"status_url":      "/_tasks/" + result.TaskID, // on the Elasticsearch cluster
+			"request_id":      c.GetString("request_id"),
+			"timestamp":       time.Now(),
+		})
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 300*time.Second) // 5 minutes for large updates
+	defer cancel()
+
+	result, err := h.documentService.UpdateByQuery(ctx, indexName, &req)
+	if err != nil {
+		h.respondUpdateByQueryError(c, indexName, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"update_by_query": result,
+		"request_id":      c.GetString("request_id"),
+		"timestamp":       time.Now(),
+	})
+}
+
+func (h *DocumentHandler) respondUpdateByQueryError(c *gin.Context, indexName string, err error) {
+	h.logger.Error("Failed to update by query",
+		zap.String("index", indexName),
+		zap.Error(err))
+
+	message := err.Error()
+	if errors.Is(err, context.DeadlineExceeded) {
+		message += " (the update continues in Elasticsearch; use wait_for_completion=false for large updates)"
+	}
+
+	c.JSON(http.StatusInternalServerError, models.ErrorResponse{
+		Error:     "Failed to update by query",
+		Message:   message,
+		RequestID: c.GetString("request_id"),
+		Timestamp: time.Now(),
+	})
+}
+
 // GetBulkOperationStatus handles GET /api/v1/bulk/status
 func (h *DocumentHandler) GetBulkOperationStatus(c *gin.Context) {
-	// This would typically track ongoing bulk operations
-	// For now, return a simple status
+	// A single background job's progress
+	if jobID := c.Query("job_id"); jobID != "" {
+		job, ok := h.documentService.GetBulkJob(jobID)
+		if !ok {
+			c.JSON(http.StatusNotFound, models.ErrorResponse{
+				Error:     "Bulk job not found",
+				Message:   fmt.Sprintf("No bulk job with ID %s (finished jobs expire)", jobID),
+				RequestID: c.GetString("request_id"),
+				Timestamp: time.Now(),
+			})
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{
+			"job":        job,
+			"request_id": c.GetString("request_id"),
+			"timestamp":  time.Now(),
+		})
+		return
+	}
+
+	// Batches queued and running on the shared bulk scheduler, by priority
+	queue := h.documentService.GetBulkQueueStats()
+
+	activeOperations := 0
+	for _, running := range queue.Running {
+		activeOperations += running
+	}
+
+	jobs := h.documentService.ListBulkJobs()
+	activeJobs := 0
+	for _, job := range jobs {
+		if job.Status == services.BulkJobRunning {
+			activeJobs++
+		}
+	}
+
 	c.JSON(http.StatusOK, gin.H{
 		"message": "Bulk operations status endpoint",
 		"status":  "operational",
-		"active_operations": 0, // Would track actual operations
+		"active_operations": activeOperations, // batches currently being sent
+		"active_jobs":       activeJobs,
+		"queue":             queue,
+		"jobs":              jobs,
+		"request_id": c.GetString("request_id"),
+		"timestamp":  time.Now(),
+	})
+}
+
+// StartBulkJob handles POST /api/v1/bulk/async
+func (h *DocumentHandler) StartBulkJob(c *gin.Context) {
+	var req models.BulkRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		h.logger.Error("Invalid async bulk request", zap.Error(err))
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{
+			Error:     "Invalid request",
+			Message:   err.Error(),
+			RequestID: c.GetString("request_id"),
+			Timestamp: time.Now(),
+		})
+		return
+	}
+
+	job, err := h.documentService.StartBulkJob(c.Request.Context(), &req)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{
+			Error:     "Invalid bulk request",
+			Message:   err.Error(),
+			RequestID: c.GetString("request_id"),
+			Timestamp: time.Now(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusAccepted, gin.H{
+		"job":        job,
+		"status_url": "/api/v1/bulk/status?job_id=" + job.ID,
+		"request_id": c.GetString("request_id"),
+		"timestamp":  time.Now(),
+	})
+}
+
+// CancelBulkJob handles DELETE /api/v1/bulk/async/:job_id
+func (h *DocumentHandler) CancelBulkJob(c *gin.Context) {
+	jobID := c.Param("job_id")
+
+	job, ok := h.documentService.CancelBulkJob(jobID)
+	if !ok {
+		c.JSON(http.StatusNotFound, models.ErrorResponse{
+			Error:     "Bulk job not found",
+			Message:   fmt.Sprintf("No bulk job with ID %s (finished jobs expire)", jobID),
+			RequestID: c.GetString("request_id"),
+			Timestamp: time.Now(),
+		})
+		return
+	}
+
+	// Cancellation is asynchronous; the job reports cancelled once in-flight batches stop
+	c.JSON(http.StatusOK, gin.H{
+		"job":        job,
 		"request_id": c.GetString("request_id"),
 		"timestamp":  time.Now(),
 	})
@@ -578,4 +1216,44 @@ func (h *DocumentHandler) GetWritePerformanceMetrics(c *gin.Context) {
 		"request_id": c.GetString("request_id"),
 		"timestamp":  time.Now(),
 	})
-}
\ No newline at end of file
+}
+
+// GetWritePerformanceHistory handles GET /api/v1/indices/:index/metrics/write-performance/history?window=1h
+func (h *DocumentHandler) GetWritePerformanceHistory(c *gin.Context) {
+	indexName := c.Param("index")
+
+	window := time.Hour
+	if value := c.Query("window"); value != "" {
+		parsed, err := time.ParseDuration(value)
+		if err != nil || parsed <= 0 {
+			c.JSON(http.StatusBadRequest, models.ErrorResponse{
+				Error:     "Invalid request",
+				Message:   "window must be a positive duration such as 15m or 6h",
+				RequestID: c.GetString("request_id"),
+				Timestamp: time.Now(),
+			})
+			return
+		}
+		window = parsed
+	}
+
+	samples, interval, ok := h.documentService.WriteMetricsHistory(indexName, window)
+	if !ok {
+		c.JSON(http.StatusServiceUnavailable, models.ErrorResponse{
+			Error:     "Write metrics history unavailable",
+			Message:   "The write metrics sampler is not running",
+			RequestID: c.GetString("request_id"),
+			Timestamp: time.Now(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, models.WriteMetricsHistory{
+		IndexName: indexName,
+		Window:    window.String(),
+		Interval:  interval.String(),
+		Samples:   samples,
+		RequestID: c.GetString("request_id"),
+		Timestamp: time.Now(),
+	})
+}
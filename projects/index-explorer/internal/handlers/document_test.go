@@ -0,0 +1,141 @@
+package handlers
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+
+	"github.com/saif-islam/es-playground/projects/index-explorer/internal/services"
+	"github.com/saif-islam/es-playground/shared"
+)
+
+// fakeBulkCluster is a fake Elasticsearch that accepts every bulk action and keeps the
+// documents it was sent
+type fakeBulkCluster struct {
+	mu        sync.Mutex
+	documents []map[string]interface{}
+}
+
+func (f *fakeBulkCluster) serveHTTP(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("X-Elastic-Product", "Elasticsearch")
+	w.Header().Set("Content-Type", "application/json")
+	if !strings.HasSuffix(r.URL.Path, "/_bulk") {
+		w.Write([]byte(`{"version":{"number":"8.11.1"}}`))
+		return
+	}
+
+	var items []map[string]interface{}
+	scanner := bufio.NewScanner(r.Body)
+	for scanner.Scan() {
+		var action map[string]map[string]interface{}
+		if err := json.Unmarshal(scanner.Bytes(), &action); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			fmt.Fprintf(w, `{"error":{"type":"illegal_argument_exception","reason":%q},"status":400}`, err.Error())
+			return
+		}
+		var document map[string]interface{}
+		if scanner.Scan() {
+			json.Unmarshal(scanner.Bytes(), &document)
+		}
+		f.mu.Lock()
+		f.documents = append(f.documents, document)
+		f.mu.Unlock()
+		for name, meta := range action {
+			items = append(items, map[string]interface{}{
+				name: map[string]interface{}{"_index": meta["_index"], "_id": fmt.Sprintf("%d", len(items)), "result": "created", "status": http.StatusCreated},
+			})
+		}
+	}
+	json.NewEncoder(w).Encode(map[string]interface{}{"took": 1, "errors": false, "items": items})
+}
+
+// newImportRouter serves the import endpoints backed by a fakeBulkCluster
+func newImportRouter(t *testing.T) (*gin.Engine, *fakeBulkCluster) {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+
+	cluster := &fakeBulkCluster{}
+	es := httptest.NewServer(http.HandlerFunc(cluster.serveHTTP))
+	t.Cleanup(es.Close)
+
+	client, err := shared.NewESClient(&shared.ESConfig{URLs: []string{es.URL}}, zap.NewNop())
+	if err != nil {
+		t.Fatalf("Failed to create client: %v", err)
+	}
+	handler := NewDocumentHandler(services.NewDocumentService(client, zap.NewNop()), services.NewIndexService(client, zap.NewNop()), zap.NewNop())
+
+	router := gin.New()
+	router.POST("/api/v1/indices/:index/import/ndjson", handler.BulkImportNDJSON)
+	router.POST("/api/v1/indices/:index/import/csv", handler.BulkImportCSV)
+	return router, cluster
+}
+
+func gzipped(t *testing.T, data string) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	gz := gzip.NewWriter(&buf)
+	if _, err := gz.Write([]byte(data)); err != nil {
+		t.Fatalf("Failed to compress: %v", err)
+	}
+	gz.Close()
+	return buf.Bytes()
+}
+
+func TestBulkImport_ContentEncoding(t *testing.T) {
+	ndjson := "{\"title\":\"Laptop\",\"price\":999}\n{\"title\":\"Mouse\",\"price\":25}\n{\"title\":\"Dock\",\"price\":180}\n"
+	csv := "title,price\nLaptop,999\nMouse,25\nDock,180\n"
+	compressedNDJSON := gzipped(t, ndjson)
+
+	testCases := []struct {
+		name              string
+		path              string
+		encoding          string
+		body              []byte
+		expectedStatus    int
+		expectedDocuments int
+	}{
+		{name: "plain NDJSON", path: "ndjson", body: []byte(ndjson), expectedStatus: http.StatusOK, expectedDocuments: 3},
+		{name: "gzipped NDJSON", path: "ndjson", encoding: "gzip", body: compressedNDJSON, expectedStatus: http.StatusOK, expectedDocuments: 3},
+		{name: "gzipped CSV", path: "csv", encoding: "GZIP", body: gzipped(t, csv), expectedStatus: http.StatusOK, expectedDocuments: 3},
+		{name: "identity", path: "csv", encoding: "identity", body: []byte(csv), expectedStatus: http.StatusOK, expectedDocuments: 3},
+		{name: "not gzip", path: "ndjson", encoding: "gzip", body: []byte(ndjson), expectedStatus: http.StatusBadRequest},
+		{name: "truncated gzip", path: "ndjson", encoding: "gzip", body: compressedNDJSON[:len(compressedNDJSON)-12], expectedStatus: http.StatusBadRequest},
+		{name: "unsupported encoding", path: "csv", encoding: "br", body: []byte(csv), expectedStatus: http.StatusUnsupportedMediaType},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			router, cluster := newImportRouter(t)
+
+			req := httptest.NewRequest(http.MethodPost, "/api/v1/indices/products/import/"+tc.path, bytes.NewReader(tc.body))
+			if tc.encoding != "" {
+				req.Header.Set("Content-Encoding", tc.encoding)
+			}
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+
+			if w.Code != tc.expectedStatus {
+				t.Fatalf("Expected status %d, got %d: %s", tc.expectedStatus, w.Code, w.Body.String())
+			}
+			if tc.expectedStatus != http.StatusOK {
+				return
+			}
+			if len(cluster.documents) != tc.expectedDocuments {
+				t.Fatalf("Expected %d documents indexed, got %d", tc.expectedDocuments, len(cluster.documents))
+			}
+			if cluster.documents[0]["title"] != "Laptop" {
+				t.Errorf("Expected the decompressed documents, got %v", cluster.documents[0])
+			}
+		})
+	}
+}
@@ -0,0 +1,1040 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+
+	"github.com/saif-islam/es-playground/projects/index-explorer/internal/models"
+	"github.com/saif-islam/es-playground/projects/index-explorer/internal/services"
+	"github.com/saif-islam/es-playground/shared"
+)
+
+// defaultReplicaStepTimeout bounds how long a replica change waits for its shards to allocate:
+// each ramp-up step, including the ramp-up after an import, and wait_for_active_shards
+const defaultReplicaStepTimeout = 5 * time.Minute
+
+// IndexHandler handles HTTP requests for index management and optimization
+type IndexHandler struct {
+	indexService    *services.IndexService
+	documentService *services.DocumentService
+	logger          *zap.Logger
+}
+
+// NewIndexHandler creates a new index handler
+func NewIndexHandler(indexService *services.IndexService, documentService *services.DocumentService, logger *zap.Logger) *IndexHandler {
+	return &IndexHandler{
+		indexService:    indexService,
+		documentService: documentService,
+		logger:          logger,
+	}
+}
+
+// CreateIndex handles POST /api/v1/indices
+func (h *IndexHandler) CreateIndex(c *gin.Context) {
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 30*time.Second)
+	defer cancel()
+
+	var req models.IndexRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		h.logger.Error("Invalid index request", zap.Error(err))
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{
+			Error:     "Invalid request",
+			Message:   err.Error(),
+			RequestID: c.GetString("request_id"),
+			Timestamp: time.Now(),
+		})
+		return
+	}
+
+	h.createIndex(ctx, c, &req)
+}
+
+// CreateWriteOptimizedIndex handles POST /api/v1/indices/write-optimized
+func (h *IndexHandler) CreateWriteOptimizedIndex(c *gin.Context) {
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 30*time.Second)
+	defer cancel()
+
+	var req models.IndexRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		h.logger.Error("Invalid index request", zap.Error(err))
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{
+			Error:     "Invalid request",
+			Message:   err.Error(),
+			RequestID: c.GetString("request_id"),
+			Timestamp: time.Now(),
+		})
+		return
+	}
+
+	req.WriteOptimized = true
+	h.createIndex(ctx, c, &req)
+}
+
+// PreviewSettings handles POST /api/v1/indices/_preview-settings
+func (h *IndexHandler) PreviewSettings(c *gin.Context) {
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 10*time.Second)
+	defer cancel()
+
+	var req models.IndexRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		h.logger.Error("Invalid index request", zap.Error(err))
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{
+			Error:     "Invalid request",
+			Message:   err.Error(),
+			RequestID: c.GetString("request_id"),
+			Timestamp: time.Now(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, h.indexService.PreviewOptimizedSettings(ctx, &req))
+}
+
+// AdviseShards handles POST /api/v1/indices/_advise-shards
+func (h *IndexHandler) AdviseShards(c *gin.Context) {
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 10*time.Second)
+	defer cancel()
+
+	var req models.ShardAdviceRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		h.logger.Error("Invalid shard advice request", zap.Error(err))
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{
+			Error:     "Invalid request",
+			Message:   err.Error(),
+			RequestID: c.GetString("request_id"),
+			Timestamp: time.Now(),
+		})
+		return
+	}
+
+	advice, err := h.indexService.AdviseShardCount(ctx, req.ExpectedTotalSizeGB, req.RetentionDays, req.ExpectedDailyGrowthGB)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{
+			Error:     "Invalid shard advice request",
+			Message:   err.Error(),
+			RequestID: c.GetString("request_id"),
+			Timestamp: time.Now(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, advice)
+}
+
+// InferMapping handles POST /api/v1/indices/:index/mappings/infer
+func (h *IndexHandler) InferMapping(c *gin.Context) {
+	var req models.MappingInferenceRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		h.logger.Error("Invalid mapping inference request", zap.Error(err))
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{
+			Error:     "Invalid request",
+			Message:   err.Error(),
+			RequestID: c.GetString("request_id"),
+			Timestamp: time.Now(),
+		})
+		return
+	}
+
+	mappings, skipped := h.indexService.InferMapping(req.Sample, req.TextHeavy)
+
+	c.JSON(http.StatusOK, models.MappingInference{
+		IndexName: c.Param("index"),
+		Mappings:  mappings,
+		Skipped:   skipped,
+		RequestID: c.GetString("request_id"),
+		Timestamp: time.Now(),
+	})
+}
+
+// createIndex creates the index and writes the response
+func (h *IndexHandler) createIndex(ctx context.Context, c *gin.Context, req *models.IndexRequest) {
+	// ?dry_run=true works like the dry_run field
+	if dryRun, _ := strconv.ParseBool(c.Query("dry_run")); dryRun {
+		req.DryRun = true
+	}
+
+	response, err := h.indexService.CreateIndex(ctx, req)
+	if err != nil {
+		h.logger.Error("Failed to create index",
+			zap.String("index", req.IndexName),
+			zap.Error(err))
+		c.JSON(shared.HTTPStatus(err), models.ErrorResponse{
+			Error:     "Failed to create index",
+			Message:   err.Error(),
+			RequestID: c.GetString("request_id"),
+			Timestamp: time.Now(),
+		})
+		return
+	}
+
+	if response.DryRun {
+		c.JSON(http.StatusOK, response)
+		return
+	}
+	c.JSON(http.StatusCreated, response)
+}
+
+// Index listing page sizes
+const (
+	defaultIndexListLimit = 100
+	maxIndexListLimit     = 1000
+)
+
+// indexSortPattern matches a _cat/indices sort such as "docs.count:desc" or "index,store.size"
+var indexSortPattern = regexp.MustCompile(`^[a-z_.]+(:(asc|desc))?(,[a-z_.]+(:(asc|desc))?)*$`)
+
+// ListIndices handles GET /api/v1/indices?pattern=logs-*&sort=docs.count:desc&offset=0&limit=100
+func (h *IndexHandler) ListIndices(c *gin.Context) {
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 15*time.Second)
+	defer cancel()
+
+	opts := services.ListIndicesOptions{
+		Pattern: c.Query("pattern"),
+		Sort:    c.Query("sort"),
+		Limit:   defaultIndexListLimit,
+	}
+
+	if value := c.Query("offset"); value != "" {
+		parsed, err := strconv.Atoi(value)
+		if err != nil || parsed < 0 {
+			h.respondInvalidListParam(c, "offset must be a non-negative integer")
+			return
+		}
+		opts.Offset = parsed
+	}
+	if value := c.Query("limit"); value != "" {
+		parsed, err := strconv.Atoi(value)
+		if err != nil || parsed < 1 || parsed > maxIndexListLimit {
+			h.respondInvalidListParam(c, fmt.Sprintf("limit must be between 1 and %d", maxIndexListLimit))
+			return
+		}
+		opts.Limit = parsed
+	}
+	if opts.Sort != "" && !indexSortPattern.MatchString(opts.Sort) {
+		h.respondInvalidListParam(c, "sort must be a comma-separated list of column[:asc|desc], e.g. docs.count:desc")
+		return
+	}
+
+	indices, total, err := h.indexService.ListIndices(ctx, opts)
+	if err != nil {
+		h.logger.Error("Failed to list indices", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{
+			Error:     "Failed to list indices",
+			Message:   err.Error(),
+			RequestID: c.GetString("request_id"),
+			Timestamp: time.Now(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"indices":    indices,
+		"count":      len(indices),
+		"total":      total,
+		"offset":     opts.Offset,
+		"limit":      opts.Limit,
+		"request_id": c.GetString("request_id"),
+		"timestamp":  time.Now(),
+	})
+}
+
+// respondInvalidListParam rejects a malformed index listing parameter
+func (h *IndexHandler) respondInvalidListParam(c *gin.Context, message string) {
+	c.JSON(http.StatusBadRequest, models.ErrorResponse{
+		Error:     "Invalid request",
+		Message:   message,
+		RequestID: c.GetString("request_id"),
+		Timestamp: time.Now(),
+	})
+}
+
+// GetIndex handles GET /api/v1/indices/:index
+func (h *IndexHandler) GetIndex(c *gin.Context) {
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 15*time.Second)
+	defer cancel()
+
+	indexName := c.Param("index")
+
+	info, err := h.indexService.GetIndexInfo(ctx, indexName)
+	if err != nil {
+		h.logger.Error("Failed to get index",
+			zap.String("index", indexName),
+			zap.Error(err))
+		c.JSON(shared.HTTPStatus(err), models.ErrorResponse{
+			Error:     "Failed to get index",
+			Message:   err.Error(),
+			RequestID: c.GetString("request_id"),
+			Timestamp: time.Now(),
+		})
+		return
+	}
+
+	// Index stats only hold lifetime totals; the recent rate comes from sampled history
+	if info.WriteMetrics != nil {
+		if rate, ok := h.documentService.LatestIndexingRate(indexName); ok {
+			info.WriteMetrics.IndexingRate = rate
+		}
+	}
+
+	c.JSON(http.StatusOK, info)
+}
+
+// DeleteIndex handles DELETE /api/v1/indices/:index
+func (h *IndexHandler) DeleteIndex(c *gin.Context) {
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 30*time.Second)
+	defer cancel()
+
+	indexName := c.Param("index")
+
+	if err := h.indexService.DeleteIndex(ctx, indexName); err != nil {
+		h.logger.Error("Failed to delete index",
+			zap.String("index", indexName),
+			zap.Error(err))
+		c.JSON(shared.HTTPStatus(err), models.ErrorResponse{
+			Error:     "Failed to delete index",
+			Message:   err.Error(),
+			RequestID: c.GetString("request_id"),
+			Timestamp: time.Now(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message":    "Index deleted successfully",
+		"index_name": indexName,
+		"request_id": c.GetString("request_id"),
+		"timestamp":  time.Now(),
+	})
+}
+
+// OptimizeIndex handles POST /api/v1/indices/:index/optimize
+func (h *IndexHandler) OptimizeIndex(c *gin.Context) {
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 30*time.Second)
+	defer cancel()
+
+	var req models.OptimizationRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		h.logger.Error("Invalid optimization request", zap.Error(err))
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{
+			Error:     "Invalid request",
+			Message:   err.Error(),
+			RequestID: c.GetString("request_id"),
+			Timestamp: time.Now(),
+		})
+		return
+	}
+
+	req.IndexName = c.Param("index")
+	if req.OptimizeFor == "" {
+		req.OptimizeFor = "write_throughput"
+	}
+
+	h.optimizeIndex(ctx, c, &req)
+}
+
+// GetIndexRecommendations handles GET /api/v1/indices/:index/recommendations
+func (h *IndexHandler) GetIndexRecommendations(c *gin.Context) {
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 30*time.Second)
+	defer cancel()
+
+	h.optimizeIndex(ctx, c, &models.OptimizationRequest{
+		IndexName:    c.Param("index"),
+		OptimizeFor:  c.DefaultQuery("optimize_for", "write_throughput"),
+		Workload:     c.Query("workload"),
+		CorpusSize:   c.Query("corpus_size"),
+		ApplyChanges: false,
+	})
+}
+
+// TuneIndexForWriteWorkload handles POST /api/v1/indices/:index/tune/write-heavy
+func (h *IndexHandler) TuneIndexForWriteWorkload(c *gin.Context) {
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 30*time.Second)
+	defer cancel()
+
+	h.optimizeIndex(ctx, c, &models.OptimizationRequest{
+		IndexName:    c.Param("index"),
+		OptimizeFor:  "write_throughput",
+		Workload:     c.DefaultQuery("workload", "bulk_write"),
+		CorpusSize:   c.Query("corpus_size"),
+		Priority:     "write_throughput",
+		ApplyChanges: true,
+	})
+}
+
+// optimizeIndex runs the optimizer and writes the response
+func (h *IndexHandler) optimizeIndex(ctx context.Context, c *gin.Context, req *models.OptimizationRequest) {
+	response, err := h.indexService.OptimizeIndex(ctx, req)
+	if err != nil {
+		h.logger.Error("Failed to optimize index",
+			zap.String("index", req.IndexName),
+			zap.Error(err))
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{
+			Error:     "Failed to optimize index",
+			Message:   err.Error(),
+			RequestID: c.GetString("request_id"),
+			Timestamp: time.Now(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, response)
+}
+
+// DiffIndexSettings handles POST /api/v1/indices/:index/settings/diff, previewing a
+// settings change without applying it
+func (h *IndexHandler) DiffIndexSettings(c *gin.Context) {
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 15*time.Second)
+	defer cancel()
+
+	indexName := c.Param("index")
+
+	var req models.SettingsDiffRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		h.logger.Error("Invalid settings diff request", zap.Error(err))
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{
+			Error:     "Invalid request",
+			Message:   err.Error(),
+			RequestID: c.GetString("request_id"),
+			Timestamp: time.Now(),
+		})
+		return
+	}
+
+	diff, err := h.indexService.DiffIndexSettings(ctx, indexName, req.Settings)
+	if err != nil {
+		h.logger.Error("Failed to diff index settings",
+			zap.String("index", indexName),
+			zap.Error(err))
+
+		status, title := shared.HTTPStatus(err), "Failed to diff index settings"
+		if errors.Is(err, services.ErrInvalidSettingsDiff) {
+			status, title = http.StatusBadRequest, "Invalid request"
+		}
+
+		c.JSON(status, models.ErrorResponse{
+			Error:     title,
+			Message:   err.Error(),
+			RequestID: c.GetString("request_id"),
+			Timestamp: time.Now(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, diff)
+}
+
+// GetIndexWritePerformance handles GET /api/v1/indices/:index/performance/write
+func (h *IndexHandler) GetIndexWritePerformance(c *gin.Context) {
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 15*time.Second)
+	defer cancel()
+
+	indexName := c.Param("index")
+
+	metrics, err := h.documentService.GetWritePerformanceMetrics(ctx, indexName)
+	if err != nil {
+		h.logger.Error("Failed to get write performance",
+			zap.String("index", indexName),
+			zap.Error(err))
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{
+			Error:     "Failed to get write performance",
+			Message:   err.Error(),
+			RequestID: c.GetString("request_id"),
+			Timestamp: time.Now(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"index_name": indexName,
+		"metrics":    metrics,
+		"request_id": c.GetString("request_id"),
+		"timestamp":  time.Now(),
+	})
+}
+
+// AnalyzeIndexWritePerformance handles GET /api/v1/indices/:index/analyze/write-performance
+func (h *IndexHandler) AnalyzeIndexWritePerformance(c *gin.Context) {
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 30*time.Second)
+	defer cancel()
+
+	indexName := c.Param("index")
+
+	info, err := h.indexService.GetIndexInfo(ctx, indexName)
+	if err != nil {
+		h.logger.Error("Failed to analyze write performance",
+			zap.String("index", indexName),
+			zap.Error(err))
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{
+			Error:     "Failed to analyze write performance",
+			Message:   err.Error(),
+			RequestID: c.GetString("request_id"),
+			Timestamp: time.Now(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"index_name":    indexName,
+		"settings":      info.Settings,
+		"stats":         info.Stats,
+		"write_metrics": info.WriteMetrics,
+		"request_id":    c.GetString("request_id"),
+		"timestamp":     time.Now(),
+	})
+}
+
+// ForceMerge handles POST /api/v1/indices/:index/forcemerge
+func (h *IndexHandler) ForceMerge(c *gin.Context) {
+	indexName := c.Param("index")
+
+	maxNumSegments := 0
+	if value := c.Query("max_num_segments"); value != "" {
+		parsed, err := strconv.Atoi(value)
+		if err != nil || parsed < 1 {
+			c.JSON(http.StatusBadRequest, models.ErrorResponse{
+				Error:     "Invalid request",
+				Message:   "max_num_segments must be a positive integer",
+				RequestID: c.GetString("request_id"),
+				Timestamp: time.Now(),
+			})
+			return
+		}
+		maxNumSegments = parsed
+	}
+	onlyExpungeDeletes := c.Query("only_expunge_deletes") == "true"
+
+	if maxNumSegments > 0 && onlyExpungeDeletes {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{
+			Error:     "Invalid request",
+			Message:   "max_num_segments and only_expunge_deletes cannot be combined",
+			RequestID: c.GetString("request_id"),
+			Timestamp: time.Now(),
+		})
+		return
+	}
+
+	// Merging a large index can outlast any request; hand back a task to poll instead
+	if c.Query("wait_for_completion") == "false" {
+		ctx, cancel := context.WithTimeout(c.Request.Context(), 30*time.Second)
+		defer cancel()
+
+		result, err := h.indexService.StartForceMerge(ctx, indexName, maxNumSegments, onlyExpungeDeletes)
+		if err != nil {
+			h.respondForceMergeError(c, indexName, err)
+			return
+		}
+
+		c.JSON(http.StatusAccepted, gin.H{
+			"force_merge": result,
+			"status_url":  "/_tasks/" + result.TaskID, // on the Elasticsearch cluster
+			"request_id":  c.GetString("request_id"),
+			"timestamp":   time.Now(),
+		})
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 300*time.Second) // 5 minutes for merges
+	defer cancel()
+
+	result, err := h.indexService.ForceMerge(ctx, indexName, maxNumSegments, onlyExpungeDeletes)
+	if err != nil {
+		h.respondForceMergeError(c, indexName, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"force_merge": result,
+		"request_id":  c.GetString("request_id"),
+		"timestamp":   time.Now(),
+	})
+}
+
+func (h *IndexHandler) respondForceMergeError(c *gin.Context, indexName string, err error) {
+	h.logger.Error("Failed to force merge index",
+		zap.String("index", indexName),
+		zap.Error(err))
+
+	message := err.Error()
+	if errors.Is(err, context.DeadlineExceeded) {
+		message += " (the merge continues in Elasticsearch; use wait_for_completion=false for long merges)"
+	}
+
+	c.JSON(http.StatusInternalServerError, models.ErrorResponse{
+		Error:     "Failed to force merge index",
+		Message:   message,
+		RequestID: c.GetString("request_id"),
+		Timestamp: time.Now(),
+	})
+}
+
+// Reindex handles POST /api/v1/indices/:dest/_reindex
+func (h *IndexHandler) Reindex(c *gin.Context) {
+	dest := c.Param("index")
+
+	var req struct {
+		Source   string                 `json:"source" binding:"required"`
+		Slices   int                    `json:"slices,omitempty"` // parallel sub-tasks, default one per source shard
+		Query    map[string]interface{} `json:"query,omitempty"`
+		Pipeline string                 `json:"pipeline,omitempty"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		h.logger.Error("Invalid reindex request", zap.Error(err))
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{
+			Error:     "Invalid request",
+			Message:   err.Error(),
+			RequestID: c.GetString("request_id"),
+			Timestamp: time.Now(),
+		})
+		return
+	}
+
+	opts := services.ReindexOptions{
+		Slices:   req.Slices,
+		Query:    req.Query,
+		Pipeline: req.Pipeline,
+	}
+
+	// Migrations can outlast any request; hand back a task to poll instead
+	if c.Query("wait_for_completion") == "false" {
+		ctx, cancel := context.WithTimeout(c.Request.Context(), 30*time.Second)
+		defer cancel()
+
+		result, err := h.indexService.StartReindex(ctx, req.Source, dest, opts)
+		if err != nil {
+			h.respondReindexError(c, req.Source, dest, err)
+			return
+		}
+
+		c.JSON(http.StatusAccepted, gin.H{
+			"reindex":    result,
+			"status_url": "/_tasks/" + result.TaskID, // on the Elasticsearch cluster
+			"request_id": c.GetString("request_id"),
+			"timestamp":  time.Now(),
+		})
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 300*time.Second) // 5 minutes for migrations
+	defer cancel()
+
+	result, err := h.indexService.Reindex(ctx, req.Source, dest, opts)
+	if err != nil {
+		h.respondReindexError(c, req.Source, dest, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"reindex":    result,
+		"request_id": c.GetString("request_id"),
+		"timestamp":  time.Now(),
+	})
+}
+
+func (h *IndexHandler) respondReindexError(c *gin.Context, source, dest string, err error) {
+	h.logger.Error("Failed to reindex",
+		zap.String("source_index", source),
+		zap.String("dest_index", dest),
+		zap.Error(err))
+
+	message := err.Error()
+	if errors.Is(err, context.DeadlineExceeded) {
+		message += " (the reindex continues in Elasticsearch; use wait_for_completion=false for long migrations)"
+	}
+
+	c.JSON(http.StatusInternalServerError, models.ErrorResponse{
+		Error:     "Failed to reindex",
+		Message:   message,
+		RequestID: c.GetString("request_id"),
+		Timestamp: time.Now(),
+	})
+}
+
+// ShrinkIndex handles POST /api/v1/indices/:index/_shrink
+func (h *IndexHandler) ShrinkIndex(c *gin.Context) {
+	source := c.Param("index")
+
+	var req struct {
+		Target       string `json:"target" binding:"required"`
+		TargetShards int    `json:"target_shards,omitempty"` // default 1
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		h.logger.Error("Invalid shrink request", zap.Error(err))
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{
+			Error:     "Invalid request",
+			Message:   err.Error(),
+			RequestID: c.GetString("request_id"),
+			Timestamp: time.Now(),
+		})
+		return
+	}
+	if req.TargetShards == 0 {
+		req.TargetShards = 1
+	}
+
+	// Relocating every shard to one node can take minutes
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 10*time.Minute)
+	defer cancel()
+
+	result, err := h.indexService.ShrinkIndex(ctx, source, req.Target, req.TargetShards)
+	if err != nil {
+		h.logger.Error("Failed to shrink index",
+			zap.String("source_index", source),
+			zap.String("target_index", req.Target),
+			zap.Error(err))
+
+		status, title := http.StatusInternalServerError, "Failed to shrink index"
+		var colocationErr *services.ShardsNotColocatedError
+		switch {
+		case errors.Is(err, services.ErrInvalidShrink):
+			status, title = http.StatusBadRequest, "Invalid request"
+		case errors.As(err, &colocationErr):
+			status, title = http.StatusConflict, "Shards not colocated"
+		}
+
+		c.JSON(status, models.ErrorResponse{
+			Error:     title,
+			Message:   err.Error(),
+			RequestID: c.GetString("request_id"),
+			Timestamp: time.Now(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"shrink":     result,
+		"request_id": c.GetString("request_id"),
+		"timestamp":  time.Now(),
+	})
+}
+
+// SplitIndex handles POST /api/v1/indices/:index/_split
+func (h *IndexHandler) SplitIndex(c *gin.Context) {
+	source := c.Param("index")
+
+	var req struct {
+		Target       string `json:"target" binding:"required"`
+		TargetShards int    `json:"target_shards" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		h.logger.Error("Invalid split request", zap.Error(err))
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{
+			Error:     "Invalid request",
+			Message:   err.Error(),
+			RequestID: c.GetString("request_id"),
+			Timestamp: time.Now(),
+		})
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 60*time.Second)
+	defer cancel()
+
+	result, err := h.indexService.SplitIndex(ctx, source, req.Target, req.TargetShards)
+	if err != nil {
+		h.logger.Error("Failed to split index",
+			zap.String("source_index", source),
+			zap.String("target_index", req.Target),
+			zap.Error(err))
+
+		status, title := http.StatusInternalServerError, "Failed to split index"
+		if errors.Is(err, services.ErrInvalidSplit) {
+			status, title = http.StatusBadRequest, "Invalid request"
+		}
+
+		c.JSON(status, models.ErrorResponse{
+			Error:     title,
+			Message:   err.Error(),
+			RequestID: c.GetString("request_id"),
+			Timestamp: time.Now(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"split":      result,
+		"request_id": c.GetString("request_id"),
+		"timestamp":  time.Now(),
+	})
+}
+
+// RestoreWriteOptimized handles POST /api/v1/indices/:index/_restore, restoring :index
+// from a snapshot under a new name with write-optimized settings
+func (h *IndexHandler) RestoreWriteOptimized(c *gin.Context) {
+	index := c.Param("index")
+
+	var req models.RestoreOptimizedRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		h.logger.Error("Invalid restore request", zap.Error(err))
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{
+			Error:     "Invalid request",
+			Message:   err.Error(),
+			RequestID: c.GetString("request_id"),
+			Timestamp: time.Now(),
+		})
+		return
+	}
+
+	wait := c.Query("wait_for_completion") != "false"
+	timeout := 30 * time.Second
+	if wait {
+		timeout = 300 * time.Second // 5 minutes for restores
+	}
+	ctx, cancel := context.WithTimeout(c.Request.Context(), timeout)
+	defer cancel()
+
+	result, err := h.indexService.RestoreWriteOptimized(ctx, index, &req, wait)
+	if err != nil {
+		h.logger.Error("Failed to restore index",
+			zap.String("repository", req.Repository),
+			zap.String("snapshot", req.Snapshot),
+			zap.String("source_index", index),
+			zap.String("target_index", req.Target),
+			zap.Error(err))
+
+		status, title := http.StatusInternalServerError, "Failed to restore index"
+		if errors.Is(err, services.ErrInvalidRestore) {
+			status, title = http.StatusBadRequest, "Invalid request"
+		}
+
+		c.JSON(status, models.ErrorResponse{
+			Error:     title,
+			Message:   err.Error(),
+			RequestID: c.GetString("request_id"),
+			Timestamp: time.Now(),
+		})
+		return
+	}
+
+	status := http.StatusOK
+	if !wait {
+		status = http.StatusAccepted
+	}
+	c.JSON(status, gin.H{
+		"restore":    result,
+		"request_id": c.GetString("request_id"),
+		"timestamp":  time.Now(),
+	})
+}
+
+// SetReplicas handles POST and PUT /api/v1/indices/:index/replicas
+func (h *IndexHandler) SetReplicas(c *gin.Context) {
+	var req models.ReplicaRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		h.logger.Error("Invalid replica request", zap.Error(err))
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{
+			Error:     "Invalid request",
+			Message:   err.Error(),
+			RequestID: c.GetString("request_id"),
+			Timestamp: time.Now(),
+		})
+		return
+	}
+
+	stepTimeout := defaultReplicaStepTimeout
+	if req.WaitTimeout != "" {
+		timeout, err := time.ParseDuration(req.WaitTimeout)
+		if err != nil || timeout <= 0 {
+			c.JSON(http.StatusBadRequest, models.ErrorResponse{
+				Error:     "Invalid request",
+				Message:   "wait_timeout must be a positive duration such as 5m",
+				RequestID: c.GetString("request_id"),
+				Timestamp: time.Now(),
+			})
+			return
+		}
+		stepTimeout = timeout
+	}
+
+	indexName := c.Param("index")
+
+	// Block until replication completes, e.g. ?wait_for_active_shards=all
+	activeShards := c.Query("wait_for_active_shards")
+	if count, err := strconv.Atoi(activeShards); activeShards != "" && activeShards != "all" && (err != nil || count < 1) {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{
+			Error:     "Invalid request",
+			Message:   "wait_for_active_shards must be all or a positive integer",
+			RequestID: c.GetString("request_id"),
+			Timestamp: time.Now(),
+		})
+		return
+	}
+	if activeShards != "" && req.Ramp {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{
+			Error:     "Invalid request",
+			Message:   "wait_for_active_shards cannot be combined with ramp, which already waits for each step",
+			RequestID: c.GetString("request_id"),
+			Timestamp: time.Now(),
+		})
+		return
+	}
+
+	if !req.Ramp {
+		ctx, cancel := context.WithTimeout(c.Request.Context(), stepTimeout+30*time.Second)
+		defer cancel()
+
+		if err := h.indexService.SetReplicas(ctx, indexName, *req.Replicas); err != nil {
+			h.logger.Error("Failed to set replicas",
+				zap.String("index", indexName),
+				zap.Error(err))
+			c.JSON(http.StatusInternalServerError, models.ErrorResponse{
+				Error:     "Failed to set replicas",
+				Message:   err.Error(),
+				RequestID: c.GetString("request_id"),
+				Timestamp: time.Now(),
+			})
+			return
+		}
+
+		response := gin.H{
+			"message":    "Replica count updated",
+			"index_name": indexName,
+			"replicas":   *req.Replicas,
+			"request_id": c.GetString("request_id"),
+			"timestamp":  time.Now(),
+		}
+
+		if activeShards != "" {
+			step, err := h.indexService.WaitForActiveShards(ctx, indexName, *req.Replicas, activeShards, stepTimeout)
+			if err != nil {
+				h.logger.Error("Failed to wait for active shards",
+					zap.String("index", indexName),
+					zap.Error(err))
+				c.JSON(http.StatusInternalServerError, models.ErrorResponse{
+					Error:     "Replica count updated, but waiting for active shards failed",
+					Message:   err.Error(),
+					RequestID: c.GetString("request_id"),
+					Timestamp: time.Now(),
+				})
+				return
+			}
+			if !step.Allocated {
+				response["message"] = "Replica count updated; shards were still allocating when wait_timeout expired"
+			}
+			response["allocation"] = step
+		}
+
+		c.JSON(http.StatusOK, response)
+		return
+	}
+
+	// Each step may wait for a full allocation, so budget for all of them
+	ctx, cancel := context.WithTimeout(c.Request.Context(), stepTimeout*time.Duration(*req.Replicas+1))
+	defer cancel()
+
+	result, err := h.indexService.RampUpReplicas(ctx, indexName, *req.Replicas, stepTimeout)
+	if err != nil {
+		h.logger.Error("Failed to ramp up replicas",
+			zap.String("index", indexName),
+			zap.Error(err))
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{
+			Error:     "Failed to ramp up replicas",
+			Message:   err.Error(),
+			RequestID: c.GetString("request_id"),
+			Timestamp: time.Now(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"ramp_up":    result,
+		"request_id": c.GetString("request_id"),
+		"timestamp":  time.Now(),
+	})
+}
+
+// ConfigureSlowLog handles PUT /api/v1/indices/:index/slowlog
+func (h *IndexHandler) ConfigureSlowLog(c *gin.Context) {
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 15*time.Second)
+	defer cancel()
+
+	var thresholds models.SlowLogThresholds
+	if err := c.ShouldBindJSON(&thresholds); err != nil {
+		h.logger.Error("Invalid slow-log request", zap.Error(err))
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{
+			Error:     "Invalid request",
+			Message:   err.Error(),
+			RequestID: c.GetString("request_id"),
+			Timestamp: time.Now(),
+		})
+		return
+	}
+
+	indexName := c.Param("index")
+
+	settings, err := h.indexService.ConfigureSlowLog(ctx, indexName, &thresholds)
+	if err != nil {
+		h.logger.Error("Failed to configure slow log",
+			zap.String("index", indexName),
+			zap.Error(err))
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{
+			Error:     "Failed to configure slow log",
+			Message:   err.Error(),
+			RequestID: c.GetString("request_id"),
+			Timestamp: time.Now(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message":    "Slow-log thresholds updated",
+		"index_name": indexName,
+		"settings":   settings,
+		"request_id": c.GetString("request_id"),
+		"timestamp":  time.Now(),
+	})
+}
+
+// CheckCompliance handles GET /api/v1/indices/:index/compliance?profile=
+func (h *IndexHandler) CheckCompliance(c *gin.Context) {
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 15*time.Second)
+	defer cancel()
+
+	indexName := c.Param("index")
+	profile := c.Query("profile")
+	if profile == "" {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{
+			Error:     "Missing profile",
+			Message:   "profile query parameter is required",
+			Details:   fmt.Sprintf("available profiles: %s", strings.Join(services.ComplianceProfiles(), ", ")),
+			RequestID: c.GetString("request_id"),
+			Timestamp: time.Now(),
+		})
+		return
+	}
+
+	report, err := h.indexService.CheckCompliance(ctx, indexName, profile)
+	if errors.Is(err, services.ErrUnknownComplianceProfile) {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{
+			Error:     "Invalid request",
+			Message:   fmt.Sprintf("unknown compliance profile %q", profile),
+			Details:   fmt.Sprintf("available profiles: %s", strings.Join(services.ComplianceProfiles(), ", ")),
+			RequestID: c.GetString("request_id"),
+			Timestamp: time.Now(),
+		})
+		return
+	}
+	if err != nil {
+		h.logger.Error("Failed to check index compliance",
+			zap.String("index", indexName),
+			zap.String("profile", profile),
+			zap.Error(err))
+		c.JSON(shared.HTTPStatus(err), models.ErrorResponse{
+			Error:     "Failed to check index compliance",
+			Message:   err.Error(),
+			RequestID: c.GetString("request_id"),
+			Timestamp: time.Now(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, report)
+}
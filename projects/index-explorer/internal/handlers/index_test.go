@@ -0,0 +1,195 @@
+package handlers
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+
+	"github.com/saif-islam/es-playground/projects/index-explorer/internal/services"
+	"github.com/saif-islam/es-playground/shared"
+)
+
+// greenHealthAPIResponse follows GET /_cluster/health/products?wait_for_active_shards=all
+// once every copy of a 3 shard index with 2 replicas has started
+const greenHealthAPIResponse = `{
+  "cluster_name": "playground",
+  "status": "green",
+  "timed_out": false,
+  "number_of_nodes": 3,
+  "number_of_data_nodes": 3,
+  "active_primary_shards": 3,
+  "active_shards": 9,
+  "relocating_shards": 0,
+  "initializing_shards": 0,
+  "unassigned_shards": 0
+}`
+
+// timedOutHealthAPIResponse is the 408 answer when the replicas are still allocating at
+// the timeout
+const timedOutHealthAPIResponse = `{
+  "cluster_name": "playground",
+  "status": "yellow",
+  "timed_out": true,
+  "number_of_nodes": 3,
+  "number_of_data_nodes": 3,
+  "active_primary_shards": 3,
+  "active_shards": 5,
+  "relocating_shards": 0,
+  "initializing_shards": 2,
+  "unassigned_shards": 2
+}`
+
+// newIndexRouter serves the given index routes backed by a fake Elasticsearch that answers
+// the product check itself and passes every other request to handler
+func newIndexRouter(t *testing.T, handler http.HandlerFunc) (*gin.Engine, *IndexHandler) {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+
+	es := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("X-Elastic-Product", "Elasticsearch")
+		w.Header().Set("Content-Type", "application/json")
+		if r.URL.Path == "/" {
+			w.Write([]byte(`{"version":{"number":"8.11.1"}}`))
+			return
+		}
+		handler(w, r)
+	}))
+	t.Cleanup(es.Close)
+
+	client, err := shared.NewESClient(&shared.ESConfig{URLs: []string{es.URL}}, zap.NewNop())
+	if err != nil {
+		t.Fatalf("Failed to create client: %v", err)
+	}
+	indexService := services.NewIndexService(client, zap.NewNop())
+	return gin.New(), NewIndexHandler(indexService, services.NewDocumentService(client, zap.NewNop()), zap.NewNop())
+}
+
+func TestSetReplicas_WaitForActiveShards(t *testing.T) {
+	testCases := []struct {
+		name              string
+		query             string
+		body              string
+		healthStatus      int
+		healthResponse    string
+		expectedStatus    int
+		expectedSettings  string // the settings update Elasticsearch receives, if any
+		expectedHealth    string // the health request query, if any
+		expectedAllocated bool
+	}{
+		{
+			name:             "no wait",
+			body:             `{"replicas":0}`,
+			expectedStatus:   http.StatusOK,
+			expectedSettings: `{"index":{"number_of_replicas":0}}`,
+		},
+		{
+			name:              "wait for all copies",
+			query:             "?wait_for_active_shards=all",
+			body:              `{"replicas":2,"wait_timeout":"10m"}`,
+			healthStatus:      http.StatusOK,
+			healthResponse:    greenHealthAPIResponse,
+			expectedStatus:    http.StatusOK,
+			expectedSettings:  `{"index":{"number_of_replicas":2}}`,
+			expectedHealth:    "timeout=600000ms&wait_for_active_shards=all",
+			expectedAllocated: true,
+		},
+		{
+			name:             "still allocating at the timeout",
+			query:            "?wait_for_active_shards=9",
+			body:             `{"replicas":2,"wait_timeout":"30s"}`,
+			healthStatus:     http.StatusRequestTimeout,
+			healthResponse:   timedOutHealthAPIResponse,
+			expectedStatus:   http.StatusOK,
+			expectedSettings: `{"index":{"number_of_replicas":2}}`,
+			expectedHealth:   "timeout=30000ms&wait_for_active_shards=9",
+		},
+		{
+			name:             "health check fails",
+			query:            "?wait_for_active_shards=all",
+			body:             `{"replicas":2}`,
+			healthStatus:     http.StatusNotFound,
+			healthResponse:   `{"error":{"root_cause":[{"type":"index_not_found_exception","reason":"no such index [products]"}],"type":"index_not_found_exception","reason":"no such index [products]"},"status":404}`,
+			expectedStatus:   http.StatusInternalServerError,
+			expectedSettings: `{"index":{"number_of_replicas":2}}`,
+			expectedHealth:   "timeout=300000ms&wait_for_active_shards=all",
+		},
+		{
+			name:           "invalid wait_for_active_shards",
+			query:          "?wait_for_active_shards=0",
+			body:           `{"replicas":2}`,
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name:           "wait combined with ramp",
+			query:          "?wait_for_active_shards=all",
+			body:           `{"replicas":2,"ramp":true}`,
+			expectedStatus: http.StatusBadRequest,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			var settings, health string
+			router, handler := newIndexRouter(t, func(w http.ResponseWriter, r *http.Request) {
+				switch r.URL.Path {
+				case "/products/_settings":
+					body, _ := io.ReadAll(r.Body)
+					settings = string(body)
+					w.Write([]byte(`{"acknowledged":true}`))
+				case "/_cluster/health/products":
+					health = r.URL.RawQuery
+					w.WriteHeader(tc.healthStatus)
+					w.Write([]byte(tc.healthResponse))
+				default:
+					t.Errorf("Unexpected request %s %s", r.Method, r.URL.Path)
+				}
+			})
+			router.PUT("/api/v1/indices/:index/replicas", handler.SetReplicas)
+
+			req := httptest.NewRequest(http.MethodPut, "/api/v1/indices/products/replicas"+tc.query, strings.NewReader(tc.body))
+			req.Header.Set("Content-Type", "application/json")
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+
+			if w.Code != tc.expectedStatus {
+				t.Fatalf("Expected status %d, got %d: %s", tc.expectedStatus, w.Code, w.Body.String())
+			}
+			if settings != tc.expectedSettings {
+				t.Errorf("Expected settings %s, got %s", tc.expectedSettings, settings)
+			}
+			if health != tc.expectedHealth {
+				t.Errorf("Expected health query %q, got %q", tc.expectedHealth, health)
+			}
+			if tc.expectedStatus != http.StatusOK || tc.expectedHealth == "" {
+				return
+			}
+
+			var response struct {
+				Message    string `json:"message"`
+				Allocation *struct {
+					ActiveShards       int  `json:"active_shards"`
+					InitializingShards int  `json:"initializing_shards"`
+					Allocated          bool `json:"allocated"`
+				} `json:"allocation"`
+			}
+			if err := json.Unmarshal(w.Body.Bytes(), &response); err != nil {
+				t.Fatalf("Failed to decode response: %v", err)
+			}
+			if response.Allocation == nil || response.Allocation.Allocated != tc.expectedAllocated {
+				t.Fatalf("Expected allocated %v, got %s", tc.expectedAllocated, w.Body.String())
+			}
+			if tc.expectedAllocated && response.Allocation.ActiveShards != 9 {
+				t.Errorf("Expected all 9 shard copies active, got %s", w.Body.String())
+			}
+			if !tc.expectedAllocated && (response.Allocation.InitializingShards != 2 || !strings.Contains(response.Message, "still allocating")) {
+				t.Errorf("Expected the health at the timeout, got %s", w.Body.String())
+			}
+		})
+	}
+}
@@ -0,0 +1,38 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+
+	"github.com/saif-islam/es-playground/projects/index-explorer/internal/services"
+)
+
+// OverviewHandler serves the aggregated dashboard feed across all playground services
+type OverviewHandler struct {
+	overviewService *services.OverviewService
+	logger          *zap.Logger
+}
+
+// NewOverviewHandler creates a new overview handler
+func NewOverviewHandler(overviewService *services.OverviewService, logger *zap.Logger) *OverviewHandler {
+	return &OverviewHandler{
+		overviewService: overviewService,
+		logger:          logger,
+	}
+}
+
+// GetOverview handles GET /api/v1/overview
+func (h *OverviewHandler) GetOverview(c *gin.Context) {
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 15*time.Second)
+	defer cancel()
+
+	overview := h.overviewService.GetOverview(ctx)
+	overview.RequestID = c.GetString("request_id")
+
+	// Unreachable services are part of the payload, so the feed itself always succeeds
+	c.JSON(http.StatusOK, overview)
+}
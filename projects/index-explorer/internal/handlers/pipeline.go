@@ -0,0 +1,158 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+
+	"github.com/saif-islam/es-playground/projects/index-explorer/internal/models"
+	"github.com/saif-islam/es-playground/shared"
+)
+
+// PutPipeline handles PUT /api/v1/pipelines/:id
+func (h *IndexHandler) PutPipeline(c *gin.Context) {
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 30*time.Second)
+	defer cancel()
+
+	id := c.Param("id")
+
+	var req models.PipelineRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		h.logger.Error("Invalid ingest pipeline request", zap.Error(err))
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{
+			Error:     "Invalid request",
+			Message:   err.Error(),
+			RequestID: c.GetString("request_id"),
+			Timestamp: time.Now(),
+		})
+		return
+	}
+
+	acknowledged, err := h.indexService.PutPipeline(ctx, id, &req)
+	if err != nil {
+		h.logger.Error("Failed to create ingest pipeline",
+			zap.String("pipeline", id),
+			zap.Error(err))
+		c.JSON(shared.HTTPStatus(err), models.ErrorResponse{
+			Error:     "Failed to create ingest pipeline",
+			Message:   err.Error(),
+			RequestID: c.GetString("request_id"),
+			Timestamp: time.Now(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"acknowledged": acknowledged,
+		"pipeline":     id,
+		"request_id":   c.GetString("request_id"),
+		"timestamp":    time.Now(),
+	})
+}
+
+// ListPipelines handles GET /api/v1/pipelines
+func (h *IndexHandler) ListPipelines(c *gin.Context) {
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 15*time.Second)
+	defer cancel()
+
+	pipelines, err := h.indexService.GetPipeline(ctx, "")
+	if err != nil {
+		h.logger.Error("Failed to list ingest pipelines", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{
+			Error:     "Failed to list ingest pipelines",
+			Message:   err.Error(),
+			RequestID: c.GetString("request_id"),
+			Timestamp: time.Now(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"pipelines":  pipelines,
+		"count":      len(pipelines),
+		"request_id": c.GetString("request_id"),
+		"timestamp":  time.Now(),
+	})
+}
+
+// GetPipeline handles GET /api/v1/pipelines/:id; the ID may be a wildcard expression
+func (h *IndexHandler) GetPipeline(c *gin.Context) {
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 15*time.Second)
+	defer cancel()
+
+	id := c.Param("id")
+
+	pipelines, err := h.indexService.GetPipeline(ctx, id)
+	if err != nil {
+		h.logger.Error("Failed to get ingest pipeline",
+			zap.String("pipeline", id),
+			zap.Error(err))
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{
+			Error:     "Failed to get ingest pipeline",
+			Message:   err.Error(),
+			RequestID: c.GetString("request_id"),
+			Timestamp: time.Now(),
+		})
+		return
+	}
+
+	if len(pipelines) == 0 {
+		c.JSON(http.StatusNotFound, models.ErrorResponse{
+			Error:     "Ingest pipeline not found",
+			Message:   fmt.Sprintf("No ingest pipeline matches %s", id),
+			RequestID: c.GetString("request_id"),
+			Timestamp: time.Now(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"pipelines":  pipelines,
+		"count":      len(pipelines),
+		"request_id": c.GetString("request_id"),
+		"timestamp":  time.Now(),
+	})
+}
+
+// DeletePipeline handles DELETE /api/v1/pipelines/:id
+func (h *IndexHandler) DeletePipeline(c *gin.Context) {
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 30*time.Second)
+	defer cancel()
+
+	id := c.Param("id")
+
+	deleted, err := h.indexService.DeletePipeline(ctx, id)
+	if err != nil {
+		h.logger.Error("Failed to delete ingest pipeline",
+			zap.String("pipeline", id),
+			zap.Error(err))
+		c.JSON(shared.HTTPStatus(err), models.ErrorResponse{
+			Error:     "Failed to delete ingest pipeline",
+			Message:   err.Error(),
+			RequestID: c.GetString("request_id"),
+			Timestamp: time.Now(),
+		})
+		return
+	}
+
+	if !deleted {
+		c.JSON(http.StatusNotFound, models.ErrorResponse{
+			Error:     "Ingest pipeline not found",
+			Message:   fmt.Sprintf("Ingest pipeline %s does not exist", id),
+			RequestID: c.GetString("request_id"),
+			Timestamp: time.Now(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message":    "Ingest pipeline deleted successfully",
+		"pipeline":   id,
+		"request_id": c.GetString("request_id"),
+		"timestamp":  time.Now(),
+	})
+}
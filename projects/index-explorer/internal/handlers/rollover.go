@@ -0,0 +1,94 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+
+	"github.com/saif-islam/es-playground/projects/index-explorer/internal/models"
+	"github.com/saif-islam/es-playground/projects/index-explorer/internal/services"
+)
+
+// RolloverHandler manages the aliases watched by the automatic rollover monitor
+type RolloverHandler struct {
+	rolloverMonitor *services.RolloverMonitor
+	logger          *zap.Logger
+}
+
+// NewRolloverHandler creates a new rollover handler
+func NewRolloverHandler(rolloverMonitor *services.RolloverMonitor, logger *zap.Logger) *RolloverHandler {
+	return &RolloverHandler{
+		rolloverMonitor: rolloverMonitor,
+		logger:          logger,
+	}
+}
+
+// ListRollovers handles GET /api/v1/rollover
+func (h *RolloverHandler) ListRollovers(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{
+		"aliases":    h.rolloverMonitor.Status(),
+		"request_id": c.GetString("request_id"),
+		"timestamp":  time.Now(),
+	})
+}
+
+// SetRolloverThresholds handles PUT /api/v1/rollover/:alias
+func (h *RolloverHandler) SetRolloverThresholds(c *gin.Context) {
+	alias := c.Param("alias")
+
+	var thresholds models.RolloverThresholds
+	if err := c.ShouldBindJSON(&thresholds); err != nil {
+		h.logger.Error("Invalid rollover thresholds", zap.Error(err))
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{
+			Error:     "Invalid request",
+			Message:   err.Error(),
+			RequestID: c.GetString("request_id"),
+			Timestamp: time.Now(),
+		})
+		return
+	}
+
+	if err := h.rolloverMonitor.Watch(alias, thresholds); err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{
+			Error:     "Invalid rollover thresholds",
+			Message:   err.Error(),
+			RequestID: c.GetString("request_id"),
+			Timestamp: time.Now(),
+		})
+		return
+	}
+
+	h.logger.Info("Watching alias for rollover", zap.String("alias", alias))
+
+	c.JSON(http.StatusOK, gin.H{
+		"message":    fmt.Sprintf("Alias %s is watched for rollover", alias),
+		"alias":      alias,
+		"thresholds": thresholds,
+		"request_id": c.GetString("request_id"),
+		"timestamp":  time.Now(),
+	})
+}
+
+// RemoveRollover handles DELETE /api/v1/rollover/:alias
+func (h *RolloverHandler) RemoveRollover(c *gin.Context) {
+	alias := c.Param("alias")
+
+	if !h.rolloverMonitor.Unwatch(alias) {
+		c.JSON(http.StatusNotFound, models.ErrorResponse{
+			Error:     "Alias not watched",
+			Message:   fmt.Sprintf("Alias %s is not watched for rollover", alias),
+			RequestID: c.GetString("request_id"),
+			Timestamp: time.Now(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message":    fmt.Sprintf("Alias %s is no longer watched for rollover", alias),
+		"request_id": c.GetString("request_id"),
+		"timestamp":  time.Now(),
+	})
+}
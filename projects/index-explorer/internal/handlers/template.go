@@ -0,0 +1,112 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+
+	"github.com/saif-islam/es-playground/projects/index-explorer/internal/models"
+)
+
+// CreateIndexTemplate handles POST /api/v1/templates
+func (h *IndexHandler) CreateIndexTemplate(c *gin.Context) {
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 30*time.Second)
+	defer cancel()
+
+	var req models.IndexTemplateRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		h.logger.Error("Invalid index template request", zap.Error(err))
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{
+			Error:     "Invalid request",
+			Message:   err.Error(),
+			RequestID: c.GetString("request_id"),
+			Timestamp: time.Now(),
+		})
+		return
+	}
+
+	response, err := h.indexService.CreateIndexTemplate(ctx, &req)
+	if err != nil {
+		h.logger.Error("Failed to create index template",
+			zap.String("template_name", req.TemplateName),
+			zap.Error(err))
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{
+			Error:     "Failed to create index template",
+			Message:   err.Error(),
+			RequestID: c.GetString("request_id"),
+			Timestamp: time.Now(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusCreated, response)
+}
+
+// ListIndexTemplates handles GET /api/v1/templates
+func (h *IndexHandler) ListIndexTemplates(c *gin.Context) {
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 15*time.Second)
+	defer cancel()
+
+	// Optional wildcard filter on template names, e.g. ?name=logs-*
+	templates, err := h.indexService.ListIndexTemplates(ctx, c.Query("name"))
+	if err != nil {
+		h.logger.Error("Failed to list index templates", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{
+			Error:     "Failed to list index templates",
+			Message:   err.Error(),
+			RequestID: c.GetString("request_id"),
+			Timestamp: time.Now(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"templates":  templates,
+		"count":      len(templates),
+		"request_id": c.GetString("request_id"),
+		"timestamp":  time.Now(),
+	})
+}
+
+// DeleteIndexTemplate handles DELETE /api/v1/templates/:name
+func (h *IndexHandler) DeleteIndexTemplate(c *gin.Context) {
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 30*time.Second)
+	defer cancel()
+
+	name := c.Param("name")
+
+	deleted, err := h.indexService.DeleteIndexTemplate(ctx, name)
+	if err != nil {
+		h.logger.Error("Failed to delete index template",
+			zap.String("template_name", name),
+			zap.Error(err))
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{
+			Error:     "Failed to delete index template",
+			Message:   err.Error(),
+			RequestID: c.GetString("request_id"),
+			Timestamp: time.Now(),
+		})
+		return
+	}
+
+	if !deleted {
+		c.JSON(http.StatusNotFound, models.ErrorResponse{
+			Error:     "Index template not found",
+			Message:   fmt.Sprintf("Index template %s does not exist", name),
+			RequestID: c.GetString("request_id"),
+			Timestamp: time.Now(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message":       "Index template deleted successfully",
+		"template_name": name,
+		"request_id":    c.GetString("request_id"),
+		"timestamp":     time.Now(),
+	})
+}
@@ -0,0 +1,80 @@
+package metrics
+
+import (
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+var (
+	// Bulk document metrics, by index and action (index, create, update, delete)
+	BulkDocumentsIndexed = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "bulk_documents_indexed_total",
+			Help: "Total number of documents written successfully by bulk operations",
+		},
+		[]string{"index", "operation"},
+	)
+
+	BulkDocumentFailures = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "bulk_document_failures_total",
+			Help: "Total number of documents that failed in bulk operations after retries",
+		},
+		[]string{"index", "operation"},
+	)
+
+	// Bulk batch metrics; operation is bulk for a batch's first request and retry for
+	// requests resending its rejected items
+	BulkBatchesProcessed = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "bulk_batches_processed_total",
+			Help: "Total number of bulk batches processed, by outcome",
+		},
+		[]string{"index", "status"},
+	)
+
+	BulkBatchDuration = promauto.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Name:    "bulk_batch_duration_seconds",
+			Help:    "Duration of bulk requests sent to Elasticsearch in seconds",
+			Buckets: []float64{0.01, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10, 30},
+		},
+		[]string{"index", "operation"},
+	)
+
+	BulkOperationDuration = promauto.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Name:    "bulk_operation_duration_seconds",
+			Help:    "Duration of whole bulk operations, across all their batches, in seconds",
+			Buckets: []float64{0.1, 0.5, 1, 5, 10, 30, 60, 300, 900, 3600},
+		},
+		[]string{"index"},
+	)
+)
+
+// RecordBulkDocuments records the documents of one action written and failed in a batch
+func RecordBulkDocuments(index, operation string, succeeded, failed int) {
+	if succeeded > 0 {
+		BulkDocumentsIndexed.WithLabelValues(index, operation).Add(float64(succeeded))
+	}
+	if failed > 0 {
+		BulkDocumentFailures.WithLabelValues(index, operation).Add(float64(failed))
+	}
+}
+
+// RecordBulkBatch records a processed batch by outcome: success, partial or failed
+func RecordBulkBatch(index, status string) {
+	BulkBatchesProcessed.WithLabelValues(index, status).Inc()
+}
+
+// RecordBulkRequest records the duration of a single bulk request to Elasticsearch
+func RecordBulkRequest(index, operation string, duration time.Duration) {
+	BulkBatchDuration.WithLabelValues(index, operation).Observe(duration.Seconds())
+}
+
+// RecordBulkOperation records the duration of a whole bulk operation
+func RecordBulkOperation(index string, duration time.Duration) {
+	BulkOperationDuration.WithLabelValues(index).Observe(duration.Seconds())
+}
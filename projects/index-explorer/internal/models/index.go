@@ -13,6 +13,15 @@ type IndexRequest struct {
 	ExpectedVolume   string                 `json:"expected_volume,omitempty"` // low, medium, high
 	ExpectedDocSize  string                 `json:"expected_doc_size,omitempty"` // small, medium, large
 	IngestionRate    string                 `json:"ingestion_rate,omitempty"` // low, medium, high
+	Allocation       *AllocationFilters     `json:"allocation,omitempty"`
+	DryRun           bool                   `json:"dry_run,omitempty"` // compute the settings without creating the index
+}
+
+// AllocationFilters constrains shard placement by custom node attribute (e.g. {"data": "hot"})
+type AllocationFilters struct {
+	Require map[string]string `json:"require,omitempty"` // node must have every attribute
+	Include map[string]string `json:"include,omitempty"` // node must have at least one value
+	Exclude map[string]string `json:"exclude,omitempty"` // node must have none of the values
 }
 
 // IndexSettings represents index settings configuration
@@ -36,6 +45,10 @@ type IndexSettings struct {
 	// Codec and compression
 	Codec string `json:"index.codec,omitempty"`
 	
+	// Soft-deletes and retention leases (history retention for CCR and peer recovery)
+	SoftDeletesEnabled              *bool  `json:"index.soft_deletes.enabled,omitempty"`
+	SoftDeletesRetentionLeasePeriod string `json:"index.soft_deletes.retention_lease.period,omitempty"`
+	
 	// Additional custom settings
 	Additional map[string]interface{} `json:"additional,omitempty"`
 }
@@ -47,10 +60,137 @@ type IndexResponse struct {
 	Created      bool      `json:"created"`  
 	Settings     *IndexSettings `json:"settings,omitempty"`
 	Optimizations []string `json:"optimizations,omitempty"`
+	DryRun       bool      `json:"dry_run,omitempty"`
+	Body         map[string]interface{} `json:"body,omitempty"` // dry runs: the exact create index request body
 	RequestID    string    `json:"request_id"`
 	Timestamp    time.Time `json:"timestamp"`
 }
 
+// SettingsPreview shows the settings index creation would apply to a request, without creating it
+type SettingsPreview struct {
+	IndexName     string                 `json:"index_name,omitempty"`
+	Settings      *IndexSettings         `json:"settings"`
+	Body          map[string]interface{} `json:"body"` // the exact create index request body
+	Optimizations []string               `json:"optimizations,omitempty"`
+	Valid         bool                   `json:"valid"`
+	Problems      []string               `json:"problems,omitempty"` // reasons creation would be rejected
+	RequestID     string                 `json:"request_id"`
+	Timestamp     time.Time              `json:"timestamp"`
+}
+
+// MappingInferenceRequest carries a sample document to derive a mapping from
+type MappingInferenceRequest struct {
+	Sample    map[string]interface{} `json:"sample" binding:"required"`
+	TextHeavy bool                   `json:"text_heavy"` // long prose fields; skips keyword sub-fields on text
+}
+
+// MappingInference is a mapping suggested from a sample document, for review before
+// creating the index with it
+type MappingInference struct {
+	IndexName string                 `json:"index_name"`
+	Mappings  map[string]interface{} `json:"mappings"`
+	Skipped   []string               `json:"skipped,omitempty"` // fields whose type can't be told from the sample
+	RequestID string                 `json:"request_id"`
+	Timestamp time.Time              `json:"timestamp"`
+}
+
+// ShardAdviceRequest describes the data an index (or series of rolled-over indices) will hold
+type ShardAdviceRequest struct {
+	ExpectedTotalSizeGB   float64 `json:"expected_total_size_gb"`
+	RetentionDays         int     `json:"retention_days,omitempty"`
+	ExpectedDailyGrowthGB float64 `json:"expected_daily_growth_gb,omitempty"`
+}
+
+// ShardAdvice recommends primary shard counts from data volume and retention
+type ShardAdvice struct {
+	RetainedSizeGB           float64         `json:"retained_size_gb"` // primary data held at steady state
+	RecommendedPrimaryShards int             `json:"recommended_primary_shards"` // per index
+	ProjectedShardSizeGB     float64         `json:"projected_shard_size_gb"`
+	Rollover                 *RolloverAdvice `json:"rollover,omitempty"` // set for growing, time-based data
+	RetainedIndices          int             `json:"retained_indices"`
+	TotalShards              int             `json:"total_shards"` // all retained indices, one replica each
+	DataNodes                int             `json:"data_nodes,omitempty"`
+	CurrentShards            int             `json:"current_shards,omitempty"`
+	Rationale                []string        `json:"rationale"`
+	Warnings                 []string        `json:"warnings,omitempty"`
+	RequestID                string          `json:"request_id"`
+	Timestamp                time.Time       `json:"timestamp"`
+}
+
+// RolloverAdvice suggests rollover conditions that keep time-based indices at a healthy shard size
+type RolloverAdvice struct {
+	MaxPrimaryShardSize string `json:"max_primary_shard_size"`
+	MaxAge              string `json:"max_age"`
+}
+
+// RolloverThresholds are the conditions on an alias's write index that trigger a rollover.
+// Zero values are ignored; at least one must be set.
+type RolloverThresholds struct {
+	MaxPrimarySizeGB float64 `json:"max_primary_size_gb,omitempty"` // primary store size of the write index
+	MaxAge           string  `json:"max_age,omitempty"`             // time since index creation, e.g. 7d or 12h
+	MaxDocs          int64   `json:"max_docs,omitempty"`
+}
+
+// RolloverStatus reports the last check of an alias watched by the rollover monitor
+type RolloverStatus struct {
+	Alias         string             `json:"alias"`
+	Thresholds    RolloverThresholds `json:"thresholds"`
+	WriteIndex    string             `json:"write_index,omitempty"`
+	PrimarySizeGB float64            `json:"primary_size_gb"`
+	DocsCount     int64              `json:"docs_count"`
+	Age           string             `json:"age,omitempty"`
+	IndexingRate  float64            `json:"indexing_rate"` // docs per second since the previous check
+	LastChecked   time.Time          `json:"last_checked,omitempty"`
+	LastRollover  *RolloverEvent     `json:"last_rollover,omitempty"`
+	LastError     string             `json:"last_error,omitempty"`
+}
+
+// RolloverEvent records one automatic rollover
+type RolloverEvent struct {
+	OldIndex string    `json:"old_index"`
+	NewIndex string    `json:"new_index"`
+	Reasons  []string  `json:"reasons"`
+	At       time.Time `json:"at"`
+}
+
+// AliasAction represents one step of an atomic alias update
+type AliasAction struct {
+	Action       string                 `json:"action" binding:"required"` // add or remove
+	Index        string                 `json:"index" binding:"required"`
+	Alias        string                 `json:"alias" binding:"required"`
+	IsWriteIndex *bool                  `json:"is_write_index,omitempty"` // add only
+	Filter       map[string]interface{} `json:"filter,omitempty"`         // add only
+	Routing      string                 `json:"routing,omitempty"`        // add only
+}
+
+// AliasInfo represents an alias pointing at an index
+type AliasInfo struct {
+	Alias        string                 `json:"alias"`
+	Index        string                 `json:"index"`
+	IsWriteIndex *bool                  `json:"is_write_index,omitempty"` // unset unless configured explicitly
+	Filter       map[string]interface{} `json:"filter,omitempty"`
+	Routing      string                 `json:"routing,omitempty"`
+}
+
+// RolloverConditions are the conditions passed to the rollover API; the alias only rolls
+// over when one is met. With none set it rolls over unconditionally.
+type RolloverConditions struct {
+	MaxAge  string `json:"max_age,omitempty"` // time since index creation, e.g. 7d or 12h
+	MaxDocs int64  `json:"max_docs,omitempty"`
+	MaxSize string `json:"max_size,omitempty"` // total store size of the write index, e.g. 50gb
+}
+
+// RolloverResult represents the outcome of an on-demand rollover
+type RolloverResult struct {
+	Alias             string                 `json:"alias"`
+	OldIndex          string                 `json:"old_index"`
+	NewIndex          string                 `json:"new_index"`
+	RolledOver        bool                   `json:"rolled_over"`
+	Conditions        map[string]bool        `json:"conditions,omitempty"`         // which conditions were met
+	Template          string                 `json:"template,omitempty"`           // index template the new index matched
+	InheritedSettings map[string]interface{} `json:"inherited_settings,omitempty"` // copied from the old index when no template matched
+}
+
 // IndexInfo represents comprehensive information about an index
 type IndexInfo struct {
 	IndexName    string                 `json:"index_name"`
@@ -280,7 +420,8 @@ type TranslogStats struct {
 
 // WriteMetrics represents write-specific performance metrics
 type WriteMetrics struct {
-	IndexingRate          float64   `json:"indexing_rate"` // docs per second
+	IndexingRate          float64   `json:"indexing_rate"`          // primary docs indexed per second recently
+	LifetimeIndexingRate  float64   `json:"lifetime_indexing_rate"` // docs indexed per second of indexing time over the index's life
 	AverageDocSize        int64     `json:"average_doc_size"`
 	WriteLatency          float64   `json:"write_latency_ms"`
 	BulkLatency           float64   `json:"bulk_latency_ms"`
@@ -292,6 +433,35 @@ type WriteMetrics struct {
 	OptimizationScore     float64   `json:"optimization_score"`
 	Recommendations       []string  `json:"recommendations"`
 	LastOptimized         time.Time `json:"last_optimized"`
+
+	// Whether the index can take writes right now
+	Writable            bool     `json:"writable"`
+	Health              string   `json:"health"` // green, yellow, red
+	ReadOnlyAllowDelete bool     `json:"read_only_allow_delete"` // set when a node passes the disk flood stage watermark
+	UnassignedShards    int      `json:"unassigned_shards"`
+	WriteBlockers       []string `json:"write_blockers,omitempty"` // why the index is not writable
+}
+
+// WriteMetricsSample is one point of an index's write metrics history. Rates and latency
+// cover the period since the previous sample rather than the index's lifetime.
+type WriteMetricsSample struct {
+	Timestamp         time.Time `json:"timestamp"`
+	IndexingRate      float64   `json:"indexing_rate"`    // primary docs indexed per second
+	WriteLatency      float64   `json:"write_latency_ms"` // average primary indexing time per doc
+	DocsCount         int64     `json:"docs_count"`
+	SegmentCount      int64     `json:"segment_count"`
+	TranslogSize      int64     `json:"translog_size"`
+	OptimizationScore float64   `json:"optimization_score"`
+}
+
+// WriteMetricsHistory is the sampled write metrics of an index over a window, oldest first
+type WriteMetricsHistory struct {
+	IndexName string               `json:"index_name"`
+	Window    string               `json:"window"`
+	Interval  string               `json:"interval"`
+	Samples   []WriteMetricsSample `json:"samples"`
+	RequestID string               `json:"request_id"`
+	Timestamp time.Time            `json:"timestamp"`
 }
 
 // BulkRequest represents a bulk operation request
@@ -303,6 +473,13 @@ type BulkRequest struct {
 	OptimizeFor       string                   `json:"optimize_for,omitempty"` // write_throughput, consistency
 	ErrorTolerance    string                   `json:"error_tolerance,omitempty"` // low, medium, high
 	Settings          *BulkSettings            `json:"settings,omitempty"`
+	DeadLetterIndex   string                   `json:"dead_letter_index,omitempty"` // receives permanently failed documents
+	Priority          string                   `json:"priority,omitempty"` // high, normal, low
+	SuspendRefresh    bool                     `json:"suspend_refresh,omitempty"` // disable index refresh until the request completes
+	MaxRetries        int                      `json:"max_retries,omitempty"` // retries for items rejected with 429/503, overrides settings.max_retries
+	// Shrink the batch size and workers while the cluster rejects or slows down batches,
+	// and grow them back to the initial settings once it recovers
+	Adaptive          bool                     `json:"adaptive,omitempty"`
 }
 
 // BulkOperation represents a single operation in a bulk request
@@ -310,10 +487,24 @@ type BulkOperation struct {
 	Action    string                 `json:"action"` // index, create, update, delete
 	Index     string                 `json:"_index,omitempty"`
 	ID        string                 `json:"_id,omitempty"`
-	Document  map[string]interface{} `json:"doc,omitempty"`
+	Document  map[string]interface{} `json:"doc,omitempty"` // for updates, the partial document to merge
 	Source    map[string]interface{} `json:"_source,omitempty"`
+
+	// Update actions: a script instead of a partial document, and the document to
+	// insert when the target does not exist yet (or doc_as_upsert to insert doc itself)
+	Script      map[string]interface{} `json:"script,omitempty"`
+	Upsert      map[string]interface{} `json:"upsert,omitempty"`
+	DocAsUpsert bool                   `json:"doc_as_upsert,omitempty"`
 	Version   *int64                 `json:"_version,omitempty"`
 	Routing   string                 `json:"_routing,omitempty"`
+
+	// Optimistic concurrency: only apply the operation if the document is still at this
+	// sequence number and primary term, as returned by an earlier write or get
+	IfSeqNo       *int64 `json:"if_seq_no,omitempty"`
+	IfPrimaryTerm *int64 `json:"if_primary_term,omitempty"`
+
+	// Caller-chosen key under which this operation's result is returned in BulkResponse.Results
+	CorrelationID string `json:"correlation_id,omitempty"`
 }
 
 // BulkSettings represents settings for bulk operations
@@ -323,18 +514,53 @@ type BulkSettings struct {
 	WaitForActiveShards string     `json:"wait_for_active_shards,omitempty"`
 	Pipeline         string        `json:"pipeline,omitempty"`
 	Routing          string        `json:"routing,omitempty"`
+	MaxRetries       int           `json:"max_retries,omitempty"`   // retries for transiently rejected items
+	RetryBackoff     time.Duration `json:"retry_backoff,omitempty"` // initial backoff, doubled per retry
 }
 
 // BulkResponse represents the response from a bulk operation
 type BulkResponse struct {
-	Took      int64              `json:"took"`
+	Took      int64              `json:"took"` // wall-clock milliseconds for the whole request
+	// Sum of the took times Elasticsearch reported for each batch. Batches run in
+	// parallel, so this exceeds Took when more than one worker is busy.
+	ElasticsearchTook int64      `json:"elasticsearch_took"`
+	Batches   int                `json:"batches"` // batches Elasticsearch answered
 	Errors    bool               `json:"errors"`
 	Items     []BulkResponseItem `json:"items"`
 	Summary   *BulkSummary       `json:"summary"`
+	DeadLettered int64           `json:"dead_lettered,omitempty"`
+	Aborted   bool               `json:"aborted,omitempty"` // stopped early under low error tolerance
+	SkippedOperations int64      `json:"-"` // reported in Summary
+	RetriedOperations int64      `json:"-"` // reported in Summary
+	// Results of operations that set a correlation_id, keyed by it. Items follow batch
+	// completion order, so this is how a caller finds the outcome of a given operation.
+	Results   map[string]*BulkItemResponse `json:"results,omitempty"`
+	Adaptation *BulkAdaptation   `json:"adaptation,omitempty"` // set for adaptive requests
 	RequestID string             `json:"request_id"`
 	Timestamp time.Time          `json:"timestamp"`
 }
 
+// BulkAdaptation reports how an adaptive bulk request tuned its batch size and workers
+type BulkAdaptation struct {
+	InitialBatchSize int                  `json:"initial_batch_size"`
+	InitialWorkers   int                  `json:"initial_workers"`
+	FinalBatchSize   int                  `json:"final_batch_size"`
+	FinalWorkers     int                  `json:"final_workers"`
+	Adjustments      int                  `json:"adjustments"`
+	Timeline         []BulkAdaptationStep `json:"timeline,omitempty"` // most recent adjustments, oldest first
+}
+
+// BulkAdaptationStep is one adjustment of an adaptive bulk request
+type BulkAdaptationStep struct {
+	AfterBatch      int       `json:"after_batch"` // ID of the batch that triggered it
+	BatchSize       int       `json:"batch_size"`
+	ParallelWorkers int       `json:"parallel_workers"`
+	RejectionRate   float64   `json:"rejection_rate"` // share of the batch's items rejected
+	TookMillis      int64     `json:"took_ms"`
+	Reason          string    `json:"reason"`
+	Timestamp       time.Time `json:"timestamp"`
+}
+
 // BulkResponseItem represents a single item response in bulk operation
 type BulkResponseItem struct {
 	Index  *BulkItemResponse `json:"index,omitempty"`
@@ -352,8 +578,9 @@ type BulkItemResponse struct {
 	Status  int    `json:"status"`
 	Error   *BulkError `json:"error,omitempty"`
 	Shards  *ShardsInfo `json:"_shards,omitempty"`
-	SeqNo   int64  `json:"_seq_no,omitempty"`
-	PrimaryTerm int64 `json:"_primary_term,omitempty"`
+	// Pointers so that sequence number 0 is still reported; absent for failed items
+	SeqNo   *int64 `json:"_seq_no,omitempty"`
+	PrimaryTerm *int64 `json:"_primary_term,omitempty"`
 }
 
 // BulkError represents an error in bulk operation
@@ -365,6 +592,60 @@ type BulkError struct {
 	Status int    `json:"status,omitempty"`
 }
 
+// DeleteByQueryResult represents the outcome of a delete by query. When it was not waited
+// for, only TaskID is set; poll the Elasticsearch tasks API with it for the counts.
+type DeleteByQueryResult struct {
+	IndexName        string        `json:"index_name"`
+	Completed        bool          `json:"completed"`
+	TaskID           string        `json:"task_id,omitempty"`
+	Total            int64         `json:"total"`
+	Deleted          int64         `json:"deleted"`
+	VersionConflicts int64         `json:"version_conflicts"` // documents changed while the delete ran, left in place
+	Batches          int64         `json:"batches"`
+	TimedOut         bool          `json:"timed_out"`
+	Failures         []string      `json:"failures,omitempty"`
+	Took             time.Duration `json:"took"`
+}
+
+// UpdateByQueryRequest represents a request to rewrite every document matching a query
+// with a script
+type UpdateByQueryRequest struct {
+	Query            map[string]interface{} `json:"query" binding:"required"`
+	Script           map[string]interface{} `json:"script" binding:"required"`  // source, params and lang (painless by default)
+	TargetThroughput string                 `json:"target_throughput,omitempty"` // max (default), high, medium, low
+}
+
+// UpdateByQueryResult represents the outcome of an update by query. When it was not waited
+// for, only TaskID is set; poll the Elasticsearch tasks API with it for the counts.
+type UpdateByQueryResult struct {
+	IndexName         string        `json:"index_name"`
+	Completed         bool          `json:"completed"`
+	TaskID            string        `json:"task_id,omitempty"`
+	RequestsPerSecond int           `json:"requests_per_second"` // -1 when unthrottled
+	Total             int64         `json:"total"`
+	Updated           int64         `json:"updated"`
+	Noops             int64         `json:"noops"`
+	VersionConflicts  int64         `json:"version_conflicts"` // documents changed while the update ran, left as they were
+	Batches           int64         `json:"batches"`
+	TimedOut          bool          `json:"timed_out"`
+	Failures          []string      `json:"failures,omitempty"`
+	Took              time.Duration `json:"took"`
+}
+
+// DeadLetterDocument represents a permanently failed bulk operation kept for inspection and reprocessing
+type DeadLetterDocument struct {
+	OriginalIndex string    `json:"original_index"`
+	OriginalID    string    `json:"original_id,omitempty"`
+	Action        string    `json:"action"`
+	Routing       string    `json:"routing,omitempty"`
+	Document      string    `json:"document,omitempty"` // raw JSON so differing source mappings can't collide
+	ErrorType     string    `json:"error_type"`
+	ErrorReason   string    `json:"error_reason"`
+	Status        int       `json:"status"`
+	Attempts      int       `json:"attempts"`
+	FailedAt      time.Time `json:"failed_at"`
+}
+
 // ShardsInfo represents shard information
 type ShardsInfo struct {
 	Total      int `json:"total"`
@@ -372,18 +653,97 @@ type ShardsInfo struct {
 	Failed     int `json:"failed"`
 }
 
+// BulkQueueStats reports the shared bulk scheduler's batches per priority
+type BulkQueueStats struct {
+	Queued  map[string]int `json:"queued"`
+	Running map[string]int `json:"running"`
+}
+
 // BulkSummary provides a summary of bulk operation results
 type BulkSummary struct {
 	TotalOperations     int64         `json:"total_operations"`
 	SuccessfulOperations int64        `json:"successful_operations"`
 	FailedOperations    int64         `json:"failed_operations"`
+	SkippedOperations   int64         `json:"skipped_operations,omitempty"` // in batches that failed outright or were cancelled
+	SucceededOnRetry    int64         `json:"succeeded_on_retry"` // transiently rejected at first, then written by a retry
 	IndexedDocuments    int64         `json:"indexed_documents"`
 	UpdatedDocuments    int64         `json:"updated_documents"`
 	DeletedDocuments    int64         `json:"deleted_documents"`
+	VersionConflicts    int64         `json:"version_conflicts"` // conditional writes rejected with 409
 	ProcessingTime      time.Duration `json:"processing_time"`
 	ThroughputPerSecond float64       `json:"throughput_per_second"`
 	AverageLatency      time.Duration `json:"average_latency"`
 	ErrorRate           float64       `json:"error_rate"`
+
+	// NDJSON imports: how documents without an _id were identified
+	IDStrategy   string `json:"id_strategy,omitempty"`   // content_hash or elasticsearch
+	GeneratedIDs int64  `json:"generated_ids,omitempty"` // documents given a content hash ID
+}
+
+// BulkJob reports the progress of a bulk request running in the background
+type BulkJob struct {
+	ID               string       `json:"job_id"`
+	IndexName        string       `json:"index_name"`
+	Status           string       `json:"status"` // running, completed, failed, cancelled
+	TotalOperations  int64        `json:"total_operations"`
+	BatchesTotal     int          `json:"batches_total"` // an estimate while adaptive requests resize batches
+	BatchesDone      int          `json:"batches_done"`
+	DocumentsIndexed int64        `json:"documents_indexed"`
+	DocumentsFailed  int64        `json:"documents_failed"`
+	Throughput       float64      `json:"throughput_per_second"` // documents indexed per second so far
+	Errors           []string     `json:"errors,omitempty"`      // most recent batch and item errors
+	Summary          *BulkSummary `json:"summary,omitempty"`     // set once the job has finished
+	Error            string       `json:"error,omitempty"`       // why a failed job stopped
+	CreatedAt        time.Time    `json:"created_at"`
+	CompletedAt      *time.Time   `json:"completed_at,omitempty"`
+}
+
+// BulkProgress is sent after each batch of a streamed bulk request
+type BulkProgress struct {
+	IndexName        string    `json:"index_name"`
+	Batch            int       `json:"batch"` // ID of the batch that just completed
+	TotalOperations  int64     `json:"total_operations"`
+	BatchesTotal     int       `json:"batches_total"` // an estimate while adaptive requests resize batches
+	BatchesDone      int       `json:"batches_done"`
+	DocumentsIndexed int64     `json:"documents_indexed"`
+	DocumentsFailed  int64     `json:"documents_failed"`
+	Throughput       float64   `json:"throughput_per_second"` // documents indexed per second so far
+	Errors           []string  `json:"errors,omitempty"`      // most recent batch and item errors
+	Timestamp        time.Time `json:"timestamp"`
+}
+
+// ImportCheckpoint records the progress of a resumable NDJSON import
+type ImportCheckpoint struct {
+	IdempotencyKey   string    `json:"idempotency_key"`
+	IndexName        string    `json:"index_name"`
+	LinesProcessed   int64     `json:"lines_processed"`
+	BytesProcessed   int64     `json:"bytes_processed"`
+	DocumentsIndexed int64     `json:"documents_indexed"`
+	DocumentsFailed  int64     `json:"documents_failed"`
+	Completed        bool      `json:"completed"`
+	InProgress       bool      `json:"in_progress"` // an import is running with this key
+	CreatedAt        time.Time `json:"created_at"`
+	UpdatedAt        time.Time `json:"updated_at"`
+}
+
+// CalibrationResult represents bulk parameters measured against a cluster and document shape
+type CalibrationResult struct {
+	IndexName            string             `json:"index_name"`
+	SampleSize           int                `json:"sample_size"`
+	RecommendedBatchSize int                `json:"recommended_batch_size"`
+	RecommendedWorkers   int                `json:"recommended_workers"`
+	BestThroughput       float64            `json:"best_throughput"` // docs per second
+	Trials               []CalibrationTrial `json:"trials"`
+	Duration             time.Duration      `json:"duration"`
+}
+
+// CalibrationTrial represents one measured batch size and concurrency combination
+type CalibrationTrial struct {
+	BatchSize  int           `json:"batch_size"`
+	Workers    int           `json:"workers"`
+	Throughput float64       `json:"throughput"` // docs per second
+	ErrorRate  float64       `json:"error_rate"`
+	Duration   time.Duration `json:"duration"`
 }
 
 // OptimizationRequest represents a request to optimize an index
@@ -394,6 +754,9 @@ type OptimizationRequest struct {
 	CorpusSize   string   `json:"corpus_size,omitempty"` // small, medium, large, huge
 	Priority     string   `json:"priority,omitempty"` // write_throughput, read_latency, storage_efficiency
 	ApplyChanges bool     `json:"apply_changes"`
+	
+	// Soft-deletes history retention (e.g. "12h"); longer periods cost storage and merge work
+	SoftDeletesRetentionLeasePeriod string `json:"soft_deletes_retention_lease_period,omitempty"`
 }
 
 // OptimizationResponse represents the response from index optimization
@@ -418,6 +781,22 @@ type OptimizationChange struct {
 	Category    string      `json:"category"` // write_performance, storage, reliability
 }
 
+// SettingsDiffRequest represents proposed index settings to compare with the current ones
+type SettingsDiffRequest struct {
+	Settings map[string]interface{} `json:"settings" binding:"required"`
+}
+
+// SettingsDiffResponse represents the differences between an index's current settings and
+// a proposed change, which is not applied
+type SettingsDiffResponse struct {
+	IndexName         string               `json:"index_name"`
+	Changes           []OptimizationChange `json:"changes"`
+	Unchanged         []string             `json:"unchanged,omitempty"` // proposed settings already in effect
+	PerformanceImpact *PerformanceImpact   `json:"performance_impact"`
+	RequestID         string               `json:"request_id"`
+	Timestamp         time.Time            `json:"timestamp"`
+}
+
 // PerformanceImpact represents the expected impact of optimizations
 type PerformanceImpact struct {
 	WritePerformance  string `json:"write_performance"` // improved, degraded, neutral
@@ -427,6 +806,129 @@ type PerformanceImpact struct {
 	EstimatedImprovementPercent float64 `json:"estimated_improvement_percent"`
 }
 
+// ReplicaRequest represents a request to change the replica count of an index
+type ReplicaRequest struct {
+	Replicas    *int   `json:"replicas" binding:"required,min=0"`
+	Ramp        bool   `json:"ramp,omitempty"`         // add one replica at a time, waiting for each to allocate
+	WaitTimeout string `json:"wait_timeout,omitempty"` // allocation timeout per step, e.g. "5m"
+}
+
+// ReplicaRampUpResult represents the outcome of raising replicas after a bulk load
+type ReplicaRampUpResult struct {
+	IndexName      string            `json:"index_name"`
+	FromReplicas   int               `json:"from_replicas"`
+	TargetReplicas int               `json:"target_replicas"`
+	Completed      bool              `json:"completed"`
+	Steps          []ReplicaRampStep `json:"steps"`
+	Duration       time.Duration     `json:"duration"`
+}
+
+// ForceMergeResult represents the outcome of a force merge. When it was not waited for,
+// only TaskID is set; poll the Elasticsearch tasks API with it for progress.
+type ForceMergeResult struct {
+	IndexName          string        `json:"index_name"`
+	MaxNumSegments     int           `json:"max_num_segments,omitempty"`
+	OnlyExpungeDeletes bool          `json:"only_expunge_deletes,omitempty"`
+	Completed          bool          `json:"completed"`
+	TaskID             string        `json:"task_id,omitempty"`
+	Shards             *ShardsInfo   `json:"_shards,omitempty"`
+	Duration           time.Duration `json:"duration"`
+}
+
+// ReindexResult represents the outcome of a reindex. When it was not waited for, only
+// TaskID is set; poll the Elasticsearch tasks API with it for the counts.
+type ReindexResult struct {
+	SourceIndex      string        `json:"source_index"`
+	DestIndex        string        `json:"dest_index"`
+	Completed        bool          `json:"completed"`
+	TaskID           string        `json:"task_id,omitempty"`
+	Total            int64         `json:"total"`
+	Created          int64         `json:"created"`
+	Updated          int64         `json:"updated"`
+	VersionConflicts int64         `json:"version_conflicts"`
+	Batches          int64         `json:"batches"`
+	TimedOut         bool          `json:"timed_out"`
+	Failures         []string      `json:"failures,omitempty"`
+	Took             time.Duration `json:"took"`
+}
+
+// ShrinkResult represents the outcome of shrinking an index into one with fewer shards.
+// The source is left read-only on Node.
+type ShrinkResult struct {
+	SourceIndex        string        `json:"source_index"`
+	TargetIndex        string        `json:"target_index"`
+	SourceShards       int           `json:"source_shards"`
+	TargetShards       int           `json:"target_shards"`
+	Node               string        `json:"node"`
+	Acknowledged       bool          `json:"acknowledged"`
+	ShardsAcknowledged bool          `json:"shards_acknowledged"`
+	Duration           time.Duration `json:"duration"`
+}
+
+// SplitResult represents the outcome of splitting an index into one with more shards.
+// The source is left read-only.
+type SplitResult struct {
+	SourceIndex        string        `json:"source_index"`
+	TargetIndex        string        `json:"target_index"`
+	SourceShards       int           `json:"source_shards"`
+	TargetShards       int           `json:"target_shards"`
+	RoutingShards      int           `json:"routing_shards"`
+	Acknowledged       bool          `json:"acknowledged"`
+	ShardsAcknowledged bool          `json:"shards_acknowledged"`
+	Duration           time.Duration `json:"duration"`
+}
+
+// ReplicaRampStep represents the allocation state after a single replica change
+type ReplicaRampStep struct {
+	Replicas           int           `json:"replicas"`
+	Status             string        `json:"status"`
+	ActiveShards       int           `json:"active_shards"`
+	InitializingShards int           `json:"initializing_shards"`
+	RelocatingShards   int           `json:"relocating_shards"`
+	UnassignedShards   int           `json:"unassigned_shards"`
+	Allocated          bool          `json:"allocated"`
+	Duration           time.Duration `json:"duration"`
+}
+
+// SlowLogThresholds represents per-index slow-log thresholds by operation
+type SlowLogThresholds struct {
+	Query    *SlowLogLevels `json:"query,omitempty"`    // search query phase
+	Fetch    *SlowLogLevels `json:"fetch,omitempty"`    // search fetch phase
+	Indexing *SlowLogLevels `json:"indexing,omitempty"` // document indexing
+}
+
+// SlowLogLevels represents thresholds per log level (e.g. "500ms"; "-1" disables a level)
+type SlowLogLevels struct {
+	Warn  string `json:"warn,omitempty"`
+	Info  string `json:"info,omitempty"`
+	Debug string `json:"debug,omitempty"`
+	Trace string `json:"trace,omitempty"`
+}
+
+// ComplianceReport represents how an index's settings deviate from a baseline profile
+type ComplianceReport struct {
+	IndexName   string                 `json:"index_name"`
+	Profile     string                 `json:"profile"`
+	Description string                 `json:"description"`
+	Compliant   bool                   `json:"compliant"`
+	Score       float64                `json:"score"` // percentage of checks passed
+	Checks      []ComplianceCheck      `json:"checks"`
+	Fixes       map[string]interface{} `json:"fixes,omitempty"` // dynamic settings that can be applied to the live index
+	RequestID   string                 `json:"request_id"`
+	Timestamp   time.Time              `json:"timestamp"`
+}
+
+// ComplianceCheck represents a single setting checked against a baseline profile
+type ComplianceCheck struct {
+	Setting   string      `json:"setting"`
+	Expected  string      `json:"expected"`
+	Actual    interface{} `json:"actual"`
+	Compliant bool        `json:"compliant"`
+	Severity  string      `json:"severity"` // low, medium, high
+	Reason    string      `json:"reason"`
+	Fix       string      `json:"fix,omitempty"`
+}
+
 // IndexTemplateRequest represents a request to create an index template
 type IndexTemplateRequest struct {
 	TemplateName string                 `json:"template_name" binding:"required"`
@@ -439,15 +941,97 @@ type IndexTemplateRequest struct {
 	Metadata     map[string]interface{} `json:"_meta,omitempty"`
 	WriteOptimized bool                 `json:"write_optimized,omitempty"`
 	TextHeavy      bool                 `json:"text_heavy,omitempty"`
+	ExpectedVolume  string              `json:"expected_volume,omitempty"` // low, medium, high
+	ExpectedDocSize string              `json:"expected_doc_size,omitempty"` // small, medium, large
 }
 
 // IndexTemplateResponse represents the response after creating an index template
 type IndexTemplateResponse struct {
-	TemplateName  string    `json:"template_name"`
-	Acknowledged  bool      `json:"acknowledged"`
-	IndexPatterns []string  `json:"index_patterns"`
-	RequestID     string    `json:"request_id"`
-	Timestamp     time.Time `json:"timestamp"`
+	TemplateName  string         `json:"template_name"`
+	Acknowledged  bool           `json:"acknowledged"`
+	IndexPatterns []string       `json:"index_patterns"`
+	Settings      *IndexSettings `json:"settings,omitempty"`
+	Optimizations []string       `json:"optimizations,omitempty"`
+	RequestID     string         `json:"request_id"`
+	Timestamp     time.Time      `json:"timestamp"`
+}
+
+// IndexTemplateInfo describes a composable index template stored in the cluster
+type IndexTemplateInfo struct {
+	Name          string                 `json:"name"`
+	IndexPatterns []string               `json:"index_patterns"`
+	Priority      int                    `json:"priority,omitempty"`
+	Version       int                    `json:"version,omitempty"`
+	ComposedOf    []string               `json:"composed_of,omitempty"`
+	Template      map[string]interface{} `json:"template,omitempty"` // settings, mappings and aliases
+	Metadata      map[string]interface{} `json:"_meta,omitempty"`
+}
+
+// PipelineRequest creates or replaces an ingest pipeline
+type PipelineRequest struct {
+	Description string `json:"description,omitempty"`
+	// Processors run in order, e.g. {"set": {"field": "ingested_at", "value": "{{_ingest.timestamp}}"}},
+	// {"lowercase": {"field": "tag"}} or {"geoip": {"field": "client_ip"}}
+	Processors []map[string]interface{} `json:"processors" binding:"required,min=1"`
+	OnFailure  []map[string]interface{} `json:"on_failure,omitempty"` // run instead when a processor fails
+	Version    int                      `json:"version,omitempty"`
+	Metadata   map[string]interface{}   `json:"_meta,omitempty"`
+}
+
+// PipelineInfo describes an ingest pipeline stored in the cluster
+type PipelineInfo struct {
+	ID string `json:"id"`
+	PipelineRequest
+}
+
+// OverviewResponse combines the status of all playground services into one dashboard feed
+type OverviewResponse struct {
+	Status    string            `json:"status"` // healthy, degraded, critical
+	Indexing  *IndexingOverview `json:"indexing,omitempty"`
+	Cluster   *ClusterOverview  `json:"cluster,omitempty"`
+	Search    *SearchOverview   `json:"search,omitempty"`
+	Services  []ServiceStatus   `json:"services"`
+	RequestID string            `json:"request_id"`
+	Timestamp time.Time         `json:"timestamp"`
+}
+
+// ServiceStatus represents the reachability of one service feeding the overview
+type ServiceStatus struct {
+	Name         string        `json:"name"`
+	URL          string        `json:"url,omitempty"`
+	Status       string        `json:"status"` // up, down, disabled
+	ResponseTime time.Duration `json:"response_time"`
+	Error        string        `json:"error,omitempty"`
+}
+
+// IndexingOverview summarises write activity, as seen by the index-explorer
+type IndexingOverview struct {
+	Indices             int     `json:"indices"`
+	DocsCount           int64   `json:"docs_count"`
+	IndexTotal          int64   `json:"index_total"`
+	IndexingRate        float64 `json:"indexing_rate"`          // docs per second since the previous overview
+	AverageIndexLatency float64 `json:"average_index_latency"` // milliseconds per document
+	IndexFailed         int64   `json:"index_failed"`
+}
+
+// ClusterOverview summarises cluster health, as reported by the cluster-explorer
+type ClusterOverview struct {
+	ClusterName         string  `json:"cluster_name"`
+	Status              string  `json:"status"`
+	Nodes               int     `json:"number_of_nodes"`
+	DataNodes           int     `json:"number_of_data_nodes"`
+	ActiveShards        int     `json:"active_shards"`
+	UnassignedShards    int     `json:"unassigned_shards"`
+	ActiveShardsPercent float64 `json:"active_shards_percent"`
+}
+
+// SearchOverview summarises search traffic, as reported by the search-api's metrics
+type SearchOverview struct {
+	TotalSearches  int64   `json:"total_searches"`
+	QPS            float64 `json:"qps"`             // searches per second since the previous overview
+	AverageLatency float64 `json:"average_latency"` // milliseconds
+	HTTPRequests   int64   `json:"http_requests"`
+	Errors         int64   `json:"errors"`
 }
 
 // ErrorResponse represents an error response
@@ -457,4 +1041,31 @@ type ErrorResponse struct {
 	Details   string    `json:"details,omitempty"`
 	RequestID string    `json:"request_id"`
 	Timestamp time.Time `json:"timestamp"`
-}
\ No newline at end of file
+}
+// RestoreOptimizedRequest restores one index from a snapshot under a new name, with the
+// write-optimized settings index creation would generate for the same hints
+type RestoreOptimizedRequest struct {
+	Repository      string `json:"repository" binding:"required"`
+	Snapshot        string `json:"snapshot" binding:"required"`
+	Target          string `json:"target" binding:"required"`
+	TextHeavy       bool   `json:"text_heavy,omitempty"`
+	ExpectedVolume  string `json:"expected_volume,omitempty"`   // low, medium, high
+	ExpectedDocSize string `json:"expected_doc_size,omitempty"` // small, medium, large
+	IngestionRate   string `json:"ingestion_rate,omitempty"`    // low, medium, high
+}
+
+// RestoreOptimizedResult represents the outcome of a write-optimized restore. When it was
+// not waited for, only Accepted is set.
+type RestoreOptimizedResult struct {
+	Repository    string                 `json:"repository"`
+	Snapshot      string                 `json:"snapshot"`
+	SourceIndex   string                 `json:"source_index"`
+	TargetIndex   string                 `json:"target_index"`
+	IndexSettings map[string]interface{} `json:"index_settings"` // overrides applied during restore
+	Optimizations []string               `json:"optimizations"`
+	Accepted      bool                   `json:"accepted"`
+	Completed     bool                   `json:"completed"`
+	TotalShards   int                    `json:"total_shards,omitempty"`
+	FailedShards  int                    `json:"failed_shards,omitempty"`
+	Duration      time.Duration          `json:"duration"`
+}
@@ -0,0 +1,167 @@
+package perftest
+
+import (
+	"bufio"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+
+	"github.com/saif-islam/es-playground/projects/index-explorer/internal/handlers"
+	"github.com/saif-islam/es-playground/projects/index-explorer/internal/services"
+	"github.com/saif-islam/es-playground/shared"
+)
+
+const testAPIKey = "perf-test-key"
+
+// fakeCluster is a fake Elasticsearch that records the indices created and deleted and
+// counts the documents bulk indexed into each index
+type fakeCluster struct {
+	mu      sync.Mutex
+	created []string
+	deleted []string
+	indexed map[string]int
+}
+
+func (f *fakeCluster) serveHTTP(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("X-Elastic-Product", "Elasticsearch")
+	w.Header().Set("Content-Type", "application/json")
+	index := strings.Split(strings.TrimPrefix(r.URL.Path, "/"), "/")[0]
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	switch {
+	case strings.HasSuffix(r.URL.Path, "/_bulk"):
+		var items []string
+		scanner := bufio.NewScanner(r.Body)
+		scanner.Buffer(make([]byte, 1024*1024), 1024*1024)
+		for scanner.Scan() {
+			target := index
+			if fields := strings.Split(scanner.Text(), `"_index":"`); len(fields) > 1 {
+				target = strings.SplitN(fields[1], `"`, 2)[0]
+			}
+			if scanner.Scan() {
+				f.indexed[target]++
+				items = append(items, fmt.Sprintf(`{"index":{"_index":%q,"_id":"%d","result":"created","status":201}}`, target, len(items)))
+			}
+		}
+		fmt.Fprintf(w, `{"took":3,"errors":false,"items":[%s]}`, strings.Join(items, ","))
+	case r.Method == http.MethodPut && !strings.HasPrefix(index, "_"):
+		f.created = append(f.created, index)
+		fmt.Fprintf(w, `{"acknowledged":true,"shards_acknowledged":true,"index":%q}`, index)
+	case r.Method == http.MethodDelete:
+		f.deleted = append(f.deleted, index)
+		w.Write([]byte(`{"acknowledged":true}`))
+	default:
+		w.Write([]byte(`{"version":{"number":"8.11.1"}}`))
+	}
+}
+
+// newBenchmarkAPI serves the endpoints the benchmark uses behind API key authentication,
+// backed by a fakeCluster
+func newBenchmarkAPI(t *testing.T) (string, *fakeCluster) {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+
+	cluster := &fakeCluster{indexed: make(map[string]int)}
+	es := httptest.NewServer(http.HandlerFunc(cluster.serveHTTP))
+	t.Cleanup(es.Close)
+
+	client, err := shared.NewESClient(&shared.ESConfig{URLs: []string{es.URL}}, zap.NewNop())
+	if err != nil {
+		t.Fatalf("Failed to create client: %v", err)
+	}
+	indexService := services.NewIndexService(client, zap.NewNop())
+	documentService := services.NewDocumentService(client, zap.NewNop())
+	indexHandler := handlers.NewIndexHandler(indexService, documentService, zap.NewNop())
+	documentHandler := handlers.NewDocumentHandler(documentService, indexService, zap.NewNop())
+
+	router := gin.New()
+	v1 := router.Group("/api/v1", shared.RequireAPIKey(shared.AuthConfig{APIKeys: []string{testAPIKey}}, zap.NewNop()))
+	v1.POST("/indices/write-optimized", indexHandler.CreateWriteOptimizedIndex)
+	v1.DELETE("/indices/:index", indexHandler.DeleteIndex)
+	v1.POST("/indices/:index/bulk", documentHandler.BulkIndex)
+	v1.POST("/indices/:index/import/ndjson", documentHandler.BulkImportNDJSON)
+	v1.POST("/bulk/adaptive", documentHandler.AdaptiveBulkIndex)
+
+	api := httptest.NewServer(router)
+	t.Cleanup(api.Close)
+	return api.URL, cluster
+}
+
+func TestRun(t *testing.T) {
+	apiURL, cluster := newBenchmarkAPI(t)
+	docCount, workers, batchSize, _ := Preset("quick")
+	perfTest := New(apiURL, docCount, workers, batchSize)
+	perfTest.APIKey = testAPIKey
+
+	results := Run(perfTest)
+	Cleanup(perfTest)
+
+	if len(results) != 5 {
+		t.Fatalf("Expected 5 test results, got %d", len(results))
+	}
+	for _, result := range results {
+		if result.ErrorCount != 0 || result.DocumentCount != docCount {
+			t.Errorf("Expected %s to index %d documents without errors, got %+v", result.TestName, docCount, result)
+		}
+	}
+
+	if len(cluster.created) != 1 || cluster.created[0] != perfTest.IndexName {
+		t.Errorf("Expected %s to be created, got %v", perfTest.IndexName, cluster.created)
+	}
+	expectedIndexed := map[string]int{
+		perfTest.IndexName:               3 * docCount, // small, medium and large documents
+		perfTest.IndexName + "-adaptive": docCount,
+		perfTest.IndexName + "-ndjson":   docCount,
+	}
+	for index, expected := range expectedIndexed {
+		if cluster.indexed[index] != expected {
+			t.Errorf("Expected %d documents in %s, got %d", expected, index, cluster.indexed[index])
+		}
+	}
+	if len(cluster.deleted) != 3 {
+		t.Errorf("Expected the 3 test indices to be deleted, got %v", cluster.deleted)
+	}
+}
+
+func TestRun_Unauthorized(t *testing.T) {
+	apiURL, cluster := newBenchmarkAPI(t)
+	perfTest := New(apiURL, 10, 2, 5)
+
+	if results := Run(perfTest); len(results) != 0 {
+		t.Errorf("Expected no results without an API key, got %d", len(results))
+	}
+	if len(cluster.created) != 0 || len(cluster.indexed) != 0 {
+		t.Errorf("Expected no requests to reach Elasticsearch, got %v created and %v indexed", cluster.created, cluster.indexed)
+	}
+}
+
+func TestPreset(t *testing.T) {
+	testCases := []struct {
+		name          string
+		expectedDocs  int
+		expectedFound bool
+	}{
+		{name: "quick", expectedDocs: 100, expectedFound: true},
+		{name: "heavy", expectedDocs: 10000, expectedFound: true},
+		{name: "huge", expectedFound: false},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			docCount, workers, batchSize, ok := Preset(tc.name)
+			if ok != tc.expectedFound || docCount != tc.expectedDocs {
+				t.Errorf("Expected %d docs (found %v), got %d (found %v)", tc.expectedDocs, tc.expectedFound, docCount, ok)
+			}
+			if ok && (workers <= 0 || batchSize <= 0) {
+				t.Errorf("Expected positive workers and batch size, got %d and %d", workers, batchSize)
+			}
+		})
+	}
+}
@@ -0,0 +1,137 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+
+	"go.uber.org/zap"
+
+	"github.com/saif-islam/es-playground/projects/index-explorer/internal/models"
+	"github.com/saif-islam/es-playground/shared"
+)
+
+// UpdateAliases applies alias additions and removals in a single atomic step, so an alias
+// can be moved from an old index to a new one without readers ever seeing neither or both
+func (s *IndexService) UpdateAliases(ctx context.Context, actions []models.AliasAction) error {
+	body, err := buildAliasActionsBody(actions)
+	if err != nil {
+		return err
+	}
+
+	s.logger.Info("Updating aliases", zap.Int("actions", len(actions)))
+
+	bodyBytes, err := json.Marshal(body)
+	if err != nil {
+		return fmt.Errorf("failed to marshal alias actions: %w", err)
+	}
+
+	res, err := s.esClient.Indices.UpdateAliases(
+		strings.NewReader(string(bodyBytes)),
+		s.esClient.Indices.UpdateAliases.WithContext(ctx),
+	)
+	if err != nil {
+		return fmt.Errorf("failed to update aliases: %w", err)
+	}
+	defer res.Body.Close()
+
+	if res.IsError() {
+		return shared.ParseESError(res)
+	}
+
+	for _, action := range actions {
+		s.logger.Info("Alias updated",
+			zap.String("action", action.Action),
+			zap.String("index", action.Index),
+			zap.String("alias", action.Alias))
+	}
+
+	return nil
+}
+
+// buildAliasActionsBody validates alias actions and assembles the _aliases request body
+func buildAliasActionsBody(actions []models.AliasAction) (map[string]interface{}, error) {
+	if len(actions) == 0 {
+		return nil, fmt.Errorf("at least one alias action is required")
+	}
+
+	steps := make([]map[string]interface{}, 0, len(actions))
+	for i, action := range actions {
+		if action.Index == "" || action.Alias == "" {
+			return nil, fmt.Errorf("action %d: index and alias are required", i)
+		}
+
+		step := map[string]interface{}{
+			"index": action.Index,
+			"alias": action.Alias,
+		}
+
+		switch action.Action {
+		case "add":
+			if action.IsWriteIndex != nil {
+				step["is_write_index"] = *action.IsWriteIndex
+			}
+			if action.Filter != nil {
+				step["filter"] = action.Filter
+			}
+			if action.Routing != "" {
+				step["routing"] = action.Routing
+			}
+		case "remove":
+			if action.IsWriteIndex != nil || action.Filter != nil || action.Routing != "" {
+				return nil, fmt.Errorf("action %d: is_write_index, filter and routing only apply to add", i)
+			}
+		default:
+			return nil, fmt.Errorf("action %d: unknown action %q, must be add or remove", i, action.Action)
+		}
+
+		steps = append(steps, map[string]interface{}{action.Action: step})
+	}
+
+	return map[string]interface{}{"actions": steps}, nil
+}
+
+// GetIndexAliases lists the aliases pointing at an index
+func (s *IndexService) GetIndexAliases(ctx context.Context, indexName string) ([]models.AliasInfo, error) {
+	res, err := s.esClient.Indices.GetAlias(
+		s.esClient.Indices.GetAlias.WithContext(ctx),
+		s.esClient.Indices.GetAlias.WithIndex(indexName),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get aliases: %w", err)
+	}
+	defer res.Body.Close()
+
+	if res.IsError() {
+		return nil, shared.ParseESError(res)
+	}
+
+	var indices map[string]struct {
+		Aliases map[string]struct {
+			IsWriteIndex *bool                  `json:"is_write_index"`
+			Filter       map[string]interface{} `json:"filter"`
+			IndexRouting string                 `json:"index_routing"`
+		} `json:"aliases"`
+	}
+	if err := shared.DecodeJSONResponse(res, &indices); err != nil {
+		return nil, fmt.Errorf("failed to decode aliases: %w", err)
+	}
+
+	aliases := []models.AliasInfo{}
+	for index, entry := range indices {
+		for alias, details := range entry.Aliases {
+			aliases = append(aliases, models.AliasInfo{
+				Alias:        alias,
+				Index:        index,
+				IsWriteIndex: details.IsWriteIndex,
+				Filter:       details.Filter,
+				Routing:      details.IndexRouting,
+			})
+		}
+	}
+	sort.Slice(aliases, func(i, j int) bool { return aliases[i].Alias < aliases[j].Alias })
+
+	return aliases, nil
+}
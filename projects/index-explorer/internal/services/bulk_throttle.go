@@ -0,0 +1,188 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/saif-islam/es-playground/projects/index-explorer/internal/models"
+)
+
+// Adaptive bulk throttling thresholds
+const (
+	// A batch is under pressure when at least this share of its items was rejected with a
+	// retryable status, whether or not a retry later succeeded
+	throttleRejectionRate = 0.1
+	// ...or when Elasticsearch took this many times longer per document than the fastest
+	// healthy batch so far
+	throttleLatencyFactor = 3
+	// Healthy batches in a row before ramping back up
+	throttleRampAfter = 3
+
+	minThrottleBatchSize = 10
+	// maxThrottleSteps caps the timeline kept on the response, oldest dropped first
+	maxThrottleSteps = 100
+)
+
+// bulkThrottle adapts the batch size and concurrency of an adaptive bulk request to how
+// the cluster copes. Batches under pressure halve the batch size and drop a worker; a run
+// of healthy batches grows them back towards the request's initial settings, which are
+// never exceeded.
+//
+// Workers are limited by withholding slots of the request's slot channel: a finishing batch
+// keeps its slot instead of freeing it while fewer workers are wanted, and ramping up frees
+// withheld slots again.
+type bulkThrottle struct {
+	mu sync.Mutex
+
+	batchSize    int
+	workers      int
+	maxBatchSize int
+	maxWorkers   int
+	withheld     int // slots kept occupied to run fewer than maxWorkers batches
+
+	fastestPerDoc time.Duration // lowest Elasticsearch time per document of a healthy batch
+	healthyStreak int
+	adaptation    *models.BulkAdaptation
+}
+
+func newBulkThrottle(batchSize, workers int) *bulkThrottle {
+	return &bulkThrottle{
+		batchSize:    batchSize,
+		workers:      workers,
+		maxBatchSize: batchSize,
+		maxWorkers:   workers,
+		adaptation: &models.BulkAdaptation{
+			InitialBatchSize: batchSize,
+			InitialWorkers:   workers,
+			FinalBatchSize:   batchSize,
+			FinalWorkers:     workers,
+		},
+	}
+}
+
+// size returns the number of operations to put in the next batch
+func (t *bulkThrottle) size() int {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.batchSize
+}
+
+// withholdSlot reports whether a finishing batch should keep its slot occupied rather
+// than free it, because fewer workers are wanted than slots are free
+func (t *bulkThrottle) withholdSlot() bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.withheld < t.maxWorkers-t.workers {
+		t.withheld++
+		return true
+	}
+	return false
+}
+
+// observe adapts the settings to a finished batch and returns how many withheld slots
+// to free
+func (t *bulkThrottle) observe(result batchResult) int {
+	// Batches cancelled by an aborting request say nothing about the cluster
+	if errors.Is(result.err, context.Canceled) {
+		return 0
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	rejectionRate, perDoc := batchPressure(result)
+	slow := t.fastestPerDoc > 0 && perDoc > throttleLatencyFactor*t.fastestPerDoc
+
+	switch {
+	case result.err != nil || rejectionRate >= throttleRejectionRate || slow:
+		t.healthyStreak = 0
+		batchSize := max(t.batchSize/2, min(minThrottleBatchSize, t.maxBatchSize))
+		workers := max(t.workers-1, 1)
+		if batchSize == t.batchSize && workers == t.workers {
+			return 0
+		}
+
+		reason := fmt.Sprintf("%.0f%% of items rejected", rejectionRate*100)
+		switch {
+		case result.err != nil:
+			reason = fmt.Sprintf("batch failed: %v", result.err)
+		case slow:
+			reason = fmt.Sprintf("%v per document, over %dx the fastest batch", perDoc, throttleLatencyFactor)
+		}
+		t.adapt(result, batchSize, workers, rejectionRate, reason)
+		return 0
+
+	default:
+		if perDoc > 0 && (t.fastestPerDoc == 0 || perDoc < t.fastestPerDoc) {
+			t.fastestPerDoc = perDoc
+		}
+		t.healthyStreak++
+		if t.healthyStreak < throttleRampAfter || (t.batchSize == t.maxBatchSize && t.workers == t.maxWorkers) {
+			return 0
+		}
+
+		t.healthyStreak = 0
+		t.adapt(result, min(t.batchSize*2, t.maxBatchSize), min(t.workers+1, t.maxWorkers), rejectionRate,
+			fmt.Sprintf("%d healthy batches in a row", throttleRampAfter))
+
+		// Free the slots no longer needed to hold workers back
+		free := max(t.withheld-(t.maxWorkers-t.workers), 0)
+		t.withheld -= free
+		return free
+	}
+}
+
+// adapt switches to new settings and records the change; the caller holds the lock
+func (t *bulkThrottle) adapt(result batchResult, batchSize, workers int, rejectionRate float64, reason string) {
+	t.batchSize = batchSize
+	t.workers = workers
+
+	t.adaptation.FinalBatchSize = batchSize
+	t.adaptation.FinalWorkers = workers
+	t.adaptation.Adjustments++
+	t.adaptation.Timeline = append(t.adaptation.Timeline, models.BulkAdaptationStep{
+		AfterBatch:      result.id,
+		BatchSize:       batchSize,
+		ParallelWorkers: workers,
+		RejectionRate:   rejectionRate,
+		TookMillis:      result.took,
+		Reason:          reason,
+		Timestamp:       time.Now(),
+	})
+	if len(t.adaptation.Timeline) > maxThrottleSteps {
+		t.adaptation.Timeline = t.adaptation.Timeline[len(t.adaptation.Timeline)-maxThrottleSteps:]
+	}
+}
+
+// report returns the adaptation so far, or nil when the request isn't adaptive
+func (t *bulkThrottle) report() *models.BulkAdaptation {
+	if t == nil {
+		return nil
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	report := *t.adaptation
+	report.Timeline = append([]models.BulkAdaptationStep(nil), t.adaptation.Timeline...)
+	return &report
+}
+
+// batchPressure returns the share of a batch's items that were rejected with a retryable
+// status, including those that later succeeded on a retry, and Elasticsearch's time per document
+func batchPressure(result batchResult) (float64, time.Duration) {
+	if len(result.items) == 0 {
+		return 0, 0
+	}
+
+	rejected := result.retried
+	for _, item := range result.items {
+		if itemResult := bulkItemResult(item); itemResult != nil && itemResult.Error != nil && isRetryableBulkStatus(itemResult.Status) {
+			rejected++
+		}
+	}
+
+	perDoc := time.Duration(result.took) * time.Millisecond / time.Duration(len(result.items))
+	return float64(rejected) / float64(len(result.items)), perDoc
+}
@@ -0,0 +1,278 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/saif-islam/es-playground/projects/index-explorer/internal/models"
+)
+
+// Bulk job states
+const (
+	BulkJobRunning   = "running"
+	BulkJobCompleted = "completed"
+	BulkJobFailed    = "failed"
+	BulkJobCancelled = "cancelled"
+)
+
+const (
+	// defaultBulkJobTTL is how long a finished job stays queryable
+	defaultBulkJobTTL = time.Hour
+	// maxBulkJobErrors caps the errors kept on a job, oldest dropped first
+	maxBulkJobErrors = 20
+)
+
+// bulkJob is a bulk request running in the background
+type bulkJob struct {
+	job            models.BulkJob
+	cancel         context.CancelFunc
+	operationsDone int64 // operations in the batches recorded so far
+}
+
+// bulkJobs is the registry of background bulk requests. Finished jobs are dropped once
+// they are older than the TTL.
+type bulkJobs struct {
+	mu   sync.Mutex
+	jobs map[string]*bulkJob
+	ttl  time.Duration
+}
+
+func newBulkJobs() *bulkJobs {
+	return &bulkJobs{jobs: make(map[string]*bulkJob), ttl: defaultBulkJobTTL}
+}
+
+// SetBulkJobTTL sets how long finished bulk jobs stay queryable
+func (s *DocumentService) SetBulkJobTTL(ttl time.Duration) {
+	if ttl <= 0 {
+		return
+	}
+	s.bulkJobs.mu.Lock()
+	defer s.bulkJobs.mu.Unlock()
+	s.bulkJobs.ttl = ttl
+}
+
+// StartBulkJob validates a bulk request and runs it in the background, returning the job
+// to poll for progress. The job keeps the request's cluster but not its deadline.
+func (s *DocumentService) StartBulkJob(ctx context.Context, req *models.BulkRequest) (*models.BulkJob, error) {
+	if err := s.validateBulkRequest(req); err != nil {
+		return nil, fmt.Errorf("invalid bulk request: %w", err)
+	}
+
+	jobCtx, cancel := context.WithCancel(context.WithoutCancel(ctx))
+	now := time.Now()
+	entry := &bulkJob{
+		job: models.BulkJob{
+			ID:              fmt.Sprintf("bulk-job-%d", now.UnixNano()),
+			IndexName:       req.IndexName,
+			Status:          BulkJobRunning,
+			TotalOperations: int64(len(req.Operations)),
+			BatchesTotal:    (len(req.Operations) + req.BatchSize - 1) / req.BatchSize,
+			CreatedAt:       now,
+		},
+		cancel: cancel,
+	}
+
+	s.bulkJobs.mu.Lock()
+	s.bulkJobs.expire(now)
+	s.bulkJobs.jobs[entry.job.ID] = entry
+	snapshot := entry.snapshot()
+	s.bulkJobs.mu.Unlock()
+
+	s.logger.Info("Started bulk job",
+		zap.String("job_id", snapshot.ID),
+		zap.String("index", req.IndexName),
+		zap.Int64("operations", snapshot.TotalOperations))
+
+	go func() {
+		defer cancel()
+		response, err := s.bulkIndex(jobCtx, req, nil, func(result batchResult) {
+			s.bulkJobs.mu.Lock()
+			defer s.bulkJobs.mu.Unlock()
+			entry.recordBatch(result)
+		})
+		s.finishBulkJob(jobCtx, entry, response, err)
+	}()
+
+	return snapshot, nil
+}
+
+// StreamBulkIndex runs a bulk request like BulkIndex, calling onProgress with the totals so
+// far after each batch completes. Calls come from one goroutine, in completion order.
+func (s *DocumentService) StreamBulkIndex(ctx context.Context, req *models.BulkRequest, onProgress func(*models.BulkProgress)) (*models.BulkResponse, error) {
+	if err := s.validateBulkRequest(req); err != nil {
+		return nil, fmt.Errorf("invalid bulk request: %w", err)
+	}
+
+	// Progress is counted the same way as for background jobs
+	tracker := &bulkJob{
+		job: models.BulkJob{
+			IndexName:       req.IndexName,
+			TotalOperations: int64(len(req.Operations)),
+			BatchesTotal:    (len(req.Operations) + req.BatchSize - 1) / req.BatchSize,
+			CreatedAt:       time.Now(),
+		},
+	}
+
+	return s.bulkIndex(ctx, req, nil, func(result batchResult) {
+		tracker.recordBatch(result)
+		onProgress(tracker.progress(result.id))
+	})
+}
+
+// finishBulkJob records the outcome of a job's bulk request
+func (s *DocumentService) finishBulkJob(ctx context.Context, entry *bulkJob, response *models.BulkResponse, err error) {
+	s.bulkJobs.mu.Lock()
+	defer s.bulkJobs.mu.Unlock()
+
+	job := &entry.job
+	now := time.Now()
+	job.CompletedAt = &now
+
+	var toleranceErr *BulkToleranceError
+	switch {
+	case ctx.Err() != nil:
+		job.Status = BulkJobCancelled
+	case err != nil:
+		job.Status = BulkJobFailed
+		job.Error = err.Error()
+		if errors.As(err, &toleranceErr) {
+			job.Summary = toleranceErr.Response.Summary
+		}
+	default:
+		job.Status = BulkJobCompleted
+		job.Summary = response.Summary
+	}
+
+	s.logger.Info("Finished bulk job",
+		zap.String("job_id", job.ID),
+		zap.String("status", job.Status),
+		zap.Int64("indexed", job.DocumentsIndexed),
+		zap.Int64("failed", job.DocumentsFailed),
+		zap.Error(err))
+}
+
+// GetBulkJob returns the current state of a bulk job
+func (s *DocumentService) GetBulkJob(id string) (*models.BulkJob, bool) {
+	s.bulkJobs.mu.Lock()
+	defer s.bulkJobs.mu.Unlock()
+
+	s.bulkJobs.expire(time.Now())
+	entry, ok := s.bulkJobs.jobs[id]
+	if !ok {
+		return nil, false
+	}
+	return entry.snapshot(), true
+}
+
+// ListBulkJobs returns every job still in the registry, newest first
+func (s *DocumentService) ListBulkJobs() []*models.BulkJob {
+	s.bulkJobs.mu.Lock()
+	defer s.bulkJobs.mu.Unlock()
+
+	s.bulkJobs.expire(time.Now())
+	jobs := make([]*models.BulkJob, 0, len(s.bulkJobs.jobs))
+	for _, entry := range s.bulkJobs.jobs {
+		jobs = append(jobs, entry.snapshot())
+	}
+	sort.Slice(jobs, func(i, j int) bool {
+		return jobs[i].CreatedAt.After(jobs[j].CreatedAt)
+	})
+	return jobs
+}
+
+// CancelBulkJob stops a running job. Batches already sent to Elasticsearch are not rolled
+// back. Cancelling a finished job has no effect.
+func (s *DocumentService) CancelBulkJob(id string) (*models.BulkJob, bool) {
+	s.bulkJobs.mu.Lock()
+	defer s.bulkJobs.mu.Unlock()
+
+	entry, ok := s.bulkJobs.jobs[id]
+	if !ok {
+		return nil, false
+	}
+	if entry.job.Status == BulkJobRunning {
+		entry.cancel()
+		s.logger.Info("Cancelling bulk job", zap.String("job_id", id))
+	}
+	return entry.snapshot(), true
+}
+
+// expire drops finished jobs older than the TTL; the caller holds the lock
+func (r *bulkJobs) expire(now time.Time) {
+	for id, entry := range r.jobs {
+		if completed := entry.job.CompletedAt; completed != nil && now.Sub(*completed) > r.ttl {
+			delete(r.jobs, id)
+		}
+	}
+}
+
+// recordBatch adds a finished batch to the job's progress; the caller holds the lock
+func (e *bulkJob) recordBatch(result batchResult) {
+	job := &e.job
+	job.BatchesDone++
+	e.operationsDone += int64(result.operations)
+
+	// Adaptive requests change the batch size as they go, so the batches left are
+	// re-estimated from the operations left and the size the next batches will use
+	if size := int64(result.nextBatchSize); size > 0 {
+		remaining := max(job.TotalOperations-e.operationsDone, 0)
+		job.BatchesTotal = job.BatchesDone + int((remaining+size-1)/size)
+	}
+
+	if result.err != nil {
+		job.DocumentsFailed += int64(result.operations)
+		e.addError(fmt.Sprintf("batch %d: %v", result.id, result.err))
+	}
+	for _, item := range result.items {
+		itemResult := bulkItemResult(item)
+		if itemResult == nil {
+			continue
+		}
+		if itemResult.Error == nil {
+			job.DocumentsIndexed++
+			continue
+		}
+		job.DocumentsFailed++
+		e.addError(fmt.Sprintf("%s %s: %s", itemResult.Index, itemResult.ID, itemResult.Error.Reason))
+	}
+
+	if elapsed := time.Since(job.CreatedAt).Seconds(); elapsed > 0 {
+		job.Throughput = float64(job.DocumentsIndexed) / elapsed
+	}
+}
+
+func (e *bulkJob) addError(message string) {
+	e.job.Errors = append(e.job.Errors, message)
+	if len(e.job.Errors) > maxBulkJobErrors {
+		e.job.Errors = e.job.Errors[len(e.job.Errors)-maxBulkJobErrors:]
+	}
+}
+
+// progress reports the job's totals after the given batch
+func (e *bulkJob) progress(batch int) *models.BulkProgress {
+	return &models.BulkProgress{
+		IndexName:        e.job.IndexName,
+		Batch:            batch,
+		TotalOperations:  e.job.TotalOperations,
+		BatchesTotal:     e.job.BatchesTotal,
+		BatchesDone:      e.job.BatchesDone,
+		DocumentsIndexed: e.job.DocumentsIndexed,
+		DocumentsFailed:  e.job.DocumentsFailed,
+		Throughput:       e.job.Throughput,
+		Errors:           append([]string(nil), e.job.Errors...),
+		Timestamp:        time.Now(),
+	}
+}
+
+// snapshot copies the job so it can be returned without the lock; the caller holds the lock
+func (e *bulkJob) snapshot() *models.BulkJob {
+	snapshot := e.job
+	snapshot.Errors = append([]string(nil), e.job.Errors...)
+	return &snapshot
+}
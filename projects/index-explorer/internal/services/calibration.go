@@ -0,0 +1,314 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/saif-islam/es-playground/projects/index-explorer/internal/models"
+	"github.com/saif-islam/es-playground/shared"
+)
+
+// calibrationSampleSize is the number of documents taken from an import to calibrate against
+const calibrationSampleSize = 500
+
+// Candidate parameters tried during calibration
+var (
+	calibrationBatchSizes = []int{50, 100, 250, 500}
+	calibrationWorkers    = []int{1, 2, 4, 8}
+)
+
+// Calibrate measures bulk throughput for a sample of operations at several batch sizes and
+// worker counts, and returns the fastest error-free combination. Trials index into a
+// temporary copy of the target index, so the target itself is never written to. The result
+// is remembered and used as the default for later bulk requests against the same index.
+func (s *DocumentService) Calibrate(ctx context.Context, indexName string, sampleOps []models.BulkOperation) (*models.CalibrationResult, error) {
+	startTime := time.Now()
+
+	sample := calibrationSample(sampleOps)
+	if len(sample) == 0 {
+		return nil, fmt.Errorf("no indexable documents in calibration sample")
+	}
+
+	scratchIndex := fmt.Sprintf("%s-calibration-%d", indexName, time.Now().UnixNano())
+	if err := s.createCalibrationIndex(ctx, indexName, scratchIndex); err != nil {
+		return nil, fmt.Errorf("failed to create calibration index: %w", err)
+	}
+	defer s.deleteCalibrationIndex(ctx, scratchIndex)
+
+	s.logger.Info("Starting bulk calibration",
+		zap.String("index", indexName),
+		zap.String("calibration_index", scratchIndex),
+		zap.Int("sample_size", len(sample)))
+
+	result := &models.CalibrationResult{
+		IndexName:  indexName,
+		SampleSize: len(sample),
+		Trials:     []models.CalibrationTrial{},
+	}
+
+	for _, batchSize := range calibrationBatchSizes {
+		for _, workers := range calibrationWorkers {
+			if !calibrationTrialUseful(len(sample), batchSize, workers) {
+				continue
+			}
+
+			trial, err := s.runCalibrationTrial(ctx, scratchIndex, sample, batchSize, workers)
+			if err != nil {
+				return nil, err
+			}
+			result.Trials = append(result.Trials, *trial)
+		}
+	}
+
+	best, ok := bestCalibrationTrial(result.Trials)
+	if !ok {
+		return nil, fmt.Errorf("every calibration trial had indexing errors")
+	}
+
+	result.RecommendedBatchSize = best.BatchSize
+	result.RecommendedWorkers = best.Workers
+	result.BestThroughput = best.Throughput
+	result.Duration = time.Since(startTime)
+
+	s.calibrationMu.Lock()
+	s.calibrations[indexName] = result
+	s.calibrationMu.Unlock()
+
+	s.logger.Info("Completed bulk calibration",
+		zap.String("index", indexName),
+		zap.Int("batch_size", result.RecommendedBatchSize),
+		zap.Int("workers", result.RecommendedWorkers),
+		zap.Float64("throughput", result.BestThroughput),
+		zap.Int("trials", len(result.Trials)),
+		zap.Duration("duration", result.Duration))
+
+	return result, nil
+}
+
+// GetCalibration returns the most recent calibration result for an index
+func (s *DocumentService) GetCalibration(indexName string) (*models.CalibrationResult, bool) {
+	s.calibrationMu.RLock()
+	defer s.calibrationMu.RUnlock()
+
+	result, ok := s.calibrations[indexName]
+	return result, ok
+}
+
+// calibrateImport calibrates against the start of an import and applies the result to its options
+func (s *DocumentService) calibrateImport(ctx context.Context, indexName string, operations []models.BulkOperation, options *BulkImportOptions) {
+	sample := operations
+	if len(sample) > calibrationSampleSize {
+		sample = sample[:calibrationSampleSize]
+	}
+
+	result, err := s.Calibrate(ctx, indexName, sample)
+	if err != nil {
+		// Calibration is an optimisation; the import still runs with the requested settings
+		s.logger.Warn("Bulk calibration failed, using requested import settings",
+			zap.String("index", indexName),
+			zap.Error(err))
+		return
+	}
+
+	options.BatchSize = result.RecommendedBatchSize
+	options.ParallelWorkers = result.RecommendedWorkers
+}
+
+// calibrationSample copies the indexable operations of a sample as plain index actions
+// without IDs, so trials neither overwrite each other nor depend on existing documents
+func calibrationSample(operations []models.BulkOperation) []models.BulkOperation {
+	sample := make([]models.BulkOperation, 0, len(operations))
+	for _, op := range operations {
+		if op.Action != "index" && op.Action != "create" {
+			continue
+		}
+		doc := operationDocument(op)
+		if doc == nil {
+			continue
+		}
+		sample = append(sample, models.BulkOperation{
+			Action:   "index",
+			Document: doc,
+			Routing:  op.Routing,
+		})
+	}
+	return sample
+}
+
+// calibrationTrialUseful reports whether a combination is worth measuring for the sample:
+// a batch larger than the sample, or more workers than batches, measures nothing new
+func calibrationTrialUseful(sampleSize, batchSize, workers int) bool {
+	if batchSize > sampleSize && batchSize != calibrationBatchSizes[0] {
+		return false
+	}
+	batches := (sampleSize + batchSize - 1) / batchSize
+	return workers == 1 || batches >= workers
+}
+
+// bestCalibrationTrial returns the error-free trial with the highest throughput
+func bestCalibrationTrial(trials []models.CalibrationTrial) (models.CalibrationTrial, bool) {
+	var best models.CalibrationTrial
+	found := false
+	for _, trial := range trials {
+		if trial.ErrorRate > 0 {
+			continue
+		}
+		if !found || trial.Throughput > best.Throughput {
+			best = trial
+			found = true
+		}
+	}
+	return best, found
+}
+
+// runCalibrationTrial indexes the sample once with the given batch size and worker count
+func (s *DocumentService) runCalibrationTrial(ctx context.Context, scratchIndex string, sample []models.BulkOperation, batchSize, workers int) (*models.CalibrationTrial, error) {
+	response, err := s.BulkIndex(ctx, &models.BulkRequest{
+		IndexName:       scratchIndex,
+		Operations:      sample,
+		BatchSize:       batchSize,
+		ParallelWorkers: workers,
+		OptimizeFor:     "write_throughput",
+		ErrorTolerance:  "high",
+		Settings: &models.BulkSettings{
+			RefreshPolicy: "false",
+			Timeout:       60 * time.Second,
+			// Retries would hide the rejections calibration is meant to detect
+			MaxRetries: 0,
+		},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("calibration trial failed (batch_size=%d, workers=%d): %w", batchSize, workers, err)
+	}
+
+	trial := &models.CalibrationTrial{
+		BatchSize:  batchSize,
+		Workers:    workers,
+		Throughput: response.Summary.ThroughputPerSecond,
+		ErrorRate:  response.Summary.ErrorRate,
+		Duration:   response.Summary.ProcessingTime,
+	}
+
+	s.logger.Debug("Calibration trial finished",
+		zap.Int("batch_size", batchSize),
+		zap.Int("workers", workers),
+		zap.Float64("throughput", trial.Throughput),
+		zap.Float64("error_rate", trial.ErrorRate))
+
+	return trial, nil
+}
+
+// createCalibrationIndex creates a scratch index with the target's mappings and shard count.
+// Replicas are disabled and refresh is off, matching how a large import is usually loaded.
+func (s *DocumentService) createCalibrationIndex(ctx context.Context, indexName, scratchIndex string) error {
+	body := map[string]interface{}{
+		"settings": map[string]interface{}{
+			"index": map[string]interface{}{
+				"number_of_replicas": 0,
+				"refresh_interval":   "-1",
+			},
+		},
+	}
+
+	mappings, shards, err := s.getIndexTemplateForCalibration(ctx, indexName)
+	if err != nil {
+		return err
+	}
+	if mappings != nil {
+		body["mappings"] = mappings
+	}
+	if shards != "" {
+		body["settings"].(map[string]interface{})["index"].(map[string]interface{})["number_of_shards"] = shards
+	}
+
+	bodyBytes, err := json.Marshal(body)
+	if err != nil {
+		return err
+	}
+
+	res, err := s.esClient.Indices.Create(
+		scratchIndex,
+		s.esClient.Indices.Create.WithContext(ctx),
+		s.esClient.Indices.Create.WithBody(bytes.NewReader(bodyBytes)),
+	)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+
+	if res.IsError() {
+		return shared.ParseESError(res)
+	}
+
+	return nil
+}
+
+// getIndexTemplateForCalibration returns the mappings and shard count of an existing index.
+// A missing index yields neither, so calibration runs against dynamic mappings.
+func (s *DocumentService) getIndexTemplateForCalibration(ctx context.Context, indexName string) (map[string]interface{}, string, error) {
+	res, err := s.esClient.Indices.Get(
+		[]string{indexName},
+		s.esClient.Indices.Get.WithContext(ctx),
+	)
+	if err != nil {
+		return nil, "", err
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode == http.StatusNotFound {
+		return nil, "", nil
+	}
+	if res.IsError() {
+		return nil, "", shared.ParseESError(res)
+	}
+
+	var response map[string]struct {
+		Mappings map[string]interface{} `json:"mappings"`
+		Settings struct {
+			Index struct {
+				NumberOfShards string `json:"number_of_shards"`
+			} `json:"index"`
+		} `json:"settings"`
+	}
+	if err := shared.DecodeJSONResponse(res, &response); err != nil {
+		return nil, "", err
+	}
+
+	// An alias resolves to its backing indices; they share a template, so use any one
+	for _, index := range response {
+		return index.Mappings, index.Settings.Index.NumberOfShards, nil
+	}
+
+	return nil, "", nil
+}
+
+// deleteCalibrationIndex removes the scratch index, even if the request context has ended.
+// Values such as the selected cluster are kept from the request context.
+func (s *DocumentService) deleteCalibrationIndex(requestCtx context.Context, scratchIndex string) {
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(requestCtx), 30*time.Second)
+	defer cancel()
+
+	res, err := s.esClient.Indices.Delete(
+		[]string{scratchIndex},
+		s.esClient.Indices.Delete.WithContext(ctx),
+	)
+	if err != nil {
+		s.logger.Warn("Failed to delete calibration index",
+			zap.String("calibration_index", scratchIndex),
+			zap.Error(err))
+		return
+	}
+	defer res.Body.Close()
+
+	if res.IsError() {
+		s.logger.Warn("Failed to delete calibration index",
+			zap.String("calibration_index", scratchIndex),
+			zap.Error(shared.ParseESError(res)))
+	}
+}
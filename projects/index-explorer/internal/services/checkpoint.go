@@ -0,0 +1,463 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/elastic/go-elasticsearch/v8/esapi"
+	"go.uber.org/zap"
+
+	"github.com/saif-islam/es-playground/projects/index-explorer/internal/models"
+	"github.com/saif-islam/es-playground/shared"
+)
+
+const (
+	// checkpointIndex holds one document per idempotency key, so an import can be resumed
+	// after a restart or by another instance. Elasticsearch creates it on first use.
+	checkpointIndex = ".import-checkpoints"
+
+	// defaultCheckpointTTL is how long a checkpoint is kept after its last update. Completed
+	// checkpoints answer retries of a finished import; abandoned ones can still be resumed.
+	defaultCheckpointTTL = 24 * time.Hour
+
+	// checkpointLease is how long a running import holds its idempotency key without
+	// recording progress. The key of an instance that died mid-import frees up after it.
+	checkpointLease = 10 * time.Minute
+
+	// checkpointSweepInterval is how often checkpoints past their TTL are deleted
+	checkpointSweepInterval = time.Hour
+)
+
+// ErrImportInProgress is returned when an import is started with the idempotency key of
+// one that is still running
+var ErrImportInProgress = errors.New("import already in progress")
+
+// checkpointClaim is a checkpoint held by an import running in this process. The sequence
+// number and primary term of its stored document guard every write, so an import whose
+// lease was taken over stops instead of overwriting the new holder's progress.
+type checkpointClaim struct {
+	checkpoint  models.ImportCheckpoint
+	seqNo       int
+	primaryTerm int
+}
+
+// SetCheckpointTTL sets how long import checkpoints are kept after their last update
+func (s *DocumentService) SetCheckpointTTL(ttl time.Duration) {
+	if ttl <= 0 {
+		return
+	}
+	s.checkpointMu.Lock()
+	defer s.checkpointMu.Unlock()
+	s.checkpointTTL = ttl
+}
+
+// resumableImport runs an NDJSON import in checkpointed chunks. Lines covered by
+// an existing checkpoint for the same idempotency key are skipped, so a client can
+// simply re-send the whole file after an interruption.
+func (s *DocumentService) resumableImport(ctx context.Context, indexName string, ndjsonData io.Reader, options *BulkImportOptions) (*models.BulkResponse, error) {
+	startTime := time.Now()
+
+	checkpoint, err := s.loadCheckpoint(ctx, options.IdempotencyKey, indexName)
+	if err != nil {
+		return nil, err
+	}
+	defer s.releaseCheckpoint(ctx, options.IdempotencyKey)
+
+	if checkpoint.Completed {
+		s.logger.Info("Import already completed for idempotency key, nothing to resume",
+			zap.String("index", indexName),
+			zap.String("idempotency_key", options.IdempotencyKey))
+		return s.emptyImportResponse(startTime), nil
+	}
+
+	if checkpoint.LinesProcessed > 0 {
+		s.logger.Info("Resuming NDJSON import from checkpoint",
+			zap.String("index", indexName),
+			zap.String("idempotency_key", options.IdempotencyKey),
+			zap.Int64("lines_processed", checkpoint.LinesProcessed),
+			zap.Int64("bytes_processed", checkpoint.BytesProcessed))
+	}
+
+	// Chunks are read as they are indexed, so only one chunk is held in memory at a time
+	records := s.newNDJSONReader(ndjsonData, indexName, checkpoint.LinesProcessed, options.GenerateIDs)
+
+	var pending []ndjsonRecord
+	if options.Calibrate {
+		pending, err = records.readRecords(calibrationSampleSize)
+		if err != nil {
+			return nil, err
+		}
+		if len(pending) > 0 {
+			s.calibrateImport(ctx, indexName, recordOperations(pending), options)
+		}
+	}
+
+	interval := options.CheckpointInterval
+	if interval <= 0 {
+		interval = options.BatchSize * options.ParallelWorkers
+	}
+	if interval <= 0 {
+		interval = 1000
+	}
+
+	combined := &models.BulkResponse{}
+	for {
+		chunk := pending
+		pending = nil
+		if len(chunk) < interval {
+			rest, err := records.readRecords(interval - len(chunk))
+			if err != nil {
+				return nil, fmt.Errorf("import interrupted after line %d, retry with the same idempotency key to resume: %w",
+					checkpoint.LinesProcessed, err)
+			}
+			chunk = append(chunk, rest...)
+		}
+		if len(chunk) == 0 {
+			break
+		}
+		operations := recordOperations(chunk)
+
+		chunkResp, err := s.BulkIndex(ctx, &models.BulkRequest{
+			IndexName:       indexName,
+			Operations:      operations,
+			BatchSize:       options.BatchSize,
+			ParallelWorkers: options.ParallelWorkers,
+			OptimizeFor:     "write_throughput",
+			ErrorTolerance:  options.ErrorTolerance,
+			DeadLetterIndex: options.DeadLetterIndex,
+			Priority:        options.Priority,
+		})
+		var toleranceErr *BulkToleranceError
+		if errors.As(err, &toleranceErr) {
+			// Resuming would replay the same failures; the chunk stays unacknowledged
+			return nil, fmt.Errorf("import stopped after line %d: %w", checkpoint.LinesProcessed, err)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("import interrupted after line %d, retry with the same idempotency key to resume: %w",
+				checkpoint.LinesProcessed, err)
+		}
+
+		// Batches that failed outright are missing from the items; don't advance past them
+		if len(chunkResp.Items) < len(operations) {
+			return nil, fmt.Errorf("import interrupted after line %d (%d of %d operations acknowledged), retry with the same idempotency key to resume",
+				checkpoint.LinesProcessed, len(chunkResp.Items), len(operations))
+		}
+
+		combined.Items = append(combined.Items, chunkResp.Items...)
+		combined.ElasticsearchTook += chunkResp.ElasticsearchTook
+		combined.Batches += chunkResp.Batches
+		combined.DeadLettered += chunkResp.DeadLettered
+		combined.RetriedOperations += chunkResp.RetriedOperations
+		combined.Errors = combined.Errors || chunkResp.Errors
+
+		last := chunk[len(chunk)-1]
+		if err := s.advanceCheckpoint(ctx, options.IdempotencyKey, last.line, last.byteOffset, chunkResp.Summary); err != nil {
+			return nil, fmt.Errorf("import stopped after line %d: %w", last.line, err)
+		}
+		checkpoint.LinesProcessed = last.line
+	}
+
+	if err := s.completeCheckpoint(ctx, options.IdempotencyKey); err != nil {
+		return nil, err
+	}
+
+	processingTime := time.Since(startTime)
+	combined.Took = processingTime.Milliseconds()
+	combined.Summary = s.calculateBulkSummary(combined, processingTime)
+	combined.Summary.IDStrategy = records.idStrategy()
+	combined.Summary.GeneratedIDs = records.generated
+	combined.RequestID = s.generateRequestID()
+	combined.Timestamp = time.Now()
+
+	return combined, nil
+}
+
+// loadCheckpoint returns the checkpoint for an idempotency key, creating it if needed, and
+// marks the key as in progress. Until releaseCheckpoint is called, another import with the
+// same key fails with ErrImportInProgress, here or on any instance sharing the cluster.
+func (s *DocumentService) loadCheckpoint(ctx context.Context, key, indexName string) (*models.ImportCheckpoint, error) {
+	runningKey := runningImportKey(ctx, key)
+
+	s.checkpointMu.Lock()
+	if _, running := s.runningImports[runningKey]; running {
+		s.checkpointMu.Unlock()
+		return nil, fmt.Errorf("%w: idempotency key %s is used by a running import", ErrImportInProgress, key)
+	}
+	// Reserve the key while the stored checkpoint is read
+	s.runningImports[runningKey] = nil
+	ttl := s.checkpointTTL
+	s.checkpointMu.Unlock()
+
+	claim, err := s.claimCheckpoint(ctx, key, indexName, ttl)
+	if err != nil || claim == nil {
+		s.checkpointMu.Lock()
+		delete(s.runningImports, runningKey)
+		s.checkpointMu.Unlock()
+	}
+	if err != nil {
+		return nil, err
+	}
+	if claim == nil {
+		// Completed imports have nothing to resume, so there is nothing to hold
+		return &models.ImportCheckpoint{IdempotencyKey: key, IndexName: indexName, Completed: true}, nil
+	}
+
+	s.checkpointMu.Lock()
+	s.runningImports[runningKey] = claim
+	s.checkpointMu.Unlock()
+
+	s.sweepCheckpoints(ctx, ttl)
+
+	snapshot := claim.checkpoint
+	return &snapshot, nil
+}
+
+// claimCheckpoint marks the stored checkpoint for key as in progress, or stores a new one.
+// It returns nil without claiming anything when the import already completed.
+func (s *DocumentService) claimCheckpoint(ctx context.Context, key, indexName string, ttl time.Duration) (*checkpointClaim, error) {
+	stored, err := s.getStoredCheckpoint(ctx, key)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	claim := &checkpointClaim{checkpoint: models.ImportCheckpoint{
+		IdempotencyKey: key,
+		IndexName:      indexName,
+		CreatedAt:      now,
+	}}
+
+	switch {
+	case stored == nil:
+	case now.Sub(stored.checkpoint.UpdatedAt) > ttl:
+		// An expired key starts over, even for another index
+		claim.seqNo, claim.primaryTerm = stored.seqNo, stored.primaryTerm
+	case stored.checkpoint.IndexName != indexName:
+		return nil, fmt.Errorf("idempotency key %s is already used by an import into index %s", key, stored.checkpoint.IndexName)
+	case stored.checkpoint.Completed:
+		return nil, nil
+	case stored.checkpoint.InProgress && now.Sub(stored.checkpoint.UpdatedAt) < checkpointLease:
+		return nil, fmt.Errorf("%w: idempotency key %s is used by a running import", ErrImportInProgress, key)
+	default:
+		claim = stored
+	}
+
+	claim.checkpoint.InProgress = true
+	claim.checkpoint.UpdatedAt = now
+	if err := s.storeCheckpoint(ctx, claim); err != nil {
+		return nil, err
+	}
+	return claim, nil
+}
+
+// advanceCheckpoint records progress after a chunk has been acknowledged by Elasticsearch
+func (s *DocumentService) advanceCheckpoint(ctx context.Context, key string, line, byteOffset int64, summary *models.BulkSummary) error {
+	return s.updateCheckpoint(ctx, key, func(checkpoint *models.ImportCheckpoint) {
+		checkpoint.LinesProcessed = line
+		checkpoint.BytesProcessed = byteOffset
+		if summary != nil {
+			checkpoint.DocumentsIndexed += summary.SuccessfulOperations
+			checkpoint.DocumentsFailed += summary.FailedOperations
+		}
+	})
+}
+
+// completeCheckpoint marks a tracked import as finished
+func (s *DocumentService) completeCheckpoint(ctx context.Context, key string) error {
+	return s.updateCheckpoint(ctx, key, func(checkpoint *models.ImportCheckpoint) {
+		checkpoint.Completed = true
+		checkpoint.InProgress = false
+	})
+}
+
+// releaseCheckpoint frees the idempotency key of an import that stopped, keeping its
+// progress so a retry resumes from it. It runs even when the request was cancelled.
+func (s *DocumentService) releaseCheckpoint(requestCtx context.Context, key string) {
+	runningKey := runningImportKey(requestCtx, key)
+
+	s.checkpointMu.Lock()
+	claim := s.runningImports[runningKey]
+	delete(s.runningImports, runningKey)
+	s.checkpointMu.Unlock()
+
+	if claim == nil || !claim.checkpoint.InProgress {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(requestCtx), 30*time.Second)
+	defer cancel()
+
+	claim.checkpoint.InProgress = false
+	claim.checkpoint.UpdatedAt = time.Now()
+	if err := s.storeCheckpoint(ctx, claim); err != nil {
+		s.logger.Warn("Failed to release import checkpoint, the key frees up once its lease expires",
+			zap.String("idempotency_key", key),
+			zap.Duration("lease", checkpointLease),
+			zap.Error(err))
+	}
+}
+
+// updateCheckpoint applies update to the checkpoint this process holds for key and stores it
+func (s *DocumentService) updateCheckpoint(ctx context.Context, key string, update func(*models.ImportCheckpoint)) error {
+	s.checkpointMu.Lock()
+	claim := s.runningImports[runningImportKey(ctx, key)]
+	s.checkpointMu.Unlock()
+	if claim == nil {
+		return fmt.Errorf("no running import holds idempotency key %s", key)
+	}
+
+	update(&claim.checkpoint)
+	claim.checkpoint.UpdatedAt = time.Now()
+	return s.storeCheckpoint(ctx, claim)
+}
+
+// GetImportCheckpoint returns the checkpoint recorded for an idempotency key, or nil when
+// there is none or it expired
+func (s *DocumentService) GetImportCheckpoint(ctx context.Context, key string) (*models.ImportCheckpoint, error) {
+	stored, err := s.getStoredCheckpoint(ctx, key)
+	if err != nil || stored == nil {
+		return nil, err
+	}
+
+	s.checkpointMu.RLock()
+	ttl := s.checkpointTTL
+	s.checkpointMu.RUnlock()
+	if time.Since(stored.checkpoint.UpdatedAt) > ttl {
+		return nil, nil
+	}
+	return &stored.checkpoint, nil
+}
+
+// runningImportKey identifies an idempotency key on the cluster selected for ctx. Each
+// cluster stores its own checkpoints, so the same key can be used against two clusters.
+func runningImportKey(ctx context.Context, key string) string {
+	cluster, ok := shared.ClusterFromContext(ctx)
+	if !ok {
+		cluster = shared.DefaultClusterName
+	}
+	return cluster + "/" + key
+}
+
+// getStoredCheckpoint reads the checkpoint document for key, or nil when there is none
+func (s *DocumentService) getStoredCheckpoint(ctx context.Context, key string) (*checkpointClaim, error) {
+	res, err := s.esClient.Get(
+		checkpointIndex,
+		url.PathEscape(key),
+		s.esClient.Get.WithContext(ctx),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read import checkpoint: %w", err)
+	}
+	defer res.Body.Close()
+
+	// Missing before the first checkpoint is stored, as is the index itself
+	if res.StatusCode == http.StatusNotFound {
+		return nil, nil
+	}
+	if res.IsError() {
+		return nil, shared.ParseESError(res)
+	}
+
+	var doc struct {
+		SeqNo       int                     `json:"_seq_no"`
+		PrimaryTerm int                     `json:"_primary_term"`
+		Source      models.ImportCheckpoint `json:"_source"`
+	}
+	if err := shared.DecodeJSONResponse(res, &doc); err != nil {
+		return nil, fmt.Errorf("failed to decode import checkpoint: %w", err)
+	}
+
+	return &checkpointClaim{checkpoint: doc.Source, seqNo: doc.SeqNo, primaryTerm: doc.PrimaryTerm}, nil
+}
+
+// storeCheckpoint writes a claimed checkpoint. A new one is only created if no other import
+// stored it first, an existing one only if nobody changed it since it was read; either
+// conflict means another import holds the key.
+func (s *DocumentService) storeCheckpoint(ctx context.Context, claim *checkpointClaim) error {
+	body, err := json.Marshal(claim.checkpoint)
+	if err != nil {
+		return fmt.Errorf("failed to marshal import checkpoint: %w", err)
+	}
+
+	opts := []func(*esapi.IndexRequest){
+		s.esClient.Index.WithDocumentID(url.PathEscape(claim.checkpoint.IdempotencyKey)),
+		s.esClient.Index.WithContext(ctx),
+	}
+	if claim.primaryTerm == 0 {
+		opts = append(opts, s.esClient.Index.WithOpType("create"))
+	} else {
+		opts = append(opts,
+			s.esClient.Index.WithIfSeqNo(claim.seqNo),
+			s.esClient.Index.WithIfPrimaryTerm(claim.primaryTerm))
+	}
+
+	res, err := s.esClient.Index(checkpointIndex, strings.NewReader(string(body)), opts...)
+	if err != nil {
+		return fmt.Errorf("failed to store import checkpoint: %w", err)
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode == http.StatusConflict {
+		return fmt.Errorf("%w: idempotency key %s was taken over by another import", ErrImportInProgress, claim.checkpoint.IdempotencyKey)
+	}
+	if res.IsError() {
+		return shared.ParseESError(res)
+	}
+
+	var written struct {
+		SeqNo       int `json:"_seq_no"`
+		PrimaryTerm int `json:"_primary_term"`
+	}
+	if err := shared.DecodeJSONResponse(res, &written); err != nil {
+		return fmt.Errorf("failed to decode checkpoint write: %w", err)
+	}
+	claim.seqNo, claim.primaryTerm = written.SeqNo, written.PrimaryTerm
+	return nil
+}
+
+// emptyImportResponse builds the response for a replayed import that has nothing left to do
+func (s *DocumentService) emptyImportResponse(startTime time.Time) *models.BulkResponse {
+	processingTime := time.Since(startTime)
+	response := &models.BulkResponse{Took: processingTime.Milliseconds()}
+	response.Summary = s.calculateBulkSummary(response, processingTime)
+	response.RequestID = s.generateRequestID()
+	response.Timestamp = time.Now()
+	return response
+}
+
+// sweepCheckpoints deletes checkpoints not updated within the TTL, at most once per
+// sweep interval. Failures are only logged; expired checkpoints are ignored when read.
+func (s *DocumentService) sweepCheckpoints(ctx context.Context, ttl time.Duration) {
+	s.checkpointMu.Lock()
+	now := time.Now()
+	if now.Sub(s.lastCheckpointSweep) < checkpointSweepInterval {
+		s.checkpointMu.Unlock()
+		return
+	}
+	s.lastCheckpointSweep = now
+	s.checkpointMu.Unlock()
+
+	query := fmt.Sprintf(`{"query":{"range":{"updated_at":{"lt":%q}}}}`, now.Add(-ttl).Format(time.RFC3339Nano))
+	res, err := s.esClient.DeleteByQuery(
+		[]string{checkpointIndex},
+		strings.NewReader(query),
+		s.esClient.DeleteByQuery.WithContext(ctx),
+		s.esClient.DeleteByQuery.WithConflicts("proceed"),
+	)
+	if err != nil {
+		s.logger.Warn("Failed to delete expired import checkpoints", zap.Error(err))
+		return
+	}
+	defer res.Body.Close()
+
+	if res.IsError() {
+		s.logger.Warn("Failed to delete expired import checkpoints", zap.Error(shared.ParseESError(res)))
+	}
+}
@@ -0,0 +1,218 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/saif-islam/es-playground/projects/index-explorer/internal/models"
+	"github.com/saif-islam/es-playground/shared"
+)
+
+// baselineRule is a recommended value for a single index setting
+type baselineRule struct {
+	setting  string
+	expected string
+	minimum  bool // numeric settings where any value at or above expected is compliant
+	dynamic  bool // can be updated on an open index
+	severity string
+	reason   string
+}
+
+// baselineProfile is a named set of known-good settings for a workload
+type baselineProfile struct {
+	description string
+	rules       []baselineRule
+}
+
+// baselineProfiles holds the built-in compliance profiles
+var baselineProfiles = map[string]baselineProfile{
+	"logging": {
+		description: "Append-only time-series logs: cheap writes, compact storage, one replica",
+		rules: []baselineRule{
+			{setting: "index.refresh_interval", expected: "30s", dynamic: true, severity: "high",
+				reason: "Logs rarely need sub-second visibility; frequent refreshes create many small segments"},
+			{setting: "index.translog.durability", expected: "async", dynamic: true, severity: "medium",
+				reason: "Async translog fsync trades a few seconds of durability for much higher ingest"},
+			{setting: "index.codec", expected: "best_compression", severity: "medium",
+				reason: "Log data compresses well and is read infrequently"},
+			{setting: "index.number_of_replicas", expected: "1", minimum: true, dynamic: true, severity: "high",
+				reason: "At least one replica protects against node loss"},
+		},
+	},
+	"search": {
+		description: "User-facing search: fresh results, durable writes, few segments",
+		rules: []baselineRule{
+			{setting: "index.refresh_interval", expected: "1s", dynamic: true, severity: "medium",
+				reason: "Search users expect new documents to be visible quickly"},
+			{setting: "index.translog.durability", expected: "request", dynamic: true, severity: "high",
+				reason: "Acknowledged writes must survive a node crash"},
+			{setting: "index.merge.policy.segments_per_tier", expected: "10", dynamic: true, severity: "low",
+				reason: "Fewer segments per tier keeps query latency low"},
+			{setting: "index.number_of_replicas", expected: "1", minimum: true, dynamic: true, severity: "high",
+				reason: "Replicas add redundancy and spread search load"},
+		},
+	},
+	"write-heavy": {
+		description: "Sustained bulk ingestion: infrequent refreshes, large translog, relaxed merging",
+		rules: []baselineRule{
+			{setting: "index.refresh_interval", expected: "30s", dynamic: true, severity: "high",
+				reason: "Infrequent refreshes leave more indexing capacity for writes"},
+			{setting: "index.translog.durability", expected: "async", dynamic: true, severity: "medium",
+				reason: "Async translog fsync removes a disk sync from every bulk request"},
+			{setting: "index.translog.flush_threshold_size", expected: "1gb", dynamic: true, severity: "medium",
+				reason: "A larger translog means fewer, larger flushes"},
+			{setting: "index.merge.policy.segments_per_tier", expected: "30", dynamic: true, severity: "low",
+				reason: "Allowing more segments per tier reduces merge pressure during ingestion"},
+		},
+	},
+}
+
+// ErrUnknownComplianceProfile is returned when a compliance check names no built-in profile
+var ErrUnknownComplianceProfile = errors.New("unknown compliance profile")
+
+// ComplianceProfiles returns the names of the built-in baseline profiles
+func ComplianceProfiles() []string {
+	names := make([]string, 0, len(baselineProfiles))
+	for name := range baselineProfiles {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// CheckCompliance reports how an index's effective settings deviate from a baseline profile
+func (s *IndexService) CheckCompliance(ctx context.Context, indexName, profileName string) (*models.ComplianceReport, error) {
+	profile, ok := baselineProfiles[profileName]
+	if !ok {
+		return nil, fmt.Errorf("%w %q (available: %s)", ErrUnknownComplianceProfile, profileName, strings.Join(ComplianceProfiles(), ", "))
+	}
+
+	current, err := s.getEffectiveIndexSettings(ctx, indexName)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get index settings: %w", err)
+	}
+
+	report := evaluateCompliance(indexName, profile, current)
+	report.Profile = profileName
+	report.RequestID = s.generateRequestID()
+	report.Timestamp = time.Now()
+
+	s.logger.Info("Checked index compliance",
+		zap.String("index_name", indexName),
+		zap.String("profile", profileName),
+		zap.Float64("score", report.Score))
+
+	return report, nil
+}
+
+// evaluateCompliance checks flat settings against every rule of a profile
+func evaluateCompliance(indexName string, profile baselineProfile, current map[string]interface{}) *models.ComplianceReport {
+	report := &models.ComplianceReport{
+		IndexName:   indexName,
+		Description: profile.description,
+		Checks:      make([]models.ComplianceCheck, 0, len(profile.rules)),
+		Fixes:       make(map[string]interface{}),
+	}
+
+	passed := 0
+	for _, rule := range profile.rules {
+		actual, exists := current[rule.setting]
+		check := models.ComplianceCheck{
+			Setting:   rule.setting,
+			Expected:  rule.expected,
+			Actual:    actual,
+			Compliant: exists && ruleSatisfied(rule, fmt.Sprint(actual)),
+			Severity:  rule.severity,
+			Reason:    rule.reason,
+		}
+
+		if check.Compliant {
+			passed++
+		} else if rule.dynamic {
+			check.Fix = fmt.Sprintf("PUT /%s/_settings {\"%s\": \"%s\"}", indexName, rule.setting, rule.expected)
+			report.Fixes[rule.setting] = rule.expected
+		} else {
+			check.Fix = fmt.Sprintf("%s is static; set it to %s on a new index and reindex", rule.setting, rule.expected)
+		}
+
+		report.Checks = append(report.Checks, check)
+	}
+
+	if len(profile.rules) > 0 {
+		report.Score = float64(passed) / float64(len(profile.rules)) * 100.0
+	}
+	report.Compliant = passed == len(profile.rules)
+
+	return report
+}
+
+// ruleSatisfied compares an actual setting value with a rule's expectation
+func ruleSatisfied(rule baselineRule, actual string) bool {
+	if rule.minimum {
+		actualValue, err := strconv.Atoi(actual)
+		if err != nil {
+			return false
+		}
+		expectedValue, _ := strconv.Atoi(rule.expected)
+		return actualValue >= expectedValue
+	}
+	// Defaults come back in upper case for some enums (e.g. REQUEST)
+	return strings.EqualFold(actual, rule.expected)
+}
+
+// getEffectiveIndexSettings returns the index's flat settings with defaults filled in
+func (s *IndexService) getEffectiveIndexSettings(ctx context.Context, indexName string) (map[string]interface{}, error) {
+	res, err := s.esClient.Indices.GetSettings(
+		s.esClient.Indices.GetSettings.WithContext(ctx),
+		s.esClient.Indices.GetSettings.WithIndex(indexName),
+		s.esClient.Indices.GetSettings.WithIncludeDefaults(true),
+		s.esClient.Indices.GetSettings.WithFlatSettings(true),
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer res.Body.Close()
+
+	if res.IsError() {
+		return nil, shared.ParseESError(res)
+	}
+
+	var response map[string]struct {
+		Settings map[string]interface{} `json:"settings"`
+		Defaults map[string]interface{} `json:"defaults"`
+	}
+	if err := shared.DecodeJSONResponse(res, &response); err != nil {
+		return nil, err
+	}
+
+	index, ok := response[indexName]
+	if !ok {
+		// An alias or pattern may resolve to several indices; audit the first by name
+		names := make([]string, 0, len(response))
+		for name := range response {
+			names = append(names, name)
+		}
+		if len(names) == 0 {
+			return nil, fmt.Errorf("no settings returned for index %s", indexName)
+		}
+		sort.Strings(names)
+		index = response[names[0]]
+	}
+
+	effective := make(map[string]interface{}, len(index.Defaults)+len(index.Settings))
+	for key, value := range index.Defaults {
+		effective[key] = value
+	}
+	for key, value := range index.Settings {
+		effective[key] = value
+	}
+
+	return effective, nil
+}
@@ -0,0 +1,214 @@
+package services
+
+import (
+	"context"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"strconv"
+	"strings"
+
+	"go.uber.org/zap"
+
+	"github.com/saif-islam/es-playground/projects/index-explorer/internal/models"
+)
+
+// CSVImportOptions defines options for importing CSV. The embedded bulk options size and
+// pace the import as for NDJSON; CSV imports are not checkpointed, so IdempotencyKey is
+// ignored.
+type CSVImportOptions struct {
+	BulkImportOptions
+
+	// Delimiter separates fields (default ',')
+	Delimiter rune
+	// Header takes field names from the first row; without it fields are named column_1, column_2, ...
+	Header bool
+	// InferTypes stores numeric and boolean cells as numbers and booleans instead of strings,
+	// and leaves empty cells out of the document
+	InferTypes bool
+}
+
+// BulkImportFromCSV imports CSV records as documents, one per row. A column named _id
+// supplies the document ID; otherwise IDs follow GenerateIDs as for NDJSON. Malformed
+// rows are logged and skipped.
+func (s *DocumentService) BulkImportFromCSV(ctx context.Context, indexName string, r io.Reader, opts CSVImportOptions) (*models.BulkResponse, error) {
+	records, err := s.newCSVReader(r, indexName, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	s.logger.Info("Starting CSV bulk import",
+		zap.String("index", indexName),
+		zap.Strings("fields", records.fields),
+		zap.Int("batch_size", opts.BatchSize),
+		zap.Int("workers", opts.ParallelWorkers))
+
+	return s.streamImport(ctx, indexName, records, &opts.BulkImportOptions)
+}
+
+// csvReader parses CSV rows into index operations one row at a time
+type csvReader struct {
+	reader      *csv.Reader
+	indexName   string
+	fields      []string
+	inferTypes  bool
+	generateIDs bool
+	logger      *zap.Logger
+
+	// A headerless input's first row is read to count its columns and is still a document
+	pending   []string
+	generated int64
+}
+
+// newCSVReader reads the header, or the first row of a headerless input, to name the fields
+func (s *DocumentService) newCSVReader(r io.Reader, indexName string, opts CSVImportOptions) (*csvReader, error) {
+	reader := csv.NewReader(r)
+	if opts.Delimiter != 0 {
+		reader.Comma = opts.Delimiter
+	}
+
+	first, err := reader.Read()
+	if err == io.EOF {
+		return nil, fmt.Errorf("CSV input is empty")
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read CSV header: %w", err)
+	}
+	// Every row must have as many fields as the first
+	reader.FieldsPerRecord = len(first)
+
+	records := &csvReader{
+		reader:      reader,
+		indexName:   indexName,
+		fields:      make([]string, len(first)),
+		inferTypes:  opts.InferTypes,
+		generateIDs: opts.GenerateIDs,
+		logger:      s.logger,
+	}
+
+	for i, name := range first {
+		if i == 0 {
+			name = strings.TrimPrefix(name, "\ufeff") // Byte order mark written by spreadsheets
+		}
+		name = strings.TrimSpace(name)
+		if !opts.Header || name == "" {
+			name = fmt.Sprintf("column_%d", i+1)
+		}
+		records.fields[i] = name
+	}
+	if !opts.Header {
+		records.pending = first
+	}
+
+	return records, nil
+}
+
+// next returns the next document, skipping malformed rows. It reports false once the
+// input is exhausted.
+func (r *csvReader) next() (models.BulkOperation, bool, error) {
+	for {
+		row := r.pending
+		r.pending = nil
+
+		if row == nil {
+			var err error
+			row, err = r.reader.Read()
+			if err == io.EOF {
+				return models.BulkOperation{}, false, nil
+			}
+			var parseErr *csv.ParseError
+			if errors.As(err, &parseErr) {
+				r.logger.Warn("Failed to parse CSV row",
+					zap.Int("line", parseErr.Line),
+					zap.Error(err))
+				continue
+			}
+			if err != nil {
+				return models.BulkOperation{}, false, fmt.Errorf("failed to read CSV: %w", err)
+			}
+		}
+
+		document := make(map[string]interface{}, len(row))
+		var docID string
+		for i, cell := range row {
+			if r.fields[i] == "_id" {
+				docID = cell
+				continue
+			}
+			if !r.inferTypes {
+				document[r.fields[i]] = cell
+			} else if cell != "" {
+				document[r.fields[i]] = inferCSVValue(cell)
+			}
+		}
+
+		if docID == "" && r.generateIDs {
+			docID = contentHashID(document)
+			r.generated++
+		}
+
+		return models.BulkOperation{
+			Action:   "index",
+			Index:    r.indexName,
+			ID:       docID,
+			Document: document,
+		}, true, nil
+	}
+}
+
+// readOperations reads up to max rows as bulk operations
+func (r *csvReader) readOperations(max int) ([]models.BulkOperation, error) {
+	operations := make([]models.BulkOperation, 0, max)
+	for len(operations) < max {
+		operation, ok, err := r.next()
+		if err != nil {
+			return nil, err
+		}
+		if !ok {
+			break
+		}
+		operations = append(operations, operation)
+	}
+	return operations, nil
+}
+
+// idStrategy names how the reader identifies documents without an _id
+func (r *csvReader) idStrategy() string {
+	if r.generateIDs {
+		return IDStrategyContentHash
+	}
+	return IDStrategyElasticsearch
+}
+
+// generatedIDs returns the number of documents given a content hash ID so far
+func (r *csvReader) generatedIDs() int64 {
+	return r.generated
+}
+
+// inferCSVValue converts a cell to a boolean, integer or float when it is one. Numbers with
+// leading zeros, such as postal codes, stay strings.
+func inferCSVValue(cell string) interface{} {
+	trimmed := strings.TrimSpace(cell)
+
+	switch strings.ToLower(trimmed) {
+	case "true":
+		return true
+	case "false":
+		return false
+	}
+
+	digits := strings.TrimLeft(trimmed, "+-")
+	if len(digits) > 1 && digits[0] == '0' && digits[1] != '.' {
+		return cell
+	}
+
+	if i, err := strconv.ParseInt(trimmed, 10, 64); err == nil {
+		return i
+	}
+	if f, err := strconv.ParseFloat(trimmed, 64); err == nil && !math.IsInf(f, 0) && !math.IsNaN(f) {
+		return f
+	}
+	return cell
+}
@@ -0,0 +1,141 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/saif-islam/es-playground/projects/index-explorer/internal/models"
+)
+
+// writeDeadLetters stores operations that failed permanently in the request's dead-letter
+// index, together with the error reason, and returns how many were stored
+func (s *DocumentService) writeDeadLetters(ctx context.Context, req *models.BulkRequest, operations []models.BulkOperation,
+	items []models.BulkResponseItem, attempts []int) int64 {
+
+	var buf bytes.Buffer
+	var count int64
+	failedAt := time.Now()
+
+	for i, item := range items {
+		result := bulkItemResult(item)
+		if result == nil || result.Error == nil || i >= len(operations) {
+			continue
+		}
+
+		letter := s.buildDeadLetter(operations[i], req.IndexName, result, attempts[i], failedAt)
+		letterBytes, err := json.Marshal(letter)
+		if err != nil {
+			s.logger.Error("Failed to encode dead letter document",
+				zap.String("index", letter.OriginalIndex),
+				zap.String("id", letter.OriginalID),
+				zap.Error(err))
+			continue
+		}
+
+		buf.WriteString(`{"index":{}}`)
+		buf.WriteByte('\n')
+		buf.Write(letterBytes)
+		buf.WriteByte('\n')
+		count++
+	}
+
+	if count == 0 {
+		return 0
+	}
+
+	stored, err := s.sendDeadLetters(ctx, req.DeadLetterIndex, &buf)
+	if err != nil {
+		s.logger.Error("Failed to write dead letter documents",
+			zap.String("index", req.IndexName),
+			zap.String("dead_letter_index", req.DeadLetterIndex),
+			zap.Int64("documents", count),
+			zap.Error(err))
+		return 0
+	}
+
+	if stored < count {
+		s.logger.Error("Some dead letter documents were rejected",
+			zap.String("dead_letter_index", req.DeadLetterIndex),
+			zap.Int64("documents", count),
+			zap.Int64("stored", stored))
+	}
+
+	s.logger.Warn("Routed permanently failed documents to dead letter index",
+		zap.String("index", req.IndexName),
+		zap.String("dead_letter_index", req.DeadLetterIndex),
+		zap.Int64("documents", stored))
+
+	return stored
+}
+
+// buildDeadLetter captures the original operation and its failure
+func (s *DocumentService) buildDeadLetter(op models.BulkOperation, defaultIndex string, result *models.BulkItemResponse,
+	attempts int, failedAt time.Time) models.DeadLetterDocument {
+
+	letter := models.DeadLetterDocument{
+		OriginalIndex: op.Index,
+		OriginalID:    op.ID,
+		Action:        op.Action,
+		Routing:       op.Routing,
+		ErrorType:     result.Error.Type,
+		ErrorReason:   result.Error.Reason,
+		Status:        result.Status,
+		Attempts:      attempts,
+		FailedAt:      failedAt,
+	}
+
+	if letter.OriginalIndex == "" {
+		letter.OriginalIndex = defaultIndex
+	}
+	if letter.OriginalID == "" {
+		letter.OriginalID = result.ID
+	}
+
+	// Updates keep their whole body (doc or script, and upsert) so they can be replayed
+	if doc := operationPayload(op); doc != nil {
+		if docBytes, err := json.Marshal(doc); err == nil {
+			letter.Document = string(docBytes)
+		}
+	}
+
+	return letter
+}
+
+// sendDeadLetters bulk-writes encoded dead letters and returns how many were stored
+func (s *DocumentService) sendDeadLetters(ctx context.Context, deadLetterIndex string, body io.Reader) (int64, error) {
+	res, err := s.esClient.Bulk(
+		body,
+		s.esClient.Bulk.WithContext(ctx),
+		s.esClient.Bulk.WithIndex(deadLetterIndex),
+	)
+	if err != nil {
+		return 0, fmt.Errorf("dead letter bulk request failed: %w", err)
+	}
+	defer res.Body.Close()
+
+	if res.IsError() {
+		return 0, fmt.Errorf("dead letter bulk request error: %s", res.String())
+	}
+
+	var bulkResp struct {
+		Items []models.BulkResponseItem `json:"items"`
+	}
+	if err := json.NewDecoder(res.Body).Decode(&bulkResp); err != nil {
+		return 0, fmt.Errorf("failed to decode dead letter bulk response: %w", err)
+	}
+
+	var stored int64
+	for _, item := range bulkResp.Items {
+		if result := bulkItemResult(item); result != nil && result.Error == nil {
+			stored++
+		}
+	}
+
+	return stored, nil
+}
@@ -0,0 +1,109 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/saif-islam/es-playground/projects/index-explorer/internal/models"
+	"github.com/saif-islam/es-playground/shared"
+)
+
+// DeleteByQuery deletes every document of the index matching the query, e.g. those older
+// than a retention window. Documents changed while it runs are counted as version conflicts
+// and left in place rather than aborting the delete. It blocks until the delete is done;
+// the delete carries on in Elasticsearch if ctx expires.
+func (s *DocumentService) DeleteByQuery(ctx context.Context, indexName string, query map[string]interface{}) (*models.DeleteByQueryResult, error) {
+	return s.deleteByQuery(ctx, indexName, query, true)
+}
+
+// StartDeleteByQuery starts a delete by query without waiting for it, returning its task ID
+func (s *DocumentService) StartDeleteByQuery(ctx context.Context, indexName string, query map[string]interface{}) (*models.DeleteByQueryResult, error) {
+	return s.deleteByQuery(ctx, indexName, query, false)
+}
+
+func (s *DocumentService) deleteByQuery(ctx context.Context, indexName string, query map[string]interface{}, wait bool) (*models.DeleteByQueryResult, error) {
+	if len(query) == 0 {
+		return nil, fmt.Errorf("query is required; use match_all to delete every document")
+	}
+
+	s.logger.Info("Deleting documents by query",
+		zap.String("index", indexName),
+		zap.Any("query", query),
+		zap.Bool("wait_for_completion", wait))
+
+	bodyBytes, err := json.Marshal(map[string]interface{}{"query": query})
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal query: %w", err)
+	}
+
+	// A blocking delete outlasts the per-request timeout; ctx alone bounds it
+	if wait {
+		ctx = shared.WithRequestTimeout(ctx, 0)
+	}
+
+	res, err := s.esClient.DeleteByQuery(
+		[]string{indexName},
+		strings.NewReader(string(bodyBytes)),
+		s.esClient.DeleteByQuery.WithContext(ctx),
+		s.esClient.DeleteByQuery.WithConflicts("proceed"),
+		s.esClient.DeleteByQuery.WithWaitForCompletion(wait),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("delete by query request failed: %w", err)
+	}
+	defer res.Body.Close()
+
+	if res.IsError() {
+		return nil, shared.ParseESError(res)
+	}
+
+	var response struct {
+		Task             string `json:"task"`
+		Took             int64  `json:"took"`
+		TimedOut         bool   `json:"timed_out"`
+		Total            int64  `json:"total"`
+		Deleted          int64  `json:"deleted"`
+		Batches          int64  `json:"batches"`
+		VersionConflicts int64  `json:"version_conflicts"`
+		Failures         []struct {
+			ID    string `json:"id"`
+			Cause struct {
+				Type   string `json:"type"`
+				Reason string `json:"reason"`
+			} `json:"cause"`
+		} `json:"failures"`
+	}
+	if err := shared.DecodeJSONResponse(res, &response); err != nil {
+		return nil, fmt.Errorf("failed to decode delete by query response: %w", err)
+	}
+
+	result := &models.DeleteByQueryResult{
+		IndexName:        indexName,
+		Completed:        wait,
+		TaskID:           response.Task,
+		Total:            response.Total,
+		Deleted:          response.Deleted,
+		VersionConflicts: response.VersionConflicts,
+		Batches:          response.Batches,
+		TimedOut:         response.TimedOut,
+		Took:             time.Duration(response.Took) * time.Millisecond,
+	}
+	for _, failure := range response.Failures {
+		result.Failures = append(result.Failures,
+			fmt.Sprintf("%s: [%s] %s", failure.ID, failure.Cause.Type, failure.Cause.Reason))
+	}
+
+	s.logger.Info("Delete by query finished",
+		zap.String("index", indexName),
+		zap.Bool("completed", result.Completed),
+		zap.String("task_id", result.TaskID),
+		zap.Int64("deleted", result.Deleted),
+		zap.Int64("version_conflicts", result.VersionConflicts))
+
+	return result, nil
+}
@@ -4,18 +4,19 @@ import (
 	"bytes"
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"math"
 	"net/http"
-	"strconv"
-	"strings"
 	"sync"
 	"time"
 
+	"github.com/elastic/go-elasticsearch/v8/esapi"
 	"go.uber.org/zap"
 
 	"github.com/saif-islam/es-playground/shared"
+	"github.com/saif-islam/es-playground/projects/index-explorer/internal/metrics"
 	"github.com/saif-islam/es-playground/projects/index-explorer/internal/models"
 )
 
@@ -23,18 +24,62 @@ import (
 type DocumentService struct {
 	esClient *shared.ESClient
 	logger   *zap.Logger
+
+	// Resumable imports running in this process, keyed by cluster and idempotency key;
+	// their checkpoints are stored in the cluster they import into
+	runningImports      map[string]*checkpointClaim
+	checkpointTTL       time.Duration
+	lastCheckpointSweep time.Time
+	checkpointMu        sync.RWMutex
+
+	// Measured bulk parameters, keyed by index name
+	calibrations  map[string]*models.CalibrationResult
+	calibrationMu sync.RWMutex
+
+	// Shared worker pool running batches of every bulk request by priority
+	scheduler     *bulkScheduler
+	schedulerOnce sync.Once
+
+	// Indices with refresh disabled by running bulk requests
+	refreshSuspensions *refreshSuspensions
+
+	// Bulk requests running in the background, keyed by job ID
+	bulkJobs *bulkJobs
+
+	// Sampled write metrics, set once the sampler starts
+	history   *writeMetricsHistory
+	historyMu sync.RWMutex
 }
 
 // NewDocumentService creates a new document service instance
 func NewDocumentService(esClient *shared.ESClient, logger *zap.Logger) *DocumentService {
 	return &DocumentService{
-		esClient: esClient,
-		logger:   logger,
+		esClient:      esClient,
+		logger:        logger,
+		runningImports: make(map[string]*checkpointClaim),
+		checkpointTTL:  defaultCheckpointTTL,
+		calibrations:   make(map[string]*models.CalibrationResult),
+
+		refreshSuspensions: newRefreshSuspensions(),
+		bulkJobs:           newBulkJobs(),
 	}
 }
 
 // BulkIndex performs high-performance bulk indexing operations
 func (s *DocumentService) BulkIndex(ctx context.Context, req *models.BulkRequest) (*models.BulkResponse, error) {
+	return s.bulkIndex(ctx, req, nil, nil)
+}
+
+// operationSource yields the next batch of at most max operations, or none once exhausted
+type operationSource func(max int) ([]models.BulkOperation, error)
+
+// batchObserver is told about every batch of a bulk request as it completes
+type batchObserver func(result batchResult)
+
+// bulkIndex runs a bulk request. When more is set, it is read for further operations after
+// req.Operations, batch by batch as workers free up, so streamed input is never held whole.
+// When onBatch is set, it is called with each batch's result as it completes.
+func (s *DocumentService) bulkIndex(ctx context.Context, req *models.BulkRequest, more operationSource, onBatch batchObserver) (*models.BulkResponse, error) {
 	s.logger.Info("Starting bulk index operation",
 		zap.String("index", req.IndexName),
 		zap.Int("operations", len(req.Operations)),
@@ -48,14 +93,25 @@ func (s *DocumentService) BulkIndex(ctx context.Context, req *models.BulkRequest
 		return nil, fmt.Errorf("invalid bulk request: %w", err)
 	}
 
+	// Refreshing every second throttles large loads; switch it off until the request is done
+	if req.SuspendRefresh {
+		restoreRefresh, err := s.suspendRefresh(ctx, req.IndexName)
+		if err != nil {
+			return nil, fmt.Errorf("failed to suspend refresh: %w", err)
+		}
+		defer restoreRefresh()
+	}
+
 	// Process operations in optimized batches
-	response, err := s.processBulkOperations(ctx, req)
+	response, err := s.processBulkOperations(ctx, req, more, onBatch)
 	if err != nil {
 		return nil, fmt.Errorf("failed to process bulk operations: %w", err)
 	}
 
 	// Calculate performance metrics
 	processingTime := time.Since(startTime)
+	metrics.RecordBulkOperation(req.IndexName, processingTime)
+	response.Took = processingTime.Milliseconds()
 	response.Summary = s.calculateBulkSummary(response, processingTime)
 	response.RequestID = s.generateRequestID()
 	response.Timestamp = time.Now()
@@ -64,12 +120,29 @@ func (s *DocumentService) BulkIndex(ctx context.Context, req *models.BulkRequest
 		zap.String("index", req.IndexName),
 		zap.Int64("successful", response.Summary.SuccessfulOperations),
 		zap.Int64("failed", response.Summary.FailedOperations),
+		zap.Int64("skipped", response.Summary.SkippedOperations),
+		zap.Int64("succeeded_on_retry", response.Summary.SucceededOnRetry),
 		zap.Float64("throughput", response.Summary.ThroughputPerSecond),
 		zap.Duration("duration", processingTime))
 
+	if err := checkErrorTolerance(req.ErrorTolerance, response); err != nil {
+		s.logger.Warn("Bulk operation exceeded its error tolerance",
+			zap.String("index", req.IndexName),
+			zap.String("error_tolerance", req.ErrorTolerance),
+			zap.Bool("aborted", response.Aborted),
+			zap.Float64("error_rate", response.Summary.ErrorRate))
+		return nil, err
+	}
+
 	return response, nil
 }
 
+// ValidateBulkRequest checks a bulk request and fills in its defaults, so that a handler can
+// reject it before committing to a streamed response
+func (s *DocumentService) ValidateBulkRequest(req *models.BulkRequest) error {
+	return s.validateBulkRequest(req)
+}
+
 // validateBulkRequest validates and sets defaults for bulk request
 func (s *DocumentService) validateBulkRequest(req *models.BulkRequest) error {
 	if req.IndexName == "" {
@@ -80,6 +153,16 @@ func (s *DocumentService) validateBulkRequest(req *models.BulkRequest) error {
 		return fmt.Errorf("no operations provided")
 	}
 
+	// Prefer parameters measured against this index over the size-based heuristics
+	if calibration, ok := s.GetCalibration(req.IndexName); ok {
+		if req.BatchSize == 0 {
+			req.BatchSize = calibration.RecommendedBatchSize
+		}
+		if req.ParallelWorkers == 0 {
+			req.ParallelWorkers = calibration.RecommendedWorkers
+		}
+	}
+
 	// Set intelligent defaults based on optimization strategy
 	if req.BatchSize == 0 {
 		req.BatchSize = s.calculateOptimalBatchSize(req)
@@ -94,13 +177,58 @@ func (s *DocumentService) validateBulkRequest(req *models.BulkRequest) error {
 	}
 
 	if req.ErrorTolerance == "" {
-		req.ErrorTolerance = "medium"
+		req.ErrorTolerance = ErrorToleranceMedium
+	}
+	if err := validateErrorTolerance(req.ErrorTolerance); err != nil {
+		return err
+	}
+
+	if req.Priority == "" {
+		req.Priority = PriorityNormal
+	}
+	if err := validatePriority(req.Priority); err != nil {
+		return err
 	}
 
 	if req.Settings == nil {
 		req.Settings = s.getDefaultBulkSettings(req)
 	}
+	if req.MaxRetries < 0 {
+		return fmt.Errorf("max_retries must not be negative")
+	}
+	if req.MaxRetries > 0 {
+		req.Settings.MaxRetries = req.MaxRetries
+	}
+
+	if req.DeadLetterIndex != "" && req.DeadLetterIndex == req.IndexName {
+		return fmt.Errorf("dead letter index must differ from the target index")
+	}
+
+	return validateBulkOperations(req.Operations)
+}
+
+// validateBulkOperations checks conditional write parameters and correlation IDs
+func validateBulkOperations(operations []models.BulkOperation) error {
+	correlationIDs := make(map[string]int)
+	for i, op := range operations {
+		if (op.IfSeqNo == nil) != (op.IfPrimaryTerm == nil) {
+			return fmt.Errorf("operation %d: if_seq_no and if_primary_term must be set together", i)
+		}
+		if op.IfSeqNo != nil && op.Action == "create" {
+			return fmt.Errorf("operation %d: create cannot be conditional, use index instead", i)
+		}
+		if err := validateUpdateOperation(op); err != nil {
+			return fmt.Errorf("operation %d: %w", i, err)
+		}
 
+		if op.CorrelationID == "" {
+			continue
+		}
+		if first, exists := correlationIDs[op.CorrelationID]; exists {
+			return fmt.Errorf("operation %d: correlation_id %q is already used by operation %d", i, op.CorrelationID, first)
+		}
+		correlationIDs[op.CorrelationID] = i
+	}
 	return nil
 }
 
@@ -167,7 +295,8 @@ func (s *DocumentService) estimateAverageDocumentSize(operations []models.BulkOp
 // getDefaultBulkSettings returns default settings for bulk operations
 func (s *DocumentService) getDefaultBulkSettings(req *models.BulkRequest) *models.BulkSettings {
 	settings := &models.BulkSettings{
-		Timeout: 60 * time.Second,
+		Timeout:    60 * time.Second,
+		MaxRetries: 3,
 	}
 
 	switch req.OptimizeFor {
@@ -186,82 +315,173 @@ func (s *DocumentService) getDefaultBulkSettings(req *models.BulkRequest) *model
 }
 
 // processBulkOperations processes bulk operations with optimal performance
-func (s *DocumentService) processBulkOperations(ctx context.Context, req *models.BulkRequest) (*models.BulkResponse, error) {
+func (s *DocumentService) processBulkOperations(ctx context.Context, req *models.BulkRequest, more operationSource, onBatch batchObserver) (*models.BulkResponse, error) {
 	totalOps := len(req.Operations)
 	batchSize := req.BatchSize
 	workerCount := req.ParallelWorkers
 
-	// Calculate number of batches
-	numBatches := int(math.Ceil(float64(totalOps) / float64(batchSize)))
-
 	s.logger.Info("Processing bulk operations",
 		zap.Int("total_operations", totalOps),
+		zap.Bool("streaming", more != nil),
 		zap.Int("batch_size", batchSize),
-		zap.Int("num_batches", numBatches),
 		zap.Int("workers", workerCount))
 
-	// Create channels for work distribution
-	batchChan := make(chan batchWork, numBatches)
-	resultChan := make(chan batchResult, numBatches)
+	// Adaptive requests tune the batch size and workers to the cluster as batches complete
+	var throttle *bulkThrottle
+	if req.Adaptive {
+		throttle = newBulkThrottle(batchSize, workerCount)
+	}
 
-	// Start workers
-	var wg sync.WaitGroup
-	for i := 0; i < workerCount; i++ {
-		wg.Add(1)
-		go s.bulkWorker(ctx, req, batchChan, resultChan, &wg)
+	// Batches come from the request first, then from the stream
+	nextBatch := func(start int) ([]models.BulkOperation, error) {
+		size := batchSize
+		if throttle != nil {
+			size = throttle.size()
+		}
+		if start < totalOps {
+			return req.Operations[start:int(math.Min(float64(start+size), float64(totalOps)))], nil
+		}
+		if more == nil {
+			return nil, nil
+		}
+		return more(size)
 	}
 
-	// Send batches to workers
+	// Cancelled to stop a low tolerance request at its first failure
+	ctx, abort := context.WithCancel(ctx)
+	defer abort()
+
+	resultChan := make(chan batchResult, workerCount)
+	scheduler := s.bulkScheduler()
+
+	// Batches run on the shared scheduler; this request keeps at most workerCount of
+	// them queued or running, so its concurrency is bounded as before. Streamed batches
+	// are only read once a slot is free, which bounds memory as well.
+	slots := make(chan struct{}, workerCount)
+	var wg sync.WaitGroup
+	var sourceErr error
+	numBatches := 0
 	go func() {
-		defer close(batchChan)
-		for i := 0; i < numBatches; i++ {
-			start := i * batchSize
-			end := int(math.Min(float64(start+batchSize), float64(totalOps)))
-			
-			batch := batchWork{
-				id:         i,
-				operations: req.Operations[start:end],
-			}
-			
+		// Close result channel once every submitted batch has reported
+		defer func() {
+			wg.Wait()
+			close(resultChan)
+		}()
+
+		for start := 0; ; numBatches++ {
 			select {
-			case batchChan <- batch:
+			case slots <- struct{}{}:
 			case <-ctx.Done():
 				return
 			}
-		}
-	}()
 
-	// Close result channel when all workers are done
-	go func() {
-		wg.Wait()
-		close(resultChan)
+			operations, err := nextBatch(start)
+			if err != nil || len(operations) == 0 {
+				sourceErr = err
+				<-slots
+				return
+			}
+			start += len(operations)
+
+			batch := batchWork{
+				id:         numBatches,
+				operations: operations,
+			}
+			
+			wg.Add(1)
+			scheduler.submit(req.Priority, func() {
+				defer func() {
+					if throttle == nil || !throttle.withholdSlot() {
+						<-slots
+					}
+					wg.Done()
+				}()
+
+				var result batchResult
+				if err := ctx.Err(); err != nil {
+					result = batchResult{id: batch.id, err: err}
+				} else {
+					result = s.processBatch(ctx, req, batch)
+				}
+				result.operations = len(batch.operations)
+				resultChan <- result
+			})
+		}
 	}()
 
 	// Collect results
 	var allItems []models.BulkResponseItem
 	totalTook := int64(0)
+	tookBatches := 0
 	hasErrors := false
+	deadLettered := int64(0)
+	retried := int64(0)
+	skipped := int64(0)
+	aborted := false
+	var correlated map[string]*models.BulkItemResponse
 
 	for result := range resultChan {
+		result.nextBatchSize = batchSize
+		if throttle != nil {
+			for free := throttle.observe(result); free > 0; free-- {
+				<-slots
+			}
+			result.nextBatchSize = throttle.size()
+		}
+		if onBatch != nil {
+			onBatch(result)
+		}
+
+		// Low tolerance stops scheduling at the first failure; batches already running
+		// are cancelled and the rest are never read
+		if (result.err != nil || result.hasErrors) && req.ErrorTolerance == ErrorToleranceLow && !aborted {
+			aborted = true
+			abort()
+		}
+
 		if result.err != nil {
-			s.logger.Error("Batch processing failed",
-				zap.Int("batch_id", result.id),
-				zap.Error(result.err))
+			skipped += int64(result.operations)
+			if !aborted || !errors.Is(result.err, context.Canceled) {
+				s.logger.Error("Batch processing failed",
+					zap.Int("batch_id", result.id),
+					zap.Error(result.err))
+			}
 			// Continue processing other batches
 			continue
 		}
 
 		allItems = append(allItems, result.items...)
 		totalTook += result.took
+		tookBatches++
+		deadLettered += result.deadLettered
+		retried += result.retried
 		if result.hasErrors {
 			hasErrors = true
 		}
+		for id, item := range result.correlated {
+			if correlated == nil {
+				correlated = make(map[string]*models.BulkItemResponse)
+			}
+			correlated[id] = item
+		}
+	}
+
+	// The producer is done once the result channel is closed
+	if sourceErr != nil {
+		return nil, sourceErr
 	}
 
 	return &models.BulkResponse{
-		Took:   totalTook / int64(numBatches), // Average took time
+		ElasticsearchTook: totalTook,
+		Batches: tookBatches,
 		Errors: hasErrors,
 		Items:  allItems,
+		DeadLettered: deadLettered,
+		Results: correlated,
+		Aborted: aborted,
+		SkippedOperations: skipped,
+		RetriedOperations: retried,
+		Adaptation: throttle.report(),
 	}, nil
 }
 
@@ -277,31 +497,238 @@ type batchResult struct {
 	items     []models.BulkResponseItem
 	took      int64
 	hasErrors bool
+	deadLettered int64
+	retried   int64 // items that succeeded on a retry
+	correlated map[string]*models.BulkItemResponse // results of operations with a correlation ID
+	operations int // size of the batch, counted as skipped when err is set
+	nextBatchSize int // batch size for the operations not yet read, set before observers run
 	err       error
 }
 
-// bulkWorker processes batches of bulk operations
-func (s *DocumentService) bulkWorker(ctx context.Context, req *models.BulkRequest, 
-	batchChan <-chan batchWork, resultChan chan<- batchResult, wg *sync.WaitGroup) {
-	
-	defer wg.Done()
+// bulkScheduler returns the shared scheduler, starting its workers on first use
+func (s *DocumentService) bulkScheduler() *bulkScheduler {
+	s.schedulerOnce.Do(func() {
+		s.scheduler = newBulkScheduler(defaultSchedulerWorkers)
+	})
+	return s.scheduler
+}
+
+// GetBulkQueueStats returns the number of queued and running batches per priority
+func (s *DocumentService) GetBulkQueueStats() *models.BulkQueueStats {
+	return s.bulkScheduler().stats()
+}
+
+// processBatch processes a single batch of operations, retrying items that were
+// rejected for transient reasons and dead-lettering those that still fail
+func (s *DocumentService) processBatch(ctx context.Context, req *models.BulkRequest, batch batchWork) batchResult {
+	requestStart := time.Now()
+	items, took, err := s.executeBulk(ctx, req, batch.operations)
+	metrics.RecordBulkRequest(req.IndexName, "bulk", time.Since(requestStart))
+	if err != nil {
+		recordBatchMetrics(req.IndexName, batch.operations, nil)
+		return batchResult{
+			id:  batch.id,
+			err: err,
+		}
+	}
+
+	attempts := make([]int, len(items))
+	for i := range attempts {
+		attempts[i] = 1
+	}
+
+	backoff := req.Settings.RetryBackoff
+	if backoff <= 0 {
+		backoff = defaultRetryBackoff
+	}
+
+	for retry := 0; retry < req.Settings.MaxRetries; retry++ {
+		var retryIdx []int
+		for i, item := range items {
+			if result := bulkItemResult(item); result != nil && isRetryableBulkStatus(result.Status) {
+				retryIdx = append(retryIdx, i)
+			}
+		}
+		if len(retryIdx) == 0 {
+			break
+		}
 
-	for batch := range batchChan {
 		select {
 		case <-ctx.Done():
-			return
-		default:
-			result := s.processBatch(ctx, req, batch)
-			resultChan <- result
+			retryIdx = nil
+		case <-time.After(backoff):
+		}
+		if retryIdx == nil {
+			break
+		}
+		backoff *= 2
+
+		retryOps := make([]models.BulkOperation, len(retryIdx))
+		for i, idx := range retryIdx {
+			retryOps[i] = batch.operations[idx]
+		}
+
+		requestStart := time.Now()
+		retryItems, retryTook, err := s.executeBulk(ctx, req, retryOps)
+		metrics.RecordBulkRequest(req.IndexName, "retry", time.Since(requestStart))
+		if err != nil {
+			s.logger.Warn("Bulk retry failed, keeping previous item results",
+				zap.Int("batch_id", batch.id),
+				zap.Int("items", len(retryOps)),
+				zap.Error(err))
+			break
+		}
+
+		took += retryTook
+		for i, idx := range retryIdx {
+			if i < len(retryItems) {
+				items[idx] = retryItems[i]
+				attempts[idx]++
+			}
 		}
 	}
+
+	hasErrors := false
+	var retried int64
+	for i, item := range items {
+		result := bulkItemResult(item)
+		if result == nil {
+			continue
+		}
+		if result.Error != nil {
+			hasErrors = true
+		} else if attempts[i] > 1 {
+			retried++
+		}
+	}
+
+	var deadLettered int64
+	if hasErrors && req.DeadLetterIndex != "" {
+		deadLettered = s.writeDeadLetters(ctx, req, batch.operations, items, attempts)
+	}
+
+	recordBatchMetrics(req.IndexName, batch.operations, items)
+
+	return batchResult{
+		id:           batch.id,
+		items:        items,
+		took:         took,
+		hasErrors:    hasErrors,
+		deadLettered: deadLettered,
+		retried:      retried,
+		correlated:   correlateBulkItems(batch.operations, items),
+	}
 }
 
-// processBatch processes a single batch of operations
-func (s *DocumentService) processBatch(ctx context.Context, req *models.BulkRequest, batch batchWork) batchResult {
+// recordBatchMetrics records a batch's outcome and its documents by action. Without items,
+// the bulk request itself failed and every operation counts as failed.
+func recordBatchMetrics(indexName string, operations []models.BulkOperation, items []models.BulkResponseItem) {
+	type counts struct{ succeeded, failed int }
+	byAction := make(map[string]*counts)
+	count := func(action string) *counts {
+		if byAction[action] == nil {
+			byAction[action] = &counts{}
+		}
+		return byAction[action]
+	}
+
+	failed := 0
+	if items == nil {
+		for _, op := range operations {
+			count(op.Action).failed++
+		}
+		failed = len(operations)
+	}
+	for _, item := range items {
+		result := bulkItemResult(item)
+		if result == nil {
+			continue
+		}
+		if result.Error != nil {
+			count(bulkItemAction(item)).failed++
+			failed++
+		} else {
+			count(bulkItemAction(item)).succeeded++
+		}
+	}
+
+	for action, c := range byAction {
+		metrics.RecordBulkDocuments(indexName, action, c.succeeded, c.failed)
+	}
+
+	switch {
+	case failed == 0:
+		metrics.RecordBulkBatch(indexName, "success")
+	case failed < len(operations):
+		metrics.RecordBulkBatch(indexName, "partial")
+	default:
+		metrics.RecordBulkBatch(indexName, "failed")
+	}
+}
+
+// correlateBulkItems maps the correlation ID of each operation to its result. Items of a
+// single bulk call come back in the order the operations were sent.
+func correlateBulkItems(operations []models.BulkOperation, items []models.BulkResponseItem) map[string]*models.BulkItemResponse {
+	var correlated map[string]*models.BulkItemResponse
+	for i, op := range operations {
+		if op.CorrelationID == "" || i >= len(items) {
+			continue
+		}
+		if correlated == nil {
+			correlated = make(map[string]*models.BulkItemResponse)
+		}
+		correlated[op.CorrelationID] = bulkItemResult(items[i])
+	}
+	return correlated
+}
+
+// defaultRetryBackoff is the initial wait before retrying rejected bulk items
+const defaultRetryBackoff = 100 * time.Millisecond
+
+// isRetryableBulkStatus reports whether a bulk item failure is transient
+func isRetryableBulkStatus(status int) bool {
+	switch status {
+	case http.StatusTooManyRequests, http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		return true
+	}
+	return false
+}
+
+// bulkItemAction returns the action a bulk item responded to
+func bulkItemAction(item models.BulkResponseItem) string {
+	switch {
+	case item.Index != nil:
+		return "index"
+	case item.Create != nil:
+		return "create"
+	case item.Update != nil:
+		return "update"
+	case item.Delete != nil:
+		return "delete"
+	}
+	return ""
+}
+
+// bulkItemResult returns the populated response of a bulk item, whatever its action
+func bulkItemResult(item models.BulkResponseItem) *models.BulkItemResponse {
+	switch {
+	case item.Index != nil:
+		return item.Index
+	case item.Create != nil:
+		return item.Create
+	case item.Update != nil:
+		return item.Update
+	case item.Delete != nil:
+		return item.Delete
+	}
+	return nil
+}
+
+// executeBulk sends a single bulk request and returns the per-item results
+func (s *DocumentService) executeBulk(ctx context.Context, req *models.BulkRequest, operations []models.BulkOperation) ([]models.BulkResponseItem, int64, error) {
 	// Build bulk request body
 	var buf bytes.Buffer
-	for _, op := range batch.operations {
+	for _, op := range operations {
 		// Action line
 		actionLine := s.buildActionLine(op, req.IndexName)
 		buf.WriteString(actionLine)
@@ -309,44 +736,36 @@ func (s *DocumentService) processBatch(ctx context.Context, req *models.BulkRequ
 
 		// Document line (if needed)
 		if op.Action != "delete" {
-			var doc interface{}
-			if op.Document != nil {
-				doc = op.Document
-			} else if op.Source != nil {
-				doc = op.Source
-			}
-
-			if doc != nil {
-				docBytes, _ := json.Marshal(doc)
-				buf.Write(docBytes)
+			if payload := operationPayload(op); payload != nil {
+				payloadBytes, _ := json.Marshal(payload)
+				buf.Write(payloadBytes)
 				buf.WriteByte('\n')
 			}
 		}
 	}
 
 	// Execute bulk request
-	res, err := s.esClient.Bulk(
+	options := []func(*esapi.BulkRequest){
 		s.esClient.Bulk.WithContext(ctx),
-		s.esClient.Bulk.WithBody(&buf),
 		s.esClient.Bulk.WithIndex(req.IndexName),
 		s.esClient.Bulk.WithRefresh(req.Settings.RefreshPolicy),
 		s.esClient.Bulk.WithTimeout(req.Settings.Timeout),
-	)
+	}
+	// Documents pass through the ingest pipeline before they are indexed
+	if req.Settings.Pipeline != "" {
+		options = append(options, s.esClient.Bulk.WithPipeline(req.Settings.Pipeline))
+	}
+
+	res, err := s.esClient.Bulk(&buf, options...)
 
 	if err != nil {
-		return batchResult{
-			id:  batch.id,
-			err: fmt.Errorf("bulk request failed: %w", err),
-		}
+		return nil, 0, fmt.Errorf("bulk request failed: %w", err)
 	}
 	defer res.Body.Close()
 
 	if res.IsError() {
 		body, _ := io.ReadAll(res.Body)
-		return batchResult{
-			id:  batch.id,
-			err: fmt.Errorf("bulk request error: %s - %s", res.Status(), string(body)),
-		}
+		return nil, 0, fmt.Errorf("bulk request error: %s - %s", res.Status(), string(body))
 	}
 
 	// Parse response
@@ -357,18 +776,62 @@ func (s *DocumentService) processBatch(ctx context.Context, req *models.BulkRequ
 	}
 
 	if err := json.NewDecoder(res.Body).Decode(&bulkResp); err != nil {
-		return batchResult{
-			id:  batch.id,
-			err: fmt.Errorf("failed to decode bulk response: %w", err),
+		return nil, 0, fmt.Errorf("failed to decode bulk response: %w", err)
+	}
+
+	return bulkResp.Items, bulkResp.Took, nil
+}
+
+// operationPayload returns the source line sent after an operation's action line: the
+// document for index and create, the update body (doc or script, plus upsert) for update
+func operationPayload(op models.BulkOperation) map[string]interface{} {
+	if op.Action != "update" {
+		return operationDocument(op)
+	}
+
+	body := map[string]interface{}{}
+	if op.Script != nil {
+		body["script"] = op.Script
+	} else if doc := operationDocument(op); doc != nil {
+		body["doc"] = doc
+	}
+	if op.Upsert != nil {
+		body["upsert"] = op.Upsert
+	}
+	if op.DocAsUpsert {
+		body["doc_as_upsert"] = true
+	}
+	return body
+}
+
+// validateUpdateOperation checks that scripts and upserts are only used by updates, and
+// that an update carries exactly one of a partial document or a script
+func validateUpdateOperation(op models.BulkOperation) error {
+	if op.Action != "update" {
+		if op.Script != nil || op.Upsert != nil || op.DocAsUpsert {
+			return fmt.Errorf("script, upsert and doc_as_upsert are only valid for update actions")
 		}
+		return nil
 	}
 
-	return batchResult{
-		id:        batch.id,
-		items:     bulkResp.Items,
-		took:      bulkResp.Took,
-		hasErrors: bulkResp.Errors,
+	hasDoc := operationDocument(op) != nil
+	switch {
+	case op.Script != nil && hasDoc:
+		return fmt.Errorf("update cannot set both a script and doc")
+	case op.Script == nil && !hasDoc:
+		return fmt.Errorf("update requires a script or doc")
+	case op.DocAsUpsert && op.Script != nil:
+		return fmt.Errorf("doc_as_upsert requires doc, use upsert with a script")
+	}
+	return nil
+}
+
+// operationDocument returns the document body of an operation, if any
+func operationDocument(op models.BulkOperation) map[string]interface{} {
+	if op.Document != nil {
+		return op.Document
 	}
+	return op.Source
 }
 
 // buildActionLine builds the action line for bulk operations
@@ -392,6 +855,11 @@ func (s *DocumentService) buildActionLine(op models.BulkOperation, defaultIndex
 		actionBody["_version"] = *op.Version
 	}
 
+	if op.IfSeqNo != nil && op.IfPrimaryTerm != nil {
+		actionBody["if_seq_no"] = *op.IfSeqNo
+		actionBody["if_primary_term"] = *op.IfPrimaryTerm
+	}
+
 	if op.Routing != "" {
 		actionBody["_routing"] = op.Routing
 	}
@@ -405,9 +873,15 @@ func (s *DocumentService) buildActionLine(op models.BulkOperation, defaultIndex
 // calculateBulkSummary calculates summary statistics for bulk operations
 func (s *DocumentService) calculateBulkSummary(response *models.BulkResponse, processingTime time.Duration) *models.BulkSummary {
 	summary := &models.BulkSummary{
-		TotalOperations:  int64(len(response.Items)),
-		ProcessingTime:   processingTime,
-		AverageLatency:   time.Duration(response.Took) * time.Millisecond,
+		TotalOperations:   int64(len(response.Items)) + response.SkippedOperations,
+		SkippedOperations: response.SkippedOperations,
+		SucceededOnRetry:  response.RetriedOperations,
+		ProcessingTime:    processingTime,
+	}
+
+	// Average time Elasticsearch spent on a batch
+	if response.Batches > 0 {
+		summary.AverageLatency = time.Duration(response.ElasticsearchTook/int64(response.Batches)) * time.Millisecond
 	}
 
 	// Count operation results
@@ -428,6 +902,9 @@ func (s *DocumentService) calculateBulkSummary(response *models.BulkResponse, pr
 		if itemResponse != nil {
 			if itemResponse.Error != nil {
 				summary.FailedOperations++
+				if itemResponse.Status == http.StatusConflict {
+					summary.VersionConflicts++
+				}
 			} else {
 				summary.SuccessfulOperations++
 				
@@ -452,7 +929,8 @@ func (s *DocumentService) calculateBulkSummary(response *models.BulkResponse, pr
 	}
 
 	if summary.TotalOperations > 0 {
-		summary.ErrorRate = float64(summary.FailedOperations) / float64(summary.TotalOperations) * 100.0
+		// Operations lost with a failed batch count against the error rate like failed items
+		summary.ErrorRate = float64(summary.FailedOperations+summary.SkippedOperations) / float64(summary.TotalOperations) * 100.0
 	}
 
 	return summary
@@ -460,21 +938,48 @@ func (s *DocumentService) calculateBulkSummary(response *models.BulkResponse, pr
 
 // IndexDocument indexes a single document (wrapper around bulk for consistency)
 func (s *DocumentService) IndexDocument(ctx context.Context, indexName, docID string, document map[string]interface{}) (*models.BulkResponse, error) {
-	bulkReq := &models.BulkRequest{
-		IndexName: indexName,
-		Operations: []models.BulkOperation{
-			{
-				Action:   "index",
-				ID:       docID,
-				Document: document,
-			},
-		},
+	return s.writeDocument(ctx, indexName, models.BulkOperation{
+		Action:   "index",
+		ID:       docID,
+		Document: document,
+	})
+}
+
+// writeDocument runs a single document operation through the bulk path. A failed item is
+// returned as a *shared.ResponseError carrying its status, so handlers answer a missing
+// document with 404 and a version conflict with 409 rather than a bulk failure.
+func (s *DocumentService) writeDocument(ctx context.Context, indexName string, op models.BulkOperation) (*models.BulkResponse, error) {
+	response, err := s.BulkIndex(ctx, &models.BulkRequest{
+		IndexName:       indexName,
+		Operations:      []models.BulkOperation{op},
 		BatchSize:       1,
 		ParallelWorkers: 1,
 		OptimizeFor:     "consistency", // Single doc operations prioritize consistency
+		Priority:        PriorityHigh,  // Interactive writes go ahead of queued imports
+		// The item's own failure is reported below, never as a tolerance error
+		ErrorTolerance: ErrorToleranceHigh,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if len(response.Items) == 0 {
+		// The bulk request itself failed; processBulkOperations logged why
+		return nil, fmt.Errorf("failed to %s document %s: bulk request failed", op.Action, op.ID)
 	}
 
-	return s.BulkIndex(ctx, bulkReq)
+	item := bulkItemResult(response.Items[0])
+	switch {
+	case item == nil:
+		return nil, fmt.Errorf("failed to %s document %s: empty bulk item", op.Action, op.ID)
+	case item.Error != nil:
+		return nil, &shared.ResponseError{StatusCode: item.Status, Type: item.Error.Type, Reason: item.Error.Reason}
+	case item.Status == http.StatusNotFound:
+		// Deleting a missing document answers with result not_found and no error object
+		return nil, &shared.ResponseError{StatusCode: http.StatusNotFound, Reason: fmt.Sprintf("document %s not found", item.ID)}
+	}
+
+	return response, nil
 }
 
 // GetDocument retrieves a single document by ID
@@ -493,12 +998,14 @@ func (s *DocumentService) GetDocument(ctx context.Context, indexName, docID stri
 	}
 	defer res.Body.Close()
 
-	if res.StatusCode == http.StatusNotFound {
-		return nil, fmt.Errorf("document not found")
-	}
-
 	if res.IsError() {
-		return nil, shared.ParseESError(res)
+		err := shared.ParseESError(res)
+		// A missing index comes with an error object, a missing document only with found: false
+		var esErr *shared.ResponseError
+		if errors.As(err, &esErr) && esErr.StatusCode == http.StatusNotFound && esErr.Type == "" {
+			esErr.Reason = fmt.Sprintf("document %s not found", docID)
+		}
+		return nil, err
 	}
 
 	var response struct {
@@ -511,7 +1018,7 @@ func (s *DocumentService) GetDocument(ctx context.Context, indexName, docID stri
 	}
 
 	if !response.Found {
-		return nil, fmt.Errorf("document not found")
+		return nil, &shared.ResponseError{StatusCode: http.StatusNotFound, Reason: fmt.Sprintf("document %s not found", docID)}
 	}
 
 	return response.Source, nil
@@ -519,39 +1026,19 @@ func (s *DocumentService) GetDocument(ctx context.Context, indexName, docID stri
 
 // UpdateDocument updates a single document
 func (s *DocumentService) UpdateDocument(ctx context.Context, indexName, docID string, updates map[string]interface{}) (*models.BulkResponse, error) {
-	bulkReq := &models.BulkRequest{
-		IndexName: indexName,
-		Operations: []models.BulkOperation{
-			{
-				Action:   "update",
-				ID:       docID,
-				Document: map[string]interface{}{"doc": updates},
-			},
-		},
-		BatchSize:       1,
-		ParallelWorkers: 1,
-		OptimizeFor:     "consistency",
-	}
-
-	return s.BulkIndex(ctx, bulkReq)
+	return s.writeDocument(ctx, indexName, models.BulkOperation{
+		Action:   "update",
+		ID:       docID,
+		Document: updates,
+	})
 }
 
 // DeleteDocument deletes a single document
 func (s *DocumentService) DeleteDocument(ctx context.Context, indexName, docID string) (*models.BulkResponse, error) {
-	bulkReq := &models.BulkRequest{
-		IndexName: indexName,
-		Operations: []models.BulkOperation{
-			{
-				Action: "delete",
-				ID:     docID,
-			},
-		},
-		BatchSize:       1,
-		ParallelWorkers: 1,
-		OptimizeFor:     "consistency",
-	}
-
-	return s.BulkIndex(ctx, bulkReq)
+	return s.writeDocument(ctx, indexName, models.BulkOperation{
+		Action: "delete",
+		ID:     docID,
+	})
 }
 
 // BulkImportFromNDJSON imports documents from NDJSON format with optimal performance
@@ -565,23 +1052,65 @@ func (s *DocumentService) BulkImportFromNDJSON(ctx context.Context, indexName st
 		zap.Int("batch_size", options.BatchSize),
 		zap.Int("workers", options.ParallelWorkers))
 
-	// Parse NDJSON into operations
-	operations, err := s.parseNDJSON(ndjsonData, indexName)
+	// Tracked imports are checkpointed so a retry can resume where it stopped
+	if options.IdempotencyKey != "" {
+		return s.resumableImport(ctx, indexName, ndjsonData, options)
+	}
+
+	return s.streamImport(ctx, indexName, s.newNDJSONReader(ndjsonData, indexName, 0, options.GenerateIDs), options)
+}
+
+// importSource parses documents for a streaming import
+type importSource interface {
+	readOperations(max int) ([]models.BulkOperation, error)
+	// idStrategy names how documents without an _id are identified
+	idStrategy() string
+	// generatedIDs is the number of documents given a content hash ID so far
+	generatedIDs() int64
+}
+
+// streamImport bulk indexes every document of records. Documents are parsed as workers
+// need them, so memory stays bounded by the batches in flight however large the input
+// is. The first batch (or the calibration sample) is read up front to size the request.
+func (s *DocumentService) streamImport(ctx context.Context, indexName string, records importSource, options *BulkImportOptions) (*models.BulkResponse, error) {
+	headSize := options.BatchSize
+	if options.Calibrate || headSize <= 0 {
+		headSize = calibrationSampleSize
+	}
+	head, err := records.readOperations(headSize)
 	if err != nil {
-		return nil, fmt.Errorf("failed to parse NDJSON: %w", err)
+		return nil, err
+	}
+
+	if options.Calibrate {
+		s.calibrateImport(ctx, indexName, head, options)
 	}
 
 	// Create bulk request
 	bulkReq := &models.BulkRequest{
 		IndexName:       indexName,
-		Operations:      operations,
+		Operations:      head,
 		BatchSize:       options.BatchSize,
 		ParallelWorkers: options.ParallelWorkers,
 		OptimizeFor:     "write_throughput",
 		ErrorTolerance:  options.ErrorTolerance,
+		DeadLetterIndex: options.DeadLetterIndex,
+		Priority:        options.Priority,
 	}
 
-	return s.BulkIndex(ctx, bulkReq)
+	response, err := s.bulkIndex(ctx, bulkReq, records.readOperations, nil)
+	if err != nil {
+		var toleranceErr *BulkToleranceError
+		if errors.As(err, &toleranceErr) {
+			toleranceErr.Response.Summary.IDStrategy = records.idStrategy()
+			toleranceErr.Response.Summary.GeneratedIDs = records.generatedIDs()
+		}
+		return nil, err
+	}
+
+	response.Summary.IDStrategy = records.idStrategy()
+	response.Summary.GeneratedIDs = records.generatedIDs()
+	return response, nil
 }
 
 // BulkImportOptions defines options for bulk import operations
@@ -590,6 +1119,17 @@ type BulkImportOptions struct {
 	ParallelWorkers int
 	ErrorTolerance  string
 	GenerateIDs     bool
+
+	// IdempotencyKey enables checkpointing; retrying with the same key resumes the import
+	IdempotencyKey string
+	// CheckpointInterval is the number of documents indexed between checkpoints
+	CheckpointInterval int
+	// DeadLetterIndex receives documents that still fail after retries
+	DeadLetterIndex string
+	// Calibrate measures the best batch size and worker count on a sample before importing
+	Calibrate bool
+	// Priority of the import's batches on the shared scheduler (default low)
+	Priority string
 }
 
 // getDefaultImportOptions returns default options for bulk import
@@ -597,61 +1137,45 @@ func (s *DocumentService) getDefaultImportOptions() *BulkImportOptions {
 	return &BulkImportOptions{
 		BatchSize:       1000,
 		ParallelWorkers: 8,
-		ErrorTolerance:  "medium",
+		ErrorTolerance:  ErrorToleranceMedium,
 		GenerateIDs:     true,
+		Priority:        PriorityLow,
 	}
 }
 
-// parseNDJSON parses NDJSON data into bulk operations
-func (s *DocumentService) parseNDJSON(reader io.Reader, indexName string) ([]models.BulkOperation, error) {
-	var operations []models.BulkOperation
-	
-	// Read all data
-	data, err := io.ReadAll(reader)
+// GetWritePerformanceMetrics calculates write performance metrics for an index, along with
+// whether it can take writes at all. The indexing rate is taken from the write metrics
+// history when it has a current sample, and otherwise measured over a second stats sample
+// taken shortly after the first.
+func (s *DocumentService) GetWritePerformanceMetrics(ctx context.Context, indexName string) (*models.WriteMetrics, error) {
+	stats, err := s.getIndexStats(ctx, indexName)
 	if err != nil {
 		return nil, err
 	}
+	sampledAt := time.Now()
 
-	// Split by lines
-	lines := strings.Split(string(data), "\n")
-	
-	for i, line := range lines {
-		line = strings.TrimSpace(line)
-		if line == "" {
-			continue
-		}
-
-		var document map[string]interface{}
-		if err := json.Unmarshal([]byte(line), &document); err != nil {
-			s.logger.Warn("Failed to parse JSON line",
-				zap.Int("line", i+1),
-				zap.Error(err))
-			continue
-		}
-
-		// Extract ID if present
-		var docID string
-		if id, exists := document["_id"]; exists {
-			docID = fmt.Sprintf("%v", id)
-			delete(document, "_id") // Remove from document body
-		}
+	// Calculate write metrics
+	metrics := s.calculateWriteMetrics(stats)
 
-		operation := models.BulkOperation{
-			Action:   "index",
-			Index:    indexName,
-			ID:       docID,
-			Document: document,
-		}
+	if err := s.addWriteHealth(ctx, indexName, metrics); err != nil {
+		return nil, err
+	}
 
-		operations = append(operations, operation)
+	if rate, ok := s.LatestIndexingRate(indexName); ok {
+		metrics.IndexingRate = rate
+		return metrics, nil
 	}
 
-	return operations, nil
+	rate, err := s.measureIndexingRate(ctx, indexName, stats, sampledAt)
+	if err != nil {
+		return nil, err
+	}
+	metrics.IndexingRate = rate
+	return metrics, nil
 }
 
-// GetWritePerformanceMetrics calculates write performance metrics for an index
-func (s *DocumentService) GetWritePerformanceMetrics(ctx context.Context, indexName string) (*models.WriteMetrics, error) {
-	// Get index statistics
+// getIndexStats fetches the statistics of a single index
+func (s *DocumentService) getIndexStats(ctx context.Context, indexName string) (*models.IndexStats, error) {
 	res, err := s.esClient.Indices.Stats(
 		s.esClient.Indices.Stats.WithContext(ctx),
 		s.esClient.Indices.Stats.WithIndex(indexName),
@@ -686,9 +1210,42 @@ func (s *DocumentService) GetWritePerformanceMetrics(ctx context.Context, indexN
 	statsBytes, _ := json.Marshal(indexStats)
 	json.Unmarshal(statsBytes, &stats)
 
-	// Calculate write metrics
-	metrics := s.calculateWriteMetrics(&stats)
-	return metrics, nil
+	return &stats, nil
+}
+
+// measureIndexingRate waits briefly, samples the index's stats again and returns the
+// primary docs indexed per second between the two samples
+func (s *DocumentService) measureIndexingRate(ctx context.Context, indexName string, first *models.IndexStats, firstAt time.Time) (float64, error) {
+	if first.Primaries == nil {
+		return 0, nil
+	}
+
+	timer := time.NewTimer(indexingRateSampleInterval)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return 0, ctx.Err()
+	case <-timer.C:
+	}
+
+	second, err := s.getIndexStats(ctx, indexName)
+	if err != nil {
+		return 0, err
+	}
+	if second.Primaries == nil {
+		return 0, nil
+	}
+
+	return indexingRateBetween(first.Primaries.Indexing.IndexTotal, second.Primaries.Indexing.IndexTotal, time.Since(firstAt)), nil
+}
+
+// indexingRateBetween returns docs per second between two readings of an index_total
+// counter. Counters go backwards when an index is recreated; that reports no rate.
+func indexingRateBetween(before, after int64, elapsed time.Duration) float64 {
+	if after < before || elapsed <= 0 {
+		return 0
+	}
+	return float64(after-before) / elapsed.Seconds()
 }
 
 // calculateWriteMetrics calculates write performance metrics from index stats
@@ -700,10 +1257,10 @@ func (s *DocumentService) calculateWriteMetrics(stats *models.IndexStats) *model
 	total := stats.Total
 	metrics := &models.WriteMetrics{}
 
-	// Calculate indexing rate (docs per second)
+	// Calculate lifetime indexing rate (docs per second of indexing time)
 	if total.Indexing.IndexTimeInMillis > 0 {
 		timeSeconds := float64(total.Indexing.IndexTimeInMillis) / 1000.0
-		metrics.IndexingRate = float64(total.Indexing.IndexTotal) / timeSeconds
+		metrics.LifetimeIndexingRate = float64(total.Indexing.IndexTotal) / timeSeconds
 	}
 
 	// Calculate average document size
@@ -799,7 +1356,7 @@ func (s *DocumentService) generateWriteOptimizationRecommendations(stats *models
 	}
 
 	// Low indexing rate
-	if metrics.IndexingRate < 100 && stats.Indexing.IndexTotal > 100 {
+	if metrics.LifetimeIndexingRate < 100 && stats.Indexing.IndexTotal > 100 {
 		recommendations = append(recommendations,
 			"Low indexing rate - consider increasing bulk batch sizes or using parallel processing")
 	}
@@ -4,13 +4,14 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
-	"io"
 	"math"
-	"net/http"
+	"regexp"
+	"sort"
 	"strconv"
 	"strings"
 	"time"
 
+	"github.com/elastic/go-elasticsearch/v8/esapi"
 	"go.uber.org/zap"
 
 	"github.com/saif-islam/es-playground/shared"
@@ -39,22 +40,38 @@ func (s *IndexService) CreateIndex(ctx context.Context, req *models.IndexRequest
 		zap.Bool("text_heavy", req.TextHeavy),
 		zap.String("expected_volume", req.ExpectedVolume))
 
+	if err := validateAllocationFilters(req.Allocation); err != nil {
+		return nil, fmt.Errorf("invalid allocation filters: %w", err)
+	}
+
 	// Build optimized settings based on request parameters
 	settings := s.buildOptimizedSettings(req)
 	
-	// Prepare the index creation request
-	indexBody := map[string]interface{}{}
-	
-	if settings != nil {
-		indexBody["settings"] = settings
+	// Soft-deletes support depends on the cluster version
+	if err := s.validateSoftDeletesSettings(ctx, settings.SoftDeletesEnabled, settings.SoftDeletesRetentionLeasePeriod); err != nil {
+		return nil, fmt.Errorf("invalid soft-deletes configuration: %w", err)
 	}
 	
-	if req.Mappings != nil {
-		indexBody["mappings"] = req.Mappings
-	}
-	
-	if req.Aliases != nil {
-		indexBody["aliases"] = req.Aliases
+	// Prepare the index creation request
+	indexBody := buildCreateIndexBody(req, settings)
+
+	// A dry run stops short of creating the index, after the same validation
+	if req.DryRun {
+		optimizations := s.getAppliedOptimizations(req)
+		s.logger.Info("Computed index settings without creating the index (dry run)",
+			zap.String("index_name", req.IndexName),
+			zap.Strings("optimizations", optimizations))
+
+		return &models.IndexResponse{
+			IndexName:     req.IndexName,
+			Created:       false,
+			Settings:      settings,
+			Optimizations: optimizations,
+			DryRun:        true,
+			Body:          indexBody,
+			RequestID:     s.generateRequestID(),
+			Timestamp:     time.Now(),
+		}, nil
 	}
 
 	bodyBytes, err := json.Marshal(indexBody)
@@ -105,6 +122,52 @@ func (s *IndexService) CreateIndex(ctx context.Context, req *models.IndexRequest
 	return response, nil
 }
 
+// PreviewOptimizedSettings returns the settings and request body CreateIndex would use,
+// along with the optimizations behind them, without creating anything. Problems that
+// would make creation fail are reported rather than returned as an error.
+func (s *IndexService) PreviewOptimizedSettings(ctx context.Context, req *models.IndexRequest) *models.SettingsPreview {
+	preview := &models.SettingsPreview{
+		IndexName: req.IndexName,
+		RequestID: s.generateRequestID(),
+		Timestamp: time.Now(),
+	}
+
+	if err := validateAllocationFilters(req.Allocation); err != nil {
+		preview.Problems = append(preview.Problems, fmt.Sprintf("invalid allocation filters: %v", err))
+	}
+
+	settings := s.buildOptimizedSettings(req)
+	if err := s.validateSoftDeletesSettings(ctx, settings.SoftDeletesEnabled, settings.SoftDeletesRetentionLeasePeriod); err != nil {
+		preview.Problems = append(preview.Problems, fmt.Sprintf("invalid soft-deletes configuration: %v", err))
+	}
+
+	preview.Settings = settings
+	preview.Body = buildCreateIndexBody(req, settings)
+	preview.Optimizations = s.getAppliedOptimizations(req)
+	preview.Valid = len(preview.Problems) == 0
+
+	return preview
+}
+
+// buildCreateIndexBody assembles the create index request body
+func buildCreateIndexBody(req *models.IndexRequest, settings *models.IndexSettings) map[string]interface{} {
+	indexBody := map[string]interface{}{}
+
+	if settings != nil {
+		indexBody["settings"] = settings
+	}
+
+	if req.Mappings != nil {
+		indexBody["mappings"] = req.Mappings
+	}
+
+	if req.Aliases != nil {
+		indexBody["aliases"] = req.Aliases
+	}
+
+	return indexBody
+}
+
 // buildOptimizedSettings creates write-optimized settings based on request parameters
 func (s *IndexService) buildOptimizedSettings(req *models.IndexRequest) *models.IndexSettings {
 	settings := &models.IndexSettings{
@@ -135,9 +198,56 @@ func (s *IndexService) buildOptimizedSettings(req *models.IndexRequest) *models.
 	// Apply document size optimizations
 	s.applyDocSizeOptimizations(settings, req.ExpectedDocSize)
 
+	// Pin shards to matching nodes (e.g. hot tier for write-heavy indices)
+	s.applyAllocationFilters(settings, req.Allocation)
+
 	return settings
 }
 
+// applyAllocationFilters translates allocation filters into index.routing.allocation settings
+func (s *IndexService) applyAllocationFilters(settings *models.IndexSettings, filters *models.AllocationFilters) {
+	if filters == nil {
+		return
+	}
+
+	rules := map[string]map[string]string{
+		"require": filters.Require,
+		"include": filters.Include,
+		"exclude": filters.Exclude,
+	}
+	for rule, attributes := range rules {
+		for attribute, value := range attributes {
+			settings.Additional[fmt.Sprintf("index.routing.allocation.%s.%s", rule, attribute)] = value
+		}
+	}
+}
+
+// validateAllocationFilters rejects empty attribute names and values, which Elasticsearch would
+// otherwise accept as filters that no node can satisfy
+func validateAllocationFilters(filters *models.AllocationFilters) error {
+	if filters == nil {
+		return nil
+	}
+
+	rules := map[string]map[string]string{
+		"require": filters.Require,
+		"include": filters.Include,
+		"exclude": filters.Exclude,
+	}
+	for rule, attributes := range rules {
+		for attribute, value := range attributes {
+			if strings.TrimSpace(attribute) == "" {
+				return fmt.Errorf("%s filter has an empty node attribute", rule)
+			}
+			if strings.TrimSpace(value) == "" {
+				return fmt.Errorf("%s filter for node attribute %s has no value", rule, attribute)
+			}
+		}
+	}
+
+	return nil
+}
+
 // applyWriteOptimizations applies settings for write-heavy workloads
 func (s *IndexService) applyWriteOptimizations(settings *models.IndexSettings, req *models.IndexRequest) {
 	// Optimize refresh interval for write performance
@@ -298,6 +408,18 @@ func (s *IndexService) getAppliedOptimizations(req *models.IndexRequest) []strin
 			"increased mapping depth limits")
 	}
 
+	if req.Allocation != nil {
+		for attribute, value := range req.Allocation.Require {
+			optimizations = append(optimizations,
+				fmt.Sprintf("shards pinned to nodes with %s=%s", attribute, value))
+		}
+	}
+
+	if req.Settings != nil && req.Settings.SoftDeletesRetentionLeasePeriod != "" {
+		optimizations = append(optimizations,
+			fmt.Sprintf("soft-deletes history retained for %s (extra storage and merge cost)", req.Settings.SoftDeletesRetentionLeasePeriod))
+	}
+
 	return optimizations
 }
 
@@ -321,7 +443,7 @@ func (s *IndexService) GetIndexInfo(ctx context.Context, indexName string) (*mod
 		return nil, shared.ParseESError(catRes)
 	}
 
-	var catIndices []models.IndexInfo
+	var catIndices []catIndex
 	if err := shared.DecodeJSONResponse(catRes, &catIndices); err != nil {
 		return nil, fmt.Errorf("failed to decode cat indices: %w", err)
 	}
@@ -330,7 +452,8 @@ func (s *IndexService) GetIndexInfo(ctx context.Context, indexName string) (*mod
 		return nil, fmt.Errorf("index %s not found", indexName)
 	}
 
-	indexInfo := &catIndices[0]
+	info := catIndices[0].indexInfo()
+	indexInfo := &info
 
 	// Enrich with detailed settings
 	if err := s.enrichIndexSettings(ctx, indexInfo); err != nil {
@@ -460,10 +583,11 @@ func (s *IndexService) enrichWriteMetrics(ctx context.Context, indexInfo *models
 	stats := indexInfo.Stats.Total
 	writeMetrics := &models.WriteMetrics{}
 
-	// Calculate indexing rate (docs per second)
+	// Calculate lifetime indexing rate (docs per second of indexing time). The recent rate
+	// needs two samples over time and is filled in from the write metrics history.
 	if stats.Indexing.IndexTimeInMillis > 0 {
 		timeSeconds := float64(stats.Indexing.IndexTimeInMillis) / 1000.0
-		writeMetrics.IndexingRate = float64(stats.Indexing.IndexTotal) / timeSeconds
+		writeMetrics.LifetimeIndexingRate = float64(stats.Indexing.IndexTotal) / timeSeconds
 	}
 
 	// Calculate average document size
@@ -549,7 +673,7 @@ func (s *IndexService) generateWriteRecommendations(stats *models.IndexStatsDeta
 	}
 
 	// Low indexing rate
-	if metrics.IndexingRate < 100 {
+	if metrics.LifetimeIndexingRate < 100 {
 		recommendations = append(recommendations,
 			"Indexing rate appears low - consider increasing bulk batch sizes or refresh interval")
 	}
@@ -591,6 +715,12 @@ func (s *IndexService) OptimizeIndex(ctx context.Context, req *models.Optimizati
 		zap.String("optimize_for", req.OptimizeFor),
 		zap.String("workload", req.Workload))
 
+	if req.SoftDeletesRetentionLeasePeriod != "" {
+		if err := s.validateSoftDeletesSettings(ctx, nil, req.SoftDeletesRetentionLeasePeriod); err != nil {
+			return nil, fmt.Errorf("invalid soft-deletes configuration: %w", err)
+		}
+	}
+
 	// Get current settings
 	currentSettings, err := s.getCurrentIndexSettings(ctx, req.IndexName)
 	if err != nil {
@@ -675,6 +805,11 @@ func (s *IndexService) generateOptimizedSettings(req *models.OptimizationRequest
 		s.addWriteThroughputSettings(optimized, req) // Default to write optimization
 	}
 
+	// History retention is dynamic, so it can be tuned on existing indices
+	if req.SoftDeletesRetentionLeasePeriod != "" {
+		optimized["index.soft_deletes.retention_lease.period"] = req.SoftDeletesRetentionLeasePeriod
+	}
+
 	return optimized
 }
 
@@ -733,7 +868,7 @@ func (s *IndexService) calculateOptimizationChanges(current, recommended map[str
 
 	for key, newValue := range recommended {
 		currentValue := current[key]
-		if currentValue != newValue {
+		if !settingValuesEqual(currentValue, newValue) {
 			change := models.OptimizationChange{
 				Setting:  key,
 				OldValue: currentValue,
@@ -746,6 +881,9 @@ func (s *IndexService) calculateOptimizationChanges(current, recommended map[str
 		}
 	}
 
+	sort.Slice(changes, func(i, j int) bool {
+		return changes[i].Setting < changes[j].Setting
+	})
 	return changes
 }
 
@@ -762,6 +900,10 @@ func (s *IndexService) getOptimizationReason(setting string, value interface{})
 		return "Optimize segment count for workload characteristics"
 	case "index.codec":
 		return "Use best compression for storage efficiency"
+	case "index.soft_deletes.retention_lease.period":
+		return "Retain soft-deleted history for CCR and peer recovery; longer periods cost storage and slow merges"
+	case "index.number_of_replicas":
+		return "Every replica repeats each write; fewer replicas speed up bulk loads at the cost of redundancy"
 	default:
 		return "Performance optimization for write-heavy workload"
 	}
@@ -770,9 +912,10 @@ func (s *IndexService) getOptimizationReason(setting string, value interface{})
 // getOptimizationImpact returns the expected impact level
 func (s *IndexService) getOptimizationImpact(setting string) string {
 	switch setting {
-	case "index.refresh_interval", "index.translog.durability":
+	case "index.refresh_interval", "index.translog.durability", "index.number_of_replicas":
 		return "high"
-	case "index.translog.flush_threshold_size", "index.merge.policy.segments_per_tier":
+	case "index.translog.flush_threshold_size", "index.merge.policy.segments_per_tier",
+		"index.soft_deletes.retention_lease.period":
 		return "medium"
 	default:
 		return "low"
@@ -849,9 +992,9 @@ func (s *IndexService) applyOptimizedSettings(ctx context.Context, indexName str
 	}
 
 	res, err := s.esClient.Indices.PutSettings(
-		s.esClient.Indices.PutSettings.WithContext(ctx),
+		strings.NewReader(string(bodyBytes)),
 		s.esClient.Indices.PutSettings.WithIndex(indexName),
-		s.esClient.Indices.PutSettings.WithBody(strings.NewReader(string(bodyBytes))),
+		s.esClient.Indices.PutSettings.WithContext(ctx),
 	)
 	if err != nil {
 		return fmt.Errorf("failed to update settings: %w", err)
@@ -865,6 +1008,83 @@ func (s *IndexService) applyOptimizedSettings(ctx context.Context, indexName str
 	return nil
 }
 
+// validateSoftDeletesSettings checks soft-deletes settings against the cluster version
+func (s *IndexService) validateSoftDeletesSettings(ctx context.Context, enabled *bool, retentionLeasePeriod string) error {
+	if enabled == nil && retentionLeasePeriod == "" {
+		return nil
+	}
+
+	if retentionLeasePeriod != "" {
+		if !timeValuePattern.MatchString(retentionLeasePeriod) {
+			return fmt.Errorf("retention lease period %q is not a valid time value (e.g. 12h, 1d)", retentionLeasePeriod)
+		}
+		if enabled != nil && !*enabled {
+			return fmt.Errorf("retention lease period has no effect when soft-deletes are disabled")
+		}
+	}
+
+	major, minor, err := s.getClusterVersion(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to determine cluster version: %w", err)
+	}
+
+	// Soft-deletes arrived in 6.5, retention leases in 6.7; from 8.0 soft-deletes are mandatory
+	if enabled != nil && *enabled && (major < 6 || (major == 6 && minor < 5)) {
+		return fmt.Errorf("soft-deletes require Elasticsearch 6.5 or later (cluster is %d.%d)", major, minor)
+	}
+	if enabled != nil && !*enabled && major >= 8 {
+		return fmt.Errorf("soft-deletes cannot be disabled on Elasticsearch %d.%d", major, minor)
+	}
+	if retentionLeasePeriod != "" && (major < 6 || (major == 6 && minor < 7)) {
+		return fmt.Errorf("retention leases require Elasticsearch 6.7 or later (cluster is %d.%d)", major, minor)
+	}
+
+	return nil
+}
+
+// timeValuePattern matches Elasticsearch time values such as 30s or 12h
+var timeValuePattern = regexp.MustCompile(`^\d+(nanos|micros|ms|s|m|h|d)$`)
+
+// getClusterVersion returns the major and minor version of the connected cluster
+func (s *IndexService) getClusterVersion(ctx context.Context) (int, int, error) {
+	res, err := s.esClient.Info(
+		s.esClient.Info.WithContext(ctx),
+	)
+	if err != nil {
+		return 0, 0, err
+	}
+	defer res.Body.Close()
+
+	if res.IsError() {
+		return 0, 0, shared.ParseESError(res)
+	}
+
+	var info struct {
+		Version struct {
+			Number string `json:"number"`
+		} `json:"version"`
+	}
+	if err := shared.DecodeJSONResponse(res, &info); err != nil {
+		return 0, 0, err
+	}
+
+	parts := strings.SplitN(info.Version.Number, ".", 3)
+	if len(parts) < 2 {
+		return 0, 0, fmt.Errorf("unexpected version number %q", info.Version.Number)
+	}
+
+	major, err := strconv.Atoi(parts[0])
+	if err != nil {
+		return 0, 0, fmt.Errorf("unexpected version number %q", info.Version.Number)
+	}
+	minor, err := strconv.Atoi(parts[1])
+	if err != nil {
+		return 0, 0, fmt.Errorf("unexpected version number %q", info.Version.Number)
+	}
+
+	return major, minor, nil
+}
+
 // DeleteIndex deletes an index
 func (s *IndexService) DeleteIndex(ctx context.Context, indexName string) error {
 	s.logger.Info("Deleting index", zap.String("index_name", indexName))
@@ -886,28 +1106,97 @@ func (s *IndexService) DeleteIndex(ctx context.Context, indexName string) error
 	return nil
 }
 
-// ListIndices lists all indices with basic information
-func (s *IndexService) ListIndices(ctx context.Context) ([]models.IndexInfo, error) {
-	res, err := s.esClient.Cat.Indices(
+// ListIndicesOptions filters, sorts and pages ListIndices
+type ListIndicesOptions struct {
+	// Pattern limits the listing to matching index names, e.g. "logs-*"; empty lists all
+	Pattern string
+	// Sort orders by _cat/indices columns, e.g. "docs.count:desc" or "index,store.size:desc"
+	Sort string
+	// Offset skips that many indices; Limit caps the page, 0 returns the rest
+	Offset int
+	Limit  int
+}
+
+// ListIndices lists indices with basic information, returning one page and the total
+// number of matching indices. Filtering and sorting happen in Elasticsearch; _cat has
+// no paging, so the page is cut from the full listing.
+func (s *IndexService) ListIndices(ctx context.Context, opts ListIndicesOptions) ([]models.IndexInfo, int, error) {
+	options := []func(*esapi.CatIndicesRequest){
 		s.esClient.Cat.Indices.WithContext(ctx),
 		s.esClient.Cat.Indices.WithFormat("json"),
 		s.esClient.Cat.Indices.WithV(true),
-	)
+	}
+	if opts.Pattern != "" {
+		options = append(options, s.esClient.Cat.Indices.WithIndex(opts.Pattern))
+	}
+	if opts.Sort != "" {
+		options = append(options, s.esClient.Cat.Indices.WithS(strings.Split(opts.Sort, ",")...))
+	}
+
+	res, err := s.esClient.Cat.Indices(options...)
 	if err != nil {
-		return nil, fmt.Errorf("failed to list indices: %w", err)
+		return nil, 0, fmt.Errorf("failed to list indices: %w", err)
 	}
 	defer res.Body.Close()
 
 	if res.IsError() {
-		return nil, shared.ParseESError(res)
+		return nil, 0, shared.ParseESError(res)
+	}
+
+	var rows []catIndex
+	if err := shared.DecodeJSONResponse(res, &rows); err != nil {
+		return nil, 0, fmt.Errorf("failed to decode indices: %w", err)
+	}
+
+	total := len(rows)
+	if opts.Offset >= total {
+		return []models.IndexInfo{}, total, nil
+	}
+	rows = rows[opts.Offset:]
+	if opts.Limit > 0 && len(rows) > opts.Limit {
+		rows = rows[:opts.Limit]
 	}
 
-	var indices []models.IndexInfo
-	if err := shared.DecodeJSONResponse(res, &indices); err != nil {
-		return nil, fmt.Errorf("failed to decode indices: %w", err)
+	indices := make([]models.IndexInfo, len(rows))
+	for i, row := range rows {
+		indices[i] = row.indexInfo()
 	}
+	return indices, total, nil
+}
+
+// catIndex is a row of the _cat/indices API; cat APIs report every value as a string
+type catIndex struct {
+	Index            string `json:"index"`
+	UUID             string `json:"uuid"`
+	Health           string `json:"health"`
+	Status           string `json:"status"`
+	Primary          string `json:"pri"`
+	Replica          string `json:"rep"`
+	DocsCount        string `json:"docs.count"`
+	DocsDeleted      string `json:"docs.deleted"`
+	StoreSize        string `json:"store.size"`
+	PrimaryStoreSize string `json:"pri.store.size"`
+}
 
-	return indices, nil
+// indexInfo converts a cat row to the index info model. Counts of closed indices are
+// reported as null and stay zero.
+func (c catIndex) indexInfo() models.IndexInfo {
+	primary, _ := strconv.Atoi(c.Primary)
+	replica, _ := strconv.Atoi(c.Replica)
+	docsCount, _ := strconv.ParseInt(c.DocsCount, 10, 64)
+	docsDeleted, _ := strconv.ParseInt(c.DocsDeleted, 10, 64)
+	return models.IndexInfo{
+		IndexName:        c.Index,
+		UUID:             c.UUID,
+		Health:           c.Health,
+		Status:           c.Status,
+		Primary:          primary,
+		Replica:          replica,
+		DocsCount:        docsCount,
+		DocsDeleted:      docsDeleted,
+		StoreSize:        c.StoreSize,
+		PrimaryStoreSize: c.PrimaryStoreSize,
+	}
 }
 
 // generateRequestID generates a unique request ID
@@ -1,73 +1,125 @@
 package services
 
 import (
+	"bufio"
+	"bytes"
 	"context"
 	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
 	"strings"
 	"testing"
 	"time"
 
-	"github.com/elastic/go-elasticsearch/v8"
-	"github.com/elastic/go-elasticsearch/v8/esapi"
 	"go.uber.org/zap"
 
 	"github.com/saif-islam/es-playground/projects/index-explorer/internal/models"
 	"github.com/saif-islam/es-playground/shared"
 )
 
-// Mock Elasticsearch client for testing
-type mockESClient struct {
-	responses map[string]string
-}
+// newFakeESClient returns a client for a fake Elasticsearch answering with handler. The
+// product check headers are already set, so handlers only write the body.
+func newFakeESClient(tb testing.TB, handler http.HandlerFunc) *shared.ESClient {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("X-Elastic-Product", "Elasticsearch")
+		w.Header().Set("Content-Type", "application/json")
+		handler(w, r)
+	}))
+	tb.Cleanup(server.Close)
 
-func (m *mockESClient) Indices() *elasticsearch.Indices {
-	return &elasticsearch.Indices{}
+	client, err := shared.NewESClient(&shared.ESConfig{URLs: []string{server.URL}}, zap.NewNop())
+	if err != nil {
+		tb.Fatalf("Failed to create client: %v", err)
+	}
+	return client
 }
 
-func (m *mockESClient) Info(o ...func(*esapi.InfoRequest)) (*esapi.Response, error) {
-	return &esapi.Response{
-		StatusCode: 200,
-		Body:       strings.NewReader(`{"version":{"number":"8.11.1"}}`),
-	}, nil
+// newMockESClient returns a client for a fake single-node cluster that accepts every
+// index, settings and bulk request, for tests of the service logic around them
+func newMockESClient(tb testing.TB) *shared.ESClient {
+	return newFakeESClient(tb, mockElasticsearch)
 }
 
-func (m *mockESClient) Search(o ...func(*esapi.SearchRequest)) (*esapi.Response, error) {
-	return &esapi.Response{
-		StatusCode: 200,
-		Body:       strings.NewReader(`{"hits":{"total":{"value":0}}}`),
-	}, nil
-}
+// mockElasticsearch answers the requests the index and document services send like a
+// healthy 8.11 cluster. Anything else gets a 404 so an unexpected request fails the test.
+func mockElasticsearch(w http.ResponseWriter, r *http.Request) {
+	parts := strings.Split(strings.Trim(r.URL.Path, "/"), "/")
 
-func (m *mockESClient) WaitForCluster(ctx context.Context, status string, timeout time.Duration) error {
-	return nil
+	switch {
+	case r.URL.Path == "/" && (r.Method == http.MethodGet || r.Method == http.MethodHead):
+		w.Write([]byte(`{"version":{"number":"8.11.1"}}`))
+	case parts[len(parts)-1] == "_bulk":
+		writeMockBulkResponse(w, r)
+	case parts[0] == "_cluster" && len(parts) > 1 && parts[1] == "health":
+		w.Write([]byte(`{"status":"green","number_of_nodes":1,"active_shards":1,"unassigned_shards":0}`))
+	case len(parts) >= 2 && parts[1] == "_settings" && r.Method == http.MethodGet:
+		fmt.Fprintf(w, `{%q:{"settings":{"index":{"number_of_shards":"1","number_of_replicas":"1","refresh_interval":"1s"}}}}`, parts[0])
+	case len(parts) == 2 && parts[1] == "_settings":
+		w.Write([]byte(`{"acknowledged":true}`))
+	case len(parts) == 2 && parts[1] == "_stats":
+		fmt.Fprintf(w, `{"indices":{%q:{"total":{"docs":{"count":1000},"store":{"size_in_bytes":512000},"indexing":{"index_total":1000,"index_time_in_millis":500},"segments":{"count":12}}}}}`, parts[0])
+	case len(parts) == 1 && parts[0] != "" && !strings.HasPrefix(parts[0], "_") && r.Method == http.MethodPut:
+		fmt.Fprintf(w, `{"acknowledged":true,"shards_acknowledged":true,"index":%q}`, parts[0])
+	default:
+		w.WriteHeader(http.StatusNotFound)
+		fmt.Fprintf(w, `{"error":{"type":"resource_not_found_exception","reason":"no handler for %s %s"},"status":404}`, r.Method, r.URL.Path)
+	}
 }
 
-func (m *mockESClient) Ping() error {
-	return nil
-}
+// writeMockBulkResponse reports every action of a bulk request as written
+func writeMockBulkResponse(w http.ResponseWriter, r *http.Request) {
+	var items []map[string]interface{}
+	expectSource := false
 
-func (m *mockESClient) GetClusterHealth() (*shared.ClusterHealth, error) {
-	return &shared.ClusterHealth{
-		Status:               "green",
-		NumberOfNodes:        1,
-		ActivePrimaryShards:  1,
-		ActiveShards:         1,
-		RelocatingShards:     0,
-		InitializingShards:   0,
-		UnassignedShards:     0,
-		DelayedUnassignedShards: 0,
-	}, nil
-}
+	scanner := bufio.NewScanner(r.Body)
+	scanner.Buffer(make([]byte, 1024*1024), 64*1024*1024)
+	for scanner.Scan() {
+		if len(bytes.TrimSpace(scanner.Bytes())) == 0 {
+			continue
+		}
+		if expectSource {
+			expectSource = false
+			continue
+		}
 
-func newMockESClient() shared.ESClientInterface {
-	return &mockESClient{
-		responses: make(map[string]string),
+		var action map[string]struct {
+			Index string `json:"_index"`
+			ID    string `json:"_id"`
+		}
+		if err := json.Unmarshal(scanner.Bytes(), &action); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			fmt.Fprintf(w, `{"error":{"type":"illegal_argument_exception","reason":%q},"status":400}`, err.Error())
+			return
+		}
+		for name, meta := range action {
+			status, result := http.StatusCreated, "created"
+			switch name {
+			case "update":
+				status, result = http.StatusOK, "updated"
+			case "delete":
+				status, result = http.StatusOK, "deleted"
+			}
+			id := meta.ID
+			if id == "" {
+				id = fmt.Sprintf("generated-%d", len(items))
+			}
+			items = append(items, map[string]interface{}{
+				name: map[string]interface{}{"_index": meta.Index, "_id": id, "_version": 1, "result": result, "status": status},
+			})
+			expectSource = name != "delete"
+		}
 	}
+
+	json.NewEncoder(w).Encode(map[string]interface{}{"took": 1, "errors": false, "items": items})
 }
 
-func TestIndexService_CreateWriteOptimizedIndex(t *testing.T) {
+func TestIndexService_CreateIndex(t *testing.T) {
 	logger := zap.NewNop()
-	esClient := newMockESClient()
+	esClient := newMockESClient(t)
 	service := NewIndexService(esClient, logger)
 
 	ctx := context.Background()
@@ -112,7 +164,7 @@ func TestIndexService_CreateWriteOptimizedIndex(t *testing.T) {
 
 	for _, tc := range testCases {
 		t.Run(tc.name, func(t *testing.T) {
-			response, err := service.CreateWriteOptimizedIndex(ctx, tc.request)
+			response, err := service.CreateIndex(ctx, tc.request)
 			
 			if tc.expectError {
 				if err == nil {
@@ -136,8 +188,8 @@ func TestIndexService_CreateWriteOptimizedIndex(t *testing.T) {
 				t.Errorf("Expected index name %s, got %s", tc.request.IndexName, response.IndexName)
 			}
 			
-			if !response.WriteOptimized {
-				t.Errorf("Expected write optimized to be true")
+			if !response.Created || len(response.Optimizations) == 0 {
+				t.Errorf("Expected a created index with write optimizations, got %+v", response)
 			}
 		})
 	}
@@ -145,7 +197,7 @@ func TestIndexService_CreateWriteOptimizedIndex(t *testing.T) {
 
 func TestIndexService_OptimizeIndex(t *testing.T) {
 	logger := zap.NewNop()
-	esClient := newMockESClient()
+	esClient := newMockESClient(t)
 	service := NewIndexService(esClient, logger)
 
 	ctx := context.Background()
@@ -171,43 +223,35 @@ func TestIndexService_OptimizeIndex(t *testing.T) {
 	}
 	
 	// Verify optimization was calculated
-	if len(response.Recommendations) == 0 {
-		t.Errorf("Expected recommendations but got none")
+	if len(response.OptimizationsApplied) == 0 {
+		t.Errorf("Expected recommended changes but got none")
 	}
 	
-	if response.OptimizationScore == 0 {
-		t.Errorf("Expected optimization score but got 0")
+	if response.PerformanceImpact == nil || response.Applied {
+		t.Errorf("Expected an impact estimate without applying changes, got %+v", response)
 	}
 }
 
-func TestIndexService_GetIndexRecommendations(t *testing.T) {
-	logger := zap.NewNop()
-	esClient := newMockESClient()
-	service := NewIndexService(esClient, logger)
+func TestIndexService_GenerateWriteRecommendations(t *testing.T) {
+	service := NewIndexService(nil, zap.NewNop())
 
-	ctx := context.Background()
-	
-	response, err := service.GetIndexRecommendations(ctx, "test-index", "bulk_write", "large")
-	if err != nil {
-		t.Errorf("Unexpected error: %v", err)
-		return
-	}
-	
-	if response == nil {
-		t.Errorf("Expected response but got nil")
-		return
-	}
-	
-	// Verify recommendations were generated
-	if len(response.Recommendations) == 0 {
-		t.Errorf("Expected recommendations but got none")
+	stats := &models.IndexStatsDetails{}
+	stats.Segments.Count = 120
+	stats.Indexing.IsThrottled = true
+
+	metrics := &models.WriteMetrics{LifetimeIndexingRate: 5000, OptimizationScore: service.calculateOptimizationScore(stats)}
+	recommendations := service.generateWriteRecommendations(stats, metrics)
+
+	// Too many segments, throttling and the low score they cause
+	if len(recommendations) != 3 {
+		t.Errorf("Expected 3 recommendations, got %v", recommendations)
 	}
 }
 
 // Benchmark tests for write optimization
-func BenchmarkIndexService_CreateWriteOptimizedIndex(b *testing.B) {
+func BenchmarkIndexService_CreateIndex(b *testing.B) {
 	logger := zap.NewNop()
-	esClient := newMockESClient()
+	esClient := newMockESClient(b)
 	service := NewIndexService(esClient, logger)
 
 	ctx := context.Background()
@@ -222,8 +266,8 @@ func BenchmarkIndexService_CreateWriteOptimizedIndex(b *testing.B) {
 
 	b.ResetTimer()
 	for i := 0; i < b.N; i++ {
-		request.IndexName = "benchmark-index-" + string(rune(i))
-		_, err := service.CreateWriteOptimizedIndex(ctx, request)
+		request.IndexName = fmt.Sprintf("benchmark-index-%d", i)
+		_, err := service.CreateIndex(ctx, request)
 		if err != nil {
 			b.Errorf("Benchmark failed: %v", err)
 		}
@@ -232,7 +276,7 @@ func BenchmarkIndexService_CreateWriteOptimizedIndex(b *testing.B) {
 
 func BenchmarkIndexService_OptimizeIndex(b *testing.B) {
 	logger := zap.NewNop()
-	esClient := newMockESClient()
+	esClient := newMockESClient(b)
 	service := NewIndexService(esClient, logger)
 
 	ctx := context.Background()
@@ -257,7 +301,7 @@ func BenchmarkIndexService_OptimizeIndex(b *testing.B) {
 // Test helper functions
 func TestApplyWriteOptimizations(t *testing.T) {
 	logger := zap.NewNop()
-	esClient := newMockESClient()
+	esClient := newMockESClient(t)
 	service := NewIndexService(esClient, logger)
 
 	settings := &models.IndexSettings{
@@ -278,7 +322,7 @@ func TestApplyWriteOptimizations(t *testing.T) {
 				IngestionRate: "high",
 			},
 			expectedRefresh: "30s",
-			expectedReplicas: 0,
+			expectedReplicas: 1,
 		},
 		{
 			name: "medium ingestion rate", 
@@ -286,7 +330,7 @@ func TestApplyWriteOptimizations(t *testing.T) {
 				IngestionRate: "medium",
 			},
 			expectedRefresh: "5s",
-			expectedReplicas: 0,
+			expectedReplicas: 1,
 		},
 		{
 			name: "low ingestion rate",
@@ -300,8 +344,8 @@ func TestApplyWriteOptimizations(t *testing.T) {
 
 	for _, tc := range testCases {
 		t.Run(tc.name, func(t *testing.T) {
-			// Reset settings
-			settings.RefreshInterval = "1s"
+			// Reset settings; only an unset refresh interval is tuned and replicas are kept
+			settings.RefreshInterval = ""
 			settings.NumberOfReplicas = 1
 			
 			service.applyWriteOptimizations(settings, tc.request)
@@ -317,10 +361,328 @@ func TestApplyWriteOptimizations(t *testing.T) {
 	}
 }
 
+func TestApplyAllocationFilters(t *testing.T) {
+	service := &IndexService{logger: zap.NewNop()}
+
+	settings := &models.IndexSettings{
+		Additional: make(map[string]interface{}),
+	}
+
+	service.applyAllocationFilters(settings, &models.AllocationFilters{
+		Require: map[string]string{"data": "hot"},
+		Exclude: map[string]string{"_name": "node-3"},
+	})
+
+	if settings.Additional["index.routing.allocation.require.data"] != "hot" {
+		t.Errorf("Expected require filter for data=hot, got %v", settings.Additional["index.routing.allocation.require.data"])
+	}
+
+	if settings.Additional["index.routing.allocation.exclude._name"] != "node-3" {
+		t.Errorf("Expected exclude filter for _name=node-3, got %v", settings.Additional["index.routing.allocation.exclude._name"])
+	}
+
+	err := validateAllocationFilters(&models.AllocationFilters{
+		Include: map[string]string{"zone": ""},
+	})
+	if err == nil {
+		t.Errorf("Expected error for allocation filter without a value")
+	}
+}
+
+func TestIndexService_PreviewOptimizedSettings(t *testing.T) {
+	service := &IndexService{logger: zap.NewNop()}
+
+	preview := service.PreviewOptimizedSettings(context.Background(), &models.IndexRequest{
+		IndexName:      "text-corpus",
+		WriteOptimized: true,
+		Mappings:       map[string]interface{}{"properties": map[string]interface{}{}},
+	})
+
+	if !preview.Valid || len(preview.Problems) != 0 {
+		t.Errorf("Expected a valid preview, got problems %v", preview.Problems)
+	}
+
+	if preview.Body["settings"] != preview.Settings || preview.Body["mappings"] == nil {
+		t.Errorf("Expected the create body to carry the previewed settings and mappings, got %v", preview.Body)
+	}
+
+	if len(preview.Optimizations) == 0 {
+		t.Errorf("Expected write optimizations to be explained")
+	}
+
+	invalid := service.PreviewOptimizedSettings(context.Background(), &models.IndexRequest{
+		Allocation: &models.AllocationFilters{Require: map[string]string{"zone": ""}},
+	})
+	if invalid.Valid || len(invalid.Problems) != 1 {
+		t.Errorf("Expected the invalid allocation filter to be reported, got %v", invalid.Problems)
+	}
+}
+
+func TestBuildIndexTemplateBody(t *testing.T) {
+	service := &IndexService{logger: zap.NewNop()}
+
+	req := &models.IndexTemplateRequest{
+		TemplateName:   "daily-logs",
+		IndexPatterns:  []string{"logs-*"},
+		Mappings:       map[string]interface{}{"properties": map[string]interface{}{}},
+		Priority:       200,
+		WriteOptimized: true,
+	}
+
+	// Matching indices get the settings CreateIndex would apply
+	settings := service.buildOptimizedSettings(templateIndexRequest(req))
+	direct := service.buildOptimizedSettings(&models.IndexRequest{IndexName: "logs-2024.01.01", WriteOptimized: true})
+	if settings.RefreshInterval != direct.RefreshInterval || settings.NumberOfReplicas != direct.NumberOfReplicas {
+		t.Errorf("Expected template settings to match direct creation, got %+v and %+v", settings, direct)
+	}
+
+	body := buildIndexTemplateBody(req, settings)
+	if patterns, ok := body["index_patterns"].([]string); !ok || len(patterns) != 1 || patterns[0] != "logs-*" {
+		t.Errorf("Expected the index patterns in the body, got %v", body["index_patterns"])
+	}
+	if body["priority"] != 200 {
+		t.Errorf("Expected priority 200, got %v", body["priority"])
+	}
+	if _, ok := body["version"]; ok {
+		t.Errorf("Expected an unset version to be omitted")
+	}
+
+	template, ok := body["template"].(map[string]interface{})
+	if !ok || template["settings"] != settings || template["mappings"] == nil {
+		t.Errorf("Expected settings and mappings under template, got %v", body["template"])
+	}
+}
+
+func TestBuildReindexBody(t *testing.T) {
+	body := buildReindexBody("old-index", "new-index", ReindexOptions{})
+	source := body["source"].(map[string]interface{})
+	dest := body["dest"].(map[string]interface{})
+	if source["index"] != "old-index" || dest["index"] != "new-index" {
+		t.Errorf("Expected old-index copied into new-index, got %v", body)
+	}
+	if _, ok := source["query"]; ok {
+		t.Errorf("Expected no query without a filter")
+	}
+	if _, ok := dest["pipeline"]; ok {
+		t.Errorf("Expected no pipeline when none is set")
+	}
+
+	query := map[string]interface{}{"term": map[string]interface{}{"status": "active"}}
+	body = buildReindexBody("old-index", "new-index", ReindexOptions{Query: query, Pipeline: "enrich"})
+	if body["source"].(map[string]interface{})["query"] == nil {
+		t.Errorf("Expected the query filter on the source")
+	}
+	if body["dest"].(map[string]interface{})["pipeline"] != "enrich" {
+		t.Errorf("Expected the ingest pipeline on the destination, got %v", body["dest"])
+	}
+
+	service := &IndexService{logger: zap.NewNop()}
+	if _, err := service.Reindex(context.Background(), "same", "same", ReindexOptions{}); err == nil {
+		t.Errorf("Expected reindexing an index into itself to be rejected")
+	}
+}
+
+func TestBuildAliasActionsBody(t *testing.T) {
+	writeIndex := true
+	body, err := buildAliasActionsBody([]models.AliasAction{
+		{Action: "remove", Index: "logs-v1", Alias: "logs"},
+		{Action: "add", Index: "logs-v2", Alias: "logs", IsWriteIndex: &writeIndex},
+	})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	// Both steps go in one request, in order, so the swap is atomic
+	steps := body["actions"].([]map[string]interface{})
+	if len(steps) != 2 {
+		t.Fatalf("Expected 2 actions, got %d", len(steps))
+	}
+	if remove, ok := steps[0]["remove"].(map[string]interface{}); !ok || remove["index"] != "logs-v1" {
+		t.Errorf("Expected the remove first, got %v", steps[0])
+	}
+	if add, ok := steps[1]["add"].(map[string]interface{}); !ok || add["index"] != "logs-v2" || add["is_write_index"] != true {
+		t.Errorf("Expected the add of the write index second, got %v", steps[1])
+	}
+
+	invalid := [][]models.AliasAction{
+		nil,
+		{{Action: "rename", Index: "logs-v1", Alias: "logs"}},
+		{{Action: "add", Index: "logs-v1"}},
+		{{Action: "remove", Index: "logs-v1", Alias: "logs", Routing: "1"}},
+	}
+	for _, actions := range invalid {
+		if _, err := buildAliasActionsBody(actions); err == nil {
+			t.Errorf("Expected %v to be rejected", actions)
+		}
+	}
+}
+
+func TestMatchIndexTemplate(t *testing.T) {
+	templates := []models.IndexTemplateInfo{
+		{Name: "everything", IndexPatterns: []string{"*"}},
+		{Name: "logs", IndexPatterns: []string{"metrics-*", "logs-*"}, Priority: 100},
+		{Name: "app-logs", IndexPatterns: []string{"logs-app-*-000*"}, Priority: 200},
+	}
+
+	tests := map[string]string{
+		"logs-web-000002":    "logs",
+		"logs-app-eu-000002": "app-logs",
+		"orders-000002":      "everything",
+	}
+	for index, expected := range tests {
+		if template := matchIndexTemplate(templates, index); template == nil || template.Name != expected {
+			t.Errorf("Expected %s to match %s, got %v", index, expected, template)
+		}
+	}
+
+	if template := matchIndexTemplate(templates[1:], "orders-000002"); template != nil {
+		t.Errorf("Expected no template for orders-000002, got %s", template.Name)
+	}
+}
+
+func TestInheritableSettings(t *testing.T) {
+	inherited := inheritableSettings(map[string]interface{}{
+		"index.number_of_shards":               "3",
+		"index.refresh_interval":               "30s",
+		"index.translog.durability":            "async",
+		"index.uuid":                           "abc",
+		"index.creation_date":                  "1700000000000",
+		"index.provided_name":                  "logs-000001",
+		"index.merge.policy.segments_per_tier": "20",
+	})
+
+	if len(inherited) != 4 {
+		t.Errorf("Expected 4 inherited settings, got %v", inherited)
+	}
+	if _, ok := inherited["index.uuid"]; ok {
+		t.Errorf("Expected index identity settings to stay behind")
+	}
+
+	// Settings left behind by a bulk load don't carry over
+	inherited = inheritableSettings(map[string]interface{}{
+		"index.refresh_interval":   "-1",
+		"index.number_of_replicas": "0",
+		"index.number_of_shards":   "3",
+	})
+	if len(inherited) != 1 || inherited["index.number_of_shards"] != "3" {
+		t.Errorf("Expected a disabled refresh and zero replicas to stay behind, got %v", inherited)
+	}
+
+	if err := validateRolloverConditions(models.RolloverConditions{MaxSize: "50GB", MaxAge: "7d"}); err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+	if err := validateRolloverConditions(models.RolloverConditions{MaxSize: "lots"}); err == nil {
+		t.Errorf("Expected an invalid max_size to be rejected")
+	}
+}
+
+func TestAdviseShards(t *testing.T) {
+	// A single 200GB index splits into 40GB shards
+	advice := adviseShards(200, 0, 0)
+	if advice.RecommendedPrimaryShards != 5 || advice.ProjectedShardSizeGB != 40 || advice.Rollover != nil {
+		t.Errorf("Expected 5 primaries of 40GB without rollover, got %d of %.1fGB", advice.RecommendedPrimaryShards, advice.ProjectedShardSizeGB)
+	}
+
+	// 5GB a day for 30 days should roll over every 8 days rather than create daily indices
+	advice = adviseShards(0, 30, 5)
+	if advice.RetainedSizeGB != 150 {
+		t.Errorf("Expected 150GB retained, got %.1f", advice.RetainedSizeGB)
+	}
+	if advice.RecommendedPrimaryShards != 1 || advice.Rollover == nil || advice.Rollover.MaxAge != "8d" {
+		t.Errorf("Expected one shard rolled over every 8 days, got %d shards and %+v", advice.RecommendedPrimaryShards, advice.Rollover)
+	}
+	if advice.RetainedIndices != 4 || advice.TotalShards != 8 {
+		t.Errorf("Expected 4 retained indices and 8 shards, got %d and %d", advice.RetainedIndices, advice.TotalShards)
+	}
+	if len(advice.Warnings) == 0 {
+		t.Errorf("Expected a warning that the volume presets would over-shard 40GB indices")
+	}
+
+	// Daily indices are fine once a day of data fills a shard
+	advice = adviseShards(0, 7, 90)
+	if advice.RecommendedPrimaryShards != 3 || advice.Rollover.MaxAge != "1d" || advice.RetainedIndices != 7 {
+		t.Errorf("Expected 7 daily indices of 3 primaries, got %d indices of %d", advice.RetainedIndices, advice.RecommendedPrimaryShards)
+	}
+
+	if warnings := shardCapacityWarnings(2, 1500, 600); len(warnings) != 1 || !strings.Contains(warnings[0], "exceed") {
+		t.Errorf("Expected 1050 shards per node to exceed the limit, got %v", warnings)
+	}
+	if warnings := shardCapacityWarnings(3, 100, 40); len(warnings) != 0 {
+		t.Errorf("Expected no capacity warning, got %v", warnings)
+	}
+}
+
+func TestBuildSlowLogSettings(t *testing.T) {
+	settings, err := buildSlowLogSettings(&models.SlowLogThresholds{
+		Query:    &models.SlowLogLevels{Warn: "2s", Info: "500ms"},
+		Indexing: &models.SlowLogLevels{Warn: "-1"},
+	})
+	if err != nil {
+		t.Errorf("Unexpected error: %v", err)
+		return
+	}
+
+	expected := map[string]string{
+		"search.slowlog.threshold.query.warn":   "2s",
+		"search.slowlog.threshold.query.info":   "500ms",
+		"indexing.slowlog.threshold.index.warn": "-1",
+	}
+	if len(settings) != len(expected) {
+		t.Errorf("Expected %d settings, got %d", len(expected), len(settings))
+	}
+	for key, value := range expected {
+		if settings[key] != value {
+			t.Errorf("Expected %s=%s, got %v", key, value, settings[key])
+		}
+	}
+
+	if _, err := buildSlowLogSettings(&models.SlowLogThresholds{
+		Fetch: &models.SlowLogLevels{Warn: "slow"},
+	}); err == nil {
+		t.Errorf("Expected error for invalid threshold")
+	}
+}
+
+func TestEvaluateCompliance(t *testing.T) {
+	current := map[string]interface{}{
+		"index.refresh_interval":    "1s",
+		"index.translog.durability": "REQUEST",
+		"index.codec":               "default",
+		"index.number_of_replicas":  "2",
+	}
+
+	report := evaluateCompliance("app-logs", baselineProfiles["logging"], current)
+
+	if report.Compliant {
+		t.Errorf("Expected index to be non-compliant")
+	}
+
+	if report.Score != 25.0 {
+		t.Errorf("Expected score 25, got %f", report.Score)
+	}
+
+	if report.Fixes["index.refresh_interval"] != "30s" {
+		t.Errorf("Expected refresh interval fix, got %v", report.Fixes["index.refresh_interval"])
+	}
+
+	if _, ok := report.Fixes["index.codec"]; ok {
+		t.Errorf("Static setting index.codec should not be offered as a live fix")
+	}
+}
+
+func TestCheckCompliance_UnknownProfile(t *testing.T) {
+	service := NewIndexService(nil, zap.NewNop())
+
+	_, err := service.CheckCompliance(context.Background(), "app-logs", "archival")
+	if !errors.Is(err, ErrUnknownComplianceProfile) {
+		t.Errorf("Expected ErrUnknownComplianceProfile, got %v", err)
+	}
+}
+
 // Performance test with various document sizes
 func BenchmarkWriteOptimizations(b *testing.B) {
 	logger := zap.NewNop()
-	esClient := newMockESClient()
+	esClient := newMockESClient(b)
 	service := NewIndexService(esClient, logger)
 
 	ctx := context.Background()
@@ -342,7 +704,7 @@ func BenchmarkWriteOptimizations(b *testing.B) {
 				
 				b.ResetTimer()
 				for i := 0; i < b.N; i++ {
-					_, err := service.CreateWriteOptimizedIndex(ctx, request)
+					_, err := service.CreateIndex(ctx, request)
 					if err != nil {
 						b.Errorf("Benchmark failed: %v", err)
 					}
@@ -350,4 +712,585 @@ func BenchmarkWriteOptimizations(b *testing.B) {
 			})
 		}
 	}
-}
\ No newline at end of file
+}
+func TestValidateForceMerge(t *testing.T) {
+	if err := validateForceMerge(1, false); err != nil {
+		t.Errorf("Expected merging to one segment to be valid, got %v", err)
+	}
+	if err := validateForceMerge(0, true); err != nil {
+		t.Errorf("Expected expunging deletes to be valid, got %v", err)
+	}
+	if err := validateForceMerge(1, true); err == nil {
+		t.Errorf("Expected a segment target with only_expunge_deletes to be rejected")
+	}
+	if err := validateForceMerge(-1, false); err == nil {
+		t.Errorf("Expected a negative segment target to be rejected")
+	}
+}
+
+func TestIndexService_InferMapping(t *testing.T) {
+	service := &IndexService{logger: zap.NewNop()}
+
+	var sample map[string]interface{}
+	if err := json.Unmarshal([]byte(`{
+		"_id": "doc-1",
+		"sku": "ABC-123",
+		"title": "A write-optimized laptop bag",
+		"price": 49.99,
+		"stock": 12,
+		"active": true,
+		"created_at": "2024-05-01T10:00:00Z",
+		"tags": ["travel", "bags"],
+		"seller": {"name": "Acme Outdoor Supplies", "rating": 4.5},
+		"discontinued_on": null
+	}`), &sample); err != nil {
+		t.Fatalf("Failed to parse sample: %v", err)
+	}
+
+	mapping, skipped := service.InferMapping(sample, false)
+	properties := mapping["properties"].(map[string]interface{})
+
+	expectedTypes := map[string]string{
+		"sku":        "keyword",
+		"title":      "text",
+		"price":      "double",
+		"stock":      "long",
+		"active":     "boolean",
+		"created_at": "date",
+		"tags":       "keyword",
+	}
+	for field, expected := range expectedTypes {
+		got, _ := properties[field].(map[string]interface{})["type"].(string)
+		if got != expected {
+			t.Errorf("Expected %s to be mapped as %s, got %v", field, expected, properties[field])
+		}
+	}
+
+	if _, ok := properties["_id"]; ok {
+		t.Errorf("Expected the _id metadata field to be left out")
+	}
+	if _, ok := properties["title"].(map[string]interface{})["fields"]; !ok {
+		t.Errorf("Expected text fields to get a keyword sub-field")
+	}
+	seller := properties["seller"].(map[string]interface{})["properties"].(map[string]interface{})
+	if seller["rating"].(map[string]interface{})["type"] != "double" {
+		t.Errorf("Expected nested objects to be mapped, got %v", seller)
+	}
+	if len(skipped) != 1 || skipped[0] != "discontinued_on" {
+		t.Errorf("Expected the null field to be skipped, got %v", skipped)
+	}
+
+	textHeavy, _ := service.InferMapping(sample, true)
+	title := textHeavy["properties"].(map[string]interface{})["title"].(map[string]interface{})
+	if _, ok := title["fields"]; ok {
+		t.Errorf("Expected text-heavy mappings to skip keyword sub-fields, got %v", title)
+	}
+}
+
+func TestShrinkPlanning(t *testing.T) {
+	if err := validateShrink(6, 4); !errors.Is(err, ErrInvalidShrink) {
+		t.Errorf("Expected a non-factor target to be rejected, got %v", err)
+	}
+	if err := validateShrink(5, 5); !errors.Is(err, ErrInvalidShrink) {
+		t.Errorf("Expected an equal shard count to be rejected, got %v", err)
+	}
+	if err := validateShrink(6, 3); err != nil {
+		t.Errorf("Expected shrinking 6 shards to 3 to be valid, got %v", err)
+	}
+
+	copies := []shardCopy{
+		{Shard: "0", PriRep: "p", State: "STARTED", Node: "node-a"},
+		{Shard: "1", PriRep: "p", State: "STARTED", Node: "node-b"},
+		{Shard: "2", PriRep: "p", State: "STARTED", Node: "node-b"},
+		{Shard: "0", PriRep: "r", State: "STARTED", Node: "node-b"},
+		{Shard: "1", PriRep: "r", State: "RELOCATING", Node: "node-a"},
+	}
+	if node := pickShrinkNode(copies); node != "node-b" {
+		t.Errorf("Expected node-b, which holds every shard, got %q", node)
+	}
+	if missing := missingShards(copies, "node-a", 3); !reflect.DeepEqual(missing, []int{1, 2}) {
+		t.Errorf("Expected shards [1 2] missing from node-a, got %v", missing)
+	}
+	if missing := missingShards(copies, "node-b", 3); len(missing) != 0 {
+		t.Errorf("Expected no shards missing from node-b, got %v", missing)
+	}
+}
+
+func TestSplitPlanning(t *testing.T) {
+	routing := []struct {
+		shards   int
+		expected int
+	}{
+		{1, 1024},
+		{2, 1024},
+		{5, 640},
+		{600, 1200},
+	}
+	for _, tc := range routing {
+		if got := defaultRoutingShards(tc.shards); got != tc.expected {
+			t.Errorf("Expected %d routing shards for %d shards, got %d", tc.expected, tc.shards, got)
+		}
+	}
+
+	if err := validateSplit(5, 640, 10); err != nil {
+		t.Errorf("Expected splitting 5 shards to 10 to be valid, got %v", err)
+	}
+	if err := validateSplit(5, 640, 15); !errors.Is(err, ErrInvalidSplit) {
+		t.Errorf("Expected a target not dividing the routing shards to be rejected, got %v", err)
+	}
+	if err := validateSplit(4, 1024, 6); !errors.Is(err, ErrInvalidSplit) {
+		t.Errorf("Expected a target that isn't a multiple of the source to be rejected, got %v", err)
+	}
+	if err := validateSplit(4, 1024, 4); !errors.Is(err, ErrInvalidSplit) {
+		t.Errorf("Expected an equal shard count to be rejected, got %v", err)
+	}
+}
+
+func TestRestoreIndexSettings(t *testing.T) {
+	service := &IndexService{logger: zap.NewNop()}
+	settings := service.buildOptimizedSettings(&models.IndexRequest{
+		IndexName:       "logs-restored",
+		WriteOptimized:  true,
+		ExpectedVolume:  "high",
+		ExpectedDocSize: "large",
+		IngestionRate:   "high",
+	})
+
+	indexSettings, err := restoreIndexSettings(settings)
+	if err != nil {
+		t.Fatalf("Expected restore settings, got %v", err)
+	}
+
+	if indexSettings["index.refresh_interval"] != "30s" {
+		t.Errorf("Expected refresh_interval under index., got %v", indexSettings)
+	}
+	if indexSettings["index.translog.durability"] != "async" {
+		t.Errorf("Expected async translog durability, got %v", indexSettings["index.translog.durability"])
+	}
+	for key := range indexSettings {
+		if key == "index.number_of_shards" || key == "additional" || strings.HasPrefix(key, "index.mapping.") {
+			t.Errorf("Expected %s to be left out of restore settings", key)
+		}
+	}
+
+	if got := renameReplacement("logs$1"); got != `logs\$1` {
+		t.Errorf("Expected $ to be escaped in the rename replacement, got %s", got)
+	}
+}
+
+func TestSettingsDiffChanges(t *testing.T) {
+	service := &IndexService{logger: zap.NewNop()}
+	current := map[string]interface{}{
+		"index.refresh_interval":    "1s",
+		"index.number_of_replicas":  "1",
+		"index.translog.durability": "request",
+	}
+	proposed := flattenIndexSettings(map[string]interface{}{
+		"refresh_interval":   "1s",
+		"number_of_replicas": 0,
+		"index": map[string]interface{}{
+			"translog": map[string]interface{}{"durability": "async"},
+		},
+	})
+
+	changes := service.calculateOptimizationChanges(current, proposed)
+	if len(changes) != 2 {
+		t.Fatalf("Expected 2 changes, got %+v", changes)
+	}
+	if changes[0].Setting != "index.number_of_replicas" || changes[0].OldValue != "1" || changes[0].NewValue != 0 {
+		t.Errorf("Expected the replica change first, got %+v", changes[0])
+	}
+	if changes[1].Setting != "index.translog.durability" || changes[1].Impact != "high" {
+		t.Errorf("Expected a high impact durability change, got %+v", changes[1])
+	}
+}
+
+func TestCreateIndexDryRun(t *testing.T) {
+	// No client: a dry run must never reach the cluster
+	service := &IndexService{logger: zap.NewNop()}
+
+	response, err := service.CreateIndex(context.Background(), &models.IndexRequest{
+		IndexName:      "corpus",
+		WriteOptimized: true,
+		TextHeavy:      true,
+		ExpectedVolume: "high",
+		DryRun:         true,
+	})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if response.Created || response.Acknowledged || !response.DryRun {
+		t.Errorf("Expected an uncreated dry run, got %+v", response)
+	}
+	if response.Body["settings"] != response.Settings || len(response.Optimizations) == 0 {
+		t.Errorf("Expected the create body with the computed settings and optimizations, got %+v", response)
+	}
+}
+
+func newTestIndexService(t *testing.T, handler http.HandlerFunc) *IndexService {
+	return NewIndexService(newFakeESClient(t, handler), zap.NewNop())
+}
+
+// clusterVersionHandler answers GET / with the given version number
+func clusterVersionHandler(version string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintf(w, `{"version":{"number":%q}}`, version)
+	}
+}
+
+func TestTimeValuePattern(t *testing.T) {
+	testCases := []struct {
+		value    string
+		expected bool
+	}{
+		{"12h", true},
+		{"1d", true},
+		{"30s", true},
+		{"500ms", true},
+		{"10micros", true},
+		{"100nanos", true},
+		{"0m", true},
+		{"12", false},
+		{"h", false},
+		{"1w", false},
+		{"1.5h", false},
+		{"-1h", false},
+		{"12 h", false},
+		{"12H", false},
+		{"", false},
+	}
+
+	for _, tc := range testCases {
+		if got := timeValuePattern.MatchString(tc.value); got != tc.expected {
+			t.Errorf("Expected %q to match %v, got %v", tc.value, tc.expected, got)
+		}
+	}
+}
+
+func TestIndexService_GetClusterVersion(t *testing.T) {
+	testCases := []struct {
+		name          string
+		version       string
+		expectedMajor int
+		expectedMinor int
+		expectError   bool
+	}{
+		{name: "release", version: "8.11.1", expectedMajor: 8, expectedMinor: 11},
+		{name: "snapshot", version: "7.17.0-SNAPSHOT", expectedMajor: 7, expectedMinor: 17},
+		{name: "major and minor only", version: "6.5", expectedMajor: 6, expectedMinor: 5},
+		{name: "major only", version: "8", expectError: true},
+		{name: "not a number", version: "eight.one", expectError: true},
+		{name: "empty", version: "", expectError: true},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			service := newTestIndexService(t, clusterVersionHandler(tc.version))
+
+			major, minor, err := service.getClusterVersion(context.Background())
+			if tc.expectError {
+				if err == nil {
+					t.Errorf("Expected an error for version %q, got %d.%d", tc.version, major, minor)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if major != tc.expectedMajor || minor != tc.expectedMinor {
+				t.Errorf("Expected %d.%d, got %d.%d", tc.expectedMajor, tc.expectedMinor, major, minor)
+			}
+		})
+	}
+}
+
+func TestIndexService_ValidateSoftDeletesSettings(t *testing.T) {
+	enabled, disabled := true, false
+
+	testCases := []struct {
+		name                 string
+		version              string
+		enabled              *bool
+		retentionLeasePeriod string
+		expectError          bool
+	}{
+		{name: "nothing requested", version: "5.6.0"},
+		{name: "enabled on 8.x", version: "8.11.1", enabled: &enabled},
+		{name: "enabled on 6.5", version: "6.5.4", enabled: &enabled},
+		{name: "enabled below 6.5", version: "6.4.3", enabled: &enabled, expectError: true},
+		{name: "enabled on 5.x", version: "5.6.16", enabled: &enabled, expectError: true},
+		{name: "disabled on 7.x", version: "7.17.0", enabled: &disabled},
+		{name: "disabled on 8.x", version: "8.11.1", enabled: &disabled, expectError: true},
+		{name: "retention lease period", version: "8.11.1", retentionLeasePeriod: "12h"},
+		{name: "retention lease period in days", version: "7.10.2", enabled: &enabled, retentionLeasePeriod: "1d"},
+		{name: "retention lease period below 6.7", version: "6.6.2", retentionLeasePeriod: "12h", expectError: true},
+		{name: "retention lease period without a unit", version: "8.11.1", retentionLeasePeriod: "12", expectError: true},
+		{name: "retention lease period with an unknown unit", version: "8.11.1", retentionLeasePeriod: "1w", expectError: true},
+		{name: "retention lease period with soft-deletes disabled", version: "7.17.0", enabled: &disabled, retentionLeasePeriod: "12h", expectError: true},
+		{name: "unreadable cluster version", version: "unknown", enabled: &enabled, expectError: true},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			service := newTestIndexService(t, clusterVersionHandler(tc.version))
+
+			err := service.validateSoftDeletesSettings(context.Background(), tc.enabled, tc.retentionLeasePeriod)
+			if (err != nil) != tc.expectError {
+				t.Errorf("Expected error %v, got %v", tc.expectError, err)
+			}
+		})
+	}
+}
+
+func TestIndexService_ApplyOptimizedSettings(t *testing.T) {
+	var method, path, body string
+	service := newTestIndexService(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/" {
+			w.Write([]byte(`{"version":{"number":"8.11.1"}}`))
+			return
+		}
+		payload, _ := io.ReadAll(r.Body)
+		method, path, body = r.Method, r.URL.Path, string(payload)
+		w.Write([]byte(`{"acknowledged":true}`))
+	})
+
+	err := service.applyOptimizedSettings(context.Background(), "logs", map[string]interface{}{"refresh_interval": "30s"})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	expected := `{"index":{"refresh_interval":"30s"}}`
+	if method != http.MethodPut || path != "/logs/_settings" || body != expected {
+		t.Errorf("Expected PUT /logs/_settings with %s, got %s %s with %s", expected, method, path, body)
+	}
+}
+
+// replicaHandler fakes an index whose replica count is changed through the settings API;
+// health reports green while the replica count is at most allocatable
+func replicaHandler(replicas *int, allocatable int, changes *[]string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.URL.Path == "/":
+			w.Write([]byte(`{"version":{"number":"8.11.1"}}`))
+		case r.URL.Path == "/logs/_settings" && r.Method == http.MethodGet:
+			fmt.Fprintf(w, `{"logs":{"settings":{"index":{"number_of_replicas":"%d"}}}}`, *replicas)
+		case r.URL.Path == "/logs/_settings" && r.Method == http.MethodPut:
+			var body struct {
+				Index struct {
+					Replicas int `json:"number_of_replicas"`
+				} `json:"index"`
+			}
+			json.NewDecoder(r.Body).Decode(&body)
+			*replicas = body.Index.Replicas
+			*changes = append(*changes, fmt.Sprint(*replicas))
+			w.Write([]byte(`{"acknowledged":true}`))
+		case r.URL.Path == "/_cluster/health/logs":
+			if *replicas <= allocatable {
+				fmt.Fprintf(w, `{"status":"green","active_shards":%d,"unassigned_shards":0}`, *replicas+1)
+			} else {
+				fmt.Fprintf(w, `{"status":"yellow","active_shards":%d,"unassigned_shards":%d}`, allocatable+1, *replicas-allocatable)
+			}
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}
+}
+
+func TestIndexService_RampUpReplicas(t *testing.T) {
+	testCases := []struct {
+		name              string
+		current           int
+		allocatable       int
+		target            int
+		expectedChanges   []string
+		expectedSteps     int
+		expectedCompleted bool
+	}{
+		{name: "one replica at a time", current: 0, allocatable: 2, target: 2, expectedChanges: []string{"1", "2"}, expectedSteps: 2, expectedCompleted: true},
+		{name: "stops when a step does not allocate", current: 0, allocatable: 1, target: 3, expectedChanges: []string{"1", "2"}, expectedSteps: 2},
+		{name: "already at the target", current: 1, allocatable: 1, target: 1, expectedCompleted: true},
+		{name: "lowering is applied directly", current: 2, allocatable: 2, target: 0, expectedChanges: []string{"0"}, expectedCompleted: true},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			replicas := tc.current
+			var changes []string
+			service := newTestIndexService(t, replicaHandler(&replicas, tc.allocatable, &changes))
+
+			// A zero step timeout checks allocation once instead of polling
+			result, err := service.RampUpReplicas(context.Background(), "logs", tc.target, 0)
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+
+			if !reflect.DeepEqual(changes, tc.expectedChanges) {
+				t.Errorf("Expected replica changes %v, got %v", tc.expectedChanges, changes)
+			}
+			if len(result.Steps) != tc.expectedSteps || result.Completed != tc.expectedCompleted {
+				t.Errorf("Expected %d steps and completed %v, got %+v", tc.expectedSteps, tc.expectedCompleted, result)
+			}
+			if result.FromReplicas != tc.current || result.TargetReplicas != tc.target {
+				t.Errorf("Expected a ramp from %d to %d, got %d to %d", tc.current, tc.target, result.FromReplicas, result.TargetReplicas)
+			}
+			if !tc.expectedCompleted && result.Steps[len(result.Steps)-1].UnassignedShards == 0 {
+				t.Errorf("Expected the last step to report unassigned shards, got %+v", result.Steps)
+			}
+		})
+	}
+}
+
+func TestIndexService_WaitForActiveShards(t *testing.T) {
+	testCases := []struct {
+		name              string
+		activeShards      string
+		status            int
+		body              string
+		expectedAllocated bool
+		expectError       bool
+	}{
+		{name: "allocated", activeShards: "all", status: http.StatusOK, body: `{"status":"green","active_shards":2,"timed_out":false}`, expectedAllocated: true},
+		{name: "timed out", activeShards: "2", status: http.StatusRequestTimeout, body: `{"status":"yellow","active_shards":1,"unassigned_shards":1,"timed_out":true}`},
+		{name: "missing index", activeShards: "all", status: http.StatusNotFound, body: `{"error":{"type":"index_not_found_exception","reason":"no such index [logs]"},"status":404}`, expectError: true},
+		{name: "invalid count", activeShards: "0", expectError: true},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			var query map[string][]string
+			service := newTestIndexService(t, func(w http.ResponseWriter, r *http.Request) {
+				if r.URL.Path == "/" {
+					w.Write([]byte(`{"version":{"number":"8.11.1"}}`))
+					return
+				}
+				query = r.URL.Query()
+				w.WriteHeader(tc.status)
+				w.Write([]byte(tc.body))
+			})
+
+			step, err := service.WaitForActiveShards(context.Background(), "logs", 1, tc.activeShards, 30*time.Second)
+			if tc.expectError {
+				if err == nil {
+					t.Errorf("Expected an error, got %+v", step)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+
+			if query["wait_for_active_shards"][0] != tc.activeShards || query["timeout"][0] != "30000ms" {
+				t.Errorf("Expected wait_for_active_shards %s with a 30s timeout, got %v", tc.activeShards, query)
+			}
+			if step.Allocated != tc.expectedAllocated || step.Replicas != 1 {
+				t.Errorf("Expected allocated %v for 1 replica, got %+v", tc.expectedAllocated, step)
+			}
+		})
+	}
+}
+
+// catIndicesAPIResponse follows GET /_cat/indices/logs-*?format=json&s=docs.count:desc; cat
+// APIs report numbers as strings, and null counts for closed indices
+const catIndicesAPIResponse = `[
+  {"health": "green", "status": "open", "index": "logs-000003", "uuid": "q2P0n7xTRBWuS8fSAhRfrg", "pri": "3", "rep": "1", "docs.count": "184302", "docs.deleted": "1204", "store.size": "212.4mb", "pri.store.size": "106.2mb", "dataset.size": "106.2mb"},
+  {"health": "yellow", "status": "open", "index": "logs-000002", "uuid": "Hk1qL4pXQ2mE0aNwBvYt7g", "pri": "1", "rep": "1", "docs.count": "52011", "docs.deleted": "0", "store.size": "31.8mb", "pri.store.size": "31.8mb", "dataset.size": "31.8mb"},
+  {"health": null, "status": "close", "index": "logs-000001", "uuid": "v9sR3dYcS5uJ1tKzPqLm2w", "pri": "1", "rep": "1", "docs.count": null, "docs.deleted": null, "store.size": null, "pri.store.size": null, "dataset.size": null}
+]`
+
+func TestIndexService_ListIndices(t *testing.T) {
+	testCases := []struct {
+		name            string
+		opts            ListIndicesOptions
+		expectedPath    string
+		expectedSort    string
+		expectedIndices []string
+	}{
+		{
+			name:            "all indices",
+			expectedPath:    "/_cat/indices",
+			expectedIndices: []string{"logs-000003", "logs-000002", "logs-000001"},
+		},
+		{
+			name:            "pattern and sort",
+			opts:            ListIndicesOptions{Pattern: "logs-*", Sort: "docs.count:desc,index"},
+			expectedPath:    "/_cat/indices/logs-*",
+			expectedSort:    "docs.count:desc,index",
+			expectedIndices: []string{"logs-000003", "logs-000002", "logs-000001"},
+		},
+		{
+			name:            "page",
+			opts:            ListIndicesOptions{Offset: 1, Limit: 1},
+			expectedPath:    "/_cat/indices",
+			expectedIndices: []string{"logs-000002"},
+		},
+		{
+			name:            "last page",
+			opts:            ListIndicesOptions{Offset: 2, Limit: 10},
+			expectedPath:    "/_cat/indices",
+			expectedIndices: []string{"logs-000001"},
+		},
+		{
+			name:            "past the end",
+			opts:            ListIndicesOptions{Offset: 5, Limit: 10},
+			expectedPath:    "/_cat/indices",
+			expectedIndices: []string{},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			var path, sort, format string
+			service := newTestIndexService(t, func(w http.ResponseWriter, r *http.Request) {
+				if r.URL.Path == "/" {
+					w.Write([]byte(`{"version":{"number":"8.11.1"}}`))
+					return
+				}
+				path, sort, format = r.URL.Path, r.URL.Query().Get("s"), r.URL.Query().Get("format")
+				w.Write([]byte(catIndicesAPIResponse))
+			})
+
+			indices, total, err := service.ListIndices(context.Background(), tc.opts)
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+
+			if path != tc.expectedPath || sort != tc.expectedSort || format != "json" {
+				t.Errorf("Expected %s sorted by %q as JSON, got %s sorted by %q as %q", tc.expectedPath, tc.expectedSort, path, sort, format)
+			}
+			if total != 3 {
+				t.Errorf("Expected a total of 3 indices, got %d", total)
+			}
+			names := make([]string, len(indices))
+			for i, index := range indices {
+				names[i] = index.IndexName
+			}
+			if !reflect.DeepEqual(names, tc.expectedIndices) {
+				t.Errorf("Expected indices %v, got %v", tc.expectedIndices, names)
+			}
+		})
+	}
+}
+
+func TestIndexService_ListIndices_CatValues(t *testing.T) {
+	service := newTestIndexService(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/" {
+			w.Write([]byte(`{"version":{"number":"8.11.1"}}`))
+			return
+		}
+		w.Write([]byte(catIndicesAPIResponse))
+	})
+
+	indices, _, err := service.ListIndices(context.Background(), ListIndicesOptions{})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	open := indices[0]
+	if open.Health != "green" || open.Primary != 3 || open.Replica != 1 || open.DocsCount != 184302 || open.DocsDeleted != 1204 || open.StoreSize != "212.4mb" || open.PrimaryStoreSize != "106.2mb" {
+		t.Errorf("Expected the string cat values converted, got %+v", open)
+	}
+	if closed := indices[2]; closed.Status != "close" || closed.DocsCount != 0 || closed.StoreSize != "" {
+		t.Errorf("Expected a closed index without counts, got %+v", closed)
+	}
+}
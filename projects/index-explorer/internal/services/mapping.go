@@ -0,0 +1,142 @@
+package services
+
+import (
+	"encoding/json"
+	"math"
+	"sort"
+	"strings"
+	"time"
+	"unicode"
+)
+
+// Strings up to this length without whitespace are treated as identifiers (IDs, codes,
+// hostnames, emails) and mapped as keyword only
+const maxIdentifierLength = 64
+
+// Keyword sub-fields skip values longer than this, which are never useful for sorting or
+// aggregating and would bloat the terms dictionary
+const keywordIgnoreAbove = 256
+
+// Date formats recognised by Elasticsearch's default date parser
+var inferredDateLayouts = []string{
+	time.RFC3339Nano,
+	"2006-01-02T15:04:05",
+	"2006-01-02T15:04:05.000",
+	"2006-01-02",
+}
+
+// InferMapping derives an explicit mapping from a sample document instead of relying on
+// dynamic mapping, which indexes every string as both text and keyword:
+//   - identifier-like strings (short, no whitespace) become keyword
+//   - other strings become text with a keyword sub-field, or plain text when textHeavy
+//   - ISO 8601 date strings become date
+//   - whole numbers become long, other numbers double, and booleans boolean
+//   - objects are mapped recursively and arrays by their first element
+//
+// Fields whose type can't be told from the sample (null values, empty arrays) are left to
+// dynamic mapping and returned by path in skipped.
+func (s *IndexService) InferMapping(sample map[string]interface{}, textHeavy bool) (mapping map[string]interface{}, skipped []string) {
+	properties, skipped := inferProperties(sample, "", textHeavy)
+	sort.Strings(skipped)
+	return map[string]interface{}{"properties": properties}, skipped
+}
+
+// inferProperties maps the fields of an object; prefix is its path in the document
+func inferProperties(object map[string]interface{}, prefix string, textHeavy bool) (map[string]interface{}, []string) {
+	properties := make(map[string]interface{}, len(object))
+	var skipped []string
+
+	for name, value := range object {
+		// Metadata fields such as _id can't be mapped
+		if prefix == "" && strings.HasPrefix(name, "_") {
+			continue
+		}
+
+		path := prefix + name
+		field, nestedSkipped := inferField(value, path, textHeavy)
+		skipped = append(skipped, nestedSkipped...)
+		if field == nil {
+			skipped = append(skipped, path)
+			continue
+		}
+		properties[name] = field
+	}
+
+	return properties, skipped
+}
+
+// inferField maps a single value, returning nil when its type can't be inferred
+func inferField(value interface{}, path string, textHeavy bool) (map[string]interface{}, []string) {
+	switch v := value.(type) {
+	case bool:
+		return map[string]interface{}{"type": "boolean"}, nil
+	case float64:
+		return numericField(v), nil
+	case int, int32, int64:
+		return map[string]interface{}{"type": "long"}, nil
+	case float32:
+		return numericField(float64(v)), nil
+	case json.Number:
+		if _, err := v.Int64(); err == nil {
+			return map[string]interface{}{"type": "long"}, nil
+		}
+		return map[string]interface{}{"type": "double"}, nil
+	case string:
+		return stringField(v, textHeavy), nil
+	case map[string]interface{}:
+		properties, skipped := inferProperties(v, path+".", textHeavy)
+		if len(properties) == 0 {
+			return nil, skipped
+		}
+		return map[string]interface{}{"properties": properties}, skipped
+	case []interface{}:
+		// Elasticsearch has no array type; every element shares the field's mapping
+		for _, element := range v {
+			if element != nil {
+				return inferField(element, path, textHeavy)
+			}
+		}
+		return nil, nil
+	default:
+		return nil, nil
+	}
+}
+
+// numericField maps a JSON number, which decodes as float64 whether or not it is whole
+func numericField(v float64) map[string]interface{} {
+	if v == math.Trunc(v) && math.Abs(v) < 1<<53 {
+		return map[string]interface{}{"type": "long"}
+	}
+	return map[string]interface{}{"type": "double"}
+}
+
+// stringField maps a string as date, keyword or text
+func stringField(v string, textHeavy bool) map[string]interface{} {
+	for _, layout := range inferredDateLayouts {
+		if _, err := time.Parse(layout, v); err == nil {
+			return map[string]interface{}{"type": "date"}
+		}
+	}
+
+	if isIdentifierLike(v) {
+		return map[string]interface{}{"type": "keyword", "ignore_above": keywordIgnoreAbove}
+	}
+
+	if textHeavy {
+		return map[string]interface{}{"type": "text"}
+	}
+	return map[string]interface{}{
+		"type": "text",
+		"fields": map[string]interface{}{
+			"keyword": map[string]interface{}{"type": "keyword", "ignore_above": keywordIgnoreAbove},
+		},
+	}
+}
+
+// isIdentifierLike reports whether a string looks like an ID or code rather than prose
+func isIdentifierLike(v string) bool {
+	if v == "" || len(v) > maxIdentifierLength {
+		return false
+	}
+	return strings.IndexFunc(v, unicode.IsSpace) < 0
+}
@@ -0,0 +1,293 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/saif-islam/es-playground/projects/index-explorer/internal/models"
+	"github.com/saif-islam/es-playground/shared"
+)
+
+// Write metrics history defaults
+const (
+	defaultMetricsSampleInterval = 30 * time.Second
+	defaultMetricsRetention      = 24 * time.Hour
+	// Indices beyond this are not sampled, bounding memory on clusters with many indices
+	maxSampledIndices = 1000
+	// Gap between the two stats samples used to measure an indexing rate on demand
+	indexingRateSampleInterval = 2 * time.Second
+)
+
+// WriteMetricsSamplerConfig controls background sampling of write metrics
+type WriteMetricsSamplerConfig struct {
+	// How often indices are sampled (default 30s)
+	Interval time.Duration
+	// How long samples are kept (default 24h)
+	Retention time.Duration
+	// Index patterns to sample; all open indices when empty
+	Indices []string
+}
+
+// writeMetricsHistory keeps a ring buffer of write metrics samples per index
+type writeMetricsHistory struct {
+	interval  time.Duration
+	retention time.Duration
+	capacity  int
+
+	mu      sync.RWMutex
+	indices map[string]*sampleRing
+	// Cumulative primary indexing counters from the previous round, to compute rates
+	previous map[string]indexingCounters
+}
+
+type indexingCounters struct {
+	at         time.Time
+	indexTotal int64
+	indexTime  int64
+}
+
+// sampleRing is a fixed-size buffer overwriting its oldest sample once full
+type sampleRing struct {
+	samples []models.WriteMetricsSample
+	start   int
+	count   int
+}
+
+func newWriteMetricsHistory(interval, retention time.Duration) *writeMetricsHistory {
+	capacity := int(retention / interval)
+	if capacity < 1 {
+		capacity = 1
+	}
+
+	return &writeMetricsHistory{
+		interval:  interval,
+		retention: retention,
+		capacity:  capacity,
+		indices:   make(map[string]*sampleRing),
+		previous:  make(map[string]indexingCounters),
+	}
+}
+
+func (r *sampleRing) add(sample models.WriteMetricsSample) {
+	if r.count < len(r.samples) {
+		r.samples[(r.start+r.count)%len(r.samples)] = sample
+		r.count++
+		return
+	}
+	r.samples[r.start] = sample
+	r.start = (r.start + 1) % len(r.samples)
+}
+
+// since returns the samples taken at or after cutoff, oldest first
+func (r *sampleRing) since(cutoff time.Time) []models.WriteMetricsSample {
+	samples := make([]models.WriteMetricsSample, 0, r.count)
+	for i := 0; i < r.count; i++ {
+		sample := r.samples[(r.start+i)%len(r.samples)]
+		if !sample.Timestamp.Before(cutoff) {
+			samples = append(samples, sample)
+		}
+	}
+	return samples
+}
+
+// latest returns the time of the newest sample
+func (r *sampleRing) latest() time.Time {
+	if r.count == 0 {
+		return time.Time{}
+	}
+	return r.samples[(r.start+r.count-1)%len(r.samples)].Timestamp
+}
+
+// record adds a sample for every index in stats. An index's first round only sets the
+// baseline its rates are measured from.
+func (h *writeMetricsHistory) record(now time.Time, stats map[string]models.IndexStats, score func(*models.IndexStatsDetails) float64) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	for name, indexStats := range stats {
+		primaries, total := indexStats.Primaries, indexStats.Total
+		if primaries == nil || total == nil {
+			continue
+		}
+
+		counters := indexingCounters{
+			at:         now,
+			indexTotal: primaries.Indexing.IndexTotal,
+			indexTime:  primaries.Indexing.IndexTimeInMillis,
+		}
+		previous, seen := h.previous[name]
+		if !seen && len(h.previous) >= maxSampledIndices {
+			continue
+		}
+		h.previous[name] = counters
+		if !seen {
+			continue
+		}
+
+		sample := models.WriteMetricsSample{
+			Timestamp:         now,
+			DocsCount:         primaries.Docs.Count,
+			SegmentCount:      total.Segments.Count,
+			TranslogSize:      total.Translog.SizeInBytes,
+			OptimizationScore: score(total),
+		}
+		// Counters go backwards when an index is recreated; report no rate for that period
+		docs := counters.indexTotal - previous.indexTotal
+		if elapsed := now.Sub(previous.at).Seconds(); docs >= 0 && elapsed > 0 {
+			sample.IndexingRate = float64(docs) / elapsed
+			if docs > 0 {
+				sample.WriteLatency = float64(counters.indexTime-previous.indexTime) / float64(docs)
+			}
+		}
+
+		ring, exists := h.indices[name]
+		if !exists {
+			ring = &sampleRing{samples: make([]models.WriteMetricsSample, h.capacity)}
+			h.indices[name] = ring
+		}
+		ring.add(sample)
+	}
+
+	// Forget indices that are gone once their history has aged out
+	cutoff := now.Add(-h.retention)
+	for name, ring := range h.indices {
+		if _, present := stats[name]; !present && ring.latest().Before(cutoff) {
+			delete(h.indices, name)
+		}
+	}
+	for name := range h.previous {
+		if _, present := stats[name]; !present {
+			delete(h.previous, name)
+		}
+	}
+}
+
+// samples returns an index's samples within the window, oldest first
+func (h *writeMetricsHistory) samples(indexName string, window time.Duration, now time.Time) []models.WriteMetricsSample {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
+	ring, exists := h.indices[indexName]
+	if !exists {
+		return []models.WriteMetricsSample{}
+	}
+	return ring.since(now.Add(-window))
+}
+
+// latest returns an index's newest sample if it was taken within the last two intervals
+func (h *writeMetricsHistory) latest(indexName string, now time.Time) (models.WriteMetricsSample, bool) {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
+	ring, exists := h.indices[indexName]
+	if !exists || ring.count == 0 {
+		return models.WriteMetricsSample{}, false
+	}
+	sample := ring.samples[(ring.start+ring.count-1)%len(ring.samples)]
+	if now.Sub(sample.Timestamp) > 2*h.interval {
+		return models.WriteMetricsSample{}, false
+	}
+	return sample, true
+}
+
+// StartWriteMetricsSampler samples the write metrics of matching indices on every interval
+// until ctx is cancelled, keeping them for WriteMetricsHistory. Indices are sampled on the
+// default cluster.
+func (s *DocumentService) StartWriteMetricsSampler(ctx context.Context, config WriteMetricsSamplerConfig) {
+	interval := config.Interval
+	if interval <= 0 {
+		interval = defaultMetricsSampleInterval
+	}
+	retention := config.Retention
+	if retention <= 0 {
+		retention = defaultMetricsRetention
+	}
+
+	history := newWriteMetricsHistory(interval, retention)
+	s.historyMu.Lock()
+	s.history = history
+	s.historyMu.Unlock()
+
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			if err := s.sampleWriteMetrics(ctx, history, config.Indices); err != nil && ctx.Err() == nil {
+				s.logger.Warn("Failed to sample write metrics", zap.Error(err))
+			}
+
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+
+	s.logger.Info("Started write metrics sampler",
+		zap.Duration("interval", interval),
+		zap.Duration("retention", retention),
+		zap.Strings("indices", config.Indices))
+}
+
+// sampleWriteMetrics fetches the stats of matching indices and records a sample for each
+func (s *DocumentService) sampleWriteMetrics(ctx context.Context, history *writeMetricsHistory, patterns []string) error {
+	ctx, cancel := context.WithTimeout(ctx, history.interval)
+	defer cancel()
+
+	res, err := s.esClient.Indices.Stats(
+		s.esClient.Indices.Stats.WithContext(ctx),
+		s.esClient.Indices.Stats.WithIndex(patterns...),
+		s.esClient.Indices.Stats.WithMetric("docs", "indexing", "merge", "segments", "translog"),
+	)
+	if err != nil {
+		return fmt.Errorf("failed to get index stats: %w", err)
+	}
+	defer res.Body.Close()
+
+	if res.IsError() {
+		return shared.ParseESError(res)
+	}
+
+	var statsResponse struct {
+		Indices map[string]models.IndexStats `json:"indices"`
+	}
+	if err := shared.DecodeJSONResponse(res, &statsResponse); err != nil {
+		return fmt.Errorf("failed to decode stats response: %w", err)
+	}
+
+	history.record(time.Now(), statsResponse.Indices, s.calculateWriteOptimizationScore)
+	return nil
+}
+
+// WriteMetricsHistory returns an index's sampled write metrics over the window, oldest
+// first, and the sampling interval. It reports false when the sampler is not running.
+func (s *DocumentService) WriteMetricsHistory(indexName string, window time.Duration) ([]models.WriteMetricsSample, time.Duration, bool) {
+	s.historyMu.RLock()
+	history := s.history
+	s.historyMu.RUnlock()
+
+	if history == nil {
+		return nil, 0, false
+	}
+	return history.samples(indexName, window, time.Now()), history.interval, true
+}
+
+// LatestIndexingRate returns an index's indexing rate from its newest sample. It reports
+// false when the sampler is not running or has no current sample for the index.
+func (s *DocumentService) LatestIndexingRate(indexName string) (float64, bool) {
+	s.historyMu.RLock()
+	history := s.history
+	s.historyMu.RUnlock()
+
+	if history == nil {
+		return 0, false
+	}
+	sample, ok := history.latest(indexName, time.Now())
+	return sample.IndexingRate, ok
+}
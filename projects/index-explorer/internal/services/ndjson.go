@@ -0,0 +1,179 @@
+package services
+
+import (
+	"bufio"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+
+	"go.uber.org/zap"
+
+	"github.com/saif-islam/es-playground/projects/index-explorer/internal/models"
+)
+
+// NDJSON lines are buffered one at a time; a single document larger than the maximum
+// fails the import rather than growing the buffer without bound
+const (
+	ndjsonInitialBufferSize = 64 * 1024
+	maxNDJSONLineSize       = 64 * 1024 * 1024
+)
+
+// ID strategies for NDJSON documents without an _id
+const (
+	IDStrategyContentHash   = "content_hash"
+	IDStrategyElasticsearch = "elasticsearch"
+)
+
+// ndjsonRecord is a parsed NDJSON line together with its position in the input
+type ndjsonRecord struct {
+	operation  models.BulkOperation
+	line       int64
+	byteOffset int64 // offset just past the end of the line
+}
+
+// ndjsonReader parses NDJSON into index operations one line at a time, so an import
+// never holds more than the records it has read but not yet sent
+type ndjsonReader struct {
+	scanner     *bufio.Scanner
+	indexName   string
+	skipLines   int64
+	generateIDs bool
+	logger      *zap.Logger
+
+	line      int64
+	offset    int64
+	generated int64 // documents given a content hash ID
+}
+
+// newNDJSONReader reads NDJSON for the index, skipping the first skipLines lines. With
+// generateIDs, documents without an _id get a content hash ID; otherwise Elasticsearch
+// assigns one.
+func (s *DocumentService) newNDJSONReader(reader io.Reader, indexName string, skipLines int64, generateIDs bool) *ndjsonReader {
+	r := &ndjsonReader{
+		scanner:     bufio.NewScanner(reader),
+		indexName:   indexName,
+		skipLines:   skipLines,
+		generateIDs: generateIDs,
+		logger:      s.logger,
+	}
+
+	r.scanner.Buffer(make([]byte, ndjsonInitialBufferSize), maxNDJSONLineSize)
+	// Count consumed bytes, newlines included, so checkpoints can record byte offsets
+	r.scanner.Split(func(data []byte, atEOF bool) (int, []byte, error) {
+		advance, token, err := bufio.ScanLines(data, atEOF)
+		r.offset += int64(advance)
+		return advance, token, err
+	})
+
+	return r
+}
+
+// next returns the next document, skipping blank and malformed lines. It reports false
+// once the input is exhausted.
+func (r *ndjsonReader) next() (ndjsonRecord, bool, error) {
+	for r.scanner.Scan() {
+		r.line++
+		if r.line <= r.skipLines {
+			continue
+		}
+
+		line := strings.TrimSpace(r.scanner.Text())
+		if line == "" {
+			continue
+		}
+
+		var document map[string]interface{}
+		if err := json.Unmarshal([]byte(line), &document); err != nil {
+			r.logger.Warn("Failed to parse JSON line",
+				zap.Int64("line", r.line),
+				zap.Error(err))
+			continue
+		}
+
+		// Extract ID if present
+		var docID string
+		if id, exists := document["_id"]; exists {
+			docID = fmt.Sprintf("%v", id)
+			delete(document, "_id") // Remove from document body
+		} else if r.generateIDs {
+			docID = contentHashID(document)
+			r.generated++
+		}
+
+		return ndjsonRecord{
+			operation: models.BulkOperation{
+				Action:   "index",
+				Index:    r.indexName,
+				ID:       docID,
+				Document: document,
+			},
+			line:       r.line,
+			byteOffset: r.offset,
+		}, true, nil
+	}
+
+	if err := r.scanner.Err(); err != nil {
+		return ndjsonRecord{}, false, fmt.Errorf("failed to read NDJSON after line %d: %w", r.line, err)
+	}
+	return ndjsonRecord{}, false, nil
+}
+
+// idStrategy names how the reader identifies documents without an _id
+func (r *ndjsonReader) idStrategy() string {
+	if r.generateIDs {
+		return IDStrategyContentHash
+	}
+	return IDStrategyElasticsearch
+}
+
+// generatedIDs returns the number of documents given a content hash ID so far
+func (r *ndjsonReader) generatedIDs() int64 {
+	return r.generated
+}
+
+// contentHashID derives a document ID from its content, so re-running an import overwrites
+// the documents it already wrote instead of duplicating them. Identical documents share an
+// ID and are stored once.
+func contentHashID(document map[string]interface{}) string {
+	// Map keys are marshalled in sorted order, so equal documents hash equally
+	content, _ := json.Marshal(document)
+	sum := sha256.Sum256(content)
+	return hex.EncodeToString(sum[:16])
+}
+
+// readRecords reads up to max records; fewer are returned only at the end of the input
+func (r *ndjsonReader) readRecords(max int) ([]ndjsonRecord, error) {
+	records := make([]ndjsonRecord, 0, max)
+	for len(records) < max {
+		record, ok, err := r.next()
+		if err != nil {
+			return nil, err
+		}
+		if !ok {
+			break
+		}
+		records = append(records, record)
+	}
+	return records, nil
+}
+
+// readOperations reads up to max records as bulk operations
+func (r *ndjsonReader) readOperations(max int) ([]models.BulkOperation, error) {
+	records, err := r.readRecords(max)
+	if err != nil {
+		return nil, err
+	}
+	return recordOperations(records), nil
+}
+
+// recordOperations returns the bulk operations of parsed records
+func recordOperations(records []ndjsonRecord) []models.BulkOperation {
+	operations := make([]models.BulkOperation, 0, len(records))
+	for _, record := range records {
+		operations = append(operations, record.operation)
+	}
+	return operations
+}
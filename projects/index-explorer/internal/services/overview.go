@@ -0,0 +1,388 @@
+package services
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/saif-islam/es-playground/projects/index-explorer/internal/models"
+	"github.com/saif-islam/es-playground/shared"
+)
+
+// OverviewConfig locates the other playground services feeding the overview.
+// An empty URL leaves that service out.
+type OverviewConfig struct {
+	ClusterExplorerURL string
+	SearchAPIURL       string
+	Timeout            time.Duration
+}
+
+// OverviewService aggregates indexing, cluster and search status from all three services
+type OverviewService struct {
+	esClient   *shared.ESClient
+	config     OverviewConfig
+	httpClient *http.Client
+	logger     *zap.Logger
+
+	// Counters from the previous overview of each cluster, used to turn totals into rates
+	previous   map[string]*overviewSample
+	previousMu sync.Mutex
+}
+
+// overviewSample records the cumulative counters observed at one point in time
+type overviewSample struct {
+	at            time.Time
+	indexTotal    int64
+	totalSearches int64
+	hasIndexing   bool
+	hasSearch     bool
+}
+
+// NewOverviewService creates a new overview service instance
+func NewOverviewService(esClient *shared.ESClient, config OverviewConfig, logger *zap.Logger) *OverviewService {
+	if config.Timeout <= 0 {
+		config.Timeout = 5 * time.Second
+	}
+
+	return &OverviewService{
+		esClient:   esClient,
+		config:     config,
+		httpClient: &http.Client{Timeout: config.Timeout},
+		logger:     logger,
+		previous:   make(map[string]*overviewSample),
+	}
+}
+
+// GetOverview collects the status of every service concurrently. A service that cannot be
+// reached is reported as down; the rest of the overview is still returned.
+func (s *OverviewService) GetOverview(ctx context.Context) *models.OverviewResponse {
+	var (
+		wg       sync.WaitGroup
+		indexing *models.IndexingOverview
+		cluster  *models.ClusterOverview
+		search   *models.SearchOverview
+		statuses = make([]models.ServiceStatus, 3)
+	)
+
+	wg.Add(3)
+	go func() {
+		defer wg.Done()
+		start := time.Now()
+		var err error
+		indexing, err = s.getIndexingOverview(ctx)
+		statuses[0] = serviceStatus("index-explorer", "", start, err)
+	}()
+	go func() {
+		defer wg.Done()
+		start := time.Now()
+		if s.config.ClusterExplorerURL == "" {
+			statuses[1] = models.ServiceStatus{Name: "cluster-explorer", Status: "disabled"}
+			return
+		}
+		var err error
+		cluster, err = s.getClusterOverview(ctx)
+		statuses[1] = serviceStatus("cluster-explorer", s.config.ClusterExplorerURL, start, err)
+	}()
+	go func() {
+		defer wg.Done()
+		start := time.Now()
+		if s.config.SearchAPIURL == "" {
+			statuses[2] = models.ServiceStatus{Name: "search-api", Status: "disabled"}
+			return
+		}
+		var err error
+		search, err = s.getSearchOverview(ctx)
+		statuses[2] = serviceStatus("search-api", s.config.SearchAPIURL, start, err)
+	}()
+	wg.Wait()
+
+	selected, ok := shared.ClusterFromContext(ctx)
+	if !ok {
+		selected = shared.DefaultClusterName
+	}
+	s.applyRates(selected, indexing, search, time.Now())
+
+	for _, status := range statuses {
+		if status.Status == "down" {
+			s.logger.Warn("Overview source unavailable",
+				zap.String("service", status.Name),
+				zap.String("error", status.Error))
+		}
+	}
+
+	return &models.OverviewResponse{
+		Status:    overallStatus(statuses, cluster),
+		Indexing:  indexing,
+		Cluster:   cluster,
+		Search:    search,
+		Services:  statuses,
+		RequestID: fmt.Sprintf("overview-%d", time.Now().UnixNano()),
+		Timestamp: time.Now(),
+	}
+}
+
+// serviceStatus reports the outcome of fetching one service's part of the overview
+func serviceStatus(name, url string, start time.Time, err error) models.ServiceStatus {
+	status := models.ServiceStatus{
+		Name:         name,
+		URL:          url,
+		Status:       "up",
+		ResponseTime: time.Since(start),
+	}
+	if err != nil {
+		status.Status = "down"
+		status.Error = err.Error()
+	}
+	return status
+}
+
+// overallStatus is critical when the cluster is red, degraded when it is yellow or any
+// service is down, and healthy otherwise
+func overallStatus(statuses []models.ServiceStatus, cluster *models.ClusterOverview) string {
+	if cluster != nil && cluster.Status == "red" {
+		return "critical"
+	}
+
+	degraded := cluster != nil && cluster.Status == "yellow"
+	for _, status := range statuses {
+		if status.Status == "down" {
+			degraded = true
+		}
+	}
+
+	if degraded {
+		return "degraded"
+	}
+	return "healthy"
+}
+
+// applyRates fills in indexing and search rates from the change since the previous overview
+// of the same cluster
+func (s *OverviewService) applyRates(clusterName string, indexing *models.IndexingOverview, search *models.SearchOverview, now time.Time) {
+	s.previousMu.Lock()
+	defer s.previousMu.Unlock()
+
+	current := &overviewSample{at: now}
+	if indexing != nil {
+		current.indexTotal = indexing.IndexTotal
+		current.hasIndexing = true
+	}
+	if search != nil {
+		current.totalSearches = search.TotalSearches
+		current.hasSearch = true
+	}
+
+	if previous, ok := s.previous[clusterName]; ok {
+		elapsed := now.Sub(previous.at).Seconds()
+		if elapsed > 0 {
+			// Counters reset when a service or node restarts; skip the rate rather than go negative
+			if indexing != nil && previous.hasIndexing && indexing.IndexTotal >= previous.indexTotal {
+				indexing.IndexingRate = float64(indexing.IndexTotal-previous.indexTotal) / elapsed
+			}
+			if search != nil && previous.hasSearch && search.TotalSearches >= previous.totalSearches {
+				search.QPS = float64(search.TotalSearches-previous.totalSearches) / elapsed
+			}
+		}
+	}
+
+	s.previous[clusterName] = current
+}
+
+// getIndexingOverview reads cluster-wide primary indexing statistics
+func (s *OverviewService) getIndexingOverview(ctx context.Context) (*models.IndexingOverview, error) {
+	res, err := s.esClient.Indices.Stats(
+		s.esClient.Indices.Stats.WithContext(ctx),
+		s.esClient.Indices.Stats.WithMetric("docs", "indexing"),
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer res.Body.Close()
+
+	if res.IsError() {
+		return nil, shared.ParseESError(res)
+	}
+
+	var stats struct {
+		All struct {
+			Primaries struct {
+				Docs struct {
+					Count int64 `json:"count"`
+				} `json:"docs"`
+				Indexing struct {
+					IndexTotal        int64 `json:"index_total"`
+					IndexTimeInMillis int64 `json:"index_time_in_millis"`
+					IndexFailed       int64 `json:"index_failed"`
+				} `json:"indexing"`
+			} `json:"primaries"`
+		} `json:"_all"`
+		Indices map[string]json.RawMessage `json:"indices"`
+	}
+	if err := shared.DecodeJSONResponse(res, &stats); err != nil {
+		return nil, err
+	}
+
+	primaries := stats.All.Primaries
+	overview := &models.IndexingOverview{
+		Indices:     len(stats.Indices),
+		DocsCount:   primaries.Docs.Count,
+		IndexTotal:  primaries.Indexing.IndexTotal,
+		IndexFailed: primaries.Indexing.IndexFailed,
+	}
+	if primaries.Indexing.IndexTotal > 0 {
+		overview.AverageIndexLatency = float64(primaries.Indexing.IndexTimeInMillis) / float64(primaries.Indexing.IndexTotal)
+	}
+
+	return overview, nil
+}
+
+// getClusterOverview reads cluster health from the cluster-explorer
+func (s *OverviewService) getClusterOverview(ctx context.Context) (*models.ClusterOverview, error) {
+	body, err := s.fetch(ctx, s.config.ClusterExplorerURL+"/api/v1/cluster/health")
+	if err != nil {
+		return nil, err
+	}
+
+	var response struct {
+		Health struct {
+			ClusterName                 string  `json:"cluster_name"`
+			Status                      string  `json:"status"`
+			NumberOfNodes               int     `json:"number_of_nodes"`
+			NumberOfDataNodes           int     `json:"number_of_data_nodes"`
+			ActiveShards                int     `json:"active_shards"`
+			UnassignedShards            int     `json:"unassigned_shards"`
+			ActiveShardsPercentAsNumber float64 `json:"active_shards_percent_as_number"`
+		} `json:"health"`
+		Message string `json:"message"`
+	}
+	if err := json.Unmarshal(body, &response); err != nil {
+		return nil, fmt.Errorf("failed to decode cluster health: %w", err)
+	}
+	if response.Health.Status == "" {
+		return nil, fmt.Errorf("cluster health unavailable: %s", response.Message)
+	}
+
+	health := response.Health
+	return &models.ClusterOverview{
+		ClusterName:         health.ClusterName,
+		Status:              health.Status,
+		Nodes:               health.NumberOfNodes,
+		DataNodes:           health.NumberOfDataNodes,
+		ActiveShards:        health.ActiveShards,
+		UnassignedShards:    health.UnassignedShards,
+		ActiveShardsPercent: health.ActiveShardsPercentAsNumber,
+	}, nil
+}
+
+// getSearchOverview reads search traffic from the search-api's Prometheus metrics
+func (s *OverviewService) getSearchOverview(ctx context.Context) (*models.SearchOverview, error) {
+	body, err := s.fetch(ctx, s.config.SearchAPIURL+"/metrics")
+	if err != nil {
+		return nil, err
+	}
+
+	totals, err := sumPrometheusMetrics(bytes.NewReader(body))
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse search metrics: %w", err)
+	}
+
+	overview := &models.SearchOverview{
+		TotalSearches: int64(totals["elasticsearch_search_requests_total"]),
+		HTTPRequests:  int64(totals["http_requests_total"]),
+		Errors:        int64(totals["elasticsearch_errors_total"]),
+	}
+	if count := totals["elasticsearch_search_duration_seconds_count"]; count > 0 {
+		overview.AverageLatency = totals["elasticsearch_search_duration_seconds_sum"] / count * 1000
+	}
+
+	return overview, nil
+}
+
+// fetch GETs a URL from another service. Non-2xx responses are returned as long as they
+// carry a body, since the cluster-explorer reports yellow and red health with 206 and 503.
+func (s *OverviewService) fetch(ctx context.Context, url string) ([]byte, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	// Keep the other services on the cluster this request selected
+	if cluster, ok := shared.ClusterFromContext(ctx); ok {
+		req.Header.Set("X-ES-Cluster", cluster)
+	}
+
+	res, err := s.httpClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer res.Body.Close()
+
+	body, err := io.ReadAll(res.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	if res.StatusCode >= 400 && res.StatusCode != http.StatusServiceUnavailable {
+		return nil, fmt.Errorf("%s returned status %d", url, res.StatusCode)
+	}
+
+	return body, nil
+}
+
+// sumPrometheusMetrics parses the Prometheus text format and sums every sample of each
+// metric across its label sets, e.g. all http_requests_total{...} lines into one total
+func sumPrometheusMetrics(reader io.Reader) (map[string]float64, error) {
+	totals := make(map[string]float64)
+
+	scanner := bufio.NewScanner(reader)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		name := line
+		rest := ""
+		if i := strings.IndexAny(line, "{ "); i >= 0 {
+			name = line[:i]
+			rest = line[i:]
+		}
+		// Label values may contain spaces, so the value starts after the closing brace
+		if strings.HasPrefix(rest, "{") {
+			end := strings.LastIndex(rest, "}")
+			if end < 0 {
+				continue
+			}
+			rest = rest[end+1:]
+		}
+
+		fields := strings.Fields(rest)
+		if len(fields) == 0 {
+			continue
+		}
+		value, err := strconv.ParseFloat(fields[0], 64)
+		if err != nil || math.IsNaN(value) || math.IsInf(value, 0) {
+			continue
+		}
+
+		totals[name] += value
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	return totals, nil
+}
@@ -0,0 +1,78 @@
+package services
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/saif-islam/es-playground/projects/index-explorer/internal/models"
+)
+
+func TestSumPrometheusMetrics(t *testing.T) {
+	metrics := `# HELP elasticsearch_search_requests_total Total number of search requests
+# TYPE elasticsearch_search_requests_total counter
+elasticsearch_search_requests_total{index="products",query_type="match",status="success"} 120
+elasticsearch_search_requests_total{index="logs",query_type="bool",status="success"} 30
+elasticsearch_search_duration_seconds_sum{index="products",query_type="match"} 3.5
+elasticsearch_search_duration_seconds_count{index="products",query_type="match"} 150
+http_requests_total{endpoint="/api/v1/search",method="GET",status="200"} 160
+label_with_space{path="a b"} 2
+broken_line{unterminated="x" 5
+`
+
+	totals, err := sumPrometheusMetrics(strings.NewReader(metrics))
+	if err != nil {
+		t.Fatalf("Expected metrics to parse, got %v", err)
+	}
+
+	expected := map[string]float64{
+		"elasticsearch_search_requests_total":         150,
+		"elasticsearch_search_duration_seconds_sum":   3.5,
+		"elasticsearch_search_duration_seconds_count": 150,
+		"http_requests_total":                         160,
+		"label_with_space":                            2,
+	}
+	for name, value := range expected {
+		if totals[name] != value {
+			t.Errorf("Expected %s to be %v, got %v", name, value, totals[name])
+		}
+	}
+
+	if _, ok := totals["broken_line"]; ok {
+		t.Errorf("Expected malformed line to be skipped")
+	}
+}
+
+func TestOverviewService_ApplyRates(t *testing.T) {
+	service := NewOverviewService(nil, OverviewConfig{}, zap.NewNop())
+	start := time.Now()
+
+	service.applyRates("default", &models.IndexingOverview{IndexTotal: 1000}, &models.SearchOverview{TotalSearches: 50}, start)
+
+	indexing := &models.IndexingOverview{IndexTotal: 3000}
+	search := &models.SearchOverview{TotalSearches: 70}
+	service.applyRates("default", indexing, search, start.Add(10*time.Second))
+
+	if indexing.IndexingRate != 200 {
+		t.Errorf("Expected indexing rate 200/s, got %v", indexing.IndexingRate)
+	}
+
+	if search.QPS != 2 {
+		t.Errorf("Expected 2 searches per second, got %v", search.QPS)
+	}
+
+	// Another cluster has no previous sample, and a counter reset yields no rate
+	other := &models.IndexingOverview{IndexTotal: 10}
+	service.applyRates("staging", other, nil, start.Add(20*time.Second))
+	if other.IndexingRate != 0 {
+		t.Errorf("Expected no rate for a cluster's first overview, got %v", other.IndexingRate)
+	}
+
+	reset := &models.IndexingOverview{IndexTotal: 100}
+	service.applyRates("default", reset, nil, start.Add(30*time.Second))
+	if reset.IndexingRate != 0 {
+		t.Errorf("Expected no rate after a counter reset, got %v", reset.IndexingRate)
+	}
+}
@@ -0,0 +1,129 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+
+	"github.com/elastic/go-elasticsearch/v8/esapi"
+	"go.uber.org/zap"
+
+	"github.com/saif-islam/es-playground/projects/index-explorer/internal/models"
+	"github.com/saif-islam/es-playground/shared"
+)
+
+// PutPipeline creates or replaces an ingest pipeline. Bulk requests run documents through
+// it when they name it in settings.pipeline, and reindexing when it is given as the pipeline.
+func (s *IndexService) PutPipeline(ctx context.Context, id string, req *models.PipelineRequest) (bool, error) {
+	s.logger.Info("Creating ingest pipeline",
+		zap.String("pipeline", id),
+		zap.Int("processors", len(req.Processors)))
+
+	if len(req.Processors) == 0 {
+		return false, fmt.Errorf("at least one processor is required")
+	}
+
+	bodyBytes, err := json.Marshal(req)
+	if err != nil {
+		return false, fmt.Errorf("failed to marshal ingest pipeline: %w", err)
+	}
+
+	res, err := s.esClient.Ingest.PutPipeline(
+		id,
+		strings.NewReader(string(bodyBytes)),
+		s.esClient.Ingest.PutPipeline.WithContext(ctx),
+	)
+	if err != nil {
+		return false, fmt.Errorf("failed to put ingest pipeline: %w", err)
+	}
+	defer res.Body.Close()
+
+	// Invalid processors are rejected with a 400 describing the problem
+	if res.IsError() {
+		return false, shared.ParseESError(res)
+	}
+
+	var putResponse struct {
+		Acknowledged bool `json:"acknowledged"`
+	}
+	if err := shared.DecodeJSONResponse(res, &putResponse); err != nil {
+		return false, fmt.Errorf("failed to decode ingest pipeline response: %w", err)
+	}
+
+	s.logger.Info("Successfully created ingest pipeline", zap.String("pipeline", id))
+	return putResponse.Acknowledged, nil
+}
+
+// GetPipeline returns the ingest pipelines whose ID matches a wildcard expression, or every
+// pipeline when id is empty. None matching is an empty list.
+func (s *IndexService) GetPipeline(ctx context.Context, id string) ([]models.PipelineInfo, error) {
+	options := []func(*esapi.IngestGetPipelineRequest){
+		s.esClient.Ingest.GetPipeline.WithContext(ctx),
+	}
+	if id != "" {
+		options = append(options, s.esClient.Ingest.GetPipeline.WithPipelineID(id))
+	}
+
+	res, err := s.esClient.Ingest.GetPipeline(options...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get ingest pipelines: %w", err)
+	}
+	defer res.Body.Close()
+
+	// An ID that matches nothing is a 404 rather than an empty object
+	if res.StatusCode == http.StatusNotFound {
+		return []models.PipelineInfo{}, nil
+	}
+
+	if res.IsError() {
+		return nil, shared.ParseESError(res)
+	}
+
+	// Pipelines are keyed by ID
+	var response map[string]models.PipelineRequest
+	if err := shared.DecodeJSONResponse(res, &response); err != nil {
+		return nil, fmt.Errorf("failed to decode ingest pipelines: %w", err)
+	}
+
+	pipelines := make([]models.PipelineInfo, 0, len(response))
+	for pipelineID, pipeline := range response {
+		pipelines = append(pipelines, models.PipelineInfo{
+			ID:              pipelineID,
+			PipelineRequest: pipeline,
+		})
+	}
+	sort.Slice(pipelines, func(i, j int) bool {
+		return pipelines[i].ID < pipelines[j].ID
+	})
+
+	return pipelines, nil
+}
+
+// DeletePipeline deletes an ingest pipeline, reporting false if it did not exist. Bulk
+// requests still naming it fail until they stop doing so.
+func (s *IndexService) DeletePipeline(ctx context.Context, id string) (bool, error) {
+	s.logger.Info("Deleting ingest pipeline", zap.String("pipeline", id))
+
+	res, err := s.esClient.Ingest.DeletePipeline(
+		id,
+		s.esClient.Ingest.DeletePipeline.WithContext(ctx),
+	)
+	if err != nil {
+		return false, fmt.Errorf("failed to delete ingest pipeline: %w", err)
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode == http.StatusNotFound {
+		return false, nil
+	}
+
+	if res.IsError() {
+		return false, shared.ParseESError(res)
+	}
+
+	s.logger.Info("Successfully deleted ingest pipeline", zap.String("pipeline", id))
+	return true, nil
+}
@@ -0,0 +1,197 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/saif-islam/es-playground/shared"
+)
+
+// refreshSuspension is an index whose refresh is disabled for one or more running bulk requests
+type refreshSuspension struct {
+	holders int
+	// refresh_interval of each concrete index behind the target before the first holder
+	// disabled it, nil when unset
+	original map[string]*string
+}
+
+// refreshSuspensions counts the bulk requests holding refresh off per index, so overlapping
+// requests disable it once and only the last one to finish restores the original value.
+// Requests queue on the lock while the first holder is still switching refresh off, so none
+// of them can read the disabled value as the one to restore.
+type refreshSuspensions struct {
+	mu     sync.Mutex
+	active map[string]*refreshSuspension
+}
+
+func newRefreshSuspensions() *refreshSuspensions {
+	return &refreshSuspensions{active: make(map[string]*refreshSuspension)}
+}
+
+// acquire registers a holder for the key, calling disable for the first one. disable
+// returns the refresh intervals to restore later.
+func (r *refreshSuspensions) acquire(key string, disable func() (map[string]*string, error)) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if suspension, ok := r.active[key]; ok {
+		suspension.holders++
+		return nil
+	}
+
+	original, err := disable()
+	if err != nil {
+		return err
+	}
+	r.active[key] = &refreshSuspension{holders: 1, original: original}
+	return nil
+}
+
+// release drops a holder for the key, calling restore with the original intervals once the
+// last holder is gone. The suspension is forgotten even if restoring fails, so a later
+// request reads the current value afresh.
+func (r *refreshSuspensions) release(key string, restore func(original map[string]*string) error) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	suspension, ok := r.active[key]
+	if !ok {
+		return nil
+	}
+
+	suspension.holders--
+	if suspension.holders > 0 {
+		return nil
+	}
+
+	delete(r.active, key)
+	return restore(suspension.original)
+}
+
+// suspendRefresh disables refresh on the index for the duration of a bulk request. The
+// returned function restores the original refresh intervals once every overlapping request
+// on the index is done; call it even when the bulk request fails. The index may be an alias
+// or date math expression, in which case each concrete index gets its own interval back.
+func (s *DocumentService) suspendRefresh(ctx context.Context, indexName string) (func(), error) {
+	// Indices of the same name on different clusters are suspended independently
+	cluster, _ := shared.ClusterFromContext(ctx)
+	key := cluster + "/" + indexName
+
+	err := s.refreshSuspensions.acquire(key, func() (map[string]*string, error) {
+		original, err := s.getRefreshIntervals(ctx, indexName)
+		if err != nil {
+			return nil, err
+		}
+
+		disabled := "-1"
+		for concrete := range original {
+			if err := s.putRefreshInterval(ctx, concrete, &disabled); err != nil {
+				return nil, err
+			}
+		}
+
+		s.logger.Info("Suspended refresh for bulk indexing",
+			zap.String("index", indexName),
+			zap.Int("concrete_indices", len(original)))
+		return original, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return func() {
+		// The request context may already be cancelled; refresh must come back regardless
+		restoreCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), 30*time.Second)
+		defer cancel()
+
+		err := s.refreshSuspensions.release(key, func(original map[string]*string) error {
+			var errs []error
+			for concrete, interval := range original {
+				if err := s.putRefreshInterval(restoreCtx, concrete, interval); err != nil {
+					errs = append(errs, fmt.Errorf("%s: %w", concrete, err))
+				}
+			}
+			return errors.Join(errs...)
+		})
+		if err != nil {
+			s.logger.Error("Failed to restore refresh interval after bulk indexing",
+				zap.String("index", indexName),
+				zap.Error(err))
+		}
+	}, nil
+}
+
+// getRefreshIntervals reads the refresh interval set on each concrete index the target
+// resolves to, nil for those using the default
+func (s *DocumentService) getRefreshIntervals(ctx context.Context, indexName string) (map[string]*string, error) {
+	res, err := s.esClient.Indices.GetSettings(
+		s.esClient.Indices.GetSettings.WithContext(ctx),
+		s.esClient.Indices.GetSettings.WithIndex(indexName),
+		s.esClient.Indices.GetSettings.WithName("index.refresh_interval"),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get refresh interval: %w", err)
+	}
+	defer res.Body.Close()
+
+	if res.IsError() {
+		return nil, shared.ParseESError(res)
+	}
+
+	var settings map[string]struct {
+		Settings struct {
+			Index struct {
+				RefreshInterval *string `json:"refresh_interval"`
+			} `json:"index"`
+		} `json:"settings"`
+	}
+	if err := shared.DecodeJSONResponse(res, &settings); err != nil {
+		return nil, fmt.Errorf("failed to decode index settings: %w", err)
+	}
+
+	// Settings are keyed by concrete index, which differs from the target for an alias or
+	// date math expression
+	intervals := make(map[string]*string, len(settings))
+	for concrete, entry := range settings {
+		intervals[concrete] = entry.Settings.Index.RefreshInterval
+	}
+	if len(intervals) == 0 {
+		return nil, fmt.Errorf("index %s not found in settings", indexName)
+	}
+	return intervals, nil
+}
+
+// putRefreshInterval sets the refresh interval, resetting it to the default when nil
+func (s *DocumentService) putRefreshInterval(ctx context.Context, indexName string, interval *string) error {
+	bodyBytes, err := json.Marshal(map[string]interface{}{
+		"index": map[string]interface{}{
+			"refresh_interval": interval,
+		},
+	})
+	if err != nil {
+		return fmt.Errorf("failed to marshal settings: %w", err)
+	}
+
+	res, err := s.esClient.Indices.PutSettings(
+		bytes.NewReader(bodyBytes),
+		s.esClient.Indices.PutSettings.WithContext(ctx),
+		s.esClient.Indices.PutSettings.WithIndex(indexName),
+	)
+	if err != nil {
+		return fmt.Errorf("failed to update refresh interval: %w", err)
+	}
+	defer res.Body.Close()
+
+	if res.IsError() {
+		return shared.ParseESError(res)
+	}
+
+	return nil
+}
@@ -0,0 +1,152 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/elastic/go-elasticsearch/v8/esapi"
+	"go.uber.org/zap"
+
+	"github.com/saif-islam/es-playground/projects/index-explorer/internal/models"
+	"github.com/saif-islam/es-playground/shared"
+)
+
+// ReindexOptions defines options for copying documents between indices
+type ReindexOptions struct {
+	// Slices splits the reindex into parallel sub-tasks; 0 uses one slice per source shard
+	Slices int
+	// Query limits the copy to matching source documents
+	Query map[string]interface{}
+	// Pipeline names an ingest pipeline documents pass through on their way in
+	Pipeline string
+}
+
+// Reindex copies the documents of source into dest, typically a freshly created
+// write-optimized index. It blocks until the copy is done; the reindex carries on in
+// Elasticsearch if ctx expires.
+func (s *IndexService) Reindex(ctx context.Context, source, dest string, opts ReindexOptions) (*models.ReindexResult, error) {
+	return s.reindex(ctx, source, dest, opts, true)
+}
+
+// StartReindex starts a reindex without waiting for it, returning its task ID
+func (s *IndexService) StartReindex(ctx context.Context, source, dest string, opts ReindexOptions) (*models.ReindexResult, error) {
+	return s.reindex(ctx, source, dest, opts, false)
+}
+
+func (s *IndexService) reindex(ctx context.Context, source, dest string, opts ReindexOptions, wait bool) (*models.ReindexResult, error) {
+	if source == "" {
+		return nil, fmt.Errorf("source index is required")
+	}
+	if source == dest {
+		return nil, fmt.Errorf("source and destination must be different indices")
+	}
+	if opts.Slices < 0 {
+		return nil, fmt.Errorf("slices must not be negative")
+	}
+
+	s.logger.Info("Reindexing",
+		zap.String("source_index", source),
+		zap.String("dest_index", dest),
+		zap.Int("slices", opts.Slices),
+		zap.String("pipeline", opts.Pipeline),
+		zap.Bool("wait_for_completion", wait))
+
+	bodyBytes, err := json.Marshal(buildReindexBody(source, dest, opts))
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal reindex body: %w", err)
+	}
+
+	// A blocking reindex outlasts the per-request timeout; ctx alone bounds it
+	if wait {
+		ctx = shared.WithRequestTimeout(ctx, 0)
+	}
+
+	options := []func(*esapi.ReindexRequest){
+		s.esClient.Reindex.WithContext(ctx),
+		s.esClient.Reindex.WithWaitForCompletion(wait),
+	}
+	if opts.Slices > 0 {
+		options = append(options, s.esClient.Reindex.WithSlices(opts.Slices))
+	} else {
+		options = append(options, s.esClient.Reindex.WithSlices("auto"))
+	}
+
+	res, err := s.esClient.Reindex(strings.NewReader(string(bodyBytes)), options...)
+	if err != nil {
+		return nil, fmt.Errorf("reindex request failed: %w", err)
+	}
+	defer res.Body.Close()
+
+	if res.IsError() {
+		return nil, shared.ParseESError(res)
+	}
+
+	var response struct {
+		Task             string `json:"task"`
+		Took             int64  `json:"took"`
+		TimedOut         bool   `json:"timed_out"`
+		Total            int64  `json:"total"`
+		Created          int64  `json:"created"`
+		Updated          int64  `json:"updated"`
+		Batches          int64  `json:"batches"`
+		VersionConflicts int64  `json:"version_conflicts"`
+		Failures         []struct {
+			ID    string `json:"id"`
+			Cause struct {
+				Type   string `json:"type"`
+				Reason string `json:"reason"`
+			} `json:"cause"`
+		} `json:"failures"`
+	}
+	if err := shared.DecodeJSONResponse(res, &response); err != nil {
+		return nil, fmt.Errorf("failed to decode reindex response: %w", err)
+	}
+
+	result := &models.ReindexResult{
+		SourceIndex:      source,
+		DestIndex:        dest,
+		Completed:        wait,
+		TaskID:           response.Task,
+		Total:            response.Total,
+		Created:          response.Created,
+		Updated:          response.Updated,
+		VersionConflicts: response.VersionConflicts,
+		Batches:          response.Batches,
+		TimedOut:         response.TimedOut,
+		Took:             time.Duration(response.Took) * time.Millisecond,
+	}
+	for _, failure := range response.Failures {
+		result.Failures = append(result.Failures,
+			fmt.Sprintf("%s: [%s] %s", failure.ID, failure.Cause.Type, failure.Cause.Reason))
+	}
+
+	s.logger.Info("Reindex finished",
+		zap.String("source_index", source),
+		zap.String("dest_index", dest),
+		zap.Bool("completed", result.Completed),
+		zap.String("task_id", result.TaskID),
+		zap.Int64("created", result.Created))
+
+	return result, nil
+}
+
+// buildReindexBody assembles the reindex request body
+func buildReindexBody(source, dest string, opts ReindexOptions) map[string]interface{} {
+	sourceBody := map[string]interface{}{"index": source}
+	if len(opts.Query) > 0 {
+		sourceBody["query"] = opts.Query
+	}
+
+	destBody := map[string]interface{}{"index": dest}
+	if opts.Pipeline != "" {
+		destBody["pipeline"] = opts.Pipeline
+	}
+
+	return map[string]interface{}{
+		"source": sourceBody,
+		"dest":   destBody,
+	}
+}
@@ -0,0 +1,250 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/saif-islam/es-playground/projects/index-explorer/internal/models"
+	"github.com/saif-islam/es-playground/shared"
+)
+
+// replicaAllocationPollInterval is how often allocation progress is checked during a ramp-up
+const replicaAllocationPollInterval = 2 * time.Second
+
+// SetReplicas updates the number of replicas of an index
+func (s *IndexService) SetReplicas(ctx context.Context, indexName string, count int) error {
+	if count < 0 {
+		return fmt.Errorf("replica count must not be negative")
+	}
+
+	s.logger.Info("Setting index replicas",
+		zap.String("index_name", indexName),
+		zap.Int("replicas", count))
+
+	return s.applyOptimizedSettings(ctx, indexName, map[string]interface{}{
+		"number_of_replicas": count,
+	})
+}
+
+// WaitForActiveShards blocks until the index has activeShards started shard copies, "all"
+// meaning every primary and replica, or the timeout expires. The returned step reports
+// Allocated false when it timed out.
+func (s *IndexService) WaitForActiveShards(ctx context.Context, indexName string, replicas int, activeShards string, timeout time.Duration) (*models.ReplicaRampStep, error) {
+	if err := validateActiveShards(activeShards); err != nil {
+		return nil, err
+	}
+
+	startTime := time.Now()
+
+	// Elasticsearch holds the request open for up to timeout
+	res, err := s.esClient.Cluster.Health(
+		s.esClient.Cluster.Health.WithContext(shared.WithRequestTimeout(ctx, 0)),
+		s.esClient.Cluster.Health.WithIndex(indexName),
+		s.esClient.Cluster.Health.WithWaitForActiveShards(activeShards),
+		s.esClient.Cluster.Health.WithTimeout(timeout),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to wait for active shards: %w", err)
+	}
+	defer res.Body.Close()
+
+	// A wait that times out is answered with 408 and the health at that point
+	if res.IsError() && res.StatusCode != http.StatusRequestTimeout {
+		return nil, shared.ParseESError(res)
+	}
+
+	var health indexHealth
+	if err := shared.DecodeJSONResponse(res, &health); err != nil {
+		return nil, err
+	}
+
+	step := &models.ReplicaRampStep{
+		Replicas:           replicas,
+		Status:             health.Status,
+		ActiveShards:       health.ActiveShards,
+		InitializingShards: health.InitializingShards,
+		RelocatingShards:   health.RelocatingShards,
+		UnassignedShards:   health.UnassignedShards,
+		Allocated:          !health.TimedOut,
+		Duration:           time.Since(startTime),
+	}
+
+	s.logger.Info("Waited for active shards",
+		zap.String("index_name", indexName),
+		zap.String("wait_for_active_shards", activeShards),
+		zap.Bool("allocated", step.Allocated),
+		zap.Int("active_shards", step.ActiveShards),
+		zap.Duration("duration", step.Duration))
+
+	return step, nil
+}
+
+// validateActiveShards checks a wait_for_active_shards value: "all" or a positive count
+func validateActiveShards(activeShards string) error {
+	if activeShards == "all" {
+		return nil
+	}
+	if count, err := strconv.Atoi(activeShards); err != nil || count < 1 {
+		return fmt.Errorf("wait_for_active_shards must be all or a positive integer")
+	}
+	return nil
+}
+
+// RampUpReplicas raises replicas one at a time from the current count to the target, waiting
+// for each step to allocate. This makes an index loaded with 0 replicas durable without
+// copying every shard at once. The ramp stops early if a step fails to allocate in time.
+func (s *IndexService) RampUpReplicas(ctx context.Context, indexName string, target int, stepTimeout time.Duration) (*models.ReplicaRampUpResult, error) {
+	startTime := time.Now()
+
+	current, err := s.getReplicaCount(ctx, indexName)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get current replica count: %w", err)
+	}
+
+	result := &models.ReplicaRampUpResult{
+		IndexName:      indexName,
+		FromReplicas:   current,
+		TargetReplicas: target,
+		Steps:          []models.ReplicaRampStep{},
+	}
+
+	// Lowering replicas needs no allocation, so apply it directly
+	if target <= current {
+		if target < current {
+			if err := s.SetReplicas(ctx, indexName, target); err != nil {
+				return nil, err
+			}
+		}
+		result.Completed = true
+		result.Duration = time.Since(startTime)
+		return result, nil
+	}
+
+	for replicas := current + 1; replicas <= target; replicas++ {
+		if err := s.SetReplicas(ctx, indexName, replicas); err != nil {
+			return nil, err
+		}
+
+		step, err := s.waitForReplicaAllocation(ctx, indexName, replicas, stepTimeout)
+		if err != nil {
+			return nil, err
+		}
+		result.Steps = append(result.Steps, *step)
+
+		if !step.Allocated {
+			s.logger.Warn("Replica ramp-up stopped, replicas did not allocate in time",
+				zap.String("index_name", indexName),
+				zap.Int("replicas", replicas),
+				zap.Int("unassigned_shards", step.UnassignedShards))
+			break
+		}
+	}
+
+	result.Completed = len(result.Steps) == target-current && result.Steps[len(result.Steps)-1].Allocated
+	result.Duration = time.Since(startTime)
+
+	s.logger.Info("Replica ramp-up finished",
+		zap.String("index_name", indexName),
+		zap.Int("from_replicas", current),
+		zap.Int("target_replicas", target),
+		zap.Bool("completed", result.Completed),
+		zap.Duration("duration", result.Duration))
+
+	return result, nil
+}
+
+// getReplicaCount returns the configured number of replicas of an index
+func (s *IndexService) getReplicaCount(ctx context.Context, indexName string) (int, error) {
+	settings, err := s.getCurrentIndexSettings(ctx, indexName)
+	if err != nil {
+		return 0, err
+	}
+
+	if index, ok := settings["index"].(map[string]interface{}); ok {
+		if replicas, ok := index["number_of_replicas"].(string); ok {
+			return strconv.Atoi(replicas)
+		}
+	}
+
+	return 0, fmt.Errorf("number_of_replicas not found in settings of index %s", indexName)
+}
+
+// waitForReplicaAllocation polls index health until all shard copies are active or the timeout expires
+func (s *IndexService) waitForReplicaAllocation(ctx context.Context, indexName string, replicas int, timeout time.Duration) (*models.ReplicaRampStep, error) {
+	stepStart := time.Now()
+	deadline := stepStart.Add(timeout)
+
+	for {
+		health, err := s.getIndexHealth(ctx, indexName)
+		if err != nil {
+			return nil, fmt.Errorf("failed to check replica allocation: %w", err)
+		}
+
+		step := &models.ReplicaRampStep{
+			Replicas:           replicas,
+			Status:             health.Status,
+			ActiveShards:       health.ActiveShards,
+			InitializingShards: health.InitializingShards,
+			RelocatingShards:   health.RelocatingShards,
+			UnassignedShards:   health.UnassignedShards,
+			Allocated:          health.Status == "green",
+			Duration:           time.Since(stepStart),
+		}
+
+		s.logger.Info("Replica allocation progress",
+			zap.String("index_name", indexName),
+			zap.Int("replicas", replicas),
+			zap.String("status", step.Status),
+			zap.Int("active_shards", step.ActiveShards),
+			zap.Int("initializing_shards", step.InitializingShards),
+			zap.Int("unassigned_shards", step.UnassignedShards))
+
+		if step.Allocated || time.Now().After(deadline) {
+			return step, nil
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-time.After(replicaAllocationPollInterval):
+		}
+	}
+}
+
+// indexHealth represents the allocation-related fields of the cluster health API for one index
+type indexHealth struct {
+	Status             string `json:"status"`
+	ActiveShards       int    `json:"active_shards"`
+	InitializingShards int    `json:"initializing_shards"`
+	RelocatingShards   int    `json:"relocating_shards"`
+	UnassignedShards   int    `json:"unassigned_shards"`
+	TimedOut           bool   `json:"timed_out"`
+}
+
+// getIndexHealth retrieves the current health of a single index
+func (s *IndexService) getIndexHealth(ctx context.Context, indexName string) (*indexHealth, error) {
+	res, err := s.esClient.Cluster.Health(
+		s.esClient.Cluster.Health.WithContext(ctx),
+		s.esClient.Cluster.Health.WithIndex(indexName),
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer res.Body.Close()
+
+	if res.IsError() {
+		return nil, shared.ParseESError(res)
+	}
+
+	var health indexHealth
+	if err := shared.DecodeJSONResponse(res, &health); err != nil {
+		return nil, err
+	}
+
+	return &health, nil
+}
@@ -0,0 +1,185 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/saif-islam/es-playground/projects/index-explorer/internal/models"
+	"github.com/saif-islam/es-playground/shared"
+)
+
+// ErrInvalidRestore is returned when a restore's parameters can't work
+var ErrInvalidRestore = errors.New("invalid restore")
+
+// Settings a restore can't override, or shouldn't: Elasticsearch rejects changes to the
+// shard count and soft-deletes, and mapping limits below what the snapshot's mappings
+// already use would fail the restore
+var restoreExcludedSettings = []string{
+	"index.number_of_shards",
+	"index.soft_deletes.enabled",
+	"index.mapping.",
+}
+
+// RestoreWriteOptimized restores index from a snapshot as a new index target, overriding
+// its settings with the write-optimized ones CreateIndex would generate for the request's
+// hints, so historical data reloads and re-ingests quickly. Aliases and global state are
+// not restored, leaving anything pointing at the original untouched. When wait is false
+// it returns once the restore is accepted.
+func (s *IndexService) RestoreWriteOptimized(ctx context.Context, index string, req *models.RestoreOptimizedRequest, wait bool) (*models.RestoreOptimizedResult, error) {
+	if req.Target == index {
+		return nil, fmt.Errorf("%w: target must differ from the snapshot index", ErrInvalidRestore)
+	}
+
+	startTime := time.Now()
+
+	exists, err := s.indexExists(ctx, req.Target)
+	if err != nil {
+		return nil, err
+	}
+	if exists {
+		return nil, fmt.Errorf("%w: target index %s already exists", ErrInvalidRestore, req.Target)
+	}
+
+	indexReq := &models.IndexRequest{
+		IndexName:       req.Target,
+		WriteOptimized:  true,
+		TextHeavy:       req.TextHeavy,
+		ExpectedVolume:  req.ExpectedVolume,
+		ExpectedDocSize: req.ExpectedDocSize,
+		IngestionRate:   req.IngestionRate,
+	}
+	indexSettings, err := restoreIndexSettings(s.buildOptimizedSettings(indexReq))
+	if err != nil {
+		return nil, err
+	}
+
+	body := map[string]interface{}{
+		"indices":              index,
+		"include_aliases":      false,
+		"include_global_state": false,
+		// Only the one index is restored, so the pattern always matches it whole
+		"rename_pattern":     "(.+)",
+		"rename_replacement": renameReplacement(req.Target),
+		"index_settings":     indexSettings,
+	}
+	bodyBytes, err := json.Marshal(body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal restore request: %w", err)
+	}
+
+	s.logger.Info("Restoring index write-optimized",
+		zap.String("repository", req.Repository),
+		zap.String("snapshot", req.Snapshot),
+		zap.String("source_index", index),
+		zap.String("target_index", req.Target),
+		zap.Any("index_settings", indexSettings))
+
+	// A blocking restore outlasts the per-request timeout; ctx alone bounds it
+	if wait {
+		ctx = shared.WithRequestTimeout(ctx, 0)
+	}
+
+	res, err := s.esClient.Snapshot.Restore(
+		req.Repository,
+		req.Snapshot,
+		s.esClient.Snapshot.Restore.WithContext(ctx),
+		s.esClient.Snapshot.Restore.WithBody(strings.NewReader(string(bodyBytes))),
+		s.esClient.Snapshot.Restore.WithWaitForCompletion(wait),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("restore request failed: %w", err)
+	}
+	defer res.Body.Close()
+
+	if res.IsError() {
+		return nil, shared.ParseESError(res)
+	}
+
+	var response struct {
+		Accepted bool `json:"accepted"`
+		Snapshot *struct {
+			Shards struct {
+				Total  int `json:"total"`
+				Failed int `json:"failed"`
+			} `json:"shards"`
+		} `json:"snapshot"`
+	}
+	if err := shared.DecodeJSONResponse(res, &response); err != nil {
+		return nil, fmt.Errorf("failed to decode restore response: %w", err)
+	}
+
+	result := &models.RestoreOptimizedResult{
+		Repository:    req.Repository,
+		Snapshot:      req.Snapshot,
+		SourceIndex:   index,
+		TargetIndex:   req.Target,
+		IndexSettings: indexSettings,
+		Optimizations: s.getAppliedOptimizations(indexReq),
+		Accepted:      response.Accepted || response.Snapshot != nil,
+		Completed:     response.Snapshot != nil,
+		Duration:      time.Since(startTime),
+	}
+	if response.Snapshot != nil {
+		result.TotalShards = response.Snapshot.Shards.Total
+		result.FailedShards = response.Snapshot.Shards.Failed
+	}
+
+	s.logger.Info("Restored index write-optimized",
+		zap.String("target_index", req.Target),
+		zap.Bool("completed", result.Completed),
+		zap.Duration("duration", result.Duration))
+
+	return result, nil
+}
+
+// restoreIndexSettings turns generated index settings into a restore's index_settings:
+// flat keys under index., without the settings a restore must leave alone
+func restoreIndexSettings(settings *models.IndexSettings) (map[string]interface{}, error) {
+	encoded, err := json.Marshal(settings)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal index settings: %w", err)
+	}
+	var generated map[string]interface{}
+	if err := json.Unmarshal(encoded, &generated); err != nil {
+		return nil, fmt.Errorf("failed to decode index settings: %w", err)
+	}
+
+	delete(generated, "additional")
+	for key, value := range settings.Additional {
+		generated[key] = value
+	}
+
+	indexSettings := make(map[string]interface{}, len(generated))
+	for key, value := range generated {
+		if !strings.HasPrefix(key, "index.") {
+			key = "index." + key
+		}
+		if excludedFromRestore(key) {
+			continue
+		}
+		indexSettings[key] = value
+	}
+	return indexSettings, nil
+}
+
+func excludedFromRestore(key string) bool {
+	for _, excluded := range restoreExcludedSettings {
+		if key == excluded || (strings.HasSuffix(excluded, ".") && strings.HasPrefix(key, excluded)) {
+			return true
+		}
+	}
+	return false
+}
+
+// renameReplacement escapes an index name for use as a Java regex replacement, where $
+// and \ are special
+func renameReplacement(name string) string {
+	return regexp.MustCompile(`[$\\]`).ReplaceAllString(name, `\$0`)
+}
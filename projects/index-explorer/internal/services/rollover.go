@@ -0,0 +1,381 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/saif-islam/es-playground/projects/index-explorer/internal/models"
+	"github.com/saif-islam/es-playground/shared"
+)
+
+// defaultRolloverInterval is how often watched aliases are checked when no interval is configured
+const defaultRolloverInterval = time.Minute
+
+// RolloverMonitorConfig lists the aliases to watch and their rollover thresholds
+type RolloverMonitorConfig struct {
+	Interval time.Duration
+	Aliases  map[string]models.RolloverThresholds
+}
+
+// RolloverMonitor rolls aliases over to a new write index once their current write index
+// crosses a size, age or document count threshold. It is a lightweight alternative to ILM
+// for write-heavy rolling indices; run it in a single instance, since two monitors would
+// each roll the alias over. Aliases are watched on the default cluster.
+type RolloverMonitor struct {
+	esClient *shared.ESClient
+	// Rolls aliases over, so the new write index gets its template or carried-over settings
+	indexService *IndexService
+	interval     time.Duration
+	logger       *zap.Logger
+
+	mu     sync.RWMutex
+	status map[string]*models.RolloverStatus
+	// Indexing totals from the previous check, used for the indexing rate
+	lastIndexTotal map[string]int64
+}
+
+// NewRolloverMonitor creates a monitor for the configured aliases; Start begins watching them
+func NewRolloverMonitor(esClient *shared.ESClient, indexService *IndexService, config RolloverMonitorConfig, logger *zap.Logger) (*RolloverMonitor, error) {
+	interval := config.Interval
+	if interval <= 0 {
+		interval = defaultRolloverInterval
+	}
+
+	monitor := &RolloverMonitor{
+		esClient:       esClient,
+		indexService:   indexService,
+		interval:       interval,
+		logger:         logger,
+		status:         make(map[string]*models.RolloverStatus),
+		lastIndexTotal: make(map[string]int64),
+	}
+
+	for alias, thresholds := range config.Aliases {
+		if err := monitor.Watch(alias, thresholds); err != nil {
+			return nil, err
+		}
+	}
+
+	return monitor, nil
+}
+
+// Start checks every watched alias on each interval until the context is cancelled
+func (m *RolloverMonitor) Start(ctx context.Context) {
+	go func() {
+		ticker := time.NewTicker(m.interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				m.checkAll(ctx)
+			}
+		}
+	}()
+}
+
+// Watch starts watching an alias, or replaces its thresholds if it is already watched
+func (m *RolloverMonitor) Watch(alias string, thresholds models.RolloverThresholds) error {
+	if err := validateRolloverThresholds(thresholds); err != nil {
+		return fmt.Errorf("invalid rollover thresholds for %s: %w", alias, err)
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if status, exists := m.status[alias]; exists {
+		status.Thresholds = thresholds
+		return nil
+	}
+	m.status[alias] = &models.RolloverStatus{Alias: alias, Thresholds: thresholds}
+	return nil
+}
+
+// Unwatch stops watching an alias, reporting whether it was watched
+func (m *RolloverMonitor) Unwatch(alias string) bool {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	_, exists := m.status[alias]
+	delete(m.status, alias)
+	delete(m.lastIndexTotal, alias)
+	return exists
+}
+
+// Status returns the latest check of every watched alias
+func (m *RolloverMonitor) Status() []models.RolloverStatus {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	statuses := make([]models.RolloverStatus, 0, len(m.status))
+	for _, status := range m.status {
+		statuses = append(statuses, *status)
+	}
+	sort.Slice(statuses, func(i, j int) bool { return statuses[i].Alias < statuses[j].Alias })
+	return statuses
+}
+
+// checkAll checks each watched alias in turn
+func (m *RolloverMonitor) checkAll(ctx context.Context) {
+	m.mu.RLock()
+	aliases := make(map[string]models.RolloverThresholds, len(m.status))
+	for alias, status := range m.status {
+		aliases[alias] = status.Thresholds
+	}
+	m.mu.RUnlock()
+
+	for alias, thresholds := range aliases {
+		checkCtx, cancel := context.WithTimeout(ctx, 30*time.Second)
+		m.checkAlias(checkCtx, alias, thresholds)
+		cancel()
+	}
+}
+
+// checkAlias measures the alias's write index and rolls it over if a threshold is crossed
+func (m *RolloverMonitor) checkAlias(ctx context.Context, alias string, thresholds models.RolloverThresholds) {
+	now := time.Now()
+	current := models.RolloverStatus{Alias: alias, Thresholds: thresholds, LastChecked: now}
+
+	writeIndex, err := m.getWriteIndex(ctx, alias)
+	if err == nil {
+		current.WriteIndex = writeIndex
+		err = m.measureWriteIndex(ctx, &current, now)
+	}
+
+	var event *models.RolloverEvent
+	if err == nil {
+		if reasons := rolloverReasons(thresholds, current, now); len(reasons) > 0 {
+			event, err = m.rollover(ctx, alias, reasons)
+		}
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	status, watched := m.status[alias]
+	if !watched {
+		return // unwatched while checking
+	}
+	lastRollover := status.LastRollover
+	*status = current
+	status.Thresholds = thresholds
+	status.LastRollover = lastRollover
+	if event != nil {
+		status.LastRollover = event
+	}
+	if err != nil {
+		status.LastError = err.Error()
+		m.logger.Warn("Rollover check failed", zap.String("alias", alias), zap.Error(err))
+	}
+}
+
+// getWriteIndex resolves the index an alias writes to
+func (m *RolloverMonitor) getWriteIndex(ctx context.Context, alias string) (string, error) {
+	res, err := m.esClient.Indices.GetAlias(
+		m.esClient.Indices.GetAlias.WithContext(ctx),
+		m.esClient.Indices.GetAlias.WithName(alias),
+	)
+	if err != nil {
+		return "", fmt.Errorf("failed to get alias: %w", err)
+	}
+	defer res.Body.Close()
+
+	if res.IsError() {
+		return "", shared.ParseESError(res)
+	}
+
+	var indices map[string]struct {
+		Aliases map[string]struct {
+			IsWriteIndex *bool `json:"is_write_index"`
+		} `json:"aliases"`
+	}
+	if err := shared.DecodeJSONResponse(res, &indices); err != nil {
+		return "", fmt.Errorf("failed to decode alias: %w", err)
+	}
+
+	// An alias over a single index writes to it unless told otherwise
+	for index, entry := range indices {
+		if flag := entry.Aliases[alias].IsWriteIndex; (flag != nil && *flag) || (flag == nil && len(indices) == 1) {
+			return index, nil
+		}
+	}
+	return "", fmt.Errorf("alias %s has no write index", alias)
+}
+
+// measureWriteIndex fills in the size, document count, age and indexing rate of the write index
+func (m *RolloverMonitor) measureWriteIndex(ctx context.Context, status *models.RolloverStatus, now time.Time) error {
+	res, err := m.esClient.Indices.Stats(
+		m.esClient.Indices.Stats.WithContext(ctx),
+		m.esClient.Indices.Stats.WithIndex(status.WriteIndex),
+		m.esClient.Indices.Stats.WithMetric("docs", "store", "indexing"),
+	)
+	if err != nil {
+		return fmt.Errorf("failed to get index stats: %w", err)
+	}
+	defer res.Body.Close()
+
+	if res.IsError() {
+		return shared.ParseESError(res)
+	}
+
+	var stats struct {
+		Indices map[string]models.IndexStats `json:"indices"`
+	}
+	if err := shared.DecodeJSONResponse(res, &stats); err != nil {
+		return fmt.Errorf("failed to decode stats response: %w", err)
+	}
+
+	indexStats, ok := stats.Indices[status.WriteIndex]
+	if !ok || indexStats.Primaries == nil {
+		return fmt.Errorf("index %s not found in stats", status.WriteIndex)
+	}
+	primaries := indexStats.Primaries
+	status.DocsCount = primaries.Docs.Count
+	status.PrimarySizeGB = float64(primaries.Store.SizeInBytes) / (1 << 30)
+
+	m.mu.Lock()
+	previous, seen := m.lastIndexTotal[status.Alias]
+	m.lastIndexTotal[status.Alias] = primaries.Indexing.IndexTotal
+	lastChecked := time.Time{}
+	if existing, ok := m.status[status.Alias]; ok && existing.WriteIndex == status.WriteIndex {
+		lastChecked = existing.LastChecked
+	}
+	m.mu.Unlock()
+
+	// Totals restart with each new write index, so only compare checks of the same index
+	if seen && !lastChecked.IsZero() && primaries.Indexing.IndexTotal >= previous {
+		if elapsed := now.Sub(lastChecked).Seconds(); elapsed > 0 {
+			status.IndexingRate = float64(primaries.Indexing.IndexTotal-previous) / elapsed
+		}
+	}
+
+	createdAt, err := m.getCreationDate(ctx, status.WriteIndex)
+	if err != nil {
+		return err
+	}
+	status.Age = now.Sub(createdAt).Truncate(time.Second).String()
+
+	return nil
+}
+
+// getCreationDate reads when an index was created
+func (m *RolloverMonitor) getCreationDate(ctx context.Context, index string) (time.Time, error) {
+	res, err := m.esClient.Indices.GetSettings(
+		m.esClient.Indices.GetSettings.WithContext(ctx),
+		m.esClient.Indices.GetSettings.WithIndex(index),
+		m.esClient.Indices.GetSettings.WithName("index.creation_date"),
+	)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("failed to get index settings: %w", err)
+	}
+	defer res.Body.Close()
+
+	if res.IsError() {
+		return time.Time{}, shared.ParseESError(res)
+	}
+
+	var settings map[string]struct {
+		Settings struct {
+			Index struct {
+				CreationDate string `json:"creation_date"`
+			} `json:"index"`
+		} `json:"settings"`
+	}
+	if err := shared.DecodeJSONResponse(res, &settings); err != nil {
+		return time.Time{}, fmt.Errorf("failed to decode index settings: %w", err)
+	}
+
+	millis, err := strconv.ParseInt(settings[index].Settings.Index.CreationDate, 10, 64)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("index %s has no creation date", index)
+	}
+	return time.UnixMilli(millis), nil
+}
+
+// rollover rolls the alias over to a new write index. The thresholds were already checked,
+// so the rollover is unconditional.
+func (m *RolloverMonitor) rollover(ctx context.Context, alias string, reasons []string) (*models.RolloverEvent, error) {
+	result, err := m.indexService.Rollover(ctx, alias, models.RolloverConditions{})
+	if err != nil {
+		return nil, err
+	}
+	if !result.RolledOver {
+		return nil, fmt.Errorf("alias %s was not rolled over", alias)
+	}
+
+	m.logger.Info("Rolled over alias",
+		zap.String("alias", alias),
+		zap.String("old_index", result.OldIndex),
+		zap.String("new_index", result.NewIndex),
+		zap.String("template", result.Template),
+		zap.Strings("reasons", reasons))
+
+	return &models.RolloverEvent{
+		OldIndex: result.OldIndex,
+		NewIndex: result.NewIndex,
+		Reasons:  reasons,
+		At:       time.Now(),
+	}, nil
+}
+
+// rolloverReasons lists the thresholds the write index has crossed
+func rolloverReasons(thresholds models.RolloverThresholds, status models.RolloverStatus, now time.Time) []string {
+	var reasons []string
+
+	if thresholds.MaxPrimarySizeGB > 0 && status.PrimarySizeGB >= thresholds.MaxPrimarySizeGB {
+		reasons = append(reasons, fmt.Sprintf("primary size %.1fGB reached %.1fGB", status.PrimarySizeGB, thresholds.MaxPrimarySizeGB))
+	}
+
+	if thresholds.MaxDocs > 0 && status.DocsCount >= thresholds.MaxDocs {
+		reasons = append(reasons, fmt.Sprintf("%d documents reached %d", status.DocsCount, thresholds.MaxDocs))
+	}
+
+	if thresholds.MaxAge != "" {
+		maxAge, _ := parseTimeValue(thresholds.MaxAge)
+		if age, err := time.ParseDuration(status.Age); err == nil && maxAge > 0 && age >= maxAge {
+			reasons = append(reasons, fmt.Sprintf("age %s reached %s", status.Age, thresholds.MaxAge))
+		}
+	}
+
+	return reasons
+}
+
+// validateRolloverThresholds checks that at least one usable threshold is set
+func validateRolloverThresholds(thresholds models.RolloverThresholds) error {
+	if thresholds.MaxPrimarySizeGB < 0 || thresholds.MaxDocs < 0 {
+		return fmt.Errorf("thresholds must not be negative")
+	}
+	if thresholds.MaxAge != "" {
+		if _, err := parseTimeValue(thresholds.MaxAge); err != nil {
+			return err
+		}
+	}
+	if thresholds.MaxPrimarySizeGB == 0 && thresholds.MaxDocs == 0 && thresholds.MaxAge == "" {
+		return fmt.Errorf("at least one of max_primary_size_gb, max_age or max_docs is required")
+	}
+	return nil
+}
+
+// rolloverAgePattern matches the day, hour, minute and second time values accepted for max_age
+var rolloverAgePattern = regexp.MustCompile(`^(\d+)(d|h|m|s)$`)
+
+// parseTimeValue parses an Elasticsearch-style time value such as 7d or 12h
+func parseTimeValue(value string) (time.Duration, error) {
+	match := rolloverAgePattern.FindStringSubmatch(value)
+	if match == nil {
+		return 0, fmt.Errorf("%q is not a valid time value (e.g. 7d, 12h, 30m)", value)
+	}
+
+	amount, _ := strconv.Atoi(match[1])
+	units := map[string]time.Duration{"d": 24 * time.Hour, "h": time.Hour, "m": time.Minute, "s": time.Second}
+	return time.Duration(amount) * units[match[2]], nil
+}
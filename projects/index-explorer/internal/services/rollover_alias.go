@@ -0,0 +1,267 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"go.uber.org/zap"
+
+	"github.com/saif-islam/es-playground/projects/index-explorer/internal/models"
+	"github.com/saif-islam/es-playground/shared"
+)
+
+// rolloverSizePattern matches the byte size values accepted for max_size
+var rolloverSizePattern = regexp.MustCompile(`^\d+(b|kb|mb|gb|tb|pb)$`)
+
+// inheritedSettingPrefixes are the flat settings buildOptimizedSettings can set; the rest of
+// an index's settings (uuid, creation date, provided name...) belong to that index alone
+var inheritedSettingPrefixes = []string{
+	"index.number_of_shards",
+	"index.number_of_replicas",
+	"index.refresh_interval",
+	"index.translog.",
+	"index.merge.",
+	"index.codec",
+}
+
+// Rollover rolls the alias over to a new write index once one of the conditions is met.
+// The new index gets its settings from the highest priority index template matching its
+// name, such as one made by CreateIndexTemplate. When no template matches, the old write
+// index's optimized settings are carried over instead of falling back to cluster defaults.
+func (s *IndexService) Rollover(ctx context.Context, alias string, conditions models.RolloverConditions) (*models.RolloverResult, error) {
+	if err := validateRolloverConditions(conditions); err != nil {
+		return nil, err
+	}
+
+	s.logger.Info("Rolling over alias",
+		zap.String("alias", alias),
+		zap.String("max_age", conditions.MaxAge),
+		zap.Int64("max_docs", conditions.MaxDocs),
+		zap.String("max_size", conditions.MaxSize))
+
+	// A dry run names the new index, so we know which template it will match
+	planned, err := s.rollover(ctx, alias, buildRolloverBody(conditions, nil), true)
+	if err != nil {
+		return nil, err
+	}
+
+	templates, err := s.ListIndexTemplates(ctx, "")
+	if err != nil {
+		return nil, fmt.Errorf("failed to check index templates: %w", err)
+	}
+
+	var inherited map[string]interface{}
+	template := matchIndexTemplate(templates, planned.NewIndex)
+	if template == nil {
+		inherited, err = s.getInheritableSettings(ctx, planned.OldIndex)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read settings of %s: %w", planned.OldIndex, err)
+		}
+		s.logger.Warn("No index template matches the new index, carrying settings over",
+			zap.String("alias", alias),
+			zap.String("new_index", planned.NewIndex),
+			zap.Int("settings", len(inherited)))
+	}
+
+	result, err := s.rollover(ctx, alias, buildRolloverBody(conditions, inherited), false)
+	if err != nil {
+		return nil, err
+	}
+
+	if template != nil {
+		result.Template = template.Name
+	}
+	if result.RolledOver {
+		result.InheritedSettings = inherited
+	}
+
+	s.logger.Info("Rollover finished",
+		zap.String("alias", alias),
+		zap.Bool("rolled_over", result.RolledOver),
+		zap.String("old_index", result.OldIndex),
+		zap.String("new_index", result.NewIndex),
+		zap.String("template", result.Template))
+
+	return result, nil
+}
+
+// rollover calls the rollover API for the alias
+func (s *IndexService) rollover(ctx context.Context, alias string, body map[string]interface{}, dryRun bool) (*models.RolloverResult, error) {
+	bodyBytes, err := json.Marshal(body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal rollover body: %w", err)
+	}
+
+	res, err := s.esClient.Indices.Rollover(
+		alias,
+		s.esClient.Indices.Rollover.WithContext(ctx),
+		s.esClient.Indices.Rollover.WithBody(strings.NewReader(string(bodyBytes))),
+		s.esClient.Indices.Rollover.WithDryRun(dryRun),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to roll over alias: %w", err)
+	}
+	defer res.Body.Close()
+
+	if res.IsError() {
+		return nil, shared.ParseESError(res)
+	}
+
+	var response struct {
+		OldIndex   string          `json:"old_index"`
+		NewIndex   string          `json:"new_index"`
+		RolledOver bool            `json:"rolled_over"`
+		Conditions map[string]bool `json:"conditions"`
+	}
+	if err := shared.DecodeJSONResponse(res, &response); err != nil {
+		return nil, fmt.Errorf("failed to decode rollover response: %w", err)
+	}
+
+	return &models.RolloverResult{
+		Alias:      alias,
+		OldIndex:   response.OldIndex,
+		NewIndex:   response.NewIndex,
+		RolledOver: response.RolledOver,
+		Conditions: response.Conditions,
+	}, nil
+}
+
+// buildRolloverBody assembles the rollover request body
+func buildRolloverBody(conditions models.RolloverConditions, settings map[string]interface{}) map[string]interface{} {
+	body := map[string]interface{}{}
+
+	rolloverConditions := map[string]interface{}{}
+	if conditions.MaxAge != "" {
+		rolloverConditions["max_age"] = conditions.MaxAge
+	}
+	if conditions.MaxDocs > 0 {
+		rolloverConditions["max_docs"] = conditions.MaxDocs
+	}
+	if conditions.MaxSize != "" {
+		rolloverConditions["max_size"] = conditions.MaxSize
+	}
+	if len(rolloverConditions) > 0 {
+		body["conditions"] = rolloverConditions
+	}
+
+	if len(settings) > 0 {
+		body["settings"] = settings
+	}
+
+	return body
+}
+
+// validateRolloverConditions checks the format of each condition that is set
+func validateRolloverConditions(conditions models.RolloverConditions) error {
+	if conditions.MaxDocs < 0 {
+		return fmt.Errorf("max_docs must not be negative")
+	}
+	if conditions.MaxAge != "" {
+		if _, err := parseTimeValue(conditions.MaxAge); err != nil {
+			return err
+		}
+	}
+	if conditions.MaxSize != "" && !rolloverSizePattern.MatchString(strings.ToLower(conditions.MaxSize)) {
+		return fmt.Errorf("%q is not a valid byte size (e.g. 50gb, 500mb)", conditions.MaxSize)
+	}
+	return nil
+}
+
+// matchIndexTemplate returns the template Elasticsearch applies to a new index: the highest
+// priority one with a matching pattern, or nil if none matches
+func matchIndexTemplate(templates []models.IndexTemplateInfo, indexName string) *models.IndexTemplateInfo {
+	var best *models.IndexTemplateInfo
+	for i := range templates {
+		template := &templates[i]
+		for _, pattern := range template.IndexPatterns {
+			if matchesIndexPattern(pattern, indexName) && (best == nil || template.Priority > best.Priority) {
+				best = template
+				break
+			}
+		}
+	}
+	return best
+}
+
+// matchesIndexPattern reports whether an index name matches a pattern using * wildcards
+func matchesIndexPattern(pattern, name string) bool {
+	parts := strings.Split(pattern, "*")
+	if len(parts) == 1 {
+		return pattern == name
+	}
+
+	if !strings.HasPrefix(name, parts[0]) {
+		return false
+	}
+	name = name[len(parts[0]):]
+
+	for _, part := range parts[1 : len(parts)-1] {
+		i := strings.Index(name, part)
+		if i < 0 {
+			return false
+		}
+		name = name[i+len(part):]
+	}
+
+	return strings.HasSuffix(name, parts[len(parts)-1])
+}
+
+// getInheritableSettings returns the optimized settings of an index that a successor
+// index should keep
+func (s *IndexService) getInheritableSettings(ctx context.Context, indexName string) (map[string]interface{}, error) {
+	res, err := s.esClient.Indices.GetSettings(
+		s.esClient.Indices.GetSettings.WithContext(ctx),
+		s.esClient.Indices.GetSettings.WithIndex(indexName),
+		s.esClient.Indices.GetSettings.WithFlatSettings(true),
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer res.Body.Close()
+
+	if res.IsError() {
+		return nil, shared.ParseESError(res)
+	}
+
+	var response map[string]struct {
+		Settings map[string]interface{} `json:"settings"`
+	}
+	if err := shared.DecodeJSONResponse(res, &response); err != nil {
+		return nil, err
+	}
+
+	return inheritableSettings(response[indexName].Settings), nil
+}
+
+// inheritableSettings keeps the flat settings listed in inheritedSettingPrefixes. A disabled
+// refresh or zero replicas are usually left over from a bulk load or import into the old
+// index, not meant for its successor, so those values are left to the cluster defaults.
+func inheritableSettings(settings map[string]interface{}) map[string]interface{} {
+	inherited := make(map[string]interface{})
+	for key, value := range settings {
+		if transientLoadSetting(key, value) {
+			continue
+		}
+		for _, prefix := range inheritedSettingPrefixes {
+			if strings.HasPrefix(key, prefix) {
+				inherited[key] = value
+				break
+			}
+		}
+	}
+	return inherited
+}
+
+// transientLoadSetting reports whether a setting value is one bulk loads apply temporarily
+func transientLoadSetting(key string, value interface{}) bool {
+	switch key {
+	case "index.refresh_interval":
+		return fmt.Sprint(value) == "-1"
+	case "index.number_of_replicas":
+		return fmt.Sprint(value) == "0"
+	}
+	return false
+}
@@ -0,0 +1,112 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/saif-islam/es-playground/projects/index-explorer/internal/models"
+	"github.com/saif-islam/es-playground/shared"
+)
+
+func TestRolloverMonitor_Thresholds(t *testing.T) {
+	if d, err := parseTimeValue("7d"); err != nil || d != 7*24*time.Hour {
+		t.Errorf("Expected 7d to parse as 168h, got %v (%v)", d, err)
+	}
+	if _, err := parseTimeValue("1w"); err == nil {
+		t.Errorf("Expected an error for an unsupported time unit")
+	}
+
+	thresholds := models.RolloverThresholds{MaxPrimarySizeGB: 50, MaxAge: "7d"}
+	now := time.Now()
+
+	status := models.RolloverStatus{PrimarySizeGB: 12.5, DocsCount: 1000, Age: "24h0m0s"}
+	if reasons := rolloverReasons(thresholds, status, now); len(reasons) != 0 {
+		t.Errorf("Expected no rollover below thresholds, got %v", reasons)
+	}
+
+	status = models.RolloverStatus{PrimarySizeGB: 51, Age: "192h0m0s"}
+	if reasons := rolloverReasons(thresholds, status, now); len(reasons) != 2 {
+		t.Errorf("Expected size and age to trigger a rollover, got %v", reasons)
+	}
+
+	// Unset thresholds are ignored rather than always triggering
+	status = models.RolloverStatus{DocsCount: 1 << 40}
+	if reasons := rolloverReasons(thresholds, status, now); len(reasons) != 0 {
+		t.Errorf("Expected max_docs to be ignored when unset, got %v", reasons)
+	}
+
+	monitor, err := NewRolloverMonitor(nil, nil, RolloverMonitorConfig{}, zap.NewNop())
+	if err != nil {
+		t.Fatalf("Expected an empty monitor, got %v", err)
+	}
+	if err := monitor.Watch("logs-write", models.RolloverThresholds{}); err == nil {
+		t.Errorf("Expected an alias without thresholds to be rejected")
+	}
+	if err := monitor.Watch("logs-write", thresholds); err != nil {
+		t.Fatalf("Expected thresholds to be accepted, got %v", err)
+	}
+	if statuses := monitor.Status(); len(statuses) != 1 || statuses[0].Thresholds != thresholds {
+		t.Errorf("Expected one watched alias with its thresholds, got %+v", statuses)
+	}
+	if !monitor.Unwatch("logs-write") || monitor.Unwatch("logs-write") {
+		t.Errorf("Expected the alias to be unwatched exactly once")
+	}
+}
+
+func TestRolloverMonitor_RolloverThroughIndexService(t *testing.T) {
+	// Fake cluster: logs-000001 has refresh and replicas switched off by a bulk load, and
+	// no template matches its successor
+	var rolloverBody string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("X-Elastic-Product", "Elasticsearch")
+		w.Header().Set("Content-Type", "application/json")
+		switch {
+		case strings.HasSuffix(r.URL.Path, "/_rollover"):
+			dryRun := r.URL.Query().Get("dry_run") == "true"
+			if !dryRun {
+				body, _ := io.ReadAll(r.Body)
+				rolloverBody = string(body)
+			}
+			fmt.Fprintf(w, `{"old_index":"logs-000001","new_index":"logs-000002","rolled_over":%t,"dry_run":%t}`, !dryRun, dryRun)
+		case strings.HasPrefix(r.URL.Path, "/_index_template"):
+			w.Write([]byte(`{"index_templates":[]}`))
+		case strings.HasSuffix(r.URL.Path, "/_settings"):
+			w.Write([]byte(`{"logs-000001":{"settings":{"index.refresh_interval":"-1","index.number_of_replicas":"0","index.translog.durability":"async"}}}`))
+		default:
+			w.Write([]byte(`{}`))
+		}
+	}))
+	defer server.Close()
+
+	client, err := shared.NewESClient(&shared.ESConfig{URLs: []string{server.URL}}, zap.NewNop())
+	if err != nil {
+		t.Fatalf("Failed to create client: %v", err)
+	}
+	monitor, err := NewRolloverMonitor(client, NewIndexService(client, zap.NewNop()), RolloverMonitorConfig{}, zap.NewNop())
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	event, err := monitor.rollover(context.Background(), "logs-write", []string{"primary size 51.0GB reached 50.0GB"})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if event.NewIndex != "logs-000002" {
+		t.Errorf("Expected logs-000002, got %s", event.NewIndex)
+	}
+
+	if !strings.Contains(rolloverBody, "index.translog.durability") {
+		t.Errorf("Expected the old index's settings to carry over, got %s", rolloverBody)
+	}
+	if strings.Contains(rolloverBody, "refresh_interval") || strings.Contains(rolloverBody, "number_of_replicas") {
+		t.Errorf("Expected bulk load settings to stay behind, got %s", rolloverBody)
+	}
+}
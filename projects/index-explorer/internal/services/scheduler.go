@@ -0,0 +1,124 @@
+package services
+
+import (
+	"fmt"
+	"sync"
+
+	"github.com/saif-islam/es-playground/projects/index-explorer/internal/models"
+)
+
+// Bulk operation priorities, from most to least urgent
+const (
+	PriorityHigh   = "high"
+	PriorityNormal = "normal"
+	PriorityLow    = "low"
+)
+
+// Scheduler sizing: shared workers take any priority, reserved workers only take high
+// priority work so an interactive write never waits for a long import batch to finish
+const (
+	defaultSchedulerWorkers = 16
+	reservedHighWorkers     = 1
+)
+
+// priorityOrder lists the scheduler queues in the order workers drain them
+var priorityOrder = []string{PriorityHigh, PriorityNormal, PriorityLow}
+
+// validatePriority checks a bulk request priority
+func validatePriority(priority string) error {
+	for _, p := range priorityOrder {
+		if priority == p {
+			return nil
+		}
+	}
+	return fmt.Errorf("invalid priority %q (must be high, normal or low)", priority)
+}
+
+// bulkScheduler runs batches from all bulk requests on one shared pool of workers, always
+// starting queued high-priority batches before normal ones and normal before low. Each
+// request bounds how many of its own batches are queued at once (its ParallelWorkers),
+// so a large import never fills the queues ahead of later arrivals.
+type bulkScheduler struct {
+	mu      sync.Mutex
+	cond    *sync.Cond
+	queues  map[string][]func()
+	running map[string]int
+}
+
+// newBulkScheduler starts a scheduler with the given number of shared workers
+func newBulkScheduler(workers int) *bulkScheduler {
+	s := &bulkScheduler{
+		queues:  make(map[string][]func()),
+		running: make(map[string]int),
+	}
+	s.cond = sync.NewCond(&s.mu)
+
+	for i := 0; i < workers; i++ {
+		go s.worker(priorityOrder)
+	}
+	for i := 0; i < reservedHighWorkers; i++ {
+		go s.worker([]string{PriorityHigh})
+	}
+
+	return s
+}
+
+// submit queues a task at the given priority
+func (s *bulkScheduler) submit(priority string, task func()) {
+	s.mu.Lock()
+	s.queues[priority] = append(s.queues[priority], task)
+	s.mu.Unlock()
+
+	// Reserved workers sleep on the same condition, so wake everyone to be sure one that
+	// can take this priority notices it
+	s.cond.Broadcast()
+}
+
+// worker runs tasks from the given queues, highest priority first
+func (s *bulkScheduler) worker(priorities []string) {
+	for {
+		s.mu.Lock()
+		priority, task := s.next(priorities)
+		for task == nil {
+			s.cond.Wait()
+			priority, task = s.next(priorities)
+		}
+		s.running[priority]++
+		s.mu.Unlock()
+
+		task()
+
+		s.mu.Lock()
+		s.running[priority]--
+		s.mu.Unlock()
+	}
+}
+
+// next pops the first task of the highest-priority non-empty queue; callers hold s.mu
+func (s *bulkScheduler) next(priorities []string) (string, func()) {
+	for _, priority := range priorities {
+		if queue := s.queues[priority]; len(queue) > 0 {
+			task := queue[0]
+			queue[0] = nil
+			s.queues[priority] = queue[1:]
+			return priority, task
+		}
+	}
+	return "", nil
+}
+
+// stats returns the number of queued and running batches per priority
+func (s *bulkScheduler) stats() *models.BulkQueueStats {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	stats := &models.BulkQueueStats{
+		Queued:  make(map[string]int, len(priorityOrder)),
+		Running: make(map[string]int, len(priorityOrder)),
+	}
+	for _, priority := range priorityOrder {
+		stats.Queued[priority] = len(s.queues[priority])
+		stats.Running[priority] = s.running[priority]
+	}
+	return stats
+}
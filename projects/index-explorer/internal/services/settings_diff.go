@@ -0,0 +1,135 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/saif-islam/es-playground/projects/index-explorer/internal/models"
+	"github.com/saif-islam/es-playground/shared"
+)
+
+// ErrInvalidSettingsDiff is returned when a proposed settings change can't be compared
+var ErrInvalidSettingsDiff = errors.New("invalid settings diff")
+
+// DiffIndexSettings compares proposed settings with an index's current ones without
+// applying anything, annotating each change the way OptimizeIndex does. Proposed settings
+// may be nested or flat, with or without the "index." prefix. Settings the index doesn't
+// set explicitly are compared against their Elasticsearch defaults.
+func (s *IndexService) DiffIndexSettings(ctx context.Context, indexName string, proposed map[string]interface{}) (*models.SettingsDiffResponse, error) {
+	if len(proposed) == 0 {
+		return nil, fmt.Errorf("%w: at least one setting is required", ErrInvalidSettingsDiff)
+	}
+
+	current, err := s.getFlatIndexSettings(ctx, indexName)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get current settings: %w", err)
+	}
+
+	flat := flattenIndexSettings(proposed)
+	changes := s.calculateOptimizationChanges(current, flat)
+
+	var unchanged []string
+	for key := range flat {
+		if settingValuesEqual(current[key], flat[key]) {
+			unchanged = append(unchanged, key)
+		}
+	}
+	sort.Strings(unchanged)
+
+	s.logger.Info("Diffed index settings",
+		zap.String("index_name", indexName),
+		zap.Int("proposed", len(flat)),
+		zap.Int("changes", len(changes)))
+
+	return &models.SettingsDiffResponse{
+		IndexName:         indexName,
+		Changes:           changes,
+		Unchanged:         unchanged,
+		PerformanceImpact: s.estimatePerformanceImpact(changes),
+		RequestID:         s.generateRequestID(),
+		Timestamp:         time.Now(),
+	}, nil
+}
+
+// getFlatIndexSettings returns an index's settings keyed by their full dotted names, with
+// defaults filled in for anything not set explicitly
+func (s *IndexService) getFlatIndexSettings(ctx context.Context, indexName string) (map[string]interface{}, error) {
+	res, err := s.esClient.Indices.GetSettings(
+		s.esClient.Indices.GetSettings.WithContext(ctx),
+		s.esClient.Indices.GetSettings.WithIndex(indexName),
+		s.esClient.Indices.GetSettings.WithIncludeDefaults(true),
+		s.esClient.Indices.GetSettings.WithFlatSettings(true),
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer res.Body.Close()
+
+	if res.IsError() {
+		return nil, shared.ParseESError(res)
+	}
+
+	var response map[string]struct {
+		Settings map[string]interface{} `json:"settings"`
+		Defaults map[string]interface{} `json:"defaults"`
+	}
+	if err := shared.DecodeJSONResponse(res, &response); err != nil {
+		return nil, err
+	}
+
+	entry, ok := response[indexName]
+	if !ok {
+		return nil, &shared.ResponseError{
+			StatusCode: http.StatusNotFound,
+			Reason:     fmt.Sprintf("index %s not found", indexName),
+		}
+	}
+
+	settings := make(map[string]interface{}, len(entry.Defaults)+len(entry.Settings))
+	for key, value := range entry.Defaults {
+		settings[key] = value
+	}
+	for key, value := range entry.Settings {
+		settings[key] = value
+	}
+	return settings, nil
+}
+
+// flattenIndexSettings turns nested settings into dotted names under "index."
+func flattenIndexSettings(settings map[string]interface{}) map[string]interface{} {
+	flat := make(map[string]interface{})
+	var walk func(prefix string, value interface{})
+	walk = func(prefix string, value interface{}) {
+		if nested, ok := value.(map[string]interface{}); ok && len(nested) > 0 {
+			for key, child := range nested {
+				walk(prefix+"."+key, child)
+			}
+			return
+		}
+		flat[prefix] = value
+	}
+
+	for key, value := range settings {
+		if key != "index" && !strings.HasPrefix(key, "index.") {
+			key = "index." + key
+		}
+		walk(key, value)
+	}
+	return flat
+}
+
+// settingValuesEqual compares setting values by their string form, since Elasticsearch
+// returns every setting as a string while requests often use numbers and booleans
+func settingValuesEqual(a, b interface{}) bool {
+	if a == nil || b == nil {
+		return a == nil && b == nil
+	}
+	return fmt.Sprint(a) == fmt.Sprint(b)
+}
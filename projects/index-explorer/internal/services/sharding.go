@@ -0,0 +1,167 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/saif-islam/es-playground/projects/index-explorer/internal/models"
+	"github.com/saif-islam/es-playground/shared"
+)
+
+// Shard sizing guidance: shards of 20-50GB balance recovery time against per-shard overhead
+const (
+	minShardSizeGB    = 20.0
+	targetShardSizeGB = 40.0
+	maxShardSizeGB    = 50.0
+
+	// Elasticsearch's default cluster.max_shards_per_node
+	maxShardsPerNode = 1000
+)
+
+// AdviseShardCount recommends a primary shard count for the expected data volume. Growing
+// data with a retention period is treated as time-based indices with rollover, which is
+// how it should be stored; otherwise a single index holds the expected total size. The
+// cluster's data nodes and current shards are used to warn about over-sharding.
+func (s *IndexService) AdviseShardCount(ctx context.Context, expectedTotalSizeGB float64, retentionDays int, expectedDailyGrowthGB float64) (*models.ShardAdvice, error) {
+	if expectedTotalSizeGB < 0 || retentionDays < 0 || expectedDailyGrowthGB < 0 {
+		return nil, fmt.Errorf("sizes and retention must not be negative")
+	}
+	if expectedTotalSizeGB == 0 && expectedDailyGrowthGB == 0 {
+		return nil, fmt.Errorf("expected total size or daily growth is required")
+	}
+
+	advice := adviseShards(expectedTotalSizeGB, retentionDays, expectedDailyGrowthGB)
+
+	dataNodes, currentShards, err := s.getShardCapacity(ctx)
+	if err != nil {
+		s.logger.Warn("Could not read cluster shard capacity for shard advice", zap.Error(err))
+		advice.Warnings = append(advice.Warnings, "cluster health unavailable, shards per node not checked")
+	} else {
+		advice.DataNodes = dataNodes
+		advice.CurrentShards = currentShards
+		advice.Warnings = append(advice.Warnings, shardCapacityWarnings(dataNodes, currentShards, advice.TotalShards)...)
+	}
+
+	advice.RequestID = s.generateRequestID()
+	advice.Timestamp = time.Now()
+
+	return advice, nil
+}
+
+// adviseShards sizes shards from data volume alone
+func adviseShards(totalGB float64, retentionDays int, dailyGB float64) *models.ShardAdvice {
+	advice := &models.ShardAdvice{RetainedSizeGB: totalGB}
+	if dailyGB > 0 && retentionDays > 0 && dailyGB*float64(retentionDays) > totalGB {
+		advice.RetainedSizeGB = dailyGB * float64(retentionDays)
+	}
+
+	var indexSizeGB float64
+	switch {
+	case dailyGB <= 0:
+		// One index holds everything
+		indexSizeGB = advice.RetainedSizeGB
+		advice.RecommendedPrimaryShards = shardsFor(indexSizeGB)
+		advice.RetainedIndices = 1
+		advice.Rationale = append(advice.Rationale,
+			fmt.Sprintf("%.1fGB in one index, split so each primary shard stays near the %.0fGB target", indexSizeGB, targetShardSizeGB))
+
+	case dailyGB >= minShardSizeGB:
+		// A day of data is already a healthy index on its own
+		indexSizeGB = dailyGB
+		advice.RecommendedPrimaryShards = shardsFor(indexSizeGB)
+		advice.Rollover = &models.RolloverAdvice{
+			MaxPrimaryShardSize: fmt.Sprintf("%.0fgb", maxShardSizeGB),
+			MaxAge:              "1d",
+		}
+		advice.Rationale = append(advice.Rationale,
+			fmt.Sprintf("%.1fGB per day fills daily indices, so roll over daily (or at %.0fGB per primary shard)", dailyGB, maxShardSizeGB))
+
+	default:
+		// Daily indices would be undersized; keep rolling one shard until it reaches the target
+		days := int(math.Ceil(targetShardSizeGB / dailyGB))
+		if retentionDays > 0 && days > retentionDays {
+			days = retentionDays
+		}
+		indexSizeGB = dailyGB * float64(days)
+		advice.RecommendedPrimaryShards = 1
+		advice.Rollover = &models.RolloverAdvice{
+			MaxPrimaryShardSize: fmt.Sprintf("%.0fgb", targetShardSizeGB),
+			MaxAge:              fmt.Sprintf("%dd", days),
+		}
+		advice.Rationale = append(advice.Rationale,
+			fmt.Sprintf("%.1fGB per day would make daily indices of undersized shards; rolling over every %d days (or at %.0fGB) keeps one well-sized shard per index", dailyGB, days, targetShardSizeGB))
+	}
+
+	advice.ProjectedShardSizeGB = roundGB(indexSizeGB / float64(advice.RecommendedPrimaryShards))
+	if advice.RetainedIndices == 0 {
+		advice.RetainedIndices = int(math.Max(1, math.Ceil(advice.RetainedSizeGB/indexSizeGB)))
+	}
+	advice.TotalShards = advice.RecommendedPrimaryShards * advice.RetainedIndices * 2
+	advice.RetainedSizeGB = roundGB(advice.RetainedSizeGB)
+
+	advice.Rationale = append(advice.Rationale,
+		fmt.Sprintf("%d retained index(es) of %d primary shard(s) with one replica is %d shards in total", advice.RetainedIndices, advice.RecommendedPrimaryShards, advice.TotalShards))
+
+	// The expected_volume presets create 3 or 5 primaries regardless of size
+	if indexSizeGB/3 < minShardSizeGB {
+		advice.Warnings = append(advice.Warnings,
+			fmt.Sprintf("the medium/high expected_volume presets (3/5 shards) would over-shard %.1fGB indices; set number_of_shards to %d explicitly", indexSizeGB, advice.RecommendedPrimaryShards))
+	}
+
+	return advice
+}
+
+// shardsFor returns the number of primaries that keeps an index's shards near the target size
+func shardsFor(indexSizeGB float64) int {
+	return int(math.Max(1, math.Ceil(indexSizeGB/targetShardSizeGB)))
+}
+
+// roundGB rounds a size to one decimal place
+func roundGB(sizeGB float64) float64 {
+	return math.Round(sizeGB*10) / 10
+}
+
+// shardCapacityWarnings flags plans that would push data nodes towards the shards-per-node limit
+func shardCapacityWarnings(dataNodes, currentShards, newShards int) []string {
+	if dataNodes == 0 {
+		return []string{"no data nodes reported by the cluster"}
+	}
+
+	perNode := float64(currentShards+newShards) / float64(dataNodes)
+	switch {
+	case perNode > maxShardsPerNode:
+		return []string{fmt.Sprintf("%.0f shards per data node would exceed the default limit of %d; add data nodes or reduce shards or retention", perNode, maxShardsPerNode)}
+	case perNode > maxShardsPerNode*0.8:
+		return []string{fmt.Sprintf("%.0f shards per data node is close to the default limit of %d", perNode, maxShardsPerNode)}
+	}
+	return nil
+}
+
+// getShardCapacity returns the number of data nodes and active shards in the cluster
+func (s *IndexService) getShardCapacity(ctx context.Context) (int, int, error) {
+	res, err := s.esClient.Cluster.Health(
+		s.esClient.Cluster.Health.WithContext(ctx),
+	)
+	if err != nil {
+		return 0, 0, err
+	}
+	defer res.Body.Close()
+
+	if res.IsError() {
+		return 0, 0, shared.ParseESError(res)
+	}
+
+	var health struct {
+		NumberOfDataNodes int `json:"number_of_data_nodes"`
+		ActiveShards      int `json:"active_shards"`
+	}
+	if err := shared.DecodeJSONResponse(res, &health); err != nil {
+		return 0, 0, err
+	}
+
+	return health.NumberOfDataNodes, health.ActiveShards, nil
+}
@@ -0,0 +1,316 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/saif-islam/es-playground/projects/index-explorer/internal/models"
+	"github.com/saif-islam/es-playground/shared"
+)
+
+// How long a shrink waits for a copy of every source shard to gather on one node
+const shrinkRelocationTimeout = 5 * time.Minute
+
+// ErrInvalidShrink is returned when a shrink's parameters can't work for the source index
+var ErrInvalidShrink = errors.New("invalid shrink")
+
+// ShardsNotColocatedError is returned when a copy of every source shard could not be
+// gathered on the shrink node, which Elasticsearch requires before it will shrink
+type ShardsNotColocatedError struct {
+	Index string
+	Node  string
+	// Shard numbers with no started copy on Node
+	Missing []int
+}
+
+func (e *ShardsNotColocatedError) Error() string {
+	return fmt.Sprintf("shards %v of index %s are not on node %s; check the node's disk watermarks and the index's allocation filters with GET _cluster/allocation/explain, then retry",
+		e.Missing, e.Index, e.Node)
+}
+
+// shardCopy is a row of the cat shards API
+type shardCopy struct {
+	Shard  string `json:"shard"`
+	PriRep string `json:"prirep"`
+	State  string `json:"state"`
+	Node   string `json:"node"`
+}
+
+// ShrinkIndex shrinks source into a new index target with targetShards primary shards,
+// typically to consolidate a write-heavy index once ingestion is done. It follows the
+// steps Elasticsearch requires: the source is blocked for writes, a copy of every shard is
+// relocated to a single node, and the shrink runs once relocation is done. The source is
+// left read-only on that node so it can be checked against the target before deletion.
+func (s *IndexService) ShrinkIndex(ctx context.Context, source, target string, targetShards int) (*models.ShrinkResult, error) {
+	if target == "" {
+		return nil, fmt.Errorf("%w: target index is required", ErrInvalidShrink)
+	}
+	if source == target {
+		return nil, fmt.Errorf("%w: source and target must be different indices", ErrInvalidShrink)
+	}
+
+	startTime := time.Now()
+
+	sourceShards, err := s.getShardCount(ctx, source)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get shard count: %w", err)
+	}
+	if err := validateShrink(sourceShards, targetShards); err != nil {
+		return nil, err
+	}
+
+	copies, err := s.getShardCopies(ctx, source)
+	if err != nil {
+		return nil, err
+	}
+	node := pickShrinkNode(copies)
+	if node == "" {
+		return nil, fmt.Errorf("index %s has no started shards to shrink", source)
+	}
+
+	s.logger.Info("Shrinking index",
+		zap.String("source_index", source),
+		zap.String("target_index", target),
+		zap.Int("source_shards", sourceShards),
+		zap.Int("target_shards", targetShards),
+		zap.String("node", node))
+
+	// Writes during relocation would be lost to the target
+	if err := s.applyOptimizedSettings(ctx, source, map[string]interface{}{
+		"routing.allocation.require._name": node,
+		"blocks.write":                     true,
+	}); err != nil {
+		return nil, fmt.Errorf("failed to prepare source index: %w", err)
+	}
+
+	if err := s.waitForShardColocation(ctx, source, node, sourceShards); err != nil {
+		return nil, err
+	}
+
+	// The target must not inherit the source's write block or node pinning
+	body := map[string]interface{}{
+		"settings": map[string]interface{}{
+			"index.number_of_shards":                 targetShards,
+			"index.routing.allocation.require._name": nil,
+			"index.blocks.write":                     nil,
+		},
+	}
+	bodyBytes, err := json.Marshal(body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal shrink body: %w", err)
+	}
+
+	res, err := s.esClient.Indices.Shrink(source, target,
+		s.esClient.Indices.Shrink.WithContext(ctx),
+		s.esClient.Indices.Shrink.WithBody(strings.NewReader(string(bodyBytes))),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("shrink request failed: %w", err)
+	}
+	defer res.Body.Close()
+
+	if res.IsError() {
+		err := shared.ParseESError(res)
+		// Shards can move off the node between our check and the shrink
+		if strings.Contains(err.Error(), "same node") {
+			if missing, checkErr := s.missingShardsOnNode(ctx, source, node, sourceShards); checkErr == nil && len(missing) > 0 {
+				return nil, &ShardsNotColocatedError{Index: source, Node: node, Missing: missing}
+			}
+		}
+		return nil, err
+	}
+
+	var response struct {
+		Acknowledged       bool `json:"acknowledged"`
+		ShardsAcknowledged bool `json:"shards_acknowledged"`
+	}
+	if err := shared.DecodeJSONResponse(res, &response); err != nil {
+		return nil, fmt.Errorf("failed to decode shrink response: %w", err)
+	}
+
+	result := &models.ShrinkResult{
+		SourceIndex:        source,
+		TargetIndex:        target,
+		SourceShards:       sourceShards,
+		TargetShards:       targetShards,
+		Node:               node,
+		Acknowledged:       response.Acknowledged,
+		ShardsAcknowledged: response.ShardsAcknowledged,
+		Duration:           time.Since(startTime),
+	}
+
+	s.logger.Info("Shrink finished",
+		zap.String("source_index", source),
+		zap.String("target_index", target),
+		zap.Bool("shards_acknowledged", result.ShardsAcknowledged),
+		zap.Duration("duration", result.Duration))
+
+	return result, nil
+}
+
+// validateShrink checks a shrink's target shard count; Elasticsearch only shrinks to a
+// factor of the source's primary shard count
+func validateShrink(sourceShards, targetShards int) error {
+	if targetShards < 1 {
+		return fmt.Errorf("%w: target_shards must be at least 1", ErrInvalidShrink)
+	}
+	if targetShards >= sourceShards {
+		return fmt.Errorf("%w: target_shards (%d) must be fewer than the source's %d shards",
+			ErrInvalidShrink, targetShards, sourceShards)
+	}
+	if sourceShards%targetShards != 0 {
+		return fmt.Errorf("%w: target_shards (%d) must be a factor of the source's %d shards",
+			ErrInvalidShrink, targetShards, sourceShards)
+	}
+	return nil
+}
+
+// getShardCount returns the number of primary shards of an index
+func (s *IndexService) getShardCount(ctx context.Context, indexName string) (int, error) {
+	settings, err := s.getCurrentIndexSettings(ctx, indexName)
+	if err != nil {
+		return 0, err
+	}
+
+	if index, ok := settings["index"].(map[string]interface{}); ok {
+		if shards, ok := index["number_of_shards"].(string); ok {
+			return strconv.Atoi(shards)
+		}
+	}
+
+	return 0, fmt.Errorf("number_of_shards not found in settings of index %s", indexName)
+}
+
+// getShardCopies lists where every shard copy of an index is allocated
+func (s *IndexService) getShardCopies(ctx context.Context, indexName string) ([]shardCopy, error) {
+	res, err := s.esClient.Cat.Shards(
+		s.esClient.Cat.Shards.WithContext(ctx),
+		s.esClient.Cat.Shards.WithIndex(indexName),
+		s.esClient.Cat.Shards.WithFormat("json"),
+		s.esClient.Cat.Shards.WithH("shard", "prirep", "state", "node"),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get shard allocation: %w", err)
+	}
+	defer res.Body.Close()
+
+	if res.IsError() {
+		return nil, shared.ParseESError(res)
+	}
+
+	var copies []shardCopy
+	if err := shared.DecodeJSONResponse(res, &copies); err != nil {
+		return nil, fmt.Errorf("failed to decode shard allocation: %w", err)
+	}
+	return copies, nil
+}
+
+// pickShrinkNode chooses the node already holding the most started copies of distinct
+// shards, preferring primaries, so the fewest shards have to move
+func pickShrinkNode(copies []shardCopy) string {
+	type candidate struct {
+		shards    map[string]bool
+		primaries int
+	}
+	candidates := make(map[string]*candidate)
+	for _, entry := range copies {
+		if entry.State != "STARTED" || entry.Node == "" {
+			continue
+		}
+		c, ok := candidates[entry.Node]
+		if !ok {
+			c = &candidate{shards: make(map[string]bool)}
+			candidates[entry.Node] = c
+		}
+		c.shards[entry.Shard] = true
+		if entry.PriRep == "p" {
+			c.primaries++
+		}
+	}
+
+	nodes := make([]string, 0, len(candidates))
+	for node := range candidates {
+		nodes = append(nodes, node)
+	}
+	sort.Slice(nodes, func(i, j int) bool {
+		a, b := candidates[nodes[i]], candidates[nodes[j]]
+		if len(a.shards) != len(b.shards) {
+			return len(a.shards) > len(b.shards)
+		}
+		if a.primaries != b.primaries {
+			return a.primaries > b.primaries
+		}
+		return nodes[i] < nodes[j]
+	})
+
+	if len(nodes) == 0 {
+		return ""
+	}
+	return nodes[0]
+}
+
+// missingShards returns the shard numbers below shardCount with no started copy on node
+func missingShards(copies []shardCopy, node string, shardCount int) []int {
+	present := make(map[string]bool)
+	for _, entry := range copies {
+		if entry.Node == node && entry.State == "STARTED" {
+			present[entry.Shard] = true
+		}
+	}
+
+	var missing []int
+	for shard := 0; shard < shardCount; shard++ {
+		if !present[strconv.Itoa(shard)] {
+			missing = append(missing, shard)
+		}
+	}
+	return missing
+}
+
+// missingShardsOnNode looks up which shards of an index have no started copy on node
+func (s *IndexService) missingShardsOnNode(ctx context.Context, indexName, node string, shardCount int) ([]int, error) {
+	copies, err := s.getShardCopies(ctx, indexName)
+	if err != nil {
+		return nil, err
+	}
+	return missingShards(copies, node, shardCount), nil
+}
+
+// waitForShardColocation polls until a copy of every shard is started on node, returning
+// a *ShardsNotColocatedError if that takes too long
+func (s *IndexService) waitForShardColocation(ctx context.Context, indexName, node string, shardCount int) error {
+	deadline := time.Now().Add(shrinkRelocationTimeout)
+
+	for {
+		missing, err := s.missingShardsOnNode(ctx, indexName, node, shardCount)
+		if err != nil {
+			return fmt.Errorf("failed to check shard relocation: %w", err)
+		}
+		if len(missing) == 0 {
+			return nil
+		}
+
+		s.logger.Info("Waiting for shards to relocate for shrink",
+			zap.String("index_name", indexName),
+			zap.String("node", node),
+			zap.Ints("missing_shards", missing))
+
+		if time.Now().After(deadline) {
+			return &ShardsNotColocatedError{Index: indexName, Node: node, Missing: missing}
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(replicaAllocationPollInterval):
+		}
+	}
+}
@@ -0,0 +1,75 @@
+package services
+
+import (
+	"context"
+	"fmt"
+
+	"go.uber.org/zap"
+
+	"github.com/saif-islam/es-playground/projects/index-explorer/internal/models"
+)
+
+// ConfigureSlowLog sets the index's native search and indexing slow-log thresholds so
+// Elasticsearch itself logs slow operations. It returns the settings that were applied.
+func (s *IndexService) ConfigureSlowLog(ctx context.Context, indexName string, thresholds *models.SlowLogThresholds) (map[string]interface{}, error) {
+	settings, err := buildSlowLogSettings(thresholds)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(settings) == 0 {
+		return nil, fmt.Errorf("no slow-log thresholds provided")
+	}
+
+	s.logger.Info("Configuring index slow-log thresholds",
+		zap.String("index_name", indexName),
+		zap.Int("settings", len(settings)))
+
+	if err := s.applyOptimizedSettings(ctx, indexName, settings); err != nil {
+		return nil, fmt.Errorf("failed to apply slow-log settings: %w", err)
+	}
+
+	return settings, nil
+}
+
+// buildSlowLogSettings maps thresholds to index slow-log setting keys
+func buildSlowLogSettings(thresholds *models.SlowLogThresholds) (map[string]interface{}, error) {
+	settings := make(map[string]interface{})
+	if thresholds == nil {
+		return settings, nil
+	}
+
+	operations := []struct {
+		prefix string
+		levels *models.SlowLogLevels
+	}{
+		{"search.slowlog.threshold.query", thresholds.Query},
+		{"search.slowlog.threshold.fetch", thresholds.Fetch},
+		{"indexing.slowlog.threshold.index", thresholds.Indexing},
+	}
+
+	for _, op := range operations {
+		if op.levels == nil {
+			continue
+		}
+
+		levels := map[string]string{
+			"warn":  op.levels.Warn,
+			"info":  op.levels.Info,
+			"debug": op.levels.Debug,
+			"trace": op.levels.Trace,
+		}
+		for level, threshold := range levels {
+			if threshold == "" {
+				continue
+			}
+			if threshold != "-1" && !timeValuePattern.MatchString(threshold) {
+				return nil, fmt.Errorf("%s.%s threshold %q is not a valid time value (e.g. 500ms, 2s, or -1 to disable)",
+					op.prefix, level, threshold)
+			}
+			settings[op.prefix+"."+level] = threshold
+		}
+	}
+
+	return settings, nil
+}
@@ -0,0 +1,203 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/bits"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/saif-islam/es-playground/projects/index-explorer/internal/models"
+	"github.com/saif-islam/es-playground/shared"
+)
+
+// ErrInvalidSplit is returned when a split's preconditions don't hold for the source index
+var ErrInvalidSplit = errors.New("invalid split")
+
+// SplitIndex splits source into a new index target with targetShards primary shards, to
+// scale out an index whose shard count has become a write bottleneck. The source is
+// blocked for writes, as Elasticsearch requires, and left that way so it can be checked
+// against the target before deletion.
+//
+// Every target shard must hold whole routing shards, so targetShards has to be a multiple
+// of the source's shards and a factor of its number_of_routing_shards. Indices that did
+// not set number_of_routing_shards can be split by powers of two up to about 1024 shards.
+func (s *IndexService) SplitIndex(ctx context.Context, source, target string, targetShards int) (*models.SplitResult, error) {
+	if target == "" {
+		return nil, fmt.Errorf("%w: target index is required", ErrInvalidSplit)
+	}
+	if source == target {
+		return nil, fmt.Errorf("%w: source and target must be different indices", ErrInvalidSplit)
+	}
+
+	startTime := time.Now()
+
+	sourceShards, routingShards, err := s.getSplitShardCounts(ctx, source)
+	if err != nil {
+		return nil, err
+	}
+	if err := validateSplit(sourceShards, routingShards, targetShards); err != nil {
+		return nil, err
+	}
+
+	// Check before blocking writes on the source for nothing
+	exists, err := s.indexExists(ctx, target)
+	if err != nil {
+		return nil, err
+	}
+	if exists {
+		return nil, fmt.Errorf("%w: target index %s already exists", ErrInvalidSplit, target)
+	}
+
+	s.logger.Info("Splitting index",
+		zap.String("source_index", source),
+		zap.String("target_index", target),
+		zap.Int("source_shards", sourceShards),
+		zap.Int("target_shards", targetShards),
+		zap.Int("routing_shards", routingShards))
+
+	if err := s.applyOptimizedSettings(ctx, source, map[string]interface{}{
+		"blocks.write": true,
+	}); err != nil {
+		return nil, fmt.Errorf("failed to block writes on source index: %w", err)
+	}
+
+	// The target must not inherit the source's write block
+	body := map[string]interface{}{
+		"settings": map[string]interface{}{
+			"index.number_of_shards": targetShards,
+			"index.blocks.write":     nil,
+		},
+	}
+	bodyBytes, err := json.Marshal(body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal split body: %w", err)
+	}
+
+	res, err := s.esClient.Indices.Split(source, target,
+		s.esClient.Indices.Split.WithContext(ctx),
+		s.esClient.Indices.Split.WithBody(strings.NewReader(string(bodyBytes))),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("split request failed: %w", err)
+	}
+	defer res.Body.Close()
+
+	if res.IsError() {
+		return nil, shared.ParseESError(res)
+	}
+
+	var response struct {
+		Acknowledged       bool `json:"acknowledged"`
+		ShardsAcknowledged bool `json:"shards_acknowledged"`
+	}
+	if err := shared.DecodeJSONResponse(res, &response); err != nil {
+		return nil, fmt.Errorf("failed to decode split response: %w", err)
+	}
+
+	result := &models.SplitResult{
+		SourceIndex:        source,
+		TargetIndex:        target,
+		SourceShards:       sourceShards,
+		TargetShards:       targetShards,
+		RoutingShards:      routingShards,
+		Acknowledged:       response.Acknowledged,
+		ShardsAcknowledged: response.ShardsAcknowledged,
+		Duration:           time.Since(startTime),
+	}
+
+	s.logger.Info("Split finished",
+		zap.String("source_index", source),
+		zap.String("target_index", target),
+		zap.Bool("shards_acknowledged", result.ShardsAcknowledged),
+		zap.Duration("duration", result.Duration))
+
+	return result, nil
+}
+
+// validateSplit checks a split's target shard count against the source's shard and
+// routing shard counts
+func validateSplit(sourceShards, routingShards, targetShards int) error {
+	if targetShards <= sourceShards {
+		return fmt.Errorf("%w: target_shards (%d) must be more than the source's %d shards",
+			ErrInvalidSplit, targetShards, sourceShards)
+	}
+	if targetShards%sourceShards != 0 {
+		return fmt.Errorf("%w: target_shards (%d) must be a multiple of the source's %d shards",
+			ErrInvalidSplit, targetShards, sourceShards)
+	}
+	if routingShards%targetShards != 0 {
+		return fmt.Errorf("%w: target_shards (%d) must be a factor of the source's number_of_routing_shards (%d); "+
+			"reindex into an index created with a suitable number_of_routing_shards instead",
+			ErrInvalidSplit, targetShards, routingShards)
+	}
+	return nil
+}
+
+// getSplitShardCounts returns an index's primary shard count and its number of routing shards
+func (s *IndexService) getSplitShardCounts(ctx context.Context, indexName string) (int, int, error) {
+	settings, err := s.getCurrentIndexSettings(ctx, indexName)
+	if err != nil {
+		return 0, 0, fmt.Errorf("failed to get index settings: %w", err)
+	}
+
+	index, _ := settings["index"].(map[string]interface{})
+	value, ok := index["number_of_shards"].(string)
+	if !ok {
+		return 0, 0, fmt.Errorf("number_of_shards not found in settings of index %s", indexName)
+	}
+	shards, err := strconv.Atoi(value)
+	if err != nil {
+		return 0, 0, fmt.Errorf("invalid number_of_shards %q: %w", value, err)
+	}
+
+	// Only present in the settings when it was set at creation
+	if value, ok := index["number_of_routing_shards"].(string); ok {
+		routingShards, err := strconv.Atoi(value)
+		if err != nil {
+			return 0, 0, fmt.Errorf("invalid number_of_routing_shards %q: %w", value, err)
+		}
+		return shards, routingShards, nil
+	}
+
+	return shards, defaultRoutingShards(shards), nil
+}
+
+// defaultRoutingShards mirrors how Elasticsearch picks number_of_routing_shards when an
+// index doesn't set it: the largest shards*2^n up to 1024, allowing at least one split
+func defaultRoutingShards(shards int) int {
+	if shards < 1 {
+		return shards
+	}
+	log2Shards := bits.Len(uint(shards - 1)) // ceil(log2(shards))
+	splits := 10 - log2Shards
+	if splits < 1 {
+		splits = 1
+	}
+	return shards << splits
+}
+
+// indexExists reports whether an index exists
+func (s *IndexService) indexExists(ctx context.Context, indexName string) (bool, error) {
+	res, err := s.esClient.Indices.Exists([]string{indexName},
+		s.esClient.Indices.Exists.WithContext(ctx),
+	)
+	if err != nil {
+		return false, fmt.Errorf("failed to check index %s: %w", indexName, err)
+	}
+	defer res.Body.Close()
+
+	switch res.StatusCode {
+	case http.StatusOK:
+		return true, nil
+	case http.StatusNotFound:
+		return false, nil
+	}
+	return false, shared.ParseESError(res)
+}
@@ -0,0 +1,201 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/elastic/go-elasticsearch/v8/esapi"
+	"go.uber.org/zap"
+
+	"github.com/saif-islam/es-playground/projects/index-explorer/internal/models"
+	"github.com/saif-islam/es-playground/shared"
+)
+
+// CreateIndexTemplate creates or replaces a composable index template. Indices matching its
+// patterns, such as rolling daily indices, get the settings CreateIndex would apply for
+// the same write-optimized and text-heavy flags.
+func (s *IndexService) CreateIndexTemplate(ctx context.Context, req *models.IndexTemplateRequest) (*models.IndexTemplateResponse, error) {
+	s.logger.Info("Creating index template",
+		zap.String("template_name", req.TemplateName),
+		zap.Strings("index_patterns", req.IndexPatterns),
+		zap.Bool("write_optimized", req.WriteOptimized),
+		zap.Bool("text_heavy", req.TextHeavy))
+
+	if len(req.IndexPatterns) == 0 {
+		return nil, fmt.Errorf("at least one index pattern is required")
+	}
+
+	indexReq := templateIndexRequest(req)
+	settings := s.buildOptimizedSettings(indexReq)
+
+	if err := s.validateSoftDeletesSettings(ctx, settings.SoftDeletesEnabled, settings.SoftDeletesRetentionLeasePeriod); err != nil {
+		return nil, fmt.Errorf("invalid soft-deletes configuration: %w", err)
+	}
+
+	bodyBytes, err := json.Marshal(buildIndexTemplateBody(req, settings))
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal index template: %w", err)
+	}
+
+	res, err := s.esClient.Indices.PutIndexTemplate(
+		req.TemplateName,
+		strings.NewReader(string(bodyBytes)),
+		s.esClient.Indices.PutIndexTemplate.WithContext(ctx),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to put index template: %w", err)
+	}
+	defer res.Body.Close()
+
+	if res.IsError() {
+		return nil, shared.ParseESError(res)
+	}
+
+	var putResponse struct {
+		Acknowledged bool `json:"acknowledged"`
+	}
+	if err := shared.DecodeJSONResponse(res, &putResponse); err != nil {
+		return nil, fmt.Errorf("failed to decode index template response: %w", err)
+	}
+
+	optimizations := s.getAppliedOptimizations(indexReq)
+
+	s.logger.Info("Successfully created index template",
+		zap.String("template_name", req.TemplateName),
+		zap.Strings("optimizations", optimizations))
+
+	return &models.IndexTemplateResponse{
+		TemplateName:  req.TemplateName,
+		Acknowledged:  putResponse.Acknowledged,
+		IndexPatterns: req.IndexPatterns,
+		Settings:      settings,
+		Optimizations: optimizations,
+		RequestID:     s.generateRequestID(),
+		Timestamp:     time.Now(),
+	}, nil
+}
+
+// templateIndexRequest expresses a template request as the index request its matching
+// indices would be created with, so both share buildOptimizedSettings
+func templateIndexRequest(req *models.IndexTemplateRequest) *models.IndexRequest {
+	return &models.IndexRequest{
+		IndexName:       req.TemplateName,
+		Settings:        req.Settings,
+		Mappings:        req.Mappings,
+		Aliases:         req.Aliases,
+		WriteOptimized:  req.WriteOptimized,
+		TextHeavy:       req.TextHeavy,
+		ExpectedVolume:  req.ExpectedVolume,
+		ExpectedDocSize: req.ExpectedDocSize,
+	}
+}
+
+// buildIndexTemplateBody assembles the put index template request body
+func buildIndexTemplateBody(req *models.IndexTemplateRequest, settings *models.IndexSettings) map[string]interface{} {
+	body := map[string]interface{}{
+		"index_patterns": req.IndexPatterns,
+		"template":       buildCreateIndexBody(templateIndexRequest(req), settings),
+	}
+
+	if req.Priority > 0 {
+		body["priority"] = req.Priority
+	}
+
+	if req.Version > 0 {
+		body["version"] = req.Version
+	}
+
+	if req.Metadata != nil {
+		body["_meta"] = req.Metadata
+	}
+
+	return body
+}
+
+// ListIndexTemplates lists composable index templates, optionally only those whose name
+// matches a wildcard expression
+func (s *IndexService) ListIndexTemplates(ctx context.Context, name string) ([]models.IndexTemplateInfo, error) {
+	options := []func(*esapi.IndicesGetIndexTemplateRequest){
+		s.esClient.Indices.GetIndexTemplate.WithContext(ctx),
+	}
+	if name != "" {
+		options = append(options, s.esClient.Indices.GetIndexTemplate.WithName(name))
+	}
+
+	res, err := s.esClient.Indices.GetIndexTemplate(options...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get index templates: %w", err)
+	}
+	defer res.Body.Close()
+
+	// A name that matches nothing is a 404 rather than an empty list
+	if res.StatusCode == http.StatusNotFound {
+		return []models.IndexTemplateInfo{}, nil
+	}
+
+	if res.IsError() {
+		return nil, shared.ParseESError(res)
+	}
+
+	var response struct {
+		IndexTemplates []struct {
+			Name          string `json:"name"`
+			IndexTemplate struct {
+				IndexPatterns []string               `json:"index_patterns"`
+				Priority      int                    `json:"priority"`
+				Version       int                    `json:"version"`
+				ComposedOf    []string               `json:"composed_of"`
+				Template      map[string]interface{} `json:"template"`
+				Metadata      map[string]interface{} `json:"_meta"`
+			} `json:"index_template"`
+		} `json:"index_templates"`
+	}
+	if err := shared.DecodeJSONResponse(res, &response); err != nil {
+		return nil, fmt.Errorf("failed to decode index templates: %w", err)
+	}
+
+	templates := make([]models.IndexTemplateInfo, 0, len(response.IndexTemplates))
+	for _, entry := range response.IndexTemplates {
+		templates = append(templates, models.IndexTemplateInfo{
+			Name:          entry.Name,
+			IndexPatterns: entry.IndexTemplate.IndexPatterns,
+			Priority:      entry.IndexTemplate.Priority,
+			Version:       entry.IndexTemplate.Version,
+			ComposedOf:    entry.IndexTemplate.ComposedOf,
+			Template:      entry.IndexTemplate.Template,
+			Metadata:      entry.IndexTemplate.Metadata,
+		})
+	}
+
+	return templates, nil
+}
+
+// DeleteIndexTemplate deletes a composable index template, reporting false if it did not exist.
+// Indices already created from the template keep their settings.
+func (s *IndexService) DeleteIndexTemplate(ctx context.Context, name string) (bool, error) {
+	s.logger.Info("Deleting index template", zap.String("template_name", name))
+
+	res, err := s.esClient.Indices.DeleteIndexTemplate(
+		name,
+		s.esClient.Indices.DeleteIndexTemplate.WithContext(ctx),
+	)
+	if err != nil {
+		return false, fmt.Errorf("failed to delete index template: %w", err)
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode == http.StatusNotFound {
+		return false, nil
+	}
+
+	if res.IsError() {
+		return false, shared.ParseESError(res)
+	}
+
+	s.logger.Info("Successfully deleted index template", zap.String("template_name", name))
+	return true, nil
+}
@@ -0,0 +1,63 @@
+package services
+
+import (
+	"fmt"
+
+	"github.com/saif-islam/es-playground/projects/index-explorer/internal/models"
+)
+
+// Error tolerance levels of a bulk request
+const (
+	ErrorToleranceLow    = "low"    // abort on the first failed operation
+	ErrorToleranceMedium = "medium" // fail once the error rate exceeds mediumToleranceMaxErrorRate
+	ErrorToleranceHigh   = "high"   // always run to completion
+)
+
+// mediumToleranceMaxErrorRate is the highest error rate, in percent, a medium tolerance request accepts
+const mediumToleranceMaxErrorRate = 10.0
+
+// BulkToleranceError is returned when a bulk request fails more operations than its error
+// tolerance allows. The operations that did run are kept in Response.
+type BulkToleranceError struct {
+	Tolerance string
+	Response  *models.BulkResponse
+}
+
+func (e *BulkToleranceError) Error() string {
+	summary := e.Response.Summary
+	if e.Response.Aborted {
+		return fmt.Sprintf("bulk request aborted on the first failure (error_tolerance %s): %d failed, %d not processed",
+			e.Tolerance, summary.FailedOperations, summary.SkippedOperations)
+	}
+	return fmt.Sprintf("bulk error rate %.1f%% exceeds the %.0f%% allowed by error_tolerance %s",
+		summary.ErrorRate, mediumToleranceMaxErrorRate, e.Tolerance)
+}
+
+// validateErrorTolerance checks a bulk request's error tolerance
+func validateErrorTolerance(tolerance string) error {
+	switch tolerance {
+	case ErrorToleranceLow, ErrorToleranceMedium, ErrorToleranceHigh:
+		return nil
+	}
+	return fmt.Errorf("invalid error_tolerance %q (must be low, medium or high)", tolerance)
+}
+
+// checkErrorTolerance returns a *BulkToleranceError when the response's failures exceed
+// what the tolerance allows
+func checkErrorTolerance(tolerance string, response *models.BulkResponse) error {
+	summary := response.Summary
+	failed := summary.FailedOperations + summary.SkippedOperations
+
+	var exceeded bool
+	switch tolerance {
+	case ErrorToleranceLow:
+		exceeded = failed > 0
+	case ErrorToleranceMedium:
+		exceeded = summary.ErrorRate > mediumToleranceMaxErrorRate
+	}
+
+	if !exceeded {
+		return nil
+	}
+	return &BulkToleranceError{Tolerance: tolerance, Response: response}
+}
@@ -0,0 +1,142 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/saif-islam/es-playground/projects/index-explorer/internal/models"
+	"github.com/saif-islam/es-playground/shared"
+)
+
+// UpdateByQuery runs the script over every document of the index matching the query, e.g. to
+// rewrite a field without re-indexing from source. Documents changed while it runs are counted
+// as version conflicts and left as they were. It blocks until the update is done; the update
+// carries on in Elasticsearch if ctx expires.
+func (s *DocumentService) UpdateByQuery(ctx context.Context, indexName string, req *models.UpdateByQueryRequest) (*models.UpdateByQueryResult, error) {
+	return s.updateByQuery(ctx, indexName, req, true)
+}
+
+// StartUpdateByQuery starts an update by query without waiting for it, returning its task ID
+func (s *DocumentService) StartUpdateByQuery(ctx context.Context, indexName string, req *models.UpdateByQueryRequest) (*models.UpdateByQueryResult, error) {
+	return s.updateByQuery(ctx, indexName, req, false)
+}
+
+func (s *DocumentService) updateByQuery(ctx context.Context, indexName string, req *models.UpdateByQueryRequest, wait bool) (*models.UpdateByQueryResult, error) {
+	if len(req.Query) == 0 {
+		return nil, fmt.Errorf("query is required; use match_all to update every document")
+	}
+	if req.Script["source"] == nil && req.Script["id"] == nil {
+		return nil, fmt.Errorf("script requires a source or a stored script id")
+	}
+
+	requestsPerSecond, err := requestsPerSecondFor(req.TargetThroughput)
+	if err != nil {
+		return nil, err
+	}
+
+	s.logger.Info("Updating documents by query",
+		zap.String("index", indexName),
+		zap.Any("query", req.Query),
+		zap.Int("requests_per_second", requestsPerSecond),
+		zap.Bool("wait_for_completion", wait))
+
+	bodyBytes, err := json.Marshal(map[string]interface{}{
+		"query":  req.Query,
+		"script": req.Script,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal update by query body: %w", err)
+	}
+
+	// A blocking update outlasts the per-request timeout; ctx alone bounds it
+	if wait {
+		ctx = shared.WithRequestTimeout(ctx, 0)
+	}
+
+	res, err := s.esClient.UpdateByQuery(
+		[]string{indexName},
+		s.esClient.UpdateByQuery.WithContext(ctx),
+		s.esClient.UpdateByQuery.WithBody(strings.NewReader(string(bodyBytes))),
+		s.esClient.UpdateByQuery.WithConflicts("proceed"),
+		s.esClient.UpdateByQuery.WithRequestsPerSecond(requestsPerSecond),
+		s.esClient.UpdateByQuery.WithWaitForCompletion(wait),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("update by query request failed: %w", err)
+	}
+	defer res.Body.Close()
+
+	if res.IsError() {
+		return nil, shared.ParseESError(res)
+	}
+
+	var response struct {
+		Task             string `json:"task"`
+		Took             int64  `json:"took"`
+		TimedOut         bool   `json:"timed_out"`
+		Total            int64  `json:"total"`
+		Updated          int64  `json:"updated"`
+		Noops            int64  `json:"noops"`
+		Batches          int64  `json:"batches"`
+		VersionConflicts int64  `json:"version_conflicts"`
+		Failures         []struct {
+			ID    string `json:"id"`
+			Cause struct {
+				Type   string `json:"type"`
+				Reason string `json:"reason"`
+			} `json:"cause"`
+		} `json:"failures"`
+	}
+	if err := shared.DecodeJSONResponse(res, &response); err != nil {
+		return nil, fmt.Errorf("failed to decode update by query response: %w", err)
+	}
+
+	result := &models.UpdateByQueryResult{
+		IndexName:         indexName,
+		Completed:         wait,
+		TaskID:            response.Task,
+		RequestsPerSecond: requestsPerSecond,
+		Total:             response.Total,
+		Updated:           response.Updated,
+		Noops:             response.Noops,
+		VersionConflicts:  response.VersionConflicts,
+		Batches:           response.Batches,
+		TimedOut:          response.TimedOut,
+		Took:              time.Duration(response.Took) * time.Millisecond,
+	}
+	for _, failure := range response.Failures {
+		result.Failures = append(result.Failures,
+			fmt.Sprintf("%s: [%s] %s", failure.ID, failure.Cause.Type, failure.Cause.Reason))
+	}
+
+	s.logger.Info("Update by query finished",
+		zap.String("index", indexName),
+		zap.Bool("completed", result.Completed),
+		zap.String("task_id", result.TaskID),
+		zap.Int64("updated", result.Updated),
+		zap.Int64("version_conflicts", result.VersionConflicts))
+
+	return result, nil
+}
+
+// requestsPerSecondFor maps a target throughput to an update by query throttle. Like bulk
+// requests, which default to write_throughput, an unset target runs unthrottled (-1).
+func requestsPerSecondFor(targetThroughput string) (int, error) {
+	switch targetThroughput {
+	case "", "max":
+		return -1, nil
+	case "high":
+		return 5000, nil
+	case "medium":
+		return 1000, nil
+	case "low":
+		return 200, nil
+	default:
+		return 0, fmt.Errorf("invalid target_throughput %q: must be max, high, medium or low", targetThroughput)
+	}
+}
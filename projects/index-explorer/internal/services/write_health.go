@@ -0,0 +1,107 @@
+package services
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/saif-islam/es-playground/projects/index-explorer/internal/models"
+	"github.com/saif-islam/es-playground/shared"
+)
+
+// writeBlockSettings are the index blocks that reject indexing, with why each is usually set
+var writeBlockSettings = []struct {
+	setting string
+	reason  string
+}{
+	{"index.blocks.read_only_allow_delete", "read-only after a node passed the disk flood stage watermark; free disk space to lift it"},
+	{"index.blocks.read_only", "index is read-only"},
+	{"index.blocks.write", "writes are blocked, e.g. for a shrink, clone or snapshot"},
+}
+
+// addWriteHealth adds whether an index can currently take writes to its metrics: its
+// health, unassigned shards and any block that rejects indexing
+func (s *DocumentService) addWriteHealth(ctx context.Context, indexName string, metrics *models.WriteMetrics) error {
+	res, err := s.esClient.Cluster.Health(
+		s.esClient.Cluster.Health.WithContext(ctx),
+		s.esClient.Cluster.Health.WithIndex(indexName),
+	)
+	if err != nil {
+		return fmt.Errorf("failed to get index health: %w", err)
+	}
+	defer res.Body.Close()
+
+	if res.IsError() {
+		return shared.ParseESError(res)
+	}
+
+	var health indexHealth
+	if err := shared.DecodeJSONResponse(res, &health); err != nil {
+		return fmt.Errorf("failed to decode index health: %w", err)
+	}
+
+	blocks, err := s.getIndexBlocks(ctx, indexName)
+	if err != nil {
+		return err
+	}
+
+	applyWriteHealth(metrics, &health, blocks)
+	return nil
+}
+
+// getIndexBlocks reads the index.blocks.* settings of an index, or of every index behind an alias
+func (s *DocumentService) getIndexBlocks(ctx context.Context, indexName string) (map[string]interface{}, error) {
+	res, err := s.esClient.Indices.GetSettings(
+		s.esClient.Indices.GetSettings.WithContext(ctx),
+		s.esClient.Indices.GetSettings.WithIndex(indexName),
+		s.esClient.Indices.GetSettings.WithName("index.blocks.*"),
+		s.esClient.Indices.GetSettings.WithFlatSettings(true),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get index blocks: %w", err)
+	}
+	defer res.Body.Close()
+
+	if res.IsError() {
+		return nil, shared.ParseESError(res)
+	}
+
+	var settings map[string]struct {
+		Settings map[string]interface{} `json:"settings"`
+	}
+	if err := shared.DecodeJSONResponse(res, &settings); err != nil {
+		return nil, fmt.Errorf("failed to decode index settings: %w", err)
+	}
+
+	// A block on any index behind the name blocks writes to it
+	blocks := make(map[string]interface{})
+	for _, entry := range settings {
+		for setting, value := range entry.Settings {
+			if fmt.Sprint(value) == "true" {
+				blocks[setting] = value
+			}
+		}
+	}
+	return blocks, nil
+}
+
+// applyWriteHealth sets the write health fields of metrics. An index is writable unless
+// a block rejects indexing or it is red, when some primary shard has no copy to write to;
+// yellow only lacks replicas.
+func applyWriteHealth(metrics *models.WriteMetrics, health *indexHealth, blocks map[string]interface{}) {
+	metrics.Health = health.Status
+	metrics.UnassignedShards = health.UnassignedShards
+	metrics.ReadOnlyAllowDelete = fmt.Sprint(blocks["index.blocks.read_only_allow_delete"]) == "true"
+	metrics.WriteBlockers = nil
+
+	for _, block := range writeBlockSettings {
+		if fmt.Sprint(blocks[block.setting]) == "true" {
+			metrics.WriteBlockers = append(metrics.WriteBlockers, fmt.Sprintf("%s: %s", block.setting, block.reason))
+		}
+	}
+	if health.Status == "red" {
+		metrics.WriteBlockers = append(metrics.WriteBlockers,
+			fmt.Sprintf("index health is red with %d unassigned shards; writes to unassigned primaries fail", health.UnassignedShards))
+	}
+
+	metrics.Writable = len(metrics.WriteBlockers) == 0
+}
@@ -18,6 +18,7 @@ import (
 	"github.com/redis/go-redis/v9"
 
 	"github.com/saif-islam/es-playground/projects/search-api/internal/abtesting"
+	"github.com/saif-islam/es-playground/projects/search-api/internal/analytics"
 	"github.com/saif-islam/es-playground/projects/search-api/internal/cache"
 	"github.com/saif-islam/es-playground/projects/search-api/internal/handlers"
 	"github.com/saif-islam/es-playground/projects/search-api/internal/metrics"
@@ -51,17 +52,7 @@ func main() {
 	metrics.SetApplicationInfo("1.0.0", "search-api", "development")
 
 	// Initialize Elasticsearch client
-	esConfig := &shared.ESConfig{
-		URLs:     config.Elasticsearch.URLs,
-		Username: config.Elasticsearch.Username,
-		Password: config.Elasticsearch.Password,
-		APIKey:   config.Elasticsearch.APIKey,
-		TLSConfig: &shared.TLSConfig{
-			InsecureSkipVerify: config.Elasticsearch.TLSConfig.InsecureSkipVerify,
-		},
-	}
-
-	esClient, err := shared.NewESClient(esConfig, logger)
+	defaultClient, err := shared.NewESClient(toESConfig(config.Elasticsearch), logger)
 	if err != nil {
 		logger.Fatal("Failed to create Elasticsearch client", zap.Error(err))
 	}
@@ -70,12 +61,35 @@ func main() {
 	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
 	defer cancel()
 
-	if err := esClient.WaitForCluster(ctx, "yellow", 30*time.Second); err != nil {
+	if err := defaultClient.WaitForCluster(ctx, "yellow", 30*time.Second); err != nil {
 		logger.Fatal("Elasticsearch cluster not ready", zap.Error(err))
 	}
 
+	// Register additional clusters; one that is unreachable at startup is skipped rather
+	// than keeping the default cluster from being served
+	clusters := shared.NewClusterRegistry(shared.DefaultClusterName, defaultClient, logger)
+	for name, clusterConfig := range config.Clusters {
+		if name == shared.DefaultClusterName {
+			logger.Warn("Ignoring cluster with reserved name", zap.String("cluster", name))
+			continue
+		}
+
+		client, err := shared.NewESClient(toESConfig(clusterConfig), logger)
+		if err != nil {
+			logger.Error("Failed to connect to cluster, skipping", zap.String("cluster", name), zap.Error(err))
+			continue
+		}
+		clusters.Register(name, client)
+	}
+
+	// Services share one client that routes each request to the selected cluster
+	esClient, err := clusters.NewRoutingClient()
+	if err != nil {
+		logger.Fatal("Failed to create cluster routing client", zap.Error(err))
+	}
+
 	// Initialize real-time analytics hub
-	analyticsHub := realtime.NewAnalyticsHub(logger)
+	analyticsHub := realtime.NewAnalyticsHub(config.Analytics.Realtime, logger)
 
 	// Initialize tracing
 	tracingProvider, err := tracing.NewTracingProvider(config.Tracing, logger)
@@ -113,9 +127,36 @@ func main() {
 
 	// Initialize A/B testing framework
 	abTestFramework := abtesting.NewABTestFramework(logger)
+	if config.Experiments.AutoStop {
+		abTestFramework.StartAutoStop(config.Experiments)
+		defer abTestFramework.Close()
+	}
+
+	// Initialize search analytics persistence
+	var analyticsSink *analytics.ESSink
+	if config.Analytics.PersistToES {
+		analyticsSink = analytics.NewESSink(esClient, config.Analytics, logger)
+		defer analyticsSink.Close()
+	}
 
 	// Initialize services
-	searchService := services.NewSearchService(esClient, logger, analyticsHub, searchTracer, cacheManager)
+	searchService := services.NewSearchService(esClient, logger, analyticsHub, searchTracer, cacheManager, analyticsSink, services.NewRedactor(config.Redaction), services.NewCostGuard(config.CostGuard, logger))
+
+	searchService.SetAutoSuggestThreshold(config.Search.AutoSuggestThreshold)
+	searchService.SetHighlightPolicy(config.Search.TextHeavyIndices, config.Search.RequireHighlightFields)
+	searchService.SetRequestTimeout(shortestRequestTimeout(config))
+	cacheManager.SetSearcher(searchService.Search)
+
+	// Fill the cache with the configured searches without holding up startup
+	if config.Cache.Warm.OnStartup {
+		go func() {
+			ctx, cancel := context.WithTimeout(context.Background(), 2*time.Minute)
+			defer cancel()
+			if _, err := searchService.WarmCache(ctx, nil); err != nil {
+				logger.Warn("Startup cache warm failed", zap.Error(err))
+			}
+		}()
+	}
 
 	// Initialize handlers
 	searchHandler := handlers.NewSearchHandler(searchService, logger)
@@ -126,7 +167,13 @@ func main() {
 		gin.SetMode(gin.ReleaseMode)
 	}
 
-	router := setupRoutes(searchHandler, experimentHandler, analyticsHub, abTestFramework, tracingProvider, logger)
+	// Only the configured gateways may grant scopes through X-Search-Scopes
+	trustedScopes, err := middleware.TrustedScopesMiddleware(config.Scopes, logger)
+	if err != nil {
+		logger.Fatal("Invalid scopes configuration", zap.Error(err))
+	}
+
+	router := setupRoutes(clusters, searchHandler, experimentHandler, analyticsHub, abTestFramework, tracingProvider, trustedScopes, config.Logging.BodyCaptureConfig, logger)
 	
 	server := &http.Server{
 		Addr:         fmt.Sprintf("%s:%d", config.Server.Host, config.Server.Port),
@@ -163,6 +210,39 @@ func main() {
 	logger.Info("Server exited")
 }
 
+// shortestRequestTimeout returns the smallest per-request timeout of the configured
+// clusters, since a search may be routed to any of them; zero when none sets one
+func shortestRequestTimeout(config *models.Config) time.Duration {
+	shortest := config.Elasticsearch.RequestTimeout
+	for _, cluster := range config.Clusters {
+		if cluster.RequestTimeout > 0 && (shortest <= 0 || cluster.RequestTimeout < shortest) {
+			shortest = cluster.RequestTimeout
+		}
+	}
+	return shortest
+}
+
+// toESConfig converts a cluster's configuration to the shared client configuration
+func toESConfig(config models.ElasticsearchConfig) *shared.ESConfig {
+	return &shared.ESConfig{
+		URLs:     config.URLs,
+		Username: config.Username,
+		Password: config.Password,
+		APIKey:   config.APIKey,
+		TLSConfig: &shared.TLSConfig{
+			InsecureSkipVerify: config.TLSConfig.InsecureSkipVerify,
+			CACertPath:         config.TLSConfig.CACertPath,
+			ClientCertPath:     config.TLSConfig.ClientCertPath,
+			ClientKeyPath:      config.TLSConfig.ClientKeyPath,
+		},
+		RequestTimeout: config.RequestTimeout,
+		CircuitBreaker: shared.BreakerConfig{
+			FailureThreshold: config.CircuitBreaker.FailureThreshold,
+			OpenTimeout:      config.CircuitBreaker.OpenTimeout,
+		},
+	}
+}
+
 func loadConfig() (*models.Config, error) {
 	configPath := os.Getenv("CONFIG_PATH")
 	if configPath == "" {
@@ -184,6 +264,14 @@ func loadConfig() (*models.Config, error) {
 		return nil, fmt.Errorf("failed to unmarshal config: %w", err)
 	}
 
+	// An unknown mode must not fall back to streaming raw query text
+	if err := realtime.ValidateQueryMode(config.Analytics.Realtime.QueryMode); err != nil {
+		return nil, fmt.Errorf("invalid analytics.realtime configuration: %w", err)
+	}
+	if err := realtime.ValidateQueryMode(config.Analytics.QueryMode); err != nil {
+		return nil, fmt.Errorf("invalid analytics configuration: %w", err)
+	}
+
 	return &config, nil
 }
 
@@ -211,13 +299,14 @@ func initLogger(config models.LoggingConfig) (*zap.Logger, error) {
 	return zapConfig.Build()
 }
 
-func setupRoutes(searchHandler *handlers.SearchHandler, experimentHandler *handlers.ExperimentHandler, analyticsHub *realtime.AnalyticsHub, abTestFramework *abtesting.ABTestFramework, tracingProvider *tracing.TracingProvider, logger *zap.Logger) *gin.Engine {
+func setupRoutes(clusters *shared.ClusterRegistry, searchHandler *handlers.SearchHandler, experimentHandler *handlers.ExperimentHandler, analyticsHub *realtime.AnalyticsHub, abTestFramework *abtesting.ABTestFramework, tracingProvider *tracing.TracingProvider, trustedScopes gin.HandlerFunc, bodyCapture shared.BodyCaptureConfig, logger *zap.Logger) *gin.Engine {
 	router := gin.New()
 	
 	// Middleware
 	router.Use(gin.Logger())
 	router.Use(gin.Recovery())
 	router.Use(middleware.PrometheusMiddleware())
+	router.Use(trustedScopes)
 	router.Use(tracing.TracingMiddleware(tracingProvider, logger))
 	router.Use(middleware.ABTestingMiddleware(abTestFramework, logger))
 	router.Use(tracing.SearchTracingMiddleware(tracingProvider))
@@ -233,12 +322,52 @@ func setupRoutes(searchHandler *handlers.SearchHandler, experimentHandler *handl
 		c.Next()
 	})
 
+	// Request and response bodies at debug level, when logging.capture_bodies is set
+	router.Use(shared.CaptureBodies(bodyCapture, logger))
+
+	// Cluster selection middleware: X-ES-Cluster header or ?cluster= picks a registered cluster
+	router.Use(func(c *gin.Context) {
+		cluster := c.GetHeader("X-ES-Cluster")
+		if cluster == "" {
+			cluster = c.Query("cluster")
+		}
+		if cluster == "" {
+			c.Next()
+			return
+		}
+
+		if _, ok := clusters.Client(cluster); !ok {
+			c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{
+				"error":      "unknown_cluster",
+				"message":    fmt.Sprintf("Cluster %s is not registered", cluster),
+				"clusters":   clusters.Names(),
+				"request_id": c.GetString("request_id"),
+				"timestamp":  time.Now(),
+			})
+			return
+		}
+
+		c.Request = c.Request.WithContext(shared.WithCluster(c.Request.Context(), cluster))
+		c.Set("cluster", cluster)
+		c.Next()
+	})
+
 	// Health check
 	router.GET("/health", func(c *gin.Context) {
+		// An open circuit breaker means searches on that cluster are failing fast
+		status := "healthy"
+		breakers := clusters.BreakerStates()
+		for _, state := range breakers {
+			if state != shared.BreakerClosed {
+				status = "degraded"
+			}
+		}
+
 		c.JSON(http.StatusOK, gin.H{
-			"status":    "healthy",
+			"status":    status,
 			"service":   "search-api",
 			"version":   "1.0.0",
+			"breakers":  breakers,
 			"timestamp": time.Now(),
 		})
 	})
@@ -267,6 +396,19 @@ func setupRoutes(searchHandler *handlers.SearchHandler, experimentHandler *handl
 	// API routes
 	api := router.Group("/api")
 	{
+		// Registered Elasticsearch clusters
+		api.GET("/v1/clusters", func(c *gin.Context) {
+			c.JSON(http.StatusOK, gin.H{
+				"clusters":   clusters.Names(),
+				"default":    clusters.DefaultName(),
+				"request_id": c.GetString("request_id"),
+				"timestamp":  time.Now(),
+			})
+		})
+
+		// Aggregate search stats for dashboards that don't hold the WebSocket open
+		api.GET("/search/analytics/summary", analyticsHub.HandleSummary)
+
 		// Add experiment tracing middleware for experiment routes
 		experiments := api.Group("/experiments")
 		experiments.Use(tracing.ExperimentTracingMiddleware(tracingProvider))
@@ -0,0 +1,177 @@
+package abtesting
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/saif-islam/es-playground/projects/search-api/internal/models"
+)
+
+// Auto-stop defaults
+const (
+	defaultAutoStopInterval = time.Minute
+	defaultAutoStopMinPower = 0.8
+	defaultWebhookTimeout   = 10 * time.Second
+)
+
+// Reasons an experiment was stopped automatically
+const (
+	StopReasonSignificant = "significant"
+	StopReasonMaxDuration = "max_duration"
+)
+
+// ExperimentOutcome describes an experiment that was stopped automatically; it is logged
+// and posted to the configured webhook
+type ExperimentOutcome struct {
+	ExperimentID     string         `json:"experiment_id"`
+	Name             string         `json:"name"`
+	Reason           string         `json:"reason"`
+	ResultStatus     ResultStatus   `json:"result_status"`
+	Winner           string         `json:"winner,omitempty"`
+	Confidence       float64        `json:"confidence,omitempty"`
+	StatisticalPower float64        `json:"statistical_power,omitempty"`
+	WinnerResult     *VariantResult `json:"winner_result,omitempty"`
+	StartedAt        *time.Time     `json:"started_at,omitempty"`
+	EndedAt          time.Time      `json:"ended_at"`
+}
+
+// StartAutoStop starts a background check that completes running experiments once they
+// reach a significant result with enough power, or once they exceed their maximum duration.
+// It runs until Close is called.
+func (f *ABTestFramework) StartAutoStop(config models.ExperimentsConfig) {
+	if config.CheckInterval <= 0 {
+		config.CheckInterval = defaultAutoStopInterval
+	}
+	if config.MinPower <= 0 {
+		config.MinPower = defaultAutoStopMinPower
+	}
+	if config.WebhookTimeout <= 0 {
+		config.WebhookTimeout = defaultWebhookTimeout
+	}
+
+	f.done = make(chan struct{})
+	go f.runAutoStop(config)
+
+	f.logger.Info("Started experiment auto-stop",
+		zap.Duration("check_interval", config.CheckInterval),
+		zap.Float64("min_power", config.MinPower),
+		zap.Bool("webhook", config.WebhookURL != ""))
+}
+
+// Close stops the background auto-stop check, if it was started
+func (f *ABTestFramework) Close() {
+	if f.done != nil {
+		close(f.done)
+	}
+}
+
+func (f *ABTestFramework) runAutoStop(config models.ExperimentsConfig) {
+	ticker := time.NewTicker(config.CheckInterval)
+	defer ticker.Stop()
+
+	client := &http.Client{Timeout: config.WebhookTimeout}
+
+	for {
+		select {
+		case <-ticker.C:
+			for _, outcome := range f.stopFinishedExperiments(time.Now(), config.MinPower) {
+				f.logger.Info("Stopped A/B test experiment",
+					zap.String("experiment_id", outcome.ExperimentID),
+					zap.String("name", outcome.Name),
+					zap.String("reason", outcome.Reason),
+					zap.String("result_status", string(outcome.ResultStatus)),
+					zap.String("winner", outcome.Winner),
+					zap.Float64("statistical_power", outcome.StatisticalPower))
+
+				if config.WebhookURL != "" {
+					go f.notifyWebhook(client, config.WebhookURL, outcome)
+				}
+			}
+		case <-f.done:
+			return
+		}
+	}
+}
+
+// stopFinishedExperiments completes every running experiment that has a significant result
+// with at least minPower, or that has run for longer than its maximum duration, and returns
+// their outcomes
+func (f *ABTestFramework) stopFinishedExperiments(now time.Time, minPower float64) []ExperimentOutcome {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	var outcomes []ExperimentOutcome
+	for _, experiment := range f.experiments {
+		experiment.mu.Lock()
+		if experiment.Status == StatusRunning {
+			reason := ""
+			switch {
+			case experiment.Results.Status == ResultStatusSignificant && experiment.Results.StatisticalPower >= minPower:
+				reason = StopReasonSignificant
+			case experiment.StartedAt != nil && experiment.MaxDuration > 0 && now.Sub(*experiment.StartedAt) >= experiment.MaxDuration:
+				reason = StopReasonMaxDuration
+			}
+
+			if reason != "" {
+				ended := now
+				experiment.Status = StatusComplete
+				experiment.EndedAt = &ended
+				outcomes = append(outcomes, experimentOutcome(experiment, reason))
+			}
+		}
+		experiment.mu.Unlock()
+	}
+
+	return outcomes
+}
+
+// experimentOutcome summarizes a stopped experiment; the caller holds the experiment lock
+func experimentOutcome(experiment *Experiment, reason string) ExperimentOutcome {
+	outcome := ExperimentOutcome{
+		ExperimentID:     experiment.ID,
+		Name:             experiment.Name,
+		Reason:           reason,
+		ResultStatus:     experiment.Results.Status,
+		Winner:           experiment.Results.Winner,
+		Confidence:       experiment.Results.Confidence,
+		StatisticalPower: experiment.Results.StatisticalPower,
+		StartedAt:        experiment.StartedAt,
+		EndedAt:          *experiment.EndedAt,
+	}
+	if result, ok := experiment.Results.VariantResults[experiment.Results.Winner]; ok {
+		outcome.WinnerResult = &result
+	}
+	return outcome
+}
+
+// notifyWebhook posts an experiment outcome to the webhook; failures are only logged
+func (f *ABTestFramework) notifyWebhook(client *http.Client, url string, outcome ExperimentOutcome) {
+	if err := postOutcome(client, url, outcome); err != nil {
+		f.logger.Warn("Failed to notify experiment webhook",
+			zap.String("experiment_id", outcome.ExperimentID),
+			zap.Error(err))
+	}
+}
+
+func postOutcome(client *http.Client, url string, outcome ExperimentOutcome) error {
+	body, err := json.Marshal(outcome)
+	if err != nil {
+		return fmt.Errorf("failed to marshal outcome: %w", err)
+	}
+
+	res, err := client.Post(url, "application/json", bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("webhook request failed: %w", err)
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode >= 300 {
+		return fmt.Errorf("webhook returned status %d", res.StatusCode)
+	}
+	return nil
+}
@@ -0,0 +1,118 @@
+package abtesting
+
+import (
+	"encoding/json"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+func TestCalculatePower(t *testing.T) {
+	f := &ABTestFramework{}
+
+	// 0.20 against 0.25 over 1000 requests each: z = 2.6823, power = 0.765 at alpha 0.05
+	power := f.calculatePower(0.20, 0.25, 1000, 1000, 0.05)
+	if math.Abs(power-0.765) > 0.001 {
+		t.Errorf("Expected power 0.765, got %.4f", power)
+	}
+
+	// More samples give more power for the same difference
+	if larger := f.calculatePower(0.20, 0.25, 4000, 4000, 0.05); larger <= power {
+		t.Errorf("Expected more power with more samples, got %.4f", larger)
+	}
+	if none := f.calculatePower(0.20, 0.20, 1000, 1000, 0.05); none > 0.05 {
+		t.Errorf("Expected no power for identical rates, got %.4f", none)
+	}
+}
+
+func newRunningExperiment(id string, startedAt time.Time, maxDuration time.Duration) *Experiment {
+	return &Experiment{
+		ID:                id,
+		Name:              id,
+		Status:            StatusRunning,
+		StartedAt:         &startedAt,
+		MaxDuration:       maxDuration,
+		ControlVariant:    &Variant{ID: "control"},
+		TreatmentVariants: map[string]*Variant{"treatment": {ID: "treatment"}},
+		Results:           ExperimentResults{VariantResults: make(map[string]VariantResult)},
+	}
+}
+
+func TestStopFinishedExperiments(t *testing.T) {
+	now := time.Now()
+
+	significant := newRunningExperiment("significant", now.Add(-time.Hour), 24*time.Hour)
+	significant.Results.Status = ResultStatusSignificant
+	significant.Results.Winner = "treatment"
+	significant.Results.StatisticalPower = 0.9
+	significant.Results.VariantResults["treatment"] = VariantResult{Variant: "treatment", Effect: 12.5}
+
+	underpowered := newRunningExperiment("underpowered", now.Add(-time.Hour), 24*time.Hour)
+	underpowered.Results.Status = ResultStatusSignificant
+	underpowered.Results.Winner = "treatment"
+	underpowered.Results.StatisticalPower = 0.5
+
+	expired := newRunningExperiment("expired", now.Add(-48*time.Hour), 24*time.Hour)
+	expired.Results.Status = ResultStatusInconclusive
+
+	f := &ABTestFramework{
+		logger: zap.NewNop(),
+		experiments: map[string]*Experiment{
+			significant.ID:  significant,
+			underpowered.ID: underpowered,
+			expired.ID:      expired,
+		},
+	}
+
+	outcomes := f.stopFinishedExperiments(now, 0.8)
+	if len(outcomes) != 2 {
+		t.Fatalf("Expected 2 stopped experiments, got %d", len(outcomes))
+	}
+
+	reasons := make(map[string]ExperimentOutcome)
+	for _, outcome := range outcomes {
+		reasons[outcome.ExperimentID] = outcome
+	}
+	if outcome := reasons["significant"]; outcome.Reason != StopReasonSignificant || outcome.Winner != "treatment" || outcome.WinnerResult == nil {
+		t.Errorf("Expected the significant experiment to stop with treatment as winner, got %+v", outcome)
+	}
+	if outcome := reasons["expired"]; outcome.Reason != StopReasonMaxDuration || outcome.Winner != "" {
+		t.Errorf("Expected the expired experiment to stop on max duration without a winner, got %+v", outcome)
+	}
+
+	if significant.Status != StatusComplete || significant.EndedAt == nil {
+		t.Errorf("Expected the significant experiment to be complete, got %s", significant.Status)
+	}
+	if underpowered.Status != StatusRunning {
+		t.Errorf("Expected the underpowered experiment to keep running, got %s", underpowered.Status)
+	}
+
+	// Stopped experiments are not stopped twice
+	if outcomes := f.stopFinishedExperiments(now.Add(time.Minute), 0.8); len(outcomes) != 0 {
+		t.Errorf("Expected no further stops, got %d", len(outcomes))
+	}
+}
+
+func TestPostOutcome(t *testing.T) {
+	var received ExperimentOutcome
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Content-Type") != "application/json" {
+			t.Errorf("Expected a JSON webhook, got %s", r.Header.Get("Content-Type"))
+		}
+		json.NewDecoder(r.Body).Decode(&received)
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer server.Close()
+
+	outcome := ExperimentOutcome{ExperimentID: "exp-1", Reason: StopReasonSignificant, Winner: "treatment"}
+	if err := postOutcome(server.Client(), server.URL, outcome); err != nil {
+		t.Fatalf("Unexpected webhook error: %v", err)
+	}
+	if received.ExperimentID != "exp-1" || received.Winner != "treatment" {
+		t.Errorf("Expected the outcome to be posted, got %+v", received)
+	}
+}
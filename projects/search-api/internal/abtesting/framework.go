@@ -2,10 +2,12 @@ package abtesting
 
 import (
 	"crypto/md5"
+	"encoding/binary"
 	"encoding/hex"
-	"encoding/json"
 	"fmt"
 	"math"
+	"sort"
+	"strings"
 	"sync"
 	"time"
 
@@ -14,6 +16,14 @@ import (
 	"github.com/saif-islam/es-playground/projects/search-api/internal/models"
 )
 
+// Response time sample retention
+const (
+	// maxRetainedSamples caps the raw observations kept per variant for percentiles
+	maxRetainedSamples = 10000
+	// percentileRefreshInterval is how many requests pass between percentile refreshes
+	percentileRefreshInterval = 100
+)
+
 // ABTestFramework manages A/B testing experiments for search queries
 type ABTestFramework struct {
 	experiments map[string]*Experiment
@@ -24,6 +34,9 @@ type ABTestFramework struct {
 	defaultTrafficSplit float64
 	minSampleSize      int
 	maxExperimentAge   time.Duration
+	
+	// Closed to stop the auto-stop check; nil until it is started
+	done chan struct{}
 }
 
 // Experiment represents an A/B test experiment
@@ -32,6 +45,7 @@ type Experiment struct {
 	Name        string                 `json:"name"`
 	Description string                 `json:"description"`
 	Status      ExperimentStatus       `json:"status"`
+	Type        ExperimentType         `json:"type"`
 	CreatedAt   time.Time              `json:"created_at"`
 	StartedAt   *time.Time             `json:"started_at,omitempty"`
 	EndedAt     *time.Time             `json:"ended_at,omitempty"`
@@ -58,6 +72,9 @@ type Experiment struct {
 	MaxDuration      time.Duration `json:"max_duration"`
 	SignificanceLevel float64      `json:"significance_level"`
 	
+	// Treatment variants in assignment order, fixed when the experiment starts
+	treatmentOrder []*Variant
+	
 	mu sync.RWMutex
 }
 
@@ -71,6 +88,9 @@ type Variant struct {
 	// Query modifications
 	QueryModifications QueryModifications `json:"query_modifications"`
 	
+	// Ranking strategy, used instead of query modifications in search_strategy experiments
+	Strategy *models.SearchStrategy `json:"strategy,omitempty"`
+	
 	// Performance metrics
 	Metrics VariantMetrics `json:"metrics"`
 	
@@ -125,7 +145,13 @@ type VariantMetrics struct {
 	ConversionRate     float64 `json:"conversion_rate,omitempty"`
 	UserSatisfaction   float64 `json:"user_satisfaction,omitempty"`
 	
-	// Statistical data
+	// Relevance metrics from reported engagement
+	Clicks             int64   `json:"clicks"`
+	Conversions        int64   `json:"conversions"`
+	Ratings            int64   `json:"ratings"` // requests rated by the user, for user_satisfaction
+	MeanReciprocalRank float64 `json:"mean_reciprocal_rank,omitempty"` // average 1/position of clicked results
+	
+	// Statistical data: the most recent maxRetainedSamples observations, in no particular order
 	ResponseTimes      []float64 `json:"-"` // Raw data for statistical analysis
 	ResultCounts       []int64   `json:"-"`
 	
@@ -161,6 +187,9 @@ type ExperimentResults struct {
 	// Variant comparisons
 	VariantResults   map[string]VariantResult `json:"variant_results"`
 	
+	// Variant comparisons on each rate metric in SecondaryMetrics, by metric then variant
+	SecondaryResults map[string]map[string]VariantResult `json:"secondary_results,omitempty"`
+	
 	// Recommendations
 	Recommendations  []string                 `json:"recommendations"`
 	
@@ -195,6 +224,16 @@ const (
 	StatusArchived ExperimentStatus = "archived"
 )
 
+// ExperimentType determines what the variants of an experiment change
+type ExperimentType string
+
+const (
+	// Variants tweak individual query parameters
+	TypeQueryModifications ExperimentType = "query_modifications"
+	// Variants swap the whole ranking strategy and are compared on relevance and engagement
+	TypeSearchStrategy ExperimentType = "search_strategy"
+)
+
 // ResultStatus represents the statistical significance status
 type ResultStatus string
 
@@ -217,6 +256,21 @@ func NewABTestFramework(logger *zap.Logger) *ABTestFramework {
 
 // CreateExperiment creates a new A/B test experiment
 func (f *ABTestFramework) CreateExperiment(name, description string, config ExperimentConfig) (*Experiment, error) {
+	if config.Type == "" {
+		config.Type = TypeQueryModifications
+	}
+	if config.Type != TypeQueryModifications && config.Type != TypeSearchStrategy {
+		return nil, fmt.Errorf("unknown experiment type %q", config.Type)
+	}
+	if config.ControlStrategy != nil {
+		if config.Type != TypeSearchStrategy {
+			return nil, fmt.Errorf("a control strategy requires a %s experiment", TypeSearchStrategy)
+		}
+		if err := validateStrategy(config.ControlStrategy); err != nil {
+			return nil, fmt.Errorf("invalid control strategy: %w", err)
+		}
+	}
+	
 	f.mu.Lock()
 	defer f.mu.Unlock()
 	
@@ -227,6 +281,7 @@ func (f *ABTestFramework) CreateExperiment(name, description string, config Expe
 		Name:              name,
 		Description:       description,
 		Status:            StatusDraft,
+		Type:              config.Type,
 		CreatedAt:         time.Now(),
 		TrafficAllocation: config.TrafficAllocation,
 		PrimaryMetric:     config.PrimaryMetric,
@@ -256,12 +311,13 @@ func (f *ABTestFramework) CreateExperiment(name, description string, config Expe
 		experiment.SignificanceLevel = 0.05 // 95% confidence
 	}
 	
-	// Create control variant
+	// Create control variant; without a control strategy it keeps the current ranking
 	experiment.ControlVariant = &Variant{
 		ID:          "control",
 		Name:        "Control",
 		Description: "Original query behavior",
 		Weight:      0.5, // 50% of allocated traffic
+		Strategy:    config.ControlStrategy,
 		Metrics:     VariantMetrics{ResponseTimes: make([]float64, 0), ResultCounts: make([]int64, 0)},
 	}
 	
@@ -289,6 +345,10 @@ func (f *ABTestFramework) AddTreatmentVariant(experimentID string, variant *Vari
 		return fmt.Errorf("cannot modify experiment %s: status is %s", experimentID, experiment.Status)
 	}
 	
+	if err := validateVariant(experiment.Type, variant); err != nil {
+		return fmt.Errorf("invalid variant %s: %w", variant.ID, err)
+	}
+	
 	experiment.mu.Lock()
 	defer experiment.mu.Unlock()
 	
@@ -326,14 +386,8 @@ func (f *ABTestFramework) StartExperiment(experimentID string) error {
 		return fmt.Errorf("experiment %s has no treatment variants", experimentID)
 	}
 	
-	// Normalize variant weights
-	totalWeight := experiment.ControlVariant.Weight
-	for _, variant := range experiment.TreatmentVariants {
-		totalWeight += variant.Weight
-	}
-	
-	if totalWeight == 0 {
-		return fmt.Errorf("experiment %s has zero total weight", experimentID)
+	if err := normalizeVariantWeights(experiment); err != nil {
+		return fmt.Errorf("experiment %s: %w", experimentID, err)
 	}
 	
 	// Start the experiment
@@ -366,7 +420,7 @@ func (f *ABTestFramework) GetVariantForRequest(request ABTestRequest) (*Experime
 		}
 		
 		// Check traffic allocation
-		if !f.shouldParticipate(request, experiment.TrafficAllocation) {
+		if !f.shouldParticipate(request, experiment.ID, experiment.TrafficAllocation) {
 			continue
 		}
 		
@@ -424,26 +478,47 @@ func (f *ABTestFramework) RecordExperimentResult(assignment *ExperimentAssignmen
 	// Update metrics
 	variant.Metrics.TotalRequests++
 	
+	// Update engagement reported with the result
+	if result.ClickThrough {
+		variant.Metrics.Clicks++
+	}
+	if result.Conversion {
+		variant.Metrics.Conversions++
+	}
+	if result.UserRating > 0 {
+		variant.Metrics.Ratings++
+		variant.Metrics.UserSatisfaction = f.updateAverage(variant.Metrics.UserSatisfaction, result.UserRating, variant.Metrics.Ratings)
+	}
+	updateEngagementRates(&variant.Metrics)
+	
 	// Update response time metrics
 	responseTime := float64(result.ResponseTime.Milliseconds())
-	variant.Metrics.ResponseTimes = append(variant.Metrics.ResponseTimes, responseTime)
+	variant.Metrics.ResponseTimes = retainSample(variant.Metrics.ResponseTimes, responseTime, variant.Metrics.TotalRequests)
 	variant.Metrics.AvgResponseTime = f.updateAverage(variant.Metrics.AvgResponseTime, responseTime, variant.Metrics.TotalRequests)
 	
+	// Sorting the samples on every request is wasteful; the percentiles are refreshed
+	// periodically and recomputed whenever analytics are read
+	if variant.Metrics.TotalRequests <= percentileRefreshInterval || variant.Metrics.TotalRequests%percentileRefreshInterval == 0 {
+		updateResponseTimePercentiles(&variant.Metrics)
+	}
+	
 	// Update result count metrics
-	variant.Metrics.ResultCounts = append(variant.Metrics.ResultCounts, result.ResultCount)
+	variant.Metrics.ResultCounts = retainSample(variant.Metrics.ResultCounts, result.ResultCount, variant.Metrics.TotalRequests)
 	variant.Metrics.AvgResultCount = f.updateAverage(variant.Metrics.AvgResultCount, float64(result.ResultCount), variant.Metrics.TotalRequests)
 	
-	// Update success/error rates
+	// Update success/error rates; both are averaged over every request so they stay rates
+	success, zeroResults := 0.0, 0.0
 	if result.Success {
-		variant.Metrics.SuccessRate = f.updateAverage(variant.Metrics.SuccessRate, 1.0, variant.Metrics.TotalRequests)
-	} else {
-		variant.Metrics.ErrorRate = f.updateAverage(variant.Metrics.ErrorRate, 1.0, variant.Metrics.TotalRequests)
+		success = 1.0
 	}
-	
-	// Update zero results rate
 	if result.ResultCount == 0 {
-		variant.Metrics.ZeroResultsRate = f.updateAverage(variant.Metrics.ZeroResultsRate, 1.0, variant.Metrics.TotalRequests)
+		zeroResults = 1.0
 	}
+	variant.Metrics.SuccessRate = f.updateAverage(variant.Metrics.SuccessRate, success, variant.Metrics.TotalRequests)
+	variant.Metrics.ErrorRate = f.updateAverage(variant.Metrics.ErrorRate, 1.0-success, variant.Metrics.TotalRequests)
+	
+	// Update zero results rate
+	variant.Metrics.ZeroResultsRate = f.updateAverage(variant.Metrics.ZeroResultsRate, zeroResults, variant.Metrics.TotalRequests)
 	
 	variant.Metrics.LastUpdated = time.Now()
 	
@@ -488,8 +563,7 @@ func (f *ABTestFramework) matchesTargeting(request ABTestRequest, targeting Expe
 	if len(targeting.QueryPatterns) > 0 {
 		matched := false
 		for _, pattern := range targeting.QueryPatterns {
-			// Simple pattern matching (in production, use regex)
-			if contains(request.Query, pattern) {
+			if matchesQueryPattern(request.Query, pattern) {
 				matched = true
 				break
 			}
@@ -532,52 +606,26 @@ func (f *ABTestFramework) matchesTargeting(request ABTestRequest, targeting Expe
 	return true
 }
 
-func (f *ABTestFramework) shouldParticipate(request ABTestRequest, trafficAllocation float64) bool {
+func (f *ABTestFramework) shouldParticipate(request ABTestRequest, experimentID string, trafficAllocation float64) bool {
 	// Use consistent hashing based on user/session ID to ensure consistent experience
-	hashInput := request.UserID
-	if hashInput == "" {
-		hashInput = request.SessionID
-	}
-	if hashInput == "" {
-		hashInput = request.RequestID
-	}
-	
-	hash := md5.Sum([]byte(hashInput))
-	hashValue := float64(hash[0]) / 255.0
-	
-	return hashValue < trafficAllocation
+	return hashBucket("participation:"+experimentID, assignmentKey(request)) < trafficAllocation
 }
 
 func (f *ABTestFramework) assignVariant(request ABTestRequest, experiment *Experiment) *Variant {
-	// Use consistent hashing for variant assignment
-	hashInput := request.UserID + experiment.ID
-	if request.UserID == "" {
-		hashInput = request.SessionID + experiment.ID
-	}
-	
-	hash := md5.Sum([]byte(hashInput))
-	hashValue := float64(hash[1]) / 255.0
-	
-	// Calculate cumulative weights
-	totalWeight := experiment.ControlVariant.Weight
-	for _, variant := range experiment.TreatmentVariants {
-		totalWeight += variant.Weight
-	}
+	// Use consistent hashing for variant assignment, salted differently from participation so
+	// that which users take part does not decide which variant they get
+	hashValue := hashBucket("variant:"+experiment.ID, assignmentKey(request))
 	
-	// Assign based on weights
-	threshold := 0.0
-	normalizedHash := hashValue * totalWeight
-	
-	// Check control first
-	threshold += experiment.ControlVariant.Weight
-	if normalizedHash < threshold {
+	// Weights were normalized to sum to 1 when the experiment started
+	threshold := experiment.ControlVariant.Weight
+	if hashValue < threshold {
 		return experiment.ControlVariant
 	}
 	
-	// Check treatment variants
-	for _, variant := range experiment.TreatmentVariants {
+	// Check treatment variants in their fixed order
+	for _, variant := range experiment.treatmentOrder {
 		threshold += variant.Weight
-		if normalizedHash < threshold {
+		if hashValue < threshold {
 			return variant
 		}
 	}
@@ -586,6 +634,39 @@ func (f *ABTestFramework) assignVariant(request ABTestRequest, experiment *Exper
 	return experiment.ControlVariant
 }
 
+// normalizeVariantWeights scales the variant weights to sum to 1 and fixes the order in which
+// treatments are assigned, so a user keeps the same variant for the whole experiment
+func normalizeVariantWeights(experiment *Experiment) error {
+	totalWeight := experiment.ControlVariant.Weight
+	if totalWeight < 0 {
+		return fmt.Errorf("control variant has negative weight %g", totalWeight)
+	}
+	
+	ids := make([]string, 0, len(experiment.TreatmentVariants))
+	for id, variant := range experiment.TreatmentVariants {
+		if variant.Weight < 0 {
+			return fmt.Errorf("variant %s has negative weight %g", id, variant.Weight)
+		}
+		totalWeight += variant.Weight
+		ids = append(ids, id)
+	}
+	
+	if totalWeight == 0 {
+		return fmt.Errorf("zero total weight")
+	}
+	
+	sort.Strings(ids)
+	experiment.treatmentOrder = make([]*Variant, 0, len(ids))
+	experiment.ControlVariant.Weight /= totalWeight
+	for _, id := range ids {
+		variant := experiment.TreatmentVariants[id]
+		variant.Weight /= totalWeight
+		experiment.treatmentOrder = append(experiment.treatmentOrder, variant)
+	}
+	
+	return nil
+}
+
 func (f *ABTestFramework) updateAverage(currentAvg, newValue float64, count int64) float64 {
 	if count <= 1 {
 		return newValue
@@ -593,6 +674,61 @@ func (f *ABTestFramework) updateAverage(currentAvg, newValue float64, count int6
 	return (currentAvg*float64(count-1) + newValue) / float64(count)
 }
 
+// assignmentKey identifies who a request is assigned for: the user, else the session, else
+// only the request itself
+func assignmentKey(request ABTestRequest) string {
+	if request.UserID != "" {
+		return request.UserID
+	}
+	if request.SessionID != "" {
+		return request.SessionID
+	}
+	return request.RequestID
+}
+
+// hashBucket maps a key to a uniform value in [0, 1) from the first 8 bytes of its hash.
+// Decisions hashed with different salts are independent of each other.
+func hashBucket(salt, key string) float64 {
+	hash := md5.Sum([]byte(salt + ":" + key))
+	// The top 53 bits are exactly representable as a float64
+	return float64(binary.BigEndian.Uint64(hash[:8])>>11) / (1 << 53)
+}
+
+// retainSample records the count-th observation of a variant. Once maxRetainedSamples are
+// held each new observation replaces the oldest, so memory stays bounded under high traffic.
+func retainSample[T any](samples []T, value T, count int64) []T {
+	if len(samples) < maxRetainedSamples {
+		return append(samples, value)
+	}
+	samples[(count-1)%maxRetainedSamples] = value
+	return samples
+}
+
+// updateResponseTimePercentiles computes P95 and P99 over the retained response times
+func updateResponseTimePercentiles(metrics *VariantMetrics) {
+	if len(metrics.ResponseTimes) == 0 {
+		return
+	}
+	sorted := make([]float64, len(metrics.ResponseTimes))
+	copy(sorted, metrics.ResponseTimes)
+	sort.Float64s(sorted)
+	
+	metrics.P95ResponseTime = percentile(sorted, 95)
+	metrics.P99ResponseTime = percentile(sorted, 99)
+}
+
+// percentile returns the nearest-rank percentile of sorted values
+func percentile(sorted []float64, p float64) float64 {
+	if len(sorted) == 0 {
+		return 0
+	}
+	rank := int(math.Ceil(p / 100 * float64(len(sorted))))
+	if rank < 1 {
+		rank = 1
+	}
+	return sorted[rank-1]
+}
+
 func (f *ABTestFramework) analyzeExperiment(experimentID string) {
 	f.mu.RLock()
 	experiment := f.experiments[experimentID]
@@ -605,6 +741,11 @@ func (f *ABTestFramework) analyzeExperiment(experimentID string) {
 	experiment.mu.Lock()
 	defer experiment.mu.Unlock()
 	
+	// A completed experiment keeps the results it was stopped with
+	if experiment.Status == StatusComplete {
+		return
+	}
+	
 	// Perform statistical analysis
 	controlMetrics := experiment.ControlVariant.Metrics
 	
@@ -614,51 +755,41 @@ func (f *ABTestFramework) analyzeExperiment(experimentID string) {
 		return
 	}
 	
-	// Analyze each treatment variant against control
+	// Analyze each treatment variant against control; significance is decided afresh each time
 	bestVariant := "control"
 	bestEffect := 0.0
+	bestPower := 0.0
+	experiment.Results.Status = ResultStatusInconclusive
 	
 	for variantID, variant := range experiment.TreatmentVariants {
 		if variant.Metrics.TotalRequests < int64(experiment.MinSampleSize) {
 			continue
 		}
 		
-		// Simple statistical analysis (in production, use proper statistical tests)
-		treatmentConversion := variant.Metrics.SuccessRate
-		controlConversion := controlMetrics.SuccessRate
+		result, ok := f.compareVariant(controlMetrics, variant.Metrics, experiment.PrimaryMetric, variantID, experiment.SignificanceLevel)
+		if !ok {
+			continue
+		}
+		experiment.Results.VariantResults[variantID] = result
 		
-		if controlConversion > 0 {
-			effect := (treatmentConversion - controlConversion) / controlConversion * 100
-			
-			// Simple significance test (in production, use t-test or chi-square)
-			pValue := f.calculatePValue(controlMetrics, variant.Metrics)
-			
-			experiment.Results.VariantResults[variantID] = VariantResult{
-				Variant:        variantID,
-				SampleSize:     variant.Metrics.TotalRequests,
-				ConversionRate: treatmentConversion,
-				PValue:         pValue,
-				Effect:         effect,
-				ConfidenceInterval: ConfidenceInterval{
-					Lower: effect - 5.0, // Simplified CI
-					Upper: effect + 5.0,
-					Level: 0.95,
-				},
-			}
-			
-			if pValue < experiment.SignificanceLevel && effect > bestEffect {
-				bestVariant = variantID
-				bestEffect = effect
-				experiment.Results.Status = ResultStatusSignificant
-			}
+		if result.PValue < experiment.SignificanceLevel && result.Effect > bestEffect {
+			bestVariant = variantID
+			bestEffect = result.Effect
+			bestPower = f.calculatePower(metricValue(controlMetrics, experiment.PrimaryMetric), result.ConversionRate,
+				controlMetrics.TotalRequests, variant.Metrics.TotalRequests, experiment.SignificanceLevel)
+			experiment.Results.Status = ResultStatusSignificant
 		}
 	}
 	
+	f.analyzeSecondaryMetrics(experiment)
+	
 	if experiment.Results.Status == ResultStatusSignificant {
 		experiment.Results.Winner = bestVariant
 		experiment.Results.Confidence = (1.0 - experiment.SignificanceLevel) * 100
+		experiment.Results.StatisticalPower = bestPower
 	} else {
-		experiment.Results.Status = ResultStatusInconclusive
+		experiment.Results.Winner = ""
+		experiment.Results.StatisticalPower = 0
 	}
 	
 	experiment.Results.UpdatedAt = time.Now()
@@ -669,37 +800,148 @@ func (f *ABTestFramework) analyzeExperiment(experimentID string) {
 		zap.String("winner", experiment.Results.Winner))
 }
 
-func (f *ABTestFramework) calculatePValue(control, treatment VariantMetrics) float64 {
-	// Simplified p-value calculation
-	// In production, use proper statistical libraries
+// compareVariant tests a treatment against the control on one rate metric. It reports false
+// when the control rate is zero and no relative effect can be given.
+func (f *ABTestFramework) compareVariant(control, treatment VariantMetrics, metric, variantID string, significanceLevel float64) (VariantResult, bool) {
+	controlRate := metricValue(control, metric)
+	treatmentRate := metricValue(treatment, metric)
+	if controlRate <= 0 {
+		return VariantResult{}, false
+	}
+	
+	return VariantResult{
+		Variant:        variantID,
+		SampleSize:     treatment.TotalRequests,
+		ConversionRate: treatmentRate,
+		PValue:         f.calculatePValue(controlRate, treatmentRate, control.TotalRequests, treatment.TotalRequests),
+		Effect:         (treatmentRate - controlRate) / controlRate * 100,
+		ConfidenceInterval: f.calculateConfidenceInterval(controlRate, treatmentRate,
+			control.TotalRequests, treatment.TotalRequests, 1-significanceLevel),
+	}, true
+}
+
+// analyzeSecondaryMetrics compares every variant with the control on the secondary metrics,
+// recommending against variants that significantly worsen one of them, such as a latency
+// win that costs conversions. Only rate metrics can be tested; others are skipped. The
+// caller holds the experiment lock.
+func (f *ABTestFramework) analyzeSecondaryMetrics(experiment *Experiment) {
+	experiment.Results.SecondaryResults = nil
+	experiment.Results.Recommendations = nil
 	
-	if control.TotalRequests == 0 || treatment.TotalRequests == 0 {
+	controlMetrics := experiment.ControlVariant.Metrics
+	for _, metric := range experiment.SecondaryMetrics {
+		if metric == experiment.PrimaryMetric || !isRateMetric(metric) {
+			continue
+		}
+		
+		results := make(map[string]VariantResult)
+		for variantID, variant := range experiment.TreatmentVariants {
+			if variant.Metrics.TotalRequests < int64(experiment.MinSampleSize) {
+				continue
+			}
+			result, ok := f.compareVariant(controlMetrics, variant.Metrics, metric, variantID, experiment.SignificanceLevel)
+			if !ok {
+				continue
+			}
+			results[variantID] = result
+			
+			worse := result.Effect < 0
+			if lowerIsBetter(metric) {
+				worse = result.Effect > 0
+			}
+			if worse && result.PValue < experiment.SignificanceLevel {
+				experiment.Results.Recommendations = append(experiment.Results.Recommendations,
+					fmt.Sprintf("Variant %s significantly worsens %s (%+.1f%% vs control)", variantID, metric, result.Effect))
+			}
+		}
+		
+		if experiment.Results.SecondaryResults == nil {
+			experiment.Results.SecondaryResults = make(map[string]map[string]VariantResult)
+		}
+		experiment.Results.SecondaryResults[metric] = results
+	}
+	
+	sort.Strings(experiment.Results.Recommendations)
+}
+
+// calculatePValue runs a two-sided two-proportion z-test of the treatment rate against the
+// control rate, each observed over the given number of requests. The primary metrics are all
+// rates in [0, 1]; for mean reciprocal rank the test is an approximation.
+func (f *ABTestFramework) calculatePValue(controlRate, treatmentRate float64, controlRequests, treatmentRequests int64) float64 {
+	if controlRequests == 0 || treatmentRequests == 0 {
 		return 1.0
 	}
 	
-	// Simple effect size calculation
-	effectSize := math.Abs(treatment.SuccessRate - control.SuccessRate)
-	pooledStdDev := math.Sqrt(((control.SuccessRate*(1-control.SuccessRate))/float64(control.TotalRequests)) +
-		((treatment.SuccessRate*(1-treatment.SuccessRate))/float64(treatment.TotalRequests)))
+	// Under the null hypothesis both variants share the pooled rate
+	n1, n2 := float64(controlRequests), float64(treatmentRequests)
+	pooledRate := (controlRate*n1 + treatmentRate*n2) / (n1 + n2)
+	standardError := math.Sqrt(pooledRate * (1 - pooledRate) * (1/n1 + 1/n2))
 	
-	if pooledStdDev == 0 {
+	if standardError == 0 {
 		return 1.0
 	}
 	
-	zScore := effectSize / pooledStdDev
+	zScore := (treatmentRate - controlRate) / standardError
+	return math.Erfc(math.Abs(zScore) / math.Sqrt2)
+}
+
+// calculateConfidenceInterval returns the confidence interval, at the given level, of the
+// treatment's effect as a percentage of the control rate. It is the Wald interval of the rate
+// difference scaled by the control rate, which is treated as exact.
+func (f *ABTestFramework) calculateConfidenceInterval(controlRate, treatmentRate float64, controlRequests, treatmentRequests int64, level float64) ConfidenceInterval {
+	interval := ConfidenceInterval{Level: level}
+	if controlRate == 0 || controlRequests == 0 || treatmentRequests == 0 {
+		return interval
+	}
+	
+	standardError := math.Sqrt(controlRate*(1-controlRate)/float64(controlRequests) +
+		treatmentRate*(1-treatmentRate)/float64(treatmentRequests))
+	margin := normalQuantile(1-(1-level)/2) * standardError
+	
+	difference := treatmentRate - controlRate
+	interval.Lower = (difference - margin) / controlRate * 100
+	interval.Upper = (difference + margin) / controlRate * 100
+	return interval
+}
+
+// calculatePower returns the power of the two-sided test at the given significance level to
+// detect a difference as large as the one observed: the chance that a real difference of
+// that size would have been found significant with these sample sizes
+func (f *ABTestFramework) calculatePower(controlRate, treatmentRate float64, controlRequests, treatmentRequests int64, significanceLevel float64) float64 {
+	if controlRequests == 0 || treatmentRequests == 0 {
+		return 0
+	}
 	
-	// Convert z-score to p-value (simplified)
-	if zScore > 1.96 {
-		return 0.01 // Significant
-	} else if zScore > 1.64 {
-		return 0.05 // Borderline
+	standardError := math.Sqrt(controlRate*(1-controlRate)/float64(controlRequests) +
+		treatmentRate*(1-treatmentRate)/float64(treatmentRequests))
+	if standardError == 0 {
+		return 0
 	}
-	return 0.1 // Not significant
+	
+	zScore := math.Abs(treatmentRate-controlRate) / standardError
+	return normalCDF(zScore - normalQuantile(1-significanceLevel/2))
 }
 
-func contains(text, pattern string) bool {
-	return len(text) >= len(pattern) && (text == pattern || 
-		(len(pattern) > 0 && text[:len(pattern)] == pattern))
+// normalCDF returns the probability that a standard normal value falls below x
+func normalCDF(x float64) float64 {
+	return 0.5 * math.Erfc(-x/math.Sqrt2)
+}
+
+// normalQuantile returns the value below which the standard normal distribution falls with
+// probability p
+func normalQuantile(p float64) float64 {
+	return math.Sqrt2 * math.Erfinv(2*p-1)
+}
+
+// matchesQueryPattern reports whether a query targeting pattern occurs anywhere in the query,
+// ignoring case. An empty pattern matches nothing, so a blank entry in the targeting rules
+// cannot pull every query into the experiment.
+func matchesQueryPattern(query, pattern string) bool {
+	pattern = strings.TrimSpace(pattern)
+	if pattern == "" {
+		return false
+	}
+	return strings.Contains(strings.ToLower(query), strings.ToLower(pattern))
 }
 
 // GetAllExperiments returns all experiments
@@ -746,29 +988,43 @@ func (f *ABTestFramework) GetExperimentAnalytics(experimentID string) *Experimen
 	
 	// Control variant analytics
 	if experiment.ControlVariant != nil {
-		analytics.TotalRequests += experiment.ControlVariant.Metrics.TotalRequests
+		metrics := currentMetrics(experiment.ControlVariant)
+		analytics.TotalRequests += metrics.TotalRequests
 		analytics.Variants["control"] = VariantAnalytics{
 			VariantID:     "control",
 			Name:          experiment.ControlVariant.Name,
-			TotalRequests: experiment.ControlVariant.Metrics.TotalRequests,
-			Metrics:       experiment.ControlVariant.Metrics,
+			TotalRequests: metrics.TotalRequests,
+			Metrics:       metrics,
 		}
 	}
 	
 	// Treatment variants analytics
 	for variantID, variant := range experiment.TreatmentVariants {
-		analytics.TotalRequests += variant.Metrics.TotalRequests
+		metrics := currentMetrics(variant)
+		analytics.TotalRequests += metrics.TotalRequests
 		analytics.Variants[variantID] = VariantAnalytics{
 			VariantID:     variantID,
 			Name:          variant.Name,
-			TotalRequests: variant.Metrics.TotalRequests,
-			Metrics:       variant.Metrics,
+			TotalRequests: metrics.TotalRequests,
+			Metrics:       metrics,
 		}
 	}
 	
 	return analytics
 }
 
+// currentMetrics returns a copy of a variant's metrics with up-to-date percentiles
+func currentMetrics(variant *Variant) VariantMetrics {
+	variant.mu.RLock()
+	defer variant.mu.RUnlock()
+	
+	metrics := variant.Metrics
+	updateResponseTimePercentiles(&metrics)
+	metrics.ResponseTimes = nil
+	metrics.ResultCounts = nil
+	return metrics
+}
+
 // GetExperimentsOverview returns overview of all experiments
 func (f *ABTestFramework) GetExperimentsOverview() *ExperimentsOverview {
 	f.mu.RLock()
@@ -815,6 +1071,8 @@ type ExperimentsOverview struct {
 // Supporting types
 
 type ExperimentConfig struct {
+	Type              ExperimentType
+	ControlStrategy   *models.SearchStrategy // search_strategy experiments only
 	TrafficAllocation float64
 	PrimaryMetric     string
 	SecondaryMetrics  []string
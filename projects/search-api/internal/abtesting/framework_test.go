@@ -0,0 +1,314 @@
+package abtesting
+
+import (
+	"fmt"
+	"math"
+	"testing"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+func TestMatchesQueryPattern(t *testing.T) {
+	tests := []struct {
+		query    string
+		pattern  string
+		expected bool
+	}{
+		{"running shoes", "running shoes", true},
+		{"running shoes", "running", true},
+		{"cheap running shoes", "running", true}, // middle of the query
+		{"cheap running shoes", "shoes", true},
+		{"Running Shoes", "running shoes", true}, // case-insensitive
+		{"running shoes", "SHOES", true},
+		{"running shoes", "boots", false},
+		{"shoe", "shoes", false},
+		{"running shoes", "", false}, // empty patterns never match
+		{"running shoes", "   ", false},
+		{"", "", false},
+	}
+
+	for _, tt := range tests {
+		if got := matchesQueryPattern(tt.query, tt.pattern); got != tt.expected {
+			t.Errorf("Expected matchesQueryPattern(%q, %q) to be %v, got %v", tt.query, tt.pattern, tt.expected, got)
+		}
+	}
+}
+
+func TestMatchesTargetingQueryPatterns(t *testing.T) {
+	f := &ABTestFramework{}
+	targeting := ExperimentTargeting{QueryPatterns: []string{"", "laptop"}}
+
+	if !f.matchesTargeting(ABTestRequest{Query: "best gaming laptop 2024"}, targeting) {
+		t.Errorf("Expected a query containing a pattern to be targeted")
+	}
+	if f.matchesTargeting(ABTestRequest{Query: "office chair"}, targeting) {
+		t.Errorf("Expected a query matching no pattern, not even the empty one, to be excluded")
+	}
+}
+
+func TestCalculatePValue(t *testing.T) {
+	f := &ABTestFramework{}
+
+	// 200/1000 against 250/1000: pooled rate 0.225, z = 2.677, two-sided p = 0.00742
+	pValue := f.calculatePValue(0.20, 0.25, 1000, 1000)
+	if math.Abs(pValue-0.00742) > 0.0001 {
+		t.Errorf("Expected p-value 0.00742, got %.5f", pValue)
+	}
+
+	// The test is two-sided, so the direction of the difference does not matter
+	if reversed := f.calculatePValue(0.25, 0.20, 1000, 1000); math.Abs(reversed-pValue) > 1e-12 {
+		t.Errorf("Expected the same p-value for a negative effect, got %.5f and %.5f", pValue, reversed)
+	}
+
+	// 100/1000 against 120/1000: z = 1.4293, p = 0.1529, not significant at 0.05
+	if pValue := f.calculatePValue(0.10, 0.12, 1000, 1000); math.Abs(pValue-0.1529) > 0.0001 {
+		t.Errorf("Expected p-value 0.1529, got %.4f", pValue)
+	}
+
+	if pValue := f.calculatePValue(0.30, 0.30, 500, 500); pValue != 1.0 {
+		t.Errorf("Expected p-value 1 for identical rates, got %f", pValue)
+	}
+	if pValue := f.calculatePValue(0.30, 0.40, 0, 500); pValue != 1.0 {
+		t.Errorf("Expected p-value 1 without control samples, got %f", pValue)
+	}
+}
+
+func TestCalculateConfidenceInterval(t *testing.T) {
+	f := &ABTestFramework{}
+
+	// Difference 0.05 with standard error 0.018641: 95% interval [0.013464, 0.086536] of the
+	// 0.20 control rate
+	interval := f.calculateConfidenceInterval(0.20, 0.25, 1000, 1000, 0.95)
+	if math.Abs(interval.Lower-6.732) > 0.01 || math.Abs(interval.Upper-43.268) > 0.01 {
+		t.Errorf("Expected interval [6.732, 43.268], got [%.3f, %.3f]", interval.Lower, interval.Upper)
+	}
+	if interval.Level != 0.95 {
+		t.Errorf("Expected level 0.95, got %f", interval.Level)
+	}
+
+	// A higher confidence level widens the interval
+	wider := f.calculateConfidenceInterval(0.20, 0.25, 1000, 1000, 0.99)
+	if wider.Lower >= interval.Lower || wider.Upper <= interval.Upper {
+		t.Errorf("Expected the 99%% interval to contain the 95%% interval, got [%.3f, %.3f]", wider.Lower, wider.Upper)
+	}
+}
+
+func TestNormalQuantile(t *testing.T) {
+	tests := map[float64]float64{0.5: 0, 0.975: 1.959964, 0.995: 2.575829, 0.025: -1.959964}
+	for p, expected := range tests {
+		if got := normalQuantile(p); math.Abs(got-expected) > 1e-5 {
+			t.Errorf("Expected quantile %f for %v, got %f", expected, p, got)
+		}
+	}
+}
+
+func TestResponseTimePercentiles(t *testing.T) {
+	metrics := VariantMetrics{}
+	for i := 1; i <= 200; i++ {
+		metrics.ResponseTimes = append(metrics.ResponseTimes, float64(201-i))
+	}
+
+	updateResponseTimePercentiles(&metrics)
+	if metrics.P95ResponseTime != 190 {
+		t.Errorf("Expected P95 of 190ms, got %v", metrics.P95ResponseTime)
+	}
+	if metrics.P99ResponseTime != 198 {
+		t.Errorf("Expected P99 of 198ms, got %v", metrics.P99ResponseTime)
+	}
+	if metrics.ResponseTimes[0] != 200 {
+		t.Errorf("Expected the retained samples to keep their order, got %v first", metrics.ResponseTimes[0])
+	}
+}
+
+func TestRetainSampleIsBounded(t *testing.T) {
+	var samples []float64
+	for count := int64(1); count <= maxRetainedSamples+500; count++ {
+		samples = retainSample(samples, float64(count), count)
+	}
+
+	if len(samples) != maxRetainedSamples {
+		t.Fatalf("Expected %d retained samples, got %d", maxRetainedSamples, len(samples))
+	}
+	// The oldest 500 samples were replaced by the newest
+	for _, sample := range samples[:500] {
+		if sample <= maxRetainedSamples {
+			t.Fatalf("Expected the oldest samples to be replaced, found %v", sample)
+		}
+	}
+}
+
+func TestTrafficAllocationWithinTolerance(t *testing.T) {
+	f := &ABTestFramework{}
+	experiment := &Experiment{
+		ID:                "exp-allocation",
+		ControlVariant:    &Variant{ID: "control", Weight: 50},
+		TreatmentVariants: map[string]*Variant{"treatment": {ID: "treatment", Weight: 50}},
+	}
+	if err := normalizeVariantWeights(experiment); err != nil {
+		t.Fatalf("Unexpected error normalizing weights: %v", err)
+	}
+
+	const users = 100000
+	for _, allocation := range []float64{0.01, 0.1, 0.5} {
+		participants, treated := 0, 0
+		for i := 0; i < users; i++ {
+			request := ABTestRequest{UserID: fmt.Sprintf("user-%d", i)}
+			if !f.shouldParticipate(request, experiment.ID, allocation) {
+				continue
+			}
+			participants++
+			if f.assignVariant(request, experiment).ID == "treatment" {
+				treated++
+			}
+		}
+
+		// Five standard deviations of the binomial proportion
+		share := float64(participants) / users
+		tolerance := 5 * math.Sqrt(allocation*(1-allocation)/users)
+		if math.Abs(share-allocation) > tolerance {
+			t.Errorf("Expected %.2f%% participation, got %.2f%%", allocation*100, share*100)
+		}
+
+		// Participation must not bias the variant split among participants
+		treatedShare := float64(treated) / float64(participants)
+		if math.Abs(treatedShare-0.5) > 5*math.Sqrt(0.25/float64(participants)) {
+			t.Errorf("Expected half of the %.0f%% participants in treatment, got %.2f%%", allocation*100, treatedShare*100)
+		}
+	}
+}
+
+func TestHashBucketIsSaltedAndStable(t *testing.T) {
+	if hashBucket("participation:exp", "user-1") != hashBucket("participation:exp", "user-1") {
+		t.Errorf("Expected the same key to land in the same bucket")
+	}
+	if hashBucket("participation:exp", "user-1") == hashBucket("variant:exp", "user-1") {
+		t.Errorf("Expected different salts to give different buckets")
+	}
+	for i := 0; i < 1000; i++ {
+		if bucket := hashBucket("salt", fmt.Sprintf("key-%d", i)); bucket < 0 || bucket >= 1 {
+			t.Fatalf("Expected a bucket in [0, 1), got %v", bucket)
+		}
+	}
+}
+
+func TestNormalizeVariantWeights(t *testing.T) {
+	experiment := &Experiment{
+		ID:             "exp-weights",
+		ControlVariant: &Variant{ID: "control", Weight: 0.5},
+		TreatmentVariants: map[string]*Variant{
+			"a": {ID: "a", Weight: 0.5},
+			"b": {ID: "b", Weight: 1.0},
+			"c": {ID: "c", Weight: 0.5},
+		},
+	}
+	if err := normalizeVariantWeights(experiment); err != nil {
+		t.Fatalf("Unexpected error normalizing weights: %v", err)
+	}
+
+	expected := map[string]float64{"control": 0.2, "a": 0.2, "b": 0.4, "c": 0.2}
+	if math.Abs(experiment.ControlVariant.Weight-expected["control"]) > 1e-9 {
+		t.Errorf("Expected control weight 0.2, got %v", experiment.ControlVariant.Weight)
+	}
+	for id, variant := range experiment.TreatmentVariants {
+		if math.Abs(variant.Weight-expected[id]) > 1e-9 {
+			t.Errorf("Expected variant %s weight %v, got %v", id, expected[id], variant.Weight)
+		}
+	}
+
+	// Assignment follows the normalized weights
+	f := &ABTestFramework{}
+	const users = 100000
+	counts := make(map[string]int)
+	for i := 0; i < users; i++ {
+		request := ABTestRequest{UserID: fmt.Sprintf("user-%d", i)}
+		counts[f.assignVariant(request, experiment).ID]++
+	}
+	for id, weight := range expected {
+		share := float64(counts[id]) / users
+		if math.Abs(share-weight) > 5*math.Sqrt(weight*(1-weight)/users) {
+			t.Errorf("Expected %.0f%% of users in %s, got %.2f%%", weight*100, id, share*100)
+		}
+	}
+
+	// Assignment is stable for a user
+	request := ABTestRequest{UserID: "user-42"}
+	first := f.assignVariant(request, experiment)
+	for i := 0; i < 20; i++ {
+		if variant := f.assignVariant(request, experiment); variant != first {
+			t.Fatalf("Expected user-42 to stay in %s, got %s", first.ID, variant.ID)
+		}
+	}
+}
+
+func TestNormalizeVariantWeightsRejectsInvalidWeights(t *testing.T) {
+	zero := &Experiment{
+		ControlVariant:    &Variant{ID: "control"},
+		TreatmentVariants: map[string]*Variant{"a": {ID: "a"}},
+	}
+	if err := normalizeVariantWeights(zero); err == nil {
+		t.Errorf("Expected zero total weight to be rejected")
+	}
+
+	negative := &Experiment{
+		ControlVariant:    &Variant{ID: "control", Weight: 1},
+		TreatmentVariants: map[string]*Variant{"a": {ID: "a", Weight: -0.5}},
+	}
+	if err := normalizeVariantWeights(negative); err == nil {
+		t.Errorf("Expected a negative weight to be rejected")
+	}
+}
+
+func TestRecordExperimentResultTracksEngagement(t *testing.T) {
+	experiment := newRunningExperiment("engagement", time.Now(), 0)
+	f := &ABTestFramework{logger: zap.NewNop(), experiments: map[string]*Experiment{experiment.ID: experiment}}
+	assignment := &ExperimentAssignment{ExperimentID: experiment.ID, VariantID: "treatment", Experiment: experiment}
+
+	f.RecordExperimentResult(assignment, ExperimentResult{Success: true, ResultCount: 5, ClickThrough: true, UserRating: 4})
+	f.RecordExperimentResult(assignment, ExperimentResult{Success: true, ResultCount: 5, ClickThrough: true, Conversion: true})
+	f.RecordExperimentResult(assignment, ExperimentResult{Success: false, ResultCount: 0, UserRating: 2})
+	f.RecordExperimentResult(assignment, ExperimentResult{Success: true, ResultCount: 3})
+
+	metrics := experiment.TreatmentVariants["treatment"].Metrics
+	if metrics.Clicks != 2 || metrics.Conversions != 1 {
+		t.Errorf("Expected 2 clicks and 1 conversion, got %d and %d", metrics.Clicks, metrics.Conversions)
+	}
+	if metrics.ClickThroughRate != 0.5 || metrics.ConversionRate != 0.25 {
+		t.Errorf("Expected CTR 0.5 and conversion rate 0.25, got %.2f and %.2f", metrics.ClickThroughRate, metrics.ConversionRate)
+	}
+	if metrics.Ratings != 2 || metrics.UserSatisfaction != 3 {
+		t.Errorf("Expected satisfaction 3 over 2 ratings, got %.2f over %d", metrics.UserSatisfaction, metrics.Ratings)
+	}
+	if metrics.SuccessRate != 0.75 || metrics.ErrorRate != 0.25 || metrics.ZeroResultsRate != 0.25 {
+		t.Errorf("Expected success 0.75, error 0.25 and zero results 0.25, got %.2f, %.2f and %.2f",
+			metrics.SuccessRate, metrics.ErrorRate, metrics.ZeroResultsRate)
+	}
+}
+
+func TestAnalyzeExperimentFlagsSecondaryRegressions(t *testing.T) {
+	experiment := newRunningExperiment("secondary", time.Now(), 0)
+	experiment.MinSampleSize = 100
+	experiment.SignificanceLevel = 0.05
+	experiment.PrimaryMetric = "click_through_rate"
+	experiment.SecondaryMetrics = []string{"click_through_rate", "conversion_rate", "zero_results_rate", "avg_response_time"}
+	experiment.ControlVariant.Metrics = VariantMetrics{TotalRequests: 1000, ClickThroughRate: 0.20, ConversionRate: 0.25, ZeroResultsRate: 0.10}
+	experiment.TreatmentVariants["treatment"].Metrics = VariantMetrics{TotalRequests: 1000, ClickThroughRate: 0.25, ConversionRate: 0.20, ZeroResultsRate: 0.10}
+	f := &ABTestFramework{logger: zap.NewNop(), experiments: map[string]*Experiment{experiment.ID: experiment}}
+
+	f.analyzeExperiment(experiment.ID)
+
+	if experiment.Results.Winner != "treatment" {
+		t.Errorf("Expected treatment to win on the primary metric, got %q", experiment.Results.Winner)
+	}
+	if len(experiment.Results.SecondaryResults) != 2 {
+		t.Fatalf("Expected results for conversion_rate and zero_results_rate only, got %v", experiment.Results.SecondaryResults)
+	}
+	conversion := experiment.Results.SecondaryResults["conversion_rate"]["treatment"]
+	if math.Abs(conversion.Effect+20) > 1e-9 || conversion.PValue >= 0.05 {
+		t.Errorf("Expected a significant -20%% conversion effect, got %+v", conversion)
+	}
+	expected := "Variant treatment significantly worsens conversion_rate (-20.0% vs control)"
+	if len(experiment.Results.Recommendations) != 1 || experiment.Results.Recommendations[0] != expected {
+		t.Errorf("Expected recommendation %q, got %v", expected, experiment.Results.Recommendations)
+	}
+}
@@ -0,0 +1,180 @@
+package abtesting
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/saif-islam/es-playground/projects/search-api/internal/models"
+)
+
+// QueryPlaceholder marks where the query text goes in a strategy's query template
+const QueryPlaceholder = "{{query}}"
+
+// Engagement events reported for a search served by an experiment variant
+const (
+	EngagementClick      = "click"
+	EngagementConversion = "conversion"
+)
+
+// Engagement is a user interaction with the results of a search served by a variant
+type Engagement struct {
+	Event    string  `json:"event"`              // click or conversion
+	Position int     `json:"position,omitempty"` // 1-based rank of the clicked result
+	Rating   float64 `json:"rating,omitempty"`   // optional explicit relevance rating
+}
+
+// validateVariant checks that a variant changes what its experiment type compares
+func validateVariant(experimentType ExperimentType, variant *Variant) error {
+	if experimentType != TypeSearchStrategy {
+		if variant.Strategy != nil {
+			return fmt.Errorf("strategies are only supported in %s experiments", TypeSearchStrategy)
+		}
+		return nil
+	}
+
+	if variant.Strategy == nil {
+		return fmt.Errorf("%s experiment variants require a strategy", TypeSearchStrategy)
+	}
+	return validateStrategy(variant.Strategy)
+}
+
+// validateStrategy checks that a strategy can build a main query
+func validateStrategy(strategy *models.SearchStrategy) error {
+	if strategy.QueryTemplate == "" && len(strategy.FieldBoosts) == 0 {
+		return fmt.Errorf("strategy needs a query_template or field_boosts")
+	}
+	if strategy.QueryTemplate != "" && len(strategy.FieldBoosts) > 0 {
+		return fmt.Errorf("query_template and field_boosts are mutually exclusive; put boosts in the template")
+	}
+
+	if strategy.QueryTemplate != "" {
+		// The placeholder sits inside a JSON string, so the template itself is valid JSON
+		var query map[string]interface{}
+		if err := json.Unmarshal([]byte(strategy.QueryTemplate), &query); err != nil {
+			return fmt.Errorf("query_template is not a JSON object: %w", err)
+		}
+		if !strings.Contains(strategy.QueryTemplate, QueryPlaceholder) {
+			return fmt.Errorf("query_template does not use the %s placeholder", QueryPlaceholder)
+		}
+	}
+
+	for field, boost := range strategy.FieldBoosts {
+		if boost <= 0 {
+			return fmt.Errorf("boost for %s must be positive", field)
+		}
+	}
+
+	for _, rescore := range strategy.Rescore {
+		if rescore.WindowSize <= 0 || rescore.Query == "" {
+			return fmt.Errorf("rescore needs a window_size and query")
+		}
+	}
+
+	return nil
+}
+
+// RecordEngagement records a click or conversion on a search served by an experiment variant,
+// feeding the click-through, conversion and mean reciprocal rank metrics
+func (f *ABTestFramework) RecordEngagement(experimentID, variantID string, engagement Engagement) error {
+	if engagement.Event != EngagementClick && engagement.Event != EngagementConversion {
+		return fmt.Errorf("unknown engagement event %q", engagement.Event)
+	}
+
+	f.mu.RLock()
+	experiment := f.experiments[experimentID]
+	f.mu.RUnlock()
+
+	if experiment == nil {
+		return fmt.Errorf("experiment %s not found", experimentID)
+	}
+
+	experiment.mu.Lock()
+	defer experiment.mu.Unlock()
+
+	variant := experiment.TreatmentVariants[variantID]
+	if variantID == "control" {
+		variant = experiment.ControlVariant
+	}
+	if variant == nil {
+		return fmt.Errorf("variant %s not found in experiment %s", variantID, experimentID)
+	}
+
+	variant.mu.Lock()
+	defer variant.mu.Unlock()
+
+	metrics := &variant.Metrics
+	switch engagement.Event {
+	case EngagementClick:
+		metrics.Clicks++
+		if engagement.Position > 0 {
+			metrics.MeanReciprocalRank = f.updateAverage(metrics.MeanReciprocalRank, 1/float64(engagement.Position), metrics.Clicks)
+		}
+	case EngagementConversion:
+		metrics.Conversions++
+	}
+
+	if engagement.Rating > 0 {
+		metrics.Ratings++
+		metrics.UserSatisfaction = f.updateAverage(metrics.UserSatisfaction, engagement.Rating, metrics.Ratings)
+	}
+
+	updateEngagementRates(metrics)
+	metrics.LastUpdated = time.Now()
+
+	f.logger.Debug("Recorded experiment engagement",
+		zap.String("experiment_id", experimentID),
+		zap.String("variant_id", variantID),
+		zap.String("event", engagement.Event))
+
+	if metrics.TotalRequests >= int64(experiment.MinSampleSize) {
+		go f.analyzeExperiment(experimentID)
+	}
+
+	return nil
+}
+
+// updateEngagementRates derives the click-through and conversion rates from the served requests
+func updateEngagementRates(metrics *VariantMetrics) {
+	if metrics.TotalRequests == 0 {
+		return
+	}
+	metrics.ClickThroughRate = float64(metrics.Clicks) / float64(metrics.TotalRequests)
+	metrics.ConversionRate = float64(metrics.Conversions) / float64(metrics.TotalRequests)
+}
+
+// metricValue returns the variant metric an experiment is judged on
+func metricValue(metrics VariantMetrics, metric string) float64 {
+	switch metric {
+	case "click_through_rate":
+		return metrics.ClickThroughRate
+	case "conversion_rate":
+		return metrics.ConversionRate
+	case "mean_reciprocal_rank":
+		return metrics.MeanReciprocalRank
+	case "error_rate":
+		return metrics.ErrorRate
+	case "zero_results_rate":
+		return metrics.ZeroResultsRate
+	default:
+		return metrics.SuccessRate
+	}
+}
+
+// isRateMetric reports whether metricValue knows the metric and it is a rate in [0, 1] that
+// the proportion test applies to
+func isRateMetric(metric string) bool {
+	switch metric {
+	case "success_rate", "error_rate", "zero_results_rate", "click_through_rate", "conversion_rate", "mean_reciprocal_rank":
+		return true
+	}
+	return false
+}
+
+// lowerIsBetter reports whether a decrease in the metric is an improvement
+func lowerIsBetter(metric string) bool {
+	return metric == "error_rate" || metric == "zero_results_rate"
+}
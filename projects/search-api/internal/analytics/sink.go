@@ -0,0 +1,286 @@
+package analytics
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/saif-islam/es-playground/projects/search-api/internal/metrics"
+	"github.com/saif-islam/es-playground/projects/search-api/internal/models"
+	"github.com/saif-islam/es-playground/projects/search-api/internal/realtime"
+	"github.com/saif-islam/es-playground/shared"
+)
+
+// ESSink persists search analytics records into a dedicated Elasticsearch index.
+// Records are buffered and written with the bulk API so searches don't pay a
+// per-query indexing cost. Query text is normalized and anonymized before it is queued.
+type ESSink struct {
+	esClient   *shared.ESClient
+	logger     *zap.Logger
+	config     models.AnalyticsConfig
+	anonymizer *realtime.QueryAnonymizer
+
+	records chan models.SearchAnalytics
+	dropped atomic.Int64 // records dropped since the last report
+	done    chan struct{}
+	wg      sync.WaitGroup
+}
+
+// analyticsDocument is the shape of a record in the analytics index
+type analyticsDocument struct {
+	models.SearchAnalytics
+	EventTime       time.Time `json:"@timestamp"`
+	ExecutionTimeMs float64   `json:"execution_time_ms"`
+	ZeroResults     bool      `json:"zero_results"`
+}
+
+// analyticsMappings keeps the fields dashboards aggregate on as keywords/numbers
+var analyticsMappings = map[string]interface{}{
+	"properties": map[string]interface{}{
+		"@timestamp":        map[string]interface{}{"type": "date"},
+		"timestamp":         map[string]interface{}{"type": "date"},
+		"query_id":          map[string]interface{}{"type": "keyword"},
+		"query":             map[string]interface{}{"type": "text", "fields": map[string]interface{}{"raw": map[string]interface{}{"type": "keyword", "ignore_above": 512}}},
+		"index":             map[string]interface{}{"type": "keyword"},
+		"user_id":           map[string]interface{}{"type": "keyword"},
+		"session_id":        map[string]interface{}{"type": "keyword"},
+		"result_count":      map[string]interface{}{"type": "long"},
+		"execution_time_ms": map[string]interface{}{"type": "float"},
+		"zero_results":      map[string]interface{}{"type": "boolean"},
+		"metadata":          map[string]interface{}{"type": "object", "enabled": false},
+	},
+}
+
+// NewESSink creates a sink and starts its background flusher
+func NewESSink(esClient *shared.ESClient, config models.AnalyticsConfig, logger *zap.Logger) *ESSink {
+	// Set defaults
+	if config.IndexName == "" {
+		config.IndexName = "search-analytics"
+	}
+	if config.BatchSize <= 0 {
+		config.BatchSize = 500
+	}
+	if config.FlushInterval <= 0 {
+		config.FlushInterval = 5 * time.Second
+	}
+	if config.BufferSize <= 0 {
+		config.BufferSize = config.BatchSize * 10
+	}
+	if config.QueryMode == "" {
+		config.QueryMode = realtime.QueryModeHash
+	}
+
+	sink := &ESSink{
+		esClient:   esClient,
+		logger:     logger,
+		config:     config,
+		anonymizer: realtime.NewQueryAnonymizer(config.QueryMode, config.TruncateLength, config.HashSalt),
+		records:    make(chan models.SearchAnalytics, config.BufferSize),
+		done:       make(chan struct{}),
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	if err := sink.ensureIndex(ctx); err != nil {
+		logger.Warn("Failed to prepare search analytics index",
+			zap.String("index", config.IndexName),
+			zap.Error(err))
+	}
+
+	sink.wg.Add(1)
+	go sink.run()
+
+	return sink
+}
+
+// Record queues an analytics record; it never blocks the search path. Dropped records
+// are counted, and logged once per flush interval rather than one by one.
+func (s *ESSink) Record(record models.SearchAnalytics) {
+	record.Query = s.anonymizer.Anonymize(normalizeQuery(record.Query))
+
+	select {
+	case s.records <- record:
+	default:
+		metrics.RecordAnalyticsRecordDrop()
+		s.dropped.Add(1)
+	}
+}
+
+// normalizeQuery lowercases a query and collapses its whitespace, so queries that differ
+// only in case or spacing are stored, and hashed, the same way
+func normalizeQuery(query string) string {
+	return strings.ToLower(strings.Join(strings.Fields(query), " "))
+}
+
+// reportDrops logs how many records were dropped since the last report
+func (s *ESSink) reportDrops() {
+	if dropped := s.dropped.Swap(0); dropped > 0 {
+		s.logger.Warn("Search analytics buffer full, dropped records",
+			zap.Int64("records", dropped),
+			zap.Duration("interval", s.config.FlushInterval))
+	}
+}
+
+// Close flushes buffered records and stops the background flusher
+func (s *ESSink) Close() {
+	close(s.done)
+	s.wg.Wait()
+}
+
+// run batches records and flushes them by size or interval
+func (s *ESSink) run() {
+	defer s.wg.Done()
+
+	ticker := time.NewTicker(s.config.FlushInterval)
+	defer ticker.Stop()
+
+	batch := make([]models.SearchAnalytics, 0, s.config.BatchSize)
+
+	for {
+		select {
+		case record := <-s.records:
+			batch = append(batch, record)
+			if len(batch) >= s.config.BatchSize {
+				s.flush(batch)
+				batch = batch[:0]
+			}
+
+		case <-ticker.C:
+			s.reportDrops()
+			if len(batch) > 0 {
+				s.flush(batch)
+				batch = batch[:0]
+			}
+
+		case <-s.done:
+			s.reportDrops()
+			// Drain whatever is still queued before exiting
+			for {
+				select {
+				case record := <-s.records:
+					batch = append(batch, record)
+				default:
+					if len(batch) > 0 {
+						s.flush(batch)
+					}
+					return
+				}
+			}
+		}
+	}
+}
+
+// flush writes a batch of records with a single bulk request
+func (s *ESSink) flush(batch []models.SearchAnalytics) {
+	var buf bytes.Buffer
+	for _, record := range batch {
+		doc := analyticsDocument{
+			SearchAnalytics: record,
+			EventTime:       record.Timestamp,
+			ExecutionTimeMs: float64(record.ExecutionTime.Microseconds()) / 1000.0,
+			ZeroResults:     record.ResultCount == 0,
+		}
+
+		docBytes, err := json.Marshal(doc)
+		if err != nil {
+			s.logger.Warn("Failed to marshal search analytics record",
+				zap.String("query_id", record.QueryID),
+				zap.Error(err))
+			continue
+		}
+
+		buf.WriteString(`{"index":{}}`)
+		buf.WriteByte('\n')
+		buf.Write(docBytes)
+		buf.WriteByte('\n')
+	}
+
+	if buf.Len() == 0 {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	res, err := s.esClient.Bulk(
+		&buf,
+		s.esClient.Bulk.WithContext(ctx),
+		s.esClient.Bulk.WithIndex(s.config.IndexName),
+	)
+	if err != nil {
+		s.logger.Error("Failed to persist search analytics",
+			zap.Int("records", len(batch)),
+			zap.Error(err))
+		return
+	}
+	defer res.Body.Close()
+
+	if res.IsError() {
+		body, _ := io.ReadAll(res.Body)
+		s.logger.Error("Search analytics bulk request failed",
+			zap.Int("records", len(batch)),
+			zap.String("status", res.Status()),
+			zap.String("response", string(body)))
+		return
+	}
+
+	var bulkResp struct {
+		Errors bool `json:"errors"`
+	}
+	if err := json.NewDecoder(res.Body).Decode(&bulkResp); err == nil && bulkResp.Errors {
+		s.logger.Warn("Some search analytics records were rejected",
+			zap.Int("records", len(batch)))
+	}
+
+	s.logger.Debug("Persisted search analytics",
+		zap.String("index", s.config.IndexName),
+		zap.Int("records", len(batch)))
+}
+
+// ensureIndex creates the analytics index with dashboard-friendly mappings if missing
+func (s *ESSink) ensureIndex(ctx context.Context) error {
+	res, err := s.esClient.Indices.Exists(
+		[]string{s.config.IndexName},
+		s.esClient.Indices.Exists.WithContext(ctx),
+	)
+	if err != nil {
+		return err
+	}
+	res.Body.Close()
+
+	if res.StatusCode == 200 {
+		return nil
+	}
+
+	body, err := json.Marshal(map[string]interface{}{
+		"mappings": analyticsMappings,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to marshal analytics mappings: %w", err)
+	}
+
+	res, err = s.esClient.Indices.Create(
+		s.config.IndexName,
+		s.esClient.Indices.Create.WithContext(ctx),
+		s.esClient.Indices.Create.WithBody(strings.NewReader(string(body))),
+	)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+
+	if res.IsError() {
+		return shared.ParseESError(res)
+	}
+
+	s.logger.Info("Created search analytics index", zap.String("index", s.config.IndexName))
+	return nil
+}
@@ -0,0 +1,80 @@
+package analytics
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"go.uber.org/zap"
+
+	"github.com/saif-islam/es-playground/projects/search-api/internal/metrics"
+	"github.com/saif-islam/es-playground/projects/search-api/internal/models"
+	"github.com/saif-islam/es-playground/projects/search-api/internal/realtime"
+)
+
+func newTestSink(mode string, bufferSize int) *ESSink {
+	return &ESSink{
+		logger:     zap.NewNop(),
+		anonymizer: realtime.NewQueryAnonymizer(mode, 0, "pepper"),
+		records:    make(chan models.SearchAnalytics, bufferSize),
+	}
+}
+
+func TestESSinkRecordAnonymizesQueries(t *testing.T) {
+	tests := []struct {
+		name     string
+		mode     string
+		query    string
+		expected string
+	}{
+		{name: "raw is normalized", mode: realtime.QueryModeRaw, query: "  Refurbished   LAPTOP ", expected: "refurbished laptop"},
+		{name: "truncate", mode: realtime.QueryModeTruncate, query: "where can I buy a refurbished laptop near me", expected: "where can i buy a refurbished la..."},
+		{name: "drop", mode: realtime.QueryModeDrop, query: "laptop", expected: ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sink := newTestSink(tt.mode, 1)
+			sink.Record(models.SearchAnalytics{QueryID: "q1", Query: tt.query})
+
+			if got := (<-sink.records).Query; got != tt.expected {
+				t.Errorf("Expected %q, got %q", tt.expected, got)
+			}
+		})
+	}
+}
+
+func TestESSinkRecordHashesNormalizedQueries(t *testing.T) {
+	sink := newTestSink(realtime.QueryModeHash, 2)
+	sink.Record(models.SearchAnalytics{Query: "Laptop  Bag"})
+	sink.Record(models.SearchAnalytics{Query: "laptop bag"})
+
+	first, second := (<-sink.records).Query, (<-sink.records).Query
+	if !strings.HasPrefix(first, "sha256:") || strings.Contains(first, "aptop") {
+		t.Errorf("Expected a sha256 hash without the query text, got %q", first)
+	}
+	if first != second {
+		t.Errorf("Expected queries differing only in case and spacing to hash the same, got %q and %q", first, second)
+	}
+}
+
+func TestESSinkRecordCountsDrops(t *testing.T) {
+	sink := newTestSink(realtime.QueryModeHash, 1)
+	before := testutil.ToFloat64(metrics.AnalyticsRecordsDropped)
+
+	for i := 0; i < 3; i++ {
+		sink.Record(models.SearchAnalytics{Query: "laptop"})
+	}
+
+	if dropped := testutil.ToFloat64(metrics.AnalyticsRecordsDropped) - before; dropped != 2 {
+		t.Errorf("Expected 2 dropped records in the metric, got %v", dropped)
+	}
+	if dropped := sink.dropped.Load(); dropped != 2 {
+		t.Errorf("Expected 2 drops waiting to be reported, got %d", dropped)
+	}
+
+	sink.reportDrops()
+	if dropped := sink.dropped.Load(); dropped != 0 {
+		t.Errorf("Expected the drop count to reset once reported, got %d", dropped)
+	}
+}
@@ -5,15 +5,25 @@ import (
 	"crypto/md5"
 	"encoding/hex"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"sort"
+	"strings"
 	"time"
 
 	"github.com/redis/go-redis/v9"
 	"go.uber.org/zap"
 
 	"github.com/saif-islam/es-playground/projects/search-api/internal/models"
+	"github.com/saif-islam/es-playground/shared"
 )
 
+// Search results are cached under search:<index namespace>:<request hash>
+const searchKeyPrefix = "search:"
+
+// scanBatchSize is the COUNT hint for each SCAN while invalidating
+const scanBatchSize = 500
+
 // RedisCache provides Redis-based caching functionality
 type RedisCache struct {
 	client   *redis.Client
@@ -186,16 +196,34 @@ func (c *RedisCache) Exists(ctx context.Context, key string) bool {
 	return err == nil && count > 0
 }
 
+// getJSON retrieves a cached value into v. Values come back from Redis as generic JSON, so
+// they are decoded again into the caller's type.
+func (c *RedisCache) getJSON(ctx context.Context, key string, v interface{}) bool {
+	data, found := c.Get(ctx, key)
+	if !found {
+		return false
+	}
+
+	raw, err := json.Marshal(data)
+	if err == nil {
+		err = json.Unmarshal(raw, v)
+	}
+	if err != nil {
+		c.logger.Error("Failed to decode cache entry", zap.String("key", key), zap.Error(err))
+		return false
+	}
+	return true
+}
+
 // GetSearchResult retrieves a cached search result
 func (c *RedisCache) GetSearchResult(ctx context.Context, req *models.SearchRequest) (*models.SearchResponse, bool) {
-	key := c.generateSearchKey(req)
+	key := c.generateSearchKey(ctx, req)
 	
-	if data, found := c.Get(ctx, key); found {
-		if response, ok := data.(*models.SearchResponse); ok {
-			// Add cache hit indicator
-			response.CacheHit = true
-			return response, true
-		}
+	var response models.SearchResponse
+	if c.getJSON(ctx, key, &response) {
+		// Add cache hit indicator
+		response.CacheHit = true
+		return &response, true
 	}
 	
 	return nil, false
@@ -203,7 +231,7 @@ func (c *RedisCache) GetSearchResult(ctx context.Context, req *models.SearchRequ
 
 // SetSearchResult caches a search result
 func (c *RedisCache) SetSearchResult(ctx context.Context, req *models.SearchRequest, response *models.SearchResponse) error {
-	key := c.generateSearchKey(req)
+	key := c.generateSearchKey(ctx, req)
 	ttl := c.config.TTL
 	
 	// Calculate adaptive TTL based on query characteristics
@@ -218,32 +246,67 @@ func (c *RedisCache) SetSearchResult(ctx context.Context, req *models.SearchRequ
 	return c.Set(ctx, key, &cachedResponse, ttl)
 }
 
+// GetCountResult retrieves a cached count
+func (c *RedisCache) GetCountResult(ctx context.Context, req *models.SearchRequest) (*models.CountResponse, bool) {
+	var response models.CountResponse
+	if c.getJSON(ctx, c.generateCountKey(ctx, req), &response) {
+		response.CacheHit = true
+		return &response, true
+	}
+	return nil, false
+}
+
+// SetCountResult caches a count
+func (c *RedisCache) SetCountResult(ctx context.Context, req *models.SearchRequest, response *models.CountResponse) error {
+	cachedResponse := *response
+	cachedResponse.CacheHit = false
+	return c.Set(ctx, c.generateCountKey(ctx, req), &cachedResponse, c.config.TTL)
+}
+
 // InvalidatePattern removes all keys matching a pattern
 func (c *RedisCache) InvalidatePattern(ctx context.Context, pattern string) error {
+	_, err := c.deleteMatching(ctx, pattern)
+	return err
+}
+
+// deleteMatching removes all keys matching a pattern and returns how many were removed.
+// Keys are found with SCAN rather than KEYS so large caches do not block Redis.
+func (c *RedisCache) deleteMatching(ctx context.Context, pattern string) (int64, error) {
 	if !c.enabled {
-		return nil
+		return 0, nil
 	}
 
 	fullPattern := c.buildKey(pattern)
 	
-	// Find matching keys
-	keys, err := c.client.Keys(ctx, fullPattern).Result()
-	if err != nil {
-		return err
-	}
+	var deleted int64
+	var cursor uint64
+	for {
+		keys, next, err := c.client.Scan(ctx, cursor, fullPattern, scanBatchSize).Result()
+		if err != nil {
+			return deleted, err
+		}
 
-	if len(keys) > 0 {
-		// Delete matching keys
-		if err := c.client.Del(ctx, keys...).Err(); err != nil {
-			return err
+		if len(keys) > 0 {
+			count, err := c.client.Del(ctx, keys...).Result()
+			if err != nil {
+				return deleted, err
+			}
+			deleted += count
 		}
-		
+
+		cursor = next
+		if cursor == 0 {
+			break
+		}
+	}
+
+	if deleted > 0 {
 		c.logger.Info("Invalidated cache keys", 
 			zap.String("pattern", pattern), 
-			zap.Int("count", len(keys)))
+			zap.Int64("count", deleted))
 	}
 
-	return nil
+	return deleted, nil
 }
 
 // GetStats returns cache statistics
@@ -314,8 +377,7 @@ func (c *RedisCache) Clear(ctx context.Context) error {
 		return nil
 	}
 
-	pattern := c.buildKey("*")
-	return c.InvalidatePattern(ctx, pattern)
+	return c.InvalidatePattern(ctx, "*")
 }
 
 // Helper methods
@@ -329,22 +391,96 @@ func (c *RedisCache) buildKey(key string) string {
 	return fmt.Sprintf("%s:%s", c.prefix, key)
 }
 
-func (c *RedisCache) generateSearchKey(req *models.SearchRequest) string {
+func (c *RedisCache) generateSearchKey(ctx context.Context, req *models.SearchRequest) string {
 	// Create a deterministic key based on search parameters
 	keyData := map[string]interface{}{
-		"query":      req.Query,
-		"index":      req.Index,
-		"size":       req.Size,
-		"from":       req.From,
-		"query_type": req.QueryType,
-		"fields":     req.Fields,
-		"sort":       req.Sort,
-		"filters":    req.Filters,
+		// The same index on another cluster holds other documents
+		"cluster":        requestCluster(ctx),
+		"query":          req.Query,
+		"index":          req.Index,
+		"size":           req.Size,
+		"from":           req.From,
+		"query_type":     req.QueryType,
+		"fields":         req.Fields,
+		"default_field":  req.DefaultField,
+		"sort":           req.Sort,
+		"filters":        req.Filters,
+		// Results may be redacted differently depending on the caller's scopes
+		"scopes":         req.Scopes,
+		// Experiment strategies rank the same query differently
+		"strategy":       req.Strategy,
+		"knn":            req.KNN,
+		"collapse":       req.Collapse,
+		"relation":       req.Relation,
+		"function_score": req.FunctionScore,
+		"aggregations":   req.Aggregations,
+		"aggs_only":      req.AggregationsOnly,
+		// Sparse results carry a correction only when it was asked for
+		"auto_suggest":   req.AutoSuggest,
+	}
+	
+	keyBytes, _ := json.Marshal(keyData)
+	hash := md5.Sum(keyBytes)
+	return fmt.Sprintf("%s%s:%s", searchKeyPrefix, indexNamespace(req.Index), hex.EncodeToString(hash[:]))
+}
+
+// generateCountKey keys a count by the parts of the request that decide which documents
+// match. Counts share the search namespace, so invalidating an index drops them too.
+func (c *RedisCache) generateCountKey(ctx context.Context, req *models.SearchRequest) string {
+	keyData := map[string]interface{}{
+		"cluster":        requestCluster(ctx),
+		"query":          req.Query,
+		"index":          req.Index,
+		"query_type":     req.QueryType,
+		"fields":         req.Fields,
+		"default_field":  req.DefaultField,
+		"operator":       req.Operator,
+		"fuzziness":      req.Fuzziness,
+		"filters":        req.Filters,
+		"strategy":       req.Strategy,
+		"relation":       req.Relation,
+		"function_score": req.FunctionScore,
 	}
 	
 	keyBytes, _ := json.Marshal(keyData)
 	hash := md5.Sum(keyBytes)
-	return fmt.Sprintf("search:%s", hex.EncodeToString(hash[:]))
+	return fmt.Sprintf("%scount:%s:%s", searchKeyPrefix, indexNamespace(req.Index), hex.EncodeToString(hash[:]))
+}
+
+// requestCluster returns the cluster a request was routed to
+func requestCluster(ctx context.Context) string {
+	if cluster, ok := shared.ClusterFromContext(ctx); ok {
+		return cluster
+	}
+	return shared.DefaultClusterName
+}
+
+// indexNamespace lists the searched indices in a cache key, sorted and comma-delimited on
+// both sides, so a search over "orders,products" is stored under ",orders,products," and
+// can be found again when either index is invalidated
+func indexNamespace(index string) string {
+	var indices []string
+	for _, name := range strings.Split(index, ",") {
+		if name = strings.TrimSpace(name); name != "" {
+			indices = append(indices, name)
+		}
+	}
+	sort.Strings(indices)
+	return "," + strings.Join(indices, ",") + ","
+}
+
+// escapePattern escapes the characters Redis treats specially in a MATCH pattern, so index
+// names such as "logs-*" only match themselves
+func escapePattern(s string) string {
+	var b strings.Builder
+	for _, r := range s {
+		switch r {
+		case '*', '?', '[', ']', '\\':
+			b.WriteByte('\\')
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
 }
 
 func (c *RedisCache) calculateSearchTTL(req *models.SearchRequest, response *models.SearchResponse) time.Duration {
@@ -400,10 +536,26 @@ func (c *RedisCache) getPopularKeys(ctx context.Context, limit int) []string {
 	return []string{}
 }
 
+// ErrCacheDisabled is returned when warming a cache that is turned off
+var ErrCacheDisabled = errors.New("cache is disabled")
+
+// Searcher runs a search and caches its result
+type Searcher func(ctx context.Context, req *models.SearchRequest) (*models.SearchResponse, error)
+
+// WarmSummary reports how a cache warm run went
+type WarmSummary struct {
+	Requested     int           `json:"requested"`
+	Warmed        int           `json:"warmed"`
+	AlreadyCached int           `json:"already_cached"`
+	Failed        int           `json:"failed"`
+	Duration      time.Duration `json:"duration"`
+}
+
 // CacheManager provides high-level cache operations
 type CacheManager struct {
-	cache  *RedisCache
-	logger *zap.Logger
+	cache    *RedisCache
+	logger   *zap.Logger
+	searcher Searcher
 }
 
 // NewCacheManager creates a new cache manager
@@ -414,6 +566,88 @@ func NewCacheManager(cache *RedisCache, logger *zap.Logger) *CacheManager {
 	}
 }
 
+// InvalidateIndex removes every cached search that covered the index, including searches
+// over several indices, and returns how many keys were removed. Call it after re-indexing
+// so users do not see stale results until the TTL expires. Wildcard and alias searches are
+// cached under the expression that was searched, so invalidate that expression too.
+func (cm *CacheManager) InvalidateIndex(ctx context.Context, index string) (int64, error) {
+	pattern := fmt.Sprintf("%s*,%s,*", searchKeyPrefix, escapePattern(index))
+	deleted, err := cm.cache.deleteMatching(ctx, pattern)
+	if err != nil {
+		cm.logger.Error("Failed to invalidate cached searches", zap.String("index", index), zap.Error(err))
+		return deleted, fmt.Errorf("failed to invalidate cache for index %s: %w", index, err)
+	}
+	return deleted, nil
+}
+
+// InvalidateAll removes every cached search, for example after a schema change. Cache
+// statistics are kept.
+func (cm *CacheManager) InvalidateAll(ctx context.Context) (int64, error) {
+	deleted, err := cm.cache.deleteMatching(ctx, searchKeyPrefix+"*")
+	if err != nil {
+		cm.logger.Error("Failed to invalidate cached searches", zap.Error(err))
+		return deleted, fmt.Errorf("failed to invalidate cache: %w", err)
+	}
+	return deleted, nil
+}
+
+// SetSearcher sets how Warm runs searches. The search service depends on the cache manager,
+// so it is wired in after both exist.
+func (cm *CacheManager) SetSearcher(searcher Searcher) {
+	cm.searcher = searcher
+}
+
+// WarmConfig returns the configured warm-up searches
+func (cm *CacheManager) WarmConfig() models.CacheWarmConfig {
+	return cm.cache.config.Warm
+}
+
+// Warm runs each search that is not cached yet so its result is cached, avoiding a latency
+// spike on popular searches after a deploy. Searches run one at a time; a failed search is
+// logged and counted, and the rest still run. Warming stops early when ctx is done.
+func (cm *CacheManager) Warm(ctx context.Context, reqs []*models.SearchRequest) (*WarmSummary, error) {
+	if !cm.cache.enabled {
+		return nil, ErrCacheDisabled
+	}
+	if cm.searcher == nil {
+		return nil, errors.New("cache warming has no searcher")
+	}
+
+	startTime := time.Now()
+	summary := &WarmSummary{Requested: len(reqs)}
+	for i, req := range reqs {
+		if ctx.Err() != nil {
+			summary.Failed += len(reqs) - i
+			break
+		}
+
+		if cm.cache.Exists(ctx, cm.cache.generateSearchKey(ctx, req)) {
+			summary.AlreadyCached++
+			continue
+		}
+
+		if _, err := cm.searcher(ctx, req); err != nil {
+			cm.logger.Warn("Failed to warm cached search",
+				zap.String("index", req.Index),
+				zap.String("query", req.Query),
+				zap.Error(err))
+			summary.Failed++
+			continue
+		}
+		summary.Warmed++
+	}
+	summary.Duration = time.Since(startTime)
+
+	cm.logger.Info("Warmed search cache",
+		zap.Int("requested", summary.Requested),
+		zap.Int("warmed", summary.Warmed),
+		zap.Int("already_cached", summary.AlreadyCached),
+		zap.Int("failed", summary.Failed),
+		zap.Duration("duration", summary.Duration))
+
+	return summary, nil
+}
+
 // GetCache returns the underlying cache instance
 func (cm *CacheManager) GetCache() *RedisCache {
 	return cm.cache
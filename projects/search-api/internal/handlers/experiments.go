@@ -3,6 +3,7 @@ package handlers
 import (
 	"net/http"
 	"strconv"
+	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
@@ -46,6 +47,9 @@ func (h *ExperimentHandler) RegisterRoutes(router *gin.RouterGroup) {
 		experiments.PUT("/:id/variants/:variant_id", h.UpdateVariant)
 		experiments.DELETE("/:id/variants/:variant_id", h.DeleteVariant)
 		
+		// Engagement on searches served by a variant
+		experiments.POST("/:id/engagement", h.RecordEngagement)
+		
 		// Results
 		experiments.GET("/:id/results", h.GetResults)
 		experiments.GET("/:id/results/export", h.ExportResults)
@@ -88,6 +92,8 @@ func (h *ExperimentHandler) CreateExperiment(c *gin.Context) {
 	
 	// Create experiment configuration
 	config := abtesting.ExperimentConfig{
+		Type:              req.Type,
+		ControlStrategy:   req.ControlStrategy,
 		TrafficAllocation: req.TrafficAllocation,
 		PrimaryMetric:     req.PrimaryMetric,
 		SecondaryMetrics:  req.SecondaryMetrics,
@@ -103,6 +109,9 @@ func (h *ExperimentHandler) CreateExperiment(c *gin.Context) {
 	}
 	if config.PrimaryMetric == "" {
 		config.PrimaryMetric = "success_rate"
+		if config.Type == abtesting.TypeSearchStrategy {
+			config.PrimaryMetric = "click_through_rate" // ranking recipes are judged on engagement
+		}
 	}
 	if config.MinSampleSize == 0 {
 		config.MinSampleSize = 100
@@ -114,7 +123,7 @@ func (h *ExperimentHandler) CreateExperiment(c *gin.Context) {
 	experiment, err := h.framework.CreateExperiment(req.Name, req.Description, config)
 	if err != nil {
 		h.logger.Error("Failed to create experiment", zap.Error(err))
-		c.JSON(http.StatusInternalServerError, models.ErrorResponse{
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{
 			Error:   "creation_failed",
 			Message: err.Error(),
 		})
@@ -129,6 +138,7 @@ func (h *ExperimentHandler) CreateExperiment(c *gin.Context) {
 			Description:        variantReq.Description,
 			Weight:             variantReq.Weight,
 			QueryModifications: variantReq.QueryModifications,
+			Strategy:           variantReq.Strategy,
 		}
 		
 		if err := h.framework.AddTreatmentVariant(experiment.ID, variant); err != nil {
@@ -162,6 +172,31 @@ func (h *ExperimentHandler) StartExperiment(c *gin.Context) {
 	c.JSON(http.StatusOK, gin.H{"status": "started"})
 }
 
+// RecordEngagement records a click or conversion on a search served by a variant. Clients
+// take the experiment and variant from the X-AB-Test-Experiment and X-AB-Test-Variant headers.
+func (h *ExperimentHandler) RecordEngagement(c *gin.Context) {
+	experimentID := c.Param("id")
+	
+	var req RecordEngagementRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{
+			Error:   "invalid_request",
+			Message: err.Error(),
+		})
+		return
+	}
+	
+	if err := h.framework.RecordEngagement(experimentID, req.VariantID, req.Engagement); err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{
+			Error:   "engagement_rejected",
+			Message: err.Error(),
+		})
+		return
+	}
+	
+	c.JSON(http.StatusOK, gin.H{"status": "recorded"})
+}
+
 // GetResults returns experiment results
 func (h *ExperimentHandler) GetResults(c *gin.Context) {
 	experimentID := c.Param("id")
@@ -465,6 +500,8 @@ func (h *ExperimentHandler) getAvailableTemplates() []ExperimentTemplate {
 type CreateExperimentRequest struct {
 	Name               string                          `json:"name" binding:"required"`
 	Description        string                          `json:"description"`
+	Type               abtesting.ExperimentType        `json:"type"`             // query_modifications (default) or search_strategy
+	ControlStrategy    *models.SearchStrategy          `json:"control_strategy"` // search_strategy only; omit to keep the current ranking
 	TrafficAllocation  float64                         `json:"traffic_allocation"`
 	PrimaryMetric      string                          `json:"primary_metric"`
 	SecondaryMetrics   []string                        `json:"secondary_metrics"`
@@ -481,6 +518,12 @@ type CreateVariantRequest struct {
 	Description        string                          `json:"description"`
 	Weight             float64                         `json:"weight"`
 	QueryModifications abtesting.QueryModifications    `json:"query_modifications"`
+	Strategy           *models.SearchStrategy          `json:"strategy"` // required in search_strategy experiments
+}
+
+type RecordEngagementRequest struct {
+	VariantID string `json:"variant_id" binding:"required"`
+	abtesting.Engagement
 }
 
 type CreateFromTemplateRequest struct {
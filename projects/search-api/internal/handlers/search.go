@@ -2,15 +2,19 @@ package handlers
 
 import (
 	"context"
-	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
 	"net/http"
 	"strconv"
+	"strings"
 	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
 	"go.uber.org/zap"
 
+	"github.com/saif-islam/es-playground/projects/search-api/internal/cache"
 	"github.com/saif-islam/es-playground/projects/search-api/internal/middleware"
 	"github.com/saif-islam/es-playground/projects/search-api/internal/models"
 	"github.com/saif-islam/es-playground/projects/search-api/internal/services"
@@ -32,12 +36,24 @@ func NewSearchHandler(searchService *services.SearchService, logger *zap.Logger)
 
 // RegisterRoutes registers all search-related routes
 func (h *SearchHandler) RegisterRoutes(router *gin.RouterGroup) {
+	// Searches that found nothing, for relevance tuning
+	router.GET("/search/analytics/zero-results", h.GetZeroResultQueries)
+
+	// Async searches for long-running aggregations
+	router.POST("/search/async", h.SubmitAsyncSearch)
+	router.GET("/search/async/:id", h.GetAsyncSearch)
+	router.DELETE("/search/async/:id", h.DeleteAsyncSearch)
+
 	v1 := router.Group("/v1")
 	{
 		// Basic searches
 		v1.GET("/search", h.Search)
 		v1.POST("/search", h.AdvancedSearch)
 		v1.POST("/multi-search", h.MultiSearch)
+		v1.POST("/search/_msearch", h.MultiSearch)
+		v1.POST("/search/_count", h.Count)
+		v1.POST("/search/_validate", h.ValidateSearch)
+		v1.POST("/search/:index/_explain/:id", h.ExplainDocument)
 		
 		// Suggestions and autocomplete
 		v1.GET("/suggest", h.Suggest)
@@ -49,15 +65,34 @@ func (h *SearchHandler) RegisterRoutes(router *gin.RouterGroup) {
 		v1.POST("/query/explain", h.ExplainQuery)
 		v1.POST("/query/validate", h.ValidateQuery)
 		
-		// Templates and analytics
+		// Stored Mustache search templates
 		v1.GET("/templates", h.ListTemplates)
 		v1.POST("/templates", h.CreateTemplate)
 		v1.GET("/templates/:id", h.GetTemplate)
+		v1.PUT("/templates/:id", h.StoreTemplate)
+		v1.DELETE("/templates/:id", h.DeleteTemplate)
+		v1.POST("/templates/:id/render", h.RenderTemplate)
 		v1.POST("/templates/:id/search", h.SearchWithTemplate)
 		
 		// Analytics
 		v1.GET("/analytics/search-stats", h.GetSearchStats)
 		v1.GET("/analytics/performance", h.GetPerformanceMetrics)
+		
+		// Cache invalidation after re-indexing or schema changes
+		v1.POST("/search/cache/invalidate", h.InvalidateCache)
+		v1.POST("/search/cache/warm", h.WarmCache)
+		
+		// Point-in-time readers for consistent pagination
+		v1.POST("/pit", h.OpenPIT)
+		v1.DELETE("/pit", h.ClosePIT)
+		
+		// Scroll fallback for clients that can't use point-in-time readers
+		v1.POST("/scroll", h.OpenScroll)
+		v1.POST("/scroll/next", h.ContinueScroll)
+		v1.DELETE("/scroll", h.ClearScroll)
+		
+		// Alias diagnostics
+		v1.GET("/aliases/:alias/mapping-conflicts", h.CheckAliasMappingConsistency)
 	}
 }
 
@@ -106,12 +141,17 @@ func (h *SearchHandler) Search(c *gin.Context) {
 		req.ABTestVariant = assignment.VariantID
 	}
 
+	req.Scopes = requestScopes(c)
+
 	// Perform search
 	ctx, cancel := context.WithTimeout(c.Request.Context(), 30*time.Second)
 	defer cancel()
 
 	response, err := h.searchService.Search(ctx, req)
 	if err != nil {
+		if respondQueryTooExpensive(c, err, req.RequestID) || respondInvalidQuery(c, err, req.RequestID) {
+			return
+		}
 		h.logger.Error("Search failed", zap.Error(err), zap.String("request_id", req.RequestID))
 		c.JSON(http.StatusInternalServerError, models.ErrorResponse{
 			Error:     "search_failed",
@@ -122,6 +162,7 @@ func (h *SearchHandler) Search(c *gin.Context) {
 		return
 	}
 
+	c.Set(middleware.ABTestResultCountKey, response.Total.Value)
 	c.JSON(http.StatusOK, response)
 }
 
@@ -143,8 +184,8 @@ func (h *SearchHandler) AdvancedSearch(c *gin.Context) {
 		return
 	}
 
-	// Validate required fields
-	if req.Index == "" {
+	// Validate required fields (a point in time already identifies the indices)
+	if req.Index == "" && req.PIT == nil {
 		c.JSON(http.StatusBadRequest, models.ErrorResponse{
 			Error:     "missing_index",
 			Message:   "Index field is required",
@@ -154,6 +195,26 @@ func (h *SearchHandler) AdvancedSearch(c *gin.Context) {
 		return
 	}
 
+	if req.KNN != nil && req.KNN.K > 0 && req.KNN.NumCandidates > 0 && req.KNN.NumCandidates < req.KNN.K {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{
+			Error:     "invalid_knn",
+			Message:   "knn num_candidates must be at least k",
+			RequestID: req.RequestID,
+			Timestamp: time.Now(),
+		})
+		return
+	}
+
+	if req.QueryType == "function_score" && req.FunctionScore == nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{
+			Error:     "invalid_function_score",
+			Message:   "query_type function_score requires a function_score block",
+			RequestID: req.RequestID,
+			Timestamp: time.Now(),
+		})
+		return
+	}
+
 	// Set defaults
 	if req.Size == 0 {
 		req.Size = 10
@@ -176,12 +237,17 @@ func (h *SearchHandler) AdvancedSearch(c *gin.Context) {
 		req.ABTestVariant = assignment.VariantID
 	}
 
+	req.Scopes = requestScopes(c)
+
 	// Perform search
 	ctx, cancel := context.WithTimeout(c.Request.Context(), timeout)
 	defer cancel()
 
 	response, err := h.searchService.Search(ctx, req)
 	if err != nil {
+		if respondQueryTooExpensive(c, err, req.RequestID) || respondInvalidQuery(c, err, req.RequestID) {
+			return
+		}
 		h.logger.Error("Advanced search failed", zap.Error(err), zap.String("request_id", req.RequestID))
 		c.JSON(http.StatusInternalServerError, models.ErrorResponse{
 			Error:     "search_failed",
@@ -192,6 +258,158 @@ func (h *SearchHandler) AdvancedSearch(c *gin.Context) {
 		return
 	}
 
+	c.Set(middleware.ABTestResultCountKey, response.Total.Value)
+	c.JSON(http.StatusOK, response)
+}
+
+// Count returns how many documents match a search without fetching them (POST /search/_count)
+func (h *SearchHandler) Count(c *gin.Context) {
+	req := &models.SearchRequest{
+		RequestID: uuid.New().String(),
+	}
+
+	if err := c.ShouldBindJSON(req); err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{
+			Error:     "invalid_json",
+			Message:   err.Error(),
+			RequestID: req.RequestID,
+			Timestamp: time.Now(),
+		})
+		return
+	}
+
+	if req.Index == "" {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{
+			Error:     "missing_index",
+			Message:   "Index field is required",
+			RequestID: req.RequestID,
+			Timestamp: time.Now(),
+		})
+		return
+	}
+
+	req.Scopes = requestScopes(c)
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 30*time.Second)
+	defer cancel()
+
+	response, err := h.searchService.Count(ctx, req)
+	if err != nil {
+		if respondQueryTooExpensive(c, err, req.RequestID) || respondInvalidQuery(c, err, req.RequestID) {
+			return
+		}
+		if errors.Is(err, services.ErrKNNCount) {
+			c.JSON(http.StatusBadRequest, models.ErrorResponse{
+				Error:     "invalid_knn",
+				Message:   err.Error(),
+				RequestID: req.RequestID,
+				Timestamp: time.Now(),
+			})
+			return
+		}
+		h.logger.Error("Count failed", zap.Error(err), zap.String("request_id", req.RequestID))
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{
+			Error:     "count_failed",
+			Message:   err.Error(),
+			RequestID: req.RequestID,
+			Timestamp: time.Now(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, response)
+}
+
+// ValidateSearch checks whether a search's query is valid without running it
+// (POST /search/_validate)
+func (h *SearchHandler) ValidateSearch(c *gin.Context) {
+	req := &models.SearchRequest{
+		RequestID: uuid.New().String(),
+	}
+
+	if err := c.ShouldBindJSON(req); err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{
+			Error:     "invalid_json",
+			Message:   err.Error(),
+			RequestID: req.RequestID,
+			Timestamp: time.Now(),
+		})
+		return
+	}
+
+	if req.Index == "" {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{
+			Error:     "missing_index",
+			Message:   "Index field is required",
+			RequestID: req.RequestID,
+			Timestamp: time.Now(),
+		})
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 10*time.Second)
+	defer cancel()
+
+	response, err := h.searchService.Validate(ctx, req)
+	if err != nil {
+		if respondInvalidQuery(c, err, req.RequestID) {
+			return
+		}
+		h.logger.Error("Query validation failed", zap.Error(err), zap.String("request_id", req.RequestID))
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{
+			Error:     "validation_failed",
+			Message:   err.Error(),
+			RequestID: req.RequestID,
+			Timestamp: time.Now(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, response)
+}
+
+// ExplainDocument explains whether and how a document matches a search's query
+// (POST /search/:index/_explain/:id)
+func (h *SearchHandler) ExplainDocument(c *gin.Context) {
+	index := c.Param("index")
+	docID := c.Param("id")
+	req := &models.SearchRequest{
+		RequestID: uuid.New().String(),
+	}
+
+	if err := c.ShouldBindJSON(req); err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{
+			Error:     "invalid_json",
+			Message:   err.Error(),
+			RequestID: req.RequestID,
+			Timestamp: time.Now(),
+		})
+		return
+	}
+	req.Index = index
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 10*time.Second)
+	defer cancel()
+
+	response, err := h.searchService.Explain(ctx, index, docID, req)
+	if err != nil {
+		if respondInvalidQuery(c, err, req.RequestID) {
+			return
+		}
+		status, code := http.StatusInternalServerError, "explain_failed"
+		if errors.Is(err, services.ErrDocumentNotFound) {
+			status, code = http.StatusNotFound, "document_not_found"
+		}
+		h.logger.Error("Explain failed", zap.Error(err), zap.String("request_id", req.RequestID))
+		c.JSON(status, models.ErrorResponse{
+			Error:     code,
+			Message:   err.Error(),
+			RequestID: req.RequestID,
+			Timestamp: time.Now(),
+		})
+		return
+	}
+
 	c.JSON(http.StatusOK, response)
 }
 
@@ -230,55 +448,40 @@ func (h *SearchHandler) MultiSearch(c *gin.Context) {
 		return
 	}
 
-	// Process searches concurrently
-	responses := make([]*models.SearchResponse, len(requests))
-	errors := make([]error, len(requests))
-
-	// Create channels for concurrent processing
-	type result struct {
-		index    int
-		response *models.SearchResponse
-		err      error
+	// Run every search in one _msearch round trip; cached results never reach the cluster
+	scopes := requestScopes(c)
+	searchReqs := make([]*models.SearchRequest, len(requests))
+	for i := range requests {
+		if requests[i].RequestID == "" {
+			requests[i].RequestID = uuid.New().String()
+		}
+		if requests[i].Size == 0 {
+			requests[i].Size = 10
+		}
+		requests[i].Scopes = scopes
+		searchReqs[i] = &requests[i]
 	}
 
-	resultCh := make(chan result, len(requests))
-
-	// Start searches concurrently
-	for i, req := range requests {
-		go func(idx int, searchReq models.SearchRequest) {
-			if searchReq.RequestID == "" {
-				searchReq.RequestID = uuid.New().String()
-			}
-
-			ctx, cancel := context.WithTimeout(c.Request.Context(), 30*time.Second)
-			defer cancel()
-
-			resp, err := h.searchService.Search(ctx, &searchReq)
-			resultCh <- result{index: idx, response: resp, err: err}
-		}(i, req)
-	}
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 30*time.Second)
+	defer cancel()
 
-	// Collect results
-	for i := 0; i < len(requests); i++ {
-		res := <-resultCh
-		responses[res.index] = res.response
-		errors[res.index] = res.err
-	}
+	responses, errs := h.searchService.MultiSearch(ctx, searchReqs)
 
-	// Check for errors
-	haseErrors := false
-	for _, err := range errors {
+	// Errors are reported by position, with an empty message for searches that succeeded
+	messages := make([]string, len(errs))
+	failed := 0
+	for i, err := range errs {
 		if err != nil {
-			haseErrors = true
-			break
+			messages[i] = err.Error()
+			failed++
 		}
 	}
 
-	if haseErrors {
-		h.logger.Error("Multi-search had errors", zap.Errors("errors", errors))
+	if failed > 0 {
+		h.logger.Error("Multi-search had errors", zap.Int("failed", failed), zap.Strings("errors", messages))
 		c.JSON(http.StatusMultiStatus, gin.H{
 			"responses": responses,
-			"errors":    errors,
+			"errors":    messages,
 			"timestamp": time.Now(),
 		})
 		return
@@ -525,39 +728,202 @@ func (h *SearchHandler) ValidateQuery(c *gin.Context) {
 	})
 }
 
-// Template management handlers (placeholders)
+// ListTemplates lists the stored search templates (GET /templates)
 func (h *SearchHandler) ListTemplates(c *gin.Context) {
+	requestID := uuid.New().String()
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 10*time.Second)
+	defer cancel()
+
+	templates, err := h.searchService.ListTemplates(ctx)
+	if err != nil {
+		h.respondTemplateError(c, err, requestID, "list_templates_failed")
+		return
+	}
+
 	c.JSON(http.StatusOK, gin.H{
-		"templates": []models.SearchTemplate{},
-		"timestamp": time.Now(),
+		"templates":  templates,
+		"count":      len(templates),
+		"request_id": requestID,
+		"timestamp":  time.Now(),
 	})
 }
 
+// CreateTemplate stores a search template under the id in its body (POST /templates)
 func (h *SearchHandler) CreateTemplate(c *gin.Context) {
+	h.storeTemplate(c, "")
+}
+
+// StoreTemplate stores a search template under the id in the path, replacing any stored
+// there (PUT /templates/:id)
+func (h *SearchHandler) StoreTemplate(c *gin.Context) {
+	h.storeTemplate(c, c.Param("id"))
+}
+
+func (h *SearchHandler) storeTemplate(c *gin.Context, id string) {
+	requestID := uuid.New().String()
+
+	var req models.StoreTemplateRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{
+			Error:     "invalid_json",
+			Message:   err.Error(),
+			RequestID: requestID,
+			Timestamp: time.Now(),
+		})
+		return
+	}
+	if id != "" {
+		req.ID = id
+	}
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 10*time.Second)
+	defer cancel()
+
+	if err := h.searchService.StoreTemplate(ctx, req.ID, req.Source); err != nil {
+		h.respondTemplateError(c, err, requestID, "store_template_failed")
+		return
+	}
+
 	c.JSON(http.StatusCreated, gin.H{
-		"message":   "Template creation coming soon",
-		"timestamp": time.Now(),
+		"template_id": req.ID,
+		"stored":      true,
+		"request_id":  requestID,
+		"timestamp":   time.Now(),
 	})
 }
 
+// GetTemplate returns a stored search template (GET /templates/:id)
 func (h *SearchHandler) GetTemplate(c *gin.Context) {
+	requestID := uuid.New().String()
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 10*time.Second)
+	defer cancel()
+
+	template, err := h.searchService.GetTemplate(ctx, c.Param("id"))
+	if err != nil {
+		h.respondTemplateError(c, err, requestID, "get_template_failed")
+		return
+	}
+
+	c.JSON(http.StatusOK, template)
+}
+
+// DeleteTemplate removes a stored search template (DELETE /templates/:id)
+func (h *SearchHandler) DeleteTemplate(c *gin.Context) {
+	requestID := uuid.New().String()
 	id := c.Param("id")
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 10*time.Second)
+	defer cancel()
+
+	if err := h.searchService.DeleteTemplate(ctx, id); err != nil {
+		h.respondTemplateError(c, err, requestID, "delete_template_failed")
+		return
+	}
+
 	c.JSON(http.StatusOK, gin.H{
 		"template_id": id,
-		"message":     "Template retrieval coming soon",
+		"deleted":     true,
+		"request_id":  requestID,
 		"timestamp":   time.Now(),
 	})
 }
 
-func (h *SearchHandler) SearchWithTemplate(c *gin.Context) {
+// RenderTemplate renders a stored search template with params without running it
+// (POST /templates/:id/render)
+func (h *SearchHandler) RenderTemplate(c *gin.Context) {
+	requestID := uuid.New().String()
 	id := c.Param("id")
+
+	var req models.TemplateSearchRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{
+			Error:     "invalid_json",
+			Message:   err.Error(),
+			RequestID: requestID,
+			Timestamp: time.Now(),
+		})
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 10*time.Second)
+	defer cancel()
+
+	rendered, err := h.searchService.RenderTemplate(ctx, id, req.Params)
+	if err != nil {
+		h.respondTemplateError(c, err, requestID, "render_template_failed")
+		return
+	}
+
 	c.JSON(http.StatusOK, gin.H{
 		"template_id": id,
-		"message":     "Template search coming soon",
+		"rendered":    rendered,
+		"request_id":  requestID,
 		"timestamp":   time.Now(),
 	})
 }
 
+// SearchWithTemplate runs a stored search template with params (POST /templates/:id/search)
+func (h *SearchHandler) SearchWithTemplate(c *gin.Context) {
+	req := &models.TemplateSearchRequest{}
+	if err := c.ShouldBindJSON(req); err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{
+			Error:     "invalid_json",
+			Message:   err.Error(),
+			RequestID: uuid.New().String(),
+			Timestamp: time.Now(),
+		})
+		return
+	}
+	if req.RequestID == "" {
+		req.RequestID = uuid.New().String()
+	}
+
+	if req.Index == "" {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{
+			Error:     "missing_index",
+			Message:   "Index field is required",
+			RequestID: req.RequestID,
+			Timestamp: time.Now(),
+		})
+		return
+	}
+
+	req.Scopes = requestScopes(c)
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 30*time.Second)
+	defer cancel()
+
+	response, err := h.searchService.SearchTemplate(ctx, c.Param("id"), req)
+	if err != nil {
+		h.respondTemplateError(c, err, req.RequestID, "template_search_failed")
+		return
+	}
+
+	c.JSON(http.StatusOK, response)
+}
+
+// respondTemplateError answers a failed template operation, with 404 for a missing
+// template and 400 for one Elasticsearch rejected
+func (h *SearchHandler) respondTemplateError(c *gin.Context, err error, requestID, code string) {
+	status := http.StatusInternalServerError
+	switch {
+	case errors.Is(err, services.ErrTemplateNotFound):
+		status, code = http.StatusNotFound, "template_not_found"
+	case errors.Is(err, services.ErrInvalidTemplate):
+		status, code = http.StatusBadRequest, "invalid_template"
+	}
+
+	h.logger.Error("Search template operation failed", zap.Error(err), zap.String("request_id", requestID))
+	c.JSON(status, models.ErrorResponse{
+		Error:     code,
+		Message:   err.Error(),
+		RequestID: requestID,
+		Timestamp: time.Now(),
+	})
+}
+
 // Analytics handlers (placeholders)
 func (h *SearchHandler) GetSearchStats(c *gin.Context) {
 	// Parse optional query parameters for filtering
@@ -581,13 +947,38 @@ func (h *SearchHandler) GetSearchStats(c *gin.Context) {
 	c.JSON(http.StatusOK, stats)
 }
 
-func (h *SearchHandler) GetPerformanceMetrics(c *gin.Context) {
-	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "24"))
+// GetZeroResultQueries lists the most frequent searches that matched nothing
+// (GET /search/analytics/zero-results), with suggested corrections where possible. The
+// optional field parameter picks the field corrections are drawn from.
+func (h *SearchHandler) GetZeroResultQueries(c *gin.Context) {
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "20"))
+	if limit <= 0 {
+		limit = 20
+	}
 	if limit > 100 {
 		limit = 100
 	}
 
-	// Placeholder metrics
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 10*time.Second)
+	defer cancel()
+
+	queries := h.searchService.ZeroResultQueries(ctx, limit, c.Query("field"))
+
+	c.JSON(http.StatusOK, gin.H{
+		"queries":    queries,
+		"count":      len(queries),
+		"request_id": uuid.New().String(),
+		"timestamp":  time.Now(),
+	})
+}
+
+func (h *SearchHandler) GetPerformanceMetrics(c *gin.Context) {
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "24"))
+	if limit > 100 {
+		limit = 100
+	}
+
+	// Placeholder metrics
 	metrics := gin.H{
 		"response_times": []float64{45.2, 38.1, 52.3, 41.7, 39.8},
 		"throughput":     []int{150, 180, 165, 172, 190},
@@ -599,3 +990,492 @@ func (h *SearchHandler) GetPerformanceMetrics(c *gin.Context) {
 
 	c.JSON(http.StatusOK, metrics)
 }
+
+// CheckAliasMappingConsistency reports fields with conflicting types across an alias's indices
+func (h *SearchHandler) CheckAliasMappingConsistency(c *gin.Context) {
+	alias := c.Param("alias")
+	requestID := uuid.New().String()
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 30*time.Second)
+	defer cancel()
+
+	report, err := h.searchService.CheckAliasMappingConsistency(ctx, alias)
+	if err != nil {
+		status, code := http.StatusInternalServerError, "mapping_check_failed"
+		if errors.Is(err, services.ErrAliasNotFound) {
+			status, code = http.StatusNotFound, "alias_not_found"
+		}
+		h.logger.Error("Alias mapping check failed", zap.Error(err), zap.String("alias", alias))
+		c.JSON(status, models.ErrorResponse{
+			Error:     code,
+			Message:   err.Error(),
+			RequestID: requestID,
+			Timestamp: time.Now(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, report)
+}
+
+// InvalidateCache purges cached search results (POST /search/cache/invalidate). Pass
+// ?index=<name> to purge the searches over one index, or ?all=true to purge every search.
+func (h *SearchHandler) InvalidateCache(c *gin.Context) {
+	requestID := uuid.New().String()
+	index := c.Query("index")
+	all := c.Query("all") == "true"
+
+	if index == "" && !all {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{
+			Error:     "missing_index",
+			Message:   "index parameter is required; use all=true to purge every cached search",
+			RequestID: requestID,
+			Timestamp: time.Now(),
+		})
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 30*time.Second)
+	defer cancel()
+
+	deleted, err := h.searchService.InvalidateCache(ctx, index)
+	if err != nil {
+		h.logger.Error("Cache invalidation failed", zap.Error(err), zap.String("index", index))
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{
+			Error:     "cache_invalidation_failed",
+			Message:   err.Error(),
+			RequestID: requestID,
+			Timestamp: time.Now(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"index":        index,
+		"deleted_keys": deleted,
+		"request_id":   requestID,
+		"timestamp":    time.Now(),
+	})
+}
+
+// WarmCache runs searches to fill the cache (POST /search/cache/warm). The body may list the
+// searches as {"queries": [...]}; without it the configured and most popular searches run.
+func (h *SearchHandler) WarmCache(c *gin.Context) {
+	requestID := uuid.New().String()
+
+	var body struct {
+		Queries []*models.SearchRequest `json:"queries"`
+	}
+	if err := c.ShouldBindJSON(&body); err != nil && !errors.Is(err, io.EOF) {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{
+			Error:     "invalid_json",
+			Message:   err.Error(),
+			RequestID: requestID,
+			Timestamp: time.Now(),
+		})
+		return
+	}
+
+	for i, req := range body.Queries {
+		if req.Index == "" {
+			c.JSON(http.StatusBadRequest, models.ErrorResponse{
+				Error:     "missing_index",
+				Message:   fmt.Sprintf("Index is required for query %d", i),
+				RequestID: requestID,
+				Timestamp: time.Now(),
+			})
+			return
+		}
+		if req.Size == 0 {
+			req.Size = 10
+		}
+	}
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 2*time.Minute)
+	defer cancel()
+
+	summary, err := h.searchService.WarmCache(ctx, body.Queries)
+	if err != nil {
+		status, code := http.StatusInternalServerError, "cache_warm_failed"
+		if errors.Is(err, cache.ErrCacheDisabled) {
+			status, code = http.StatusServiceUnavailable, "cache_disabled"
+		}
+		h.logger.Error("Cache warm failed", zap.Error(err))
+		c.JSON(status, models.ErrorResponse{
+			Error:     code,
+			Message:   err.Error(),
+			RequestID: requestID,
+			Timestamp: time.Now(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"summary":    summary,
+		"request_id": requestID,
+		"timestamp":  time.Now(),
+	})
+}
+
+// OpenPIT opens a point-in-time reader for consistent searches across requests
+func (h *SearchHandler) OpenPIT(c *gin.Context) {
+	requestID := uuid.New().String()
+
+	var req models.OpenPITRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{
+			Error:     "invalid_json",
+			Message:   err.Error(),
+			RequestID: requestID,
+			Timestamp: time.Now(),
+		})
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 10*time.Second)
+	defer cancel()
+
+	pit, err := h.searchService.OpenPIT(ctx, req.Index, req.KeepAlive)
+	if err != nil {
+		h.logger.Error("Failed to open point in time", zap.Error(err), zap.String("index", req.Index))
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{
+			Error:     "pit_open_failed",
+			Message:   err.Error(),
+			RequestID: requestID,
+			Timestamp: time.Now(),
+		})
+		return
+	}
+
+	pit.RequestID = requestID
+	c.JSON(http.StatusOK, pit)
+}
+
+// ClosePIT releases a point-in-time reader
+func (h *SearchHandler) ClosePIT(c *gin.Context) {
+	requestID := uuid.New().String()
+
+	var req models.ClosePITRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{
+			Error:     "invalid_json",
+			Message:   err.Error(),
+			RequestID: requestID,
+			Timestamp: time.Now(),
+		})
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 10*time.Second)
+	defer cancel()
+
+	if err := h.searchService.ClosePIT(ctx, req.ID); err != nil {
+		h.logger.Error("Failed to close point in time", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{
+			Error:     "pit_close_failed",
+			Message:   err.Error(),
+			RequestID: requestID,
+			Timestamp: time.Now(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"closed":     true,
+		"request_id": requestID,
+		"timestamp":  time.Now(),
+	})
+}
+
+// OpenScroll starts a scroll and returns its first page along with the scroll ID.
+// Prefer a point in time where the client supports it; an open scroll holds resources
+// on every shard until it is cleared or its keep_alive lapses.
+func (h *SearchHandler) OpenScroll(c *gin.Context) {
+	req := &models.OpenScrollRequest{
+		SearchRequest: models.SearchRequest{
+			RequestID: uuid.New().String(),
+		},
+	}
+
+	if err := c.ShouldBindJSON(req); err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{
+			Error:     "invalid_json",
+			Message:   err.Error(),
+			RequestID: req.RequestID,
+			Timestamp: time.Now(),
+		})
+		return
+	}
+
+	if req.Index == "" {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{
+			Error:     "missing_index",
+			Message:   "Index field is required",
+			RequestID: req.RequestID,
+			Timestamp: time.Now(),
+		})
+		return
+	}
+
+	req.Scopes = requestScopes(c)
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 30*time.Second)
+	defer cancel()
+
+	response, err := h.searchService.OpenScroll(ctx, &req.SearchRequest, req.KeepAlive)
+	if err != nil {
+		if respondQueryTooExpensive(c, err, req.RequestID) || respondInvalidQuery(c, err, req.RequestID) {
+			return
+		}
+		h.logger.Error("Failed to open scroll", zap.Error(err), zap.String("index", req.Index))
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{
+			Error:     "scroll_open_failed",
+			Message:   err.Error(),
+			RequestID: req.RequestID,
+			Timestamp: time.Now(),
+		})
+		return
+	}
+
+	response.RequestID = req.RequestID
+	c.JSON(http.StatusOK, response)
+}
+
+// ContinueScroll returns the next page of a scroll
+func (h *SearchHandler) ContinueScroll(c *gin.Context) {
+	requestID := uuid.New().String()
+
+	var req models.ScrollRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{
+			Error:     "invalid_json",
+			Message:   err.Error(),
+			RequestID: requestID,
+			Timestamp: time.Now(),
+		})
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 30*time.Second)
+	defer cancel()
+
+	response, err := h.searchService.Scroll(ctx, req.ScrollID, req.KeepAlive, requestScopes(c))
+	if err != nil {
+		if respondInvalidQuery(c, err, requestID) {
+			return
+		}
+		if errors.Is(err, services.ErrScrollNotFound) {
+			c.JSON(http.StatusNotFound, models.ErrorResponse{
+				Error:     "scroll_not_found",
+				Message:   err.Error(),
+				RequestID: requestID,
+				Timestamp: time.Now(),
+			})
+			return
+		}
+		h.logger.Error("Failed to continue scroll", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{
+			Error:     "scroll_failed",
+			Message:   err.Error(),
+			RequestID: requestID,
+			Timestamp: time.Now(),
+		})
+		return
+	}
+
+	response.RequestID = requestID
+	c.JSON(http.StatusOK, response)
+}
+
+// ClearScroll releases scrolls the client no longer needs
+func (h *SearchHandler) ClearScroll(c *gin.Context) {
+	requestID := uuid.New().String()
+
+	var req models.ClearScrollRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{
+			Error:     "invalid_json",
+			Message:   err.Error(),
+			RequestID: requestID,
+			Timestamp: time.Now(),
+		})
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 10*time.Second)
+	defer cancel()
+
+	freed, err := h.searchService.ClearScroll(ctx, req.ScrollIDs)
+	if err != nil {
+		h.logger.Error("Failed to clear scroll", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{
+			Error:     "scroll_clear_failed",
+			Message:   err.Error(),
+			RequestID: requestID,
+			Timestamp: time.Now(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"num_freed":  freed,
+		"request_id": requestID,
+		"timestamp":  time.Now(),
+	})
+}
+
+// SubmitAsyncSearch submits a long-running search and returns its ID (POST /search/async)
+func (h *SearchHandler) SubmitAsyncSearch(c *gin.Context) {
+	req := &models.AsyncSearchRequest{
+		SearchRequest: models.SearchRequest{
+			RequestID: uuid.New().String(),
+		},
+	}
+
+	if err := c.ShouldBindJSON(req); err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{
+			Error:     "invalid_json",
+			Message:   err.Error(),
+			RequestID: req.RequestID,
+			Timestamp: time.Now(),
+		})
+		return
+	}
+
+	if req.Index == "" {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{
+			Error:     "missing_index",
+			Message:   "Index field is required",
+			RequestID: req.RequestID,
+			Timestamp: time.Now(),
+		})
+		return
+	}
+
+	req.Scopes = requestScopes(c)
+
+	// The submit only blocks for wait_for_completion_timeout; allow headroom on top
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 60*time.Second)
+	defer cancel()
+
+	response, err := h.searchService.SubmitAsync(ctx, req)
+	if err != nil {
+		if respondQueryTooExpensive(c, err, req.RequestID) || respondInvalidQuery(c, err, req.RequestID) {
+			return
+		}
+		h.logger.Error("Async search submit failed", zap.Error(err), zap.String("request_id", req.RequestID))
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{
+			Error:     "async_search_failed",
+			Message:   err.Error(),
+			RequestID: req.RequestID,
+			Timestamp: time.Now(),
+		})
+		return
+	}
+
+	response.RequestID = req.RequestID
+	status := http.StatusOK
+	if response.IsRunning {
+		status = http.StatusAccepted
+	}
+	c.JSON(status, response)
+}
+
+// GetAsyncSearch returns the progress and partial or final results of an async search
+func (h *SearchHandler) GetAsyncSearch(c *gin.Context) {
+	requestID := uuid.New().String()
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 30*time.Second)
+	defer cancel()
+
+	response, err := h.searchService.GetAsyncResult(ctx, c.Param("id"), requestScopes(c))
+	if err != nil {
+		h.logger.Error("Async search get failed", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{
+			Error:     "async_search_failed",
+			Message:   err.Error(),
+			RequestID: requestID,
+			Timestamp: time.Now(),
+		})
+		return
+	}
+
+	response.RequestID = requestID
+	c.JSON(http.StatusOK, response)
+}
+
+// DeleteAsyncSearch cancels an async search or discards its results
+func (h *SearchHandler) DeleteAsyncSearch(c *gin.Context) {
+	requestID := uuid.New().String()
+	id := c.Param("id")
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 10*time.Second)
+	defer cancel()
+
+	if err := h.searchService.DeleteAsync(ctx, id); err != nil {
+		h.logger.Error("Async search delete failed", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{
+			Error:     "async_search_delete_failed",
+			Message:   err.Error(),
+			RequestID: requestID,
+			Timestamp: time.Now(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"id":         id,
+		"deleted":    true,
+		"request_id": requestID,
+		"timestamp":  time.Now(),
+	})
+}
+
+// respondQueryTooExpensive answers with 400 if the cost guard rejected the search
+func respondQueryTooExpensive(c *gin.Context, err error, requestID string) bool {
+	var costErr *services.QueryCostError
+	if !errors.As(err, &costErr) {
+		return false
+	}
+
+	c.JSON(http.StatusBadRequest, models.ErrorResponse{
+		Error:     "query_too_expensive",
+		Message:   costErr.Error(),
+		RequestID: requestID,
+		Timestamp: time.Now(),
+	})
+	return true
+}
+
+// respondInvalidQuery answers with 400 if the request could not be turned into a query
+func respondInvalidQuery(c *gin.Context, err error, requestID string) bool {
+	if !errors.Is(err, services.ErrInvalidQuery) {
+		return false
+	}
+
+	c.JSON(http.StatusBadRequest, models.ErrorResponse{
+		Error:     "invalid_query",
+		Message:   err.Error(),
+		RequestID: requestID,
+		Timestamp: time.Now(),
+	})
+	return true
+}
+
+// requestScopes returns the scopes granted to the caller. The X-Search-Scopes header is
+// set by the authenticating gateway in front of the API; TrustedScopesMiddleware drops it
+// from requests that don't come from a trusted gateway.
+func requestScopes(c *gin.Context) []string {
+	header := c.GetHeader(middleware.ScopesHeader)
+	if header == "" {
+		return nil
+	}
+
+	var scopes []string
+	for _, scope := range strings.Split(header, ",") {
+		if scope = strings.TrimSpace(scope); scope != "" {
+			scopes = append(scopes, scope)
+		}
+	}
+	return scopes
+}
@@ -0,0 +1,125 @@
+package handlers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+
+	"github.com/saif-islam/es-playground/projects/search-api/internal/services"
+	"github.com/saif-islam/es-playground/shared"
+)
+
+// newTestRouter serves the search routes under /api, backed by a fake Elasticsearch that
+// answers the client's startup requests and passes the rest to handler
+func newTestRouter(t *testing.T, handler http.HandlerFunc) *gin.Engine {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("X-Elastic-Product", "Elasticsearch")
+		w.Header().Set("Content-Type", "application/json")
+		if r.URL.Path == "/" {
+			w.Write([]byte(`{"version":{"number":"8.11.1"}}`))
+			return
+		}
+		handler(w, r)
+	}))
+	t.Cleanup(server.Close)
+
+	client, err := shared.NewESClient(&shared.ESConfig{URLs: []string{server.URL}}, zap.NewNop())
+	if err != nil {
+		t.Fatalf("Failed to create client: %v", err)
+	}
+
+	router := gin.New()
+	NewSearchHandler(services.NewSearchService(client, zap.NewNop(), nil, nil, nil, nil, nil, nil), zap.NewNop()).RegisterRoutes(router.Group("/api"))
+	return router
+}
+
+func TestAsyncSearchRoutes(t *testing.T) {
+	var requests []string
+	router := newTestRouter(t, func(w http.ResponseWriter, r *http.Request) {
+		requests = append(requests, r.Method+" "+r.URL.Path)
+		if r.Method == http.MethodDelete {
+			w.Write([]byte(`{"acknowledged":true}`))
+			return
+		}
+		w.Write([]byte(`{"id":"abc","is_partial":true,"is_running":true,"response":{"_shards":{"total":2,"successful":1},"hits":{"total":{"value":1,"relation":"gte"},"hits":[]}}}`))
+	})
+
+	tests := []struct {
+		name           string
+		method         string
+		path           string
+		body           string
+		expectedStatus int
+		expectedES     string
+	}{
+		{
+			name: "submit", method: http.MethodPost, path: "/api/search/async",
+			body: `{"index":"logs-*","query":"error"}`, expectedStatus: http.StatusAccepted, expectedES: "POST /logs-*/_async_search",
+		},
+		{
+			name: "invalid wait", method: http.MethodPost, path: "/api/search/async",
+			body: `{"index":"logs-*","wait_for_completion_timeout":"soon"}`, expectedStatus: http.StatusBadRequest,
+		},
+		{name: "poll", method: http.MethodGet, path: "/api/search/async/abc", expectedStatus: http.StatusOK, expectedES: "GET /_async_search/abc"},
+		{name: "delete", method: http.MethodDelete, path: "/api/search/async/abc", expectedStatus: http.StatusOK, expectedES: "DELETE /_async_search/abc"},
+		{name: "old v1 path", method: http.MethodGet, path: "/api/v1/search/async/abc", expectedStatus: http.StatusNotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			requests = nil
+			req := httptest.NewRequest(tt.method, tt.path, strings.NewReader(tt.body))
+			req.Header.Set("Content-Type", "application/json")
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+
+			if w.Code != tt.expectedStatus {
+				t.Errorf("Expected status %d, got %d: %s", tt.expectedStatus, w.Code, w.Body.String())
+			}
+			if tt.expectedES == "" && len(requests) > 0 {
+				t.Errorf("Expected no request to Elasticsearch, got %v", requests)
+			}
+			if tt.expectedES != "" && (len(requests) != 1 || requests[0] != tt.expectedES) {
+				t.Errorf("Expected %s, got %v", tt.expectedES, requests)
+			}
+		})
+	}
+}
+
+func TestCheckAliasMappingConsistency(t *testing.T) {
+	router := newTestRouter(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/logs/_field_caps" {
+			w.Write([]byte(`{"indices":["logs-000001"],"fields":{"message":{"text":{"type":"text"}}}}`))
+			return
+		}
+		w.WriteHeader(http.StatusNotFound)
+		w.Write([]byte(`{"error":{"type":"index_not_found_exception","reason":"no such index [missing]"},"status":404}`))
+	})
+
+	tests := []struct {
+		alias          string
+		expectedStatus int
+		expectedBody   string
+	}{
+		{alias: "logs", expectedStatus: http.StatusOK, expectedBody: `"consistent":true`},
+		{alias: "missing", expectedStatus: http.StatusNotFound, expectedBody: `"error":"alias_not_found"`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.alias, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/v1/aliases/"+tt.alias+"/mapping-conflicts", nil))
+
+			if w.Code != tt.expectedStatus || !strings.Contains(w.Body.String(), tt.expectedBody) {
+				t.Errorf("Expected %d with %s, got %d: %s", tt.expectedStatus, tt.expectedBody, w.Code, w.Body.String())
+			}
+		})
+	}
+}
@@ -131,6 +131,14 @@ var (
 		[]string{"index", "query_type"},
 	)
 
+	ExpensiveQueriesTotal = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "expensive_queries_total",
+			Help: "Total number of searches flagged by the query cost guard",
+		},
+		[]string{"rule", "action"},
+	)
+
 	QueryOptimizationSuggestions = promauto.NewCounterVec(
 		prometheus.CounterOpts{
 			Name: "query_optimization_suggestions_total",
@@ -139,6 +147,38 @@ var (
 		[]string{"suggestion_type"},
 	)
 
+	// Real-time analytics stream metrics
+	AnalyticsStreamClients = promauto.NewGauge(
+		prometheus.GaugeOpts{
+			Name: "analytics_stream_clients",
+			Help: "Number of clients connected to the analytics WebSocket stream",
+		},
+	)
+
+	AnalyticsStreamDropped = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "analytics_stream_dropped_messages_total",
+			Help: "Total number of analytics stream messages dropped before reaching a client",
+		},
+		[]string{"reason"},
+	)
+
+	AnalyticsStreamDisconnects = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "analytics_stream_disconnects_total",
+			Help: "Total number of analytics stream clients disconnected by the server",
+		},
+		[]string{"reason"},
+	)
+
+	// Search analytics persistence metrics
+	AnalyticsRecordsDropped = promauto.NewCounter(
+		prometheus.CounterOpts{
+			Name: "search_analytics_dropped_records_total",
+			Help: "Total number of search analytics records dropped because the buffer was full",
+		},
+	)
+
 	// Application health metrics
 	ApplicationInfo = promauto.NewGaugeVec(
 		prometheus.GaugeOpts{
@@ -219,11 +259,36 @@ func UpdateConnectionMetrics(active, max int) {
 	ElasticsearchConnectionsMax.Set(float64(max))
 }
 
+// RecordExpensiveQuery records a search flagged by the cost guard and what was done with it
+func RecordExpensiveQuery(rule, action string) {
+	ExpensiveQueriesTotal.WithLabelValues(rule, action).Inc()
+}
+
 // RecordOptimizationSuggestion records query optimization suggestion metrics
 func RecordOptimizationSuggestion(suggestionType string) {
 	QueryOptimizationSuggestions.WithLabelValues(suggestionType).Inc()
 }
 
+// RecordAnalyticsStreamDrop records a stream message dropped for the given reason
+func RecordAnalyticsStreamDrop(reason string) {
+	AnalyticsStreamDropped.WithLabelValues(reason).Inc()
+}
+
+// RecordAnalyticsStreamDisconnect records a stream client disconnected by the server
+func RecordAnalyticsStreamDisconnect(reason string) {
+	AnalyticsStreamDisconnects.WithLabelValues(reason).Inc()
+}
+
+// RecordAnalyticsRecordDrop records a search analytics record dropped before persisting
+func RecordAnalyticsRecordDrop() {
+	AnalyticsRecordsDropped.Inc()
+}
+
+// SetAnalyticsStreamClients updates the number of connected stream clients
+func SetAnalyticsStreamClients(count int) {
+	AnalyticsStreamClients.Set(float64(count))
+}
+
 // SetApplicationInfo sets application information metrics
 func SetApplicationInfo(version, service, environment string) {
 	ApplicationInfo.WithLabelValues(version, service, environment).Set(1)
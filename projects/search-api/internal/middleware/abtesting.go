@@ -92,12 +92,14 @@ func ABTestingMiddleware(framework *abtesting.ABTestFramework, logger *zap.Logge
 		c.Next()
 		responseTime := time.Since(startTime)
 		
-		// Record experiment result
+		// Record experiment result; the search handlers report how many results were found
+		success := c.Writer.Status() < 400
+		resultCount := c.GetInt64(ABTestResultCountKey)
 		go func() {
 			result := abtesting.ExperimentResult{
-				Success:      c.Writer.Status() < 400,
+				Success:      success,
 				ResponseTime: responseTime,
-				ResultCount:  0, // Will be updated by search handler
+				ResultCount:  resultCount,
 			}
 			
 			framework.RecordExperimentResult(assignment, result)
@@ -105,12 +107,22 @@ func ABTestingMiddleware(framework *abtesting.ABTestFramework, logger *zap.Logge
 	}
 }
 
+// ABTestResultCountKey is the context key search handlers set to the total hits of the search
+const ABTestResultCountKey = "ab_test_result_count"
+
 // ApplyVariantModifications applies A/B test variant modifications to a search request
 func ApplyVariantModifications(searchReq *models.SearchRequest, assignment *abtesting.ExperimentAssignment) {
 	if assignment == nil || assignment.Variant == nil {
 		return
 	}
 	
+	// Strategy experiments swap the whole ranking recipe instead of tweaking parameters;
+	// a control variant without a strategy keeps the request's own ranking
+	if assignment.Experiment != nil && assignment.Experiment.Type == abtesting.TypeSearchStrategy {
+		searchReq.Strategy = assignment.Variant.Strategy
+		return
+	}
+	
 	modifications := assignment.Variant.QueryModifications
 	
 	// Apply query type modification
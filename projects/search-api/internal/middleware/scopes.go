@@ -0,0 +1,82 @@
+package middleware
+
+import (
+	"fmt"
+	"net"
+	"net/netip"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+
+	"github.com/saif-islam/es-playground/projects/search-api/internal/models"
+)
+
+// ScopesHeader carries the scopes the authenticating gateway granted the caller
+const ScopesHeader = "X-Search-Scopes"
+
+// TrustedScopesMiddleware drops the X-Search-Scopes header from requests that don't come
+// straight from one of the configured trusted proxies, so clients can't grant themselves
+// scopes by setting it. The peer address of the connection is checked, never
+// X-Forwarded-For, which the client controls as well. With no trusted proxies configured
+// the header is always dropped.
+func TrustedScopesMiddleware(config models.ScopesConfig, logger *zap.Logger) (gin.HandlerFunc, error) {
+	trusted, err := parseTrustedProxies(config.TrustedProxies)
+	if err != nil {
+		return nil, err
+	}
+
+	return func(c *gin.Context) {
+		if c.GetHeader(ScopesHeader) != "" && !trustedPeer(c.Request.RemoteAddr, trusted) {
+			logger.Warn("Ignoring X-Search-Scopes from an untrusted peer",
+				zap.String("remote_addr", c.Request.RemoteAddr),
+				zap.String("path", c.Request.URL.Path))
+			c.Request.Header.Del(ScopesHeader)
+		}
+		c.Next()
+	}, nil
+}
+
+// parseTrustedProxies accepts single addresses ("10.0.0.5") and CIDR ranges ("10.0.0.0/24")
+func parseTrustedProxies(proxies []string) ([]netip.Prefix, error) {
+	prefixes := make([]netip.Prefix, 0, len(proxies))
+	for _, proxy := range proxies {
+		proxy = strings.TrimSpace(proxy)
+		if strings.Contains(proxy, "/") {
+			prefix, err := netip.ParsePrefix(proxy)
+			if err != nil {
+				return nil, fmt.Errorf("invalid trusted proxy %q: %w", proxy, err)
+			}
+			prefixes = append(prefixes, prefix.Masked())
+			continue
+		}
+
+		addr, err := netip.ParseAddr(proxy)
+		if err != nil {
+			return nil, fmt.Errorf("invalid trusted proxy %q: %w", proxy, err)
+		}
+		addr = addr.Unmap()
+		prefixes = append(prefixes, netip.PrefixFrom(addr, addr.BitLen()))
+	}
+	return prefixes, nil
+}
+
+// trustedPeer reports whether the connection's peer address is in one of the trusted ranges
+func trustedPeer(remoteAddr string, trusted []netip.Prefix) bool {
+	host, _, err := net.SplitHostPort(remoteAddr)
+	if err != nil {
+		host = remoteAddr
+	}
+	addr, err := netip.ParseAddr(host)
+	if err != nil {
+		return false
+	}
+	addr = addr.Unmap()
+
+	for _, prefix := range trusted {
+		if prefix.Contains(addr) {
+			return true
+		}
+	}
+	return false
+}
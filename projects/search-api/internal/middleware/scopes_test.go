@@ -0,0 +1,86 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+
+	"github.com/saif-islam/es-playground/projects/search-api/internal/models"
+)
+
+func TestTrustedScopesMiddleware(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	tests := []struct {
+		name          string
+		trusted       []string
+		remoteAddr    string
+		forwardedFor  string
+		expectedScope string
+	}{
+		{
+			name:          "trusted gateway",
+			trusted:       []string{"10.0.0.0/24"},
+			remoteAddr:    "10.0.0.7:51234",
+			expectedScope: "pii:read",
+		},
+		{
+			name:          "trusted single address",
+			trusted:       []string{"10.0.0.7"},
+			remoteAddr:    "10.0.0.7:51234",
+			expectedScope: "pii:read",
+		},
+		{
+			name:       "spoofed by a client",
+			trusted:    []string{"10.0.0.0/24"},
+			remoteAddr: "203.0.113.9:40000",
+		},
+		{
+			name:         "spoofed with X-Forwarded-For",
+			trusted:      []string{"10.0.0.0/24"},
+			remoteAddr:   "203.0.113.9:40000",
+			forwardedFor: "10.0.0.7",
+		},
+		{
+			name:       "no trusted proxies",
+			remoteAddr: "10.0.0.7:51234",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			trustedScopes, err := TrustedScopesMiddleware(models.ScopesConfig{TrustedProxies: tt.trusted}, zap.NewNop())
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+
+			var scope string
+			router := gin.New()
+			router.Use(trustedScopes)
+			router.GET("/search", func(c *gin.Context) {
+				scope = c.GetHeader(ScopesHeader)
+			})
+
+			req := httptest.NewRequest(http.MethodGet, "/search", nil)
+			req.RemoteAddr = tt.remoteAddr
+			req.Header.Set(ScopesHeader, "pii:read")
+			if tt.forwardedFor != "" {
+				req.Header.Set("X-Forwarded-For", tt.forwardedFor)
+			}
+			router.ServeHTTP(httptest.NewRecorder(), req)
+
+			if scope != tt.expectedScope {
+				t.Errorf("Expected scopes %q, got %q", tt.expectedScope, scope)
+			}
+		})
+	}
+}
+
+func TestTrustedScopesMiddleware_InvalidProxy(t *testing.T) {
+	if _, err := TrustedScopesMiddleware(models.ScopesConfig{TrustedProxies: []string{"gateway.internal"}}, zap.NewNop()); err == nil {
+		t.Error("Expected an error for a trusted proxy that is not an address or CIDR range")
+	}
+}
@@ -4,17 +4,25 @@ import (
 	"time"
 	
 	"github.com/saif-islam/es-playground/projects/search-api/internal/tracing"
+	"github.com/saif-islam/es-playground/shared"
 )
 
 // Config represents the application configuration
 type Config struct {
 	Server        ServerConfig        `yaml:"server"`
 	Elasticsearch ElasticsearchConfig `yaml:"elasticsearch"`
+	// Additional named clusters, selected per request with the X-ES-Cluster header
+	Clusters      map[string]ElasticsearchConfig `yaml:"clusters"`
 	Redis         RedisConfig         `yaml:"redis"`
 	Logging       LoggingConfig       `yaml:"logging"`
 	Search        SearchConfig        `yaml:"search"`
 	Cache         CacheConfig         `yaml:"cache"`
 	Tracing       tracing.TracingConfig `yaml:"tracing"`
+	Analytics     AnalyticsConfig     `yaml:"analytics"`
+	Redaction     RedactionConfig     `yaml:"redaction"`
+	CostGuard     CostGuardConfig     `yaml:"cost_guard"`
+	Scopes        ScopesConfig        `yaml:"scopes"`
+	Experiments   ExperimentsConfig   `yaml:"experiments"`
 }
 
 // ServerConfig holds HTTP server configuration
@@ -33,11 +41,24 @@ type ElasticsearchConfig struct {
 	Password  string    `yaml:"password"`
 	APIKey    string    `yaml:"api_key"`
 	TLSConfig TLSConfig `yaml:"tls"`
+	// Per-request timeout for individual Elasticsearch calls
+	RequestTimeout time.Duration `yaml:"request_timeout"`
+	// Fail fast after repeated failures instead of waiting on a struggling cluster
+	CircuitBreaker CircuitBreakerConfig `yaml:"circuit_breaker"`
+}
+
+// CircuitBreakerConfig holds circuit breaker configuration
+type CircuitBreakerConfig struct {
+	FailureThreshold int           `yaml:"failure_threshold"` // Consecutive failures that open the breaker, 0 disables it
+	OpenTimeout      time.Duration `yaml:"open_timeout"`      // How long to fail fast before probing the cluster again
 }
 
 // TLSConfig holds TLS configuration
 type TLSConfig struct {
-	InsecureSkipVerify bool `yaml:"insecure_skip_verify"`
+	InsecureSkipVerify bool   `yaml:"insecure_skip_verify"`
+	CACertPath         string `yaml:"ca_cert_path"`     // PEM CA bundle trusted instead of the system roots
+	ClientCertPath     string `yaml:"client_cert_path"` // PEM client certificate for mutual TLS
+	ClientKeyPath      string `yaml:"client_key_path"`
 }
 
 // RedisConfig holds Redis connection settings
@@ -53,6 +74,8 @@ type LoggingConfig struct {
 	Level  string `yaml:"level"`
 	Format string `yaml:"format"`
 	Output string `yaml:"output"`
+
+	shared.BodyCaptureConfig `yaml:",inline"`
 }
 
 // SearchConfig holds search-specific configuration
@@ -61,6 +84,14 @@ type SearchConfig struct {
 	MaxSize     int               `yaml:"max_size"`
 	Timeout     time.Duration     `yaml:"timeout"`
 	Indices     map[string]string `yaml:"indices"`
+	// Searches with auto_suggest finding fewer results than this get a "did you mean"
+	// correction, defaults to 3
+	AutoSuggestThreshold int `yaml:"auto_suggest_threshold"`
+	// Index patterns, e.g. "articles-*", whose documents are large enough that highlighting
+	// every field is expensive; such searches get a warning, or are rejected with
+	// RequireHighlightFields
+	TextHeavyIndices       []string `yaml:"text_heavy_indices"`
+	RequireHighlightFields bool     `yaml:"require_highlight_fields"`
 }
 
 // CacheConfig holds cache configuration
@@ -82,5 +113,121 @@ type CacheConfig struct {
 	MaxConnections  int           `yaml:"max_connections"`
 	ReadTimeout     time.Duration `yaml:"read_timeout"`
 	WriteTimeout    time.Duration `yaml:"write_timeout"`
+	
+	// Searches run to fill the cache after a deploy
+	Warm            CacheWarmConfig `yaml:"warm"`
+}
+
+// CacheWarmConfig lists the searches run to fill a cold cache, at startup or through
+// POST /api/v1/search/cache/warm
+type CacheWarmConfig struct {
+	OnStartup  bool          `yaml:"on_startup"`  // warm the configured queries when the server starts
+	Queries    []WarmQuery   `yaml:"queries"`
+	TopQueries int           `yaml:"top_queries"` // also warm this many of the most frequent recent searches
+	Window     time.Duration `yaml:"window"`      // how far back top_queries looks, defaults to 1h
+}
+
+// WarmQuery is a search to run when warming the cache. It matches the cache entry of a
+// plain GET /search with the same parameters.
+type WarmQuery struct {
+	Index     string `yaml:"index"`
+	Query     string `yaml:"query"`
+	QueryType string `yaml:"query_type"`
+	Size      int    `yaml:"size"` // defaults to 10
+}
+
+// SearchRequest returns the search the query stands for, with GET /search defaults
+func (q WarmQuery) SearchRequest() *SearchRequest {
+	size := q.Size
+	if size <= 0 {
+		size = 10
+	}
+	return &SearchRequest{
+		Index:     q.Index,
+		Query:     q.Query,
+		QueryType: q.QueryType,
+		Size:      size,
+	}
 }
 
+
+// ScopesConfig controls where caller scopes are accepted from. Scopes arrive in the
+// X-Search-Scopes header, set by the authenticating gateway in front of the API.
+type ScopesConfig struct {
+	// Addresses or CIDR ranges of the gateways allowed to set X-Search-Scopes; the header
+	// is dropped from every other request, and from all requests when the list is empty
+	TrustedProxies []string `yaml:"trusted_proxies"`
+}
+
+// RedactionConfig holds per-index rules for hiding sensitive fields in search results
+type RedactionConfig struct {
+	// Callers holding this scope see documents unredacted
+	ElevatedScope string                     `yaml:"elevated_scope"`
+	// Rules keyed by index name or wildcard pattern, e.g. "customers-*"
+	Indices       map[string][]RedactionRule `yaml:"indices"`
+}
+
+// RedactionRule hides one _source field, addressed by dotted path (e.g. "contact.email")
+type RedactionRule struct {
+	Field    string `yaml:"field"`
+	Action   string `yaml:"action"`    // mask (default) or remove
+	Mask     string `yaml:"mask"`      // replacement text, defaults to "****"
+	KeepLast int    `yaml:"keep_last"` // trailing characters left visible when masking
+}
+
+// ExperimentsConfig controls how running A/B experiments are concluded
+type ExperimentsConfig struct {
+	// Complete experiments automatically once they have a significant winner with enough
+	// power, or once they exceed their max_duration
+	AutoStop       bool          `yaml:"auto_stop"`
+	CheckInterval  time.Duration `yaml:"check_interval"`  // defaults to 1m
+	MinPower       float64       `yaml:"min_power"`       // power a significant result needs, defaults to 0.8
+	WebhookURL     string        `yaml:"webhook_url"`     // receives the outcome of each stopped experiment
+	WebhookTimeout time.Duration `yaml:"webhook_timeout"` // defaults to 10s
+}
+
+// CostGuardConfig holds the rules used to reject or flag expensive searches before they run
+type CostGuardConfig struct {
+	Enabled bool   `yaml:"enabled"`
+	Mode    string `yaml:"mode"` // reject (default) or warn
+
+	MaxSize                int      `yaml:"max_size"`                  // largest allowed size, defaults to 500
+	AllowLeadingWildcards  bool     `yaml:"allow_leading_wildcards"`   // e.g. *term or ?erm
+	AllowRegexp            bool     `yaml:"allow_regexp"`              // /regex/ terms in query_string queries
+	MaxAggregationSize     int      `yaml:"max_aggregation_size"`      // largest terms aggregation size, defaults to 1000
+	HighCardinalityFields  []string `yaml:"high_cardinality_fields"`   // field patterns such as "user_id" or "*.id"
+	HighCardinalityMaxSize int      `yaml:"high_cardinality_max_size"` // terms size cap on those fields, defaults to 100
+
+	// Callers holding this scope, granted through X-Search-Scopes by a trusted gateway, may
+	// set allow_expensive to skip the guard; when empty, allow_expensive is ignored
+	OverrideScope string `yaml:"override_scope"`
+}
+
+// AnalyticsConfig holds settings for persisting search analytics to Elasticsearch and
+// for the live analytics stream
+type AnalyticsConfig struct {
+	PersistToES    bool           `yaml:"persist_to_es"`
+	IndexName      string         `yaml:"index_name"`
+	BatchSize      int            `yaml:"batch_size"`
+	FlushInterval  time.Duration  `yaml:"flush_interval"`
+	BufferSize     int            `yaml:"buffer_size"`
+	QueryMode      string         `yaml:"query_mode"`      // persisted query text: hash (default), raw, truncate or drop
+	TruncateLength int            `yaml:"truncate_length"` // characters kept in truncate mode, defaults to 32
+	HashSalt       string         `yaml:"hash_salt"`       // mixed into hashes so short queries cannot be guessed
+	Realtime       RealtimeConfig `yaml:"realtime"`
+}
+
+// RealtimeConfig controls what the live analytics stream exposes about each search
+type RealtimeConfig struct {
+	QueryMode          string  `yaml:"query_mode"`            // raw (default), hash, truncate or drop
+	TruncateLength     int     `yaml:"truncate_length"`       // characters kept in truncate mode, defaults to 32
+	HashSalt           string  `yaml:"hash_salt"`             // mixed into hashes so short queries cannot be guessed
+	SampleRate         float64 `yaml:"sample_rate"`           // fraction of search events broadcast, 0 means all
+	MaxEventsPerSecond int     `yaml:"max_events_per_second"` // cap on broadcast search events, 0 means no cap
+
+	// Backpressure for slow clients: each client has a bounded buffer that drops its oldest
+	// message when full, and a client that stays behind for max_lag is disconnected
+	ClientBufferSize int           `yaml:"client_buffer_size"` // messages buffered per client, defaults to 64
+	MaxLag           time.Duration `yaml:"max_lag"`            // defaults to 30s
+	WriteTimeout     time.Duration `yaml:"write_timeout"`      // per message write deadline, defaults to 10s
+}
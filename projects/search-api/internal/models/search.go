@@ -11,8 +11,9 @@ type SearchRequest struct {
 	From        int               `json:"from" form:"from"`
 	
 	// Advanced query options
-	QueryType   string            `json:"query_type,omitempty" form:"query_type"` // match, multi_match, bool, etc.
+	QueryType   string            `json:"query_type,omitempty" form:"query_type"` // match, multi_match, query_string, function_score, etc.
 	Fields      []string          `json:"fields,omitempty" form:"fields"`         // fields to search in
+	DefaultField string           `json:"default_field,omitempty" form:"default_field"` // field for a match query when fields is empty
 	Operator    string            `json:"operator,omitempty" form:"operator"`     // AND, OR
 	Fuzziness   string            `json:"fuzziness,omitempty" form:"fuzziness"`   // AUTO, 0, 1, 2
 	MinScore    float64           `json:"min_score,omitempty" form:"min_score"`
@@ -24,6 +25,7 @@ type SearchRequest struct {
 	
 	// Aggregations
 	Aggregations map[string]AggregationConfig `json:"aggregations,omitempty"`
+	AggregationsOnly bool         `json:"aggregations_only,omitempty" form:"aggregations_only"` // size 0: return aggregations without fetching hits
 	
 	// Result customization
 	Highlight   HighlightConfig   `json:"highlight,omitempty"`
@@ -42,19 +44,217 @@ type SearchRequest struct {
 	Suggest     map[string]SuggesterConfig `json:"suggest,omitempty"`
 	Rescore     []RescoreConfig   `json:"rescore,omitempty"`
 	
+	// One top hit per value of a field, e.g. one result per domain
+	Collapse    *CollapseConfig   `json:"collapse,omitempty"`
+	
+	// Runs the query text against nested objects or child or parent documents instead
+	Relation    *RelationQuery    `json:"relation,omitempty"`
+	
+	// Rescales the query's scores, e.g. to boost recent or popular documents; used when
+	// query_type is function_score
+	FunctionScore *FunctionScoreConfig `json:"function_score,omitempty"`
+	
+	// Approximate nearest neighbour search over a dense_vector field; runs alongside the
+	// query when one is given, otherwise instead of it
+	KNN         *KNNQuery         `json:"knn,omitempty"`
+	
+	// Consistent pagination across requests
+	PIT         *PointInTime      `json:"pit,omitempty"`          // search a point-in-time snapshot instead of the live index
+	SearchAfter []interface{}     `json:"search_after,omitempty"` // sort values of the last hit of the previous page
+	
 	RequestID   string            `json:"request_id,omitempty"`
 	
+	// Scopes granted to the caller; set from the X-Search-Scopes header, never from the body
+	Scopes      []string          `json:"-" form:"-"`
+	
+	// Skip the query cost guard; only honored for callers allowed to override it
+	AllowExpensive bool           `json:"allow_expensive,omitempty" form:"allow_expensive"`
+	
+	// Suggest a spelling correction when the search finds few results
+	AutoSuggest bool              `json:"auto_suggest,omitempty" form:"auto_suggest"`
+	
 	// A/B testing and experimentation
 	ABTestVariant string                 `json:"ab_test_variant,omitempty"`
 	Metadata      map[string]interface{} `json:"metadata,omitempty"`
+	
+	// Ranking strategy of the assigned search_strategy experiment variant, never from the body
+	Strategy      *SearchStrategy        `json:"-" form:"-"`
+}
+
+// SearchStrategy is a complete ranking recipe compared end to end in a search_strategy
+// experiment. The main query comes from the template when one is set, otherwise from a
+// multi_match over the boosted fields; filters, sorting and paging still come from the request.
+type SearchStrategy struct {
+	Name          string             `json:"name,omitempty"`
+	QueryTemplate string             `json:"query_template,omitempty"` // query DSL JSON; {{query}} inside a string is replaced with the query text
+	FieldBoosts   map[string]float64 `json:"field_boosts,omitempty"`   // e.g. {"title": 3, "body": 1}
+	Operator      string             `json:"operator,omitempty"`       // for the field boost multi_match
+	Rescore       []RescoreConfig    `json:"rescore,omitempty"`        // replaces the request's rescore
+}
+
+// RelationQuery runs the query text as a nested, has_child or has_parent query, so documents
+// match through their comments, children or parent
+type RelationQuery struct {
+	Type         string           `json:"type" binding:"required,oneof=nested has_child has_parent"`
+	Path         string           `json:"path,omitempty"`          // nested object path, required for nested
+	RelationType string           `json:"relation_type,omitempty"` // child type for has_child, parent type for has_parent
+	ScoreMode    string           `json:"score_mode,omitempty"`    // how matching relatives score the hit; none scores it as a filter would
+	InnerHits    *InnerHitsConfig `json:"inner_hits,omitempty"`    // return the matching nested objects or relatives
+}
+
+// FunctionScoreConfig wraps the main query in a function_score query
+type FunctionScoreConfig struct {
+	QueryType string          `json:"query_type,omitempty"` // type of the wrapped text query, simple_query_string by default
+	Functions []ScoreFunction `json:"functions" binding:"required,min=1,dive"`
+	ScoreMode string          `json:"score_mode,omitempty" binding:"omitempty,oneof=multiply sum avg first max min"`   // how function scores combine
+	BoostMode string          `json:"boost_mode,omitempty" binding:"omitempty,oneof=multiply replace sum avg max min"` // how they combine with the query score
+	MaxBoost  float64         `json:"max_boost,omitempty"`
+	MinScore  float64         `json:"min_score,omitempty"` // documents scoring lower are dropped
+}
+
+// ScoreFunction is one function of a function_score query: a decay or field_value_factor
+// function, optionally weighted, or just a weight
+type ScoreFunction struct {
+	Filter           []Filter          `json:"filter,omitempty"` // the function only applies to matching documents
+	Weight           float64           `json:"weight,omitempty"`
+	Decay            *DecayFunction    `json:"decay,omitempty"`
+	FieldValueFactor *FieldValueFactor `json:"field_value_factor,omitempty"`
+}
+
+// DecayFunction scores documents by how far a numeric, date or geo field is from an origin
+type DecayFunction struct {
+	Type   string      `json:"type" binding:"required,oneof=gauss linear exp"`
+	Field  string      `json:"field" binding:"required"`
+	Origin interface{} `json:"origin,omitempty"`         // e.g. "now" for dates; required for geo fields
+	Scale  interface{} `json:"scale" binding:"required"` // distance at which the score is decay, e.g. "7d" or 100
+	Offset interface{} `json:"offset,omitempty"`
+	Decay  float64     `json:"decay,omitempty"` // Elasticsearch uses 0.5 by default
+}
+
+// FieldValueFactor scores documents by a numeric field, e.g. a popularity count
+type FieldValueFactor struct {
+	Field    string   `json:"field" binding:"required"`
+	Factor   float64  `json:"factor,omitempty"`
+	Modifier string   `json:"modifier,omitempty" binding:"omitempty,oneof=none log log1p log2p ln ln1p ln2p square sqrt reciprocal"`
+	Missing  *float64 `json:"missing,omitempty"` // value for documents without the field
+}
+
+// KNNQuery represents a kNN search over a dense_vector field. Request filters apply to the
+// nearest neighbours too, together with the kNN filter.
+type KNNQuery struct {
+	Field         string    `json:"field" binding:"required"`
+	QueryVector   []float32 `json:"query_vector" binding:"required,min=1"`
+	K             int       `json:"k,omitempty" binding:"omitempty,min=1"`                        // defaults to the request size
+	NumCandidates int       `json:"num_candidates,omitempty" binding:"omitempty,min=1,max=10000"` // candidates per shard, at least k
+	Filter        []Filter  `json:"filter,omitempty"`
+	Boost         float64   `json:"boost,omitempty"` // weight against the query score in hybrid searches
+}
+
+// CollapseConfig represents field collapsing; the field must be a keyword or numeric
+// field with doc values
+type CollapseConfig struct {
+	Field                      string                   `json:"field" binding:"required"`
+	InnerHits                  *InnerHitsConfig `json:"inner_hits,omitempty"`
+	MaxConcurrentGroupSearches int                      `json:"max_concurrent_group_searches,omitempty"`
+}
+
+// InnerHitsConfig asks for the documents behind each hit: the other members of a collapsed
+// group, or the nested objects, child or parent documents that matched
+type InnerHitsConfig struct {
+	Name string      `json:"name,omitempty"` // key in each hit's inner_hits; "collapsed" for collapse, otherwise the path or relation type
+	Size int         `json:"size,omitempty"` // Elasticsearch returns 3 by default
+	Sort []SortField `json:"sort,omitempty"`
+}
+
+// PointInTime references a point-in-time reader for searches against a consistent snapshot
+type PointInTime struct {
+	ID        string `json:"id" binding:"required"`
+	KeepAlive string `json:"keep_alive,omitempty"` // extends the reader on each search, e.g. 1m
+}
+
+// OpenPITRequest represents a request to open a point-in-time reader
+type OpenPITRequest struct {
+	Index     string `json:"index" binding:"required"`
+	KeepAlive string `json:"keep_alive,omitempty"`
+}
+
+// ClosePITRequest represents a request to close a point-in-time reader
+type ClosePITRequest struct {
+	ID string `json:"id" binding:"required"`
+}
+
+// PITResponse represents an open point-in-time reader
+type PITResponse struct {
+	ID        string    `json:"id"`
+	Index     string    `json:"index"`
+	KeepAlive string    `json:"keep_alive"`
+	RequestID string    `json:"request_id"`
+	Timestamp time.Time `json:"timestamp"`
+}
+
+// AsyncSearchRequest represents a long-running search submitted for background execution
+type AsyncSearchRequest struct {
+	SearchRequest
+	WaitForCompletion string `json:"wait_for_completion_timeout,omitempty"` // how long to block before returning an ID, e.g. 1s
+	KeepAlive         string `json:"keep_alive,omitempty"`                  // how long results are retained, e.g. 5m
+}
+
+// OpenScrollRequest starts a scroll over every document matching a search, for clients
+// that cannot manage point-in-time readers
+type OpenScrollRequest struct {
+	SearchRequest
+	KeepAlive string `json:"keep_alive,omitempty"` // how long the scroll lives between pages, e.g. 1m
+}
+
+// ScrollRequest fetches the next page of a scroll
+type ScrollRequest struct {
+	ScrollID  string `json:"scroll_id" binding:"required"`
+	KeepAlive string `json:"keep_alive,omitempty"`
+}
+
+// ClearScrollRequest releases the search contexts of scrolls that are no longer needed
+type ClearScrollRequest struct {
+	ScrollIDs []string `json:"scroll_ids" binding:"required,min=1"`
+}
+
+// ScrollResponse is a page of a scroll
+type ScrollResponse struct {
+	// Pass to the next request; absent once the scroll is exhausted, when it has already
+	// been cleared
+	ScrollID  string          `json:"scroll_id,omitempty"`
+	KeepAlive string          `json:"keep_alive"`
+	Done      bool            `json:"done"`
+	Result    *SearchResponse `json:"result"`
+	RequestID string          `json:"request_id"`
+	Timestamp time.Time       `json:"timestamp"`
+}
+
+// AsyncSearchResponse represents the state and (partial) results of an async search
+type AsyncSearchResponse struct {
+	ID             string          `json:"id,omitempty"`
+	IsRunning      bool            `json:"is_running"`
+	IsPartial      bool            `json:"is_partial"`
+	Progress       float64         `json:"progress"` // percentage of shards that have reported
+	StartTime      time.Time       `json:"start_time"`
+	ExpirationTime time.Time       `json:"expiration_time"`
+	Result         *SearchResponse `json:"result,omitempty"`
+	RequestID      string          `json:"request_id"`
+	Timestamp      time.Time       `json:"timestamp"`
 }
 
 // Filter represents a search filter
 type Filter struct {
 	Field    string      `json:"field"`
-	Type     string      `json:"type"`     // term, terms, range, exists, wildcard, etc.
+	Type     string      `json:"type"`     // term, terms, range, exists, wildcard, nested, has_child, has_parent, etc.
 	Value    interface{} `json:"value"`
 	Operator string      `json:"operator,omitempty"` // gte, lte, gt, lt for range
+	
+	// nested, has_child and has_parent filters apply Filters to the nested objects at Path,
+	// or to the child or parent documents of RelationType
+	Path         string           `json:"path,omitempty"`
+	RelationType string           `json:"relation_type,omitempty"`
+	Filters      []Filter         `json:"filters,omitempty"`
+	InnerHits    *InnerHitsConfig `json:"inner_hits,omitempty"` // return the matching nested objects or relatives
 }
 
 // HighlightConfig represents highlighting configuration
@@ -71,11 +271,24 @@ type HighlightConfig struct {
 
 // AggregationConfig represents aggregation configuration
 type AggregationConfig struct {
-	Type     string                 `json:"type"`     // terms, date_histogram, stats, etc.
+	Type     string                 `json:"type"`     // terms, date_histogram, stats, cardinality, percentiles, range, filters, nested, bucket_sort, avg_bucket, etc.
 	Field    string                 `json:"field"`
 	Size     int                    `json:"size,omitempty"`
 	Settings map[string]interface{} `json:"settings,omitempty"`
 	SubAggs  map[string]AggregationConfig `json:"aggs,omitempty"`
+	
+	Ranges      []AggregationRange  `json:"ranges,omitempty"`       // range and date_range buckets
+	Filters     map[string][]Filter `json:"filters,omitempty"`      // filters aggregation buckets by name
+	Path        string              `json:"path,omitempty"`         // nested aggregation object path
+	BucketsPath string              `json:"buckets_path,omitempty"` // sibling pipeline input, e.g. "per_day>sales"
+}
+
+// AggregationRange is a bucket of a range or date_range aggregation; From is inclusive and
+// To exclusive, and either may be left open
+type AggregationRange struct {
+	Key  string      `json:"key,omitempty"`
+	From interface{} `json:"from,omitempty"`
+	To   interface{} `json:"to,omitempty"`
 }
 
 // SuggesterConfig represents suggester configuration
@@ -113,6 +326,7 @@ type SearchResponse struct {
 	Hits         []SearchHit            `json:"hits"`
 	Aggregations map[string]interface{} `json:"aggregations,omitempty"`
 	Suggest      map[string][]SuggestOption `json:"suggest,omitempty"`
+	DidYouMean   string                 `json:"did_you_mean,omitempty"` // correction offered for sparse auto_suggest results
 	
 	// Performance and debug info
 	Shards       ShardInfo              `json:"_shards"`
@@ -125,6 +339,13 @@ type SearchResponse struct {
 	// Caching
 	CacheHit     bool                   `json:"cache_hit,omitempty"`
 	
+	// Point in time (may change between requests; always use the latest)
+	PITID        string                 `json:"pit_id,omitempty"`
+	
+	// Sort values to pass as search_after for the next page; absent on the last page,
+	// when the search is not sorted, or when it is sorted on a field redacted for the caller
+	SearchAfter  []interface{}          `json:"search_after,omitempty"`
+	
 	// Request tracking
 	RequestID    string                 `json:"request_id"`
 	Timestamp    time.Time              `json:"timestamp"`
@@ -137,6 +358,13 @@ type HitsTotal struct {
 	Relation string `json:"relation"` // eq, gte
 }
 
+// CountResponse is the number of documents a search matches, without fetching them
+type CountResponse struct {
+	Count    int64     `json:"count"`
+	Shards   ShardInfo `json:"_shards"`
+	CacheHit bool      `json:"cache_hit,omitempty"`
+}
+
 // ShardInfo represents shard execution information
 type ShardInfo struct {
 	Total      int `json:"total"`
@@ -174,6 +402,23 @@ type SearchHit struct {
 	Score     *float64        `json:"_score"`
 	Source    interface{}     `json:"_source"`
 	Highlight map[string][]string `json:"highlight,omitempty"`
+	Sort      []interface{}   `json:"sort,omitempty"`
+	InnerHits map[string]InnerHits `json:"inner_hits,omitempty"` // collapsed group members or matching relatives, by inner hits name
+	Nested    *NestedIdentity `json:"_nested,omitempty"`     // which nested object an inner hit is
+}
+
+// NestedIdentity locates a nested object inner hit within its document
+type NestedIdentity struct {
+	Field  string          `json:"field"`
+	Offset int             `json:"offset"`
+	Nested *NestedIdentity `json:"_nested,omitempty"` // for objects nested more than one level deep
+}
+
+// InnerHits represents the hits of a collapsed group, whose total includes the top hit
+// itself, or the nested objects, child or parent documents that matched
+type InnerHits struct {
+	Total HitsTotal   `json:"total"`
+	Hits  []SearchHit `json:"hits"`
 }
 
 // SuggestRequest represents an autocomplete/suggestion request
@@ -256,6 +501,7 @@ type SearchAnalytics struct {
 	UserID           string                 `json:"user_id,omitempty"`
 	SessionID        string                 `json:"session_id,omitempty"`
 	Timestamp        time.Time              `json:"timestamp"`
+	VectorDims       int                    `json:"vector_dims,omitempty"` // query vector dimension of kNN searches
 	Performance      SearchPerformanceMetrics `json:"performance"`
 	Metadata         map[string]interface{} `json:"metadata,omitempty"`
 }
@@ -319,6 +565,28 @@ type SearchTemplate struct {
 	IsPublic    bool                   `json:"is_public"`
 }
 
+// StoredTemplate is a Mustache search template stored in the cluster as a script
+type StoredTemplate struct {
+	ID     string `json:"id"`
+	Source string `json:"source"` // Mustache template of the search body
+}
+
+// StoreTemplateRequest stores a search template. The id comes from the path on PUT.
+type StoreTemplateRequest struct {
+	ID     string      `json:"id,omitempty"`
+	Source interface{} `json:"source" binding:"required"` // search body template, as a string or JSON
+}
+
+// TemplateSearchRequest runs or renders a stored search template with params
+type TemplateSearchRequest struct {
+	Index     string                 `json:"index"` // required to search, ignored when rendering
+	Params    map[string]interface{} `json:"params,omitempty"`
+	RequestID string                 `json:"request_id,omitempty"`
+
+	// Scopes granted to the caller; set from the X-Search-Scopes header, never from the body
+	Scopes []string `json:"-"`
+}
+
 // SearchExplain represents query explanation
 type SearchExplain struct {
 	QueryID       string                 `json:"query_id"`
@@ -338,4 +606,52 @@ type QueryExplanation struct {
 	FieldsSearched []string             `json:"fields_searched"`
 	Complexity   string                 `json:"complexity"` // simple, moderate, complex
 	EstimatedCost float64               `json:"estimated_cost"`
-}
\ No newline at end of file
+}
+
+// ValidateResponse reports whether a search's query is valid, with Elasticsearch's
+// explanation of how it was parsed on each index
+type ValidateResponse struct {
+	Valid        bool                         `json:"valid"`
+	Explanations []QueryValidationExplanation `json:"explanations,omitempty"`
+	Shards       ShardInfo                    `json:"_shards"`
+	Query        map[string]interface{}       `json:"query"` // the query that was validated
+}
+
+// QueryValidationExplanation is the validation result of a query on one index
+type QueryValidationExplanation struct {
+	Index       string `json:"index"`
+	Valid       bool   `json:"valid"`
+	Explanation string `json:"explanation,omitempty"`
+	Error       string `json:"error,omitempty"`
+}
+
+// ExplainResponse tells whether a document matches a search's query and how it was scored
+type ExplainResponse struct {
+	Index       string            `json:"_index"`
+	ID          string            `json:"_id"`
+	Matched     bool              `json:"matched"`
+	Explanation *ScoreExplanation `json:"explanation,omitempty"`
+}
+
+// ScoreExplanation is one node of Elasticsearch's scoring explanation tree
+type ScoreExplanation struct {
+	Value       float64            `json:"value"`
+	Description string             `json:"description"`
+	Details     []ScoreExplanation `json:"details,omitempty"`
+}
+
+// AliasMappingReport represents the mapping consistency of an alias across its indices
+type AliasMappingReport struct {
+	Alias         string            `json:"alias"`
+	Indices       []string          `json:"indices"`
+	FieldsChecked int               `json:"fields_checked"`
+	Consistent    bool              `json:"consistent"`
+	Conflicts     []MappingConflict `json:"conflicts"`
+	CheckedAt     time.Time         `json:"checked_at"`
+}
+
+// MappingConflict represents a field mapped with different types across indices
+type MappingConflict struct {
+	Field string              `json:"field"`
+	Types map[string][]string `json:"types"` // field type -> indices using it
+}
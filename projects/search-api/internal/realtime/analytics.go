@@ -1,10 +1,8 @@
 package realtime
 
 import (
-	"context"
 	"encoding/json"
 	"fmt"
-	"log"
 	"net/http"
 	"sync"
 	"time"
@@ -19,17 +17,29 @@ import (
 
 // AnalyticsHub manages real-time analytics connections and data streams
 type AnalyticsHub struct {
-	clients    map[*websocket.Conn]bool
+	clients    map[*websocket.Conn]*analyticsClient
 	broadcast  chan []byte
-	register   chan *websocket.Conn
+	register   chan *analyticsClient
 	unregister chan *websocket.Conn
 	logger     *zap.Logger
 	mu         sync.RWMutex
+
+	// Per-client backpressure
+	clientBufferSize int
+	maxLag           time.Duration
+	writeTimeout     time.Duration
 	
 	// Analytics data
 	searchMetrics    *SearchMetricsBuffer
 	queryPatterns    *QueryPatternTracker
 	performanceStats *PerformanceStatsTracker
+	queryFrequency   *queryFrequencyTracker
+	searchSummaries  *searchSummaryTracker
+	zeroResults      *zeroResultTracker
+
+	// Privacy and volume controls for the live event feed
+	anonymizer *QueryAnonymizer
+	sampler    *eventSampler
 }
 
 // SearchEvent represents a real-time search event
@@ -48,6 +58,7 @@ type SearchEvent struct {
 	CacheHit      bool                   `json:"cache_hit"`
 	ABTestVariant string                 `json:"ab_test_variant,omitempty"`
 	TraceID       string                 `json:"trace_id,omitempty"`
+	VectorDims    int                    `json:"vector_dims,omitempty"`
 	Metadata      map[string]interface{} `json:"metadata,omitempty"`
 }
 
@@ -63,6 +74,7 @@ type RealTimeMetrics struct {
 	TopIndices       []IndexStats         `json:"top_indices"`
 	PerformanceAlerts []PerformanceAlert  `json:"performance_alerts"`
 	ABTestResults    map[string]ABMetrics `json:"ab_test_results"`
+	SampledOutEvents int64                `json:"sampled_out_events"`
 }
 
 // QueryStats represents query performance statistics
@@ -111,16 +123,34 @@ var upgrader = websocket.Upgrader{
 }
 
 // NewAnalyticsHub creates a new analytics hub
-func NewAnalyticsHub(logger *zap.Logger) *AnalyticsHub {
+func NewAnalyticsHub(config models.RealtimeConfig, logger *zap.Logger) *AnalyticsHub {
 	hub := &AnalyticsHub{
-		clients:          make(map[*websocket.Conn]bool),
+		clients:          make(map[*websocket.Conn]*analyticsClient),
 		broadcast:        make(chan []byte, 256),
-		register:         make(chan *websocket.Conn),
+		register:         make(chan *analyticsClient),
 		unregister:       make(chan *websocket.Conn),
 		logger:           logger,
+		clientBufferSize: config.ClientBufferSize,
+		maxLag:           config.MaxLag,
+		writeTimeout:     config.WriteTimeout,
 		searchMetrics:    NewSearchMetricsBuffer(1000), // Keep last 1000 searches
 		queryPatterns:    NewQueryPatternTracker(),
 		performanceStats: NewPerformanceStatsTracker(),
+		queryFrequency:   newQueryFrequencyTracker(),
+		searchSummaries:  newSearchSummaryTracker(),
+		zeroResults:      newZeroResultTracker(),
+		anonymizer:       NewQueryAnonymizer(config.QueryMode, config.TruncateLength, config.HashSalt),
+		sampler:          newEventSampler(config),
+	}
+	
+	if hub.clientBufferSize <= 0 {
+		hub.clientBufferSize = defaultClientBufferSize
+	}
+	if hub.maxLag <= 0 {
+		hub.maxLag = defaultMaxLag
+	}
+	if hub.writeTimeout <= 0 {
+		hub.writeTimeout = defaultWriteTimeout
 	}
 	
 	go hub.run()
@@ -129,49 +159,66 @@ func NewAnalyticsHub(logger *zap.Logger) *AnalyticsHub {
 	return hub
 }
 
-// run handles the main hub loop
+// run handles the main hub loop. It never writes to a socket itself: messages are queued
+// on each client's bounded buffer, so one slow client cannot hold up the others.
 func (h *AnalyticsHub) run() {
 	for {
 		select {
 		case client := <-h.register:
 			h.mu.Lock()
-			h.clients[client] = true
+			h.clients[client.conn] = client
+			count := len(h.clients)
 			h.mu.Unlock()
+			metrics.SetAnalyticsStreamClients(count)
 			h.logger.Info("Client connected to analytics stream",
-				zap.Int("total_clients", len(h.clients)))
-			
-			// Send initial metrics to new client
-			go h.sendInitialMetrics(client)
+				zap.Int("total_clients", count))
 
-		case client := <-h.unregister:
-			h.mu.Lock()
-			if _, ok := h.clients[client]; ok {
-				delete(h.clients, client)
-				client.Close()
+		case conn := <-h.unregister:
+			if client, ok := h.clients[conn]; ok {
+				h.removeClient(client, "")
 			}
-			h.mu.Unlock()
-			h.logger.Info("Client disconnected from analytics stream",
-				zap.Int("total_clients", len(h.clients)))
 
 		case message := <-h.broadcast:
-			h.mu.RLock()
-			for client := range h.clients {
-				select {
-				case client.WriteMessage(websocket.TextMessage, message):
-				default:
-					delete(h.clients, client)
-					client.Close()
+			now := time.Now()
+			for _, client := range h.clients {
+				if !client.enqueue(message, now, h.maxLag) {
+					h.logger.Warn("Disconnecting lagging analytics client",
+						zap.String("remote_addr", client.conn.RemoteAddr().String()),
+						zap.Duration("lagging_for", now.Sub(client.laggingSince)))
+					metrics.RecordAnalyticsStreamDisconnect("lagging")
+					h.removeClient(client, "client too slow")
 				}
 			}
-			h.mu.RUnlock()
 		}
 	}
 }
 
+// removeClient forgets a client and closes its buffer, which stops its writer. A non-empty
+// reason is sent to the client as the close frame. Only the run loop calls it.
+func (h *AnalyticsHub) removeClient(client *analyticsClient, reason string) {
+	h.mu.Lock()
+	delete(h.clients, client.conn)
+	count := len(h.clients)
+	h.mu.Unlock()
+
+	client.closeReason = reason
+	close(client.send)
+
+	metrics.SetAnalyticsStreamClients(count)
+	h.logger.Info("Client disconnected from analytics stream",
+		zap.Int("total_clients", count))
+}
+
 // RecordSearchEvent records a search event for real-time analytics
 func (h *AnalyticsHub) RecordSearchEvent(event SearchEvent) {
+	// Anonymize first so the raw query is never buffered, tracked or sent to clients
+	event.Query = h.anonymizer.Anonymize(event.Query)
+
 	// Add to metrics buffer
 	h.searchMetrics.Add(event)
+
+	// Roll into the per-minute aggregates behind Summary
+	h.searchSummaries.record(event)
 	
 	// Update query patterns
 	h.queryPatterns.Track(event.Query, event.ResponseTime, event.Success)
@@ -187,6 +234,11 @@ func (h *AnalyticsHub) RecordSearchEvent(event SearchEvent) {
 		}
 	}
 	
+	// Only a sample of events is streamed individually; metrics above cover all of them
+	if !h.sampler.allow(time.Now()) {
+		return
+	}
+
 	// Broadcast event to all connected clients
 	eventJSON, err := json.Marshal(map[string]interface{}{
 		"type": "search_event",
@@ -200,21 +252,36 @@ func (h *AnalyticsHub) RecordSearchEvent(event SearchEvent) {
 	select {
 	case h.broadcast <- eventJSON:
 	default:
+		metrics.RecordAnalyticsStreamDrop("hub_full")
 		h.logger.Warn("Broadcast channel full, dropping search event")
 	}
 }
 
+// TrackQuery counts a successful search towards the popular queries used to warm the cache.
+// Only plain searches are counted, and only while the stream shows raw queries: anonymized
+// queries cannot be replayed and must not be kept.
+func (h *AnalyticsHub) TrackQuery(req *models.SearchRequest) {
+	if !h.RawQueries() || !replayable(req) {
+		return
+	}
+	h.queryFrequency.track(req, time.Now())
+}
+
+// PopularQueries returns the most frequent plain searches over the window, which is capped
+// at an hour
+func (h *AnalyticsHub) PopularQueries(window time.Duration, limit int) []PopularQuery {
+	return h.queryFrequency.top(window, limit, time.Now())
+}
+
 // generateMetrics generates and broadcasts aggregated metrics every second
 func (h *AnalyticsHub) generateMetrics() {
 	ticker := time.NewTicker(1 * time.Second)
 	defer ticker.Stop()
 	
 	for range ticker.C {
-		metrics := h.generateRealTimeMetrics()
-		
 		metricsJSON, err := json.Marshal(map[string]interface{}{
 			"type": "metrics_update",
-			"data": metrics,
+			"data": h.generateRealTimeMetrics(),
 		})
 		if err != nil {
 			h.logger.Error("Failed to marshal metrics", zap.Error(err))
@@ -225,6 +292,7 @@ func (h *AnalyticsHub) generateMetrics() {
 		case h.broadcast <- metricsJSON:
 		default:
 			// Channel full, skip this update
+			metrics.RecordAnalyticsStreamDrop("hub_full")
 		}
 	}
 }
@@ -344,6 +412,11 @@ func (h *AnalyticsHub) generateRealTimeMetrics() RealTimeMetrics {
 		topIndices = append(topIndices, *stat)
 	}
 	
+	abResults := make(map[string]ABMetrics, len(abStats))
+	for variant, stat := range abStats {
+		abResults[variant] = *stat
+	}
+	
 	// Get performance alerts
 	alerts := h.performanceStats.GetRecentAlerts(5 * time.Minute)
 	
@@ -357,7 +430,8 @@ func (h *AnalyticsHub) generateRealTimeMetrics() RealTimeMetrics {
 		TopQueries:       topQueries,
 		TopIndices:       topIndices,
 		PerformanceAlerts: alerts,
-		ABTestResults:    abStats,
+		ABTestResults:    abResults,
+		SampledOutEvents: h.sampler.droppedEvents(),
 	}
 }
 
@@ -410,26 +484,17 @@ func (h *AnalyticsHub) broadcastAlert(alert PerformanceAlert) {
 	select {
 	case h.broadcast <- alertJSON:
 	default:
+		metrics.RecordAnalyticsStreamDrop("hub_full")
 		h.logger.Warn("Broadcast channel full, dropping alert")
 	}
 }
 
-// sendInitialMetrics sends initial metrics to a newly connected client
-func (h *AnalyticsHub) sendInitialMetrics(client *websocket.Conn) {
-	metrics := h.generateRealTimeMetrics()
-	
-	initialData, err := json.Marshal(map[string]interface{}{
+// initialMetrics builds the snapshot sent to a newly connected client
+func (h *AnalyticsHub) initialMetrics() ([]byte, error) {
+	return json.Marshal(map[string]interface{}{
 		"type": "initial_metrics",
-		"data": metrics,
+		"data": h.generateRealTimeMetrics(),
 	})
-	if err != nil {
-		h.logger.Error("Failed to marshal initial metrics", zap.Error(err))
-		return
-	}
-	
-	if err := client.WriteMessage(websocket.TextMessage, initialData); err != nil {
-		h.logger.Error("Failed to send initial metrics", zap.Error(err))
-	}
 }
 
 // HandleWebSocket handles WebSocket connections for real-time analytics
@@ -440,7 +505,17 @@ func (h *AnalyticsHub) HandleWebSocket(c *gin.Context) {
 		return
 	}
 	
-	h.register <- conn
+	client := newAnalyticsClient(conn, h.clientBufferSize)
+	
+	// Queue initial metrics before registering, while the buffer is still empty
+	if initialData, err := h.initialMetrics(); err != nil {
+		h.logger.Error("Failed to marshal initial metrics", zap.Error(err))
+	} else {
+		client.send <- initialData
+	}
+	
+	go client.writePump(h.writeTimeout)
+	h.register <- client
 	
 	// Keep connection alive
 	defer func() {
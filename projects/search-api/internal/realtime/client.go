@@ -0,0 +1,90 @@
+package realtime
+
+import (
+	"time"
+
+	"github.com/gorilla/websocket"
+
+	"github.com/saif-islam/es-playground/projects/search-api/internal/metrics"
+)
+
+// Stream backpressure defaults
+const (
+	defaultClientBufferSize = 64
+	defaultMaxLag           = 30 * time.Second
+	defaultWriteTimeout     = 10 * time.Second
+)
+
+// analyticsClient is one WebSocket subscriber. The hub only ever hands it messages through
+// its bounded send buffer, and a dedicated writer goroutine drains the buffer to the socket,
+// so a slow client can never block the hub or the other clients.
+type analyticsClient struct {
+	conn *websocket.Conn
+	send chan []byte
+
+	// Owned by the hub's run loop: when the client first fell behind, zero while keeping up
+	laggingSince time.Time
+	// Set by the hub before it closes send to disconnect the client itself
+	closeReason string
+}
+
+func newAnalyticsClient(conn *websocket.Conn, bufferSize int) *analyticsClient {
+	return &analyticsClient{
+		conn: conn,
+		send: make(chan []byte, bufferSize),
+	}
+}
+
+// enqueue hands a message to the client, dropping its oldest buffered message to make room
+// when the buffer is full. It reports false once the client has stayed behind for longer
+// than maxLag and should be disconnected. Only the hub's run loop calls it.
+func (c *analyticsClient) enqueue(message []byte, now time.Time, maxLag time.Duration) bool {
+	select {
+	case c.send <- message:
+		// Caught up once the writer has drained at least half of the buffer
+		if len(c.send) <= cap(c.send)/2 {
+			c.laggingSince = time.Time{}
+		}
+		return true
+	default:
+	}
+
+	if c.laggingSince.IsZero() {
+		c.laggingSince = now
+	}
+
+	// The writer may drain the buffer concurrently, so neither step is guaranteed to act
+	select {
+	case <-c.send:
+		metrics.RecordAnalyticsStreamDrop("client_lagging")
+	default:
+	}
+	select {
+	case c.send <- message:
+	default:
+		metrics.RecordAnalyticsStreamDrop("client_lagging")
+	}
+
+	return now.Sub(c.laggingSince) < maxLag
+}
+
+// writePump writes buffered messages to the socket until the hub closes the buffer or a
+// write fails. Closing the connection also ends the client's read loop.
+func (c *analyticsClient) writePump(writeTimeout time.Duration) {
+	defer c.conn.Close()
+
+	for message := range c.send {
+		c.conn.SetWriteDeadline(time.Now().Add(writeTimeout))
+		if err := c.conn.WriteMessage(websocket.TextMessage, message); err != nil {
+			return
+		}
+	}
+
+	// The hub closed the buffer; tell the client why if it was disconnected on purpose
+	if c.closeReason == "" {
+		return
+	}
+	c.conn.SetWriteDeadline(time.Now().Add(writeTimeout))
+	c.conn.WriteMessage(websocket.CloseMessage,
+		websocket.FormatCloseMessage(websocket.ClosePolicyViolation, c.closeReason))
+}
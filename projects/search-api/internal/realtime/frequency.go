@@ -0,0 +1,138 @@
+package realtime
+
+import (
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/saif-islam/es-playground/projects/search-api/internal/models"
+)
+
+// Query frequency tracking keeps per-minute counts for the last hour
+const (
+	frequencyBucketWidth     = time.Minute
+	frequencyRetention       = time.Hour
+	maxTrackedQueriesPerSlot = 10000
+)
+
+// PopularQuery is a plain search and how often it ran within a window. It carries exactly
+// the parameters needed to replay the search and hit its cache entry.
+type PopularQuery struct {
+	Index     string `json:"index"`
+	Query     string `json:"query"`
+	QueryType string `json:"query_type,omitempty"`
+	Size      int    `json:"size"`
+	Count     int64  `json:"count"`
+}
+
+type queryKey struct {
+	index     string
+	query     string
+	queryType string
+	size      int
+}
+
+type frequencyBucket struct {
+	start  time.Time
+	counts map[queryKey]int64
+}
+
+// queryFrequencyTracker counts replayable searches in one-minute buckets, so the most
+// frequent searches over any window up to an hour can be found
+type queryFrequencyTracker struct {
+	mu      sync.Mutex
+	buckets []*frequencyBucket
+}
+
+func newQueryFrequencyTracker() *queryFrequencyTracker {
+	return &queryFrequencyTracker{}
+}
+
+// replayable reports whether a search can be run again from a PopularQuery and produce the
+// same cache entry: only the query, index, size and query type may be set
+func replayable(req *models.SearchRequest) bool {
+	return req.Query != "" && req.Index != "" && req.From == 0 &&
+		len(req.Fields) == 0 && req.DefaultField == "" && len(req.Sort) == 0 && len(req.Filters) == 0 &&
+		len(req.Scopes) == 0 && req.Strategy == nil && req.KNN == nil && req.Collapse == nil &&
+		req.Relation == nil && req.FunctionScore == nil && req.PIT == nil && len(req.SearchAfter) == 0 &&
+		len(req.Aggregations) == 0 && !req.AggregationsOnly
+}
+
+func (t *queryFrequencyTracker) track(req *models.SearchRequest, now time.Time) {
+	key := queryKey{index: req.Index, query: req.Query, queryType: req.QueryType, size: req.Size}
+	start := now.Truncate(frequencyBucketWidth)
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	var bucket *frequencyBucket
+	if n := len(t.buckets); n > 0 && t.buckets[n-1].start.Equal(start) {
+		bucket = t.buckets[n-1]
+	} else {
+		bucket = &frequencyBucket{start: start, counts: make(map[queryKey]int64)}
+		t.buckets = append(t.buckets, bucket)
+		t.expire(now)
+	}
+
+	// Bound memory under a flood of unique queries; those are not worth warming anyway
+	if _, exists := bucket.counts[key]; !exists && len(bucket.counts) >= maxTrackedQueriesPerSlot {
+		return
+	}
+	bucket.counts[key]++
+}
+
+// expire drops buckets older than the retention; the caller holds the lock
+func (t *queryFrequencyTracker) expire(now time.Time) {
+	cutoff := now.Add(-frequencyRetention)
+	i := 0
+	for i < len(t.buckets) && t.buckets[i].start.Before(cutoff) {
+		i++
+	}
+	t.buckets = t.buckets[i:]
+}
+
+// top returns the most frequent searches over the window, most frequent first
+func (t *queryFrequencyTracker) top(window time.Duration, limit int, now time.Time) []PopularQuery {
+	if window > frequencyRetention {
+		window = frequencyRetention
+	}
+	cutoff := now.Add(-window).Truncate(frequencyBucketWidth)
+
+	t.mu.Lock()
+	totals := make(map[queryKey]int64)
+	for _, bucket := range t.buckets {
+		if bucket.start.Before(cutoff) {
+			continue
+		}
+		for key, count := range bucket.counts {
+			totals[key] += count
+		}
+	}
+	t.mu.Unlock()
+
+	queries := make([]PopularQuery, 0, len(totals))
+	for key, count := range totals {
+		queries = append(queries, PopularQuery{
+			Index:     key.index,
+			Query:     key.query,
+			QueryType: key.queryType,
+			Size:      key.size,
+			Count:     count,
+		})
+	}
+
+	sort.Slice(queries, func(i, j int) bool {
+		if queries[i].Count != queries[j].Count {
+			return queries[i].Count > queries[j].Count
+		}
+		if queries[i].Index != queries[j].Index {
+			return queries[i].Index < queries[j].Index
+		}
+		return queries[i].Query < queries[j].Query
+	})
+
+	if limit > 0 && len(queries) > limit {
+		queries = queries[:limit]
+	}
+	return queries
+}
@@ -0,0 +1,139 @@
+package realtime
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"math/rand"
+	"sync"
+	"time"
+
+	"github.com/saif-islam/es-playground/projects/search-api/internal/models"
+)
+
+// Query modes for the live analytics stream
+const (
+	QueryModeRaw      = "raw"
+	QueryModeHash     = "hash"
+	QueryModeTruncate = "truncate"
+	QueryModeDrop     = "drop"
+)
+
+const defaultTruncateLength = 32
+
+// ValidateQueryMode checks the configured query mode; empty selects raw
+func ValidateQueryMode(mode string) error {
+	switch mode {
+	case "", QueryModeRaw, QueryModeHash, QueryModeTruncate, QueryModeDrop:
+		return nil
+	}
+	return fmt.Errorf("invalid query_mode %q (must be raw, hash, truncate or drop)", mode)
+}
+
+// QueryAnonymizer rewrites query text before it reaches buffers, patterns, alerts, clients
+// or the persisted search analytics
+type QueryAnonymizer struct {
+	mode           string
+	truncateLength int
+	salt           string
+}
+
+// NewQueryAnonymizer creates an anonymizer for a query mode; empty selects raw
+func NewQueryAnonymizer(mode string, truncateLength int, salt string) *QueryAnonymizer {
+	if mode == "" {
+		mode = QueryModeRaw
+	}
+	if truncateLength <= 0 {
+		truncateLength = defaultTruncateLength
+	}
+
+	return &QueryAnonymizer{
+		mode:           mode,
+		truncateLength: truncateLength,
+		salt:           salt,
+	}
+}
+
+// Anonymize returns the query as the configured mode allows it to be shown. Hashes are
+// stable, so identical queries still group together in the top queries.
+func (a *QueryAnonymizer) Anonymize(query string) string {
+	if query == "" {
+		return query
+	}
+
+	switch a.mode {
+	case QueryModeHash:
+		sum := sha256.Sum256([]byte(a.salt + query))
+		return "sha256:" + hex.EncodeToString(sum[:8])
+	case QueryModeTruncate:
+		runes := []rune(query)
+		if len(runes) > a.truncateLength {
+			return string(runes[:a.truncateLength]) + "..."
+		}
+		return query
+	case QueryModeRaw:
+		return query
+	default:
+		// Drop mode, and any mode that slipped past ValidateQueryMode: never leak the text
+		return ""
+	}
+}
+
+// eventSampler decides which search events are broadcast individually. Aggregated metrics
+// are still computed from every event, so sampling only thins the live event feed.
+type eventSampler struct {
+	rate      float64
+	maxPerSec int
+
+	mu          sync.Mutex
+	random      *rand.Rand
+	window      time.Time
+	windowCount int
+	dropped     int64
+}
+
+func newEventSampler(config models.RealtimeConfig) *eventSampler {
+	rate := config.SampleRate
+	if rate <= 0 || rate > 1 {
+		rate = 1
+	}
+
+	return &eventSampler{
+		rate:      rate,
+		maxPerSec: config.MaxEventsPerSecond,
+		random:    rand.New(rand.NewSource(time.Now().UnixNano())),
+	}
+}
+
+// allow reports whether an event seen at the given time should be broadcast
+func (s *eventSampler) allow(now time.Time) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.rate < 1 && s.random.Float64() >= s.rate {
+		s.dropped++
+		return false
+	}
+
+	if s.maxPerSec > 0 {
+		second := now.Truncate(time.Second)
+		if !second.Equal(s.window) {
+			s.window = second
+			s.windowCount = 0
+		}
+		if s.windowCount >= s.maxPerSec {
+			s.dropped++
+			return false
+		}
+		s.windowCount++
+	}
+
+	return true
+}
+
+// droppedEvents returns how many search events have not been broadcast so far
+func (s *eventSampler) droppedEvents() int64 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.dropped
+}
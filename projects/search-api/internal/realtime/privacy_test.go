@@ -0,0 +1,64 @@
+package realtime
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/saif-islam/es-playground/projects/search-api/internal/models"
+)
+
+func TestQueryAnonymizer(t *testing.T) {
+	query := "where can I buy a refurbished laptop near me"
+
+	tests := []struct {
+		name     string
+		config   models.RealtimeConfig
+		query    string
+		expected string
+	}{
+		{name: "default is raw", config: models.RealtimeConfig{}, query: query, expected: query},
+		{name: "raw", config: models.RealtimeConfig{QueryMode: QueryModeRaw}, query: query, expected: query},
+		{name: "truncate", config: models.RealtimeConfig{QueryMode: QueryModeTruncate, TruncateLength: 9}, query: query, expected: "where can..."},
+		{name: "truncate short query", config: models.RealtimeConfig{QueryMode: QueryModeTruncate}, query: "laptop", expected: "laptop"},
+		{name: "drop", config: models.RealtimeConfig{QueryMode: QueryModeDrop}, query: query, expected: ""},
+		{name: "unknown mode drops", config: models.RealtimeConfig{QueryMode: "hashed"}, query: query, expected: ""},
+		{name: "empty query", config: models.RealtimeConfig{QueryMode: QueryModeHash}, query: "", expected: ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := NewQueryAnonymizer(tt.config.QueryMode, tt.config.TruncateLength, tt.config.HashSalt).Anonymize(tt.query); got != tt.expected {
+				t.Errorf("Expected %q, got %q", tt.expected, got)
+			}
+		})
+	}
+}
+
+func TestQueryAnonymizerHash(t *testing.T) {
+	salted := NewQueryAnonymizer(QueryModeHash, 0, "pepper")
+	unsalted := NewQueryAnonymizer(QueryModeHash, 0, "")
+
+	hash := salted.Anonymize("laptop")
+	if !strings.HasPrefix(hash, "sha256:") || strings.Contains(hash, "laptop") {
+		t.Errorf("Expected a sha256 hash without the query text, got %q", hash)
+	}
+	if salted.Anonymize("laptop") != hash {
+		t.Error("Expected identical queries to hash identically")
+	}
+	if unsalted.Anonymize("laptop") == hash {
+		t.Error("Expected the salt to change the hash")
+	}
+}
+
+func TestValidateQueryMode(t *testing.T) {
+	for _, mode := range []string{"", QueryModeRaw, QueryModeHash, QueryModeTruncate, QueryModeDrop} {
+		if err := ValidateQueryMode(mode); err != nil {
+			t.Errorf("Expected %q to be valid, got %v", mode, err)
+		}
+	}
+	for _, mode := range []string{"hashed", "RAW", "none"} {
+		if err := ValidateQueryMode(mode); err == nil {
+			t.Errorf("Expected %q to be rejected", mode)
+		}
+	}
+}
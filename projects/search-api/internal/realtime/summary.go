@@ -0,0 +1,222 @@
+package realtime
+
+import (
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/saif-islam/es-playground/projects/search-api/internal/models"
+)
+
+// Search summaries are aggregated in per-minute buckets kept for an hour
+const (
+	summaryBucketWidth       = time.Minute
+	summaryRetention         = time.Hour
+	defaultSummaryWindow     = 15 * time.Minute
+	maxSummaryQueriesPerSlot = 1000
+	summaryTopQueries        = 10
+)
+
+// SearchSummary aggregates the searches recorded within a window, optionally for one index
+type SearchSummary struct {
+	Index           string         `json:"index,omitempty"`
+	Window          string         `json:"window"`
+	From            time.Time      `json:"from"`
+	To              time.Time      `json:"to"`
+	TotalSearches   int64          `json:"total_searches"`
+	SearchesPerSec  float64        `json:"searches_per_sec"`
+	AvgResponseTime float64        `json:"avg_response_time_ms"`
+	ErrorRate       float64        `json:"error_rate_percent"`
+	ZeroResultRate  float64        `json:"zero_result_rate_percent"` // of successful searches
+	CacheHitRate    float64        `json:"cache_hit_rate_percent"`
+	TopQueries      []SummaryQuery `json:"top_queries"`
+}
+
+// SummaryQuery is a query and how often it ran within a summary's window
+type SummaryQuery struct {
+	Query string `json:"query"`
+	Count int64  `json:"count"`
+}
+
+// searchAggregate holds running totals for one index within one bucket
+type searchAggregate struct {
+	searches    int64
+	errors      int64
+	zeroResults int64
+	cacheHits   int64
+	totalTime   time.Duration
+	queries     map[string]int64
+}
+
+type summaryBucket struct {
+	start   time.Time
+	indices map[string]*searchAggregate
+}
+
+// searchSummaryTracker accumulates search events into per-minute, per-index aggregates,
+// so summaries over any window up to an hour don't depend on the size of the event buffer
+type searchSummaryTracker struct {
+	mu      sync.Mutex
+	buckets []*summaryBucket
+}
+
+func newSearchSummaryTracker() *searchSummaryTracker {
+	return &searchSummaryTracker{}
+}
+
+func (t *searchSummaryTracker) record(event SearchEvent) {
+	if event.Timestamp.IsZero() {
+		event.Timestamp = time.Now()
+	}
+	start := event.Timestamp.Truncate(summaryBucketWidth)
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	var bucket *summaryBucket
+	if n := len(t.buckets); n > 0 && !t.buckets[n-1].start.Before(start) {
+		// Events can arrive slightly out of order; count them in the newest bucket
+		bucket = t.buckets[n-1]
+	} else {
+		bucket = &summaryBucket{start: start, indices: make(map[string]*searchAggregate)}
+		t.buckets = append(t.buckets, bucket)
+		t.expire(event.Timestamp)
+	}
+
+	aggregate, exists := bucket.indices[event.Index]
+	if !exists {
+		aggregate = &searchAggregate{queries: make(map[string]int64)}
+		bucket.indices[event.Index] = aggregate
+	}
+
+	aggregate.searches++
+	aggregate.totalTime += event.ResponseTime
+	if !event.Success {
+		aggregate.errors++
+	} else if event.ResultCount == 0 {
+		aggregate.zeroResults++
+	}
+	if event.CacheHit {
+		aggregate.cacheHits++
+	}
+
+	// Bound memory under a flood of unique queries
+	if event.Query != "" {
+		if _, seen := aggregate.queries[event.Query]; seen || len(aggregate.queries) < maxSummaryQueriesPerSlot {
+			aggregate.queries[event.Query]++
+		}
+	}
+}
+
+// expire drops buckets older than the retention; the caller holds the lock
+func (t *searchSummaryTracker) expire(now time.Time) {
+	cutoff := now.Add(-summaryRetention)
+	i := 0
+	for i < len(t.buckets) && t.buckets[i].start.Before(cutoff) {
+		i++
+	}
+	t.buckets = t.buckets[i:]
+}
+
+// summary combines the buckets within the window, for one index or all of them when index
+// is empty. The window is capped at the retention.
+func (t *searchSummaryTracker) summary(index string, window time.Duration, now time.Time) SearchSummary {
+	if window > summaryRetention {
+		window = summaryRetention
+	}
+	from := now.Add(-window)
+	cutoff := from.Truncate(summaryBucketWidth)
+
+	var total searchAggregate
+	queries := make(map[string]int64)
+
+	t.mu.Lock()
+	for _, bucket := range t.buckets {
+		if bucket.start.Before(cutoff) {
+			continue
+		}
+		for name, aggregate := range bucket.indices {
+			if index != "" && name != index {
+				continue
+			}
+			total.searches += aggregate.searches
+			total.errors += aggregate.errors
+			total.zeroResults += aggregate.zeroResults
+			total.cacheHits += aggregate.cacheHits
+			total.totalTime += aggregate.totalTime
+			for query, count := range aggregate.queries {
+				queries[query] += count
+			}
+		}
+	}
+	t.mu.Unlock()
+
+	summary := SearchSummary{
+		Index:         index,
+		Window:        window.String(),
+		From:          from,
+		To:            now,
+		TotalSearches: total.searches,
+		TopQueries:    topSummaryQueries(queries, summaryTopQueries),
+	}
+	if total.searches > 0 {
+		summary.SearchesPerSec = float64(total.searches) / window.Seconds()
+		summary.AvgResponseTime = float64(total.totalTime.Milliseconds()) / float64(total.searches)
+		summary.ErrorRate = float64(total.errors) / float64(total.searches) * 100
+		summary.CacheHitRate = float64(total.cacheHits) / float64(total.searches) * 100
+	}
+	if successful := total.searches - total.errors; successful > 0 {
+		summary.ZeroResultRate = float64(total.zeroResults) / float64(successful) * 100
+	}
+	return summary
+}
+
+// topSummaryQueries returns the most frequent queries, most frequent first
+func topSummaryQueries(counts map[string]int64, limit int) []SummaryQuery {
+	queries := make([]SummaryQuery, 0, len(counts))
+	for query, count := range counts {
+		queries = append(queries, SummaryQuery{Query: query, Count: count})
+	}
+
+	sort.Slice(queries, func(i, j int) bool {
+		if queries[i].Count != queries[j].Count {
+			return queries[i].Count > queries[j].Count
+		}
+		return queries[i].Query < queries[j].Query
+	})
+
+	if len(queries) > limit {
+		queries = queries[:limit]
+	}
+	return queries
+}
+
+// Summary returns aggregate search stats over the window, capped at an hour, for one index
+// or all of them when index is empty
+func (h *AnalyticsHub) Summary(index string, window time.Duration) SearchSummary {
+	return h.searchSummaries.summary(index, window, time.Now())
+}
+
+// HandleSummary handles GET /api/search/analytics/summary?index=...&window=15m, serving the
+// same aggregates as the stream to clients that don't hold a WebSocket open
+func (h *AnalyticsHub) HandleSummary(c *gin.Context) {
+	window := defaultSummaryWindow
+	if value := c.Query("window"); value != "" {
+		parsed, err := time.ParseDuration(value)
+		if err != nil || parsed <= 0 || parsed > summaryRetention {
+			c.JSON(http.StatusBadRequest, models.ErrorResponse{
+				Error:     "invalid_parameters",
+				Message:   "window must be a positive duration up to " + summaryRetention.String() + ", e.g. 15m",
+				RequestID: c.GetString("request_id"),
+				Timestamp: time.Now(),
+			})
+			return
+		}
+		window = parsed
+	}
+
+	c.JSON(http.StatusOK, h.Summary(c.Query("index"), window))
+}
@@ -0,0 +1,128 @@
+package realtime
+
+import (
+	"encoding/json"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/saif-islam/es-playground/projects/search-api/internal/models"
+)
+
+// At most this many distinct zero-result searches are kept; the least recently seen is
+// dropped to make room for a new one
+const maxZeroResultQueries = 1000
+
+// ZeroResultQuery is a search that matched no documents and how often it ran
+type ZeroResultQuery struct {
+	Index   string          `json:"index"`
+	Query   string          `json:"query"`
+	Field   string          `json:"field,omitempty"`
+	Filters []models.Filter `json:"filters,omitempty"`
+	Count   int64           `json:"count"`
+	// Corrections suggested from the index's terms, when the query text is kept raw
+	Suggestions []string  `json:"suggestions,omitempty"`
+	FirstSeen   time.Time `json:"first_seen"`
+	LastSeen    time.Time `json:"last_seen"`
+}
+
+// zeroResultTracker keeps a capped list of searches that found nothing, grouped by index,
+// query text and filters
+type zeroResultTracker struct {
+	mu      sync.Mutex
+	queries map[string]*ZeroResultQuery
+}
+
+func newZeroResultTracker() *zeroResultTracker {
+	return &zeroResultTracker{queries: make(map[string]*ZeroResultQuery)}
+}
+
+func (t *zeroResultTracker) record(entry ZeroResultQuery, now time.Time) {
+	signature, _ := json.Marshal([]interface{}{entry.Index, entry.Query, entry.Filters})
+	key := string(signature)
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if existing, ok := t.queries[key]; ok {
+		existing.Count++
+		existing.LastSeen = now
+		return
+	}
+
+	if len(t.queries) >= maxZeroResultQueries {
+		t.evictLeastRecent()
+	}
+	entry.Count = 1
+	entry.FirstSeen = now
+	entry.LastSeen = now
+	t.queries[key] = &entry
+}
+
+// evictLeastRecent drops the entry seen longest ago; the caller holds the lock
+func (t *zeroResultTracker) evictLeastRecent() {
+	var oldestKey string
+	var oldest time.Time
+	for key, entry := range t.queries {
+		if oldestKey == "" || entry.LastSeen.Before(oldest) {
+			oldestKey, oldest = key, entry.LastSeen
+		}
+	}
+	delete(t.queries, oldestKey)
+}
+
+// top returns the most frequent zero-result searches, most frequent first
+func (t *zeroResultTracker) top(limit int) []ZeroResultQuery {
+	t.mu.Lock()
+	queries := make([]ZeroResultQuery, 0, len(t.queries))
+	for _, entry := range t.queries {
+		queries = append(queries, *entry)
+	}
+	t.mu.Unlock()
+
+	sort.Slice(queries, func(i, j int) bool {
+		if queries[i].Count != queries[j].Count {
+			return queries[i].Count > queries[j].Count
+		}
+		return queries[i].LastSeen.After(queries[j].LastSeen)
+	})
+
+	if limit > 0 && len(queries) > limit {
+		queries = queries[:limit]
+	}
+	return queries
+}
+
+// RecordZeroResult remembers a search that matched nothing. The query text is anonymized
+// like the rest of the analytics, and filters are only kept while queries are shown raw.
+func (h *AnalyticsHub) RecordZeroResult(req *models.SearchRequest) {
+	query := h.anonymizer.Anonymize(req.Query)
+	if query == "" {
+		return
+	}
+
+	entry := ZeroResultQuery{
+		Index: req.Index,
+		Query: query,
+		Field: req.DefaultField,
+	}
+	if entry.Field == "" && len(req.Fields) > 0 {
+		entry.Field, _, _ = strings.Cut(req.Fields[0], "^") // without its boost
+	}
+	if h.RawQueries() {
+		entry.Filters = req.Filters
+	}
+
+	h.zeroResults.record(entry, time.Now())
+}
+
+// ZeroResultQueries returns the most frequent searches that matched nothing
+func (h *AnalyticsHub) ZeroResultQueries(limit int) []ZeroResultQuery {
+	return h.zeroResults.top(limit)
+}
+
+// RawQueries reports whether analytics keep query text as it was searched
+func (h *AnalyticsHub) RawQueries() bool {
+	return h.anonymizer.mode == QueryModeRaw
+}
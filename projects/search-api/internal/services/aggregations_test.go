@@ -0,0 +1,187 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"reflect"
+	"testing"
+
+	"github.com/saif-islam/es-playground/projects/search-api/internal/models"
+)
+
+// aggregationsAPIResponse follows POST /products/_search with size 0 and cardinality,
+// range, date_histogram and avg_bucket aggregations
+const aggregationsAPIResponse = `{
+  "took": 12,
+  "timed_out": false,
+  "_shards": {"total": 1, "successful": 1, "skipped": 0, "failed": 0},
+  "hits": {"total": {"value": 184, "relation": "eq"}, "max_score": null, "hits": []},
+  "aggregations": {
+    "brands": {"value": 23},
+    "price_bands": {
+      "buckets": [
+        {"key": "budget", "to": 100.0, "doc_count": 61},
+        {"key": "premium", "from": 100.0, "doc_count": 123}
+      ]
+    },
+    "per_month": {
+      "buckets": [
+        {"key_as_string": "2024-01-01", "key": 1704067200000, "doc_count": 90, "sales": {"value": 41200.5}},
+        {"key_as_string": "2024-02-01", "key": 1706745600000, "doc_count": 94, "sales": {"value": 38100.0}}
+      ]
+    },
+    "avg_monthly_sales": {"value": 39650.25}
+  }
+}`
+
+func TestSearch_Aggregations(t *testing.T) {
+	tests := []struct {
+		name          string
+		req           models.SearchRequest
+		expectedAggs  string // aggs sent to Elasticsearch
+		expectedSize  float64
+		expectedError error
+	}{
+		{
+			name: "metric, bucket and pipeline aggregations only",
+			req: models.SearchRequest{
+				Query: "laptop", QueryType: "match", Fields: []string{"title"}, Size: 10, From: 20,
+				Sort:             []models.SortField{{Field: "price", Order: "asc"}},
+				AggregationsOnly: true,
+				Aggregations: map[string]models.AggregationConfig{
+					"brands": {Type: "cardinality", Field: "brand", Settings: map[string]interface{}{"precision_threshold": 1000}},
+					"price_bands": {Type: "range", Field: "price", Ranges: []models.AggregationRange{
+						{Key: "budget", To: 100},
+						{Key: "premium", From: 100},
+					}},
+					"per_month": {Type: "date_histogram", Field: "created_at",
+						Settings: map[string]interface{}{"calendar_interval": "month"},
+						SubAggs: map[string]models.AggregationConfig{
+							"sales": {Type: "sum", Field: "price"},
+						}},
+					"avg_monthly_sales": {Type: "avg_bucket", BucketsPath: "per_month>sales"},
+				},
+			},
+			expectedAggs: `{
+				"avg_monthly_sales": {"avg_bucket": {"buckets_path": "per_month>sales"}},
+				"brands": {"cardinality": {"field": "brand", "precision_threshold": 1000}},
+				"per_month": {
+					"date_histogram": {"field": "created_at", "calendar_interval": "month"},
+					"aggs": {"sales": {"sum": {"field": "price"}}}
+				},
+				"price_bands": {"range": {"field": "price", "ranges": [{"key": "budget", "to": 100}, {"key": "premium", "from": 100}]}}
+			}`,
+			expectedSize: 0,
+		},
+		{
+			name: "filters and nested aggregations alongside hits",
+			req: models.SearchRequest{
+				Query: "laptop", QueryType: "match", Fields: []string{"title"}, Size: 10,
+				Aggregations: map[string]models.AggregationConfig{
+					"stock": {Type: "filters", Filters: map[string][]models.Filter{
+						"in_stock": {{Field: "in_stock", Value: true, Type: "term"}},
+					}},
+					"variants": {Type: "nested", Path: "variants", SubAggs: map[string]models.AggregationConfig{
+						"colors": {Type: "terms", Field: "variants.color", Size: 5},
+						"top":    {Type: "bucket_sort", Size: 3, Settings: map[string]interface{}{"sort": []interface{}{map[string]interface{}{"_count": "desc"}}}},
+					}},
+				},
+			},
+			expectedAggs: `{
+				"stock": {"filters": {"filters": {"in_stock": {"term": {"in_stock": true}}}}},
+				"variants": {
+					"nested": {"path": "variants"},
+					"aggs": {
+						"colors": {"terms": {"field": "variants.color", "size": 5}},
+						"top": {"bucket_sort": {"size": 3, "sort": [{"_count": "desc"}]}}
+					}
+				}
+			}`,
+			expectedSize: 10,
+		},
+		{
+			name: "aggregations only without aggregations",
+			req: models.SearchRequest{
+				Query: "laptop", QueryType: "match", Fields: []string{"title"}, Size: 10, AggregationsOnly: true,
+			},
+			expectedError: ErrInvalidQuery,
+		},
+		{
+			name: "range without ranges",
+			req: models.SearchRequest{
+				Query: "laptop", QueryType: "match", Fields: []string{"title"}, Size: 10,
+				Aggregations: map[string]models.AggregationConfig{"price_bands": {Type: "range", Field: "price"}},
+			},
+			expectedError: ErrInvalidQuery,
+		},
+		{
+			name: "sub-aggregation pipeline without buckets_path",
+			req: models.SearchRequest{
+				Query: "laptop", QueryType: "match", Fields: []string{"title"}, Size: 10,
+				Aggregations: map[string]models.AggregationConfig{
+					"per_month": {Type: "date_histogram", Field: "created_at", SubAggs: map[string]models.AggregationConfig{
+						"growth": {Type: "derivative"},
+					}},
+				},
+			},
+			expectedError: ErrInvalidQuery,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var sent map[string]interface{}
+			s := newFakeESService(t, func(w http.ResponseWriter, r *http.Request) {
+				body, _ := io.ReadAll(r.Body)
+				json.Unmarshal(body, &sent)
+				w.Write([]byte(aggregationsAPIResponse))
+			})
+
+			req := tt.req
+			req.Index = "products"
+			response, err := s.Search(context.Background(), &req)
+			if tt.expectedError != nil {
+				if !errors.Is(err, tt.expectedError) {
+					t.Fatalf("Expected %v, got %v", tt.expectedError, err)
+				}
+				if sent != nil {
+					t.Errorf("Expected nothing sent, got %v", sent)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+
+			var expectedAggs map[string]interface{}
+			if err := json.Unmarshal([]byte(tt.expectedAggs), &expectedAggs); err != nil {
+				t.Fatalf("Invalid expected aggregations: %v", err)
+			}
+			if !reflect.DeepEqual(sent["aggs"], expectedAggs) {
+				got, _ := json.Marshal(sent["aggs"])
+				t.Errorf("Expected aggs %s, got %s", tt.expectedAggs, got)
+			}
+			if sent["size"] != tt.expectedSize {
+				t.Errorf("Expected size %v, got %v", tt.expectedSize, sent["size"])
+			}
+			if req.AggregationsOnly {
+				for _, key := range []string{"from", "sort", "highlight", "_source"} {
+					if _, ok := sent[key]; ok {
+						t.Errorf("Expected no %s in an aggregation-only search, got %v", key, sent[key])
+					}
+				}
+			}
+
+			if response.Total.Value != 184 || len(response.Hits) != 0 {
+				t.Errorf("Expected 184 matches and no hits, got %d and %d hits", response.Total.Value, len(response.Hits))
+			}
+			avg, _ := response.Aggregations["avg_monthly_sales"].(map[string]interface{})
+			if avg["value"] != 39650.25 {
+				t.Errorf("Expected the pipeline result passed through, got %v", response.Aggregations["avg_monthly_sales"])
+			}
+		})
+	}
+}
@@ -0,0 +1,201 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/elastic/go-elasticsearch/v8/esapi"
+	"go.uber.org/zap"
+
+	"github.com/saif-islam/es-playground/projects/search-api/internal/models"
+)
+
+const (
+	// defaultAsyncWaitForCompletion is how long a submit blocks before handing back an ID
+	defaultAsyncWaitForCompletion = time.Second
+	// defaultAsyncKeepAlive is how long async search results are retained
+	defaultAsyncKeepAlive = 5 * time.Minute
+	// asyncSubmitHeadroom is left between wait_for_completion_timeout and the request
+	// timeout, for Elasticsearch to answer and the response to arrive
+	asyncSubmitHeadroom = time.Second
+)
+
+// SetRequestTimeout sets the per-request Elasticsearch timeout, which an async submit's
+// wait_for_completion_timeout is kept below; zero leaves the wait uncapped
+func (s *SearchService) SetRequestTimeout(timeout time.Duration) {
+	s.requestTimeout = timeout
+}
+
+// SubmitAsync starts a search in the background. If it finishes within the wait timeout
+// the final results are returned directly; otherwise the response carries an ID that
+// can be polled with GetAsyncResult for partial and final results.
+func (s *SearchService) SubmitAsync(ctx context.Context, req *models.AsyncSearchRequest) (*models.AsyncSearchResponse, error) {
+	waitForCompletion, err := s.asyncWaitForCompletion(req.WaitForCompletion)
+	if err != nil {
+		return nil, err
+	}
+	keepAlive, err := parseAsyncDuration(req.KeepAlive, defaultAsyncKeepAlive)
+	if err != nil || keepAlive <= 0 {
+		return nil, fmt.Errorf("%w: keep_alive %q is not a positive duration such as 5m", ErrInvalidQuery, req.KeepAlive)
+	}
+
+	if s.costGuard != nil {
+		// Running in the background does not make an expensive query any cheaper for the cluster
+		if _, err := s.costGuard.Check(&req.SearchRequest); err != nil {
+			return nil, err
+		}
+	}
+
+	query, err := s.buildElasticsearchQuery(&req.SearchRequest)
+	if err != nil {
+		return nil, fmt.Errorf("failed to build query: %w", err)
+	}
+
+	keepOnCompletion := true
+	submitReq := esapi.AsyncSearchSubmitRequest{
+		Index:                    []string{req.Index},
+		Body:                     strings.NewReader(query),
+		WaitForCompletionTimeout: waitForCompletion,
+		KeepAlive:                keepAlive,
+		KeepOnCompletion:         &keepOnCompletion,
+	}
+
+	res, err := submitReq.Do(ctx, s.esClient)
+	if err != nil {
+		return nil, fmt.Errorf("async search submit failed: %w", err)
+	}
+	defer res.Body.Close()
+
+	if res.IsError() {
+		return nil, fmt.Errorf("async search submit failed: %s", res.String())
+	}
+
+	response, err := s.decodeAsyncSearch(res, &req.SearchRequest)
+	if err != nil {
+		return nil, err
+	}
+
+	s.logger.Info("Submitted async search",
+		zap.String("id", response.ID),
+		zap.String("index", req.Index),
+		zap.Bool("is_running", response.IsRunning))
+
+	return response, nil
+}
+
+// GetAsyncResult returns the current state of an async search, with partial
+// results while it is still running. Scopes are those of the caller fetching the results.
+func (s *SearchService) GetAsyncResult(ctx context.Context, id string, scopes []string) (*models.AsyncSearchResponse, error) {
+	getReq := esapi.AsyncSearchGetRequest{
+		DocumentID: id,
+	}
+
+	res, err := getReq.Do(ctx, s.esClient)
+	if err != nil {
+		return nil, fmt.Errorf("async search get failed: %w", err)
+	}
+	defer res.Body.Close()
+
+	if res.IsError() {
+		return nil, fmt.Errorf("async search get failed: %s", res.String())
+	}
+
+	return s.decodeAsyncSearch(res, &models.SearchRequest{Scopes: scopes})
+}
+
+// DeleteAsync cancels a running async search or discards its stored results
+func (s *SearchService) DeleteAsync(ctx context.Context, id string) error {
+	deleteReq := esapi.AsyncSearchDeleteRequest{
+		DocumentID: id,
+	}
+
+	res, err := deleteReq.Do(ctx, s.esClient)
+	if err != nil {
+		return fmt.Errorf("async search delete failed: %w", err)
+	}
+	defer res.Body.Close()
+
+	if res.IsError() {
+		return fmt.Errorf("async search delete failed: %s", res.String())
+	}
+
+	s.logger.Info("Deleted async search", zap.String("id", id))
+	return nil
+}
+
+// decodeAsyncSearch converts an async search response to our format
+func (s *SearchService) decodeAsyncSearch(res *esapi.Response, req *models.SearchRequest) (*models.AsyncSearchResponse, error) {
+	var asyncResp struct {
+		ID                     string                 `json:"id"`
+		IsRunning              bool                   `json:"is_running"`
+		IsPartial              bool                   `json:"is_partial"`
+		StartTimeInMillis      int64                  `json:"start_time_in_millis"`
+		ExpirationTimeInMillis int64                  `json:"expiration_time_in_millis"`
+		Response               map[string]interface{} `json:"response"`
+	}
+	if err := json.NewDecoder(res.Body).Decode(&asyncResp); err != nil {
+		return nil, fmt.Errorf("failed to parse async search response: %w", err)
+	}
+
+	response := &models.AsyncSearchResponse{
+		ID:             asyncResp.ID,
+		IsRunning:      asyncResp.IsRunning,
+		IsPartial:      asyncResp.IsPartial,
+		StartTime:      time.UnixMilli(asyncResp.StartTimeInMillis),
+		ExpirationTime: time.UnixMilli(asyncResp.ExpirationTimeInMillis),
+		Timestamp:      time.Now(),
+	}
+
+	if asyncResp.Response != nil {
+		response.Result = s.transformSearchResponse(asyncResp.Response, req)
+		response.Progress = asyncSearchProgress(response.Result.Shards)
+	}
+
+	return response, nil
+}
+
+// asyncWaitForCompletion parses an optional wait_for_completion_timeout. A wait the
+// request timeout would cut short is lowered to end before it, so the submit returns an
+// ID to poll instead of failing.
+func (s *SearchService) asyncWaitForCompletion(value string) (time.Duration, error) {
+	wait, err := parseAsyncDuration(value, defaultAsyncWaitForCompletion)
+	if err != nil || wait <= 0 {
+		return 0, fmt.Errorf("%w: wait_for_completion_timeout %q is not a positive duration such as 1s", ErrInvalidQuery, value)
+	}
+	if s.requestTimeout <= 0 {
+		return wait, nil
+	}
+
+	limit := s.requestTimeout - asyncSubmitHeadroom
+	if limit <= 0 {
+		limit = s.requestTimeout / 2
+	}
+	if wait > limit {
+		s.logger.Debug("Lowered wait_for_completion_timeout below the request timeout",
+			zap.Duration("requested", wait),
+			zap.Duration("wait_for_completion_timeout", limit),
+			zap.Duration("request_timeout", s.requestTimeout))
+		wait = limit
+	}
+	return wait, nil
+}
+
+// asyncSearchProgress returns the percentage of shards that have reported results
+func asyncSearchProgress(shards models.ShardInfo) float64 {
+	if shards.Total == 0 {
+		return 0
+	}
+	done := shards.Successful + shards.Skipped + shards.Failed
+	return float64(done) / float64(shards.Total) * 100.0
+}
+
+// parseAsyncDuration parses an optional duration, falling back to a default
+func parseAsyncDuration(value string, fallback time.Duration) (time.Duration, error) {
+	if value == "" {
+		return fallback, nil
+	}
+	return time.ParseDuration(value)
+}
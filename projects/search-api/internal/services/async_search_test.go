@@ -0,0 +1,243 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/saif-islam/es-playground/projects/search-api/internal/cache"
+	"github.com/saif-islam/es-playground/projects/search-api/internal/models"
+	"github.com/saif-islam/es-playground/projects/search-api/internal/tracing"
+	"github.com/saif-islam/es-playground/shared"
+)
+
+// newFakeESService returns a search service whose client talks to handler as if it were
+// Elasticsearch; the client's startup requests to / are answered for it. Tracing is a no-op
+// and the cache is disabled, so searches always reach handler.
+func newFakeESService(t *testing.T, handler http.HandlerFunc) *SearchService {
+	t.Helper()
+	cacheManager := cache.NewCacheManager(cache.NewRedisCache(nil, models.CacheConfig{}, zap.NewNop()), zap.NewNop())
+	return newFakeESServiceWithCache(t, handler, cacheManager)
+}
+
+// newFakeESServiceWithCache is newFakeESService caching through cacheManager
+func newFakeESServiceWithCache(t *testing.T, handler http.HandlerFunc, cacheManager *cache.CacheManager) *SearchService {
+	t.Helper()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("X-Elastic-Product", "Elasticsearch")
+		w.Header().Set("Content-Type", "application/json")
+		if r.URL.Path == "/" {
+			w.Write([]byte(`{"version":{"number":"8.11.1"}}`))
+			return
+		}
+		handler(w, r)
+	}))
+	t.Cleanup(server.Close)
+
+	client, err := shared.NewESClient(&shared.ESConfig{URLs: []string{server.URL}}, zap.NewNop())
+	if err != nil {
+		t.Fatalf("Failed to create client: %v", err)
+	}
+	provider, _ := tracing.NewTracingProvider(tracing.TracingConfig{MaxTagLength: 1024}, zap.NewNop())
+	return NewSearchService(client, zap.NewNop(), nil, tracing.NewSearchOperationTracer(provider), cacheManager, nil, nil, nil)
+}
+
+// runningAsyncSearch is an async search response with one of four shards reported
+const runningAsyncSearch = `{
+  "id": "FmRldE8zREVEUzA2ZVpUeGs2ejJFUFEaMkZ5QTVrSTZSaVN3WlNFVmtlWHJsdzoxMDc=",
+  "is_partial": true,
+  "is_running": true,
+  "start_time_in_millis": 1700000000000,
+  "expiration_time_in_millis": 1700000300000,
+  "response": {
+    "took": 1122,
+    "timed_out": false,
+    "num_reduce_phases": 0,
+    "_shards": {"total": 4, "successful": 1, "skipped": 0, "failed": 0},
+    "hits": {"total": {"value": 157, "relation": "gte"}, "max_score": null, "hits": []}
+  }
+}`
+
+// completedAsyncSearch is the same search once every shard has reported
+const completedAsyncSearch = `{
+  "id": "FmRldE8zREVEUzA2ZVpUeGs2ejJFUFEaMkZ5QTVrSTZSaVN3WlNFVmtlWHJsdzoxMDc=",
+  "is_partial": false,
+  "is_running": false,
+  "start_time_in_millis": 1700000000000,
+  "expiration_time_in_millis": 1700000300000,
+  "completion_time_in_millis": 1700000060000,
+  "response": {
+    "took": 60000,
+    "timed_out": false,
+    "num_reduce_phases": 3,
+    "_shards": {"total": 4, "successful": 4, "skipped": 0, "failed": 0},
+    "hits": {"total": {"value": 1024, "relation": "eq"}, "max_score": null, "hits": []},
+    "aggregations": {"per_day": {"buckets": [{"key_as_string": "2023-11-14", "key": 1699920000000, "doc_count": 1024}]}}
+  }
+}`
+
+const asyncSearchID = "FmRldE8zREVEUzA2ZVpUeGs2ejJFUFEaMkZ5QTVrSTZSaVN3WlNFVmtlWHJsdzoxMDc="
+
+func TestSubmitAsync(t *testing.T) {
+	var path string
+	var query map[string]string
+	s := newFakeESService(t, func(w http.ResponseWriter, r *http.Request) {
+		path = r.Method + " " + r.URL.Path
+		query = map[string]string{}
+		for key := range r.URL.Query() {
+			query[key] = r.URL.Query().Get(key)
+		}
+		w.Write([]byte(runningAsyncSearch))
+	})
+	s.SetRequestTimeout(10 * time.Second)
+
+	req := &models.AsyncSearchRequest{
+		SearchRequest: models.SearchRequest{
+			Index: "logs-*",
+			Size:  0,
+			Aggregations: map[string]models.AggregationConfig{
+				"per_day": {Type: "date_histogram", Field: "@timestamp", Settings: map[string]interface{}{"calendar_interval": "day"}},
+			},
+		},
+		WaitForCompletion: "2s",
+	}
+
+	response, err := s.SubmitAsync(context.Background(), req)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if path != "POST /logs-*/_async_search" {
+		t.Errorf("Expected POST /logs-*/_async_search, got %s", path)
+	}
+	if query["wait_for_completion_timeout"] != "2000ms" || query["keep_alive"] != "300000ms" || query["keep_on_completion"] != "true" {
+		t.Errorf("Expected a 2s wait, 5m keep alive and kept results, got %v", query)
+	}
+
+	if response.ID != asyncSearchID || !response.IsRunning || !response.IsPartial {
+		t.Errorf("Expected a running partial search with its ID, got %+v", response)
+	}
+	if response.Progress != 25 {
+		t.Errorf("Expected 25%% progress with 1 of 4 shards reported, got %g", response.Progress)
+	}
+	if response.Result == nil || response.Result.Total.Value != 157 || response.Result.Total.Relation != "gte" {
+		t.Errorf("Expected partial results for 157+ hits, got %+v", response.Result)
+	}
+	if !response.ExpirationTime.Equal(time.UnixMilli(1700000300000)) {
+		t.Errorf("Expected the expiration time from Elasticsearch, got %v", response.ExpirationTime)
+	}
+}
+
+func TestSubmitAsync_WaitForCompletion(t *testing.T) {
+	tests := []struct {
+		name           string
+		requestTimeout time.Duration
+		wait           string
+		keepAlive      string
+		expected       string // wait_for_completion_timeout sent to Elasticsearch
+		expectError    bool
+	}{
+		{name: "default", requestTimeout: 10 * time.Second, expected: "1000ms"},
+		{name: "within the request timeout", requestTimeout: 10 * time.Second, wait: "5s", expected: "5000ms"},
+		{name: "millisecond wait", requestTimeout: 10 * time.Second, wait: "1ms", expected: "1ms"},
+		{name: "clamped below the request timeout", requestTimeout: 10 * time.Second, wait: "1m", expected: "9000ms"},
+		{name: "equal to the request timeout", requestTimeout: 10 * time.Second, wait: "10s", expected: "9000ms"},
+		{name: "short request timeout", requestTimeout: 800 * time.Millisecond, wait: "5s", expected: "400ms"},
+		{name: "no request timeout", wait: "1m", expected: "60000ms"},
+		{name: "not a duration", requestTimeout: 10 * time.Second, wait: "soon", expectError: true},
+		{name: "zero", requestTimeout: 10 * time.Second, wait: "0s", expectError: true},
+		{name: "negative", requestTimeout: 10 * time.Second, wait: "-1s", expectError: true},
+		{name: "bad keep alive", requestTimeout: 10 * time.Second, keepAlive: "0s", expectError: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var sent string
+			s := newFakeESService(t, func(w http.ResponseWriter, r *http.Request) {
+				sent = r.URL.Query().Get("wait_for_completion_timeout")
+				w.Write([]byte(runningAsyncSearch))
+			})
+			s.SetRequestTimeout(tt.requestTimeout)
+
+			_, err := s.SubmitAsync(context.Background(), &models.AsyncSearchRequest{
+				SearchRequest:     models.SearchRequest{Index: "logs-*", Query: "error"},
+				WaitForCompletion: tt.wait,
+				KeepAlive:         tt.keepAlive,
+			})
+			if tt.expectError {
+				if !errors.Is(err, ErrInvalidQuery) {
+					t.Errorf("Expected ErrInvalidQuery, got %v", err)
+				}
+				if sent != "" {
+					t.Errorf("Expected an invalid submit not to reach Elasticsearch")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if sent != tt.expected {
+				t.Errorf("Expected wait_for_completion_timeout %s, got %s", tt.expected, sent)
+			}
+		})
+	}
+}
+
+func TestGetAsyncResult(t *testing.T) {
+	var path string
+	s := newFakeESService(t, func(w http.ResponseWriter, r *http.Request) {
+		path = r.Method + " " + r.URL.Path
+		w.Write([]byte(completedAsyncSearch))
+	})
+
+	response, err := s.GetAsyncResult(context.Background(), asyncSearchID, nil)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if path != "GET /_async_search/"+asyncSearchID {
+		t.Errorf("Expected GET /_async_search/%s, got %s", asyncSearchID, path)
+	}
+	if response.IsRunning || response.IsPartial || response.Progress != 100 {
+		t.Errorf("Expected a complete search, got %+v", response)
+	}
+	if response.Result == nil || response.Result.Total.Value != 1024 || response.Result.Aggregations["per_day"] == nil {
+		t.Errorf("Expected the final hits and aggregations, got %+v", response.Result)
+	}
+}
+
+func TestAsyncSearch_NotFound(t *testing.T) {
+	s := newFakeESService(t, func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+		w.Write([]byte(`{"error":{"root_cause":[{"type":"resource_not_found_exception","reason":"expired"}],"type":"resource_not_found_exception","reason":"expired"},"status":404}`))
+	})
+
+	if _, err := s.GetAsyncResult(context.Background(), "expired", nil); err == nil || !strings.Contains(err.Error(), "resource_not_found_exception") {
+		t.Errorf("Expected the Elasticsearch error for an expired search, got %v", err)
+	}
+	if err := s.DeleteAsync(context.Background(), "expired"); err == nil {
+		t.Error("Expected deleting an expired search to fail")
+	}
+}
+
+func TestDeleteAsync(t *testing.T) {
+	var path string
+	s := newFakeESService(t, func(w http.ResponseWriter, r *http.Request) {
+		path = r.Method + " " + r.URL.Path
+		w.Write([]byte(`{"acknowledged":true}`))
+	})
+
+	if err := s.DeleteAsync(context.Background(), asyncSearchID); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if path != "DELETE /_async_search/"+asyncSearchID {
+		t.Errorf("Expected DELETE /_async_search/%s, got %s", asyncSearchID, path)
+	}
+}
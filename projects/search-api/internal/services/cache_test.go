@@ -0,0 +1,320 @@
+package services
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/redis/go-redis/v9"
+	"go.uber.org/zap"
+
+	"github.com/saif-islam/es-playground/projects/search-api/internal/cache"
+	"github.com/saif-islam/es-playground/projects/search-api/internal/models"
+	"github.com/saif-islam/es-playground/projects/search-api/internal/realtime"
+)
+
+// fakeRedis speaks enough RESP2 to back the search cache: strings, counters, hashes and a
+// single-page SCAN. Commands it does not know are answered with an error, as an old server
+// would answer HELLO.
+type fakeRedis struct {
+	mu     sync.Mutex
+	values map[string]string
+}
+
+// newFakeRedis starts a fakeRedis and returns a client connected to it
+func newFakeRedis(t *testing.T) (*fakeRedis, *redis.Client) {
+	t.Helper()
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Failed to listen: %v", err)
+	}
+	fake := &fakeRedis{values: make(map[string]string)}
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go fake.serve(conn)
+		}
+	}()
+
+	client := redis.NewClient(&redis.Options{Addr: listener.Addr().String(), Protocol: 2, DisableIndentity: true})
+	t.Cleanup(func() {
+		client.Close()
+		listener.Close()
+	})
+	return fake, client
+}
+
+func (f *fakeRedis) serve(conn net.Conn) {
+	defer conn.Close()
+	reader := bufio.NewReader(conn)
+	for {
+		args, err := readCommand(reader)
+		if err != nil {
+			return
+		}
+		if _, err := io.WriteString(conn, f.exec(args)); err != nil {
+			return
+		}
+	}
+}
+
+// readCommand reads one command sent as a RESP array of bulk strings
+func readCommand(reader *bufio.Reader) ([]string, error) {
+	line, err := reader.ReadString('\n')
+	if err != nil {
+		return nil, err
+	}
+	count, err := strconv.Atoi(strings.TrimSpace(strings.TrimPrefix(line, "*")))
+	if err != nil {
+		return nil, err
+	}
+	args := make([]string, count)
+	for i := range args {
+		if line, err = reader.ReadString('\n'); err != nil {
+			return nil, err
+		}
+		size, err := strconv.Atoi(strings.TrimSpace(strings.TrimPrefix(line, "$")))
+		if err != nil {
+			return nil, err
+		}
+		payload := make([]byte, size+2)
+		if _, err := io.ReadFull(reader, payload); err != nil {
+			return nil, err
+		}
+		args[i] = string(payload[:size])
+	}
+	return args, nil
+}
+
+func (f *fakeRedis) exec(args []string) string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	switch strings.ToUpper(args[0]) {
+	case "PING":
+		return "+PONG\r\n"
+	case "GET":
+		value, ok := f.values[args[1]]
+		if !ok {
+			return "$-1\r\n"
+		}
+		return bulkString(value)
+	case "SET":
+		f.values[args[1]] = args[2]
+		return "+OK\r\n"
+	case "EXISTS", "DEL":
+		var count int
+		for _, key := range args[1:] {
+			if _, ok := f.values[key]; ok {
+				count++
+				if strings.EqualFold(args[0], "DEL") {
+					delete(f.values, key)
+				}
+			}
+		}
+		return fmt.Sprintf(":%d\r\n", count)
+	case "INCR", "HINCRBY", "HSET":
+		// Counters and access statistics are not inspected
+		return ":1\r\n"
+	case "SCAN":
+		pattern := "*"
+		for i := 2; i+1 < len(args); i += 2 {
+			if strings.EqualFold(args[i], "MATCH") {
+				pattern = args[i+1]
+			}
+		}
+		var keys []string
+		for key := range f.values {
+			if matched, _ := path.Match(pattern, key); matched {
+				keys = append(keys, key)
+			}
+		}
+		reply := fmt.Sprintf("*2\r\n%s*%d\r\n", bulkString("0"), len(keys))
+		for _, key := range keys {
+			reply += bulkString(key)
+		}
+		return reply
+	default:
+		return "-ERR unknown command '" + args[0] + "'\r\n"
+	}
+}
+
+func bulkString(s string) string {
+	return fmt.Sprintf("$%d\r\n%s\r\n", len(s), s)
+}
+
+// keys returns the stored keys, sorted
+func (f *fakeRedis) keys() []string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	keys := make([]string, 0, len(f.values))
+	for key := range f.values {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// newCachedFakeESService returns a fake Elasticsearch search service caching in a fakeRedis,
+// with cache warming wired to its searches as in main
+func newCachedFakeESService(t *testing.T, handler http.HandlerFunc, config models.CacheConfig) (*SearchService, *fakeRedis) {
+	t.Helper()
+
+	fake, client := newFakeRedis(t)
+	config.Enabled = true
+	cacheManager := cache.NewCacheManager(cache.NewRedisCache(client, config, zap.NewNop()), zap.NewNop())
+	s := newFakeESServiceWithCache(t, handler, cacheManager)
+	cacheManager.SetSearcher(s.Search)
+	return s, fake
+}
+
+func TestInvalidateCache(t *testing.T) {
+	tests := []struct {
+		name             string
+		index            string
+		expectedDeleted  int64
+		expectedSearches []string // indices searched again after invalidating
+	}{
+		{name: "one index", index: "products", expectedDeleted: 2, expectedSearches: []string{"products", "orders,products"}},
+		{name: "wildcard name matches only itself", index: "logs-*", expectedDeleted: 1, expectedSearches: []string{"logs-*"}},
+		{name: "unknown index", index: "missing", expectedDeleted: 0},
+		{name: "every search", expectedDeleted: 5, expectedSearches: []string{"products", "orders,products", "orders", "logs-*", "logs-2023"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var searched []string
+			s, fake := newCachedFakeESService(t, func(w http.ResponseWriter, r *http.Request) {
+				searched = append(searched, strings.TrimSuffix(strings.TrimPrefix(r.URL.Path, "/"), "/_search"))
+				w.Write([]byte(`{"took":3,"hits":{"total":{"value":1,"relation":"eq"},"hits":[{"_index":"products","_id":"1","_score":1.0,"_source":{"title":"Laptop"}}]}}`))
+			}, models.CacheConfig{})
+
+			indices := []string{"products", "orders,products", "orders", "logs-*", "logs-2023"}
+			search := func() {
+				for _, index := range indices {
+					if _, err := s.Search(context.Background(), &models.SearchRequest{Index: index, Size: 10}); err != nil {
+						t.Fatalf("Unexpected error searching %s: %v", index, err)
+					}
+				}
+			}
+
+			search()
+			if len(searched) != len(indices) || len(fake.keys()) != len(indices) {
+				t.Fatalf("Expected each search to reach Elasticsearch once and be cached, got %v and keys %v", searched, fake.keys())
+			}
+
+			deleted, err := s.InvalidateCache(context.Background(), tt.index)
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if deleted != tt.expectedDeleted {
+				t.Errorf("Expected %d cached searches removed, got %d", tt.expectedDeleted, deleted)
+			}
+
+			// Only the invalidated searches go back to Elasticsearch
+			searched = nil
+			search()
+			if strings.Join(searched, " ") != strings.Join(tt.expectedSearches, " ") {
+				t.Errorf("Expected %v to be searched again, got %v", tt.expectedSearches, searched)
+			}
+		})
+	}
+}
+
+func TestWarmCache(t *testing.T) {
+	var searched []string
+	s, _ := newCachedFakeESService(t, func(w http.ResponseWriter, r *http.Request) {
+		index := strings.TrimSuffix(strings.TrimPrefix(r.URL.Path, "/"), "/_search")
+		searched = append(searched, index)
+		if index == "missing" {
+			w.WriteHeader(http.StatusNotFound)
+			w.Write([]byte(`{"error":{"root_cause":[{"type":"index_not_found_exception","reason":"no such index [missing]"}],"type":"index_not_found_exception","reason":"no such index [missing]"},"status":404}`))
+			return
+		}
+		w.Write([]byte(`{"took":3,"hits":{"total":{"value":1,"relation":"eq"},"hits":[{"_index":"` + index + `","_id":"1","_score":1.0,"_source":{"title":"Laptop"}}]}}`))
+	}, models.CacheConfig{Warm: models.CacheWarmConfig{Queries: []models.WarmQuery{
+		{Index: "products", Query: "laptop"},
+		{Index: "orders", Query: "laptop"},
+		{Index: "missing", Query: "laptop"},
+	}}})
+
+	// A search users already ran is not repeated
+	if _, err := s.Search(context.Background(), models.WarmQuery{Index: "products", Query: "laptop"}.SearchRequest()); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	searched = nil
+	summary, err := s.WarmCache(context.Background(), nil)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if summary.Requested != 3 || summary.AlreadyCached != 1 || summary.Warmed != 1 || summary.Failed != 1 {
+		t.Errorf("Expected 1 already cached, 1 warmed and 1 failed of 3, got %+v", summary)
+	}
+	if strings.Join(searched, " ") != "orders missing" {
+		t.Errorf("Expected only the uncached searches to reach Elasticsearch, got %v", searched)
+	}
+
+	// The warmed search is now served from the cache
+	searched = nil
+	response, err := s.Search(context.Background(), models.WarmQuery{Index: "orders", Query: "laptop"}.SearchRequest())
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if !response.CacheHit || len(searched) != 0 {
+		t.Errorf("Expected the warmed search to be a cache hit, got cache hit %v after searching %v", response.CacheHit, searched)
+	}
+}
+
+func TestWarmCache_Disabled(t *testing.T) {
+	s := newFakeESService(t, func(w http.ResponseWriter, r *http.Request) {
+		t.Errorf("Expected no search when the cache is disabled, got %s", r.URL.Path)
+	})
+
+	if _, err := s.WarmCache(context.Background(), []*models.SearchRequest{{Index: "products", Query: "laptop", Size: 10}}); !errors.Is(err, cache.ErrCacheDisabled) {
+		t.Errorf("Expected ErrCacheDisabled, got %v", err)
+	}
+}
+
+func TestWarmCache_PopularQueries(t *testing.T) {
+	var searched []string
+	s, _ := newCachedFakeESService(t, func(w http.ResponseWriter, r *http.Request) {
+		searched = append(searched, strings.TrimSuffix(strings.TrimPrefix(r.URL.Path, "/"), "/_search"))
+		w.Write([]byte(`{"took":3,"hits":{"total":{"value":1,"relation":"eq"},"hits":[{"_index":"products","_id":"1","_score":1.0,"_source":{"title":"Laptop"}}]}}`))
+	}, models.CacheConfig{Warm: models.CacheWarmConfig{TopQueries: 1}})
+	s.analyticsHub = realtime.NewAnalyticsHub(models.RealtimeConfig{}, zap.NewNop())
+
+	// orders is searched most; cache hits count towards popularity too
+	for _, index := range []string{"orders", "orders", "products"} {
+		if _, err := s.Search(context.Background(), &models.SearchRequest{Index: index, Query: "laptop", Size: 10}); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+	}
+	if _, err := s.InvalidateCache(context.Background(), ""); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	searched = nil
+	summary, err := s.WarmCache(context.Background(), nil)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if summary.Requested != 1 || summary.Warmed != 1 || strings.Join(searched, " ") != "orders" {
+		t.Errorf("Expected the most frequent search to be warmed, got %+v after searching %v", summary, searched)
+	}
+}
@@ -0,0 +1,244 @@
+package services
+
+import (
+	"fmt"
+	"path"
+	"strings"
+
+	"go.uber.org/zap"
+
+	"github.com/saif-islam/es-playground/projects/search-api/internal/metrics"
+	"github.com/saif-islam/es-playground/projects/search-api/internal/models"
+)
+
+// Cost guard defaults, used when the configuration leaves a limit unset
+const (
+	defaultCostGuardMaxSize       = 500
+	defaultMaxAggregationSize     = 1000
+	defaultHighCardinalityMaxSize = 100
+)
+
+// QueryCostError is returned when the cost guard rejects a search
+type QueryCostError struct {
+	Issues []string
+}
+
+func (e *QueryCostError) Error() string {
+	return fmt.Sprintf("query rejected as too expensive: %s", strings.Join(e.Issues, "; "))
+}
+
+// costIssue is one reason a search was judged expensive
+type costIssue struct {
+	rule    string
+	message string
+}
+
+// CostGuard inspects searches before they are sent to Elasticsearch and rejects, or only
+// flags, the ones that are clearly expensive: oversized pages, leading wildcards, regular
+// expressions and large terms aggregations.
+type CostGuard struct {
+	config models.CostGuardConfig
+	logger *zap.Logger
+}
+
+// NewCostGuard creates a cost guard from configuration, or returns nil when it is disabled
+func NewCostGuard(config models.CostGuardConfig, logger *zap.Logger) *CostGuard {
+	if !config.Enabled {
+		return nil
+	}
+
+	if config.Mode == "" {
+		config.Mode = "reject"
+	}
+	if config.MaxSize <= 0 {
+		config.MaxSize = defaultCostGuardMaxSize
+	}
+	if config.MaxAggregationSize <= 0 {
+		config.MaxAggregationSize = defaultMaxAggregationSize
+	}
+	if config.HighCardinalityMaxSize <= 0 {
+		config.HighCardinalityMaxSize = defaultHighCardinalityMaxSize
+	}
+
+	return &CostGuard{
+		config: config,
+		logger: logger,
+	}
+}
+
+// Check returns warnings for an expensive search in warn mode, or a *QueryCostError in
+// reject mode. Requests that set allow_expensive skip the guard when the caller may override it.
+func (g *CostGuard) Check(req *models.SearchRequest) ([]string, error) {
+	issues := g.inspect(req)
+	if len(issues) == 0 {
+		return nil, nil
+	}
+
+	messages := make([]string, len(issues))
+	for i, issue := range issues {
+		messages[i] = issue.message
+	}
+
+	if req.AllowExpensive && g.canOverride(req.Scopes) {
+		g.record(req, issues, "allowed")
+		return nil, nil
+	}
+
+	if g.config.Mode == "warn" {
+		g.record(req, issues, "warned")
+		return messages, nil
+	}
+
+	g.record(req, issues, "rejected")
+	return nil, &QueryCostError{Issues: messages}
+}
+
+// canOverride reports whether the caller may skip the guard with allow_expensive. Scopes
+// only come from a trusted gateway, and without an override scope nobody may skip it.
+func (g *CostGuard) canOverride(scopes []string) bool {
+	if g.config.OverrideScope == "" {
+		return false
+	}
+	for _, scope := range scopes {
+		if scope == g.config.OverrideScope {
+			return true
+		}
+	}
+	return false
+}
+
+// record logs and counts a guarded search
+func (g *CostGuard) record(req *models.SearchRequest, issues []costIssue, action string) {
+	for _, issue := range issues {
+		metrics.RecordExpensiveQuery(issue.rule, action)
+	}
+
+	g.logger.Warn("Expensive search detected",
+		zap.String("request_id", req.RequestID),
+		zap.String("index", req.Index),
+		zap.String("action", action),
+		zap.Int("issues", len(issues)))
+}
+
+// inspect applies every rule to the request
+func (g *CostGuard) inspect(req *models.SearchRequest) []costIssue {
+	var issues []costIssue
+
+	if req.Size > g.config.MaxSize {
+		issues = append(issues, costIssue{"size", fmt.Sprintf("size %d exceeds the limit of %d; paginate with search_after instead", req.Size, g.config.MaxSize)})
+	}
+
+	// Only query_string interprets wildcards and regular expressions in the query text
+	if textQueryType(req) == "query_string" {
+		for _, term := range queryStringTerms(req.Query) {
+			if !g.config.AllowLeadingWildcards && hasLeadingWildcard(term) {
+				issues = append(issues, costIssue{"leading_wildcard", fmt.Sprintf("leading wildcard in %q scans every term in the index", term)})
+			}
+			if !g.config.AllowRegexp && strings.HasPrefix(term, "/") {
+				issues = append(issues, costIssue{"regexp", fmt.Sprintf("regular expression %q is not allowed", term)})
+			}
+		}
+	}
+
+	if !g.config.AllowLeadingWildcards {
+		for _, filters := range [][]models.Filter{req.Filters, req.PostFilter} {
+			issues = append(issues, leadingWildcardFilters(filters)...)
+		}
+	}
+
+	for name, agg := range req.Aggregations {
+		issues = append(issues, g.inspectAggregation(name, agg)...)
+	}
+
+	return issues
+}
+
+// leadingWildcardFilters finds wildcard filters with a leading wildcard, including inside
+// nested, has_child and has_parent filters
+func leadingWildcardFilters(filters []models.Filter) []costIssue {
+	var issues []costIssue
+	for _, filter := range filters {
+		value, _ := filter.Value.(string)
+		if filter.Type == "wildcard" && hasLeadingWildcard(value) {
+			issues = append(issues, costIssue{"leading_wildcard", fmt.Sprintf("leading wildcard in filter on %s", filter.Field)})
+		}
+		issues = append(issues, leadingWildcardFilters(filter.Filters)...)
+	}
+	return issues
+}
+
+// inspectAggregation checks terms aggregation sizes, including in sub-aggregations
+func (g *CostGuard) inspectAggregation(name string, agg models.AggregationConfig) []costIssue {
+	var issues []costIssue
+
+	if agg.Type == "terms" {
+		limit := g.config.MaxAggregationSize
+		if g.highCardinality(agg.Field) {
+			limit = g.config.HighCardinalityMaxSize
+		}
+		if agg.Size > limit {
+			issues = append(issues, costIssue{"aggregation_size", fmt.Sprintf("terms aggregation %q on %s requests %d buckets, above the limit of %d", name, agg.Field, agg.Size, limit)})
+		}
+	}
+
+	for subName, subAgg := range agg.SubAggs {
+		issues = append(issues, g.inspectAggregation(name+">"+subName, subAgg)...)
+	}
+
+	return issues
+}
+
+// highCardinality reports whether a field matches one of the configured high-cardinality patterns
+func (g *CostGuard) highCardinality(field string) bool {
+	for _, pattern := range g.config.HighCardinalityFields {
+		if ok, _ := path.Match(pattern, field); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// queryStringTerms splits a query_string query into its terms, without field prefixes,
+// grouping and boolean operators. Quoted phrases are skipped since wildcards are not
+// expanded inside them.
+func queryStringTerms(query string) []string {
+	// Drop quoted phrases first, they may span several whitespace-separated tokens
+	var unquoted strings.Builder
+	inPhrase := false
+	for _, r := range query {
+		if r == '"' {
+			inPhrase = !inPhrase
+			unquoted.WriteRune(' ')
+			continue
+		}
+		if !inPhrase {
+			unquoted.WriteRune(r)
+		}
+	}
+
+	var terms []string
+	for _, token := range strings.Fields(unquoted.String()) {
+		token = strings.TrimLeft(token, "+-!(")
+		if token == "" {
+			continue
+		}
+		// field:value, but not the colon of an escaped or regex value
+		if i := strings.Index(token, ":"); i > 0 && !strings.HasPrefix(token, "/") && token[i-1] != '\\' {
+			token = strings.TrimLeft(token[i+1:], "(")
+		}
+		token = strings.TrimRight(token, ")")
+		if token != "" {
+			terms = append(terms, token)
+		}
+	}
+	return terms
+}
+
+// hasLeadingWildcard reports whether a term starts with * or ?. A lone * (match all, or
+// field:* for exists) is cheap and allowed.
+func hasLeadingWildcard(term string) bool {
+	if term == "*" {
+		return false
+	}
+	return strings.HasPrefix(term, "*") || strings.HasPrefix(term, "?")
+}
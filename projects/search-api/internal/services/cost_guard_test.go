@@ -0,0 +1,131 @@
+package services
+
+import (
+	"errors"
+	"testing"
+
+	"go.uber.org/zap"
+
+	"github.com/saif-islam/es-playground/projects/search-api/internal/models"
+)
+
+func TestCostGuardRules(t *testing.T) {
+	guard := NewCostGuard(models.CostGuardConfig{
+		Enabled:               true,
+		HighCardinalityFields: []string{"*.id"},
+	}, zap.NewNop())
+
+	tests := []struct {
+		name     string
+		req      *models.SearchRequest
+		expected []string
+	}{
+		{
+			name: "cheap search",
+			req:  &models.SearchRequest{Query: "laptop", QueryType: "query_string", Size: 10},
+		},
+		{
+			name:     "oversized page",
+			req:      &models.SearchRequest{Query: "laptop", Size: 501},
+			expected: []string{"size"},
+		},
+		{
+			name:     "leading wildcard and regexp",
+			req:      &models.SearchRequest{Query: `title:*top AND /lap.*/ AND "*quoted"`, QueryType: "query_string", Size: 10},
+			expected: []string{"leading_wildcard", "regexp"},
+		},
+		{
+			name: "wildcards outside query_string",
+			req:  &models.SearchRequest{Query: "*top", QueryType: "match", Size: 10},
+		},
+		{
+			name: "match all",
+			req:  &models.SearchRequest{Query: "*", QueryType: "query_string", Size: 10},
+		},
+		{
+			name: "nested wildcard filter",
+			req: &models.SearchRequest{Size: 10, Filters: []models.Filter{
+				{Type: "nested", Path: "tags", Filters: []models.Filter{{Type: "wildcard", Field: "tags.name", Value: "?ap"}}},
+			}},
+			expected: []string{"leading_wildcard"},
+		},
+		{
+			name: "high cardinality sub-aggregation",
+			req: &models.SearchRequest{Size: 10, Aggregations: map[string]models.AggregationConfig{
+				"brands": {Type: "terms", Field: "brand", Size: 500, SubAggs: map[string]models.AggregationConfig{
+					"users": {Type: "terms", Field: "user.id", Size: 101},
+				}},
+			}},
+			expected: []string{"aggregation_size"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			issues := guard.inspect(tt.req)
+
+			rules := make([]string, len(issues))
+			for i, issue := range issues {
+				rules[i] = issue.rule
+			}
+			if len(rules) != len(tt.expected) {
+				t.Fatalf("Expected rules %v, got %v", tt.expected, rules)
+			}
+			for i := range rules {
+				if rules[i] != tt.expected[i] {
+					t.Errorf("Expected rules %v, got %v", tt.expected, rules)
+				}
+			}
+		})
+	}
+}
+
+func TestCostGuardCheck(t *testing.T) {
+	tests := []struct {
+		name           string
+		mode           string
+		overrideScope  string
+		allowExpensive bool
+		scopes         []string
+		expectRejected bool
+		expectWarnings bool
+	}{
+		{name: "rejected", mode: "reject", overrideScope: "search:expensive", expectRejected: true},
+		{name: "warned", mode: "warn", overrideScope: "search:expensive", expectWarnings: true},
+		{
+			name: "overridden with the scope", mode: "reject", overrideScope: "search:expensive",
+			allowExpensive: true, scopes: []string{"pii:read", "search:expensive"},
+		},
+		{
+			name: "override without the scope", mode: "reject", overrideScope: "search:expensive",
+			allowExpensive: true, scopes: []string{"pii:read"}, expectRejected: true,
+		},
+		{
+			name: "override without an override scope configured", mode: "reject",
+			allowExpensive: true, scopes: []string{"search:expensive"}, expectRejected: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			guard := NewCostGuard(models.CostGuardConfig{Enabled: true, Mode: tt.mode, OverrideScope: tt.overrideScope}, zap.NewNop())
+			req := &models.SearchRequest{Query: "laptop", Size: 1000, AllowExpensive: tt.allowExpensive, Scopes: tt.scopes}
+
+			warnings, err := guard.Check(req)
+
+			var costErr *QueryCostError
+			if rejected := errors.As(err, &costErr); rejected != tt.expectRejected {
+				t.Errorf("Expected rejected %v, got error %v", tt.expectRejected, err)
+			}
+			if (len(warnings) > 0) != tt.expectWarnings {
+				t.Errorf("Expected warnings %v, got %v", tt.expectWarnings, warnings)
+			}
+		})
+	}
+}
+
+func TestNewCostGuardDisabled(t *testing.T) {
+	if guard := NewCostGuard(models.CostGuardConfig{}, zap.NewNop()); guard != nil {
+		t.Error("Expected no guard when disabled")
+	}
+}
@@ -0,0 +1,97 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/elastic/go-elasticsearch/v8/esapi"
+	"go.uber.org/zap"
+
+	"github.com/saif-islam/es-playground/projects/search-api/internal/models"
+)
+
+// ErrKNNCount is returned when counting a kNN search; nearest neighbours have no total
+var ErrKNNCount = errors.New("knn searches cannot be counted")
+
+// Count returns how many documents match a search, using the _count API so no hits are
+// scored or fetched. The query and filters are built as for Search; paging, sorting and
+// result options are ignored. Counts are cached under their own key.
+func (s *SearchService) Count(ctx context.Context, req *models.SearchRequest) (*models.CountResponse, error) {
+	ctx, span := s.tracer.TraceSearchOperation(ctx, "count", req)
+	defer span.End()
+
+	startTime := time.Now()
+
+	if req.KNN != nil {
+		return nil, ErrKNNCount
+	}
+
+	if s.costGuard != nil {
+		if _, err := s.costGuard.Check(req); err != nil {
+			return nil, err
+		}
+	}
+
+	if cachedResponse, found := s.cacheManager.GetCache().GetCountResult(ctx, req); found {
+		s.tracer.RecordCacheOperation(ctx, "get", true, "count_result")
+		return cachedResponse, nil
+	}
+	s.tracer.RecordCacheOperation(ctx, "get", false, "count_result")
+
+	_, body, err := s.mainQueryBody(req)
+	if err != nil {
+		s.tracer.RecordError(ctx, err, map[string]interface{}{
+			"operation": "build_query",
+			"index":     req.Index,
+		})
+		return nil, err
+	}
+
+	ctx, esSpan := s.tracer.TraceElasticsearchOperation(ctx, "POST", fmt.Sprintf("/%s/_count", req.Index), body)
+	defer esSpan.End()
+
+	countReq := esapi.CountRequest{
+		Index: []string{req.Index},
+		Body:  strings.NewReader(body),
+	}
+
+	res, err := countReq.Do(ctx, s.esClient)
+	if err != nil {
+		s.tracer.RecordError(ctx, err, map[string]interface{}{
+			"operation": "count",
+		})
+		return nil, fmt.Errorf("count request failed: %w", err)
+	}
+	defer res.Body.Close()
+
+	if res.IsError() {
+		err := fmt.Errorf("count failed: %s", res.String())
+		s.tracer.RecordElasticsearchResult(ctx, res.StatusCode, 0, time.Since(startTime))
+		s.tracer.RecordError(ctx, err, map[string]interface{}{
+			"elasticsearch.status_code": res.StatusCode,
+		})
+		return nil, err
+	}
+
+	var response models.CountResponse
+	if err := json.NewDecoder(res.Body).Decode(&response); err != nil {
+		s.tracer.RecordError(ctx, err, map[string]interface{}{
+			"operation": "parse_response",
+		})
+		return nil, fmt.Errorf("failed to parse count response: %w", err)
+	}
+
+	s.tracer.RecordElasticsearchResult(ctx, res.StatusCode, 0, time.Since(startTime))
+
+	if err := s.cacheManager.GetCache().SetCountResult(ctx, req, &response); err != nil {
+		s.logger.Warn("Failed to cache count result", zap.Error(err))
+	} else {
+		s.tracer.RecordCacheOperation(ctx, "set", true, "count_result")
+	}
+
+	return &response, nil
+}
@@ -0,0 +1,78 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"testing"
+
+	"github.com/saif-islam/es-playground/projects/search-api/internal/models"
+)
+
+func TestCount(t *testing.T) {
+	var requests []string
+	var body map[string]json.RawMessage
+	s, _ := newCachedFakeESService(t, func(w http.ResponseWriter, r *http.Request) {
+		requests = append(requests, r.Method+" "+r.URL.Path)
+		json.NewDecoder(r.Body).Decode(&body)
+		w.Write([]byte(`{"count":1342,"_shards":{"total":2,"successful":2,"skipped":0,"failed":0}}`))
+	}, models.CacheConfig{})
+
+	req := &models.SearchRequest{
+		Index:     "products",
+		Query:     "laptop",
+		QueryType: "match",
+		Fields:    []string{"title"},
+		Filters:   []models.Filter{{Field: "in_stock", Type: "term", Value: true}},
+		From:      20,
+		Size:      10,
+		Sort:      []models.SortField{{Field: "price", Order: "asc"}},
+	}
+
+	response, err := s.Count(context.Background(), req)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(requests) != 1 || requests[0] != "POST /products/_count" {
+		t.Fatalf("Expected POST /products/_count, got %v", requests)
+	}
+	if _, ok := body["query"]; !ok || len(body) != 1 {
+		t.Errorf("Expected only the query to be sent, got %v", body)
+	}
+	if response.Count != 1342 || response.Shards.Successful != 2 || response.CacheHit {
+		t.Errorf("Expected 1342 documents counted by Elasticsearch, got %+v", response)
+	}
+
+	// Paging and sorting do not change the count, so the cached one is reused
+	req.From, req.Sort = 0, nil
+	cached, err := s.Count(context.Background(), req)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(requests) != 1 || !cached.CacheHit || cached.Count != 1342 {
+		t.Errorf("Expected the cached count, got %+v after %v", cached, requests)
+	}
+}
+
+func TestCount_Errors(t *testing.T) {
+	var requests int
+	s := newFakeESService(t, func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		w.WriteHeader(http.StatusNotFound)
+		w.Write([]byte(`{"error":{"root_cause":[{"type":"index_not_found_exception","reason":"no such index [missing]"}],"type":"index_not_found_exception","reason":"no such index [missing]"},"status":404}`))
+	})
+
+	if _, err := s.Count(context.Background(), &models.SearchRequest{Index: "missing", Size: 10}); err == nil {
+		t.Error("Expected counting a missing index to fail")
+	}
+
+	requests = 0
+	_, err := s.Count(context.Background(), &models.SearchRequest{
+		Index: "products",
+		KNN:   &models.KNNQuery{Field: "embedding", QueryVector: []float32{0.1, 0.2, 0.3}, K: 5},
+	})
+	if !errors.Is(err, ErrKNNCount) || requests != 0 {
+		t.Errorf("Expected ErrKNNCount without reaching Elasticsearch, got %v", err)
+	}
+}
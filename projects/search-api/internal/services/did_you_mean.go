@@ -0,0 +1,64 @@
+package services
+
+import (
+	"context"
+	"strings"
+
+	"go.uber.org/zap"
+
+	"github.com/saif-islam/es-playground/projects/search-api/internal/models"
+)
+
+// defaultAutoSuggestThreshold is the result count below which auto_suggest searches look
+// up a correction
+const defaultAutoSuggestThreshold = 3
+
+// SetAutoSuggestThreshold sets the result count below which searches with auto_suggest
+// look up a correction; zero or less restores the default
+func (s *SearchService) SetAutoSuggestThreshold(threshold int) {
+	if threshold <= 0 {
+		threshold = defaultAutoSuggestThreshold
+	}
+	s.autoSuggestThreshold = threshold
+}
+
+// wantsCorrection reports whether a search found few enough results to look up a correction
+func (s *SearchService) wantsCorrection(req *models.SearchRequest, total int64) bool {
+	threshold := s.autoSuggestThreshold
+	if threshold <= 0 {
+		threshold = defaultAutoSuggestThreshold
+	}
+	return req.AutoSuggest && req.Query != "" && total < int64(threshold)
+}
+
+// didYouMean returns the phrase suggester's top correction of a search's query text on the
+// first field it searched, or "" when there is none. Failures are only logged: a missing
+// correction should never fail the search.
+func (s *SearchService) didYouMean(ctx context.Context, req *models.SearchRequest) string {
+	field := queriedField(req)
+	if field == "" {
+		return ""
+	}
+
+	suggestions, err := s.suggestCorrections(ctx, req.Index, field, req.Query)
+	if err != nil {
+		s.logger.Warn("Failed to suggest a correction",
+			zap.String("index", req.Index),
+			zap.String("field", field),
+			zap.Error(err))
+		return ""
+	}
+	if len(suggestions) == 0 {
+		return ""
+	}
+	return suggestions[0]
+}
+
+// queriedField returns the first field a search's query text runs against, without its boost
+func queriedField(req *models.SearchRequest) string {
+	if len(req.Fields) > 0 {
+		field, _, _ := strings.Cut(req.Fields[0], "^")
+		return field
+	}
+	return req.DefaultField
+}
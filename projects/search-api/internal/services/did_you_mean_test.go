@@ -0,0 +1,116 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/saif-islam/es-playground/projects/search-api/internal/models"
+)
+
+func TestSearch_DidYouMean(t *testing.T) {
+	tests := []struct {
+		name             string
+		req              models.SearchRequest
+		threshold        int
+		suggestStatus    int
+		expectedTotal    int64
+		expectedSuggests []string // field:text of each suggest request
+		expectedCorrect  string
+	}{
+		{
+			name:             "no results",
+			req:              models.SearchRequest{Index: "products", Query: "lapptop", QueryType: "match", Fields: []string{"title^2"}, AutoSuggest: true},
+			expectedSuggests: []string{"title:lapptop"},
+			expectedCorrect:  "laptop",
+		},
+		{
+			name:             "default field",
+			req:              models.SearchRequest{Index: "products", Query: "lapptop", QueryType: "match", DefaultField: "description", AutoSuggest: true},
+			expectedSuggests: []string{"description:lapptop"},
+			expectedCorrect:  "laptop",
+		},
+		{
+			name: "auto_suggest not asked for",
+			req:  models.SearchRequest{Index: "products", Query: "lapptop", QueryType: "match", Fields: []string{"title"}},
+		},
+		{
+			name:          "enough results",
+			req:           models.SearchRequest{Index: "products", Query: "laptop", QueryType: "match", Fields: []string{"title"}, AutoSuggest: true},
+			threshold:     2,
+			expectedTotal: 2,
+		},
+		{
+			// Under the default threshold of 3, but the suggester only echoes the query
+			name:             "sparse results without a better spelling",
+			req:              models.SearchRequest{Index: "products", Query: "laptop", QueryType: "match", Fields: []string{"title"}, AutoSuggest: true},
+			expectedTotal:    2,
+			expectedSuggests: []string{"title:laptop"},
+		},
+		{
+			name: "no field to draw corrections from",
+			req:  models.SearchRequest{Index: "products", Query: "lapptop", QueryType: "multi_match", AutoSuggest: true},
+		},
+		{
+			name:             "suggester failure keeps the search",
+			req:              models.SearchRequest{Index: "products", Query: "lapptop", QueryType: "match", Fields: []string{"title"}, AutoSuggest: true},
+			suggestStatus:    http.StatusBadRequest,
+			expectedSuggests: []string{"title:lapptop"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var suggests []string
+			s := newFakeESService(t, func(w http.ResponseWriter, r *http.Request) {
+				var body struct {
+					Query   json.RawMessage `json:"query"`
+					Suggest map[string]struct {
+						Text   string `json:"text"`
+						Phrase struct {
+							Field string `json:"field"`
+						} `json:"phrase"`
+					} `json:"suggest"`
+				}
+				json.NewDecoder(r.Body).Decode(&body)
+
+				if correction, ok := body.Suggest["correction"]; ok {
+					suggests = append(suggests, correction.Phrase.Field+":"+correction.Text)
+					if tt.suggestStatus != 0 {
+						w.WriteHeader(tt.suggestStatus)
+						w.Write([]byte(`{"error":{"root_cause":[{"type":"illegal_argument_exception","reason":"no mapping found for field [title]"}],"type":"search_phase_execution_exception","reason":"all shards failed"},"status":400}`))
+						return
+					}
+					fmt.Fprintf(w, phraseSuggestAPIResponse, correction.Text, correction.Text)
+					return
+				}
+				if strings.Contains(string(body.Query), `"laptop"`) {
+					w.Write([]byte(laptopSearchAPIResponse))
+					return
+				}
+				w.Write([]byte(emptySearchAPIResponse))
+			})
+			s.SetAutoSuggestThreshold(tt.threshold)
+
+			req := tt.req
+			req.Size = 10
+			response, err := s.Search(context.Background(), &req)
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if response.Total.Value != tt.expectedTotal {
+				t.Errorf("Expected %d results, got %d", tt.expectedTotal, response.Total.Value)
+			}
+			if !reflect.DeepEqual(suggests, tt.expectedSuggests) {
+				t.Errorf("Expected suggest requests %v, got %v", tt.expectedSuggests, suggests)
+			}
+			if response.DidYouMean != tt.expectedCorrect {
+				t.Errorf("Expected did you mean %q, got %q", tt.expectedCorrect, response.DidYouMean)
+			}
+		})
+	}
+}
@@ -0,0 +1,115 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/elastic/go-elasticsearch/v8/esapi"
+
+	"github.com/saif-islam/es-playground/projects/search-api/internal/models"
+)
+
+// ErrDocumentNotFound is returned when explaining a document that does not exist
+var ErrDocumentNotFound = errors.New("document not found")
+
+// Validate checks a search's query with the _validate/query API without running it. The
+// query and filters are built as for Search; a kNN clause is not validated. An invalid
+// query is reported in the response rather than as an error.
+func (s *SearchService) Validate(ctx context.Context, req *models.SearchRequest) (*models.ValidateResponse, error) {
+	ctx, span := s.tracer.TraceSearchOperation(ctx, "validate", req)
+	defer span.End()
+
+	query, body, err := s.mainQueryBody(req)
+	if err != nil {
+		return nil, err
+	}
+
+	explain := true
+	validateReq := esapi.IndicesValidateQueryRequest{
+		Index:   []string{req.Index},
+		Body:    strings.NewReader(body),
+		Explain: &explain,
+	}
+
+	res, err := validateReq.Do(ctx, s.esClient)
+	if err != nil {
+		s.tracer.RecordError(ctx, err, map[string]interface{}{
+			"operation": "validate",
+		})
+		return nil, fmt.Errorf("validate request failed: %w", err)
+	}
+	defer res.Body.Close()
+
+	if res.IsError() {
+		return nil, fmt.Errorf("validate failed: %s", res.String())
+	}
+
+	var response models.ValidateResponse
+	if err := json.NewDecoder(res.Body).Decode(&response); err != nil {
+		return nil, fmt.Errorf("failed to parse validate response: %w", err)
+	}
+	response.Query = query
+
+	return &response, nil
+}
+
+// Explain reports whether a document matches a search's query and returns the scoring
+// explanation tree, to debug why a document did or did not match
+func (s *SearchService) Explain(ctx context.Context, index, docID string, req *models.SearchRequest) (*models.ExplainResponse, error) {
+	ctx, span := s.tracer.TraceSearchOperation(ctx, "explain", req)
+	defer span.End()
+
+	_, body, err := s.mainQueryBody(req)
+	if err != nil {
+		return nil, err
+	}
+
+	explainReq := esapi.ExplainRequest{
+		Index:      index,
+		DocumentID: docID,
+		Body:       strings.NewReader(body),
+	}
+
+	res, err := explainReq.Do(ctx, s.esClient)
+	if err != nil {
+		s.tracer.RecordError(ctx, err, map[string]interface{}{
+			"operation": "explain",
+		})
+		return nil, fmt.Errorf("explain request failed: %w", err)
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode == http.StatusNotFound {
+		return nil, fmt.Errorf("%w: %s/%s", ErrDocumentNotFound, index, docID)
+	}
+	if res.IsError() {
+		return nil, fmt.Errorf("explain failed: %s", res.String())
+	}
+
+	var response models.ExplainResponse
+	if err := json.NewDecoder(res.Body).Decode(&response); err != nil {
+		return nil, fmt.Errorf("failed to parse explain response: %w", err)
+	}
+
+	return &response, nil
+}
+
+// mainQueryBody builds a search's query and the {"query": ...} body the validate and
+// explain APIs take
+func (s *SearchService) mainQueryBody(req *models.SearchRequest) (map[string]interface{}, string, error) {
+	query, err := s.buildMainQuery(req)
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to build query: %w", err)
+	}
+
+	body, err := json.Marshal(map[string]interface{}{"query": query})
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to marshal query: %w", err)
+	}
+
+	return query, string(body), nil
+}
@@ -0,0 +1,120 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"testing"
+
+	"github.com/saif-islam/es-playground/projects/search-api/internal/models"
+)
+
+// explainAPIResponse follows GET /products/_explain/1 for a match query on title
+const explainAPIResponse = `{
+  "_index": "products",
+  "_id": "1",
+  "matched": true,
+  "explanation": {
+    "value": 1.6943598,
+    "description": "weight(title:laptop in 0) [PerFieldSimilarity], result of:",
+    "details": [
+      {
+        "value": 1.6943598,
+        "description": "score(freq=1.0), computed as boost * idf * tf from:",
+        "details": [
+          {"value": 2.2, "description": "boost", "details": []},
+          {"value": 1.3862944, "description": "idf, computed as log(1 + (N - n + 0.5) / (n + 0.5)) from:", "details": []},
+          {"value": 0.5555555, "description": "tf, computed as freq / (freq + k1 * (1 - b + b * dl / avgdl)) from:", "details": []}
+        ]
+      }
+    ]
+  }
+}`
+
+func TestValidate(t *testing.T) {
+	tests := []struct {
+		name          string
+		response      string
+		expectedValid bool
+	}{
+		{
+			name:          "valid query",
+			response:      `{"_shards":{"total":1,"successful":1,"failed":0},"valid":true,"explanations":[{"index":"products","valid":true,"explanation":"+title:laptop #in_stock:T"}]}`,
+			expectedValid: true,
+		},
+		{
+			name:     "invalid query",
+			response: `{"_shards":{"total":1,"successful":1,"failed":0},"valid":false,"explanations":[{"index":"products","valid":false,"error":"[products/abc] QueryShardException[failed to create query: For input string: \"laptop\"]"}]}`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var path, explain string
+			var body map[string]json.RawMessage
+			s := newFakeESService(t, func(w http.ResponseWriter, r *http.Request) {
+				path, explain = r.URL.Path, r.URL.Query().Get("explain")
+				json.NewDecoder(r.Body).Decode(&body)
+				w.Write([]byte(tt.response))
+			})
+
+			response, err := s.Validate(context.Background(), &models.SearchRequest{
+				Index:     "products",
+				Query:     "laptop",
+				QueryType: "match",
+				Fields:    []string{"title"},
+				Filters:   []models.Filter{{Field: "in_stock", Type: "term", Value: true}},
+				Size:      10,
+			})
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+
+			if path != "/products/_validate/query" || explain != "true" {
+				t.Errorf("Expected /products/_validate/query with explain, got %s with explain=%q", path, explain)
+			}
+			if _, ok := body["query"]; !ok || len(body) != 1 {
+				t.Errorf("Expected only the query to be sent, got %v", body)
+			}
+			if response.Valid != tt.expectedValid || len(response.Explanations) != 1 || response.Explanations[0].Valid != tt.expectedValid {
+				t.Errorf("Expected valid %v with one explanation, got %+v", tt.expectedValid, response)
+			}
+			if _, ok := response.Query["bool"]; !ok {
+				t.Errorf("Expected the validated query to be returned, got %v", response.Query)
+			}
+		})
+	}
+}
+
+func TestExplain(t *testing.T) {
+	var path string
+	s := newFakeESService(t, func(w http.ResponseWriter, r *http.Request) {
+		path = r.URL.Path
+		if path == "/products/_explain/42" {
+			w.WriteHeader(http.StatusNotFound)
+			w.Write([]byte(`{"_index":"products","_id":"42","matched":false}`))
+			return
+		}
+		w.Write([]byte(explainAPIResponse))
+	})
+	req := &models.SearchRequest{Index: "products", Query: "laptop", QueryType: "match", Fields: []string{"title"}}
+
+	response, err := s.Explain(context.Background(), "products", "1", req)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if path != "/products/_explain/1" {
+		t.Errorf("Expected /products/_explain/1, got %s", path)
+	}
+	if !response.Matched || response.Explanation == nil || response.Explanation.Value != 1.6943598 {
+		t.Fatalf("Expected a matching document with its score, got %+v", response)
+	}
+	if details := response.Explanation.Details; len(details) != 1 || len(details[0].Details) != 3 || details[0].Details[0].Description != "boost" {
+		t.Errorf("Expected the scoring tree, got %+v", details)
+	}
+
+	if _, err := s.Explain(context.Background(), "products", "42", req); !errors.Is(err, ErrDocumentNotFound) {
+		t.Errorf("Expected ErrDocumentNotFound for a missing document, got %v", err)
+	}
+}
@@ -0,0 +1,81 @@
+package services
+
+import (
+	"fmt"
+	"path"
+	"strings"
+
+	"github.com/saif-islam/es-playground/projects/search-api/internal/models"
+)
+
+// supportedHighlighters are the highlighter types Elasticsearch accepts
+var supportedHighlighters = map[string]bool{
+	"unified": true,
+	"plain":   true,
+	"fvh":     true,
+}
+
+// SetHighlightPolicy flags text-heavy indices, given as patterns such as "articles-*", where
+// highlighting every field re-analyzes whole documents for each hit. Searches on them that
+// highlight without listing fields get a warning, or are rejected when requireFields is set.
+func (s *SearchService) SetHighlightPolicy(textHeavyIndices []string, requireFields bool) {
+	s.textHeavyIndices = textHeavyIndices
+	s.requireHighlightFields = requireFields
+}
+
+// validateHighlight rejects an unsupported highlighter type, and highlighting every field of
+// a text-heavy index when explicit fields are required
+func (s *SearchService) validateHighlight(req *models.SearchRequest) error {
+	if !req.Highlight.Enabled {
+		return nil
+	}
+
+	if req.Highlight.HighlightType != "" && !supportedHighlighters[req.Highlight.HighlightType] {
+		return fmt.Errorf("%w: unsupported highlighter type %q, use unified, plain or fvh",
+			ErrInvalidQuery, req.Highlight.HighlightType)
+	}
+
+	if s.requireHighlightFields && s.highlightsAllTextHeavy(req) {
+		return fmt.Errorf("%w: %s is text-heavy, list the fields to highlight instead of highlighting every field",
+			ErrInvalidQuery, req.Index)
+	}
+
+	return nil
+}
+
+// highlightWarnings warns about highlighting every field of a text-heavy index when
+// explicit fields aren't required
+func (s *SearchService) highlightWarnings(req *models.SearchRequest) []string {
+	if s.requireHighlightFields || !s.highlightsAllTextHeavy(req) {
+		return nil
+	}
+	return []string{fmt.Sprintf("highlighting every field of text-heavy index %s is expensive; list the fields to highlight", req.Index)}
+}
+
+// highlightsAllTextHeavy reports whether a search highlights every field of a text-heavy index
+func (s *SearchService) highlightsAllTextHeavy(req *models.SearchRequest) bool {
+	if !req.Highlight.Enabled || !s.textHeavy(req.Index) {
+		return false
+	}
+	if len(req.Highlight.Fields) == 0 {
+		return true
+	}
+	for _, field := range req.Highlight.Fields {
+		if field == "*" {
+			return true
+		}
+	}
+	return false
+}
+
+// textHeavy reports whether any of the comma-separated indices matches a text-heavy pattern
+func (s *SearchService) textHeavy(indices string) bool {
+	for _, index := range strings.Split(indices, ",") {
+		for _, pattern := range s.textHeavyIndices {
+			if ok, _ := path.Match(pattern, strings.TrimSpace(index)); ok {
+				return true
+			}
+		}
+	}
+	return false
+}
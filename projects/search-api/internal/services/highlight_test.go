@@ -0,0 +1,163 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/saif-islam/es-playground/projects/search-api/internal/models"
+)
+
+// highlightedSearchAPIResponse follows POST /articles-2024/_search for a match query on body
+// with highlighting
+const highlightedSearchAPIResponse = `{
+  "took": 9,
+  "timed_out": false,
+  "_shards": {"total": 1, "successful": 1, "skipped": 0, "failed": 0},
+  "hits": {
+    "total": {"value": 1, "relation": "eq"},
+    "max_score": 2.1,
+    "hits": [
+      {
+        "_index": "articles-2024",
+        "_id": "a-17",
+        "_score": 2.1,
+        "_source": {"title": "Choosing a laptop", "body": "A good laptop lasts for years."},
+        "highlight": {"body": ["A good <em>laptop</em> lasts for years."]}
+      }
+    ]
+  }
+}`
+
+func TestSearch_Highlight(t *testing.T) {
+	const allFieldsWarning = "highlighting every field of text-heavy index articles-2024 is expensive; list the fields to highlight"
+
+	tests := []struct {
+		name             string
+		index            string
+		highlight        models.HighlightConfig
+		requireFields    bool
+		expectedError    error
+		expectedType     string
+		expectedFields   []string
+		expectedWarnings []string
+	}{
+		{
+			name:           "fast vector highlighter on listed fields",
+			index:          "articles-2024",
+			highlight:      models.HighlightConfig{Enabled: true, HighlightType: "fvh", Fields: []string{"body"}},
+			expectedType:   "fvh",
+			expectedFields: []string{"body"},
+		},
+		{
+			name:          "unsupported highlighter",
+			index:         "products",
+			highlight:     models.HighlightConfig{Enabled: true, HighlightType: "fast"},
+			expectedError: ErrInvalidQuery,
+		},
+		{
+			name:             "every field of a text-heavy index",
+			index:            "articles-2024",
+			highlight:        models.HighlightConfig{Enabled: true},
+			expectedFields:   []string{"*"},
+			expectedWarnings: []string{allFieldsWarning},
+		},
+		{
+			name:             "wildcard field across a text-heavy index",
+			index:            "products, articles-2024",
+			highlight:        models.HighlightConfig{Enabled: true, Fields: []string{"title", "*"}},
+			expectedFields:   []string{"*", "title"},
+			expectedWarnings: []string{"highlighting every field of text-heavy index products, articles-2024 is expensive; list the fields to highlight"},
+		},
+		{
+			name:           "every field of another index",
+			index:          "products",
+			highlight:      models.HighlightConfig{Enabled: true},
+			requireFields:  true,
+			expectedFields: []string{"*"},
+		},
+		{
+			name:          "every field of a text-heavy index with fields required",
+			index:         "articles-2024",
+			highlight:     models.HighlightConfig{Enabled: true},
+			requireFields: true,
+			expectedError: ErrInvalidQuery,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var sent map[string]interface{}
+			s := newFakeESService(t, func(w http.ResponseWriter, r *http.Request) {
+				body, _ := io.ReadAll(r.Body)
+				json.Unmarshal(body, &sent)
+				w.Write([]byte(highlightedSearchAPIResponse))
+			})
+			s.SetHighlightPolicy([]string{"articles-*", "docs"}, tt.requireFields)
+
+			response, err := s.Search(context.Background(), &models.SearchRequest{
+				Index:     tt.index,
+				Query:     "laptop",
+				QueryType: "match",
+				Fields:    []string{"body"},
+				Size:      10,
+				Highlight: tt.highlight,
+			})
+			if tt.expectedError != nil {
+				if !errors.Is(err, tt.expectedError) {
+					t.Fatalf("Expected %v, got %v", tt.expectedError, err)
+				}
+				if sent != nil {
+					t.Errorf("Expected nothing sent, got %v", sent)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+
+			highlight, _ := sent["highlight"].(map[string]interface{})
+			var fields []string
+			for field := range highlight["fields"].(map[string]interface{}) {
+				fields = append(fields, field)
+			}
+			sort.Strings(fields)
+			if !reflect.DeepEqual(fields, tt.expectedFields) {
+				t.Errorf("Expected %v highlighted, got %v", tt.expectedFields, fields)
+			}
+			if highlightType, _ := highlight["type"].(string); highlightType != tt.expectedType {
+				t.Errorf("Expected highlighter %q, got %q", tt.expectedType, highlightType)
+			}
+			if !reflect.DeepEqual(response.Warnings, tt.expectedWarnings) {
+				t.Errorf("Expected warnings %v, got %v", tt.expectedWarnings, response.Warnings)
+			}
+			if len(response.Hits) != 1 || !strings.Contains(response.Hits[0].Highlight["body"][0], "<em>laptop</em>") {
+				t.Errorf("Expected the highlighted fragment, got %+v", response.Hits)
+			}
+		})
+	}
+}
+
+func TestMultiSearch_HighlightWarnings(t *testing.T) {
+	s := newFakeESService(t, func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"took": 9, "responses": [` + highlightedSearchAPIResponse + `, ` + highlightedSearchAPIResponse + `]}`))
+	})
+	s.SetHighlightPolicy([]string{"articles-*"}, false)
+
+	responses, errs := s.MultiSearch(context.Background(), []*models.SearchRequest{
+		{Index: "articles-2024", Query: "laptop", QueryType: "match", Fields: []string{"body"}, Size: 10, Highlight: models.HighlightConfig{Enabled: true}},
+		{Index: "articles-2024", Query: "laptop", QueryType: "match", Fields: []string{"body"}, Size: 10, Highlight: models.HighlightConfig{Enabled: true, Fields: []string{"body"}}},
+	})
+	if errs[0] != nil || errs[1] != nil {
+		t.Fatalf("Unexpected errors: %v", errs)
+	}
+	if len(responses[0].Warnings) != 1 || len(responses[1].Warnings) != 0 {
+		t.Errorf("Expected only the search highlighting every field warned, got %v and %v", responses[0].Warnings, responses[1].Warnings)
+	}
+}
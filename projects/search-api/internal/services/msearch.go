@@ -0,0 +1,174 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/elastic/go-elasticsearch/v8/esapi"
+	"go.opentelemetry.io/otel/trace"
+	"go.uber.org/zap"
+
+	"github.com/saif-islam/es-playground/projects/search-api/internal/models"
+)
+
+// multiSearchItem tracks one search of a multi-search from cache lookup to response
+type multiSearchItem struct {
+	req          *models.SearchRequest
+	ctx          context.Context
+	span         trace.Span
+	costWarnings []string
+	useCache     bool
+}
+
+// MultiSearch runs several independent searches in one round trip. Each search gets its own
+// span, cost check and cache lookup; only the cache misses are sent to Elasticsearch, in a
+// single _msearch request. Responses and errors are returned in request order, with a nil
+// response wherever a search failed. Per-search timeouts do not apply; ctx bounds the call.
+func (s *SearchService) MultiSearch(ctx context.Context, reqs []*models.SearchRequest) ([]*models.SearchResponse, []error) {
+	ctx, span := s.tracer.TraceSearchOperation(ctx, "msearch", nil)
+	defer span.End()
+	s.tracer.AddCustomAttribute(ctx, "search.msearch.count", len(reqs))
+
+	startTime := time.Now()
+	responses := make([]*models.SearchResponse, len(reqs))
+	errs := make([]error, len(reqs))
+
+	var body strings.Builder
+	var pending []int
+	items := make([]multiSearchItem, len(reqs))
+	for i, req := range reqs {
+		itemCtx, itemSpan := s.tracer.TraceSearchOperation(ctx, "search", req)
+		defer itemSpan.End()
+		items[i] = multiSearchItem{req: req, ctx: itemCtx, span: itemSpan, useCache: cacheable(req)}
+
+		if s.costGuard != nil {
+			warnings, err := s.costGuard.Check(req)
+			if err != nil {
+				errs[i] = err
+				continue
+			}
+			items[i].costWarnings = warnings
+		}
+		items[i].costWarnings = append(items[i].costWarnings, s.highlightWarnings(req)...)
+
+		if items[i].useCache {
+			if cachedResponse, found := s.lookupCachedSearch(itemCtx, req, itemSpan, startTime); found {
+				responses[i] = cachedResponse
+				continue
+			}
+		}
+
+		query, err := s.buildElasticsearchQuery(req)
+		if err != nil {
+			s.tracer.RecordError(itemCtx, err, map[string]interface{}{
+				"operation": "build_query",
+				"index":     req.Index,
+			})
+			errs[i] = fmt.Errorf("failed to build query: %w", err)
+			continue
+		}
+
+		// The point in time already pins the indices; naming them again is rejected
+		header := map[string]interface{}{}
+		if req.PIT == nil {
+			header["index"] = req.Index
+		}
+		headerJSON, err := json.Marshal(header)
+		if err != nil {
+			errs[i] = fmt.Errorf("failed to marshal search header: %w", err)
+			continue
+		}
+
+		body.Write(headerJSON)
+		body.WriteByte('\n')
+		body.WriteString(query)
+		body.WriteByte('\n')
+		pending = append(pending, i)
+	}
+
+	s.tracer.AddCustomAttribute(ctx, "search.msearch.cache_misses", len(pending))
+	if len(pending) == 0 {
+		return responses, errs
+	}
+
+	esResponses, err := s.executeMultiSearch(ctx, body.String(), startTime)
+	if err == nil && len(esResponses) != len(pending) {
+		err = fmt.Errorf("msearch returned %d responses for %d searches", len(esResponses), len(pending))
+	}
+	if err != nil {
+		s.logger.Error("Multi-search failed", zap.Error(err), zap.Int("searches", len(pending)))
+		for _, i := range pending {
+			errs[i] = err
+		}
+		return responses, errs
+	}
+
+	for n, i := range pending {
+		item := items[i]
+		esResponse := esResponses[n]
+
+		if esErr, ok := esResponse["error"]; ok {
+			errBytes, _ := json.Marshal(esErr)
+			errs[i] = fmt.Errorf("search failed: %s", errBytes)
+			s.tracer.RecordError(item.ctx, errs[i], map[string]interface{}{
+				"elasticsearch.status_code": esResponse["status"],
+			})
+			continue
+		}
+
+		responses[i] = s.completeSearch(item.ctx, item.req, esResponse, item.span, startTime, item.costWarnings, item.useCache)
+	}
+
+	return responses, errs
+}
+
+// executeMultiSearch sends an NDJSON _msearch body and returns the item responses in order
+func (s *SearchService) executeMultiSearch(ctx context.Context, body string, startTime time.Time) ([]map[string]interface{}, error) {
+	ctx, esSpan := s.tracer.TraceElasticsearchOperation(ctx, "POST", "/_msearch", nil)
+	defer esSpan.End()
+
+	msearchReq := esapi.MsearchRequest{
+		Body: strings.NewReader(body),
+	}
+
+	res, err := msearchReq.Do(ctx, s.esClient)
+	if err != nil {
+		s.tracer.RecordError(ctx, err, map[string]interface{}{
+			"operation": "msearch",
+		})
+		return nil, fmt.Errorf("msearch request failed: %w", err)
+	}
+	defer res.Body.Close()
+
+	if res.IsError() {
+		err := fmt.Errorf("msearch failed: %s", res.String())
+		s.tracer.RecordElasticsearchResult(ctx, res.StatusCode, 0, time.Since(startTime))
+		s.tracer.RecordError(ctx, err, map[string]interface{}{
+			"elasticsearch.status_code": res.StatusCode,
+		})
+		return nil, err
+	}
+
+	responseBody, err := io.ReadAll(res.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read msearch response: %w", err)
+	}
+
+	var response struct {
+		Responses []map[string]interface{} `json:"responses"`
+	}
+	if err := json.Unmarshal(responseBody, &response); err != nil {
+		s.tracer.RecordError(ctx, err, map[string]interface{}{
+			"operation": "parse_response",
+		})
+		return nil, fmt.Errorf("failed to parse msearch response: %w", err)
+	}
+
+	s.tracer.RecordElasticsearchResult(ctx, res.StatusCode, len(responseBody), time.Since(startTime))
+
+	return response.Responses, nil
+}
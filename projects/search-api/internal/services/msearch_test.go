@@ -0,0 +1,95 @@
+package services
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/saif-islam/es-playground/projects/search-api/internal/models"
+)
+
+func TestMultiSearch(t *testing.T) {
+	var requests []string
+	var lines []string
+	s := newFakeESService(t, func(w http.ResponseWriter, r *http.Request) {
+		requests = append(requests, r.Method+" "+r.URL.Path)
+		scanner := bufio.NewScanner(r.Body)
+		for scanner.Scan() {
+			lines = append(lines, scanner.Text())
+		}
+		w.Write([]byte(`{
+		  "took": 5,
+		  "responses": [
+		    {"took": 3, "timed_out": false, "hits": {"total": {"value": 2, "relation": "eq"}, "hits": [{"_index": "products", "_id": "1", "_score": 1.2, "_source": {"title": "Laptop"}}]}, "status": 200},
+		    {"error": {"root_cause": [{"type": "index_not_found_exception", "reason": "no such index [missing]"}], "type": "index_not_found_exception", "reason": "no such index [missing]"}, "status": 404}
+		  ]
+		}`))
+	})
+
+	responses, errs := s.MultiSearch(context.Background(), []*models.SearchRequest{
+		{Index: "products", Query: "laptop", QueryType: "match", Fields: []string{"title"}, Size: 10},
+		{Index: "products", Query: "laptop", QueryType: "match", Size: 10}, // no field to match on
+		{Index: "missing", Size: 10},
+	})
+
+	if len(requests) != 1 || requests[0] != "POST /_msearch" {
+		t.Fatalf("Expected a single POST /_msearch, got %v", requests)
+	}
+	if len(lines) != 4 || lines[0] != `{"index":"products"}` || lines[2] != `{"index":"missing"}` {
+		t.Errorf("Expected a header and body for each valid search, got %v", lines)
+	}
+
+	if errs[0] != nil || responses[0] == nil || responses[0].Total.Value != 2 || len(responses[0].Hits) != 1 {
+		t.Errorf("Expected the first search to succeed with 2 hits, got %+v, %v", responses[0], errs[0])
+	}
+	if !errors.Is(errs[1], ErrInvalidQuery) || responses[1] != nil {
+		t.Errorf("Expected the invalid search to fail before reaching Elasticsearch, got %v", errs[1])
+	}
+	if errs[2] == nil || !strings.Contains(errs[2].Error(), "index_not_found_exception") || responses[2] != nil {
+		t.Errorf("Expected the item error from Elasticsearch for the missing index, got %v", errs[2])
+	}
+}
+
+func TestMultiSearch_PointInTime(t *testing.T) {
+	var header string
+	s := newFakeESService(t, func(w http.ResponseWriter, r *http.Request) {
+		scanner := bufio.NewScanner(r.Body)
+		if scanner.Scan() {
+			header = scanner.Text()
+		}
+		w.Write([]byte(`{"responses":[{"pit_id":"` + pitID + `","hits":{"total":{"value":0,"relation":"eq"},"hits":[]},"status":200}]}`))
+	})
+
+	responses, errs := s.MultiSearch(context.Background(), []*models.SearchRequest{
+		{Index: "logs", Size: 10, PIT: &models.PointInTime{ID: pitID}},
+	})
+	if errs[0] != nil {
+		t.Fatalf("Unexpected error: %v", errs[0])
+	}
+	if header != `{}` {
+		t.Errorf("Expected no index in the header of a point-in-time search, got %s", header)
+	}
+	if responses[0].PITID != pitID {
+		t.Errorf("Expected the point in time ID, got %q", responses[0].PITID)
+	}
+}
+
+func TestMultiSearch_RequestFailure(t *testing.T) {
+	s := newFakeESService(t, func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte(`{"error":{"type":"illegal_argument_exception","reason":"The msearch request must be terminated by a newline [\n]"},"status":400}`))
+	})
+
+	responses, errs := s.MultiSearch(context.Background(), []*models.SearchRequest{
+		{Index: "products", Size: 10},
+		{Index: "logs", Size: 10},
+	})
+	for i := range errs {
+		if errs[i] == nil || responses[i] != nil {
+			t.Errorf("Expected search %d to carry the request error, got %+v, %v", i, responses[i], errs[i])
+		}
+	}
+}
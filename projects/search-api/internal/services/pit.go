@@ -0,0 +1,83 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/elastic/go-elasticsearch/v8/esapi"
+	"go.uber.org/zap"
+
+	"github.com/saif-islam/es-playground/projects/search-api/internal/models"
+)
+
+// defaultPITKeepAlive is used when a point in time is opened without a keep-alive
+const defaultPITKeepAlive = "1m"
+
+// OpenPIT opens a point-in-time reader on an index. Searches that reference the returned
+// ID see the index as it was when the reader was opened, even while it is being written to.
+func (s *SearchService) OpenPIT(ctx context.Context, index, keepAlive string) (*models.PITResponse, error) {
+	if keepAlive == "" {
+		keepAlive = defaultPITKeepAlive
+	}
+
+	openReq := esapi.OpenPointInTimeRequest{
+		Index:     []string{index},
+		KeepAlive: keepAlive,
+	}
+
+	res, err := openReq.Do(ctx, s.esClient)
+	if err != nil {
+		return nil, fmt.Errorf("open point in time request failed: %w", err)
+	}
+	defer res.Body.Close()
+
+	if res.IsError() {
+		return nil, fmt.Errorf("open point in time failed: %s", res.String())
+	}
+
+	var pit struct {
+		ID string `json:"id"`
+	}
+	if err := json.NewDecoder(res.Body).Decode(&pit); err != nil {
+		return nil, fmt.Errorf("failed to parse point in time response: %w", err)
+	}
+
+	s.logger.Info("Opened point in time",
+		zap.String("index", index),
+		zap.String("keep_alive", keepAlive))
+
+	return &models.PITResponse{
+		ID:        pit.ID,
+		Index:     index,
+		KeepAlive: keepAlive,
+		Timestamp: time.Now(),
+	}, nil
+}
+
+// ClosePIT releases a point-in-time reader so its segments can be merged away
+func (s *SearchService) ClosePIT(ctx context.Context, id string) error {
+	body, err := json.Marshal(map[string]string{"id": id})
+	if err != nil {
+		return fmt.Errorf("failed to marshal close request: %w", err)
+	}
+
+	closeReq := esapi.ClosePointInTimeRequest{
+		Body: strings.NewReader(string(body)),
+	}
+
+	res, err := closeReq.Do(ctx, s.esClient)
+	if err != nil {
+		return fmt.Errorf("close point in time request failed: %w", err)
+	}
+	defer res.Body.Close()
+
+	if res.IsError() {
+		return fmt.Errorf("close point in time failed: %s", res.String())
+	}
+
+	s.logger.Info("Closed point in time")
+	return nil
+}
@@ -0,0 +1,179 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"reflect"
+	"testing"
+
+	"github.com/saif-islam/es-playground/projects/search-api/internal/models"
+)
+
+const pitID = "46ToAwMDaWR5BXV1aWQyKwZub2RlXzMAAAAAAAAAACoBYwADaWR4BXV1aWQxAgZub2RlXzEAAAAAAAAAAAEBYQADaWR5BXV1aWQyKgZub2RlXzIAAAAAAAAAAAwBYgACBXV1aWQyAAAFdXVpZDEAAQltYXRjaF9hbGw_gAAAAA=="
+
+func TestOpenPIT(t *testing.T) {
+	tests := []struct {
+		name              string
+		keepAlive         string
+		expectedKeepAlive string
+	}{
+		{name: "default keep alive", expectedKeepAlive: "1m"},
+		{name: "explicit keep alive", keepAlive: "5m", expectedKeepAlive: "5m"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var path, keepAlive string
+			s := newFakeESService(t, func(w http.ResponseWriter, r *http.Request) {
+				path = r.Method + " " + r.URL.Path
+				keepAlive = r.URL.Query().Get("keep_alive")
+				w.Write([]byte(`{"id":"` + pitID + `"}`))
+			})
+
+			pit, err := s.OpenPIT(context.Background(), "logs", tt.keepAlive)
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+
+			if path != "POST /logs/_pit" || keepAlive != tt.expectedKeepAlive {
+				t.Errorf("Expected POST /logs/_pit with keep_alive %s, got %s with %s", tt.expectedKeepAlive, path, keepAlive)
+			}
+			if pit.ID != pitID || pit.Index != "logs" || pit.KeepAlive != tt.expectedKeepAlive {
+				t.Errorf("Expected the point in time opened on logs, got %+v", pit)
+			}
+		})
+	}
+}
+
+func TestClosePIT(t *testing.T) {
+	var path string
+	var body map[string]string
+	s := newFakeESService(t, func(w http.ResponseWriter, r *http.Request) {
+		path = r.Method + " " + r.URL.Path
+		payload, _ := io.ReadAll(r.Body)
+		json.Unmarshal(payload, &body)
+		w.Write([]byte(`{"succeeded":true,"num_freed":3}`))
+	})
+
+	if err := s.ClosePIT(context.Background(), pitID); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if path != "DELETE /_pit" || body["id"] != pitID {
+		t.Errorf("Expected DELETE /_pit with the ID in the body, got %s with %v", path, body)
+	}
+}
+
+func TestPIT_Errors(t *testing.T) {
+	s := newFakeESService(t, func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+		w.Write([]byte(`{"error":{"type":"index_not_found_exception","reason":"no such index [missing]"},"status":404}`))
+	})
+
+	if _, err := s.OpenPIT(context.Background(), "missing", ""); err == nil {
+		t.Error("Expected opening a point in time on a missing index to fail")
+	}
+	if err := s.ClosePIT(context.Background(), pitID); err == nil {
+		t.Error("Expected closing an unknown point in time to fail")
+	}
+}
+
+func TestBuildQueryWithPointInTime(t *testing.T) {
+	s := &SearchService{}
+
+	query, err := s.buildElasticsearchQuery(&models.SearchRequest{
+		Query:       "error",
+		QueryType:   "match",
+		Fields:      []string{"message"},
+		From:        20,
+		Size:        10,
+		Sort:        []models.SortField{{Field: "@timestamp", Order: "desc"}},
+		PIT:         &models.PointInTime{ID: pitID, KeepAlive: "2m"},
+		SearchAfter: []interface{}{float64(1700000000000), "doc-42"},
+	})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	var parsed map[string]interface{}
+	if err := json.Unmarshal([]byte(query), &parsed); err != nil {
+		t.Fatalf("Failed to parse query: %v", err)
+	}
+
+	expectedPIT := map[string]interface{}{"id": pitID, "keep_alive": "2m"}
+	if !reflect.DeepEqual(parsed["pit"], expectedPIT) {
+		t.Errorf("Expected pit %v, got %v", expectedPIT, parsed["pit"])
+	}
+	expectedAfter := []interface{}{float64(1700000000000), "doc-42"}
+	if !reflect.DeepEqual(parsed["search_after"], expectedAfter) {
+		t.Errorf("Expected search_after %v, got %v", expectedAfter, parsed["search_after"])
+	}
+	if _, ok := parsed["from"]; ok {
+		t.Errorf("Expected search_after to replace from, got from %v", parsed["from"])
+	}
+}
+
+func TestSearchReturnsNextSearchAfter(t *testing.T) {
+	tests := []struct {
+		name         string
+		sort         []models.SortField
+		hits         string
+		expectedSort string // sort sent to Elasticsearch
+		expectedNext []interface{}
+	}{
+		{
+			name:         "full page with a point in time",
+			hits:         `[{"_id":"1","_score":2.0,"sort":[2.0,41]},{"_id":"2","_score":1.5,"sort":[1.5,87]}]`,
+			expectedSort: `["_score","_shard_doc"]`,
+			expectedNext: []interface{}{1.5, float64(87)},
+		},
+		{
+			name:         "full page with a sort",
+			sort:         []models.SortField{{Field: "@timestamp", Order: "desc"}},
+			hits:         `[{"_id":"1","sort":[1700000002000,41]},{"_id":"2","sort":[1700000001000,87]}]`,
+			expectedSort: `[{"@timestamp":{"order":"desc"}}]`,
+			expectedNext: []interface{}{float64(1700000001000), float64(87)},
+		},
+		{
+			name:         "short page is the last",
+			hits:         `[{"_id":"1","_score":2.0,"sort":[2.0,41]}]`,
+			expectedSort: `["_score","_shard_doc"]`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var path string
+			var body map[string]json.RawMessage
+			s := newFakeESService(t, func(w http.ResponseWriter, r *http.Request) {
+				path = r.URL.Path
+				json.NewDecoder(r.Body).Decode(&body)
+				w.Write([]byte(`{"pit_id":"` + pitID + `","took":2,"hits":{"total":{"value":3,"relation":"eq"},"hits":` + tt.hits + `}}`))
+			})
+
+			response, err := s.Search(context.Background(), &models.SearchRequest{
+				Index: "logs",
+				Size:  2,
+				Sort:  tt.sort,
+				PIT:   &models.PointInTime{ID: pitID, KeepAlive: "1m"},
+			})
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+
+			if path != "/_search" {
+				t.Errorf("Expected a point-in-time search without an index, got %s", path)
+			}
+			if string(body["sort"]) != tt.expectedSort {
+				t.Errorf("Expected sort %s, got %s", tt.expectedSort, body["sort"])
+			}
+			if !reflect.DeepEqual(response.SearchAfter, tt.expectedNext) {
+				t.Errorf("Expected search_after %v, got %v", tt.expectedNext, response.SearchAfter)
+			}
+			if response.PITID != pitID {
+				t.Errorf("Expected the point in time ID to be returned, got %q", response.PITID)
+			}
+		})
+	}
+}
@@ -0,0 +1,224 @@
+package services
+
+import (
+	"fmt"
+	"path"
+	"sort"
+	"strings"
+
+	"github.com/saif-islam/es-playground/projects/search-api/internal/models"
+)
+
+// defaultRedactionMask replaces masked values when a rule sets no mask of its own
+const defaultRedactionMask = "****"
+
+// Redactor masks or removes sensitive _source fields from search hits according to
+// per-index rules, along with the sort values that repeat them. Aggregations on those
+// fields are rejected. Callers holding the elevated scope see documents unchanged.
+type Redactor struct {
+	elevatedScope string
+	rules         map[string][]models.RedactionRule
+}
+
+// NewRedactor creates a redactor from configuration, or returns nil when no rules are configured
+func NewRedactor(config models.RedactionConfig) *Redactor {
+	if len(config.Indices) == 0 {
+		return nil
+	}
+
+	return &Redactor{
+		elevatedScope: config.ElevatedScope,
+		rules:         config.Indices,
+	}
+}
+
+// Elevated reports whether the given scopes allow unredacted results
+func (r *Redactor) Elevated(scopes []string) bool {
+	if r.elevatedScope == "" {
+		return false
+	}
+	for _, scope := range scopes {
+		if scope == r.elevatedScope {
+			return true
+		}
+	}
+	return false
+}
+
+// RedactHit applies the rules of the hit's index, or of the requested index or alias
+// when the hit's own index has none, to its source, highlights and sort values.
+// sortFields names the field behind each sort value; when it is nil, as for inner hits
+// sorted by their own config, the sort values are dropped instead.
+func (r *Redactor) RedactHit(hit *models.SearchHit, requestedIndex string, sortFields []string) {
+	rules := r.rulesFor(hit.Index)
+	if len(rules) == 0 && requestedIndex != "" {
+		rules = r.rulesFor(requestedIndex)
+	}
+	if len(rules) == 0 {
+		return
+	}
+
+	if sortFields == nil {
+		hit.Sort = nil
+	}
+
+	source, _ := hit.Source.(map[string]interface{})
+	for _, rule := range rules {
+		if source != nil {
+			redactField(source, rule.Field, rule)
+		}
+
+		// Highlight fragments quote the field's content, so they are dropped either way
+		for field := range hit.Highlight {
+			if coversField(rule, field) {
+				delete(hit.Highlight, field)
+			}
+		}
+
+		// A sort value is the sorted field's value, and the last hit's become the
+		// search_after cursor
+		for i, field := range sortFields {
+			if i < len(hit.Sort) && coversField(rule, field) {
+				hit.Sort[i] = maskValue(hit.Sort[i], rule)
+			}
+		}
+	}
+}
+
+// RedactsAny reports whether a search of index may redact any of the fields
+func (r *Redactor) RedactsAny(index string, fields []string) bool {
+	for _, rule := range r.requestRules(index) {
+		for _, field := range fields {
+			if coversField(rule, field) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// CheckAggregations rejects aggregations, including sub-aggregations, over a field a
+// search of index redacts: their buckets and metrics would reveal the hidden values
+func (r *Redactor) CheckAggregations(index string, aggs map[string]models.AggregationConfig) error {
+	rules := r.requestRules(index)
+	if len(rules) == 0 {
+		return nil
+	}
+
+	names := make([]string, 0, len(aggs))
+	for name := range aggs {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		config := aggs[name]
+		if config.Field != "" {
+			for _, rule := range rules {
+				if coversField(rule, config.Field) {
+					return fmt.Errorf("%w: aggregation %q is on the redacted field %s", ErrInvalidQuery, name, config.Field)
+				}
+			}
+		}
+		if err := r.CheckAggregations(index, config.SubAggs); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// requestRules returns the rules that may apply to a search of index, a comma-separated
+// list of names and patterns. An alias can't be resolved to its indices here, so when
+// no rule matches by name every rule applies.
+func (r *Redactor) requestRules(index string) []models.RedactionRule {
+	var matched []models.RedactionRule
+	for _, name := range strings.Split(index, ",") {
+		matched = append(matched, r.rulesFor(strings.TrimSpace(name))...)
+	}
+	if len(matched) > 0 {
+		return matched
+	}
+
+	for _, rules := range r.rules {
+		matched = append(matched, rules...)
+	}
+	return matched
+}
+
+// rulesFor returns the rules whose index pattern matches the index name
+func (r *Redactor) rulesFor(index string) []models.RedactionRule {
+	if rules, ok := r.rules[index]; ok {
+		return rules
+	}
+
+	var matched []models.RedactionRule
+	for pattern, rules := range r.rules {
+		if ok, _ := path.Match(pattern, index); ok {
+			matched = append(matched, rules...)
+		}
+	}
+	return matched
+}
+
+// coversField reports whether a rule hides field, or one of its subfields such as the
+// keyword subfield of a text field
+func coversField(rule models.RedactionRule, field string) bool {
+	return field == rule.Field || strings.HasPrefix(field, rule.Field+".")
+}
+
+// redactField applies a rule to a dotted field path, descending into nested objects and
+// arrays of objects. A key that itself contains the remaining dots is also matched.
+func redactField(source map[string]interface{}, field string, rule models.RedactionRule) {
+	if value, ok := source[field]; ok {
+		if rule.Action == "remove" {
+			delete(source, field)
+		} else {
+			source[field] = maskValue(value, rule)
+		}
+	}
+
+	head, rest, nested := strings.Cut(field, ".")
+	if !nested {
+		return
+	}
+
+	switch child := source[head].(type) {
+	case map[string]interface{}:
+		redactField(child, rest, rule)
+	case []interface{}:
+		for _, item := range child {
+			if object, ok := item.(map[string]interface{}); ok {
+				redactField(object, rest, rule)
+			}
+		}
+	}
+}
+
+// maskValue replaces a value with the rule's mask, keeping the last characters of strings
+// visible if configured. Every element of an array is masked.
+func maskValue(value interface{}, rule models.RedactionRule) interface{} {
+	mask := rule.Mask
+	if mask == "" {
+		mask = defaultRedactionMask
+	}
+
+	switch v := value.(type) {
+	case []interface{}:
+		masked := make([]interface{}, len(v))
+		for i, item := range v {
+			masked[i] = maskValue(item, rule)
+		}
+		return masked
+	case string:
+		runes := []rune(v)
+		// Showing the whole value (or all but one character) would defeat the mask
+		if rule.KeepLast > 0 && rule.KeepLast < len(runes)-1 {
+			return mask + string(runes[len(runes)-rule.KeepLast:])
+		}
+		return mask
+	case nil:
+		return nil
+	default:
+		return mask
+	}
+}
@@ -0,0 +1,341 @@
+package services
+
+import (
+	"encoding/json"
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/saif-islam/es-playground/projects/search-api/internal/models"
+)
+
+func newTestRedactor() *Redactor {
+	return NewRedactor(models.RedactionConfig{
+		ElevatedScope: "pii:read",
+		Indices: map[string][]models.RedactionRule{
+			"customers-*": {
+				{Field: "email", KeepLast: 4},
+				{Field: "ssn", Action: "remove"},
+			},
+			"orders": {
+				{Field: "card.number", Mask: "XXXX", KeepLast: 4},
+			},
+		},
+	})
+}
+
+func TestNewRedactor(t *testing.T) {
+	if NewRedactor(models.RedactionConfig{ElevatedScope: "pii:read"}) != nil {
+		t.Error("Expected no redactor without rules")
+	}
+
+	r := newTestRedactor()
+	if !r.Elevated([]string{"search", "pii:read"}) || r.Elevated([]string{"search"}) {
+		t.Error("Expected only the elevated scope to see documents unredacted")
+	}
+	if (&Redactor{}).Elevated([]string{""}) {
+		t.Error("Expected no caller to be elevated without an elevated scope")
+	}
+}
+
+func TestRulesFor(t *testing.T) {
+	r := newTestRedactor()
+
+	tests := []struct {
+		index    string
+		expected []string
+	}{
+		{index: "orders", expected: []string{"card.number"}},
+		{index: "customers-2024", expected: []string{"email", "ssn"}},
+		{index: "customers", expected: nil},
+		{index: "orders-2024", expected: nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.index, func(t *testing.T) {
+			var fields []string
+			for _, rule := range r.rulesFor(tt.index) {
+				fields = append(fields, rule.Field)
+			}
+			if !reflect.DeepEqual(fields, tt.expected) {
+				t.Errorf("Expected rules for %v, got %v", tt.expected, fields)
+			}
+		})
+	}
+}
+
+func TestMaskValue(t *testing.T) {
+	tests := []struct {
+		name     string
+		value    interface{}
+		rule     models.RedactionRule
+		expected interface{}
+	}{
+		{name: "default mask", value: "jane@example.com", expected: "****"},
+		{name: "custom mask", value: "jane@example.com", rule: models.RedactionRule{Mask: "[hidden]"}, expected: "[hidden]"},
+		{name: "keep last", value: "4111111111111111", rule: models.RedactionRule{KeepLast: 4}, expected: "****1111"},
+		{name: "keep last counts runes", value: "ñandú-1234", rule: models.RedactionRule{KeepLast: 4}, expected: "****1234"},
+		{name: "keep last would show the value", value: "12345", rule: models.RedactionRule{KeepLast: 4}, expected: "****"},
+		{name: "number", value: float64(42), expected: "****"},
+		{name: "nil", value: nil, expected: nil},
+		{name: "array", value: []interface{}{"a@example.com", float64(1)}, expected: []interface{}{"****", "****"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := maskValue(tt.value, tt.rule); !reflect.DeepEqual(got, tt.expected) {
+				t.Errorf("Expected %v, got %v", tt.expected, got)
+			}
+		})
+	}
+}
+
+func TestRedactField(t *testing.T) {
+	tests := []struct {
+		name     string
+		source   map[string]interface{}
+		rule     models.RedactionRule
+		expected map[string]interface{}
+	}{
+		{
+			name:     "top-level mask",
+			source:   map[string]interface{}{"email": "jane@example.com", "name": "Jane"},
+			rule:     models.RedactionRule{Field: "email"},
+			expected: map[string]interface{}{"email": "****", "name": "Jane"},
+		},
+		{
+			name:     "top-level remove",
+			source:   map[string]interface{}{"ssn": "123-45-6789", "name": "Jane"},
+			rule:     models.RedactionRule{Field: "ssn", Action: "remove"},
+			expected: map[string]interface{}{"name": "Jane"},
+		},
+		{
+			name:     "nested object",
+			source:   map[string]interface{}{"contact": map[string]interface{}{"email": "jane@example.com", "city": "Oslo"}},
+			rule:     models.RedactionRule{Field: "contact.email"},
+			expected: map[string]interface{}{"contact": map[string]interface{}{"email": "****", "city": "Oslo"}},
+		},
+		{
+			name: "array of objects",
+			source: map[string]interface{}{"contacts": []interface{}{
+				map[string]interface{}{"email": "a@example.com"},
+				map[string]interface{}{"email": "b@example.com"},
+				"not an object",
+			}},
+			rule: models.RedactionRule{Field: "contacts.email", Action: "remove"},
+			expected: map[string]interface{}{"contacts": []interface{}{
+				map[string]interface{}{},
+				map[string]interface{}{},
+				"not an object",
+			}},
+		},
+		{
+			name:     "dotted key",
+			source:   map[string]interface{}{"contact.email": "jane@example.com"},
+			rule:     models.RedactionRule{Field: "contact.email"},
+			expected: map[string]interface{}{"contact.email": "****"},
+		},
+		{
+			name:     "missing field",
+			source:   map[string]interface{}{"name": "Jane", "contact": "none"},
+			rule:     models.RedactionRule{Field: "contact.email"},
+			expected: map[string]interface{}{"name": "Jane", "contact": "none"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			redactField(tt.source, tt.rule.Field, tt.rule)
+			if !reflect.DeepEqual(tt.source, tt.expected) {
+				t.Errorf("Expected %v, got %v", tt.expected, tt.source)
+			}
+		})
+	}
+}
+
+func TestRedactHit(t *testing.T) {
+	r := newTestRedactor()
+
+	hit := models.SearchHit{
+		Index:     "customers-2024",
+		Source:    map[string]interface{}{"email": "jane@example.com", "ssn": "123-45-6789", "name": "Jane"},
+		Highlight: map[string][]string{"email.keyword": {"<em>jane</em>@example.com"}, "name": {"<em>Jane</em>"}},
+		Sort:      []interface{}{"jane@example.com", float64(3)},
+	}
+	r.RedactHit(&hit, "customers", []string{"email.keyword", "visits"})
+
+	expectedSource := map[string]interface{}{"email": "****.com", "name": "Jane"}
+	if !reflect.DeepEqual(hit.Source, expectedSource) {
+		t.Errorf("Expected source %v, got %v", expectedSource, hit.Source)
+	}
+	if _, ok := hit.Highlight["email.keyword"]; ok || len(hit.Highlight) != 1 {
+		t.Errorf("Expected only the name highlight to remain, got %v", hit.Highlight)
+	}
+	if expected := []interface{}{"****.com", float64(3)}; !reflect.DeepEqual(hit.Sort, expected) {
+		t.Errorf("Expected the email sort value to be masked, got %v", hit.Sort)
+	}
+
+	// Inner hits are sorted by their own config, so their sort values can't be matched to fields
+	inner := models.SearchHit{Index: "customers-2024", Sort: []interface{}{"jane@example.com"}}
+	r.RedactHit(&inner, "", nil)
+	if inner.Sort != nil {
+		t.Errorf("Expected the sort values of an inner hit to be dropped, got %v", inner.Sort)
+	}
+
+	// The requested alias supplies the rules when the concrete index has none
+	aliased := models.SearchHit{Index: "crm-000001", Source: map[string]interface{}{"card": map[string]interface{}{"number": "4111111111111111"}}}
+	r.RedactHit(&aliased, "orders", []string{})
+	if expected := map[string]interface{}{"card": map[string]interface{}{"number": "XXXX1111"}}; !reflect.DeepEqual(aliased.Source, expected) {
+		t.Errorf("Expected the requested index's rules to apply, got %v", aliased.Source)
+	}
+
+	unrelated := models.SearchHit{Index: "products", Source: map[string]interface{}{"email": "shop@example.com"}, Sort: []interface{}{"shop@example.com"}}
+	r.RedactHit(&unrelated, "products", nil)
+	if unrelated.Source.(map[string]interface{})["email"] != "shop@example.com" || unrelated.Sort == nil {
+		t.Errorf("Expected a hit without rules to be unchanged, got %+v", unrelated)
+	}
+}
+
+func TestRedactor_CheckAggregations(t *testing.T) {
+	r := newTestRedactor()
+
+	tests := []struct {
+		name        string
+		index       string
+		aggs        map[string]models.AggregationConfig
+		expectError bool
+	}{
+		{
+			name:  "unredacted field",
+			index: "customers-*",
+			aggs:  map[string]models.AggregationConfig{"names": {Type: "terms", Field: "name.keyword"}},
+		},
+		{
+			name:        "redacted field",
+			index:       "customers-*",
+			aggs:        map[string]models.AggregationConfig{"emails": {Type: "terms", Field: "email"}},
+			expectError: true,
+		},
+		{
+			name:        "keyword subfield of a redacted field",
+			index:       "customers-2024",
+			aggs:        map[string]models.AggregationConfig{"emails": {Type: "cardinality", Field: "email.keyword"}},
+			expectError: true,
+		},
+		{
+			name:  "field that only shares a prefix",
+			index: "customers-2024",
+			aggs:  map[string]models.AggregationConfig{"domains": {Type: "terms", Field: "email_domain"}},
+		},
+		{
+			name:  "sub-aggregation on a redacted field",
+			index: "products,customers-2024",
+			aggs: map[string]models.AggregationConfig{"cities": {Type: "terms", Field: "city", SubAggs: map[string]models.AggregationConfig{
+				"ssns": {Type: "terms", Field: "ssn"},
+			}}},
+			expectError: true,
+		},
+		{
+			name:  "field redacted in another index",
+			index: "orders",
+			aggs:  map[string]models.AggregationConfig{"emails": {Type: "terms", Field: "email"}},
+		},
+		{
+			name:        "alias that matches no rule",
+			index:       "crm",
+			aggs:        map[string]models.AggregationConfig{"cards": {Type: "terms", Field: "card.number"}},
+			expectError: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := r.CheckAggregations(tt.index, tt.aggs)
+			if tt.expectError && !errors.Is(err, ErrInvalidQuery) {
+				t.Errorf("Expected ErrInvalidQuery, got %v", err)
+			}
+			if !tt.expectError && err != nil {
+				t.Errorf("Unexpected error: %v", err)
+			}
+		})
+	}
+}
+
+func TestBuildQueryRejectsRedactedAggregations(t *testing.T) {
+	s := &SearchService{redactor: newTestRedactor()}
+	req := &models.SearchRequest{
+		Index:        "customers-*",
+		Aggregations: map[string]models.AggregationConfig{"emails": {Type: "terms", Field: "email.keyword"}},
+	}
+
+	if _, err := s.buildElasticsearchQuery(req); !errors.Is(err, ErrInvalidQuery) {
+		t.Errorf("Expected ErrInvalidQuery for an aggregation on a redacted field, got %v", err)
+	}
+
+	req.Scopes = []string{"pii:read"}
+	if _, err := s.buildElasticsearchQuery(req); err != nil {
+		t.Errorf("Expected the elevated scope to aggregate on any field, got %v", err)
+	}
+}
+
+func TestTransformSearchResponseRedactsSortValues(t *testing.T) {
+	esResponse := map[string]interface{}{
+		"hits": map[string]interface{}{
+			"total": map[string]interface{}{"value": float64(2), "relation": "eq"},
+			"hits": []interface{}{
+				map[string]interface{}{"_index": "customers-2024", "_id": "1", "_source": map[string]interface{}{"email": "ann@example.com"}, "sort": []interface{}{"ann@example.com", "1"}},
+				map[string]interface{}{"_index": "customers-2024", "_id": "2", "_source": map[string]interface{}{"email": "bob@example.com"}, "sort": []interface{}{"bob@example.com", "2"}},
+			},
+		},
+	}
+
+	tests := []struct {
+		name           string
+		sort           []models.SortField
+		scopes         []string
+		expectedSort   []interface{}
+		expectedCursor []interface{}
+	}{
+		{
+			name:         "sorted on a redacted field",
+			sort:         []models.SortField{{Field: "email.keyword", Order: "asc"}, {Field: "id", Order: "asc"}},
+			expectedSort: []interface{}{"****.com", "2"},
+		},
+		{
+			name:           "sorted on other fields",
+			sort:           []models.SortField{{Field: "signup", Order: "asc"}, {Field: "id", Order: "asc"}},
+			expectedSort:   []interface{}{"bob@example.com", "2"},
+			expectedCursor: []interface{}{"bob@example.com", "2"},
+		},
+		{
+			name:           "elevated caller",
+			sort:           []models.SortField{{Field: "email.keyword", Order: "asc"}, {Field: "id", Order: "asc"}},
+			scopes:         []string{"pii:read"},
+			expectedSort:   []interface{}{"bob@example.com", "2"},
+			expectedCursor: []interface{}{"bob@example.com", "2"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := &SearchService{redactor: newTestRedactor()}
+			req := &models.SearchRequest{Index: "customers-*", Size: 2, Sort: tt.sort, Scopes: tt.scopes}
+
+			// Each run needs its own copy, since redaction rewrites the hits in place
+			var raw map[string]interface{}
+			encoded, _ := json.Marshal(esResponse)
+			json.Unmarshal(encoded, &raw)
+			response := s.transformSearchResponse(raw, req)
+
+			if len(response.Hits) != 2 {
+				t.Fatalf("Expected 2 hits, got %d", len(response.Hits))
+			}
+			if got := response.Hits[1].Sort; !reflect.DeepEqual(got, tt.expectedSort) {
+				t.Errorf("Expected sort values %v, got %v", tt.expectedSort, got)
+			}
+			if !reflect.DeepEqual(response.SearchAfter, tt.expectedCursor) {
+				t.Errorf("Expected search_after %v, got %v", tt.expectedCursor, response.SearchAfter)
+			}
+		})
+	}
+}
@@ -0,0 +1,208 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/elastic/go-elasticsearch/v8/esapi"
+	"go.uber.org/zap"
+
+	"github.com/saif-islam/es-playground/projects/search-api/internal/models"
+)
+
+// Every open scroll keeps a search context on each shard it covers, and the segments it
+// started on can't be merged away until it ends. A long keep-alive lets scrolls that
+// clients abandon pile up on the cluster, so it is capped; clients should clear scrolls
+// they stop early rather than wait for them to expire.
+const (
+	defaultScrollKeepAlive = time.Minute
+	maxScrollKeepAlive     = 10 * time.Minute
+)
+
+// ErrScrollNotFound is returned when a scroll has expired or been cleared
+var ErrScrollNotFound = errors.New("scroll not found")
+
+// OpenScroll runs a search as the first page of a scroll, a fallback for clients that
+// can't manage point-in-time readers. Pages are fetched with Scroll until one comes back
+// empty, at which point the scroll is cleared. Results are in index order unless the
+// search is sorted, which is the cheapest way to iterate every document.
+func (s *SearchService) OpenScroll(ctx context.Context, req *models.SearchRequest, keepAlive string) (*models.ScrollResponse, error) {
+	duration, err := parseScrollKeepAlive(keepAlive)
+	if err != nil {
+		return nil, err
+	}
+
+	switch {
+	case req.PIT != nil || len(req.SearchAfter) > 0:
+		return nil, fmt.Errorf("%w: a scroll cannot be combined with pit or search_after", ErrInvalidQuery)
+	case req.From > 0:
+		return nil, fmt.Errorf("%w: a scroll pages with scroll_id, not from", ErrInvalidQuery)
+	case req.KNN != nil:
+		return nil, fmt.Errorf("%w: knn searches cannot be scrolled", ErrInvalidQuery)
+	}
+
+	if s.costGuard != nil {
+		if _, err := s.costGuard.Check(req); err != nil {
+			return nil, err
+		}
+	}
+
+	scrollReq := *req
+	if len(scrollReq.Sort) == 0 {
+		scrollReq.Sort = []models.SortField{{Field: "_doc", Order: "asc"}}
+	}
+
+	query, err := s.buildElasticsearchQuery(&scrollReq)
+	if err != nil {
+		return nil, fmt.Errorf("failed to build query: %w", err)
+	}
+
+	searchReq := esapi.SearchRequest{
+		Index:  []string{req.Index},
+		Body:   strings.NewReader(query),
+		Scroll: duration,
+	}
+
+	res, err := searchReq.Do(ctx, s.esClient)
+	if err != nil {
+		return nil, fmt.Errorf("open scroll request failed: %w", err)
+	}
+	defer res.Body.Close()
+
+	if res.IsError() {
+		return nil, fmt.Errorf("open scroll failed: %s", res.String())
+	}
+
+	response, err := s.decodeScrollPage(ctx, res, req, duration)
+	if err != nil {
+		return nil, err
+	}
+
+	s.logger.Info("Opened scroll",
+		zap.String("index", req.Index),
+		zap.Duration("keep_alive", duration),
+		zap.Int64("total", response.Result.Total.Value))
+
+	return response, nil
+}
+
+// Scroll fetches the next page of a scroll, extending it by keepAlive. Scopes are those of
+// the caller fetching the page.
+func (s *SearchService) Scroll(ctx context.Context, scrollID, keepAlive string, scopes []string) (*models.ScrollResponse, error) {
+	duration, err := parseScrollKeepAlive(keepAlive)
+	if err != nil {
+		return nil, err
+	}
+
+	body, err := json.Marshal(map[string]string{"scroll_id": scrollID})
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal scroll request: %w", err)
+	}
+
+	// The ID goes in the body: it can be too long for a URL
+	scrollReq := esapi.ScrollRequest{
+		Body:   strings.NewReader(string(body)),
+		Scroll: duration,
+	}
+
+	res, err := scrollReq.Do(ctx, s.esClient)
+	if err != nil {
+		return nil, fmt.Errorf("scroll request failed: %w", err)
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode == http.StatusNotFound {
+		return nil, fmt.Errorf("%w: it expired or was cleared", ErrScrollNotFound)
+	}
+	if res.IsError() {
+		return nil, fmt.Errorf("scroll failed: %s", res.String())
+	}
+
+	return s.decodeScrollPage(ctx, res, &models.SearchRequest{Scopes: scopes}, duration)
+}
+
+// ClearScroll releases the search contexts of scrolls and returns how many were freed.
+// Scrolls that already expired are not an error.
+func (s *SearchService) ClearScroll(ctx context.Context, scrollIDs []string) (int, error) {
+	body, err := json.Marshal(map[string][]string{"scroll_id": scrollIDs})
+	if err != nil {
+		return 0, fmt.Errorf("failed to marshal clear scroll request: %w", err)
+	}
+
+	clearReq := esapi.ClearScrollRequest{
+		Body: strings.NewReader(string(body)),
+	}
+
+	res, err := clearReq.Do(ctx, s.esClient)
+	if err != nil {
+		return 0, fmt.Errorf("clear scroll request failed: %w", err)
+	}
+	defer res.Body.Close()
+
+	// A 404 means none of the scrolls were still open
+	if res.IsError() && res.StatusCode != http.StatusNotFound {
+		return 0, fmt.Errorf("clear scroll failed: %s", res.String())
+	}
+
+	var cleared struct {
+		NumFreed int `json:"num_freed"`
+	}
+	if err := json.NewDecoder(res.Body).Decode(&cleared); err != nil {
+		return 0, fmt.Errorf("failed to parse clear scroll response: %w", err)
+	}
+
+	s.logger.Info("Cleared scrolls",
+		zap.Int("requested", len(scrollIDs)),
+		zap.Int("freed", cleared.NumFreed))
+
+	return cleared.NumFreed, nil
+}
+
+// decodeScrollPage converts a page of scroll results to our format. An empty page ends
+// the scroll, so it is cleared right away instead of holding its contexts until it expires.
+func (s *SearchService) decodeScrollPage(ctx context.Context, res *esapi.Response, req *models.SearchRequest, keepAlive time.Duration) (*models.ScrollResponse, error) {
+	var esResponse map[string]interface{}
+	if err := json.NewDecoder(res.Body).Decode(&esResponse); err != nil {
+		return nil, fmt.Errorf("failed to parse scroll response: %w", err)
+	}
+
+	result := s.transformSearchResponse(esResponse, req)
+	result.Timestamp = time.Now()
+
+	response := &models.ScrollResponse{
+		ScrollID:  getString(esResponse, "_scroll_id"),
+		KeepAlive: keepAlive.String(),
+		Result:    result,
+		Timestamp: time.Now(),
+	}
+
+	if len(result.Hits) == 0 {
+		response.Done = true
+		if response.ScrollID != "" {
+			if _, err := s.ClearScroll(ctx, []string{response.ScrollID}); err != nil {
+				s.logger.Warn("Failed to clear exhausted scroll; it will expire on its own", zap.Error(err))
+			}
+			response.ScrollID = ""
+		}
+	}
+
+	return response, nil
+}
+
+// parseScrollKeepAlive parses an optional scroll keep-alive, capped at maxScrollKeepAlive
+func parseScrollKeepAlive(value string) (time.Duration, error) {
+	keepAlive, err := parseAsyncDuration(value, defaultScrollKeepAlive)
+	if err != nil || keepAlive <= 0 {
+		return 0, fmt.Errorf("%w: keep_alive %q is not a positive duration such as 1m", ErrInvalidQuery, value)
+	}
+	if keepAlive > maxScrollKeepAlive {
+		return 0, fmt.Errorf("%w: keep_alive %s is above the %s limit; page faster or clear the scroll and start again",
+			ErrInvalidQuery, keepAlive, maxScrollKeepAlive)
+	}
+	return keepAlive, nil
+}
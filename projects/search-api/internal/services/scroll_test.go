@@ -0,0 +1,213 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/saif-islam/es-playground/projects/search-api/internal/models"
+)
+
+// fakeScrollCluster is a fake Elasticsearch scrolling over five products the way the
+// search, _search/scroll and clear scroll APIs do: each open scroll keeps a context until
+// it is cleared
+type fakeScrollCluster struct {
+	mu         sync.Mutex
+	contexts   map[string]int // open scroll id to the offset of its next page
+	size       int
+	nextID     int
+	keepAlives []string // scroll parameter of every request that opened or extended a scroll
+	sorts      []interface{}
+}
+
+var scrollProducts = []string{"Gaming Laptop", "Laptop Stand", "Wireless Mouse", "USB-C Dock", "Mechanical Keyboard"}
+
+func newFakeScrollCluster() *fakeScrollCluster {
+	return &fakeScrollCluster{contexts: make(map[string]int)}
+}
+
+// page writes the hits from offset under scrollID
+func (f *fakeScrollCluster) page(w http.ResponseWriter, scrollID string, offset int) {
+	end := offset + f.size
+	if end > len(scrollProducts) {
+		end = len(scrollProducts)
+	}
+	hits := make([]string, 0, end-offset)
+	for i := offset; i < end; i++ {
+		hits = append(hits, fmt.Sprintf(`{"_index":"products","_id":"%d","_score":null,"_source":{"title":%q},"sort":[%d]}`, i+1, scrollProducts[i], i))
+	}
+	f.contexts[scrollID] = end
+	fmt.Fprintf(w, `{"_scroll_id":%q,"took":1,"timed_out":false,"_shards":{"total":1,"successful":1,"skipped":0,"failed":0},"hits":{"total":{"value":%d,"relation":"eq"},"max_score":null,"hits":[%s]}}`,
+		scrollID, len(scrollProducts), strings.Join(hits, ","))
+}
+
+func (f *fakeScrollCluster) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	switch {
+	case r.URL.Path == "/products/_search":
+		var body struct {
+			Size int           `json:"size"`
+			Sort []interface{} `json:"sort"`
+		}
+		json.NewDecoder(r.Body).Decode(&body)
+		f.size, f.sorts = body.Size, body.Sort
+		f.keepAlives = append(f.keepAlives, r.URL.Query().Get("scroll"))
+		f.nextID++
+		f.page(w, fmt.Sprintf("FGluY2x1ZGVfY29udGV4dF91dWlkDXF1ZXJ5QW5kRmV0Y2gB%d", f.nextID), 0)
+
+	case r.URL.Path == "/_search/scroll" && r.Method == http.MethodDelete:
+		var body struct {
+			ScrollID []string `json:"scroll_id"`
+		}
+		json.NewDecoder(r.Body).Decode(&body)
+		freed := 0
+		for _, id := range body.ScrollID {
+			if _, ok := f.contexts[id]; ok {
+				delete(f.contexts, id)
+				freed++
+			}
+		}
+		if freed == 0 {
+			w.WriteHeader(http.StatusNotFound)
+		}
+		fmt.Fprintf(w, `{"succeeded":true,"num_freed":%d}`, freed)
+
+	case r.URL.Path == "/_search/scroll":
+		var body struct {
+			ScrollID string `json:"scroll_id"`
+		}
+		json.NewDecoder(r.Body).Decode(&body)
+		offset, ok := f.contexts[body.ScrollID]
+		if !ok {
+			w.WriteHeader(http.StatusNotFound)
+			w.Write([]byte(`{"error":{"root_cause":[{"type":"search_context_missing_exception","reason":"No search context found for id [1]"}],"type":"search_phase_execution_exception","reason":"all shards failed"},"status":404}`))
+			return
+		}
+		f.keepAlives = append(f.keepAlives, r.URL.Query().Get("scroll"))
+		f.page(w, body.ScrollID, offset)
+
+	default:
+		w.WriteHeader(http.StatusNotFound)
+	}
+}
+
+func TestScroll(t *testing.T) {
+	cluster := newFakeScrollCluster()
+	s := newFakeESService(t, cluster.ServeHTTP)
+	ctx := context.Background()
+
+	page, err := s.OpenScroll(ctx, &models.SearchRequest{Index: "products", Query: "*", QueryType: "query_string", Size: 2}, "")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	expectedSort := []interface{}{map[string]interface{}{"_doc": map[string]interface{}{"order": "asc"}}}
+	if !reflect.DeepEqual(cluster.sorts, expectedSort) {
+		t.Errorf("Expected an unsorted scroll in _doc order, got %v", cluster.sorts)
+	}
+
+	// Page through every product, extending the scroll by 5m each time
+	var titles []string
+	pages := 0
+	for !page.Done {
+		pages++
+		if page.ScrollID == "" || page.Result.Total.Value != 5 {
+			t.Fatalf("Expected a scroll id and 5 matches on page %d, got %+v", pages, page)
+		}
+		for _, hit := range page.Result.Hits {
+			titles = append(titles, hit.Source.(map[string]interface{})["title"].(string))
+		}
+		if page, err = s.Scroll(ctx, page.ScrollID, "5m", nil); err != nil {
+			t.Fatalf("Unexpected error on page %d: %v", pages+1, err)
+		}
+	}
+
+	if pages != 3 || !reflect.DeepEqual(titles, scrollProducts) {
+		t.Errorf("Expected every product over 3 pages, got %v over %d", titles, pages)
+	}
+	expectedKeepAlives := []string{"60000ms", "300000ms", "300000ms", "300000ms"}
+	if !reflect.DeepEqual(cluster.keepAlives, expectedKeepAlives) {
+		t.Errorf("Expected keep-alives %v, got %v", expectedKeepAlives, cluster.keepAlives)
+	}
+	// The empty page clears the scroll rather than leaving it to expire
+	if page.ScrollID != "" || len(cluster.contexts) != 0 {
+		t.Errorf("Expected the exhausted scroll cleared, got id %q and %d open", page.ScrollID, len(cluster.contexts))
+	}
+	if _, err := s.Scroll(ctx, "FGluY2x1ZGVfY29udGV4dF91dWlkDXF1ZXJ5QW5kRmV0Y2gB1", "", nil); !errors.Is(err, ErrScrollNotFound) {
+		t.Errorf("Expected the cleared scroll not found, got %v", err)
+	}
+}
+
+func TestClearScroll(t *testing.T) {
+	cluster := newFakeScrollCluster()
+	s := newFakeESService(t, cluster.ServeHTTP)
+	ctx := context.Background()
+
+	var open []string
+	for i := 0; i < 2; i++ {
+		page, err := s.OpenScroll(ctx, &models.SearchRequest{Index: "products", Query: "*", QueryType: "query_string", Size: 2}, "2m")
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		open = append(open, page.ScrollID)
+	}
+
+	tests := []struct {
+		name          string
+		scrollIDs     []string
+		expectedFreed int
+	}{
+		{name: "open and expired scrolls", scrollIDs: []string{open[0], "expired"}, expectedFreed: 1},
+		{name: "only expired scrolls", scrollIDs: []string{open[0]}, expectedFreed: 0},
+		{name: "last open scroll", scrollIDs: []string{open[1]}, expectedFreed: 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			freed, err := s.ClearScroll(ctx, tt.scrollIDs)
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if freed != tt.expectedFreed {
+				t.Errorf("Expected %d freed, got %d", tt.expectedFreed, freed)
+			}
+		})
+	}
+	if len(cluster.contexts) != 0 {
+		t.Errorf("Expected no scrolls left open, got %v", cluster.contexts)
+	}
+}
+
+func TestOpenScroll_Invalid(t *testing.T) {
+	cluster := newFakeScrollCluster()
+	s := newFakeESService(t, cluster.ServeHTTP)
+
+	tests := []struct {
+		name      string
+		req       models.SearchRequest
+		keepAlive string
+	}{
+		{name: "keep-alive over the limit", keepAlive: "1h"},
+		{name: "keep-alive not a duration", keepAlive: "soon"},
+		{name: "offset paging", req: models.SearchRequest{From: 10}},
+		{name: "search_after", req: models.SearchRequest{SearchAfter: []interface{}{42}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := tt.req
+			req.Index, req.Query, req.QueryType, req.Size = "products", "*", "query_string", 2
+			if _, err := s.OpenScroll(context.Background(), &req, tt.keepAlive); !errors.Is(err, ErrInvalidQuery) {
+				t.Errorf("Expected ErrInvalidQuery, got %v", err)
+			}
+		})
+	}
+	if cluster.nextID != 0 {
+		t.Errorf("Expected no scroll opened, got %d", cluster.nextID)
+	}
+}
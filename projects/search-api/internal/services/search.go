@@ -3,13 +3,18 @@ package services
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"net/http"
+	"sort"
 	"strings"
 	"time"
 
-	"github.com/elastic/go-elasticsearch/v8"
+	"github.com/elastic/go-elasticsearch/v8/esapi"
+	"go.opentelemetry.io/otel/trace"
 	"go.uber.org/zap"
 
+	"github.com/saif-islam/es-playground/projects/search-api/internal/analytics"
 	"github.com/saif-islam/es-playground/projects/search-api/internal/cache"
 	"github.com/saif-islam/es-playground/projects/search-api/internal/metrics"
 	"github.com/saif-islam/es-playground/projects/search-api/internal/models"
@@ -18,23 +23,62 @@ import (
 	"github.com/saif-islam/es-playground/shared"
 )
 
+// kNN candidate defaults: each shard considers this many candidates per requested neighbour,
+// up to the Elasticsearch maximum
+const (
+	knnCandidatesPerResult = 10
+	maxKNNCandidates       = 10000
+)
+
+// defaultCollapseInnerHitsName names the inner hits of a collapsed group when the request
+// does not
+const defaultCollapseInnerHitsName = "collapsed"
+
+// ErrInvalidQuery is returned when a request cannot be turned into a query
+var ErrInvalidQuery = errors.New("invalid query")
+
+// ErrAliasNotFound is returned when an alias or index pattern matches no index
+var ErrAliasNotFound = errors.New("alias not found")
+
+// defaultWarmWindow is how far back cache warming looks for popular searches
+const defaultWarmWindow = time.Hour
+
 // SearchService handles advanced search operations with optimization focus
 type SearchService struct {
-	esClient      shared.ESClientInterface
+	esClient      *shared.ESClient
 	logger        *zap.Logger
 	analyticsHub  *realtime.AnalyticsHub
 	tracer        *tracing.SearchOperationTracer
 	cacheManager  *cache.CacheManager
+	analyticsSink *analytics.ESSink // optional, nil when persistence is disabled
+	redactor      *Redactor         // optional, nil when no redaction rules are configured
+	costGuard     *CostGuard        // optional, nil when the cost guard is disabled
+
+	// Searches with auto_suggest finding fewer results than this get a correction
+	autoSuggestThreshold int
+
+	// Per-request Elasticsearch timeout; async submits stop waiting before it
+	requestTimeout time.Duration
+
+	// Index patterns where highlighting every field is expensive, and whether such
+	// searches are rejected rather than warned about
+	textHeavyIndices       []string
+	requireHighlightFields bool
 }
 
 // NewSearchService creates a new search service
-func NewSearchService(esClient shared.ESClientInterface, logger *zap.Logger, analyticsHub *realtime.AnalyticsHub, tracer *tracing.SearchOperationTracer, cacheManager *cache.CacheManager) *SearchService {
+func NewSearchService(esClient *shared.ESClient, logger *zap.Logger, analyticsHub *realtime.AnalyticsHub, tracer *tracing.SearchOperationTracer, cacheManager *cache.CacheManager, analyticsSink *analytics.ESSink, redactor *Redactor, costGuard *CostGuard) *SearchService {
 	return &SearchService{
-		esClient:     esClient,
-		logger:       logger,
-		analyticsHub: analyticsHub,
-		tracer:       tracer,
-		cacheManager: cacheManager,
+		esClient:      esClient,
+		logger:        logger,
+		analyticsHub:  analyticsHub,
+		tracer:        tracer,
+		cacheManager:  cacheManager,
+		analyticsSink: analyticsSink,
+		redactor:      redactor,
+		costGuard:     costGuard,
+
+		autoSuggestThreshold: defaultAutoSuggestThreshold,
 	}
 }
 
@@ -46,33 +90,24 @@ func (s *SearchService) Search(ctx context.Context, req *models.SearchRequest) (
 	
 	startTime := time.Now()
 	
-	// Try cache first
-	if cachedResponse, found := s.cacheManager.GetCache().GetSearchResult(ctx, req); found {
-		s.tracer.RecordCacheOperation(ctx, "get", true, "search_result")
-		s.tracer.RecordSearchResult(ctx, cachedResponse.Total.Value, time.Since(startTime), true)
-		
-		// Update analytics for cache hit
-		if s.analyticsHub != nil {
-			analyticsEvent := realtime.SearchEvent{
-				Timestamp:    startTime,
-				QueryID:      req.RequestID,
-				Index:        req.Index,
-				Query:        req.Query,
-				QueryType:    req.QueryType,
-				ResponseTime: time.Since(startTime),
-				ResultCount:  cachedResponse.Total.Value,
-				Success:      true,
-				CacheHit:     true,
-				TraceID:      span.SpanContext().TraceID().String(),
-			}
-			s.analyticsHub.RecordSearchEvent(analyticsEvent)
+	// Reject clearly expensive queries before they reach the cache or the cluster
+	var costWarnings []string
+	if s.costGuard != nil {
+		warnings, err := s.costGuard.Check(req)
+		if err != nil {
+			return nil, err
 		}
-		
-		return cachedResponse, nil
+		costWarnings = warnings
 	}
+	costWarnings = append(costWarnings, s.highlightWarnings(req)...)
 	
-	// Cache miss - record it
-	s.tracer.RecordCacheOperation(ctx, "get", false, "search_result")
+	// Try cache first
+	useCache := cacheable(req)
+	if useCache {
+		if cachedResponse, found := s.lookupCachedSearch(ctx, req, span, startTime); found {
+			return cachedResponse, nil
+		}
+	}
 	
 	// Build Elasticsearch query
 	query, err := s.buildElasticsearchQuery(req)
@@ -89,16 +124,28 @@ func (s *SearchService) Search(ctx context.Context, req *models.SearchRequest) (
 	ctx, esSpan := s.tracer.TraceElasticsearchOperation(ctx, "POST", fmt.Sprintf("/%s/_search", req.Index), query)
 	defer esSpan.End()
 	
-	searchReq := elasticsearch.Search{
+	searchReq := esapi.SearchRequest{
 		Index: []string{req.Index},
 		Body:  strings.NewReader(query),
 	}
 	
-	if req.Timeout != "" {
-		searchReq.Timeout = req.Timeout
+	// The point in time already pins the indices; naming them again is rejected
+	if req.PIT != nil {
+		searchReq.Index = nil
+	}
+	
+	if timeout, err := time.ParseDuration(req.Timeout); err == nil {
+		searchReq.Timeout = timeout
 	}
 	
-	res := searchReq.Do(ctx, s.esClient.(*elasticsearch.Client))
+	res, err := searchReq.Do(ctx, s.esClient)
+	if err != nil {
+		s.tracer.RecordError(ctx, err, map[string]interface{}{
+			"operation": "search",
+		})
+		s.recordFailedSearch(req, span, startTime, err)
+		return nil, fmt.Errorf("search request failed: %w", err)
+	}
 	if res.IsError() {
 		err := fmt.Errorf("search failed: %s", res.String())
 		s.tracer.RecordElasticsearchResult(ctx, res.StatusCode, 0, time.Since(startTime))
@@ -106,6 +153,7 @@ func (s *SearchService) Search(ctx context.Context, req *models.SearchRequest) (
 			"elasticsearch.status_code": res.StatusCode,
 			"elasticsearch.error": res.String(),
 		})
+		s.recordFailedSearch(req, span, startTime, err)
 		return nil, err
 	}
 	defer res.Body.Close()
@@ -119,28 +167,134 @@ func (s *SearchService) Search(ctx context.Context, req *models.SearchRequest) (
 		return nil, fmt.Errorf("failed to parse response: %w", err)
 	}
 
+	s.tracer.RecordElasticsearchResult(ctx, res.StatusCode, len(res.String()), time.Since(startTime))
+
+	return s.completeSearch(ctx, req, esResponse, span, startTime, costWarnings, useCache), nil
+}
+
+// cacheable reports whether a search's results may be cached; point-in-time and search_after
+// pages are tied to a specific snapshot and position
+func cacheable(req *models.SearchRequest) bool {
+	return req.PIT == nil && len(req.SearchAfter) == 0
+}
+
+// InvalidateCache removes the cached results of searches over an index, or of every search
+// when index is empty, and returns how many cache keys were removed
+func (s *SearchService) InvalidateCache(ctx context.Context, index string) (int64, error) {
+	if index == "" {
+		return s.cacheManager.InvalidateAll(ctx)
+	}
+	return s.cacheManager.InvalidateIndex(ctx, index)
+}
+
+// WarmCache runs searches to fill the cache. Without requests it warms the configured
+// queries plus the most frequent plain searches over the configured window.
+func (s *SearchService) WarmCache(ctx context.Context, reqs []*models.SearchRequest) (*cache.WarmSummary, error) {
+	if len(reqs) == 0 {
+		reqs = s.warmList()
+	}
+	return s.cacheManager.Warm(ctx, reqs)
+}
+
+// warmList builds the default searches for WarmCache
+func (s *SearchService) warmList() []*models.SearchRequest {
+	config := s.cacheManager.WarmConfig()
+
+	var reqs []*models.SearchRequest
+	for _, query := range config.Queries {
+		reqs = append(reqs, query.SearchRequest())
+	}
+
+	if config.TopQueries > 0 && s.analyticsHub != nil {
+		window := config.Window
+		if window <= 0 {
+			window = defaultWarmWindow
+		}
+		for _, popular := range s.analyticsHub.PopularQueries(window, config.TopQueries) {
+			reqs = append(reqs, &models.SearchRequest{
+				Index:     popular.Index,
+				Query:     popular.Query,
+				QueryType: popular.QueryType,
+				Size:      popular.Size,
+			})
+		}
+	}
+
+	return reqs
+}
+
+// lookupCachedSearch returns the cached result of a search, recording the cache hit or miss
+func (s *SearchService) lookupCachedSearch(ctx context.Context, req *models.SearchRequest, span trace.Span, startTime time.Time) (*models.SearchResponse, bool) {
+	cachedResponse, found := s.cacheManager.GetCache().GetSearchResult(ctx, req)
+	if !found {
+		s.tracer.RecordCacheOperation(ctx, "get", false, "search_result")
+		return nil, false
+	}
+
+	s.tracer.RecordCacheOperation(ctx, "get", true, "search_result")
+	s.tracer.RecordSearchResult(ctx, cachedResponse.Total.Value, time.Since(startTime), true)
+
+	// Update analytics for cache hit
+	if s.analyticsHub != nil {
+		analyticsEvent := realtime.SearchEvent{
+			Timestamp:    startTime,
+			QueryID:      req.RequestID,
+			Index:        req.Index,
+			Query:        req.Query,
+			QueryType:    req.QueryType,
+			ResponseTime: time.Since(startTime),
+			ResultCount:  cachedResponse.Total.Value,
+			Success:      true,
+			CacheHit:     true,
+			TraceID:      span.SpanContext().TraceID().String(),
+			VectorDims:   vectorDims(req),
+		}
+		s.analyticsHub.RecordSearchEvent(analyticsEvent)
+		s.analyticsHub.TrackQuery(req)
+	}
+	s.recordZeroResult(req, cachedResponse.Total.Value)
+
+	return cachedResponse, true
+}
+
+// completeSearch converts a search response from Elasticsearch to our format and records it
+// in tracing, metrics, the cache and search analytics
+func (s *SearchService) completeSearch(ctx context.Context, req *models.SearchRequest, esResponse map[string]interface{}, span trace.Span, startTime time.Time, costWarnings []string, useCache bool) *models.SearchResponse {
 	// Transform to our response format
 	response := s.transformSearchResponse(esResponse, req)
 	response.ResponseTime = time.Since(startTime)
 	response.RequestID = req.RequestID
 	response.Timestamp = time.Now()
+	response.Warnings = append(response.Warnings, costWarnings...)
 	
 	// Record tracing results
-	s.tracer.RecordElasticsearchResult(ctx, res.StatusCode, len(res.String()), time.Since(startTime))
 	s.tracer.RecordSearchResult(ctx, response.Total.Value, time.Duration(response.Took)*time.Millisecond, true)
 
 	// Record metrics
 	queryType := req.QueryType
-	if queryType == "" {
+	switch {
+	case queryType != "":
+	case req.KNN != nil && req.Query == "":
+		queryType = "knn"
+	case req.KNN != nil:
+		queryType = "hybrid"
+	default:
 		queryType = "simple_query_string"
 	}
 	metrics.RecordElasticsearchSearch(req.Index, queryType, response.ResponseTime, response.Total.Value)
 
+	// Offer a correction for sparse results; it is cached along with them
+	if s.wantsCorrection(req, response.Total.Value) {
+		response.DidYouMean = s.didYouMean(ctx, req)
+	}
+
 	// Cache the successful result
-	if err := s.cacheManager.GetCache().SetSearchResult(ctx, req, response); err != nil {
-		s.logger.Warn("Failed to cache search result", zap.Error(err))
-	} else {
-		s.tracer.RecordCacheOperation(ctx, "set", true, "search_result")
+	if useCache {
+		if err := s.cacheManager.GetCache().SetSearchResult(ctx, req, response); err != nil {
+			s.logger.Warn("Failed to cache search result", zap.Error(err))
+		} else {
+			s.tracer.RecordCacheOperation(ctx, "set", true, "search_result")
+		}
 	}
 	
 	// Record real-time analytics event
@@ -156,27 +310,71 @@ func (s *SearchService) Search(ctx context.Context, req *models.SearchRequest) (
 			Success:      true,
 			CacheHit:     false,
 			TraceID:      span.SpanContext().TraceID().String(),
+			VectorDims:   vectorDims(req),
 		}
 		s.analyticsHub.RecordSearchEvent(analyticsEvent)
+		s.analyticsHub.TrackQuery(req)
 	}
+	s.recordZeroResult(req, response.Total.Value)
 
 	// Log search analytics
 	s.logSearchAnalytics(req, response, startTime)
 
-	return response, nil
+	return response
+}
+
+// recordFailedSearch records a search Elasticsearch failed in real-time analytics, so it
+// counts towards the error rate
+func (s *SearchService) recordFailedSearch(req *models.SearchRequest, span trace.Span, startTime time.Time, err error) {
+	if s.analyticsHub == nil {
+		return
+	}
+	s.analyticsHub.RecordSearchEvent(realtime.SearchEvent{
+		Timestamp:    startTime,
+		QueryID:      req.RequestID,
+		Index:        req.Index,
+		Query:        req.Query,
+		QueryType:    req.QueryType,
+		ResponseTime: time.Since(startTime),
+		Success:      false,
+		ErrorMessage: err.Error(),
+		TraceID:      span.SpanContext().TraceID().String(),
+		VectorDims:   vectorDims(req),
+	})
 }
 
 // buildElasticsearchQuery builds comprehensive Elasticsearch query JSON
 func (s *SearchService) buildElasticsearchQuery(req *models.SearchRequest) (string, error) {
+	// Buckets and metrics over a redacted field would reveal what the hits hide
+	if s.redactor != nil && !s.redactor.Elevated(req.Scopes) {
+		if err := s.redactor.CheckAggregations(req.Index, req.Aggregations); err != nil {
+			return "", err
+		}
+	}
+
 	query := map[string]interface{}{
 		"size": req.Size,
 		"from": req.From,
 	}
 
-	// Build main query
-	mainQuery := s.buildMainQuery(req)
-	if mainQuery != nil {
-		query["query"] = mainQuery
+	// Build main query; a kNN search without query text has nothing to add to it
+	if req.KNN == nil || req.Query != "" {
+		mainQuery, err := s.buildMainQuery(req)
+		if err != nil {
+			return "", err
+		}
+		if mainQuery != nil {
+			query["query"] = mainQuery
+		}
+	}
+
+	// Add kNN search
+	if req.KNN != nil {
+		knn, err := s.buildKNNQuery(req)
+		if err != nil {
+			return "", err
+		}
+		query["knn"] = knn
 	}
 
 	// Add sorting
@@ -190,9 +388,21 @@ func (s *SearchService) buildElasticsearchQuery(req *models.SearchRequest) (stri
 			}
 		}
 		query["sort"] = sorts
+	} else if req.PIT != nil {
+		// Hits only carry the sort values the next page resumes from when a sort is given;
+		// the point in time's shard order breaks ties in relevance
+		query["sort"] = []interface{}{"_score", "_shard_doc"}
+	}
+
+	// Add field collapsing
+	if req.Collapse != nil {
+		query["collapse"] = buildCollapse(req.Collapse)
 	}
 
 	// Add highlighting
+	if err := s.validateHighlight(req); err != nil {
+		return "", err
+	}
 	if req.Highlight.Enabled {
 		highlight := s.buildHighlightConfig(req.Highlight)
 		query["highlight"] = highlight
@@ -202,7 +412,11 @@ func (s *SearchService) buildElasticsearchQuery(req *models.SearchRequest) (stri
 	if len(req.Aggregations) > 0 {
 		aggs := make(map[string]interface{})
 		for name, aggConfig := range req.Aggregations {
-			aggs[name] = s.buildAggregation(aggConfig)
+			agg, err := s.buildAggregation(name, aggConfig)
+			if err != nil {
+				return "", err
+			}
+			aggs[name] = agg
 		}
 		query["aggs"] = aggs
 	}
@@ -222,10 +436,14 @@ func (s *SearchService) buildElasticsearchQuery(req *models.SearchRequest) (stri
 		query["suggest"] = suggest
 	}
 
-	// Add rescoring
-	if len(req.Rescore) > 0 {
-		rescores := make([]map[string]interface{}, len(req.Rescore))
-		for i, rescore := range req.Rescore {
+	// Add rescoring; an experiment strategy brings its own
+	rescoreConfigs := req.Rescore
+	if req.Strategy != nil {
+		rescoreConfigs = req.Strategy.Rescore
+	}
+	if len(rescoreConfigs) > 0 {
+		rescores := make([]map[string]interface{}, len(rescoreConfigs))
+		for i, rescore := range rescoreConfigs {
 			rescores[i] = s.buildRescore(rescore)
 		}
 		query["rescore"] = rescores
@@ -256,6 +474,33 @@ func (s *SearchService) buildElasticsearchQuery(req *models.SearchRequest) (stri
 		query["track_scores"] = true
 	}
 
+	// Add point in time and search_after for consistent pagination
+	if req.PIT != nil {
+		pit := map[string]interface{}{
+			"id": req.PIT.ID,
+		}
+		if req.PIT.KeepAlive != "" {
+			pit["keep_alive"] = req.PIT.KeepAlive
+		}
+		query["pit"] = pit
+	}
+
+	if len(req.SearchAfter) > 0 {
+		query["search_after"] = req.SearchAfter
+		delete(query, "from") // search_after replaces offset paging
+	}
+
+	// Aggregation-only searches fetch no hits, so nothing that shapes hits applies
+	if req.AggregationsOnly {
+		if len(req.Aggregations) == 0 {
+			return "", fmt.Errorf("%w: aggregations_only needs at least one aggregation", ErrInvalidQuery)
+		}
+		query["size"] = 0
+		for _, key := range []string{"from", "sort", "collapse", "highlight", "rescore", "_source", "search_after"} {
+			delete(query, key)
+		}
+	}
+
 	// Convert to JSON
 	queryJSON, err := json.Marshal(query)
 	if err != nil {
@@ -266,11 +511,15 @@ func (s *SearchService) buildElasticsearchQuery(req *models.SearchRequest) (stri
 }
 
 // buildMainQuery builds the main query part based on request
-func (s *SearchService) buildMainQuery(req *models.SearchRequest) map[string]interface{} {
+func (s *SearchService) buildMainQuery(req *models.SearchRequest) (map[string]interface{}, error) {
+	if req.QueryType == "function_score" {
+		return s.buildFunctionScoreQuery(req)
+	}
+	
 	if req.Query == "" && len(req.Filters) == 0 {
 		return map[string]interface{}{
 			"match_all": map[string]interface{}{},
-		}
+		}, nil
 	}
 
 	boolQuery := map[string]interface{}{
@@ -282,34 +531,36 @@ func (s *SearchService) buildMainQuery(req *models.SearchRequest) map[string]int
 	if req.Query != "" {
 		var mainQuery map[string]interface{}
 		
-		switch req.QueryType {
-		case "match":
+		switch {
+		case req.Strategy != nil:
+			strategyQuery, err := buildStrategyQuery(req.Strategy, req.Query)
+			if err != nil {
+				return nil, err
+			}
+			mainQuery = strategyQuery
+		case req.QueryType == "match" && len(req.Fields) <= 1:
+			field := req.DefaultField
+			if len(req.Fields) == 1 {
+				field = req.Fields[0]
+			}
+			if field == "" {
+				return nil, fmt.Errorf("%w: a match query needs a field in fields or default_field", ErrInvalidQuery)
+			}
 			mainQuery = map[string]interface{}{
 				"match": map[string]interface{}{
-					"_all": map[string]interface{}{
-						"query": req.Query,
-						"operator": req.Operator,
-						"fuzziness": req.Fuzziness,
-					},
+					field: textQueryConfig(req),
 				},
 			}
-		case "multi_match":
-			queryConfig := map[string]interface{}{
-				"query": req.Query,
-			}
+		case req.QueryType == "match" || req.QueryType == "multi_match":
+			// A match over several fields is a multi_match across them
+			queryConfig := textQueryConfig(req)
 			if len(req.Fields) > 0 {
 				queryConfig["fields"] = req.Fields
 			}
-			if req.Operator != "" {
-				queryConfig["operator"] = req.Operator
-			}
-			if req.Fuzziness != "" {
-				queryConfig["fuzziness"] = req.Fuzziness
-			}
 			mainQuery = map[string]interface{}{
 				"multi_match": queryConfig,
 			}
-		case "query_string":
+		case req.QueryType == "query_string":
 			mainQuery = map[string]interface{}{
 				"query_string": map[string]interface{}{
 					"query": req.Query,
@@ -325,6 +576,10 @@ func (s *SearchService) buildMainQuery(req *models.SearchRequest) map[string]int
 			}
 		}
 		
+		if relation := req.Relation; relation != nil {
+			mainQuery = buildRelationQuery(relation.Type, relation.Path, relation.RelationType, relation.ScoreMode, mainQuery, relation.InnerHits)
+		}
+		
 		boolQuery["must"] = []interface{}{mainQuery}
 	}
 
@@ -336,7 +591,205 @@ func (s *SearchService) buildMainQuery(req *models.SearchRequest) map[string]int
 
 	return map[string]interface{}{
 		"bool": boolQuery,
+	}, nil
+}
+
+// textQueryConfig holds the query text with the operator and fuzziness when they are set
+func textQueryConfig(req *models.SearchRequest) map[string]interface{} {
+	queryConfig := map[string]interface{}{
+		"query": req.Query,
+	}
+	if req.Operator != "" {
+		queryConfig["operator"] = req.Operator
+	}
+	if req.Fuzziness != "" {
+		queryConfig["fuzziness"] = req.Fuzziness
+	}
+	return queryConfig
+}
+
+// buildFunctionScoreQuery wraps the main query, built with the function score's own query
+// type, in a function_score query
+func (s *SearchService) buildFunctionScoreQuery(req *models.SearchRequest) (map[string]interface{}, error) {
+	config := req.FunctionScore
+	if config == nil || len(config.Functions) == 0 {
+		return nil, fmt.Errorf("function_score query requires at least one function")
+	}
+	
+	inner := *req
+	inner.QueryType = config.QueryType
+	if inner.QueryType == "function_score" {
+		return nil, fmt.Errorf("function_score queries cannot be nested")
+	}
+	query, err := s.buildMainQuery(&inner)
+	if err != nil {
+		return nil, err
+	}
+	
+	functions := make([]interface{}, 0, len(config.Functions))
+	for i, function := range config.Functions {
+		built, err := s.buildScoreFunction(function)
+		if err != nil {
+			return nil, fmt.Errorf("function %d: %w", i, err)
+		}
+		functions = append(functions, built)
 	}
+	
+	functionScore := map[string]interface{}{
+		"query":     query,
+		"functions": functions,
+	}
+	if config.ScoreMode != "" {
+		functionScore["score_mode"] = config.ScoreMode
+	}
+	if config.BoostMode != "" {
+		functionScore["boost_mode"] = config.BoostMode
+	}
+	if config.MaxBoost > 0 {
+		functionScore["max_boost"] = config.MaxBoost
+	}
+	if config.MinScore > 0 {
+		functionScore["min_score"] = config.MinScore
+	}
+	
+	return map[string]interface{}{
+		"function_score": functionScore,
+	}, nil
+}
+
+// buildScoreFunction builds one function_score function
+func (s *SearchService) buildScoreFunction(function models.ScoreFunction) (map[string]interface{}, error) {
+	if function.Decay != nil && function.FieldValueFactor != nil {
+		return nil, fmt.Errorf("a function may have either a decay or a field_value_factor, not both")
+	}
+	if function.Decay == nil && function.FieldValueFactor == nil && function.Weight == 0 {
+		return nil, fmt.Errorf("a function needs a decay, a field_value_factor or a weight")
+	}
+	
+	built := make(map[string]interface{})
+	if len(function.Filter) > 0 {
+		built["filter"] = s.buildFilters(function.Filter)
+	}
+	if function.Weight != 0 {
+		built["weight"] = function.Weight
+	}
+	
+	if decay := function.Decay; decay != nil {
+		params := map[string]interface{}{
+			"scale": decay.Scale,
+		}
+		if decay.Origin != nil {
+			params["origin"] = decay.Origin
+		}
+		if decay.Offset != nil {
+			params["offset"] = decay.Offset
+		}
+		if decay.Decay > 0 {
+			params["decay"] = decay.Decay
+		}
+		built[decay.Type] = map[string]interface{}{
+			decay.Field: params,
+		}
+	}
+	
+	if factor := function.FieldValueFactor; factor != nil {
+		params := map[string]interface{}{
+			"field": factor.Field,
+		}
+		if factor.Factor != 0 {
+			params["factor"] = factor.Factor
+		}
+		if factor.Modifier != "" {
+			params["modifier"] = factor.Modifier
+		}
+		if factor.Missing != nil {
+			params["missing"] = *factor.Missing
+		}
+		built["field_value_factor"] = params
+	}
+	
+	return built, nil
+}
+
+// textQueryType is the type of the query built from the query text; a function_score
+// query wraps a text query of its own type
+func textQueryType(req *models.SearchRequest) string {
+	if req.QueryType == "function_score" && req.FunctionScore != nil {
+		return req.FunctionScore.QueryType
+	}
+	return req.QueryType
+}
+
+// buildKNNQuery builds the top-level knn clause. The query's filters do not restrict the
+// nearest neighbours, so the request filters are repeated in the kNN filter.
+func (s *SearchService) buildKNNQuery(req *models.SearchRequest) (map[string]interface{}, error) {
+	k := req.KNN.K
+	if k == 0 {
+		k = req.Size
+	}
+	if k <= 0 {
+		k = 10
+	}
+
+	numCandidates := req.KNN.NumCandidates
+	if numCandidates == 0 {
+		numCandidates = k * knnCandidatesPerResult
+		if numCandidates > maxKNNCandidates {
+			numCandidates = maxKNNCandidates
+		}
+	}
+	if numCandidates < k {
+		return nil, fmt.Errorf("knn num_candidates (%d) must be at least k (%d)", numCandidates, k)
+	}
+
+	knn := map[string]interface{}{
+		"field":          req.KNN.Field,
+		"query_vector":   req.KNN.QueryVector,
+		"k":              k,
+		"num_candidates": numCandidates,
+	}
+
+	filters := append(append([]models.Filter{}, req.Filters...), req.KNN.Filter...)
+	if len(filters) > 0 {
+		knn["filter"] = s.buildFilters(filters)
+	}
+
+	if req.KNN.Boost > 0 {
+		knn["boost"] = req.KNN.Boost
+	}
+
+	return knn, nil
+}
+
+// buildStrategyQuery builds the main query of an experiment strategy: its query template
+// with the query text filled in, or a multi_match over its boosted fields
+func buildStrategyQuery(strategy *models.SearchStrategy, queryText string) (map[string]interface{}, error) {
+	if strategy.QueryTemplate == "" {
+		fields := make([]string, 0, len(strategy.FieldBoosts))
+		for field, boost := range strategy.FieldBoosts {
+			fields = append(fields, fmt.Sprintf("%s^%g", field, boost))
+		}
+		sort.Strings(fields)
+
+		queryConfig := map[string]interface{}{
+			"query":  queryText,
+			"fields": fields,
+		}
+		if strategy.Operator != "" {
+			queryConfig["operator"] = strategy.Operator
+		}
+		return map[string]interface{}{"multi_match": queryConfig}, nil
+	}
+
+	// The placeholder sits inside a JSON string, so the query text is substituted escaped
+	escaped, _ := json.Marshal(queryText)
+	rendered := strings.ReplaceAll(strategy.QueryTemplate, "{{query}}", string(escaped[1:len(escaped)-1]))
+
+	var query map[string]interface{}
+	if err := json.Unmarshal([]byte(rendered), &query); err != nil {
+		return nil, fmt.Errorf("failed to render strategy %q: %w", strategy.Name, err)
+	}
+	return query, nil
 }
 
 // buildFilters builds filter queries from filter array
@@ -409,6 +862,15 @@ func (s *SearchService) buildSingleFilter(filter models.Filter) map[string]inter
 				filter.Field: filter.Value,
 			},
 		}
+	case "nested", "has_child", "has_parent":
+		inner := map[string]interface{}{
+			"match_all": map[string]interface{}{},
+		}
+		if len(filter.Filters) > 0 {
+			inner = s.buildFilters(filter.Filters)
+		}
+		// Filters do not score, so neither do the relatives that satisfy them
+		return buildRelationQuery(filter.Type, filter.Path, filter.RelationType, "none", inner, filter.InnerHits)
 	default:
 		return map[string]interface{}{
 			"term": map[string]interface{}{
@@ -418,6 +880,87 @@ func (s *SearchService) buildSingleFilter(filter models.Filter) map[string]inter
 	}
 }
 
+// buildCollapse builds the collapse clause, keeping one top hit per value of the field
+func buildCollapse(config *models.CollapseConfig) map[string]interface{} {
+	collapse := map[string]interface{}{
+		"field": config.Field,
+	}
+
+	if config.InnerHits != nil {
+		collapse["inner_hits"] = buildInnerHits(config.InnerHits, defaultCollapseInnerHitsName)
+	}
+
+	if config.MaxConcurrentGroupSearches > 0 {
+		collapse["max_concurrent_group_searches"] = config.MaxConcurrentGroupSearches
+	}
+
+	return collapse
+}
+
+// buildInnerHits builds an inner_hits clause; Elasticsearch names it after the nested path or
+// relation type when neither the config nor defaultName gives a name
+func buildInnerHits(config *models.InnerHitsConfig, defaultName string) map[string]interface{} {
+	innerHits := make(map[string]interface{})
+	
+	name := config.Name
+	if name == "" {
+		name = defaultName
+	}
+	if name != "" {
+		innerHits["name"] = name
+	}
+	if config.Size > 0 {
+		innerHits["size"] = config.Size
+	}
+	if len(config.Sort) > 0 {
+		sorts := make([]map[string]interface{}, len(config.Sort))
+		for i, sort := range config.Sort {
+			sorts[i] = map[string]interface{}{
+				sort.Field: map[string]interface{}{
+					"order": sort.Order,
+				},
+			}
+		}
+		innerHits["sort"] = sorts
+	}
+	
+	return innerHits
+}
+
+// buildRelationQuery wraps a query in a nested, has_child or has_parent query, so it runs
+// against the nested objects at path or the related documents of relationType
+func buildRelationQuery(kind, path, relationType, scoreMode string, query map[string]interface{}, innerHits *models.InnerHitsConfig) map[string]interface{} {
+	relation := map[string]interface{}{
+		"query": query,
+	}
+	
+	switch kind {
+	case "nested":
+		relation["path"] = path
+	case "has_child":
+		relation["type"] = relationType
+	case "has_parent":
+		relation["parent_type"] = relationType
+	}
+	
+	if scoreMode != "" {
+		// has_parent only scores by the parent or not at all
+		if kind == "has_parent" {
+			relation["score"] = scoreMode != "none"
+		} else {
+			relation["score_mode"] = scoreMode
+		}
+	}
+	
+	if innerHits != nil {
+		relation["inner_hits"] = buildInnerHits(innerHits, "")
+	}
+	
+	return map[string]interface{}{
+		kind: relation,
+	}
+}
+
 // buildHighlightConfig builds highlighting configuration
 func (s *SearchService) buildHighlightConfig(config models.HighlightConfig) map[string]interface{} {
 	highlight := make(map[string]interface{})
@@ -449,7 +992,8 @@ func (s *SearchService) buildHighlightConfig(config models.HighlightConfig) map[
 			fields[field] = map[string]interface{}{}
 		}
 	} else {
-		// Default to highlighting all text fields
+		// Default to highlighting all text fields; expensive on text-heavy indices,
+		// see validateHighlight
 		fields["*"] = map[string]interface{}{}
 	}
 	highlight["fields"] = fields
@@ -457,61 +1001,106 @@ func (s *SearchService) buildHighlightConfig(config models.HighlightConfig) map[
 	return highlight
 }
 
-// buildAggregation builds aggregation configuration
-func (s *SearchService) buildAggregation(config models.AggregationConfig) map[string]interface{} {
-	agg := make(map[string]interface{})
+// Pipeline aggregations reading the buckets of a sibling aggregation through buckets_path
+var bucketsPathAggregations = map[string]bool{
+	"avg_bucket":            true,
+	"sum_bucket":            true,
+	"min_bucket":            true,
+	"max_bucket":            true,
+	"stats_bucket":          true,
+	"extended_stats_bucket": true,
+	"percentiles_bucket":    true,
+	"cumulative_sum":        true,
+	"derivative":            true,
+}
 
-	switch config.Type {
-	case "terms":
-		termsAgg := map[string]interface{}{
-			"field": config.Field,
-		}
+// buildAggregation builds aggregation configuration. Settings carry any further options
+// of the aggregation type, e.g. percents for percentiles or precision_threshold for
+// cardinality, and override the fields built here.
+func (s *SearchService) buildAggregation(name string, config models.AggregationConfig) (map[string]interface{}, error) {
+	body := make(map[string]interface{})
+	if config.Field != "" {
+		body["field"] = config.Field
+	}
+
+	switch {
+	case config.Type == "terms":
 		if config.Size > 0 {
-			termsAgg["size"] = config.Size
+			body["size"] = config.Size
 		}
-		agg["terms"] = termsAgg
 
-	case "date_histogram":
-		dateHistAgg := map[string]interface{}{
-			"field": config.Field,
+	case config.Type == "range" || config.Type == "date_range":
+		if len(config.Ranges) == 0 {
+			return nil, fmt.Errorf("%w: %s aggregation %q needs ranges", ErrInvalidQuery, config.Type, name)
 		}
-		// Add settings from config
-		for key, value := range config.Settings {
-			dateHistAgg[key] = value
+		ranges := make([]map[string]interface{}, len(config.Ranges))
+		for i, r := range config.Ranges {
+			bucket := make(map[string]interface{})
+			if r.Key != "" {
+				bucket["key"] = r.Key
+			}
+			if r.From != nil {
+				bucket["from"] = r.From
+			}
+			if r.To != nil {
+				bucket["to"] = r.To
+			}
+			ranges[i] = bucket
 		}
-		agg["date_histogram"] = dateHistAgg
+		body["ranges"] = ranges
 
-	case "stats":
-		agg["stats"] = map[string]interface{}{
-			"field": config.Field,
+	case config.Type == "filters":
+		// One bucket per named filter; the aggregation has no field
+		if len(config.Filters) == 0 {
+			return nil, fmt.Errorf("%w: filters aggregation %q needs filters", ErrInvalidQuery, name)
 		}
+		filters := make(map[string]interface{}, len(config.Filters))
+		for bucket, bucketFilters := range config.Filters {
+			filters[bucket] = s.buildFilters(bucketFilters)
+		}
+		body = map[string]interface{}{"filters": filters}
 
-	case "histogram":
-		histAgg := map[string]interface{}{
-			"field": config.Field,
+	case config.Type == "nested":
+		if config.Path == "" {
+			return nil, fmt.Errorf("%w: nested aggregation %q needs a path", ErrInvalidQuery, name)
 		}
-		for key, value := range config.Settings {
-			histAgg[key] = value
+		body = map[string]interface{}{"path": config.Path}
+
+	case config.Type == "bucket_sort":
+		// Sorts or truncates its parent's buckets; sort, from and gap_policy go in settings
+		body = make(map[string]interface{})
+		if config.Size > 0 {
+			body["size"] = config.Size
 		}
-		agg["histogram"] = histAgg
 
-	default:
-		// Generic aggregation
-		agg[config.Type] = map[string]interface{}{
-			"field": config.Field,
+	case bucketsPathAggregations[config.Type]:
+		if config.BucketsPath == "" {
+			return nil, fmt.Errorf("%w: %s aggregation %q needs buckets_path", ErrInvalidQuery, config.Type, name)
 		}
+		body = map[string]interface{}{"buckets_path": config.BucketsPath}
+	}
+
+	for key, value := range config.Settings {
+		body[key] = value
+	}
+	agg := map[string]interface{}{
+		config.Type: body,
 	}
 
 	// Add sub-aggregations
 	if len(config.SubAggs) > 0 {
 		subAggs := make(map[string]interface{})
-		for name, subAgg := range config.SubAggs {
-			subAggs[name] = s.buildAggregation(subAgg)
+		for subName, subAgg := range config.SubAggs {
+			built, err := s.buildAggregation(name+">"+subName, subAgg)
+			if err != nil {
+				return nil, err
+			}
+			subAggs[subName] = built
 		}
 		agg["aggs"] = subAggs
 	}
 
-	return agg
+	return agg, nil
 }
 
 // buildSuggester builds suggester configuration
@@ -581,8 +1170,12 @@ func (s *SearchService) buildRescore(config models.RescoreConfig) map[string]int
 func (s *SearchService) transformSearchResponse(esResponse map[string]interface{}, req *models.SearchRequest) *models.SearchResponse {
 	response := &models.SearchResponse{
 		Query: req.Query,
+		PITID: getString(esResponse, "pit_id"),
 	}
 
+	// Sensitive fields are hidden from callers without the elevated scope
+	redact := s.redactor != nil && !s.redactor.Elevated(req.Scopes)
+
 	// Parse hits
 	if hits, ok := esResponse["hits"].(map[string]interface{}); ok {
 		// Total hits
@@ -609,36 +1202,17 @@ func (s *SearchService) transformSearchResponse(esResponse map[string]interface{
 			response.Hits = make([]models.SearchHit, len(hitsList))
 			for i, hit := range hitsList {
 				if hitMap, ok := hit.(map[string]interface{}); ok {
-					searchHit := models.SearchHit{
-						Index:  getString(hitMap, "_index"),
-						ID:     getString(hitMap, "_id"),
-						Source: hitMap["_source"],
-					}
-					
-					if score, ok := hitMap["_score"].(float64); ok {
-						searchHit.Score = &score
-					}
-					
-					if highlight, ok := hitMap["highlight"].(map[string]interface{}); ok {
-						searchHit.Highlight = make(map[string][]string)
-						for field, fragments := range highlight {
-							if fragList, ok := fragments.([]interface{}); ok {
-								searchHit.Highlight[field] = make([]string, len(fragList))
-								for j, frag := range fragList {
-									if fragStr, ok := frag.(string); ok {
-										searchHit.Highlight[field][j] = fragStr
-									}
-								}
-							}
-						}
-					}
-					
-					response.Hits[i] = searchHit
+					response.Hits[i] = s.transformHit(hitMap, req, redact, sortFields(req.Sort))
 				}
 			}
 		}
 	}
 
+	// A cursor of masked sort values would resume from the wrong place
+	if !redact || !s.redactor.RedactsAny(req.Index, sortFields(req.Sort)) {
+		response.SearchAfter = nextSearchAfter(response.Hits, req.Size)
+	}
+
 	// Parse aggregations
 	if aggs, ok := esResponse["aggregations"].(map[string]interface{}); ok {
 		response.Aggregations = aggs
@@ -687,6 +1261,88 @@ func (s *SearchService) transformSearchResponse(esResponse map[string]interface{
 	return response
 }
 
+// transformHit transforms a single Elasticsearch hit, including the inner hits of a
+// collapsed group or of matching nested objects and relatives. sortFields names the
+// field behind each of the hit's sort values, or is nil when they are unknown.
+func (s *SearchService) transformHit(hitMap map[string]interface{}, req *models.SearchRequest, redact bool, sortFields []string) models.SearchHit {
+	searchHit := models.SearchHit{
+		Index:  getString(hitMap, "_index"),
+		ID:     getString(hitMap, "_id"),
+		Source: hitMap["_source"],
+	}
+	
+	if score, ok := hitMap["_score"].(float64); ok {
+		searchHit.Score = &score
+	}
+	
+	if sortValues, ok := hitMap["sort"].([]interface{}); ok {
+		searchHit.Sort = sortValues
+	}
+	
+	if highlight, ok := hitMap["highlight"].(map[string]interface{}); ok {
+		searchHit.Highlight = make(map[string][]string)
+		for field, fragments := range highlight {
+			if fragList, ok := fragments.([]interface{}); ok {
+				searchHit.Highlight[field] = make([]string, len(fragList))
+				for j, frag := range fragList {
+					if fragStr, ok := frag.(string); ok {
+						searchHit.Highlight[field][j] = fragStr
+					}
+				}
+			}
+		}
+	}
+	
+	// Collapsed hits carry the group's other members, and relation queries the matching
+	// nested objects or relatives, under the inner hits name
+	if innerHits, ok := hitMap["inner_hits"].(map[string]interface{}); ok {
+		searchHit.InnerHits = make(map[string]models.InnerHits, len(innerHits))
+		for name, group := range innerHits {
+			groupMap, _ := group.(map[string]interface{})
+			groupHits, _ := groupMap["hits"].(map[string]interface{})
+			
+			var inner models.InnerHits
+			if total, ok := groupHits["total"].(map[string]interface{}); ok {
+				if value, ok := total["value"].(float64); ok {
+					inner.Total.Value = int64(value)
+				}
+				inner.Total.Relation = getString(total, "relation")
+			}
+			if hitsList, ok := groupHits["hits"].([]interface{}); ok {
+				inner.Hits = make([]models.SearchHit, 0, len(hitsList))
+				for _, innerHit := range hitsList {
+					if innerHitMap, ok := innerHit.(map[string]interface{}); ok {
+						inner.Hits = append(inner.Hits, s.transformHit(innerHitMap, req, redact, nil))
+					}
+				}
+			}
+			searchHit.InnerHits[name] = inner
+		}
+	}
+	
+	if nested, ok := hitMap["_nested"].(map[string]interface{}); ok {
+		searchHit.Nested = transformNestedIdentity(nested)
+	}
+	
+	if redact {
+		s.redactor.RedactHit(&searchHit, req.Index, sortFields)
+	}
+	
+	return searchHit
+}
+
+// transformNestedIdentity reads the _nested identity of a nested object inner hit
+func transformNestedIdentity(nested map[string]interface{}) *models.NestedIdentity {
+	identity := &models.NestedIdentity{
+		Field:  getString(nested, "field"),
+		Offset: getInt(nested, "offset"),
+	}
+	if child, ok := nested["_nested"].(map[string]interface{}); ok {
+		identity.Nested = transformNestedIdentity(child)
+	}
+	return identity
+}
+
 // logSearchAnalytics logs search analytics for performance monitoring
 func (s *SearchService) logSearchAnalytics(req *models.SearchRequest, resp *models.SearchResponse, startTime time.Time) {
 	analytics := models.SearchAnalytics{
@@ -696,6 +1352,7 @@ func (s *SearchService) logSearchAnalytics(req *models.SearchRequest, resp *mode
 		ExecutionTime: resp.ResponseTime,
 		ResultCount:   resp.Total.Value,
 		Timestamp:     time.Now(),
+		VectorDims:    vectorDims(req),
 		Performance: models.SearchPerformanceMetrics{
 			QueryTime:        int64(resp.Took),
 			TotalShards:      resp.Shards.Total,
@@ -710,6 +1367,128 @@ func (s *SearchService) logSearchAnalytics(req *models.SearchRequest, resp *mode
 		zap.Duration("execution_time", analytics.ExecutionTime),
 		zap.Int64("result_count", analytics.ResultCount),
 		zap.Int64("query_time_ms", analytics.Performance.QueryTime))
+
+	// Persist for long-term dashboards when enabled
+	if s.analyticsSink != nil {
+		s.analyticsSink.Record(analytics)
+	}
+}
+
+// CheckAliasMappingConsistency uses the field capabilities API to find fields that are
+// mapped with different types across the indices behind an alias
+func (s *SearchService) CheckAliasMappingConsistency(ctx context.Context, alias string) (*models.AliasMappingReport, error) {
+	fieldCapsReq := esapi.FieldCapsRequest{
+		Index:  []string{alias},
+		Fields: []string{"*"},
+	}
+
+	res, err := fieldCapsReq.Do(ctx, s.esClient)
+	if err != nil {
+		return nil, fmt.Errorf("field caps request failed: %w", err)
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode == http.StatusNotFound {
+		return nil, fmt.Errorf("%w: %s", ErrAliasNotFound, alias)
+	}
+	if res.IsError() {
+		return nil, fmt.Errorf("field caps failed: %s", res.String())
+	}
+
+	var fieldCaps struct {
+		Indices []string                                `json:"indices"`
+		Fields  map[string]map[string]fieldCapsTypeInfo `json:"fields"`
+	}
+	if err := json.NewDecoder(res.Body).Decode(&fieldCaps); err != nil {
+		return nil, fmt.Errorf("failed to parse field caps response: %w", err)
+	}
+	if len(fieldCaps.Indices) == 0 {
+		return nil, fmt.Errorf("%w: %s matches no index", ErrAliasNotFound, alias)
+	}
+
+	report := &models.AliasMappingReport{
+		Alias:     alias,
+		Indices:   fieldCaps.Indices,
+		Conflicts: findMappingConflicts(fieldCaps.Fields, fieldCaps.Indices),
+		CheckedAt: time.Now(),
+	}
+	for field := range fieldCaps.Fields {
+		if !strings.HasPrefix(field, "_") {
+			report.FieldsChecked++
+		}
+	}
+	report.Consistent = len(report.Conflicts) == 0
+
+	if !report.Consistent {
+		s.logger.Warn("Alias has conflicting field mappings",
+			zap.String("alias", alias),
+			zap.Int("conflicts", len(report.Conflicts)))
+	}
+
+	return report, nil
+}
+
+// fieldCapsTypeInfo represents the capabilities of a field for a single type
+type fieldCapsTypeInfo struct {
+	Type    string   `json:"type"`
+	Indices []string `json:"indices"`
+}
+
+// findMappingConflicts returns fields mapped with more than one type, sorted by field name
+func findMappingConflicts(fields map[string]map[string]fieldCapsTypeInfo, allIndices []string) []models.MappingConflict {
+	conflicts := []models.MappingConflict{}
+	for field, types := range fields {
+		// Metadata fields are managed by Elasticsearch
+		if strings.HasPrefix(field, "_") || len(types) < 2 {
+			continue
+		}
+
+		conflict := models.MappingConflict{
+			Field: field,
+			Types: make(map[string][]string, len(types)),
+		}
+		for fieldType, info := range types {
+			// Elasticsearch omits the index list when every index agrees on the type
+			indices := info.Indices
+			if len(indices) == 0 {
+				indices = allIndices
+			}
+			conflict.Types[fieldType] = indices
+		}
+		conflicts = append(conflicts, conflict)
+	}
+
+	sort.Slice(conflicts, func(i, j int) bool {
+		return conflicts[i].Field < conflicts[j].Field
+	})
+	return conflicts
+}
+
+// nextSearchAfter returns the cursor for the page after hits: the sort values of the last hit,
+// or nil when the page is short and there is nothing more to fetch
+func nextSearchAfter(hits []models.SearchHit, size int) []interface{} {
+	if len(hits) == 0 || len(hits) < size {
+		return nil
+	}
+	return hits[len(hits)-1].Sort
+}
+
+// sortFields returns the field behind each sort value of a hit; never nil, since an
+// unsorted search has no sort values to name
+func sortFields(sorts []models.SortField) []string {
+	fields := make([]string, len(sorts))
+	for i, sort := range sorts {
+		fields[i] = sort.Field
+	}
+	return fields
+}
+
+// vectorDims returns the query vector dimension of a kNN search, or zero
+func vectorDims(req *models.SearchRequest) int {
+	if req.KNN == nil {
+		return 0
+	}
+	return len(req.KNN.QueryVector)
 }
 
 // Helper functions
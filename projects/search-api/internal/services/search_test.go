@@ -0,0 +1,655 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/saif-islam/es-playground/projects/search-api/internal/models"
+)
+
+func TestBuildMatchQueryNeverUsesAllField(t *testing.T) {
+	s := &SearchService{}
+
+	tests := []struct {
+		name     string
+		req      *models.SearchRequest
+		expected string
+	}{
+		{
+			name:     "single field",
+			req:      &models.SearchRequest{Query: "laptop", QueryType: "match", Fields: []string{"title"}},
+			expected: `{"match":{"title":{"query":"laptop"}}}`,
+		},
+		{
+			name:     "default field",
+			req:      &models.SearchRequest{Query: "laptop", QueryType: "match", DefaultField: "body", Operator: "and"},
+			expected: `{"match":{"body":{"operator":"and","query":"laptop"}}}`,
+		},
+		{
+			name:     "several fields",
+			req:      &models.SearchRequest{Query: "laptop", QueryType: "match", Fields: []string{"title", "body"}, DefaultField: "body"},
+			expected: `{"multi_match":{"fields":["title","body"],"query":"laptop"}}`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			query, err := s.buildElasticsearchQuery(tt.req)
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if strings.Contains(query, "_all") {
+				t.Errorf("Expected no _all field in the query, got %s", query)
+			}
+
+			var parsed struct {
+				Query struct {
+					Bool struct {
+						Must []json.RawMessage `json:"must"`
+					} `json:"bool"`
+				} `json:"query"`
+			}
+			if err := json.Unmarshal([]byte(query), &parsed); err != nil {
+				t.Fatalf("Failed to parse query: %v", err)
+			}
+			if len(parsed.Query.Bool.Must) != 1 || string(parsed.Query.Bool.Must[0]) != tt.expected {
+				t.Errorf("Expected must clause %s, got %s", tt.expected, parsed.Query.Bool.Must)
+			}
+		})
+	}
+}
+
+func TestBuildMatchQueryRequiresField(t *testing.T) {
+	s := &SearchService{}
+
+	_, err := s.buildElasticsearchQuery(&models.SearchRequest{Query: "laptop", QueryType: "match"})
+	if !errors.Is(err, ErrInvalidQuery) {
+		t.Errorf("Expected ErrInvalidQuery for a match query without a field, got %v", err)
+	}
+}
+
+func TestFindMappingConflicts(t *testing.T) {
+	allIndices := []string{"logs-000001", "logs-000002", "logs-000003"}
+
+	tests := []struct {
+		name     string
+		fields   map[string]map[string]fieldCapsTypeInfo
+		expected []models.MappingConflict
+	}{
+		{
+			name: "consistent mappings",
+			fields: map[string]map[string]fieldCapsTypeInfo{
+				"message":    {"text": {Type: "text"}},
+				"@timestamp": {"date": {Type: "date"}},
+			},
+			expected: []models.MappingConflict{},
+		},
+		{
+			name: "conflicts sorted by field",
+			fields: map[string]map[string]fieldCapsTypeInfo{
+				"status": {
+					"keyword": {Type: "keyword", Indices: []string{"logs-000001"}},
+					"long":    {Type: "long", Indices: []string{"logs-000002", "logs-000003"}},
+				},
+				"host.ip": {
+					"ip":      {Type: "ip", Indices: []string{"logs-000002"}},
+					"keyword": {Type: "keyword", Indices: []string{"logs-000001", "logs-000003"}},
+				},
+				"message": {"text": {Type: "text"}},
+			},
+			expected: []models.MappingConflict{
+				{Field: "host.ip", Types: map[string][]string{"ip": {"logs-000002"}, "keyword": {"logs-000001", "logs-000003"}}},
+				{Field: "status", Types: map[string][]string{"keyword": {"logs-000001"}, "long": {"logs-000002", "logs-000003"}}},
+			},
+		},
+		{
+			name: "type without an index list covers every index",
+			fields: map[string]map[string]fieldCapsTypeInfo{
+				"user": {
+					"keyword": {Type: "keyword"},
+					"object":  {Type: "object", Indices: []string{"logs-000003"}},
+				},
+			},
+			expected: []models.MappingConflict{
+				{Field: "user", Types: map[string][]string{"keyword": allIndices, "object": {"logs-000003"}}},
+			},
+		},
+		{
+			name: "metadata fields are skipped",
+			fields: map[string]map[string]fieldCapsTypeInfo{
+				"_id":      {"_id": {Type: "_id"}, "keyword": {Type: "keyword"}},
+				"_routing": {"_routing": {Type: "_routing"}, "text": {Type: "text"}},
+			},
+			expected: []models.MappingConflict{},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			conflicts := findMappingConflicts(tt.fields, allIndices)
+			if !reflect.DeepEqual(conflicts, tt.expected) {
+				t.Errorf("Expected conflicts %+v, got %+v", tt.expected, conflicts)
+			}
+		})
+	}
+}
+
+func TestCheckAliasMappingConsistency(t *testing.T) {
+	s := newFakeESService(t, func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/logs/_field_caps":
+			w.Write([]byte(`{
+			  "indices": ["logs-000001", "logs-000002"],
+			  "fields": {
+			    "_id": {"_id": {"type": "_id", "metadata_field": true, "searchable": true, "aggregatable": false}},
+			    "message": {"text": {"type": "text", "metadata_field": false, "searchable": true, "aggregatable": false}},
+			    "status": {
+			      "keyword": {"type": "keyword", "metadata_field": false, "searchable": true, "aggregatable": true, "indices": ["logs-000001"]},
+			      "long": {"type": "long", "metadata_field": false, "searchable": true, "aggregatable": true, "indices": ["logs-000002"]}
+			    }
+			  }
+			}`))
+		case "/empty-*/_field_caps":
+			w.Write([]byte(`{"indices": [], "fields": {}}`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+			w.Write([]byte(`{"error":{"root_cause":[{"type":"index_not_found_exception","reason":"no such index [missing]","index":"missing"}],"type":"index_not_found_exception","reason":"no such index [missing]","index":"missing"},"status":404}`))
+		}
+	})
+
+	report, err := s.CheckAliasMappingConsistency(context.Background(), "logs")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if report.Consistent || report.FieldsChecked != 2 || len(report.Conflicts) != 1 || report.Conflicts[0].Field != "status" {
+		t.Errorf("Expected one conflict on status out of 2 fields, got %+v", report)
+	}
+
+	for _, alias := range []string{"missing", "empty-*"} {
+		if _, err := s.CheckAliasMappingConsistency(context.Background(), alias); !errors.Is(err, ErrAliasNotFound) {
+			t.Errorf("Expected ErrAliasNotFound for %s, got %v", alias, err)
+		}
+	}
+}
+
+func TestSearchWithStrategy(t *testing.T) {
+	tests := []struct {
+		name            string
+		strategy        *models.SearchStrategy
+		query           string
+		expectedQuery   string // the must clause sent to Elasticsearch
+		expectedRescore string
+	}{
+		{
+			name:          "no strategy keeps the request ranking",
+			query:         "laptop",
+			expectedQuery: `{"multi_match":{"fields":["title"],"query":"laptop"}}`,
+		},
+		{
+			name:          "field boosts",
+			strategy:      &models.SearchStrategy{Name: "title-heavy", FieldBoosts: map[string]float64{"title": 3, "body": 1}, Operator: "and"},
+			query:         "laptop",
+			expectedQuery: `{"multi_match":{"fields":["body^1","title^3"],"operator":"and","query":"laptop"}}`,
+		},
+		{
+			name:          "query template",
+			strategy:      &models.SearchStrategy{Name: "phrase", QueryTemplate: `{"match_phrase":{"title":{"query":"{{query}}","slop":2}}}`},
+			query:         `15" laptop`,
+			expectedQuery: `{"match_phrase":{"title":{"query":"15\" laptop","slop":2}}}`,
+		},
+		{
+			name: "strategy rescore replaces the request's",
+			strategy: &models.SearchStrategy{
+				FieldBoosts: map[string]float64{"title": 2},
+				Rescore:     []models.RescoreConfig{{WindowSize: 50, Query: "gaming"}},
+			},
+			query:           "laptop",
+			expectedQuery:   `{"multi_match":{"fields":["title^2"],"query":"laptop"}}`,
+			expectedRescore: `[{"query":{"rescore_query":{"simple_query_string":{"query":"gaming"}}},"window_size":50}]`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var body struct {
+				Query struct {
+					Bool struct {
+						Must []json.RawMessage `json:"must"`
+					} `json:"bool"`
+				} `json:"query"`
+				Rescore json.RawMessage `json:"rescore"`
+			}
+			s := newFakeESService(t, func(w http.ResponseWriter, r *http.Request) {
+				json.NewDecoder(r.Body).Decode(&body)
+				w.Write([]byte(`{"took":3,"hits":{"total":{"value":1,"relation":"eq"},"hits":[{"_index":"products","_id":"1","_score":2.5,"_source":{"title":"Laptop"}}]}}`))
+			})
+
+			response, err := s.Search(context.Background(), &models.SearchRequest{
+				Index:     "products",
+				Query:     tt.query,
+				QueryType: "multi_match",
+				Fields:    []string{"title"},
+				Rescore:   []models.RescoreConfig{{WindowSize: 10, Query: "request"}},
+				Strategy:  tt.strategy,
+			})
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if response.Total.Value != 1 || len(response.Hits) != 1 {
+				t.Errorf("Expected the hit from Elasticsearch, got %+v", response)
+			}
+
+			if len(body.Query.Bool.Must) != 1 || string(body.Query.Bool.Must[0]) != tt.expectedQuery {
+				t.Errorf("Expected must clause %s, got %s", tt.expectedQuery, body.Query.Bool.Must)
+			}
+			expectedRescore := tt.expectedRescore
+			if expectedRescore == "" && tt.strategy == nil {
+				expectedRescore = `[{"query":{"rescore_query":{"simple_query_string":{"query":"request"}}},"window_size":10}]`
+			}
+			if string(body.Rescore) != expectedRescore {
+				t.Errorf("Expected rescore %s, got %s", expectedRescore, body.Rescore)
+			}
+		})
+	}
+}
+
+func TestSearchWithKNN(t *testing.T) {
+	vector := []float32{0.12, -0.5, 0.33}
+
+	tests := []struct {
+		name          string
+		req           *models.SearchRequest
+		expectedKNN   string
+		expectQuery   bool
+		expectedError bool
+	}{
+		{
+			name:        "vector only",
+			req:         &models.SearchRequest{Size: 5, KNN: &models.KNNQuery{Field: "embedding", QueryVector: vector}},
+			expectedKNN: `{"field":"embedding","k":5,"num_candidates":50,"query_vector":[0.12,-0.5,0.33]}`,
+		},
+		{
+			name: "hybrid with a boost",
+			req: &models.SearchRequest{
+				Size: 10, Query: "laptop", QueryType: "multi_match", Fields: []string{"title"},
+				KNN: &models.KNNQuery{Field: "embedding", QueryVector: vector, K: 20, NumCandidates: 100, Boost: 0.3},
+			},
+			expectedKNN: `{"boost":0.3,"field":"embedding","k":20,"num_candidates":100,"query_vector":[0.12,-0.5,0.33]}`,
+			expectQuery: true,
+		},
+		{
+			name: "request filters apply to the neighbours",
+			req: &models.SearchRequest{
+				Size:    10,
+				Filters: []models.Filter{{Field: "in_stock", Type: "term", Value: true}},
+				KNN:     &models.KNNQuery{Field: "embedding", QueryVector: vector, K: 2000, Filter: []models.Filter{{Field: "brand", Type: "term", Value: "acme"}}},
+			},
+			expectedKNN: `{"field":"embedding","filter":{"bool":{"must":[{"term":{"in_stock":true}},{"term":{"brand":"acme"}}]}},"k":2000,"num_candidates":10000,"query_vector":[0.12,-0.5,0.33]}`,
+		},
+		{
+			name:          "fewer candidates than neighbours",
+			req:           &models.SearchRequest{Size: 10, KNN: &models.KNNQuery{Field: "embedding", QueryVector: vector, K: 50, NumCandidates: 20}},
+			expectedError: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var body map[string]json.RawMessage
+			s := newFakeESService(t, func(w http.ResponseWriter, r *http.Request) {
+				json.NewDecoder(r.Body).Decode(&body)
+				w.Write([]byte(`{"took":4,"hits":{"total":{"value":1,"relation":"eq"},"hits":[{"_index":"products","_id":"7","_score":0.93,"_source":{"title":"Laptop stand"}}]}}`))
+			})
+			tt.req.Index = "products"
+
+			response, err := s.Search(context.Background(), tt.req)
+			if tt.expectedError {
+				if err == nil {
+					t.Error("Expected an error")
+				}
+				if body != nil {
+					t.Error("Expected the search not to reach Elasticsearch")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+
+			if string(body["knn"]) != tt.expectedKNN {
+				t.Errorf("Expected knn %s, got %s", tt.expectedKNN, body["knn"])
+			}
+			if _, ok := body["query"]; ok != tt.expectQuery {
+				t.Errorf("Expected a query clause %v, got %s", tt.expectQuery, body["query"])
+			}
+			if len(response.Hits) != 1 || response.Hits[0].ID != "7" {
+				t.Errorf("Expected the nearest neighbour from Elasticsearch, got %+v", response.Hits)
+			}
+		})
+	}
+}
+
+// collapsedSearchAPIResponse follows a collapsed search with inner hits: one top hit per
+// brand carrying the other members of its group
+const collapsedSearchAPIResponse = `{
+  "took": 6,
+  "timed_out": false,
+  "hits": {
+    "total": {"value": 5, "relation": "eq"},
+    "max_score": 1.8,
+    "hits": [
+      {
+        "_index": "products",
+        "_id": "1",
+        "_score": 1.8,
+        "_source": {"title": "Laptop Pro", "brand": "acme"},
+        "fields": {"brand": ["acme"]},
+        "inner_hits": {
+          "by_brand": {
+            "hits": {
+              "total": {"value": 3, "relation": "eq"},
+              "max_score": null,
+              "hits": [
+                {"_index": "products", "_id": "4", "_score": null, "_source": {"title": "Laptop Air", "brand": "acme"}, "sort": [899]},
+                {"_index": "products", "_id": "1", "_score": null, "_source": {"title": "Laptop Pro", "brand": "acme"}, "sort": [1299]}
+              ]
+            }
+          }
+        }
+      },
+      {
+        "_index": "products",
+        "_id": "2",
+        "_score": 1.1,
+        "_source": {"title": "Laptop Stand", "brand": "globex"},
+        "fields": {"brand": ["globex"]},
+        "inner_hits": {"by_brand": {"hits": {"total": {"value": 1, "relation": "eq"}, "hits": []}}}
+      }
+    ]
+  }
+}`
+
+func TestSearchWithCollapse(t *testing.T) {
+	tests := []struct {
+		name             string
+		collapse         *models.CollapseConfig
+		expectedCollapse string
+	}{
+		{
+			name:             "field only",
+			collapse:         &models.CollapseConfig{Field: "brand"},
+			expectedCollapse: `{"field":"brand"}`,
+		},
+		{
+			name: "default inner hits name",
+			collapse: &models.CollapseConfig{
+				Field:     "brand",
+				InnerHits: &models.InnerHitsConfig{},
+			},
+			expectedCollapse: `{"field":"brand","inner_hits":{"name":"collapsed"}}`,
+		},
+		{
+			name: "inner hits with size and sort",
+			collapse: &models.CollapseConfig{
+				Field:                      "brand",
+				InnerHits:                  &models.InnerHitsConfig{Name: "by_brand", Size: 2, Sort: []models.SortField{{Field: "price", Order: "asc"}}},
+				MaxConcurrentGroupSearches: 4,
+			},
+			expectedCollapse: `{"field":"brand","inner_hits":{"name":"by_brand","size":2,"sort":[{"price":{"order":"asc"}}]},"max_concurrent_group_searches":4}`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var body map[string]json.RawMessage
+			s := newFakeESService(t, func(w http.ResponseWriter, r *http.Request) {
+				json.NewDecoder(r.Body).Decode(&body)
+				w.Write([]byte(collapsedSearchAPIResponse))
+			})
+
+			response, err := s.Search(context.Background(), &models.SearchRequest{
+				Index:     "products",
+				Query:     "laptop",
+				QueryType: "match",
+				Fields:    []string{"title"},
+				Size:      10,
+				Collapse:  tt.collapse,
+			})
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+
+			if string(body["collapse"]) != tt.expectedCollapse {
+				t.Errorf("Expected collapse %s, got %s", tt.expectedCollapse, body["collapse"])
+			}
+			if len(response.Hits) != 2 {
+				t.Fatalf("Expected one hit per brand, got %d", len(response.Hits))
+			}
+
+			group, ok := response.Hits[0].InnerHits["by_brand"]
+			if !ok || group.Total.Value != 3 || group.Total.Relation != "eq" {
+				t.Fatalf("Expected 3 acme products in the group, got %+v", response.Hits[0].InnerHits)
+			}
+			if len(group.Hits) != 2 || group.Hits[0].ID != "4" || group.Hits[0].Score != nil || !reflect.DeepEqual(group.Hits[0].Sort, []interface{}{float64(899)}) {
+				t.Errorf("Expected the sorted members of the group, got %+v", group.Hits)
+			}
+			if single := response.Hits[1].InnerHits["by_brand"]; single.Total.Value != 1 || len(single.Hits) != 0 {
+				t.Errorf("Expected a group of one without other members, got %+v", single)
+			}
+		})
+	}
+}
+
+func TestSearchWithFunctionScore(t *testing.T) {
+	missing := 1.0
+	tests := []struct {
+		name              string
+		functionScore     *models.FunctionScoreConfig
+		expectedFunctions string
+		expectedModes     string // score_mode and boost_mode
+		expectedError     bool
+	}{
+		{
+			name: "recency decay and popularity",
+			functionScore: &models.FunctionScoreConfig{
+				QueryType: "match",
+				Functions: []models.ScoreFunction{
+					{Decay: &models.DecayFunction{Type: "gauss", Field: "published_at", Origin: "now", Scale: "7d", Offset: "1d", Decay: 0.5}},
+					{FieldValueFactor: &models.FieldValueFactor{Field: "popularity", Factor: 1.2, Modifier: "log1p", Missing: &missing}, Weight: 2},
+				},
+				ScoreMode: "sum",
+				BoostMode: "multiply",
+			},
+			expectedFunctions: `[{"gauss":{"published_at":{"decay":0.5,"offset":"1d","origin":"now","scale":"7d"}}},{"field_value_factor":{"factor":1.2,"field":"popularity","missing":1,"modifier":"log1p"},"weight":2}]`,
+			expectedModes:     "sum multiply",
+		},
+		{
+			name: "filtered weight",
+			functionScore: &models.FunctionScoreConfig{
+				QueryType: "match",
+				Functions: []models.ScoreFunction{{Filter: []models.Filter{{Field: "featured", Type: "term", Value: true}}, Weight: 3}},
+			},
+			expectedFunctions: `[{"filter":{"term":{"featured":true}},"weight":3}]`,
+			expectedModes:     " ",
+		},
+		{
+			name: "decay and field value factor together",
+			functionScore: &models.FunctionScoreConfig{
+				QueryType: "match",
+				Functions: []models.ScoreFunction{{
+					Decay:            &models.DecayFunction{Type: "exp", Field: "price", Origin: 100, Scale: 50},
+					FieldValueFactor: &models.FieldValueFactor{Field: "popularity"},
+				}},
+			},
+			expectedError: true,
+		},
+		{
+			name: "nested function score",
+			functionScore: &models.FunctionScoreConfig{
+				QueryType: "function_score",
+				Functions: []models.ScoreFunction{{Weight: 2}},
+			},
+			expectedError: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var body map[string]json.RawMessage
+			s := newFakeESService(t, func(w http.ResponseWriter, r *http.Request) {
+				json.NewDecoder(r.Body).Decode(&body)
+				w.Write([]byte(`{"took":5,"hits":{"total":{"value":1,"relation":"eq"},"max_score":4.2,"hits":[{"_index":"articles","_id":"9","_score":4.2,"_source":{"title":"Laptop review"}}]}}`))
+			})
+
+			response, err := s.Search(context.Background(), &models.SearchRequest{
+				Index:         "articles",
+				Query:         "laptop",
+				QueryType:     "function_score",
+				Fields:        []string{"title"},
+				Size:          10,
+				FunctionScore: tt.functionScore,
+			})
+			if tt.expectedError {
+				if err == nil {
+					t.Error("Expected an error")
+				}
+				if body != nil {
+					t.Error("Expected the search not to reach Elasticsearch")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+
+			var query struct {
+				FunctionScore map[string]json.RawMessage `json:"function_score"`
+			}
+			json.Unmarshal(body["query"], &query)
+			if query.FunctionScore == nil {
+				t.Fatalf("Expected a function_score query, got %s", body["query"])
+			}
+			if !strings.Contains(string(query.FunctionScore["query"]), `"match"`) {
+				t.Errorf("Expected the match query to be wrapped, got %s", query.FunctionScore["query"])
+			}
+			if string(query.FunctionScore["functions"]) != tt.expectedFunctions {
+				t.Errorf("Expected functions %s, got %s", tt.expectedFunctions, query.FunctionScore["functions"])
+			}
+			var scoreMode, boostMode string
+			json.Unmarshal(query.FunctionScore["score_mode"], &scoreMode)
+			json.Unmarshal(query.FunctionScore["boost_mode"], &boostMode)
+			if modes := scoreMode + " " + boostMode; modes != tt.expectedModes {
+				t.Errorf("Expected modes %q, got %q", tt.expectedModes, modes)
+			}
+			if len(response.Hits) != 1 || *response.Hits[0].Score != 4.2 {
+				t.Errorf("Expected the rescored hit from Elasticsearch, got %+v", response.Hits)
+			}
+		})
+	}
+}
+
+// nestedSearchAPIResponse follows a nested query on comments with inner hits: the post
+// carries the matching comment and where it sits in the comments array
+const nestedSearchAPIResponse = `{
+  "took": 4,
+  "timed_out": false,
+  "hits": {
+    "total": {"value": 1, "relation": "eq"},
+    "max_score": 1.3,
+    "hits": [
+      {
+        "_index": "posts",
+        "_id": "1",
+        "_score": 1.3,
+        "_source": {"title": "Choosing a laptop", "comments": [{"author": "ana", "text": "Great tips"}, {"author": "ben", "text": "Which laptop for travel?"}]},
+        "inner_hits": {
+          "comments": {
+            "hits": {
+              "total": {"value": 1, "relation": "eq"},
+              "max_score": 1.3,
+              "hits": [
+                {"_index": "posts", "_id": "1", "_nested": {"field": "comments", "offset": 1}, "_score": 1.3, "_source": {"author": "ben", "text": "Which laptop for travel?"}}
+              ]
+            }
+          }
+        }
+      }
+    ]
+  }
+}`
+
+func TestSearchWithRelations(t *testing.T) {
+	tests := []struct {
+		name           string
+		relation       *models.RelationQuery
+		filters        []models.Filter
+		expectedMust   string
+		expectedFilter string
+	}{
+		{
+			name:         "nested query with inner hits",
+			relation:     &models.RelationQuery{Type: "nested", Path: "comments", ScoreMode: "max", InnerHits: &models.InnerHitsConfig{Size: 2}},
+			expectedMust: `[{"nested":{"inner_hits":{"size":2},"path":"comments","query":{"match":{"comments.text":{"query":"laptop"}}},"score_mode":"max"}}]`,
+		},
+		{
+			name:         "has_child query",
+			relation:     &models.RelationQuery{Type: "has_child", RelationType: "answer", ScoreMode: "sum"},
+			expectedMust: `[{"has_child":{"query":{"match":{"comments.text":{"query":"laptop"}}},"score_mode":"sum","type":"answer"}}]`,
+		},
+		{
+			name:           "has_parent filter",
+			relation:       &models.RelationQuery{Type: "has_parent", RelationType: "question", ScoreMode: "none"},
+			filters:        []models.Filter{{Type: "has_parent", RelationType: "question", Filters: []models.Filter{{Field: "tags", Type: "term", Value: "hardware"}}}},
+			expectedMust:   `[{"has_parent":{"parent_type":"question","query":{"match":{"comments.text":{"query":"laptop"}}},"score":false}}]`,
+			expectedFilter: `[{"has_parent":{"parent_type":"question","query":{"term":{"tags":"hardware"}},"score":false}}]`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var body struct {
+				Query struct {
+					Bool map[string]json.RawMessage `json:"bool"`
+				} `json:"query"`
+			}
+			s := newFakeESService(t, func(w http.ResponseWriter, r *http.Request) {
+				json.NewDecoder(r.Body).Decode(&body)
+				w.Write([]byte(nestedSearchAPIResponse))
+			})
+
+			response, err := s.Search(context.Background(), &models.SearchRequest{
+				Index:     "posts",
+				Query:     "laptop",
+				QueryType: "match",
+				Fields:    []string{"comments.text"},
+				Size:      10,
+				Relation:  tt.relation,
+				Filters:   tt.filters,
+			})
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+
+			if string(body.Query.Bool["must"]) != tt.expectedMust {
+				t.Errorf("Expected must %s, got %s", tt.expectedMust, body.Query.Bool["must"])
+			}
+			if tt.expectedFilter != "" && string(body.Query.Bool["filter"]) != tt.expectedFilter {
+				t.Errorf("Expected filter %s, got %s", tt.expectedFilter, body.Query.Bool["filter"])
+			}
+
+			comments := response.Hits[0].InnerHits["comments"]
+			if comments.Total.Value != 1 || len(comments.Hits) != 1 {
+				t.Fatalf("Expected the matching comment as an inner hit, got %+v", response.Hits[0].InnerHits)
+			}
+			expectedNested := &models.NestedIdentity{Field: "comments", Offset: 1}
+			if !reflect.DeepEqual(comments.Hits[0].Nested, expectedNested) {
+				t.Errorf("Expected nested identity %+v, got %+v", expectedNested, comments.Hits[0].Nested)
+			}
+		})
+	}
+}
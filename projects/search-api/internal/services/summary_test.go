@@ -0,0 +1,147 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+
+	"github.com/saif-islam/es-playground/projects/search-api/internal/models"
+	"github.com/saif-islam/es-playground/projects/search-api/internal/realtime"
+)
+
+// laptopSearchAPIResponse follows POST /products/_search for a match query on title
+const laptopSearchAPIResponse = `{
+  "took": 4,
+  "timed_out": false,
+  "_shards": {"total": 1, "successful": 1, "skipped": 0, "failed": 0},
+  "hits": {
+    "total": {"value": 2, "relation": "eq"},
+    "max_score": 1.3862942,
+    "hits": [
+      {"_index": "products", "_id": "1", "_score": 1.3862942, "_source": {"title": "Gaming Laptop", "price": 1299}},
+      {"_index": "products", "_id": "7", "_score": 0.9808291, "_source": {"title": "Laptop Stand", "price": 49}}
+    ]
+  }
+}`
+
+func TestSearchAnalyticsSummary(t *testing.T) {
+	s := newFakeESService(t, func(w http.ResponseWriter, r *http.Request) {
+		var body struct {
+			Query json.RawMessage `json:"query"`
+		}
+		json.NewDecoder(r.Body).Decode(&body)
+		switch {
+		case strings.Contains(string(body.Query), "lapptop"):
+			w.Write([]byte(`{"took":2,"timed_out":false,"_shards":{"total":1,"successful":1,"skipped":0,"failed":0},"hits":{"total":{"value":0,"relation":"eq"},"max_score":null,"hits":[]}}`))
+		case strings.Contains(string(body.Query), "title:("):
+			w.WriteHeader(http.StatusBadRequest)
+			w.Write([]byte(`{"error":{"root_cause":[{"type":"query_shard_exception","reason":"Failed to parse query [title:(]"}],"type":"search_phase_execution_exception","reason":"all shards failed"},"status":400}`))
+		default:
+			w.Write([]byte(laptopSearchAPIResponse))
+		}
+	})
+	hub := realtime.NewAnalyticsHub(models.RealtimeConfig{}, zap.NewNop())
+	s.analyticsHub = hub
+
+	searches := []struct {
+		index, query, queryType string
+	}{
+		{"products", "laptop", "match"},
+		{"products", "laptop", "match"},
+		{"products", "lapptop", "match"},
+		{"products", "title:(", "query_string"},
+		{"orders", "invoice", "match"},
+	}
+	for _, search := range searches {
+		s.Search(context.Background(), &models.SearchRequest{
+			Index:     search.index,
+			Query:     search.query,
+			QueryType: search.queryType,
+			Fields:    []string{"title"},
+			Size:      10,
+		})
+	}
+
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.GET("/api/search/analytics/summary", hub.HandleSummary)
+
+	tests := []struct {
+		name               string
+		query              string
+		expectedStatus     int
+		expectedTotal      int64
+		expectedErrorRate  float64
+		expectedZeroResult float64
+		expectedTopQuery   string
+	}{
+		{
+			name:               "one index",
+			query:              "?index=products&window=15m",
+			expectedStatus:     http.StatusOK,
+			expectedTotal:      4,
+			expectedErrorRate:  25,
+			expectedZeroResult: 100.0 / 3,
+			expectedTopQuery:   "laptop",
+		},
+		{
+			name:               "every index",
+			query:              "",
+			expectedStatus:     http.StatusOK,
+			expectedTotal:      5,
+			expectedErrorRate:  20,
+			expectedZeroResult: 25,
+			expectedTopQuery:   "laptop",
+		},
+		{
+			name:           "index without searches",
+			query:          "?index=logs",
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name:           "window over an hour",
+			query:          "?window=2h",
+			expectedStatus: http.StatusBadRequest,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/search/analytics/summary"+tt.query, nil))
+			if w.Code != tt.expectedStatus {
+				t.Fatalf("Expected status %d, got %d: %s", tt.expectedStatus, w.Code, w.Body.String())
+			}
+			if tt.expectedStatus != http.StatusOK {
+				return
+			}
+
+			var summary realtime.SearchSummary
+			if err := json.Unmarshal(w.Body.Bytes(), &summary); err != nil {
+				t.Fatalf("Failed to decode summary: %v", err)
+			}
+			if summary.Window != "15m0s" || summary.TotalSearches != tt.expectedTotal {
+				t.Errorf("Expected %d searches over 15m0s, got %d over %s", tt.expectedTotal, summary.TotalSearches, summary.Window)
+			}
+			if math.Abs(summary.ErrorRate-tt.expectedErrorRate) > 0.01 || math.Abs(summary.ZeroResultRate-tt.expectedZeroResult) > 0.01 {
+				t.Errorf("Expected %.2f%% errors and %.2f%% zero results, got %.2f%% and %.2f%%", tt.expectedErrorRate, tt.expectedZeroResult, summary.ErrorRate, summary.ZeroResultRate)
+			}
+			if tt.expectedTopQuery == "" {
+				if len(summary.TopQueries) != 0 {
+					t.Errorf("Expected no top queries, got %v", summary.TopQueries)
+				}
+				return
+			}
+			if len(summary.TopQueries) == 0 || summary.TopQueries[0] != (realtime.SummaryQuery{Query: tt.expectedTopQuery, Count: 2}) {
+				t.Errorf("Expected %s searched twice first, got %v", tt.expectedTopQuery, summary.TopQueries)
+			}
+		})
+	}
+}
@@ -0,0 +1,268 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/elastic/go-elasticsearch/v8/esapi"
+	"go.uber.org/zap"
+
+	"github.com/saif-islam/es-playground/projects/search-api/internal/metrics"
+	"github.com/saif-islam/es-playground/projects/search-api/internal/models"
+)
+
+// Search templates are stored scripts in the Mustache language
+const templateLang = "mustache"
+
+// ErrTemplateNotFound is returned when no search template is stored under an id
+var ErrTemplateNotFound = errors.New("search template not found")
+
+// ErrInvalidTemplate is returned when a search template is rejected by Elasticsearch
+var ErrInvalidTemplate = errors.New("invalid search template")
+
+// StoreTemplate stores a Mustache search template with PUT _scripts/<id>, replacing any
+// template stored under the id. The source is the search body to render, as a string or
+// as JSON.
+func (s *SearchService) StoreTemplate(ctx context.Context, id string, source interface{}) error {
+	if id == "" || source == nil {
+		return fmt.Errorf("%w: id and source are required", ErrInvalidTemplate)
+	}
+
+	body, err := json.Marshal(map[string]interface{}{
+		"script": map[string]interface{}{
+			"lang":   templateLang,
+			"source": source,
+		},
+	})
+	if err != nil {
+		return fmt.Errorf("failed to build template: %w", err)
+	}
+
+	putReq := esapi.PutScriptRequest{
+		ScriptID: id,
+		Body:     strings.NewReader(string(body)),
+	}
+	res, err := putReq.Do(ctx, s.esClient)
+	if err != nil {
+		return fmt.Errorf("store template request failed: %w", err)
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode == http.StatusBadRequest {
+		return fmt.Errorf("%w: %s", ErrInvalidTemplate, res.String())
+	}
+	if res.IsError() {
+		return fmt.Errorf("store template failed: %s", res.String())
+	}
+
+	s.logger.Info("Stored search template", zap.String("template_id", id))
+	return nil
+}
+
+// GetTemplate returns the search template stored under id
+func (s *SearchService) GetTemplate(ctx context.Context, id string) (*models.StoredTemplate, error) {
+	getReq := esapi.GetScriptRequest{ScriptID: id}
+	res, err := getReq.Do(ctx, s.esClient)
+	if err != nil {
+		return nil, fmt.Errorf("get template request failed: %w", err)
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode == http.StatusNotFound {
+		return nil, fmt.Errorf("%w: %s", ErrTemplateNotFound, id)
+	}
+	if res.IsError() {
+		return nil, fmt.Errorf("get template failed: %s", res.String())
+	}
+
+	var response struct {
+		Found  bool         `json:"found"`
+		Script storedScript `json:"script"`
+	}
+	if err := json.NewDecoder(res.Body).Decode(&response); err != nil {
+		return nil, fmt.Errorf("failed to parse template: %w", err)
+	}
+	// Painless scripts share the namespace but are not search templates
+	if !response.Found || response.Script.Lang != templateLang {
+		return nil, fmt.Errorf("%w: %s", ErrTemplateNotFound, id)
+	}
+
+	return &models.StoredTemplate{ID: id, Source: response.Script.Source}, nil
+}
+
+// storedScript is a script as returned by the cluster
+type storedScript struct {
+	Lang   string `json:"lang"`
+	Source string `json:"source"`
+}
+
+// ListTemplates returns every stored search template, sorted by id. Elasticsearch has no
+// API listing stored scripts, so they are read from the cluster state metadata.
+func (s *SearchService) ListTemplates(ctx context.Context) ([]models.StoredTemplate, error) {
+	stateReq := esapi.ClusterStateRequest{
+		Metric:     []string{"metadata"},
+		FilterPath: []string{"metadata.stored_scripts"},
+	}
+	res, err := stateReq.Do(ctx, s.esClient)
+	if err != nil {
+		return nil, fmt.Errorf("list templates request failed: %w", err)
+	}
+	defer res.Body.Close()
+
+	if res.IsError() {
+		return nil, fmt.Errorf("list templates failed: %s", res.String())
+	}
+
+	var response struct {
+		Metadata struct {
+			StoredScripts map[string]storedScript `json:"stored_scripts"`
+		} `json:"metadata"`
+	}
+	if err := json.NewDecoder(res.Body).Decode(&response); err != nil {
+		return nil, fmt.Errorf("failed to parse stored scripts: %w", err)
+	}
+
+	templates := make([]models.StoredTemplate, 0, len(response.Metadata.StoredScripts))
+	for id, script := range response.Metadata.StoredScripts {
+		if script.Lang == templateLang {
+			templates = append(templates, models.StoredTemplate{ID: id, Source: script.Source})
+		}
+	}
+	sort.Slice(templates, func(i, j int) bool {
+		return templates[i].ID < templates[j].ID
+	})
+
+	return templates, nil
+}
+
+// DeleteTemplate removes the search template stored under id
+func (s *SearchService) DeleteTemplate(ctx context.Context, id string) error {
+	deleteReq := esapi.DeleteScriptRequest{ScriptID: id}
+	res, err := deleteReq.Do(ctx, s.esClient)
+	if err != nil {
+		return fmt.Errorf("delete template request failed: %w", err)
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode == http.StatusNotFound {
+		return fmt.Errorf("%w: %s", ErrTemplateNotFound, id)
+	}
+	if res.IsError() {
+		return fmt.Errorf("delete template failed: %s", res.String())
+	}
+
+	s.logger.Info("Deleted search template", zap.String("template_id", id))
+	return nil
+}
+
+// RenderTemplate renders a stored search template with params into the search body it
+// would run, without running it
+func (s *SearchService) RenderTemplate(ctx context.Context, id string, params map[string]interface{}) (map[string]interface{}, error) {
+	body, err := json.Marshal(map[string]interface{}{"params": params})
+	if err != nil {
+		return nil, fmt.Errorf("failed to build render request: %w", err)
+	}
+
+	renderReq := esapi.RenderSearchTemplateRequest{
+		TemplateID: id,
+		Body:       strings.NewReader(string(body)),
+	}
+	res, err := renderReq.Do(ctx, s.esClient)
+	if err != nil {
+		return nil, fmt.Errorf("render template request failed: %w", err)
+	}
+	defer res.Body.Close()
+
+	if err := templateError(res, id, "render template"); err != nil {
+		return nil, err
+	}
+
+	var response struct {
+		TemplateOutput map[string]interface{} `json:"template_output"`
+	}
+	if err := json.NewDecoder(res.Body).Decode(&response); err != nil {
+		return nil, fmt.Errorf("failed to parse rendered template: %w", err)
+	}
+
+	return response.TemplateOutput, nil
+}
+
+// SearchTemplate runs the search template stored under id with the request's params. Hits
+// go through the same transformation and redaction as Search; the cost guard and cache
+// are skipped since the query is only known once Elasticsearch renders it.
+func (s *SearchService) SearchTemplate(ctx context.Context, id string, req *models.TemplateSearchRequest) (*models.SearchResponse, error) {
+	searchReq := &models.SearchRequest{
+		Index:     req.Index,
+		QueryType: "template",
+		RequestID: req.RequestID,
+		Scopes:    req.Scopes,
+	}
+
+	ctx, span := s.tracer.TraceSearchOperation(ctx, "search_template", searchReq)
+	defer span.End()
+
+	startTime := time.Now()
+
+	body, err := json.Marshal(map[string]interface{}{
+		"id":     id,
+		"params": req.Params,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to build template search: %w", err)
+	}
+
+	templateReq := esapi.SearchTemplateRequest{
+		Index: []string{req.Index},
+		Body:  strings.NewReader(string(body)),
+	}
+	res, err := templateReq.Do(ctx, s.esClient)
+	if err != nil {
+		s.tracer.RecordError(ctx, err, map[string]interface{}{
+			"operation": "search_template",
+		})
+		return nil, fmt.Errorf("template search request failed: %w", err)
+	}
+	defer res.Body.Close()
+
+	s.tracer.RecordElasticsearchResult(ctx, res.StatusCode, 0, time.Since(startTime))
+	if err := templateError(res, id, "template search"); err != nil {
+		return nil, err
+	}
+
+	var esResponse map[string]interface{}
+	if err := json.NewDecoder(res.Body).Decode(&esResponse); err != nil {
+		return nil, fmt.Errorf("failed to parse response: %w", err)
+	}
+
+	response := s.transformSearchResponse(esResponse, searchReq)
+	response.ResponseTime = time.Since(startTime)
+	response.RequestID = req.RequestID
+	response.Timestamp = time.Now()
+
+	s.tracer.RecordSearchResult(ctx, response.Total.Value, time.Duration(response.Took)*time.Millisecond, true)
+	metrics.RecordElasticsearchSearch(req.Index, "template", response.ResponseTime, response.Total.Value)
+
+	return response, nil
+}
+
+// templateError turns a failed render or template search into an error. Elasticsearch
+// answers a missing stored template with a 404 or, on some versions, a 400 naming it.
+func templateError(res *esapi.Response, id, operation string) error {
+	if !res.IsError() {
+		return nil
+	}
+	message := res.String()
+	if res.StatusCode == http.StatusNotFound || strings.Contains(message, "unable to find script") {
+		return fmt.Errorf("%w: %s", ErrTemplateNotFound, id)
+	}
+	if res.StatusCode == http.StatusBadRequest {
+		return fmt.Errorf("%w: %s", ErrInvalidTemplate, message)
+	}
+	return fmt.Errorf("%s failed: %s", operation, message)
+}
@@ -0,0 +1,261 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"reflect"
+	"regexp"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/saif-islam/es-playground/projects/search-api/internal/models"
+)
+
+// productSearchTemplate is a Mustache template of a product search body
+const productSearchTemplate = `{"query":{"match":{"title":"{{query_string}}"}},"size":{{size}}}`
+
+// mustacheTag matches a complete Mustache tag
+var mustacheTag = regexp.MustCompile(`{{[^{}]+}}`)
+
+// fakeScriptStore is a fake Elasticsearch keeping stored scripts the way the _scripts,
+// _render/template, _search/template and cluster state APIs expose them
+type fakeScriptStore struct {
+	mu       sync.Mutex
+	scripts  map[string]storedScript
+	searched []string // indices searched with a template, and the rendered body
+}
+
+func newFakeScriptStore() *fakeScriptStore {
+	return &fakeScriptStore{scripts: map[string]storedScript{
+		"boost-recent": {Lang: "painless", Source: "doc['created_at'].value.millis * params.factor"},
+	}}
+}
+
+// render fills a stored template's {{name}} placeholders with params, like Mustache does
+// for plain variables
+func (f *fakeScriptStore) render(id string, params map[string]interface{}) (map[string]interface{}, bool) {
+	script, ok := f.scripts[id]
+	if !ok || script.Lang != templateLang {
+		return nil, false
+	}
+	source := script.Source
+	for name, value := range params {
+		source = strings.ReplaceAll(source, "{{"+name+"}}", fmt.Sprint(value))
+	}
+	var output map[string]interface{}
+	json.Unmarshal([]byte(source), &output)
+	return output, true
+}
+
+func (f *fakeScriptStore) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	var body struct {
+		Script struct {
+			Lang   string          `json:"lang"`
+			Source json.RawMessage `json:"source"`
+		} `json:"script"`
+		ID     string                 `json:"id"`
+		Params map[string]interface{} `json:"params"`
+	}
+	json.NewDecoder(r.Body).Decode(&body)
+
+	path := strings.Trim(r.URL.Path, "/")
+	id := path[strings.LastIndex(path, "/")+1:]
+	switch {
+	case strings.HasPrefix(path, "_scripts/") && r.Method == http.MethodPut:
+		// Elasticsearch keeps a JSON source as its string form
+		var source string
+		if err := json.Unmarshal(body.Script.Source, &source); err != nil {
+			source = string(body.Script.Source)
+		}
+		if strings.Count(source, "{{") != len(mustacheTag.FindAllString(source, -1)) {
+			w.WriteHeader(http.StatusBadRequest)
+			w.Write([]byte(`{"error":{"root_cause":[{"type":"general_script_exception","reason":"Failed to compile inline script [` + id + `] using lang [mustache]"}],"type":"general_script_exception","reason":"Failed to compile inline script [` + id + `] using lang [mustache]"},"status":400}`))
+			return
+		}
+		f.scripts[id] = storedScript{Lang: body.Script.Lang, Source: source}
+		w.Write([]byte(`{"acknowledged":true}`))
+
+	case strings.HasPrefix(path, "_scripts/") && r.Method == http.MethodDelete:
+		if _, ok := f.scripts[id]; !ok {
+			w.WriteHeader(http.StatusNotFound)
+			w.Write([]byte(`{"error":{"root_cause":[{"type":"resource_not_found_exception","reason":"stored script [` + id + `] does not exist and cannot be deleted"}],"type":"resource_not_found_exception","reason":"stored script [` + id + `] does not exist and cannot be deleted"},"status":404}`))
+			return
+		}
+		delete(f.scripts, id)
+		w.Write([]byte(`{"acknowledged":true}`))
+
+	case strings.HasPrefix(path, "_scripts/"):
+		script, ok := f.scripts[id]
+		if !ok {
+			w.WriteHeader(http.StatusNotFound)
+			fmt.Fprintf(w, `{"_id":%q,"found":false}`, id)
+			return
+		}
+		response, _ := json.Marshal(map[string]interface{}{"_id": id, "found": true, "script": script})
+		w.Write(response)
+
+	case path == "_cluster/state/metadata":
+		response, _ := json.Marshal(map[string]interface{}{
+			"metadata": map[string]interface{}{"stored_scripts": f.scripts},
+		})
+		w.Write(response)
+
+	case strings.HasPrefix(path, "_render/template/"):
+		output, ok := f.render(id, body.Params)
+		if !ok {
+			w.WriteHeader(http.StatusNotFound)
+			w.Write([]byte(`{"error":{"root_cause":[{"type":"resource_not_found_exception","reason":"unable to find script [` + id + `] in cluster state"}],"type":"resource_not_found_exception","reason":"unable to find script [` + id + `] in cluster state"},"status":404}`))
+			return
+		}
+		response, _ := json.Marshal(map[string]interface{}{"template_output": output})
+		w.Write(response)
+
+	case strings.HasSuffix(path, "/_search/template"):
+		// Some versions answer a missing template with a 400 naming it
+		output, ok := f.render(body.ID, body.Params)
+		if !ok {
+			w.WriteHeader(http.StatusBadRequest)
+			w.Write([]byte(`{"error":{"root_cause":[{"type":"illegal_argument_exception","reason":"unable to find script [` + body.ID + `] in cluster state"}],"type":"illegal_argument_exception","reason":"unable to find script [` + body.ID + `] in cluster state"},"status":400}`))
+			return
+		}
+		rendered, _ := json.Marshal(output)
+		f.searched = append(f.searched, strings.TrimSuffix(path, "/_search/template")+" "+string(rendered))
+		if strings.Contains(string(rendered), "laptop") {
+			w.Write([]byte(laptopSearchAPIResponse))
+			return
+		}
+		w.Write([]byte(emptySearchAPIResponse))
+
+	default:
+		w.WriteHeader(http.StatusNotFound)
+	}
+}
+
+func TestSearchTemplates(t *testing.T) {
+	store := newFakeScriptStore()
+	s := newFakeESService(t, store.ServeHTTP)
+	ctx := context.Background()
+
+	// A template given as a string and one given as JSON
+	if err := s.StoreTemplate(ctx, "product-search", productSearchTemplate); err != nil {
+		t.Fatalf("Unexpected error storing a string template: %v", err)
+	}
+	if err := s.StoreTemplate(ctx, "match-all", map[string]interface{}{"query": map[string]interface{}{"match_all": map[string]interface{}{}}}); err != nil {
+		t.Fatalf("Unexpected error storing a JSON template: %v", err)
+	}
+
+	t.Run("store", func(t *testing.T) {
+		tests := []struct {
+			name          string
+			id            string
+			source        interface{}
+			expectedError error
+		}{
+			{name: "unbalanced tags", id: "broken", source: `{"query":{"match":{"title":"{{query_string}"}}}`, expectedError: ErrInvalidTemplate},
+			{name: "no source", id: "empty", expectedError: ErrInvalidTemplate},
+			{name: "no id", source: productSearchTemplate, expectedError: ErrInvalidTemplate},
+		}
+		for _, tt := range tests {
+			t.Run(tt.name, func(t *testing.T) {
+				if err := s.StoreTemplate(ctx, tt.id, tt.source); !errors.Is(err, tt.expectedError) {
+					t.Errorf("Expected %v, got %v", tt.expectedError, err)
+				}
+			})
+		}
+		if _, ok := store.scripts["broken"]; ok {
+			t.Errorf("Expected the rejected template not to be stored")
+		}
+		if store.scripts["product-search"].Lang != templateLang {
+			t.Errorf("Expected the template stored as mustache, got %+v", store.scripts["product-search"])
+		}
+	})
+
+	t.Run("get and list", func(t *testing.T) {
+		template, err := s.GetTemplate(ctx, "product-search")
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if template.Source != productSearchTemplate {
+			t.Errorf("Expected %s, got %s", productSearchTemplate, template.Source)
+		}
+
+		// Painless scripts share the namespace but are not templates
+		for _, id := range []string{"boost-recent", "missing"} {
+			if _, err := s.GetTemplate(ctx, id); !errors.Is(err, ErrTemplateNotFound) {
+				t.Errorf("Expected %s not found, got %v", id, err)
+			}
+		}
+
+		templates, err := s.ListTemplates(ctx)
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		expected := []models.StoredTemplate{
+			{ID: "match-all", Source: `{"query":{"match_all":{}}}`},
+			{ID: "product-search", Source: productSearchTemplate},
+		}
+		if !reflect.DeepEqual(templates, expected) {
+			t.Errorf("Expected %v, got %v", expected, templates)
+		}
+	})
+
+	t.Run("render", func(t *testing.T) {
+		output, err := s.RenderTemplate(ctx, "product-search", map[string]interface{}{"query_string": "laptop", "size": 5})
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		expected := map[string]interface{}{
+			"query": map[string]interface{}{"match": map[string]interface{}{"title": "laptop"}},
+			"size":  float64(5),
+		}
+		if !reflect.DeepEqual(output, expected) {
+			t.Errorf("Expected %v, got %v", expected, output)
+		}
+
+		if _, err := s.RenderTemplate(ctx, "missing", nil); !errors.Is(err, ErrTemplateNotFound) {
+			t.Errorf("Expected template not found, got %v", err)
+		}
+	})
+
+	t.Run("search", func(t *testing.T) {
+		response, err := s.SearchTemplate(ctx, "product-search", &models.TemplateSearchRequest{
+			Index:     "products",
+			Params:    map[string]interface{}{"query_string": "laptop", "size": 10},
+			RequestID: "req-7",
+		})
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		expectedSearch := []string{`products {"query":{"match":{"title":"laptop"}},"size":10}`}
+		if !reflect.DeepEqual(store.searched, expectedSearch) {
+			t.Errorf("Expected %v, got %v", expectedSearch, store.searched)
+		}
+		if response.Total.Value != 2 || len(response.Hits) != 2 || response.Hits[0].ID != "1" || response.RequestID != "req-7" {
+			t.Errorf("Expected the two laptop hits for req-7, got %+v", response)
+		}
+
+		// Answered with a 400 naming the script
+		if _, err := s.SearchTemplate(ctx, "missing", &models.TemplateSearchRequest{Index: "products"}); !errors.Is(err, ErrTemplateNotFound) {
+			t.Errorf("Expected template not found, got %v", err)
+		}
+	})
+
+	t.Run("delete", func(t *testing.T) {
+		if err := s.DeleteTemplate(ctx, "product-search"); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if _, err := s.GetTemplate(ctx, "product-search"); !errors.Is(err, ErrTemplateNotFound) {
+			t.Errorf("Expected the deleted template not found, got %v", err)
+		}
+		if err := s.DeleteTemplate(ctx, "product-search"); !errors.Is(err, ErrTemplateNotFound) {
+			t.Errorf("Expected deleting it again not found, got %v", err)
+		}
+	})
+}
@@ -0,0 +1,123 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/elastic/go-elasticsearch/v8/esapi"
+	"go.uber.org/zap"
+
+	"github.com/saif-islam/es-playground/projects/search-api/internal/models"
+	"github.com/saif-islam/es-playground/projects/search-api/internal/realtime"
+)
+
+// Corrections are looked up for at most this many zero-result searches per listing, each
+// with one suggest request
+const (
+	maxZeroResultSuggestionLookups = 20
+	zeroResultSuggestionSize       = 3
+)
+
+// recordZeroResult remembers a search with query text that matched nothing, for relevance
+// tuning
+func (s *SearchService) recordZeroResult(req *models.SearchRequest, total int64) {
+	if s.analyticsHub == nil || total != 0 || req.Query == "" {
+		return
+	}
+	s.analyticsHub.RecordZeroResult(req)
+}
+
+// ZeroResultQueries returns the most frequent searches that matched nothing, with phrase
+// suggester corrections for the first few. Corrections come from field, or from the field
+// the search used when field is empty; searches on neither get none. No corrections are
+// looked up while analytics anonymize query text.
+func (s *SearchService) ZeroResultQueries(ctx context.Context, limit int, field string) []realtime.ZeroResultQuery {
+	if s.analyticsHub == nil {
+		return []realtime.ZeroResultQuery{}
+	}
+
+	queries := s.analyticsHub.ZeroResultQueries(limit)
+	if !s.analyticsHub.RawQueries() {
+		return queries
+	}
+
+	for i := range queries {
+		if i >= maxZeroResultSuggestionLookups || ctx.Err() != nil {
+			break
+		}
+		suggestField := field
+		if suggestField == "" {
+			suggestField = queries[i].Field
+		}
+		if suggestField == "" {
+			continue
+		}
+
+		suggestions, err := s.suggestCorrections(ctx, queries[i].Index, suggestField, queries[i].Query)
+		if err != nil {
+			s.logger.Warn("Failed to suggest corrections for zero-result query",
+				zap.String("index", queries[i].Index),
+				zap.String("field", suggestField),
+				zap.Error(err))
+			continue
+		}
+		queries[i].Suggestions = suggestions
+	}
+
+	return queries
+}
+
+// suggestCorrections asks the phrase suggester for corrected versions of text
+func (s *SearchService) suggestCorrections(ctx context.Context, index, field, text string) ([]string, error) {
+	body, err := json.Marshal(map[string]interface{}{
+		"size": 0,
+		"suggest": map[string]interface{}{
+			"correction": s.buildSuggester(models.SuggesterConfig{
+				Text:  text,
+				Field: field,
+				Size:  zeroResultSuggestionSize,
+				Type:  "phrase",
+			}),
+		},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to build suggest request: %w", err)
+	}
+
+	searchReq := esapi.SearchRequest{
+		Index: []string{index},
+		Body:  strings.NewReader(string(body)),
+	}
+	res, err := searchReq.Do(ctx, s.esClient)
+	if err != nil {
+		return nil, fmt.Errorf("suggest request failed: %w", err)
+	}
+	defer res.Body.Close()
+
+	if res.IsError() {
+		return nil, fmt.Errorf("suggest failed: %s", res.String())
+	}
+
+	var response struct {
+		Suggest map[string][]struct {
+			Options []struct {
+				Text string `json:"text"`
+			} `json:"options"`
+		} `json:"suggest"`
+	}
+	if err := json.NewDecoder(res.Body).Decode(&response); err != nil {
+		return nil, fmt.Errorf("failed to parse suggest response: %w", err)
+	}
+
+	var suggestions []string
+	for _, entry := range response.Suggest["correction"] {
+		for _, option := range entry.Options {
+			if option.Text != "" && option.Text != text {
+				suggestions = append(suggestions, option.Text)
+			}
+		}
+	}
+	return suggestions, nil
+}
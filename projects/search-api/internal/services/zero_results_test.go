@@ -0,0 +1,199 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+
+	"go.uber.org/zap"
+
+	"github.com/saif-islam/es-playground/projects/search-api/internal/models"
+	"github.com/saif-islam/es-playground/projects/search-api/internal/realtime"
+)
+
+// emptySearchAPIResponse follows POST /products/_search for a query that matched nothing
+const emptySearchAPIResponse = `{"took":2,"timed_out":false,"_shards":{"total":1,"successful":1,"skipped":0,"failed":0},"hits":{"total":{"value":0,"relation":"eq"},"max_score":null,"hits":[]}}`
+
+// phraseSuggestAPIResponse follows POST /products/_search with a size 0 phrase suggester
+// named correction; the suggester may echo the original text among its options
+const phraseSuggestAPIResponse = `{
+  "took": 5,
+  "timed_out": false,
+  "_shards": {"total": 1, "successful": 1, "skipped": 0, "failed": 0},
+  "hits": {"total": {"value": 0, "relation": "eq"}, "max_score": null, "hits": []},
+  "suggest": {
+    "correction": [
+      {
+        "text": "%s",
+        "offset": 0,
+        "length": 7,
+        "options": [
+          {"text": "laptop", "score": 0.01914},
+          {"text": "%s", "score": 0.00312}
+        ]
+      }
+    ]
+  }
+}`
+
+// suggestRequest is a suggest request the fake Elasticsearch received
+type suggestRequest struct {
+	index, field, text string
+}
+
+// newZeroResultService returns a service searching a fake Elasticsearch where only laptop
+// matches, with analytics in queryMode, and the suggest requests the fake received
+func newZeroResultService(t *testing.T, queryMode string) (*SearchService, func() []suggestRequest) {
+	t.Helper()
+
+	var mu sync.Mutex
+	var suggests []suggestRequest
+	s := newFakeESService(t, func(w http.ResponseWriter, r *http.Request) {
+		var body struct {
+			Query   json.RawMessage `json:"query"`
+			Suggest map[string]struct {
+				Text   string `json:"text"`
+				Phrase struct {
+					Field string `json:"field"`
+					Size  int    `json:"size"`
+				} `json:"phrase"`
+			} `json:"suggest"`
+		}
+		json.NewDecoder(r.Body).Decode(&body)
+
+		if correction, ok := body.Suggest["correction"]; ok {
+			mu.Lock()
+			suggests = append(suggests, suggestRequest{
+				index: strings.TrimSuffix(strings.TrimPrefix(r.URL.Path, "/"), "/_search"),
+				field: correction.Phrase.Field,
+				text:  correction.Text,
+			})
+			mu.Unlock()
+			fmt.Fprintf(w, phraseSuggestAPIResponse, correction.Text, correction.Text)
+			return
+		}
+		if strings.Contains(string(body.Query), `"laptop"`) {
+			w.Write([]byte(laptopSearchAPIResponse))
+			return
+		}
+		w.Write([]byte(emptySearchAPIResponse))
+	})
+	s.analyticsHub = realtime.NewAnalyticsHub(models.RealtimeConfig{QueryMode: queryMode, HashSalt: "pepper"}, zap.NewNop())
+
+	return s, func() []suggestRequest {
+		mu.Lock()
+		defer mu.Unlock()
+		return suggests
+	}
+}
+
+func TestZeroResultQueries(t *testing.T) {
+	searches := []*models.SearchRequest{
+		{Index: "products", Query: "lapptop", QueryType: "match", Fields: []string{"title^2"}, Size: 10},
+		{Index: "products", Query: "laptop", QueryType: "match", Fields: []string{"title"}, Size: 10},
+		{Index: "products", Query: "lapptop", QueryType: "match", Fields: []string{"title^2"}, Size: 10},
+		{Index: "products", Query: "labtop", QueryType: "match", Fields: []string{"title"}, Size: 10,
+			Filters: []models.Filter{{Field: "category", Value: "electronics", Type: "term"}}},
+	}
+
+	tests := []struct {
+		name             string
+		queryMode        string
+		field            string
+		expectedQueries  []string // query:count, most frequent first
+		expectedSuggests []suggestRequest
+		expectedFilters  bool
+	}{
+		{
+			name:            "raw queries get corrections from the searched field",
+			queryMode:       realtime.QueryModeRaw,
+			expectedQueries: []string{"lapptop:2", "labtop:1"},
+			expectedSuggests: []suggestRequest{
+				{index: "products", field: "title", text: "lapptop"},
+				{index: "products", field: "title", text: "labtop"},
+			},
+			expectedFilters: true,
+		},
+		{
+			name:            "corrections from another field",
+			queryMode:       realtime.QueryModeRaw,
+			field:           "title.shingles",
+			expectedQueries: []string{"lapptop:2", "labtop:1"},
+			expectedSuggests: []suggestRequest{
+				{index: "products", field: "title.shingles", text: "lapptop"},
+				{index: "products", field: "title.shingles", text: "labtop"},
+			},
+			expectedFilters: true,
+		},
+		{
+			name:      "anonymized queries get no corrections",
+			queryMode: realtime.QueryModeHash,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s, suggests := newZeroResultService(t, tt.queryMode)
+			for _, search := range searches {
+				if _, err := s.Search(context.Background(), search); err != nil {
+					t.Fatalf("Unexpected error searching %q: %v", search.Query, err)
+				}
+			}
+
+			queries := s.ZeroResultQueries(context.Background(), 10, tt.field)
+			var counts []string
+			for _, query := range queries {
+				counts = append(counts, query.Query+":"+strconv.FormatInt(query.Count, 10))
+				if query.Index != "products" || query.Field != "title" {
+					t.Errorf("Expected products searched on title without its boost, got %+v", query)
+				}
+				// Only labtop, searched once, was filtered
+				if expectedFilters := tt.expectedFilters && query.Count == 1; (len(query.Filters) > 0) != expectedFilters {
+					t.Errorf("Expected filters kept %v, got %+v", expectedFilters, query)
+				}
+			}
+
+			if tt.queryMode != realtime.QueryModeRaw {
+				if len(queries) != 2 || queries[0].Count != 2 || strings.Contains(queries[0].Query, "lapptop") {
+					t.Fatalf("Expected 2 hashed queries, most frequent first, got %+v", queries)
+				}
+				if len(suggests()) != 0 || queries[0].Suggestions != nil {
+					t.Errorf("Expected no corrections looked up, got %v and %v", suggests(), queries[0].Suggestions)
+				}
+				return
+			}
+
+			if !reflect.DeepEqual(counts, tt.expectedQueries) {
+				t.Fatalf("Expected %v, got %v", tt.expectedQueries, counts)
+			}
+			if !reflect.DeepEqual(suggests(), tt.expectedSuggests) {
+				t.Errorf("Expected suggest requests %v, got %v", tt.expectedSuggests, suggests())
+			}
+			for _, query := range queries {
+				// The suggester's echo of the original text is not a correction
+				if !reflect.DeepEqual(query.Suggestions, []string{"laptop"}) {
+					t.Errorf("Expected laptop suggested for %s, got %v", query.Query, query.Suggestions)
+				}
+			}
+		})
+	}
+}
+
+func TestZeroResultQueries_WithoutAnalytics(t *testing.T) {
+	s := newFakeESService(t, func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(emptySearchAPIResponse))
+	})
+
+	if _, err := s.Search(context.Background(), &models.SearchRequest{Index: "products", Query: "lapptop", QueryType: "match", Fields: []string{"title"}, Size: 10}); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if queries := s.ZeroResultQueries(context.Background(), 10, ""); queries == nil || len(queries) != 0 {
+		t.Errorf("Expected an empty list, got %v", queries)
+	}
+}
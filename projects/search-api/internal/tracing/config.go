@@ -5,6 +5,7 @@ import (
 	"time"
 
 	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
 	"go.opentelemetry.io/otel/exporters/jaeger"
 	"go.opentelemetry.io/otel/propagation"
 	"go.opentelemetry.io/otel/sdk/resource"
@@ -180,7 +181,7 @@ func (tp *TracingProvider) AddSpanEvent(ctx context.Context, name string, attrib
 func (tp *TracingProvider) SetSpanAttributes(ctx context.Context, attributes map[string]interface{}) {
 	span := trace.SpanFromContext(ctx)
 	if span.IsRecording() {
-		attrs := make([]trace.Attribute, 0, len(attributes))
+		attrs := make([]attribute.KeyValue, 0, len(attributes))
 		for k, v := range attributes {
 			attrs = append(attrs, convertToAttribute(k, v))
 		}
@@ -194,7 +195,7 @@ func (tp *TracingProvider) RecordError(ctx context.Context, err error, attribute
 	if span.IsRecording() {
 		opts := []trace.EventOption{trace.WithStackTrace(true)}
 		if attributes != nil {
-			attrs := make([]trace.Attribute, 0, len(attributes))
+			attrs := make([]attribute.KeyValue, 0, len(attributes))
 			for k, v := range attributes {
 				attrs = append(attrs, convertToAttribute(k, v))
 			}
@@ -204,20 +205,20 @@ func (tp *TracingProvider) RecordError(ctx context.Context, err error, attribute
 	}
 }
 
-// Helper function to convert interface{} to trace.Attribute
-func convertToAttribute(key string, value interface{}) trace.Attribute {
+// Helper function to convert interface{} to attribute.KeyValue
+func convertToAttribute(key string, value interface{}) attribute.KeyValue {
 	switch v := value.(type) {
 	case string:
-		return trace.StringAttribute(key, v)
+		return attribute.String(key, v)
 	case int:
-		return trace.IntAttribute(key, v)
+		return attribute.Int(key, v)
 	case int64:
-		return trace.Int64Attribute(key, v)
+		return attribute.Int64(key, v)
 	case float64:
-		return trace.Float64Attribute(key, v)
+		return attribute.Float64(key, v)
 	case bool:
-		return trace.BoolAttribute(key, v)
+		return attribute.Bool(key, v)
 	default:
-		return trace.StringAttribute(key, "unsupported_type")
+		return attribute.String(key, "unsupported_type")
 	}
 }
\ No newline at end of file
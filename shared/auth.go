@@ -0,0 +1,114 @@
+package shared
+
+import (
+	"crypto/subtle"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+// verifiedAPIKeyContextKey holds the API key RequireAPIKey accepted for a request
+const verifiedAPIKeyContextKey = "verified_api_key"
+
+// AuthConfig configures API key authentication of the services' own APIs
+type AuthConfig struct {
+	// Keys accepted as "Authorization: Bearer <key>" or "X-API-Key: <key>"; an empty
+	// list disables authentication
+	APIKeys []string `yaml:"api_keys"`
+
+	// Routes whose GET and HEAD requests need no key, as registered with the router
+	// (e.g. "/api/v1/clusters" or "/api/v1/indices/:index"). Reads of every other
+	// route need a key like writes do.
+	PublicPaths []string `yaml:"public_paths"`
+}
+
+// Enabled reports whether any API key is configured
+func (c AuthConfig) Enabled() bool {
+	for _, key := range c.APIKeys {
+		if key != "" {
+			return true
+		}
+	}
+	return false
+}
+
+// RequireAPIKey returns middleware rejecting requests that don't carry one of the
+// configured API keys. Reads are protected too, since they expose cluster data; only
+// GET and HEAD requests to the configured public paths, and CORS preflights, pass
+// without a key. With no keys configured every request passes. An accepted key is
+// stored on the context for the rate limiter to count against.
+func RequireAPIKey(config AuthConfig, logger *zap.Logger) gin.HandlerFunc {
+	keys := make([][]byte, 0, len(config.APIKeys))
+	for _, key := range config.APIKeys {
+		if key != "" {
+			keys = append(keys, []byte(key))
+		}
+	}
+
+	public := make(map[string]bool, len(config.PublicPaths))
+	for _, path := range config.PublicPaths {
+		public[path] = true
+	}
+
+	return func(c *gin.Context) {
+		if len(keys) == 0 || c.Request.Method == http.MethodOptions {
+			c.Next()
+			return
+		}
+		if readMethod(c.Request.Method) && public[c.FullPath()] {
+			c.Next()
+			return
+		}
+
+		credential := requestCredential(c.Request)
+		if credential == "" {
+			rejectUnauthorized(c, logger, "An API key is required: send Authorization: Bearer <key> or X-API-Key")
+			return
+		}
+
+		for _, key := range keys {
+			if subtle.ConstantTimeCompare([]byte(credential), key) == 1 {
+				c.Set(verifiedAPIKeyContextKey, credential)
+				c.Next()
+				return
+			}
+		}
+		rejectUnauthorized(c, logger, "Invalid API key")
+	}
+}
+
+// readMethod reports whether an HTTP method only reads
+func readMethod(method string) bool {
+	return method == http.MethodGet || method == http.MethodHead
+}
+
+// requestCredential returns the bearer token or X-API-Key header of a request
+func requestCredential(r *http.Request) string {
+	if key := r.Header.Get("X-API-Key"); key != "" {
+		return key
+	}
+
+	scheme, token, found := strings.Cut(r.Header.Get("Authorization"), " ")
+	if found && strings.EqualFold(scheme, "Bearer") {
+		return strings.TrimSpace(token)
+	}
+	return ""
+}
+
+func rejectUnauthorized(c *gin.Context, logger *zap.Logger, message string) {
+	logger.Warn("Rejected unauthenticated request",
+		zap.String("method", c.Request.Method),
+		zap.String("path", c.Request.URL.Path),
+		zap.String("client_ip", c.ClientIP()))
+
+	c.Header("WWW-Authenticate", `Bearer realm="es-playground"`)
+	c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{
+		"error":      "Unauthorized",
+		"message":    message,
+		"request_id": c.GetString("request_id"),
+		"timestamp":  time.Now(),
+	})
+}
@@ -0,0 +1,148 @@
+package shared
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+func TestAuthConfig_Enabled(t *testing.T) {
+	if (AuthConfig{}).Enabled() || (AuthConfig{APIKeys: []string{""}}).Enabled() {
+		t.Error("Expected auth without non-empty keys to be disabled")
+	}
+	if !(AuthConfig{APIKeys: []string{"", "secret"}}).Enabled() {
+		t.Error("Expected auth with a key to be enabled")
+	}
+}
+
+func TestRequireAPIKey(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	testCases := []struct {
+		name           string
+		config         AuthConfig
+		method         string
+		path           string
+		header         string
+		value          string
+		expectedStatus int
+		expectedKey    string // key stored for the rate limiter
+	}{
+		// Credentials
+		{
+			name: "missing key", config: AuthConfig{APIKeys: []string{"secret"}},
+			method: http.MethodDelete, path: "/api/v1/indices/logs", expectedStatus: http.StatusUnauthorized,
+		},
+		{
+			name: "wrong key", config: AuthConfig{APIKeys: []string{"secret"}},
+			method: http.MethodDelete, path: "/api/v1/indices/logs", header: "X-API-Key", value: "guess", expectedStatus: http.StatusUnauthorized,
+		},
+		{
+			name: "prefix of a key", config: AuthConfig{APIKeys: []string{"secret"}},
+			method: http.MethodDelete, path: "/api/v1/indices/logs", header: "X-API-Key", value: "secre", expectedStatus: http.StatusUnauthorized,
+		},
+		{
+			name: "key with a suffix", config: AuthConfig{APIKeys: []string{"secret"}},
+			method: http.MethodDelete, path: "/api/v1/indices/logs", header: "X-API-Key", value: "secrets", expectedStatus: http.StatusUnauthorized,
+		},
+		{
+			name: "valid api key", config: AuthConfig{APIKeys: []string{"other", "secret"}},
+			method: http.MethodDelete, path: "/api/v1/indices/logs", header: "X-API-Key", value: "secret",
+			expectedStatus: http.StatusOK, expectedKey: "secret",
+		},
+		{
+			name: "valid bearer token", config: AuthConfig{APIKeys: []string{"secret"}},
+			method: http.MethodDelete, path: "/api/v1/indices/logs", header: "Authorization", value: "bearer  secret",
+			expectedStatus: http.StatusOK, expectedKey: "secret",
+		},
+		{
+			name: "basic credentials", config: AuthConfig{APIKeys: []string{"secret"}},
+			method: http.MethodDelete, path: "/api/v1/indices/logs", header: "Authorization", value: "Basic secret", expectedStatus: http.StatusUnauthorized,
+		},
+
+		// Reads
+		{
+			name: "read without a key", config: AuthConfig{APIKeys: []string{"secret"}},
+			method: http.MethodGet, path: "/api/v1/overview", expectedStatus: http.StatusUnauthorized,
+		},
+		{
+			name: "websocket upgrade without a key", config: AuthConfig{APIKeys: []string{"secret"}},
+			method: http.MethodGet, path: "/api/v1/cluster/monitor/health/ws", header: "Upgrade", value: "websocket", expectedStatus: http.StatusUnauthorized,
+		},
+		{
+			name: "read with a key", config: AuthConfig{APIKeys: []string{"secret"}},
+			method: http.MethodGet, path: "/api/v1/overview", header: "X-API-Key", value: "secret",
+			expectedStatus: http.StatusOK, expectedKey: "secret",
+		},
+		{
+			name: "public read", config: AuthConfig{APIKeys: []string{"secret"}, PublicPaths: []string{"/api/v1/overview"}},
+			method: http.MethodGet, path: "/api/v1/overview", expectedStatus: http.StatusOK,
+		},
+		{
+			name: "public head", config: AuthConfig{APIKeys: []string{"secret"}, PublicPaths: []string{"/api/v1/overview"}},
+			method: http.MethodHead, path: "/api/v1/overview", expectedStatus: http.StatusOK,
+		},
+		{
+			name: "public route with a parameter", config: AuthConfig{APIKeys: []string{"secret"}, PublicPaths: []string{"/api/v1/indices/:index"}},
+			method: http.MethodGet, path: "/api/v1/indices/logs", expectedStatus: http.StatusOK,
+		},
+		{
+			name: "write to a public path", config: AuthConfig{APIKeys: []string{"secret"}, PublicPaths: []string{"/api/v1/indices/:index"}},
+			method: http.MethodDelete, path: "/api/v1/indices/logs", expectedStatus: http.StatusUnauthorized,
+		},
+		{
+			name: "public path matches the route, not the url", config: AuthConfig{APIKeys: []string{"secret"}, PublicPaths: []string{"/api/v1/indices/logs"}},
+			method: http.MethodGet, path: "/api/v1/indices/logs", expectedStatus: http.StatusUnauthorized,
+		},
+		{
+			name: "preflight", config: AuthConfig{APIKeys: []string{"secret"}},
+			method: http.MethodOptions, path: "/api/v1/indices/logs", expectedStatus: http.StatusOK,
+		},
+
+		// Disabled
+		{
+			name: "no keys configured", config: AuthConfig{APIKeys: []string{""}},
+			method: http.MethodDelete, path: "/api/v1/indices/logs", expectedStatus: http.StatusOK,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			var verifiedKey string
+			handler := func(c *gin.Context) {
+				verifiedKey = c.GetString(verifiedAPIKeyContextKey)
+				c.Status(http.StatusOK)
+			}
+
+			router := gin.New()
+			v1 := router.Group("/api/v1")
+			v1.Use(RequireAPIKey(tc.config, zap.NewNop()))
+			v1.GET("/overview", handler)
+			v1.HEAD("/overview", handler)
+			v1.GET("/indices/:index", handler)
+			v1.DELETE("/indices/:index", handler)
+			v1.OPTIONS("/indices/:index", handler)
+			v1.GET("/cluster/monitor/health/ws", handler)
+
+			req := httptest.NewRequest(tc.method, tc.path, nil)
+			if tc.header != "" {
+				req.Header.Set(tc.header, tc.value)
+			}
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+
+			if w.Code != tc.expectedStatus {
+				t.Fatalf("Expected status %d, got %d", tc.expectedStatus, w.Code)
+			}
+			if w.Code == http.StatusUnauthorized && w.Header().Get("WWW-Authenticate") == "" {
+				t.Error("Expected a WWW-Authenticate challenge with the 401")
+			}
+			if verifiedKey != tc.expectedKey {
+				t.Errorf("Expected verified key %q, got %q", tc.expectedKey, verifiedKey)
+			}
+		})
+	}
+}
@@ -0,0 +1,115 @@
+package shared
+
+import (
+	"bytes"
+	"io"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// Bytes of each body kept when BodyCaptureConfig.MaxBodyBytes is unset
+const defaultMaxCapturedBodyBytes = 4096
+
+// BodyCaptureConfig configures debug logging of request and response bodies. It is meant
+// to be inlined into a service's logging config.
+type BodyCaptureConfig struct {
+	// Log request and response bodies at debug level; bodies may hold sensitive data, so
+	// keep this off in production
+	CaptureBodies bool `yaml:"capture_bodies"`
+	// Bytes of each body logged, the rest is dropped (default 4096)
+	MaxBodyBytes int `yaml:"max_body_bytes"`
+}
+
+// CaptureBodies returns middleware logging each request's method, path, status and the
+// first MaxBodyBytes of its request and response bodies at debug level, tagged with the
+// request ID. Bodies are copied as the handler reads and writes them, so streamed uploads
+// and responses are neither buffered in full nor delayed. The middleware does nothing
+// unless capture is enabled and the logger logs at debug level.
+func CaptureBodies(config BodyCaptureConfig, logger *zap.Logger) gin.HandlerFunc {
+	passThrough := func(c *gin.Context) { c.Next() }
+	if !config.CaptureBodies {
+		return passThrough
+	}
+	if !logger.Core().Enabled(zapcore.DebugLevel) {
+		logger.Warn("logging.capture_bodies has no effect unless logging.level is debug")
+		return passThrough
+	}
+
+	limit := config.MaxBodyBytes
+	if limit <= 0 {
+		limit = defaultMaxCapturedBodyBytes
+	}
+	logger.Warn("Logging request and response bodies, which may contain sensitive data",
+		zap.Int("max_body_bytes", limit))
+
+	return func(c *gin.Context) {
+		start := time.Now()
+
+		request := &cappedBuffer{limit: limit}
+		if c.Request.Body != nil {
+			c.Request.Body = &teeReadCloser{
+				Reader: io.TeeReader(c.Request.Body, request),
+				Closer: c.Request.Body,
+			}
+		}
+
+		response := &cappedBuffer{limit: limit}
+		c.Writer = &capturingWriter{ResponseWriter: c.Writer, body: response}
+
+		c.Next()
+
+		logger.Debug("HTTP exchange",
+			zap.String("request_id", c.GetString("request_id")),
+			zap.String("method", c.Request.Method),
+			zap.String("path", c.Request.URL.Path),
+			zap.String("query", c.Request.URL.RawQuery),
+			zap.ByteString("request_body", request.Bytes()),
+			zap.Bool("request_body_truncated", request.truncated),
+			zap.Int("status", c.Writer.Status()),
+			zap.ByteString("response_body", response.Bytes()),
+			zap.Bool("response_body_truncated", response.truncated),
+			zap.Duration("duration", time.Since(start)))
+	}
+}
+
+// cappedBuffer keeps the first limit bytes written to it and discards the rest
+type cappedBuffer struct {
+	bytes.Buffer
+	limit     int
+	truncated bool
+}
+
+func (b *cappedBuffer) Write(p []byte) (int, error) {
+	if room := b.limit - b.Len(); room < len(p) {
+		b.truncated = true
+		if room > 0 {
+			b.Buffer.Write(p[:room])
+		}
+		return len(p), nil
+	}
+	return b.Buffer.Write(p)
+}
+
+type teeReadCloser struct {
+	io.Reader
+	io.Closer
+}
+
+// capturingWriter copies what a handler writes into body on its way to the client
+type capturingWriter struct {
+	gin.ResponseWriter
+	body *cappedBuffer
+}
+
+func (w *capturingWriter) Write(p []byte) (int, error) {
+	w.body.Write(p)
+	return w.ResponseWriter.Write(p)
+}
+
+func (w *capturingWriter) WriteString(s string) (int, error) {
+	w.body.Write([]byte(s))
+	return w.ResponseWriter.WriteString(s)
+}
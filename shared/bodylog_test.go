@@ -0,0 +1,144 @@
+package shared
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"go.uber.org/zap/zaptest/observer"
+)
+
+// searchAPIResponse is the response of the fake Elasticsearch the body capture tests search
+const searchAPIResponse = `{"took":3,"timed_out":false,"_shards":{"total":1,"successful":1,"skipped":0,"failed":0},"hits":{"total":{"value":1,"relation":"eq"},"max_score":1.3862942,"hits":[{"_index":"products","_id":"1","_score":1.3862942,"_source":{"title":"Laptop"}}]}}`
+
+// newSearchProxy returns a router that streams searches through to a fake Elasticsearch
+// behind the body capture middleware, and the body the fake received
+func newSearchProxy(t *testing.T, config BodyCaptureConfig, logger *zap.Logger) (*gin.Engine, *string) {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+
+	var received string
+	es := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("X-Elastic-Product", "Elasticsearch")
+		w.Header().Set("Content-Type", "application/json")
+		if r.URL.Path != "/products/_search" {
+			w.Write([]byte(`{"version":{"number":"8.11.1"}}`))
+			return
+		}
+		body, _ := io.ReadAll(r.Body)
+		received = string(body)
+		w.Write([]byte(searchAPIResponse))
+	}))
+	t.Cleanup(es.Close)
+
+	client, err := NewESClient(&ESConfig{URLs: []string{es.URL}}, zap.NewNop())
+	if err != nil {
+		t.Fatalf("Failed to create client: %v", err)
+	}
+
+	router := gin.New()
+	router.Use(func(c *gin.Context) {
+		c.Set("request_id", "req-42")
+		c.Next()
+	})
+	router.Use(CaptureBodies(config, logger))
+	router.POST("/search", func(c *gin.Context) {
+		res, err := client.Search(
+			client.Search.WithContext(c.Request.Context()),
+			client.Search.WithIndex("products"),
+			client.Search.WithBody(c.Request.Body),
+		)
+		if err != nil {
+			c.JSON(http.StatusBadGateway, gin.H{"error": err.Error()})
+			return
+		}
+		defer res.Body.Close()
+		c.DataFromReader(res.StatusCode, -1, "application/json", res.Body, nil)
+	})
+	return router, &received
+}
+
+func TestCaptureBodies(t *testing.T) {
+	query := `{"query":{"match":{"title":{"query":"laptop"}}},"size":10}`
+
+	testCases := []struct {
+		name             string
+		config           BodyCaptureConfig
+		level            zapcore.Level
+		expectedCaptured bool
+		expectedRequest  string
+		expectedResponse string
+		expectedCut      bool
+	}{
+		{
+			name:             "full bodies",
+			config:           BodyCaptureConfig{CaptureBodies: true},
+			level:            zapcore.DebugLevel,
+			expectedCaptured: true,
+			expectedRequest:  query,
+			expectedResponse: searchAPIResponse,
+		},
+		{
+			name:             "bodies over the limit",
+			config:           BodyCaptureConfig{CaptureBodies: true, MaxBodyBytes: 20},
+			level:            zapcore.DebugLevel,
+			expectedCaptured: true,
+			expectedRequest:  query[:20],
+			expectedResponse: searchAPIResponse[:20],
+			expectedCut:      true,
+		},
+		{
+			name:   "capture disabled",
+			config: BodyCaptureConfig{},
+			level:  zapcore.DebugLevel,
+		},
+		{
+			name:   "logger above debug level",
+			config: BodyCaptureConfig{CaptureBodies: true},
+			level:  zapcore.InfoLevel,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			core, logs := observer.New(tc.level)
+			router, received := newSearchProxy(t, tc.config, zap.New(core))
+
+			req := httptest.NewRequest(http.MethodPost, "/search?explain=false", strings.NewReader(query))
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+
+			// Capturing never changes what Elasticsearch or the client see
+			if *received != query || w.Code != http.StatusOK || w.Body.String() != searchAPIResponse {
+				t.Fatalf("Expected the search to pass through unchanged, got %q sent and %d %q returned", *received, w.Code, w.Body.String())
+			}
+
+			exchanges := logs.FilterMessage("HTTP exchange").All()
+			if !tc.expectedCaptured {
+				if len(exchanges) != 0 {
+					t.Errorf("Expected no bodies logged, got %v", exchanges[0].ContextMap())
+				}
+				return
+			}
+			if len(exchanges) != 1 || exchanges[0].Level != zapcore.DebugLevel {
+				t.Fatalf("Expected one exchange logged at debug level, got %d", len(exchanges))
+			}
+
+			fields := exchanges[0].ContextMap()
+			if fields["request_id"] != "req-42" || fields["method"] != http.MethodPost || fields["path"] != "/search" || fields["query"] != "explain=false" || fields["status"] != int64(http.StatusOK) {
+				t.Errorf("Expected the request details, got %v", fields)
+			}
+			if fields["request_body"] != tc.expectedRequest || fields["request_body_truncated"] != tc.expectedCut {
+				t.Errorf("Expected request body %q (truncated %v), got %q (truncated %v)", tc.expectedRequest, tc.expectedCut, fields["request_body"], fields["request_body_truncated"])
+			}
+			if fields["response_body"] != tc.expectedResponse || fields["response_body_truncated"] != tc.expectedCut {
+				t.Errorf("Expected response body %q (truncated %v), got %q (truncated %v)", tc.expectedResponse, tc.expectedCut, fields["response_body"], fields["response_body_truncated"])
+			}
+		})
+	}
+}
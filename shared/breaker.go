@@ -0,0 +1,150 @@
+package shared
+
+import (
+	"errors"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// defaultBreakerOpenTimeout is how long an open breaker rejects requests before probing
+const defaultBreakerOpenTimeout = 30 * time.Second
+
+// ErrCircuitOpen is returned instead of calling Elasticsearch while the breaker is open
+var ErrCircuitOpen = errors.New("elasticsearch circuit breaker is open")
+
+// BreakerState is the state of a circuit breaker
+type BreakerState string
+
+// Circuit breaker states
+const (
+	BreakerClosed   BreakerState = "closed"    // requests flow normally
+	BreakerOpen     BreakerState = "open"      // requests fail fast
+	BreakerHalfOpen BreakerState = "half_open" // a single probe request is testing recovery
+)
+
+// BreakerConfig configures the circuit breaker around a cluster's client
+type BreakerConfig struct {
+	// Consecutive failed requests that open the breaker; 0 disables it
+	FailureThreshold int `yaml:"failure_threshold"`
+	// How long the breaker stays open before letting a probe request through, defaults to 30s
+	OpenTimeout time.Duration `yaml:"open_timeout"`
+}
+
+// CircuitBreaker stops sending requests to an overloaded cluster. It opens after a number
+// of consecutive failures, fails fast while open, and after the open timeout lets one probe
+// request through: success closes it again, failure reopens it.
+type CircuitBreaker struct {
+	mu          sync.Mutex
+	threshold   int
+	openTimeout time.Duration
+	state       BreakerState
+	failures    int
+	openedAt    time.Time
+	now         func() time.Time
+}
+
+// NewCircuitBreaker creates a closed circuit breaker
+func NewCircuitBreaker(config BreakerConfig) *CircuitBreaker {
+	if config.OpenTimeout <= 0 {
+		config.OpenTimeout = defaultBreakerOpenTimeout
+	}
+
+	return &CircuitBreaker{
+		threshold:   config.FailureThreshold,
+		openTimeout: config.OpenTimeout,
+		state:       BreakerClosed,
+		now:         time.Now,
+	}
+}
+
+// State returns the current state of the breaker
+func (b *CircuitBreaker) State() BreakerState {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	return b.state
+}
+
+// allow reports whether a request may be sent, moving an open breaker whose timeout has
+// passed to half-open and letting that one request through as the probe
+func (b *CircuitBreaker) allow() bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	switch b.state {
+	case BreakerOpen:
+		if b.now().Sub(b.openedAt) < b.openTimeout {
+			return false
+		}
+		b.state = BreakerHalfOpen
+		return true
+	case BreakerHalfOpen:
+		// The probe is still in flight
+		return false
+	default:
+		return true
+	}
+}
+
+// record updates the breaker with the outcome of a request it allowed
+func (b *CircuitBreaker) record(success bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if success {
+		b.state = BreakerClosed
+		b.failures = 0
+		return
+	}
+
+	b.failures++
+	if b.state == BreakerHalfOpen || b.failures >= b.threshold {
+		b.state = BreakerOpen
+		b.openedAt = b.now()
+	}
+}
+
+// breakerTransport sends requests through a circuit breaker
+type breakerTransport struct {
+	base    http.RoundTripper
+	breaker *CircuitBreaker
+}
+
+// newBreakerTransport wraps base with a circuit breaker
+func newBreakerTransport(base http.RoundTripper, breaker *CircuitBreaker) http.RoundTripper {
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	return &breakerTransport{base: base, breaker: breaker}
+}
+
+// RoundTrip implements http.RoundTripper
+func (t *breakerTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if !t.breaker.allow() {
+		return nil, ErrCircuitOpen
+	}
+
+	res, err := t.base.RoundTrip(req)
+	if err != nil && req.Context().Err() != nil {
+		// The caller gave up; that says nothing about the cluster. A probe is released
+		// by reopening the breaker for another timeout.
+		if t.breaker.State() == BreakerHalfOpen {
+			t.breaker.record(false)
+		}
+		return nil, err
+	}
+
+	t.breaker.record(err == nil && !overloaded(res.StatusCode))
+	return res, err
+}
+
+// overloaded reports whether a response status means the cluster is struggling rather
+// than that the request itself was wrong
+func overloaded(status int) bool {
+	switch status {
+	case http.StatusTooManyRequests, http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		return true
+	}
+	return false
+}
@@ -0,0 +1,263 @@
+package shared
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// stubTransport answers every request with status, or fails with err, without a network
+type stubTransport struct {
+	status int
+	err    error
+	calls  int
+}
+
+func (s *stubTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	s.calls++
+	if err := req.Context().Err(); err != nil {
+		return nil, err
+	}
+	if s.err != nil {
+		return nil, s.err
+	}
+	return &http.Response{StatusCode: s.status, Body: http.NoBody, Request: req}, nil
+}
+
+// newTestBreaker returns a breaker whose clock only moves when advance is called
+func newTestBreaker(threshold int, openTimeout time.Duration) (*CircuitBreaker, func(time.Duration)) {
+	breaker := NewCircuitBreaker(BreakerConfig{FailureThreshold: threshold, OpenTimeout: openTimeout})
+
+	now := time.Unix(1700000000, 0)
+	breaker.now = func() time.Time { return now }
+	return breaker, func(d time.Duration) { now = now.Add(d) }
+}
+
+func sendThrough(t *testing.T, transport http.RoundTripper, ctx context.Context) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, "http://localhost:9200/_search", nil)
+	if err != nil {
+		t.Fatalf("Failed to build request: %v", err)
+	}
+	_, err = transport.RoundTrip(req)
+	return err
+}
+
+func TestNewCircuitBreaker(t *testing.T) {
+	breaker := NewCircuitBreaker(BreakerConfig{FailureThreshold: 3})
+	if breaker.State() != BreakerClosed || breaker.openTimeout != defaultBreakerOpenTimeout {
+		t.Errorf("Expected a closed breaker with the default timeout, got %s with %v", breaker.State(), breaker.openTimeout)
+	}
+}
+
+func TestCircuitBreaker_OpensAtThreshold(t *testing.T) {
+	breaker, _ := newTestBreaker(3, time.Minute)
+	base := &stubTransport{status: http.StatusServiceUnavailable}
+	transport := newBreakerTransport(base, breaker)
+
+	for i := 0; i < 2; i++ {
+		sendThrough(t, transport, context.Background())
+	}
+	if breaker.State() != BreakerClosed {
+		t.Fatalf("Expected the breaker to stay closed below the threshold, got %s", breaker.State())
+	}
+
+	// A success resets the count of consecutive failures
+	base.status = http.StatusOK
+	sendThrough(t, transport, context.Background())
+	base.status = http.StatusServiceUnavailable
+	for i := 0; i < 2; i++ {
+		sendThrough(t, transport, context.Background())
+	}
+	if breaker.State() != BreakerClosed {
+		t.Fatalf("Expected failures before a success not to count, got %s", breaker.State())
+	}
+
+	sendThrough(t, transport, context.Background())
+	if breaker.State() != BreakerOpen {
+		t.Errorf("Expected the breaker to open at the third consecutive failure, got %s", breaker.State())
+	}
+}
+
+func TestCircuitBreaker_FailureStatuses(t *testing.T) {
+	testCases := []struct {
+		name     string
+		status   int
+		err      error
+		expected BreakerState
+	}{
+		{name: "too many requests", status: http.StatusTooManyRequests, expected: BreakerOpen},
+		{name: "bad gateway", status: http.StatusBadGateway, expected: BreakerOpen},
+		{name: "service unavailable", status: http.StatusServiceUnavailable, expected: BreakerOpen},
+		{name: "gateway timeout", status: http.StatusGatewayTimeout, expected: BreakerOpen},
+		{name: "connection error", err: errors.New("connection refused"), expected: BreakerOpen},
+		{name: "bad request", status: http.StatusBadRequest, expected: BreakerClosed},
+		{name: "not found", status: http.StatusNotFound, expected: BreakerClosed},
+		{name: "internal error", status: http.StatusInternalServerError, expected: BreakerClosed},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			breaker, _ := newTestBreaker(1, time.Minute)
+			sendThrough(t, newBreakerTransport(&stubTransport{status: tc.status, err: tc.err}, breaker), context.Background())
+
+			if breaker.State() != tc.expected {
+				t.Errorf("Expected the breaker to be %s, got %s", tc.expected, breaker.State())
+			}
+		})
+	}
+}
+
+func TestCircuitBreaker_RejectsWhileOpen(t *testing.T) {
+	breaker, advance := newTestBreaker(1, time.Minute)
+	base := &stubTransport{status: http.StatusServiceUnavailable}
+	transport := newBreakerTransport(base, breaker)
+
+	sendThrough(t, transport, context.Background())
+	for i := 0; i < 3; i++ {
+		if err := sendThrough(t, transport, context.Background()); !errors.Is(err, ErrCircuitOpen) {
+			t.Errorf("Expected ErrCircuitOpen, got %v", err)
+		}
+	}
+
+	advance(time.Minute - time.Second)
+	if err := sendThrough(t, transport, context.Background()); !errors.Is(err, ErrCircuitOpen) {
+		t.Errorf("Expected ErrCircuitOpen until the open timeout passed, got %v", err)
+	}
+	if base.calls != 1 {
+		t.Errorf("Expected only the first request to reach the cluster, got %d", base.calls)
+	}
+}
+
+func TestCircuitBreaker_HalfOpenProbe(t *testing.T) {
+	testCases := []struct {
+		name          string
+		probeStatus   int
+		expectedState BreakerState
+	}{
+		{name: "probe success closes", probeStatus: http.StatusOK, expectedState: BreakerClosed},
+		{name: "probe failure reopens", probeStatus: http.StatusServiceUnavailable, expectedState: BreakerOpen},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			breaker, advance := newTestBreaker(1, time.Minute)
+			breaker.record(false)
+			advance(time.Minute)
+
+			// Exactly one request is let through as the probe
+			if !breaker.allow() {
+				t.Fatalf("Expected the probe to be allowed after the open timeout")
+			}
+			if breaker.State() != BreakerHalfOpen {
+				t.Errorf("Expected the breaker to be half-open during the probe, got %s", breaker.State())
+			}
+			if breaker.allow() {
+				t.Errorf("Expected a second request to be rejected while the probe is in flight")
+			}
+
+			base := &stubTransport{status: tc.probeStatus}
+			breaker, advance = newTestBreaker(1, time.Minute)
+			transport := newBreakerTransport(base, breaker)
+			breaker.record(false)
+			advance(time.Minute)
+
+			sendThrough(t, transport, context.Background())
+			if breaker.State() != tc.expectedState {
+				t.Errorf("Expected the breaker to be %s after the probe, got %s", tc.expectedState, breaker.State())
+			}
+
+			// A reopened breaker waits a full timeout again
+			err := sendThrough(t, transport, context.Background())
+			if tc.expectedState == BreakerOpen && !errors.Is(err, ErrCircuitOpen) {
+				t.Errorf("Expected ErrCircuitOpen right after a failed probe, got %v", err)
+			}
+			if tc.expectedState == BreakerClosed && err != nil {
+				t.Errorf("Expected requests to flow after a successful probe, got %v", err)
+			}
+		})
+	}
+}
+
+func TestCircuitBreaker_CanceledContext(t *testing.T) {
+	breaker, advance := newTestBreaker(1, time.Minute)
+	transport := newBreakerTransport(&stubTransport{status: http.StatusOK}, breaker)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	// A caller giving up says nothing about the cluster
+	if err := sendThrough(t, transport, ctx); !errors.Is(err, context.Canceled) {
+		t.Errorf("Expected context.Canceled, got %v", err)
+	}
+	if breaker.State() != BreakerClosed {
+		t.Errorf("Expected a canceled request not to count as a failure, got %s", breaker.State())
+	}
+
+	// A canceled probe releases the half-open slot by waiting another timeout
+	breaker.record(false)
+	advance(time.Minute)
+	sendThrough(t, transport, ctx)
+	if breaker.State() != BreakerOpen {
+		t.Errorf("Expected a canceled probe to reopen the breaker, got %s", breaker.State())
+	}
+	advance(time.Minute)
+	if err := sendThrough(t, transport, context.Background()); err != nil || breaker.State() != BreakerClosed {
+		t.Errorf("Expected the next probe to close the breaker, got %s (%v)", breaker.State(), err)
+	}
+}
+
+func TestESClient_CircuitBreaker(t *testing.T) {
+	var status, requests atomic.Int32
+	status.Store(http.StatusOK)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("X-Elastic-Product", "Elasticsearch")
+		w.Header().Set("Content-Type", "application/json")
+		if r.URL.Path == "/_search" {
+			requests.Add(1)
+			w.WriteHeader(int(status.Load()))
+		}
+		w.Write([]byte(`{}`))
+	}))
+	defer server.Close()
+
+	client, err := NewESClient(&ESConfig{
+		URLs:           []string{server.URL},
+		CircuitBreaker: BreakerConfig{FailureThreshold: 2, OpenTimeout: time.Minute},
+	}, zap.NewNop())
+	if err != nil {
+		t.Fatalf("Failed to create client: %v", err)
+	}
+
+	search := func() error {
+		res, err := client.Search(client.Search.WithContext(context.Background()))
+		if err == nil {
+			res.Body.Close()
+		}
+		return err
+	}
+
+	if err := search(); err != nil || client.BreakerState() != BreakerClosed {
+		t.Fatalf("Expected a healthy cluster to keep the breaker closed, got %s (%v)", client.BreakerState(), err)
+	}
+
+	// The client's own retries of a 503 count as consecutive failures
+	status.Store(http.StatusServiceUnavailable)
+	search()
+	if client.BreakerState() != BreakerOpen {
+		t.Fatalf("Expected repeated 503s to open the breaker, got %s", client.BreakerState())
+	}
+
+	requests.Store(0)
+	if err := search(); !errors.Is(err, ErrCircuitOpen) {
+		t.Errorf("Expected ErrCircuitOpen, got %v", err)
+	}
+	if n := requests.Load(); n != 0 {
+		t.Errorf("Expected no request to reach the cluster while the breaker is open, got %d", n)
+	}
+}
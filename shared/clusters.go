@@ -0,0 +1,145 @@
+package shared
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sort"
+	"sync"
+
+	"github.com/elastic/go-elasticsearch/v8"
+	"go.uber.org/zap"
+)
+
+// DefaultClusterName is the name of the cluster configured in the main elasticsearch section
+const DefaultClusterName = "default"
+
+type clusterKey struct{}
+
+// WithCluster selects the named cluster for Elasticsearch calls made with ctx
+func WithCluster(ctx context.Context, name string) context.Context {
+	return context.WithValue(ctx, clusterKey{}, name)
+}
+
+// ClusterFromContext returns the cluster selected for ctx, if any
+func ClusterFromContext(ctx context.Context) (string, bool) {
+	name, ok := ctx.Value(clusterKey{}).(string)
+	return name, ok && name != ""
+}
+
+// ClusterRegistry holds a separate client for each named Elasticsearch cluster
+type ClusterRegistry struct {
+	mu          sync.RWMutex
+	clients     map[string]*ESClient
+	defaultName string
+	logger      *zap.Logger
+}
+
+// NewClusterRegistry creates a registry whose default cluster is served by defaultClient
+func NewClusterRegistry(defaultName string, defaultClient *ESClient, logger *zap.Logger) *ClusterRegistry {
+	if logger == nil {
+		logger = zap.NewNop()
+	}
+
+	return &ClusterRegistry{
+		clients:     map[string]*ESClient{defaultName: defaultClient},
+		defaultName: defaultName,
+		logger:      logger,
+	}
+}
+
+// Register adds or replaces a named cluster
+func (r *ClusterRegistry) Register(name string, client *ESClient) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.clients[name] = client
+	r.logger.Info("Registered Elasticsearch cluster", zap.String("cluster", name))
+}
+
+// Client returns the client of a named cluster
+func (r *ClusterRegistry) Client(name string) (*ESClient, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	client, ok := r.clients[name]
+	return client, ok
+}
+
+// DefaultName returns the name of the cluster used when a request selects none
+func (r *ClusterRegistry) DefaultName() string {
+	return r.defaultName
+}
+
+// Names returns the registered cluster names in sorted order
+func (r *ClusterRegistry) Names() []string {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	names := make([]string, 0, len(r.clients))
+	for name := range r.clients {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// BreakerStates returns the circuit breaker state of each registered cluster
+func (r *ClusterRegistry) BreakerStates() map[string]BreakerState {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	states := make(map[string]BreakerState, len(r.clients))
+	for name, client := range r.clients {
+		states[name] = client.BreakerState()
+	}
+	return states
+}
+
+// NewRoutingClient returns a client that sends each request to the cluster selected in
+// the request's context (see WithCluster), falling back to the default cluster. Services
+// can hold this single client and still serve every registered cluster.
+func (r *ClusterRegistry) NewRoutingClient() (*ESClient, error) {
+	defaultClient, ok := r.Client(r.defaultName)
+	if !ok {
+		return nil, fmt.Errorf("default cluster %s is not registered", r.defaultName)
+	}
+
+	client, err := elasticsearch.NewClient(elasticsearch.Config{
+		Addresses: defaultClient.config.URLs,
+		Transport: &clusterRoutingTransport{registry: r},
+		// The selected cluster's client already retries; retrying here as well would
+		// multiply the attempts and count each one against that cluster's breaker
+		DisableRetry: true,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create routing client: %w", err)
+	}
+
+	return &ESClient{
+		Client: client,
+		logger: r.logger,
+		config: defaultClient.config,
+	}, nil
+}
+
+// clusterRoutingTransport forwards requests to the client of the selected cluster,
+// which applies that cluster's addresses, credentials and transport settings
+type clusterRoutingTransport struct {
+	registry *ClusterRegistry
+}
+
+// RoundTrip implements http.RoundTripper
+func (t *clusterRoutingTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	name, ok := ClusterFromContext(req.Context())
+	if !ok {
+		name = t.registry.defaultName
+	}
+
+	client, ok := t.registry.Client(name)
+	if !ok {
+		return nil, fmt.Errorf("unknown Elasticsearch cluster: %s", name)
+	}
+
+	return client.Perform(req)
+}
@@ -0,0 +1,187 @@
+package shared
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"go.uber.org/zap"
+)
+
+// fakeCluster is a fake Elasticsearch that answers every request with its name, or with
+// status once it is set, and counts the requests other than the client's startup ping
+type fakeCluster struct {
+	client   *ESClient
+	requests atomic.Int32
+	status   atomic.Int32
+}
+
+func newFakeCluster(t *testing.T, name string) *fakeCluster {
+	cluster := &fakeCluster{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("X-Elastic-Product", "Elasticsearch")
+		w.Header().Set("Content-Type", "application/json")
+		if r.Method == http.MethodHead {
+			return
+		}
+		cluster.requests.Add(1)
+		if status := cluster.status.Load(); status != 0 {
+			w.WriteHeader(int(status))
+		}
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"cluster_name": name,
+			"version":      map[string]string{"number": "8.11.1"},
+		})
+	}))
+	t.Cleanup(server.Close)
+
+	client, err := NewESClient(&ESConfig{URLs: []string{server.URL}}, zap.NewNop())
+	if err != nil {
+		t.Fatalf("Failed to create client: %v", err)
+	}
+	cluster.client = client
+	return cluster
+}
+
+// clusterName asks client which cluster answered
+func clusterName(ctx context.Context, client *ESClient) (string, error) {
+	res, err := client.Info(client.Info.WithContext(ctx))
+	if err != nil {
+		return "", err
+	}
+	defer res.Body.Close()
+
+	var info struct {
+		ClusterName string `json:"cluster_name"`
+	}
+	if err := json.NewDecoder(res.Body).Decode(&info); err != nil {
+		return "", err
+	}
+	return info.ClusterName, nil
+}
+
+func TestClusterRegistry(t *testing.T) {
+	primary := newFakeCluster(t, "primary")
+	registry := NewClusterRegistry(DefaultClusterName, primary.client, nil)
+
+	if registry.DefaultName() != DefaultClusterName {
+		t.Errorf("Expected default cluster %s, got %s", DefaultClusterName, registry.DefaultName())
+	}
+	if client, ok := registry.Client(DefaultClusterName); !ok || client != primary.client {
+		t.Errorf("Expected the default client to be registered")
+	}
+	if _, ok := registry.Client("eu"); ok {
+		t.Errorf("Expected an unknown cluster to be missing")
+	}
+
+	eu, us := newFakeCluster(t, "eu"), newFakeCluster(t, "us")
+	registry.Register("us", us.client)
+	registry.Register("eu", us.client)
+	registry.Register("eu", eu.client)
+
+	if names := registry.Names(); !reflect.DeepEqual(names, []string{"default", "eu", "us"}) {
+		t.Errorf("Expected sorted cluster names, got %v", names)
+	}
+	if client, _ := registry.Client("eu"); client != eu.client {
+		t.Errorf("Expected registering a name again to replace its client")
+	}
+
+	states := registry.BreakerStates()
+	if len(states) != 3 || states["eu"] != BreakerClosed {
+		t.Errorf("Expected a closed breaker state for each cluster, got %v", states)
+	}
+}
+
+func TestClusterFromContext(t *testing.T) {
+	if _, ok := ClusterFromContext(context.Background()); ok {
+		t.Errorf("Expected no cluster without a selection")
+	}
+	if _, ok := ClusterFromContext(WithCluster(context.Background(), "")); ok {
+		t.Errorf("Expected an empty selection to count as none")
+	}
+	if name, ok := ClusterFromContext(WithCluster(context.Background(), "eu")); !ok || name != "eu" {
+		t.Errorf("Expected eu to be selected, got %q", name)
+	}
+}
+
+func TestRoutingClient(t *testing.T) {
+	primary, eu := newFakeCluster(t, "primary"), newFakeCluster(t, "eu")
+	registry := NewClusterRegistry(DefaultClusterName, primary.client, zap.NewNop())
+	registry.Register("eu", eu.client)
+
+	client, err := registry.NewRoutingClient()
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	testCases := []struct {
+		name        string
+		ctx         context.Context
+		expected    string
+		expectError string
+	}{
+		{name: "no selection falls back to the default", ctx: context.Background(), expected: "primary"},
+		{name: "default selected by name", ctx: WithCluster(context.Background(), DefaultClusterName), expected: "primary"},
+		{name: "selected cluster", ctx: WithCluster(context.Background(), "eu"), expected: "eu"},
+		{name: "unknown cluster", ctx: WithCluster(context.Background(), "us"), expectError: "unknown Elasticsearch cluster: us"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			name, err := clusterName(tc.ctx, client)
+			if tc.expectError != "" {
+				if err == nil || !strings.Contains(err.Error(), tc.expectError) {
+					t.Errorf("Expected error %q, got %v", tc.expectError, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if name != tc.expected {
+				t.Errorf("Expected the request to reach %s, got %s", tc.expected, name)
+			}
+		})
+	}
+
+	missing := NewClusterRegistry("primary", nil, nil)
+	missing.clients = map[string]*ESClient{}
+	if _, err := missing.NewRoutingClient(); err == nil {
+		t.Errorf("Expected a routing client without a default cluster to be rejected")
+	}
+}
+
+func TestRoutingClient_LeavesRetriesToTheCluster(t *testing.T) {
+	primary := newFakeCluster(t, "primary")
+	registry := NewClusterRegistry(DefaultClusterName, primary.client, zap.NewNop())
+	client, err := registry.NewRoutingClient()
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	// Both clients answer the product check first; only count the failing calls after it
+	if _, err := clusterName(context.Background(), client); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	primary.status.Store(http.StatusBadGateway)
+
+	primary.requests.Store(0)
+	clusterName(context.Background(), primary.client)
+	direct := primary.requests.Load()
+
+	primary.requests.Store(0)
+	clusterName(context.Background(), client)
+	routed := primary.requests.Load()
+
+	if direct < 2 {
+		t.Fatalf("Expected the cluster's own client to retry a 502, got %d attempts", direct)
+	}
+	if routed != direct {
+		t.Errorf("Expected the routing client to leave retries to the cluster's client (%d attempts), got %d", direct, routed)
+	}
+}
@@ -3,8 +3,11 @@ package shared
 import (
 	"context"
 	"crypto/tls"
+	"crypto/x509"
+	"errors"
 	"fmt"
 	"net/http"
+	"os"
 	"time"
 
 	"github.com/elastic/go-elasticsearch/v8"
@@ -18,18 +21,27 @@ type ESConfig struct {
 	Password  string   `yaml:"password"`
 	APIKey    string   `yaml:"api_key"`
 	TLSConfig *TLSConfig `yaml:"tls"`
+	// RequestTimeout bounds each individual HTTP request to Elasticsearch (0 disables)
+	RequestTimeout time.Duration `yaml:"request_timeout"`
+	// CircuitBreaker fails requests fast while the cluster keeps failing
+	CircuitBreaker BreakerConfig `yaml:"circuit_breaker"`
 }
 
-// TLSConfig holds TLS configuration
+// TLSConfig holds TLS configuration. ClientCertPath and ClientKeyPath enable mutual TLS
+// and must be set together.
 type TLSConfig struct {
-	InsecureSkipVerify bool `yaml:"insecure_skip_verify"`
+	InsecureSkipVerify bool   `yaml:"insecure_skip_verify"`
+	CACertPath         string `yaml:"ca_cert_path"`     // PEM CA bundle trusted instead of the system roots
+	ClientCertPath     string `yaml:"client_cert_path"` // PEM client certificate
+	ClientKeyPath      string `yaml:"client_key_path"`  // PEM private key of the client certificate
 }
 
 // ESClient wraps the Elasticsearch client with additional functionality
 type ESClient struct {
 	*elasticsearch.Client
-	logger *zap.Logger
-	config *ESConfig
+	logger  *zap.Logger
+	config  *ESConfig
+	breaker *CircuitBreaker
 }
 
 // NewESClient creates a new Elasticsearch client with the given configuration
@@ -47,10 +59,27 @@ func NewESClient(config *ESConfig, logger *zap.Logger) (*ESClient, error) {
 
 	// Configure TLS if specified
 	if config.TLSConfig != nil {
+		tlsConfig, err := config.TLSConfig.load()
+		if err != nil {
+			return nil, fmt.Errorf("invalid TLS configuration: %w", err)
+		}
 		esConfig.Transport = &http.Transport{
-			TLSClientConfig: &tls.Config{
-				InsecureSkipVerify: config.TLSConfig.InsecureSkipVerify,
-			},
+			TLSClientConfig: tlsConfig,
+		}
+	}
+
+	// Bound individual requests so one stuck connection can't eat the operation timeout
+	if config.RequestTimeout > 0 {
+		esConfig.Transport = newTimeoutTransport(esConfig.Transport, config.RequestTimeout)
+	}
+
+	// Stop hammering a failing cluster; retrying a rejected request would only spin
+	var breaker *CircuitBreaker
+	if config.CircuitBreaker.FailureThreshold > 0 {
+		breaker = NewCircuitBreaker(config.CircuitBreaker)
+		esConfig.Transport = newBreakerTransport(esConfig.Transport, breaker)
+		esConfig.RetryOnError = func(_ *http.Request, err error) bool {
+			return !errors.Is(err, ErrCircuitOpen)
 		}
 	}
 
@@ -60,9 +89,10 @@ func NewESClient(config *ESConfig, logger *zap.Logger) (*ESClient, error) {
 	}
 
 	esClient := &ESClient{
-		Client: client,
-		logger: logger,
-		config: config,
+		Client:  client,
+		logger:  logger,
+		config:  config,
+		breaker: breaker,
 	}
 
 	// Test connection
@@ -76,6 +106,49 @@ func NewESClient(config *ESConfig, logger *zap.Logger) (*ESClient, error) {
 	return esClient, nil
 }
 
+// load builds the client TLS configuration, reading the CA bundle and client certificate.
+// A file that is configured but missing or unparseable is an error, so a secured cluster
+// is never reached over a weaker transport than intended.
+func (c *TLSConfig) load() (*tls.Config, error) {
+	tlsConfig := &tls.Config{
+		InsecureSkipVerify: c.InsecureSkipVerify,
+	}
+
+	if c.CACertPath != "" {
+		caCert, err := os.ReadFile(c.CACertPath)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read CA certificate: %w", err)
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(caCert) {
+			return nil, fmt.Errorf("no PEM certificates found in CA certificate %s", c.CACertPath)
+		}
+		tlsConfig.RootCAs = pool
+	}
+
+	if (c.ClientCertPath == "") != (c.ClientKeyPath == "") {
+		return nil, fmt.Errorf("client_cert_path and client_key_path must be set together")
+	}
+	if c.ClientCertPath != "" {
+		cert, err := tls.LoadX509KeyPair(c.ClientCertPath, c.ClientKeyPath)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load client certificate %s: %w", c.ClientCertPath, err)
+		}
+		tlsConfig.Certificates = []tls.Certificate{cert}
+	}
+
+	return tlsConfig, nil
+}
+
+// BreakerState returns the state of the client's circuit breaker; a client without one
+// is always closed
+func (c *ESClient) BreakerState() BreakerState {
+	if c.breaker == nil {
+		return BreakerClosed
+	}
+	return c.breaker.State()
+}
+
 // Ping tests the connection to Elasticsearch
 func (c *ESClient) Ping(ctx context.Context) error {
 	res, err := c.Client.Ping(
@@ -124,6 +197,9 @@ func (c *ESClient) WaitForCluster(ctx context.Context, status string, timeout ti
 	ctx, cancel := context.WithTimeout(ctx, timeout)
 	defer cancel()
 
+	// Health with wait_for_status long-polls, so the per-request timeout must not apply
+	ctx = WithRequestTimeout(ctx, 0)
+
 	res, err := c.Client.Cluster.Health(
 		c.Client.Cluster.Health.WithContext(ctx),
 		c.Client.Cluster.Health.WithWaitForStatus(status),
@@ -165,5 +241,6 @@ func DefaultESConfig() *ESConfig {
 		TLSConfig: &TLSConfig{
 			InsecureSkipVerify: false,
 		},
+		RequestTimeout: 30 * time.Second,
 	}
 }
\ No newline at end of file
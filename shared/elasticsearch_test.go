@@ -0,0 +1,200 @@
+package shared
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"io"
+	"log"
+	"math/big"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// testCA issues certificates for the TLS tests
+type testCA struct {
+	cert *x509.Certificate
+	key  *ecdsa.PrivateKey
+	pem  []byte
+}
+
+func newTestCA(t *testing.T, name string) *testCA {
+	t.Helper()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("Failed to generate key: %v", err)
+	}
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: name},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		KeyUsage:              x509.KeyUsageCertSign,
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatalf("Failed to create CA certificate: %v", err)
+	}
+	cert, _ := x509.ParseCertificate(der)
+	return &testCA{cert: cert, key: key, pem: pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})}
+}
+
+// issue returns a PEM certificate and key signed by the CA, for a server on 127.0.0.1 or a
+// client
+func (ca *testCA) issue(t *testing.T, name string, usage x509.ExtKeyUsage) (certPEM, keyPEM []byte) {
+	t.Helper()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("Failed to generate key: %v", err)
+	}
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(time.Now().UnixNano()),
+		Subject:      pkix.Name{CommonName: name},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{usage},
+		IPAddresses:  []net.IP{net.ParseIP("127.0.0.1")},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, ca.cert, &key.PublicKey, ca.key)
+	if err != nil {
+		t.Fatalf("Failed to create certificate: %v", err)
+	}
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatalf("Failed to marshal key: %v", err)
+	}
+	return pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER})
+}
+
+// writeTestFile writes data to a file in dir and returns its path
+func writeTestFile(t *testing.T, dir, name string, data []byte) string {
+	t.Helper()
+	path := filepath.Join(dir, name)
+	if err := os.WriteFile(path, data, 0o600); err != nil {
+		t.Fatalf("Failed to write %s: %v", name, err)
+	}
+	return path
+}
+
+func TestNewESClient_TLS(t *testing.T) {
+	ca := newTestCA(t, "playground-ca")
+	otherCA := newTestCA(t, "other-ca")
+
+	// A fake Elasticsearch that requires a client certificate issued by the CA
+	var requests atomic.Int32
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+		w.Header().Set("X-Elastic-Product", "Elasticsearch")
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"version":{"number":"8.11.1"}}`))
+	}))
+	serverCert, serverKey := ca.issue(t, "es-node-1", x509.ExtKeyUsageServerAuth)
+	certificate, err := tls.X509KeyPair(serverCert, serverKey)
+	if err != nil {
+		t.Fatalf("Failed to load server certificate: %v", err)
+	}
+	clientCAs := x509.NewCertPool()
+	clientCAs.AddCert(ca.cert)
+	server.TLS = &tls.Config{
+		Certificates: []tls.Certificate{certificate},
+		ClientAuth:   tls.RequireAndVerifyClientCert,
+		ClientCAs:    clientCAs,
+	}
+	server.Config.ErrorLog = log.New(io.Discard, "", 0) // rejected handshakes are expected
+	server.StartTLS()
+	t.Cleanup(server.Close)
+
+	dir := t.TempDir()
+	caPath := writeTestFile(t, dir, "ca.pem", ca.pem)
+	otherCAPath := writeTestFile(t, dir, "other-ca.pem", otherCA.pem)
+	clientCert, clientKey := ca.issue(t, "search-api", x509.ExtKeyUsageClientAuth)
+	clientCertPath := writeTestFile(t, dir, "client.pem", clientCert)
+	clientKeyPath := writeTestFile(t, dir, "client-key.pem", clientKey)
+	notPEMPath := writeTestFile(t, dir, "not-a-cert.pem", []byte("not a certificate"))
+
+	testCases := []struct {
+		name          string
+		tls           TLSConfig
+		expectedError string // empty when the client connects
+		expectRequest bool   // whether the request reaches Elasticsearch
+	}{
+		{
+			name:          "mutual TLS",
+			tls:           TLSConfig{CACertPath: caPath, ClientCertPath: clientCertPath, ClientKeyPath: clientKeyPath},
+			expectRequest: true,
+		},
+		{
+			name:          "no client certificate",
+			tls:           TLSConfig{CACertPath: caPath},
+			expectedError: "failed to ping Elasticsearch",
+		},
+		{
+			name:          "server not signed by the CA",
+			tls:           TLSConfig{CACertPath: otherCAPath, ClientCertPath: clientCertPath, ClientKeyPath: clientKeyPath},
+			expectedError: "certificate signed by unknown authority",
+		},
+		{
+			name:          "system roots only",
+			tls:           TLSConfig{ClientCertPath: clientCertPath, ClientKeyPath: clientKeyPath},
+			expectedError: "failed to ping Elasticsearch",
+		},
+		{
+			name:          "certificate without key",
+			tls:           TLSConfig{CACertPath: caPath, ClientCertPath: clientCertPath},
+			expectedError: "must be set together",
+		},
+		{
+			name:          "missing CA file",
+			tls:           TLSConfig{CACertPath: filepath.Join(dir, "missing.pem")},
+			expectedError: "failed to read CA certificate",
+		},
+		{
+			name:          "CA file without certificates",
+			tls:           TLSConfig{CACertPath: notPEMPath},
+			expectedError: "no PEM certificates found",
+		},
+		{
+			name:          "unparseable client certificate",
+			tls:           TLSConfig{CACertPath: caPath, ClientCertPath: notPEMPath, ClientKeyPath: clientKeyPath},
+			expectedError: "failed to load client certificate",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			requests.Store(0)
+			tlsConfig := tc.tls
+
+			client, err := NewESClient(&ESConfig{URLs: []string{server.URL}, TLSConfig: &tlsConfig}, zap.NewNop())
+			if tc.expectedError == "" {
+				if err != nil || client == nil {
+					t.Fatalf("Expected the client to connect, got %v", err)
+				}
+			} else if err == nil || !strings.Contains(err.Error(), tc.expectedError) {
+				t.Fatalf("Expected an error containing %q, got %v", tc.expectedError, err)
+			}
+
+			if (requests.Load() > 0) != tc.expectRequest {
+				t.Errorf("Expected a request to reach Elasticsearch %v, got %d requests", tc.expectRequest, requests.Load())
+			}
+		})
+	}
+}
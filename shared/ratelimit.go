@@ -0,0 +1,167 @@
+package shared
+
+import (
+	"fmt"
+	"math"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+// How clients are told apart by a rate limiter
+const (
+	RateLimitByIP     = "ip"
+	RateLimitByAPIKey = "api_key"
+)
+
+// Idle clients' buckets are dropped at most this often
+const rateLimitSweepInterval = time.Minute
+
+// RateLimitConfig configures a token bucket rate limit per client
+type RateLimitConfig struct {
+	// Sustained requests per second allowed per client; 0 disables the limit
+	RequestsPerSecond float64 `yaml:"requests_per_second"`
+	// Requests a client may send at once above the sustained rate, defaults to one
+	// second's worth
+	Burst int `yaml:"burst"`
+	// How clients are told apart: ip (default) or api_key, which counts requests against
+	// the key RequireAPIKey verified and falls back to the IP for requests it didn't
+	// verify (safe methods, or no keys configured)
+	Key string `yaml:"key"`
+}
+
+// RateLimiter keeps a token bucket per client. Each bucket holds up to Burst tokens and
+// refills at RequestsPerSecond; a request takes one token or is rejected.
+type RateLimiter struct {
+	rate  float64
+	burst float64
+	key   string
+
+	mu        sync.Mutex
+	buckets   map[string]*tokenBucket
+	lastSweep time.Time
+	now       func() time.Time
+}
+
+type tokenBucket struct {
+	tokens  float64
+	updated time.Time
+}
+
+// NewRateLimiter creates a rate limiter, or returns nil when config disables it
+func NewRateLimiter(config RateLimitConfig) (*RateLimiter, error) {
+	if config.RequestsPerSecond <= 0 {
+		return nil, nil
+	}
+
+	switch config.Key {
+	case "":
+		config.Key = RateLimitByIP
+	case RateLimitByIP, RateLimitByAPIKey:
+	default:
+		return nil, fmt.Errorf("invalid rate limit key %q (must be %s or %s)", config.Key, RateLimitByIP, RateLimitByAPIKey)
+	}
+
+	burst := float64(config.Burst)
+	if burst < 1 {
+		burst = math.Max(1, math.Ceil(config.RequestsPerSecond))
+	}
+
+	return &RateLimiter{
+		rate:    config.RequestsPerSecond,
+		burst:   burst,
+		key:     config.Key,
+		buckets: make(map[string]*tokenBucket),
+		now:     time.Now,
+	}, nil
+}
+
+// allow takes a token from the client's bucket. When the bucket is empty it reports false
+// and how long until a token is available.
+func (l *RateLimiter) allow(client string) (bool, time.Duration) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := l.now()
+	l.sweep(now)
+
+	bucket, ok := l.buckets[client]
+	if !ok {
+		bucket = &tokenBucket{tokens: l.burst, updated: now}
+		l.buckets[client] = bucket
+	}
+
+	bucket.tokens = math.Min(l.burst, bucket.tokens+now.Sub(bucket.updated).Seconds()*l.rate)
+	bucket.updated = now
+
+	if bucket.tokens >= 1 {
+		bucket.tokens--
+		return true, 0
+	}
+	wait := time.Duration((1 - bucket.tokens) / l.rate * float64(time.Second))
+	return false, wait
+}
+
+// sweep drops buckets that have refilled completely, which are the same as new ones
+func (l *RateLimiter) sweep(now time.Time) {
+	if now.Sub(l.lastSweep) < rateLimitSweepInterval {
+		return
+	}
+	l.lastSweep = now
+
+	for client, bucket := range l.buckets {
+		if bucket.tokens+now.Sub(bucket.updated).Seconds()*l.rate >= l.burst {
+			delete(l.buckets, client)
+		}
+	}
+}
+
+// clientKey identifies the client a request is counted against. Only keys verified by
+// RequireAPIKey are used: an unverified credential is whatever the client sent, so keying
+// on it would let a client dodge its limit by sending a new one with every request.
+func (l *RateLimiter) clientKey(c *gin.Context) string {
+	if l.key == RateLimitByAPIKey {
+		if key := c.GetString(verifiedAPIKeyContextKey); key != "" {
+			return "key:" + key
+		}
+	}
+	return "ip:" + c.ClientIP()
+}
+
+// Middleware returns middleware rejecting requests over the limit with 429 Too Many
+// Requests and a Retry-After header. A nil limiter lets every request through, so
+// routes can be wired the same way whether or not a limit is configured.
+func (l *RateLimiter) Middleware(logger *zap.Logger) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if l == nil {
+			c.Next()
+			return
+		}
+
+		allowed, wait := l.allow(l.clientKey(c))
+		if allowed {
+			c.Next()
+			return
+		}
+
+		retryAfter := int(math.Ceil(wait.Seconds()))
+		logger.Warn("Rate limit exceeded",
+			zap.String("method", c.Request.Method),
+			zap.String("path", c.FullPath()),
+			zap.String("client_ip", c.ClientIP()),
+			zap.Int("retry_after_seconds", retryAfter))
+
+		c.Header("Retry-After", strconv.Itoa(retryAfter))
+		c.AbortWithStatusJSON(http.StatusTooManyRequests, gin.H{
+			"error":       "Too many requests",
+			"message":     fmt.Sprintf("Rate limit of %g requests per second exceeded, retry in %ds", l.rate, retryAfter),
+			"retry_after": retryAfter,
+			"request_id":  c.GetString("request_id"),
+			"timestamp":   time.Now(),
+		})
+	}
+}
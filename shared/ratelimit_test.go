@@ -0,0 +1,232 @@
+package shared
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+// newTestRateLimiter returns a limiter whose clock only moves when advance is called
+func newTestRateLimiter(t *testing.T, config RateLimitConfig) (*RateLimiter, func(time.Duration)) {
+	limiter, err := NewRateLimiter(config)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	now := time.Unix(1700000000, 0)
+	limiter.now = func() time.Time { return now }
+	limiter.lastSweep = now
+	return limiter, func(d time.Duration) { now = now.Add(d) }
+}
+
+func TestNewRateLimiter(t *testing.T) {
+	testCases := []struct {
+		name          string
+		config        RateLimitConfig
+		expectNil     bool
+		expectError   bool
+		expectedBurst float64
+		expectedKey   string
+	}{
+		{name: "disabled", config: RateLimitConfig{}, expectNil: true},
+		{name: "defaults", config: RateLimitConfig{RequestsPerSecond: 2.5}, expectedBurst: 3, expectedKey: RateLimitByIP},
+		{name: "slow rate", config: RateLimitConfig{RequestsPerSecond: 0.1}, expectedBurst: 1, expectedKey: RateLimitByIP},
+		{name: "explicit burst", config: RateLimitConfig{RequestsPerSecond: 1, Burst: 5, Key: RateLimitByAPIKey}, expectedBurst: 5, expectedKey: RateLimitByAPIKey},
+		{name: "unknown key", config: RateLimitConfig{RequestsPerSecond: 1, Key: "user"}, expectNil: true, expectError: true},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			limiter, err := NewRateLimiter(tc.config)
+			if (err != nil) != tc.expectError {
+				t.Fatalf("Expected error %v, got %v", tc.expectError, err)
+			}
+			if (limiter == nil) != tc.expectNil {
+				t.Fatalf("Expected nil limiter %v, got %+v", tc.expectNil, limiter)
+			}
+			if limiter == nil {
+				return
+			}
+			if limiter.burst != tc.expectedBurst || limiter.key != tc.expectedKey {
+				t.Errorf("Expected burst %g keyed by %s, got burst %g keyed by %s", tc.expectedBurst, tc.expectedKey, limiter.burst, limiter.key)
+			}
+		})
+	}
+}
+
+func TestRateLimiter_TokenBucket(t *testing.T) {
+	limiter, advance := newTestRateLimiter(t, RateLimitConfig{RequestsPerSecond: 2, Burst: 3})
+
+	// A new client gets the full burst, then has to wait for the bucket to refill
+	for i := 0; i < 3; i++ {
+		if allowed, _ := limiter.allow("a"); !allowed {
+			t.Fatalf("Expected request %d of the burst to be allowed", i+1)
+		}
+	}
+	allowed, wait := limiter.allow("a")
+	if allowed || wait != 500*time.Millisecond {
+		t.Errorf("Expected a rejection with a 500ms wait, got allowed %v with %v", allowed, wait)
+	}
+
+	// Clients have separate buckets
+	if allowed, _ := limiter.allow("b"); !allowed {
+		t.Error("Expected another client to have its own bucket")
+	}
+
+	// Tokens refill at the rate, up to the burst
+	advance(250 * time.Millisecond)
+	if allowed, wait := limiter.allow("a"); allowed || wait != 250*time.Millisecond {
+		t.Errorf("Expected a rejection with a 250ms wait after half a token, got allowed %v with %v", allowed, wait)
+	}
+	advance(250 * time.Millisecond)
+	if allowed, _ := limiter.allow("a"); !allowed {
+		t.Error("Expected a request to be allowed once a token refilled")
+	}
+
+	advance(time.Hour)
+	for i := 0; i < 3; i++ {
+		if allowed, _ := limiter.allow("a"); !allowed {
+			t.Fatalf("Expected request %d of the refilled burst to be allowed", i+1)
+		}
+	}
+	if allowed, _ := limiter.allow("a"); allowed {
+		t.Error("Expected the refilled bucket to hold no more than the burst")
+	}
+}
+
+func TestRateLimiter_Sweep(t *testing.T) {
+	limiter, advance := newTestRateLimiter(t, RateLimitConfig{RequestsPerSecond: 1, Burst: 120})
+
+	limiter.allow("idle")
+	advance(rateLimitSweepInterval)
+	for i := 0; i < 100; i++ {
+		limiter.allow("busy")
+	}
+
+	// Only the bucket that has refilled completely is dropped
+	advance(rateLimitSweepInterval)
+	limiter.allow("new")
+	if _, ok := limiter.buckets["idle"]; ok {
+		t.Error("Expected the refilled bucket to be swept")
+	}
+	if _, ok := limiter.buckets["busy"]; !ok {
+		t.Error("Expected the partially drained bucket to be kept")
+	}
+}
+
+func TestRateLimiter_ClientKey(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	testCases := []struct {
+		name        string
+		key         string
+		authKeys    []string
+		method      string
+		header      string
+		value       string
+		expectedKey string
+	}{
+		{
+			name: "ip mode ignores keys", key: RateLimitByIP, authKeys: []string{"secret"},
+			method: http.MethodPost, header: "X-API-Key", value: "secret", expectedKey: "ip:192.0.2.1",
+		},
+		{
+			name: "verified api key", key: RateLimitByAPIKey, authKeys: []string{"secret"},
+			method: http.MethodPost, header: "X-API-Key", value: "secret", expectedKey: "key:secret",
+		},
+		{
+			name: "verified bearer token", key: RateLimitByAPIKey, authKeys: []string{"secret"},
+			method: http.MethodPost, header: "Authorization", value: "Bearer secret", expectedKey: "key:secret",
+		},
+		{
+			name: "unverified key on a public read", key: RateLimitByAPIKey, authKeys: []string{"secret"},
+			method: http.MethodGet, header: "X-API-Key", value: "made-up", expectedKey: "ip:192.0.2.1",
+		},
+		{
+			name: "unverified key without auth configured", key: RateLimitByAPIKey,
+			method: http.MethodPost, header: "X-API-Key", value: "made-up", expectedKey: "ip:192.0.2.1",
+		},
+		{
+			name: "no key", key: RateLimitByAPIKey, authKeys: []string{"secret"},
+			method: http.MethodGet, expectedKey: "ip:192.0.2.1",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			limiter, _ := newTestRateLimiter(t, RateLimitConfig{RequestsPerSecond: 1, Key: tc.key})
+
+			var clientKey string
+			router := gin.New()
+			router.Use(RequireAPIKey(AuthConfig{APIKeys: tc.authKeys, PublicPaths: []string{"/bulk"}}, zap.NewNop()))
+			router.Handle(tc.method, "/bulk", func(c *gin.Context) {
+				clientKey = limiter.clientKey(c)
+			})
+
+			req := httptest.NewRequest(tc.method, "/bulk", nil)
+			req.RemoteAddr = "192.0.2.1:40000"
+			if tc.header != "" {
+				req.Header.Set(tc.header, tc.value)
+			}
+			router.ServeHTTP(httptest.NewRecorder(), req)
+
+			if clientKey != tc.expectedKey {
+				t.Errorf("Expected client key %q, got %q", tc.expectedKey, clientKey)
+			}
+		})
+	}
+}
+
+func TestRateLimiter_Middleware(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	limiter, _ := newTestRateLimiter(t, RateLimitConfig{RequestsPerSecond: 0.5, Burst: 1, Key: RateLimitByAPIKey})
+
+	router := gin.New()
+	router.Use(RequireAPIKey(AuthConfig{APIKeys: []string{"first", "second"}}, zap.NewNop()))
+	router.POST("/bulk", limiter.Middleware(zap.NewNop()), func(c *gin.Context) {
+		c.Status(http.StatusOK)
+	})
+
+	send := func(key string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/bulk", nil)
+		req.RemoteAddr = "192.0.2.1:40000"
+		req.Header.Set("X-API-Key", key)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	if w := send("first"); w.Code != http.StatusOK {
+		t.Fatalf("Expected the first request to pass, got %d", w.Code)
+	}
+	w := send("first")
+	if w.Code != http.StatusTooManyRequests || w.Header().Get("Retry-After") != "2" {
+		t.Errorf("Expected 429 with Retry-After 2, got %d with %q", w.Code, w.Header().Get("Retry-After"))
+	}
+
+	// Each verified key has its own budget, even from the same address
+	if w := send("second"); w.Code != http.StatusOK {
+		t.Errorf("Expected another verified key to pass, got %d", w.Code)
+	}
+
+	// An invalid key never reaches the limiter, so it can't open a fresh bucket
+	if w := send("made-up"); w.Code != http.StatusUnauthorized {
+		t.Errorf("Expected an invalid key to be rejected, got %d", w.Code)
+	}
+	if len(limiter.buckets) != 2 {
+		t.Errorf("Expected buckets for the two verified keys only, got %d", len(limiter.buckets))
+	}
+
+	var disabled *RateLimiter
+	router = gin.New()
+	router.POST("/bulk", disabled.Middleware(zap.NewNop()), func(c *gin.Context) {
+		c.Status(http.StatusOK)
+	})
+	if w := send("first"); w.Code != http.StatusOK {
+		t.Errorf("Expected a nil limiter to let requests through, got %d", w.Code)
+	}
+}
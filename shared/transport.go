@@ -0,0 +1,68 @@
+package shared
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"time"
+)
+
+type requestTimeoutKey struct{}
+
+// WithRequestTimeout overrides the per-request timeout for calls made with ctx.
+// A zero duration disables the timeout, e.g. for long-polling calls such as
+// cluster health with wait_for_status.
+func WithRequestTimeout(ctx context.Context, timeout time.Duration) context.Context {
+	return context.WithValue(ctx, requestTimeoutKey{}, timeout)
+}
+
+// timeoutTransport bounds every individual HTTP request sent to Elasticsearch,
+// independently of the (usually much longer) operation context. A single hung
+// connection then fails fast instead of consuming the whole operation window.
+type timeoutTransport struct {
+	base    http.RoundTripper
+	timeout time.Duration
+}
+
+// newTimeoutTransport wraps base with a per-request timeout
+func newTimeoutTransport(base http.RoundTripper, timeout time.Duration) http.RoundTripper {
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	return &timeoutTransport{base: base, timeout: timeout}
+}
+
+// RoundTrip implements http.RoundTripper
+func (t *timeoutTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	timeout := t.timeout
+	if override, ok := req.Context().Value(requestTimeoutKey{}).(time.Duration); ok {
+		timeout = override
+	}
+	if timeout <= 0 {
+		return t.base.RoundTrip(req)
+	}
+
+	ctx, cancel := context.WithTimeout(req.Context(), timeout)
+	res, err := t.base.RoundTrip(req.WithContext(ctx))
+	if err != nil {
+		cancel()
+		return nil, err
+	}
+
+	// The deadline also covers reading the body; release it once the body is closed
+	res.Body = &cancelOnCloseBody{ReadCloser: res.Body, cancel: cancel}
+	return res, nil
+}
+
+// cancelOnCloseBody cancels the request context when the response body is closed
+type cancelOnCloseBody struct {
+	io.ReadCloser
+	cancel context.CancelFunc
+}
+
+// Close closes the body and releases the request context
+func (b *cancelOnCloseBody) Close() error {
+	err := b.ReadCloser.Close()
+	b.cancel()
+	return err
+}
@@ -0,0 +1,183 @@
+package shared
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// deadlineTransport records the deadline of each request it is handed
+type deadlineTransport struct {
+	deadline    time.Time
+	hasDeadline bool
+	ctx         context.Context
+}
+
+func (d *deadlineTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	d.ctx = req.Context()
+	d.deadline, d.hasDeadline = req.Context().Deadline()
+	return &http.Response{StatusCode: http.StatusOK, Body: io.NopCloser(strings.NewReader(`{}`)), Request: req}, nil
+}
+
+func TestTimeoutTransport_Deadline(t *testing.T) {
+	testCases := []struct {
+		name           string
+		timeout        time.Duration
+		ctx            func() (context.Context, context.CancelFunc)
+		expectDeadline bool
+		expectedWithin time.Duration // expected time left until the deadline, give or take a second
+	}{
+		{
+			name:           "default timeout",
+			timeout:        10 * time.Second,
+			ctx:            func() (context.Context, context.CancelFunc) { return context.Background(), func() {} },
+			expectDeadline: true,
+			expectedWithin: 10 * time.Second,
+		},
+		{
+			name:    "override",
+			timeout: 10 * time.Second,
+			ctx: func() (context.Context, context.CancelFunc) {
+				return WithRequestTimeout(context.Background(), time.Minute), func() {}
+			},
+			expectDeadline: true,
+			expectedWithin: time.Minute,
+		},
+		{
+			name:    "override disables the timeout",
+			timeout: 10 * time.Second,
+			ctx: func() (context.Context, context.CancelFunc) {
+				return WithRequestTimeout(context.Background(), 0), func() {}
+			},
+		},
+		{
+			name:    "shorter parent deadline wins",
+			timeout: 10 * time.Second,
+			ctx: func() (context.Context, context.CancelFunc) {
+				return context.WithTimeout(context.Background(), 2*time.Second)
+			},
+			expectDeadline: true,
+			expectedWithin: 2 * time.Second,
+		},
+		{
+			name:    "shorter parent deadline wins over an override",
+			timeout: 10 * time.Second,
+			ctx: func() (context.Context, context.CancelFunc) {
+				ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+				return WithRequestTimeout(ctx, time.Minute), cancel
+			},
+			expectDeadline: true,
+			expectedWithin: 2 * time.Second,
+		},
+		{
+			name:    "longer parent deadline",
+			timeout: 10 * time.Second,
+			ctx: func() (context.Context, context.CancelFunc) {
+				return context.WithTimeout(context.Background(), time.Hour)
+			},
+			expectDeadline: true,
+			expectedWithin: 10 * time.Second,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			base := &deadlineTransport{}
+			transport := newTimeoutTransport(base, tc.timeout)
+
+			ctx, cancel := tc.ctx()
+			defer cancel()
+			req, _ := http.NewRequestWithContext(ctx, http.MethodGet, "http://localhost:9200/_search", nil)
+
+			start := time.Now()
+			res, err := transport.RoundTrip(req)
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			defer res.Body.Close()
+
+			if base.hasDeadline != tc.expectDeadline {
+				t.Fatalf("Expected a deadline %v, got %v", tc.expectDeadline, base.hasDeadline)
+			}
+			if !tc.expectDeadline {
+				return
+			}
+			if left := base.deadline.Sub(start); left < tc.expectedWithin-time.Second || left > tc.expectedWithin+time.Second {
+				t.Errorf("Expected the deadline in about %v, got %v", tc.expectedWithin, left)
+			}
+		})
+	}
+}
+
+func TestTimeoutTransport_BodyOutlivesRoundTrip(t *testing.T) {
+	base := &deadlineTransport{}
+	transport := newTimeoutTransport(base, 10*time.Second)
+
+	req, _ := http.NewRequest(http.MethodGet, "http://localhost:9200/_search", nil)
+	res, err := transport.RoundTrip(req)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	// The deadline covers reading the body, so it must not be released before it is closed
+	if err := base.ctx.Err(); err != nil {
+		t.Fatalf("Expected the request context to stay open until the body is closed, got %v", err)
+	}
+	if _, err := io.ReadAll(res.Body); err != nil {
+		t.Fatalf("Unexpected error reading the body: %v", err)
+	}
+	res.Body.Close()
+	if !errors.Is(base.ctx.Err(), context.Canceled) {
+		t.Errorf("Expected closing the body to release the request context, got %v", base.ctx.Err())
+	}
+}
+
+func TestESClient_RequestTimeout(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("X-Elastic-Product", "Elasticsearch")
+		w.Header().Set("Content-Type", "application/json")
+		if r.URL.Path == "/_search" {
+			select {
+			case <-time.After(time.Second):
+			case <-r.Context().Done():
+				return
+			}
+		}
+		w.Write([]byte(`{}`))
+	}))
+	defer server.Close()
+
+	client, err := NewESClient(&ESConfig{URLs: []string{server.URL}, RequestTimeout: 50 * time.Millisecond}, zap.NewNop())
+	if err != nil {
+		t.Fatalf("Failed to create client: %v", err)
+	}
+
+	search := func(ctx context.Context) error {
+		res, err := client.Search(client.Search.WithContext(ctx))
+		if err == nil {
+			res.Body.Close()
+		}
+		return err
+	}
+
+	// A slow response is cut off at the configured timeout rather than the caller's
+	start := time.Now()
+	if err := search(context.Background()); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Expected the request to time out, got %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > 900*time.Millisecond {
+		t.Errorf("Expected the default timeout to cut the request short, took %v", elapsed)
+	}
+
+	// A long-polling call lifts the timeout for itself
+	if err := search(WithRequestTimeout(context.Background(), 0)); err != nil {
+		t.Errorf("Expected the override to let the slow request finish, got %v", err)
+	}
+}
@@ -2,14 +2,17 @@ package shared
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
 	"strings"
+
+	"github.com/elastic/go-elasticsearch/v8/esapi"
 )
 
 // DecodeJSONResponse decodes a JSON response from Elasticsearch
-func DecodeJSONResponse(res *http.Response, v interface{}) error {
+func DecodeJSONResponse(res *esapi.Response, v interface{}) error {
 	if res.Body == nil {
 		return fmt.Errorf("response body is nil")
 	}
@@ -37,23 +40,88 @@ type ESErrorResponse struct {
 	Error ESError `json:"error"`
 }
 
-// ParseESError attempts to parse an Elasticsearch error from the response
-func ParseESError(res *http.Response) error {
+// ResponseError is an error response from Elasticsearch, as returned by ParseESError
+type ResponseError struct {
+	StatusCode int
+	// Elasticsearch's error type, such as index_not_found_exception; empty when the
+	// response carries no error object, as for a missing document
+	Type   string
+	Reason string
+}
+
+func (e *ResponseError) Error() string {
+	if e.Type == "" {
+		return fmt.Sprintf("elasticsearch error: %s", e.Reason)
+	}
+	return fmt.Sprintf("elasticsearch error [%s]: %s", e.Type, e.Reason)
+}
+
+// ParseESError parses an Elasticsearch error response into a *ResponseError
+func ParseESError(res *esapi.Response) error {
+	esErr := &ResponseError{StatusCode: res.StatusCode}
 	if res.Body == nil {
-		return fmt.Errorf("elasticsearch error: %s", res.Status())
+		esErr.Reason = statusLine(res.StatusCode)
+		return esErr
 	}
 
 	body, err := io.ReadAll(res.Body)
 	if err != nil {
-		return fmt.Errorf("elasticsearch error: %s (failed to read body: %v)", res.Status(), err)
+		esErr.Reason = fmt.Sprintf("%s (failed to read body: %v)", statusLine(res.StatusCode), err)
+		return esErr
 	}
 
-	var esErr ESErrorResponse
-	if err := json.Unmarshal(body, &esErr); err != nil {
-		return fmt.Errorf("elasticsearch error: %s (body: %s)", res.Status(), string(body))
+	var parsed ESErrorResponse
+	if err := json.Unmarshal(body, &parsed); err != nil {
+		esErr.Reason = fmt.Sprintf("%s (body: %s)", statusLine(res.StatusCode), string(body))
+		return esErr
 	}
 
-	return fmt.Errorf("elasticsearch error [%s]: %s", esErr.Error.Type, esErr.Error.Reason)
+	esErr.Type = parsed.Error.Type
+	esErr.Reason = parsed.Error.Reason
+	if esErr.Reason == "" {
+		esErr.Reason = statusLine(res.StatusCode)
+	}
+	return esErr
+}
+
+func statusLine(code int) string {
+	return fmt.Sprintf("%d %s", code, http.StatusText(code))
+}
+
+// IsNotFound reports whether err is an Elasticsearch 404, such as a missing index or document
+func IsNotFound(err error) bool {
+	return hasStatus(err, http.StatusNotFound)
+}
+
+// IsConflict reports whether err is an Elasticsearch 409, such as a version conflict
+func IsConflict(err error) bool {
+	return hasStatus(err, http.StatusConflict)
+}
+
+// IsBadRequest reports whether err is an Elasticsearch 400, such as a mapping conflict or
+// an index that already exists
+func IsBadRequest(err error) bool {
+	return hasStatus(err, http.StatusBadRequest)
+}
+
+func hasStatus(err error, code int) bool {
+	var esErr *ResponseError
+	return errors.As(err, &esErr) && esErr.StatusCode == code
+}
+
+// HTTPStatus returns the status a service should answer with for err: Elasticsearch's own
+// status when it rejected the request as a client error, 500 otherwise. Authentication
+// failures are the service's configuration problem, not the caller's, so they map to 500.
+func HTTPStatus(err error) int {
+	var esErr *ResponseError
+	if !errors.As(err, &esErr) || esErr.StatusCode < 400 || esErr.StatusCode >= 500 {
+		return http.StatusInternalServerError
+	}
+	switch esErr.StatusCode {
+	case http.StatusUnauthorized, http.StatusForbidden, http.StatusProxyAuthRequired:
+		return http.StatusInternalServerError
+	}
+	return esErr.StatusCode
 }
 
 // FormatIndexName ensures index names follow Elasticsearch conventions
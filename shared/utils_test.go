@@ -0,0 +1,169 @@
+package shared
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/elastic/go-elasticsearch/v8/esapi"
+)
+
+func newESResponse(status int, body string) *esapi.Response {
+	res := &esapi.Response{StatusCode: status, Header: http.Header{}}
+	if body != "" {
+		res.Body = io.NopCloser(strings.NewReader(body))
+	}
+	return res
+}
+
+func TestParseESError(t *testing.T) {
+	testCases := []struct {
+		name           string
+		status         int
+		body           string
+		expectedType   string
+		expectedReason string
+	}{
+		{
+			name:           "error object",
+			status:         http.StatusNotFound,
+			body:           `{"error":{"type":"index_not_found_exception","reason":"no such index [logs]"},"status":404}`,
+			expectedType:   "index_not_found_exception",
+			expectedReason: "no such index [logs]",
+		},
+		{
+			name:           "missing document",
+			status:         http.StatusNotFound,
+			body:           `{"_index":"logs","_id":"1","found":false}`,
+			expectedReason: "404 Not Found",
+		},
+		{
+			name:           "non-JSON body",
+			status:         http.StatusBadGateway,
+			body:           "upstream unavailable",
+			expectedReason: "502 Bad Gateway (body: upstream unavailable)",
+		},
+		{
+			name:           "no body",
+			status:         http.StatusConflict,
+			expectedReason: "409 Conflict",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			err := ParseESError(newESResponse(tc.status, tc.body))
+
+			var esErr *ResponseError
+			if !errors.As(err, &esErr) {
+				t.Fatalf("Expected *ResponseError, got %T", err)
+			}
+			if esErr.StatusCode != tc.status {
+				t.Errorf("Expected status %d, got %d", tc.status, esErr.StatusCode)
+			}
+			if esErr.Type != tc.expectedType {
+				t.Errorf("Expected type %q, got %q", tc.expectedType, esErr.Type)
+			}
+			if esErr.Reason != tc.expectedReason {
+				t.Errorf("Expected reason %q, got %q", tc.expectedReason, esErr.Reason)
+			}
+		})
+	}
+}
+
+func TestResponseError_Error(t *testing.T) {
+	withType := &ResponseError{StatusCode: 400, Type: "mapper_parsing_exception", Reason: "failed to parse"}
+	if got := withType.Error(); got != "elasticsearch error [mapper_parsing_exception]: failed to parse" {
+		t.Errorf("Unexpected message %q", got)
+	}
+
+	withoutType := &ResponseError{StatusCode: 404, Reason: "document 1 not found"}
+	if got := withoutType.Error(); got != "elasticsearch error: document 1 not found" {
+		t.Errorf("Unexpected message %q", got)
+	}
+}
+
+func TestStatusHelpers(t *testing.T) {
+	testCases := []struct {
+		name       string
+		err        error
+		notFound   bool
+		conflict   bool
+		badRequest bool
+		status     int
+	}{
+		{
+			name:     "missing document",
+			err:      ParseESError(newESResponse(http.StatusNotFound, `{"found":false}`)),
+			notFound: true,
+			status:   http.StatusNotFound,
+		},
+		{
+			name:     "version conflict",
+			err:      &ResponseError{StatusCode: http.StatusConflict, Type: "version_conflict_engine_exception"},
+			conflict: true,
+			status:   http.StatusConflict,
+		},
+		{
+			name:       "mapping conflict",
+			err:        &ResponseError{StatusCode: http.StatusBadRequest, Type: "illegal_argument_exception"},
+			badRequest: true,
+			status:     http.StatusBadRequest,
+		},
+		{
+			name:     "wrapped",
+			err:      fmt.Errorf("failed to update document: %w", &ResponseError{StatusCode: http.StatusNotFound}),
+			notFound: true,
+			status:   http.StatusNotFound,
+		},
+		{
+			name:   "too many requests",
+			err:    &ResponseError{StatusCode: http.StatusTooManyRequests},
+			status: http.StatusTooManyRequests,
+		},
+		{
+			name:   "unauthorized",
+			err:    &ResponseError{StatusCode: http.StatusUnauthorized, Type: "security_exception"},
+			status: http.StatusInternalServerError,
+		},
+		{
+			name:   "forbidden",
+			err:    &ResponseError{StatusCode: http.StatusForbidden, Type: "security_exception"},
+			status: http.StatusInternalServerError,
+		},
+		{
+			name:   "server error",
+			err:    &ResponseError{StatusCode: http.StatusServiceUnavailable},
+			status: http.StatusInternalServerError,
+		},
+		{
+			name:   "transport error",
+			err:    errors.New("connection refused"),
+			status: http.StatusInternalServerError,
+		},
+		{
+			name:   "nil",
+			status: http.StatusInternalServerError,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := IsNotFound(tc.err); got != tc.notFound {
+				t.Errorf("IsNotFound: expected %v, got %v", tc.notFound, got)
+			}
+			if got := IsConflict(tc.err); got != tc.conflict {
+				t.Errorf("IsConflict: expected %v, got %v", tc.conflict, got)
+			}
+			if got := IsBadRequest(tc.err); got != tc.badRequest {
+				t.Errorf("IsBadRequest: expected %v, got %v", tc.badRequest, got)
+			}
+			if got := HTTPStatus(tc.err); got != tc.status {
+				t.Errorf("HTTPStatus: expected %d, got %d", tc.status, got)
+			}
+		})
+	}
+}